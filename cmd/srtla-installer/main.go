@@ -2,6 +2,7 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
@@ -10,10 +11,14 @@ import (
 	"net"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"regexp"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
+	"unicode"
 )
 
 // Version/build info (set via -ldflags)
@@ -83,6 +88,164 @@ type UpdateInstallerResponse struct {
 	Error   string `json:"error,omitempty"`
 }
 
+// RestartServiceRequest asks for a system service to be restarted
+type RestartServiceRequest struct {
+	Token   string `json:"token"`
+	Service string `json:"restart_service"`
+}
+
+type RestartServiceResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	State   string `json:"state"` // systemctl is-active after the restart
+	Error   string `json:"error,omitempty"`
+}
+
+// ShapeInterfaceRequest caps the egress rate of a network interface.
+// RateKbps 0 removes the cap.
+type ShapeInterfaceRequest struct {
+	Token     string `json:"token"`
+	Interface string `json:"shape_interface"`
+	RateKbps  int    `json:"rate_kbps"`
+}
+
+type ShapeInterfaceResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	Error   string `json:"error,omitempty"`
+}
+
+// RouteRequest runs one ip route or ip rule command of the bind IP routing
+// tables. Args are checked against the commands the manager uses.
+type RouteRequest struct {
+	Token string   `json:"token"`
+	Args  []string `json:"ip_route"`
+}
+
+type RouteResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	Error   string `json:"error,omitempty"`
+}
+
+// VPNRequest runs a command of the VPN tunnel: wg-quick up or down, wg show
+// or tailscale up, down or status. The Tailscale auth key is handed over in
+// a root-only file rather than on the command line.
+type VPNRequest struct {
+	Token   string   `json:"token"`
+	Command string   `json:"vpn_command"`
+	Args    []string `json:"args"`
+	AuthKey string   `json:"auth_key,omitempty"`
+}
+
+type VPNResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	Output  string `json:"output"`
+	Error   string `json:"error,omitempty"`
+}
+
+// PowerCycleRequest switches a USB port off for OffSeconds and back on with
+// uhubctl, to revive a modem that no longer answers
+type PowerCycleRequest struct {
+	Token      string `json:"token"`
+	Hub        string `json:"usb_power_cycle"` // hub location, e.g. 1-1
+	Port       string `json:"port"`
+	OffSeconds int    `json:"off_seconds"`
+}
+
+type PowerCycleResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	Error   string `json:"error,omitempty"`
+}
+
+// PowerOffRequest powers the unit off, for its shutdown button
+type PowerOffRequest struct {
+	Token    string `json:"token"`
+	PowerOff bool   `json:"poweroff"`
+}
+
+type PowerOffResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	Error   string `json:"error,omitempty"`
+}
+
+// LinkAliasRequest sets the alias of a network interface, shown by "ip
+// link", to the name given to its USB modem. An empty Alias clears it.
+type LinkAliasRequest struct {
+	Token     string `json:"token"`
+	Interface string `json:"link_alias"`
+	Alias     string `json:"alias"`
+}
+
+type LinkAliasResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	Error   string `json:"error,omitempty"`
+}
+
+// OSUpdateRequest upgrades the operating system, either through apt or by
+// running osUpdateScript
+type OSUpdateRequest struct {
+	Token  string `json:"token"`
+	Method string `json:"os_update"` // "apt" or "script"
+}
+
+// OSUpdateResponse is streamed one per line: output lines as they come,
+// then a final response with Done set
+type OSUpdateResponse struct {
+	Output         string `json:"output,omitempty"`
+	Done           bool   `json:"done,omitempty"`
+	Success        bool   `json:"success"`
+	Message        string `json:"message,omitempty"`
+	RebootRequired bool   `json:"reboot_required,omitempty"`
+	Error          string `json:"error,omitempty"`
+}
+
+const (
+	// osUpdateScript is run for the "script" method. It and its directory
+	// must be owned by root and writable by nobody else, since it runs as
+	// root.
+	osUpdateScript  = "/etc/srtla-installer/os-update.sh"
+	osUpdateTimeout = 2 * time.Hour
+	rebootRequired  = "/var/run/reboot-required"
+)
+
+var interfaceNameRegex = regexp.MustCompile(`^[a-zA-Z0-9_.-]{1,15}$`)
+
+var (
+	usbHubRegex  = regexp.MustCompile(`^[0-9]{1,3}(-[0-9]{1,3}(\.[0-9]{1,3})*)?$`)
+	usbPortRegex = regexp.MustCompile(`^[0-9]{1,3}$`)
+)
+
+var (
+	wgInterfaceRegex       = regexp.MustCompile(`^[a-zA-Z0-9_=+.-]{1,15}$`)
+	tailscaleHostnameRegex = regexp.MustCompile(`^[a-zA-Z0-9-]{1,63}$`)
+	tailscaleAuthKeyRegex  = regexp.MustCompile(`^\S+$`)
+)
+
+// routeCommands are the ip commands a route request may run. <table> is
+// one of the tables left free by the kernel (1-252) and <priority> a rule
+// priority between the local and main rules (1-32765).
+var routeCommands = [][]string{
+	{"route", "replace", "<network>", "dev", "<interface>", "src", "<ip>", "table", "<table>"},
+	{"route", "replace", "default", "via", "<ip>", "dev", "<interface>", "table", "<table>"},
+	{"route", "replace", "default", "dev", "<interface>", "table", "<table>"},
+	{"route", "flush", "table", "<table>"},
+	{"rule", "add", "from", "<host>", "lookup", "<table>", "priority", "<priority>"},
+	{"rule", "del", "priority", "<priority>"},
+}
+
+// restartableServices are the only units a restart may be requested for.
+// These daemons wedge on USB modem churn.
+var restartableServices = map[string]bool{
+	"ModemManager":   true,
+	"NetworkManager": true,
+	"bluetooth":      true,
+}
+
 func main() {
 	versionFlag := flag.Bool("version", false, "Show version and exit")
 	versionShort := flag.Bool("v", false, "Show version and exit (shorthand)")
@@ -170,6 +333,62 @@ func handleConn(conn net.Conn) {
 	}
 	line = strings.TrimSpace(line)
 
+	// OS updates are the only requests with an os_update field
+	var osUpdateReq OSUpdateRequest
+	if err := json.Unmarshal([]byte(line), &osUpdateReq); err == nil && osUpdateReq.Method != "" {
+		handleOSUpdate(conn, osUpdateReq)
+		return
+	}
+
+	// Power-offs are the only requests with a poweroff field
+	var powerOffReq PowerOffRequest
+	if err := json.Unmarshal([]byte(line), &powerOffReq); err == nil && powerOffReq.PowerOff {
+		handlePowerOff(conn)
+		return
+	}
+
+	// USB power cycles are the only requests with a usb_power_cycle field
+	var cycleReq PowerCycleRequest
+	if err := json.Unmarshal([]byte(line), &cycleReq); err == nil && cycleReq.Hub != "" {
+		handlePowerCycle(conn, cycleReq)
+		return
+	}
+
+	// VPN commands are the only requests with a vpn_command field
+	var vpnReq VPNRequest
+	if err := json.Unmarshal([]byte(line), &vpnReq); err == nil && vpnReq.Command != "" {
+		handleVPN(conn, vpnReq)
+		return
+	}
+
+	// Routing changes are the only requests with an ip_route field
+	var routeReq RouteRequest
+	if err := json.Unmarshal([]byte(line), &routeReq); err == nil && len(routeReq.Args) > 0 {
+		handleRoute(conn, routeReq)
+		return
+	}
+
+	// Interface aliases are the only requests with a link_alias field
+	var aliasReq LinkAliasRequest
+	if err := json.Unmarshal([]byte(line), &aliasReq); err == nil && aliasReq.Interface != "" {
+		handleLinkAlias(conn, aliasReq)
+		return
+	}
+
+	// Link caps are the only requests with a shape_interface field
+	var shapeReq ShapeInterfaceRequest
+	if err := json.Unmarshal([]byte(line), &shapeReq); err == nil && shapeReq.Interface != "" {
+		handleShapeInterface(conn, shapeReq)
+		return
+	}
+
+	// Service restarts are the only requests with a restart_service field
+	var restartReq RestartServiceRequest
+	if err := json.Unmarshal([]byte(line), &restartReq); err == nil && restartReq.Service != "" {
+		handleServiceRestart(conn, restartReq)
+		return
+	}
+
 	// Try to detect request type by checking which fields are present
 	// Check for self-update request first (has SourcePath but no TargetPath pointing to a service)
 	var updateInstallerReq UpdateInstallerRequest
@@ -378,6 +597,385 @@ func handleBinaryUpdate(conn net.Conn, req UpdateBinaryRequest) {
 	writeBinaryUpdateResponse(conn, true, "Binary updated successfully")
 }
 
+// handleServiceRestart restarts one of restartableServices and waits for it
+// to come back up
+func handleServiceRestart(conn net.Conn, req RestartServiceRequest) {
+	log.Printf("[SERVICE] Received restart request for %s", req.Service)
+
+	if !restartableServices[req.Service] {
+		log.Printf("[SERVICE] FAILED: %s may not be restarted", req.Service)
+		writeServiceRestartResponse(conn, false, fmt.Sprintf("Service %s may not be restarted", req.Service), "")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	if output, err := exec.CommandContext(ctx, "systemctl", "restart", req.Service).CombinedOutput(); err != nil {
+		log.Printf("[SERVICE] FAILED: systemctl restart %s: %v, output: %s", req.Service, err, output)
+		writeServiceRestartResponse(conn, false, fmt.Sprintf("systemctl restart failed: %v\n%s", err, output), serviceState(req.Service))
+		return
+	}
+
+	// Verify the service came back; some take a moment to report active
+	state := serviceState(req.Service)
+	for i := 0; i < 20 && state != "active"; i++ {
+		time.Sleep(500 * time.Millisecond)
+		state = serviceState(req.Service)
+	}
+	if state != "active" {
+		log.Printf("[SERVICE] ERROR: %s is %s after restart", req.Service, state)
+		writeServiceRestartResponse(conn, false, fmt.Sprintf("Service %s is %s after restart", req.Service, state), state)
+		return
+	}
+
+	log.Printf("[SERVICE] %s restarted and active", req.Service)
+	writeServiceRestartResponse(conn, true, fmt.Sprintf("Service %s restarted", req.Service), state)
+}
+
+// handleOSUpdate upgrades the system packages or runs the update script,
+// streaming its output back line by line
+func handleOSUpdate(conn net.Conn, req OSUpdateRequest) {
+	log.Printf("[OSUPDATE] Received %s update request", req.Method)
+
+	var cmd *exec.Cmd
+	ctx, cancel := context.WithTimeout(context.Background(), osUpdateTimeout)
+	defer cancel()
+	switch req.Method {
+	case "apt":
+		cmd = exec.CommandContext(ctx, "sh", "-c",
+			"apt-get update && apt-get -y -o Dpkg::Options::=--force-confdef -o Dpkg::Options::=--force-confold upgrade")
+		cmd.Env = append(os.Environ(), "DEBIAN_FRONTEND=noninteractive")
+	case "script":
+		if _, err := os.Stat(osUpdateScript); err != nil {
+			writeOSUpdateResponse(conn, false, "Update script not found: "+err.Error())
+			return
+		}
+		for _, path := range []string{filepath.Dir(osUpdateScript), osUpdateScript} {
+			if err := checkRootOnly(path); err != nil {
+				log.Printf("[OSUPDATE] FAILED: %v", err)
+				writeOSUpdateResponse(conn, false, err.Error())
+				return
+			}
+		}
+		cmd = exec.CommandContext(ctx, osUpdateScript)
+	default:
+		writeOSUpdateResponse(conn, false, fmt.Sprintf("Unknown update method %q", req.Method))
+		return
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		writeOSUpdateResponse(conn, false, "Failed to capture output: "+err.Error())
+		return
+	}
+	cmd.Stderr = cmd.Stdout
+	if err := cmd.Start(); err != nil {
+		log.Printf("[OSUPDATE] FAILED: %v", err)
+		writeOSUpdateResponse(conn, false, "Failed to start update: "+err.Error())
+		return
+	}
+
+	enc := json.NewEncoder(conn)
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		// Keep going when the manager hangs up; the upgrade must not be
+		// interrupted half way
+		_ = enc.Encode(OSUpdateResponse{Output: scanner.Text()})
+	}
+
+	if err := cmd.Wait(); err != nil {
+		log.Printf("[OSUPDATE] FAILED: %s update: %v", req.Method, err)
+		writeOSUpdateResponse(conn, false, fmt.Sprintf("%s update failed: %v", req.Method, err))
+		return
+	}
+
+	resp := OSUpdateResponse{Done: true, Success: true, Message: fmt.Sprintf("%s update complete", req.Method)}
+	if _, err := os.Stat(rebootRequired); err == nil {
+		resp.RebootRequired = true
+		resp.Message += ", reboot required"
+	}
+	log.Printf("[OSUPDATE] %s", resp.Message)
+	enc.Encode(resp)
+}
+
+// checkRootOnly returns an error unless path is owned by root and not
+// writable by group or others, so only root can change what runs as root
+func checkRootOnly(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !ownedByRoot(info) {
+		return fmt.Errorf("%s must be owned by root", path)
+	}
+	if info.Mode().Perm()&0022 != 0 {
+		return fmt.Errorf("%s must not be writable by group or others", path)
+	}
+	return nil
+}
+
+// handleShapeInterface replaces the root qdisc of an interface with a token
+// bucket at the requested rate, or removes it
+func handleShapeInterface(conn net.Conn, req ShapeInterfaceRequest) {
+	log.Printf("[SHAPE] Received request: interface=%s rate=%dkbps", req.Interface, req.RateKbps)
+
+	if !interfaceNameRegex.MatchString(req.Interface) {
+		writeShapeResponse(conn, false, "Invalid interface name")
+		return
+	}
+	if req.RateKbps < 0 || req.RateKbps > 1000000 {
+		writeShapeResponse(conn, false, "Rate out of range")
+		return
+	}
+	if _, err := net.InterfaceByName(req.Interface); err != nil {
+		writeShapeResponse(conn, false, fmt.Sprintf("Interface %s not found", req.Interface))
+		return
+	}
+
+	if req.RateKbps == 0 {
+		output, err := exec.Command("tc", "qdisc", "del", "dev", req.Interface, "root").CombinedOutput()
+		// deleting a qdisc that isn't there is not an error for us
+		if err != nil && !strings.Contains(string(output), "No such file") && !strings.Contains(string(output), "Cannot delete qdisc with handle of zero") {
+			log.Printf("[SHAPE] FAILED: tc qdisc del: %v, output: %s", err, output)
+			writeShapeResponse(conn, false, fmt.Sprintf("tc failed: %v\n%s", err, output))
+			return
+		}
+		log.Printf("[SHAPE] Removed cap on %s", req.Interface)
+		writeShapeResponse(conn, true, fmt.Sprintf("Removed cap on %s", req.Interface))
+		return
+	}
+
+	// 100ms worth of data, so short bursts aren't dropped
+	burst := req.RateKbps * 1000 / 8 / 10
+	if burst < 16000 {
+		burst = 16000
+	}
+	output, err := exec.Command("tc", "qdisc", "replace", "dev", req.Interface, "root", "tbf",
+		"rate", fmt.Sprintf("%dkbit", req.RateKbps),
+		"burst", strconv.Itoa(burst),
+		"latency", "400ms").CombinedOutput()
+	if err != nil {
+		log.Printf("[SHAPE] FAILED: tc qdisc replace: %v, output: %s", err, output)
+		writeShapeResponse(conn, false, fmt.Sprintf("tc failed: %v\n%s", err, output))
+		return
+	}
+	log.Printf("[SHAPE] Capped %s at %d kbps", req.Interface, req.RateKbps)
+	writeShapeResponse(conn, true, fmt.Sprintf("Capped %s at %d kbps", req.Interface, req.RateKbps))
+}
+
+// handleRoute runs ip with the request's arguments once they match one of
+// routeCommands
+func handleRoute(conn net.Conn, req RouteRequest) {
+	if !allowedRoute(req.Args) {
+		log.Printf("[ROUTE] FAILED: ip %s is not allowed", strings.Join(req.Args, " "))
+		writeRouteResponse(conn, false, "Command not allowed")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, "ip", req.Args...).CombinedOutput()
+	if err != nil {
+		// the manager drops rules that may not be there, so this is common
+		log.Printf("[ROUTE] ip %s: %v, output: %s", strings.Join(req.Args, " "), err, output)
+		writeRouteResponse(conn, false, fmt.Sprintf("%v: %s", err, strings.TrimSpace(string(output))))
+		return
+	}
+	log.Printf("[ROUTE] ip %s", strings.Join(req.Args, " "))
+	writeRouteResponse(conn, true, strings.TrimSpace(string(output)))
+}
+
+// handlePowerOff asks systemd to power the unit off. systemctl returns once
+// the shutdown is queued, so the response still goes out.
+func handlePowerOff(conn net.Conn) {
+	log.Printf("[POWER] Received power-off request")
+	output, err := exec.Command("systemctl", "poweroff").CombinedOutput()
+	if err != nil {
+		log.Printf("[POWER] FAILED: systemctl poweroff: %v, output: %s", err, output)
+		writePowerOffResponse(conn, false, fmt.Sprintf("systemctl poweroff failed: %v\n%s", err, output))
+		return
+	}
+	log.Printf("[POWER] Powering off")
+	writePowerOffResponse(conn, true, "Powering off")
+}
+
+// maxLinkAlias is the longest alias the kernel keeps (IFALIASZ less the
+// terminating NUL)
+const maxLinkAlias = 255
+
+// handleLinkAlias sets the alias of an interface
+func handleLinkAlias(conn net.Conn, req LinkAliasRequest) {
+	log.Printf("[ALIAS] Received request: interface=%s alias=%q", req.Interface, req.Alias)
+
+	if !interfaceNameRegex.MatchString(req.Interface) {
+		writeLinkAliasResponse(conn, false, "Invalid interface name")
+		return
+	}
+	if len(req.Alias) > maxLinkAlias || strings.IndexFunc(req.Alias, unicode.IsControl) >= 0 {
+		writeLinkAliasResponse(conn, false, "Invalid alias")
+		return
+	}
+	if _, err := net.InterfaceByName(req.Interface); err != nil {
+		writeLinkAliasResponse(conn, false, fmt.Sprintf("Interface %s not found", req.Interface))
+		return
+	}
+
+	output, err := exec.Command("ip", "link", "set", "dev", req.Interface, "alias", req.Alias).CombinedOutput()
+	if err != nil {
+		log.Printf("[ALIAS] FAILED: ip link set: %v, output: %s", err, output)
+		writeLinkAliasResponse(conn, false, fmt.Sprintf("ip failed: %v\n%s", err, output))
+		return
+	}
+	log.Printf("[ALIAS] Set alias of %s to %q", req.Interface, req.Alias)
+	writeLinkAliasResponse(conn, true, fmt.Sprintf("Set alias of %s", req.Interface))
+}
+
+// handlePowerCycle switches a USB port off and back on
+func handlePowerCycle(conn net.Conn, req PowerCycleRequest) {
+	log.Printf("[USB] Received power cycle request: hub=%s port=%s off=%ds", req.Hub, req.Port, req.OffSeconds)
+
+	if !usbHubRegex.MatchString(req.Hub) || !usbPortRegex.MatchString(req.Port) {
+		writePowerCycleResponse(conn, false, "Invalid hub or port")
+		return
+	}
+	if req.OffSeconds < 1 || req.OffSeconds > 30 {
+		writePowerCycleResponse(conn, false, "Off time out of range")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, "uhubctl", "-l", req.Hub, "-p", req.Port, "-a", "cycle", "-d", strconv.Itoa(req.OffSeconds)).CombinedOutput()
+	if err != nil {
+		log.Printf("[USB] FAILED: uhubctl: %v, output: %s", err, output)
+		writePowerCycleResponse(conn, false, fmt.Sprintf("uhubctl failed: %v\n%s", err, output))
+		return
+	}
+	log.Printf("[USB] Power-cycled port %s of hub %s", req.Port, req.Hub)
+	writePowerCycleResponse(conn, true, fmt.Sprintf("Power-cycled port %s of hub %s", req.Port, req.Hub))
+}
+
+// handleVPN runs a tunnel command once it is one the manager uses
+func handleVPN(conn net.Conn, req VPNRequest) {
+	if !allowedVPN(req) {
+		log.Printf("[VPN] FAILED: %s %s is not allowed", req.Command, strings.Join(req.Args, " "))
+		writeVPNResponse(conn, false, "Command not allowed", "")
+		return
+	}
+
+	args := req.Args
+	if req.AuthKey != "" {
+		f, err := os.CreateTemp("", "tailscale-authkey-*")
+		if err != nil {
+			writeVPNResponse(conn, false, "Failed to write auth key: "+err.Error(), "")
+			return
+		}
+		defer os.Remove(f.Name())
+		_, err = f.WriteString(req.AuthKey)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			writeVPNResponse(conn, false, "Failed to write auth key: "+err.Error(), "")
+			return
+		}
+		args = append(args, "--auth-key=file:"+f.Name())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, req.Command, args...).CombinedOutput()
+	if err != nil {
+		log.Printf("[VPN] FAILED: %s %s: %v", req.Command, strings.Join(req.Args, " "), err)
+		writeVPNResponse(conn, false, err.Error(), string(output))
+		return
+	}
+	writeVPNResponse(conn, true, "", string(output))
+}
+
+// allowedVPN reports whether a VPN request is one the manager makes
+func allowedVPN(req VPNRequest) bool {
+	args := req.Args
+	// only tailscale up takes a key
+	if req.AuthKey != "" && (req.Command != "tailscale" || len(args) == 0 || args[0] != "up" ||
+		len(req.AuthKey) > 1024 || !tailscaleAuthKeyRegex.MatchString(req.AuthKey)) {
+		return false
+	}
+	switch req.Command {
+	case "wg-quick":
+		return len(args) == 2 && (args[0] == "up" || args[0] == "down") && wgInterfaceRegex.MatchString(args[1])
+	case "wg":
+		return len(args) == 3 && args[0] == "show" && wgInterfaceRegex.MatchString(args[1]) && args[2] == "dump"
+	case "tailscale":
+		switch {
+		case len(args) == 1 && args[0] == "down":
+			return true
+		case len(args) == 2 && args[0] == "status" && args[1] == "--json":
+			return true
+		case len(args) >= 1 && args[0] == "up":
+			for _, arg := range args[1:] {
+				if hostname, ok := strings.CutPrefix(arg, "--hostname="); ok && tailscaleHostnameRegex.MatchString(hostname) {
+					continue
+				}
+				if arg != "--timeout=20s" {
+					return false
+				}
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// allowedRoute reports whether args match one of routeCommands
+func allowedRoute(args []string) bool {
+	for _, pattern := range routeCommands {
+		if len(pattern) == len(args) && matchRoute(pattern, args) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchRoute(pattern, args []string) bool {
+	for i, p := range pattern {
+		a := args[i]
+		var ok bool
+		switch p {
+		case "<network>":
+			_, network, err := net.ParseCIDR(a)
+			ok = err == nil && network.String() == a && network.IP.To4() != nil
+		case "<ip>":
+			ip := net.ParseIP(a)
+			ok = ip != nil && ip.To4() != nil
+		case "<host>":
+			host, isHost := strings.CutSuffix(a, "/32")
+			ip := net.ParseIP(host)
+			ok = isHost && ip != nil && ip.To4() != nil
+		case "<interface>":
+			ok = interfaceNameRegex.MatchString(a)
+		case "<table>":
+			n, err := strconv.Atoi(a)
+			ok = err == nil && n >= 1 && n <= 252 && strconv.Itoa(n) == a
+		case "<priority>":
+			n, err := strconv.Atoi(a)
+			ok = err == nil && n >= 1 && n <= 32765 && strconv.Itoa(n) == a
+		default:
+			ok = p == a
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// serviceState returns what systemctl is-active reports for a unit
+func serviceState(unit string) string {
+	out, _ := exec.Command("systemctl", "is-active", unit).Output()
+	return strings.TrimSpace(string(out))
+}
+
 // copyFile copies src to dst
 func copyFile(src, dst string) error {
 	source, err := os.Open(src)
@@ -408,6 +1006,54 @@ func writeBinaryUpdateResponse(w io.Writer, success bool, msg string) {
 	w.Write(append(data, '\n'))
 }
 
+func writeShapeResponse(w io.Writer, success bool, msg string) {
+	resp := ShapeInterfaceResponse{Success: success, Message: msg}
+	data, _ := json.Marshal(resp)
+	w.Write(append(data, '\n'))
+}
+
+func writePowerOffResponse(w io.Writer, success bool, msg string) {
+	resp := PowerOffResponse{Success: success, Message: msg}
+	data, _ := json.Marshal(resp)
+	w.Write(append(data, '\n'))
+}
+
+func writeLinkAliasResponse(w io.Writer, success bool, msg string) {
+	resp := LinkAliasResponse{Success: success, Message: msg}
+	data, _ := json.Marshal(resp)
+	w.Write(append(data, '\n'))
+}
+
+func writePowerCycleResponse(w io.Writer, success bool, msg string) {
+	resp := PowerCycleResponse{Success: success, Message: msg}
+	data, _ := json.Marshal(resp)
+	w.Write(append(data, '\n'))
+}
+
+func writeVPNResponse(w io.Writer, success bool, msg, output string) {
+	resp := VPNResponse{Success: success, Message: msg, Output: output}
+	data, _ := json.Marshal(resp)
+	w.Write(append(data, '\n'))
+}
+
+func writeRouteResponse(w io.Writer, success bool, msg string) {
+	resp := RouteResponse{Success: success, Message: msg}
+	data, _ := json.Marshal(resp)
+	w.Write(append(data, '\n'))
+}
+
+func writeServiceRestartResponse(w io.Writer, success bool, msg, state string) {
+	resp := RestartServiceResponse{Success: success, Message: msg, State: state}
+	data, _ := json.Marshal(resp)
+	w.Write(append(data, '\n'))
+}
+
+func writeOSUpdateResponse(w io.Writer, success bool, msg string) {
+	resp := OSUpdateResponse{Done: true, Success: success, Message: msg}
+	data, _ := json.Marshal(resp)
+	w.Write(append(data, '\n'))
+}
+
 func writeInstallerUpdateResponse(w io.Writer, success bool, msg string) {
 	resp := UpdateInstallerResponse{Success: success, Message: msg}
 	data, _ := json.Marshal(resp)
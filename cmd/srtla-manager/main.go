@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"flag"
 	"fmt"
 	"io/fs"
@@ -16,10 +17,15 @@ import (
 
 	"srtla-manager/internal/api"
 	"srtla-manager/internal/config"
+	"srtla-manager/internal/events"
 	"srtla-manager/internal/logger"
+	"srtla-manager/internal/mockproc"
 	"srtla-manager/internal/modem"
 	"srtla-manager/internal/process"
 	"srtla-manager/internal/stats"
+	"srtla-manager/internal/system"
+	"srtla-manager/internal/tlscert"
+	"srtla-manager/internal/tracing"
 	"srtla-manager/internal/usbnet"
 	"srtla-manager/internal/version"
 	"srtla-manager/internal/wifi"
@@ -27,9 +33,14 @@ import (
 )
 
 func main() {
+	// started as ffmpeg or srtla_send by test mode
+	mockproc.Dispatch()
+
 	configPath := flag.String("config", "config.yaml", "Path to configuration file")
 	versionFlag := flag.Bool("version", false, "Show version and exit")
 	versionShort := flag.Bool("v", false, "Show version and exit (shorthand)")
+	testMode := flag.Bool("test-mode", false, "Run mock ffmpeg and srtla_send instead of the real binaries")
+	watchConfig := flag.Bool("watch-config", true, "Reload the config file when it is edited while running")
 	flag.Parse()
 
 	if *versionFlag || *versionShort {
@@ -62,16 +73,43 @@ func main() {
 		}
 	}
 	defer logger.Get().Close()
+	logger.SetProcessFiles(cfg.Logging.ProcessFiles)
 
 	logger.Printf("Starting srtla-manager on port %d", cfg.Web.Port)
 
+	if caps := system.GetPlatformCapabilities(); !caps.Production {
+		logger.Warn("Running on %s/%s: hardware integrations (USB network, modems, WiFi, V4L2, BLE) are stubbed; development use only", caps.OS, caps.Arch)
+	}
+
+	if *testMode {
+		if err := mockproc.Enable(filepath.Join(os.TempDir(), "srtla-manager-mocks")); err != nil {
+			log.Fatalf("Failed to enable test mode: %v", err)
+		}
+		logger.Warn("Test mode: ffmpeg and srtla_send are mocked, no stream leaves this machine")
+		if cfg.SRTLA.BinaryPath != "srtla_send" {
+			logger.Warn("Test mode: srtla.binary_path is %s, set it to srtla_send to use the mock", cfg.SRTLA.BinaryPath)
+		}
+	}
+
+	if cfg.Tracing.Enabled {
+		if err := tracing.Init(cfg.Tracing.Endpoint, cfg.Tracing.ServiceName, version.GetVersion()); err != nil {
+			logger.Warn("Failed to initialize tracing: %v", err)
+		} else {
+			logger.Printf("Exporting traces to %s", cfg.Tracing.Endpoint)
+		}
+	}
+
 	statsCollector := stats.NewCollector()
 	logBuffer := stats.NewLogBuffer(1000)
 
 	ffmpegHandler := process.NewFFmpegHandler()
 	srtlaHandler := process.NewSRTLAHandler()
 	modemManager := modem.NewManager()
-	usbnetSvc, err := usbnet.Start(context.Background(), usbnet.WithPersistPath("/var/lib/srtla-manager/device_mappings.json"))
+	// the handler's discovery scans for USB network devices, continuously
+	// or when asked
+	usbnetSvc, err := usbnet.Start(context.Background(),
+		usbnet.WithPersistPath("/var/lib/srtla-manager/device_mappings.json"),
+		usbnet.WithScanInterval(0))
 	if err != nil {
 		logger.Warn("Failed to start usbnet reconciler: %v", err)
 	}
@@ -80,34 +118,100 @@ func main() {
 	wsHub := api.NewHub()
 	go wsHub.Run()
 
-	logCallback := func(log process.LogLine) {
-		logBuffer.Add(log.Source, log.Line)
-		wsHub.Broadcast("log", map[string]string{
-			"source": log.Source,
-			"line":   log.Line,
-		})
-		// Also write to the logger file
-		logger.Printf("[%s] %s", log.Source, log.Line)
+	// Subsystems publish on the bus; the handler forwards events to
+	// WebSocket clients, the log buffer, alerts and the audit log
+	bus := events.NewBus()
+	cfgManager.SetBus(bus)
+	ffmpegHandler.SetBus(bus)
+	ffmpegHandler.SetLoudnessMonitoring(cfg.Loudness.Enabled)
+	ffmpegHandler.SetAudioMeters(cfg.Loudness.Meters)
+	ffmpegHandler.SetAudioResync(cfg.RTMP.AudioResync)
+	ffmpegHandler.SetReconnectGrace(time.Duration(cfg.RTMP.ReconnectGraceSeconds) * time.Second)
+	srtlaHandler.SetBus(bus)
+
+	handler := api.NewHandler(cfgManager, ffmpegHandler, srtlaHandler, modemManager, usbnetSvc, statsCollector, logBuffer, wsHub, bus, wifiManager)
+	handler.SetVersion(version.GetVersion())
+
+	// The certificate store exists even without HTTPS so a certificate can
+	// be uploaded before enabling it
+	tlsCfg := cfg.Web.TLS
+	if tlsCfg.CertFile == "" || tlsCfg.KeyFile == "" {
+		tlsCfg = config.DefaultConfig().Web.TLS
+	}
+	tlsStore := tlscert.NewStore(tlsCfg.CertFile, tlsCfg.KeyFile)
+	handler.SetTLSStore(tlsStore, cfg.Web.TLS.Enabled)
+	if cfg.Web.TLS.Enabled {
+		if err := handler.EnsureTLSCertificate(); err != nil {
+			log.Fatalf("Failed to load TLS certificate: %v", err)
+		}
+	} else {
+		tlsStore.Load() // shown in the UI if present
+	}
+	handler.ApplyMetricsConfig()
+	handler.ApplyAuditConfig()
+	handler.ApplyCaptureConfig()
+	handler.ApplyRestreamConfig()
+	handler.ApplyAudioConfig()
+	handler.ApplyTalkbackConfig()
+	handler.ApplyReceiverConfig()
+	handler.ApplyTimecodeConfig()
+	handler.ApplyButtonsConfig()
+	handler.ApplyDisplayConfig()
+	handler.ApplyAlertsConfig()
+	handler.ApplyNotifyConfig()
+	handler.ApplyQuotaConfig()
+	handler.ApplySwitcherConfig()
+	handler.ApplyTallyConfig()
+	handler.ApplyRouting()
+	handler.ApplyLinkProbeConfig()
+	handler.ApplyVPNConfig()
+	handler.ApplyDiscoveryConfig()
+
+	// Relay further stream keys and the failover sources through the
+	// failover switch; ffmpeg reads the switch instead of listening when any
+	// are set up
+	handler.StartFailoverMonitor(context.Background())
+
+	// Send the stream on to each restream destination from its own process
+	handler.StartRestreamMonitor(context.Background())
+
+	// Reload the config file when something else, such as a provisioning
+	// tool, edits it
+	if *watchConfig {
+		go func() {
+			if err := cfgManager.Watch(context.Background(), config.WatchSettle); err != nil {
+				logger.Warn("Not watching the config file for edits: %v", err)
+			}
+		}()
 	}
-	ffmpegHandler.SetLogCallback(logCallback)
-	srtlaHandler.SetLogCallback(logCallback)
 
-	handler := api.NewHandler(cfgManager, ffmpegHandler, srtlaHandler, modemManager, usbnetSvc, statsCollector, logBuffer, wsHub, wifiManager)
-	handler.SetVersion(version.GetVersion())
+	// Remove guest and other time-limited API keys once their window ends
+	handler.StartGuestExpiry(context.Background())
 
 	// Auto-start FFmpeg in receive-only mode so cameras can connect immediately
 	if err := handler.StartReceiveMode(); err != nil {
 		logger.Warn("Failed to auto-start FFmpeg in receive mode: %v", err)
 	}
 
+	// Watch the active unit when running as the passive side of a pair
+	handler.StartPairing(context.Background())
+
+	// Switch to the low-power profile when running on battery
+	handler.StartPowerMonitor(context.Background())
+
+	// Pull the program return for operators when configured
+	handler.StartReturnFeedMonitor(context.Background())
+
+	// Tidy up and run OS updates in the maintenance window
+	handler.StartMaintenanceScheduler(context.Background())
+
 	// Watch for DJI device state changes
 	// go func() {
 	// 	log.Println("[DJI] State watcher started, monitoring for streaming state")
-	// 	for deviceState := range handler.GetDJIController().SubscribeUpdates() {
-	// 		log.Printf("[DJI] State update received: state=%s, hasConfig=%v\n",
-	// 			deviceState.ConnectionState, deviceState.StreamConfig != nil)
+	// 	events.Listen(context.Background(), bus, dji.TopicDeviceState, 10, func(deviceState dji.StateChange) {
+	// 		log.Printf("[DJI] State update received: state=%s\n", deviceState.State)
 
-	// 		if deviceState.ConnectionState == dji.StateStreaming {
+	// 		if deviceState.State == dji.StateStreaming {
 	// 			log.Printf("[DJI] Device entered streaming state\n")
 
 	// 			// FFmpeg should already be running in receive mode.
@@ -121,35 +225,60 @@ func main() {
 	// 				log.Printf("[DJI] FFmpeg already running in %s mode\n", handler.GetPipelineMode())
 	// 			}
 	// 		}
-	// 	}
+	// 	})
 	// 	log.Println("[DJI] State watcher stopped")
 	// }()
 
+	// Push WiFi connection changes as they happen when the backend supports it;
+	// the 10s ticker below still covers the nmcli fallback.
 	go func() {
-		ticker := time.NewTicker(time.Second)
-		modemTicker := time.NewTicker(5 * time.Second)
-		wifiTicker := time.NewTicker(10 * time.Second)
+		if err := wifiManager.PublishChanges(context.Background(), bus); err != nil {
+			log.Printf("[WIFI] Not watching connection changes: %v", err)
+		}
+	}()
+
+	// Keep the hotspot channel decision current while nothing is streaming
+	go wifiManager.EvaluateChannels(context.Background(), wifi.ChannelRescanInterval, func() bool {
+		return handler.GetPipelineMode() != api.PipelineModeStreaming
+	})
+
+	go func() {
+		profile := handler.PowerProfile()
+		ticker := time.NewTicker(profile.StatsInterval)
+		modemTicker := time.NewTicker(profile.ModemInterval)
+		wifiTicker := time.NewTicker(profile.WiFiInterval)
 		defer ticker.Stop()
 		defer modemTicker.Stop()
 		defer wifiTicker.Stop()
 
 		for {
 			select {
+			case profile := <-handler.PowerProfileChanges():
+				ticker.Reset(profile.StatsInterval)
+				modemTicker.Reset(profile.ModemInterval)
+				wifiTicker.Reset(profile.WiFiInterval)
+
 			case <-ticker.C:
+				handler.RecordLoopTick()
+
 				ffStats := ffmpegHandler.Stats()
 				srtlaStats := srtlaHandler.Stats()
 				ffStale := ffmpegHandler.IsStale(api.FFmpegStaleThreshold)
 				srtlaStale := srtlaHandler.IsStale(api.SRTLAStaleThreshold)
 
-				statsCollector.Record(ffStats.Bitrate, srtlaStats.TotalBitrate, ffStats.FPS)
+				statsCollector.Collect()
+				loudness := handler.CheckLoudness()
+				ingest := handler.CheckIngest()
+				handler.CheckAlerts()
 
-				wsHub.Broadcast("stats", map[string]interface{}{
+				events.Publish(bus, api.TopicPipelineStats, map[string]interface{}{
 					"pipeline_mode": handler.GetPipelineMode(),
 					"ffmpeg": map[string]interface{}{
-						"state":   ffStats.State,
-						"bitrate": ffStats.Bitrate,
-						"fps":     ffStats.FPS,
-						"stale":   ffStale,
+						"state":     ffStats.State,
+						"bitrate":   ffStats.Bitrate,
+						"fps":       ffStats.FPS,
+						"stale":     ffStale,
+						"ancillary": ffStats.Ancillary,
 					},
 					"srtla": map[string]interface{}{
 						"state":       srtlaStats.State,
@@ -157,28 +286,59 @@ func main() {
 						"connections": srtlaStats.Connections,
 						"stale":       srtlaStale,
 					},
+					"loudness":    loudness,
+					"audio":       handler.AudioStatus(),
+					"ingest":      ingest,
+					"receiver":    handler.ReceiverStats(),
+					"srt":         handler.SRTStats(),
+					"session":     handler.SessionStats(),
+					"link_health": handler.UpdateLinkHealth(),
 				})
 
 			case <-modemTicker.C:
-				modemStatus := handler.GetModemStatus()
-				wsHub.Broadcast("modems", modemStatus)
-
-				usbStatus := handler.GetUSBNetStatus()
-				wsHub.Broadcast("usbnet", usbStatus)
+				handler.PublishModemStatus()
 
 			case <-wifiTicker.C:
-				wsHub.Broadcast("wifi", map[string]interface{}{
-					"type": "wifi",
-				})
+				wifiManager.PublishStatus(bus)
 			}
 		}
 	}()
 
-	mux := http.NewServeMux()
+	// Every route under /api/ is also served under /api/v1/, which takes only
+	// the methods it handles, and is described by /api/v1/openapi.json
+	mux := api.NewRouter()
+	handler.SetRouter(mux)
+	mux.HandleFunc("GET /api/openapi.json", handler.HandleOpenAPI)
+
+	// Lightweight probes for load balancers and uptime monitors
+	mux.HandleFunc("/healthz", handler.HandleHealthz)
+	mux.HandleFunc("/readyz", handler.HandleReadyz)
+
+	// Stats sinks: Prometheus scrape endpoint and stored history
+	mux.HandleFunc("GET /metrics", handler.HandleMetrics)
+	mux.HandleFunc("GET /api/metrics", handler.HandleMetricsStatus)
+	mux.HandleFunc("PUT /api/metrics", handler.HandleMetricsUpdate)
+	mux.HandleFunc("GET /api/metrics/stored", handler.HandleMetricsStored)
+
+	// Event bus counters and the audit log of recorded events
+	mux.HandleFunc("GET /api/events/stats", handler.HandleEventStats)
+	mux.HandleFunc("GET /api/audit", handler.HandleAuditLog)
 
 	mux.HandleFunc("/api/status", handler.HandleStatus)
 	mux.HandleFunc("/api/stream/start", handler.HandleStreamStart)
 	mux.HandleFunc("/api/stream/stop", handler.HandleStreamStop)
+	mux.HandleFunc("GET /api/stream/preconditions", handler.HandleStartPolicy)
+	mux.HandleFunc("POST /api/stream/test", handler.HandleTestPatternStart)
+	mux.HandleFunc("DELETE /api/stream/test", handler.HandleTestPatternStop)
+	mux.HandleFunc("GET /api/publishers", handler.HandlePublishers)
+	mux.HandleFunc("GET /api/ingest", handler.HandleIngest)
+	mux.HandleFunc("GET /api/abr", handler.HandleABRStatus)
+
+	// SRTLA receiver (srtla_rec) so this unit can be the bonding endpoint
+	mux.HandleFunc("GET /api/receiver", handler.HandleReceiverStatus)
+	mux.HandleFunc("PUT /api/receiver", handler.HandleReceiverUpdate)
+	mux.HandleFunc("POST /api/receiver/start", handler.HandleReceiverStart)
+	mux.HandleFunc("POST /api/receiver/stop", handler.HandleReceiverStop)
 	mux.HandleFunc("/api/config", func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodGet:
@@ -189,23 +349,86 @@ func main() {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	})
+	// Config export and import, with a copy kept of each config replaced
+	mux.HandleFunc("GET /api/config/export", handler.HandleConfigExport)
+	mux.HandleFunc("POST /api/config/import", handler.HandleConfigImport)
+	mux.HandleFunc("GET /api/config/history", handler.HandleConfigHistory)
+	mux.HandleFunc("GET /api/config/history/{id}", handler.HandleConfigHistoryGet)
+	mux.HandleFunc("POST /api/config/history/{id}/rollback", handler.HandleConfigRollback)
+	// Config templates pushed by a controller: stage on every unit, then apply
+	mux.HandleFunc("GET /api/config/template", handler.HandleTemplateStatus)
+	mux.HandleFunc("POST /api/config/template/stage", handler.HandleTemplateStage)
+	mux.HandleFunc("DELETE /api/config/template/stage", handler.HandleTemplateDiscard)
+	mux.HandleFunc("POST /api/config/template/apply", handler.HandleTemplateApply)
+	mux.HandleFunc("POST /api/config/template/rollback", handler.HandleTemplateRollback)
 	mux.HandleFunc("/api/srtla/ips", handler.HandleSRTLAIPs)
 	mux.HandleFunc("/api/srtla/ips/file", handler.HandleIPsFile)
 	mux.HandleFunc("/api/srtla/ips/file/load", handler.HandleIPsFileLoad)
 	mux.HandleFunc("/api/srtla/ips/file/save", handler.HandleIPsFileSave)
+	mux.HandleFunc("GET /api/links/swap", handler.HandleLinkSwapStatus)
+	mux.HandleFunc("GET /api/links/probe", handler.HandleLinkProbe)
+	mux.HandleFunc("GET /api/vpn", handler.HandleVPN)
+	mux.HandleFunc("GET /api/relay", handler.HandleRelay)
+	mux.HandleFunc("POST /api/relay/provision", handler.HandleRelayProvision)
+	mux.HandleFunc("POST /api/vpn/start", handler.HandleVPNStart)
+	mux.HandleFunc("POST /api/vpn/stop", handler.HandleVPNStop)
+	mux.HandleFunc("POST /api/links/{ip}/swap", handler.HandleLinkSwapStart)
+	mux.HandleFunc("DELETE /api/links/swap", handler.HandleLinkSwapCancel)
+	mux.HandleFunc("GET /api/srtla/bind-sets", handler.HandleBindSetList)
+	mux.HandleFunc("PUT /api/srtla/bind-sets/{name}", handler.HandleBindSetSave)
+	mux.HandleFunc("DELETE /api/srtla/bind-sets/{name}", handler.HandleBindSetDelete)
+	mux.HandleFunc("GET /api/profiles", handler.HandleProfileList)
+	mux.HandleFunc("GET /api/profiles/{name}", handler.HandleProfileGet)
+	mux.HandleFunc("PUT /api/profiles/{name}", handler.HandleProfileSave)
+	mux.HandleFunc("DELETE /api/profiles/{name}", handler.HandleProfileDelete)
+	mux.HandleFunc("GET /api/restream", handler.HandleRestreamList)
+	mux.HandleFunc("GET /api/restream/platforms", handler.HandleRestreamPlatforms)
+	mux.HandleFunc("POST /api/restream/url", handler.HandleRestreamURL)
+	mux.HandleFunc("PUT /api/restream/{name}", handler.HandleRestreamSave)
+	mux.HandleFunc("DELETE /api/restream/{name}", handler.HandleRestreamDelete)
+	mux.HandleFunc("GET /api/outputs", handler.HandleOutputs)
+	mux.HandleFunc("PUT /api/outputs/{name}/enabled", handler.HandleOutputEnabled)
+	mux.HandleFunc("GET /api/srtla/transport", handler.HandleSRTLATransport)
 	mux.HandleFunc("/api/system/dependencies", handler.HandleDependencies)
 	mux.HandleFunc("/api/system/install-deb", handler.HandleInstallDeb)
+	mux.HandleFunc("POST /api/system/uploads", handler.HandleUploadCreate)
+	mux.HandleFunc("GET /api/system/uploads/{id}", handler.HandleUploadStatus)
+	mux.HandleFunc("PATCH /api/system/uploads/{id}", handler.HandleUploadChunk)
+	mux.HandleFunc("DELETE /api/system/uploads/{id}", handler.HandleUploadDelete)
+	mux.HandleFunc("POST /api/system/uploads/{id}/install", handler.HandleUploadInstall)
 	mux.HandleFunc("/api/system/interfaces", handler.HandleInterfaces)
+	mux.HandleFunc("GET /api/system/interfaces/history", handler.HandleInterfaceHistory)
+	mux.HandleFunc("GET /api/network/speedtest", handler.HandleSpeedTestGet)
+	mux.HandleFunc("POST /api/network/speedtest", handler.HandleSpeedTestStart)
+	mux.HandleFunc("POST /api/network/stun", handler.HandleSTUNDiscover)
+	mux.HandleFunc("GET /api/routing", handler.HandleRouting)
+	mux.HandleFunc("GET /api/system/services", handler.HandleServiceList)
+	mux.HandleFunc("POST /api/system/services/{name}/restart", handler.HandleServiceRestart)
+	mux.HandleFunc("GET /api/maintenance", handler.HandleMaintenanceStatus)
+	mux.HandleFunc("POST /api/maintenance/os-update", handler.HandleOSUpdate)
+	mux.HandleFunc("POST /api/maintenance/housekeeping", handler.HandleHousekeeping)
+	mux.HandleFunc("GET /api/system/tls", handler.HandleTLSStatus)
+	mux.HandleFunc("PUT /api/system/tls", handler.HandleTLSUpload)
+	mux.HandleFunc("POST /api/system/tls/self-signed", handler.HandleTLSSelfSigned)
+	mux.HandleFunc("GET /api/quotas", handler.HandleQuotas)
+	mux.HandleFunc("POST /api/quotas/{imei}/reset", handler.HandleQuotaReset)
 	mux.HandleFunc("/api/modems", handler.HandleModems)
 	mux.HandleFunc("/api/modems/", handler.HandleModems)
 	mux.HandleFunc("/api/usbnet", handler.HandleUSBNet)
+	mux.HandleFunc("PUT /api/usbnet/{id}", handler.HandleUSBNetRename)
+	mux.HandleFunc("DELETE /api/usbnet/{id}", handler.HandleUSBNetForget)
+	mux.HandleFunc("GET /api/discovery", handler.HandleDiscovery)
+	mux.HandleFunc("POST /api/discovery/{class}/scan", handler.HandleDiscoveryScan)
 	mux.HandleFunc("/api/wifi/networks", handler.HandleWiFi)
 	mux.HandleFunc("/api/wifi/status", handler.HandleWiFi)
 	mux.HandleFunc("/api/wifi/connect", handler.HandleWiFi)
 	mux.HandleFunc("/api/wifi/disconnect", handler.HandleWiFi)
 	mux.HandleFunc("/api/wifi/hotspot", handler.HandleWiFi)
 	mux.HandleFunc("/api/wifi/hotspot/stop", handler.HandleWiFi)
+	mux.HandleFunc("/api/wifi/hotspot/channel", handler.HandleWiFi)
+	mux.HandleFunc("/api/wifi/hotspot/clients", handler.HandleWiFi)
 	mux.HandleFunc("/api/wifi/forget", handler.HandleWiFi)
+	mux.HandleFunc("/api/wifi/ca-cert", handler.HandleWiFi)
 	mux.HandleFunc("/api/logs", handler.HandleLogs)
 	mux.HandleFunc("/api/logs/download", handler.HandleLogsDownload)
 	mux.HandleFunc("/api/debug", handler.HandleDebugMode)
@@ -223,8 +446,94 @@ func main() {
 	mux.HandleFunc("/api/updates/srtla/install", handler.HandleInstallSRTLASend)
 
 	// HLS preview static files
-	mux.Handle("/preview/", http.StripPrefix("/preview/", http.FileServer(http.Dir(handler.PreviewDir()))))
-	mux.Handle("/preview-temp/", http.StripPrefix("/preview-temp/", http.FileServer(http.Dir("/tmp/srtla-preview-temp"))))
+	mux.Handle("/preview/", api.TokenPlaylists(http.StripPrefix("/preview/", http.FileServer(http.Dir(handler.PreviewDir())))))
+	mux.Handle("/return/", api.TokenPlaylists(http.StripPrefix("/return/", http.FileServer(http.Dir(handler.ReturnFeedDir())))))
+	mux.Handle("/preview-temp/", api.TokenPlaylists(http.StripPrefix("/preview-temp/", http.FileServer(http.Dir("/tmp/srtla-preview-temp")))))
+	mux.HandleFunc("POST /api/preview/token", handler.HandlePreviewToken)
+	mux.HandleFunc("DELETE /api/preview/token", handler.HandlePreviewTokenRevoke)
+
+	// Active/passive unit pairing
+	mux.HandleFunc("GET /api/pairing", handler.HandlePairingStatus)
+	mux.HandleFunc("GET /api/pairing/state", handler.HandlePairingState)
+
+	// SCTE-35 ad markers
+	mux.HandleFunc("GET /api/markers", handler.HandleMarkerList)
+	mux.HandleFunc("POST /api/markers", handler.HandleMarkerCreate)
+
+	// Power source and performance profile
+	mux.HandleFunc("GET /api/power", handler.HandlePowerStatus)
+	mux.HandleFunc("PUT /api/power/profile", handler.HandlePowerProfile)
+
+	// Passthrough capture of the TS sent to srtla
+	mux.HandleFunc("GET /api/capture", handler.HandleCaptureStatus)
+	mux.HandleFunc("GET /api/capture/download", handler.HandleCaptureDownload)
+	mux.HandleFunc("DELETE /api/capture", handler.HandleCaptureClear)
+
+	// Talkback audio to the camera operators
+	mux.HandleFunc("GET /api/talkback", handler.HandleTalkbackStatus)
+	mux.HandleFunc("PUT /api/talkback", handler.HandleTalkbackUpdate)
+	mux.HandleFunc("GET /api/talkback/devices", handler.HandleTalkbackDevices)
+
+	// Program return feed
+	mux.HandleFunc("GET /api/rtsp", handler.HandleRTSPStatus)
+	mux.HandleFunc("PUT /api/rtsp", handler.HandleRTSPUpdate)
+	mux.HandleFunc("POST /api/rtsp/start", handler.HandleRTSPStart)
+	mux.HandleFunc("POST /api/rtsp/stop", handler.HandleRTSPStop)
+	mux.HandleFunc("GET /api/return", handler.HandleReturnFeedStatus)
+	mux.HandleFunc("GET /api/ingests", handler.HandleFailoverStatus)
+	mux.HandleFunc("PUT /api/return", handler.HandleReturnFeedUpdate)
+
+	// Status display
+	mux.HandleFunc("GET /api/display", handler.HandleDisplayStatus)
+	mux.HandleFunc("PUT /api/display", handler.HandleDisplayUpdate)
+
+	// Audible alerts on the local audio output
+	mux.HandleFunc("GET /api/alerts", handler.HandleAlertsStatus)
+	mux.HandleFunc("PUT /api/alerts", handler.HandleAlertsUpdate)
+	mux.HandleFunc("POST /api/alerts/test", handler.HandleAlertsTest)
+	mux.HandleFunc("GET /api/notifications", handler.HandleNotifyStatus)
+	mux.HandleFunc("PUT /api/notifications", handler.HandleNotifyUpdate)
+	mux.HandleFunc("POST /api/notifications/test", handler.HandleNotifyTest)
+
+	// Hardware buttons and status LED
+	mux.HandleFunc("GET /api/buttons", handler.HandleButtonsStatus)
+	mux.HandleFunc("PUT /api/buttons", handler.HandleButtonsUpdate)
+
+	// SMPTE timecode for lining up feeds in post
+	mux.HandleFunc("GET /api/timecode", handler.HandleTimecodeStatus)
+	mux.HandleFunc("PUT /api/timecode", handler.HandleTimecodeUpdate)
+
+	// Vision switcher program/preview status
+	mux.HandleFunc("GET /api/switcher", handler.HandleSwitcherStatus)
+	mux.HandleFunc("PUT /api/switcher", handler.HandleSwitcherUpdate)
+
+	// Tally light
+	mux.HandleFunc("GET /api/tally", handler.HandleTallyStatus)
+	mux.HandleFunc("PUT /api/tally", handler.HandleTallyUpdate)
+	mux.HandleFunc("PUT /api/tally/override", handler.HandleTallyOverride)
+	mux.HandleFunc("DELETE /api/tally/override", handler.HandleTallyOverride)
+
+	// Scoped API keys for automation clients
+	mux.HandleFunc("GET /api/access/keys", handler.HandleAPIKeyList)
+	mux.HandleFunc("POST /api/access/keys", handler.HandleAPIKeyCreate)
+	mux.HandleFunc("DELETE /api/access/keys/{id}", handler.HandleAPIKeyRevoke)
+	mux.HandleFunc("GET /api/access/guests", handler.HandleGuestList)
+	mux.HandleFunc("POST /api/access/guests", handler.HandleGuestCreate)
+
+	// Background jobs
+	mux.HandleFunc("GET /api/jobs", handler.HandleJobList)
+	mux.HandleFunc("GET /api/jobs/{id}", handler.HandleJobGet)
+	mux.HandleFunc("DELETE /api/jobs/{id}", handler.HandleJobCancel)
+
+	// Preview share links
+	mux.HandleFunc("GET /api/share", handler.HandleShareList)
+	mux.HandleFunc("POST /api/share", handler.HandleShareCreate)
+	mux.HandleFunc("DELETE /api/share/{token}", handler.HandleShareRevoke)
+	mux.HandleFunc("GET /share/{token}/{path...}", handler.HandleShareView)
+
+	// Health overlay for an OBS browser source at the studio
+	mux.HandleFunc("GET /overlay/{$}", handler.HandleOverlayPage)
+	mux.HandleFunc("GET /overlay/status.json", handler.HandleOverlayStatus)
 
 	// DJI Camera endpoints
 	mux.HandleFunc("/api/cameras", handler.HandleCameraList)
@@ -249,12 +558,26 @@ func main() {
 			handler.HandleCameraForget(w, r)
 		case strings.HasSuffix(path, "/refresh"):
 			handler.HandleCameraRefresh(w, r)
+		case strings.HasSuffix(path, "/reservation"):
+			handler.HandleCameraReservation(w, r)
 		default:
 			http.NotFound(w, r)
 		}
 	})
 
+	// Camera groups and bulk operations
+	mux.HandleFunc("GET /api/camera-groups", handler.HandleCameraGroupList)
+	mux.HandleFunc("PUT /api/camera-groups/{id}", handler.HandleCameraGroupSave)
+	mux.HandleFunc("DELETE /api/camera-groups/{id}", handler.HandleCameraGroupDelete)
+	mux.HandleFunc("POST /api/camera-groups/{id}/{action}", handler.HandleCameraGroupAction)
+
+	// Cameras of every kind behind one interface
+	mux.HandleFunc("GET /api/v2/cameras", handler.HandleCameraV2List)
+	mux.HandleFunc("GET /api/v2/cameras/{kind}/{id}", handler.HandleCameraV2Get)
+	mux.HandleFunc("POST /api/v2/cameras/{kind}/{id}/{action}", handler.HandleCameraV2Action)
+
 	// USB Camera endpoints
+	mux.HandleFunc("GET /api/capabilities", handler.HandleCapabilities)
 	mux.HandleFunc("GET /api/usbcams", handler.HandleUSBCameraList)
 	mux.HandleFunc("POST /api/usbcams/scan", handler.HandleUSBCameraScan)
 	mux.HandleFunc("GET /api/usbcams/{id}", handler.HandleUSBCameraGet)
@@ -265,28 +588,49 @@ func main() {
 	mux.HandleFunc("POST /api/usbcams/{id}/preview/stop", handler.HandleUSBCameraPreviewStop)
 
 	mux.HandleFunc("/ws", handler.HandleWebSocket)
+	mux.HandleFunc("GET /api/ws/stats", handler.HandleWSStats)
 
 	webContent, err := fs.Sub(web.FS, "assets")
 	if err != nil {
 		log.Fatalf("Failed to get web subdirectory: %v", err)
 	}
-	mux.Handle("/", http.FileServer(http.FS(webContent)))
+	mux.HandleFunc("GET /api/branding", handler.HandleBranding)
+	mux.Handle("/", http.FileServer(http.FS(web.Overlay(webContent, handler.BrandingDir))))
+
+	root := api.RequestMiddleware(mux.Versioned(handler.AccessMiddleware(mux)))
+	// commands sent over the WebSocket pass the same access checks
+	wsHub.SetRequestHandler(root)
 
 	server := &http.Server{
 		Addr:         fmt.Sprintf(":%d", cfg.Web.Port),
-		Handler:      mux,
+		Handler:      root,
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
 
+	scheme := "http"
+	if cfg.Web.TLS.Enabled {
+		scheme = "https"
+		server.TLSConfig = &tls.Config{
+			GetCertificate: tlsStore.GetCertificate,
+			MinVersion:     tls.VersionTLS12,
+		}
+	}
+
 	go func() {
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if server.TLSConfig != nil {
+			err = server.ListenAndServeTLS("", "")
+		} else {
+			err = server.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			logger.Fatal("Server failed: %v", err)
 		}
 	}()
 
-	logger.Printf("Server started at http://localhost:%d", cfg.Web.Port)
+	logger.Printf("Server started at %s://localhost:%d", scheme, cfg.Web.Port)
 
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -299,7 +643,26 @@ func main() {
 	}
 
 	srtlaHandler.Stop()
+	handler.StopSRTProbe()
 	ffmpegHandler.Stop()
+	handler.StopTalkback()
+	handler.StopReturnFeed()
+	handler.StopFailover()
+	handler.StopRestreamOutputs()
+	handler.StopDiscovery()
+	handler.StopReceiver()
+	handler.StopTally()
+	handler.StopSwitcher()
+	handler.StopTimecode()
+	handler.StopButtons()
+	handler.StopDisplay()
+	handler.StopAlerts()
+	handler.StopNotify()
+	handler.StopQuota()
+	handler.StopMetrics()
+	handler.StopAudit()
+	handler.StopLinkProbe()
+	handler.StopRouting()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -307,5 +670,9 @@ func main() {
 		logger.Error("Server shutdown error: %v", err)
 	}
 
+	if err := tracing.Shutdown(ctx); err != nil {
+		logger.Error("Tracing shutdown error: %v", err)
+	}
+
 	logger.Println("Server stopped")
 }
@@ -0,0 +1,111 @@
+// Package abr decides the video bitrate the uplinks can carry. It lowers the
+// target quickly when the bonded links fall behind or lose packets and
+// raises it in small steps once they have been healthy for a while.
+package abr
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// DecreaseInterval is the least time between two decreases, so the
+	// encoder's last change shows up in the samples before the next
+	DecreaseInterval = 3 * time.Second
+	// IncreaseAfter is how long the links must stay healthy for each step up
+	IncreaseAfter = 15 * time.Second
+	// decreaseFactor is applied to the target on congestion
+	decreaseFactor = 0.75
+	// lagFactor is how far throughput may fall behind the input before the
+	// links count as congested
+	lagFactor = 0.8
+)
+
+// Config bounds the controller
+type Config struct {
+	MinKbps        int
+	MaxKbps        int
+	StepKbps       int     // increase per step
+	MaxLossPercent float64 // SRT loss above which the target is lowered, 0 ignores loss
+	MaxBufferMs    float64 // SRT send buffer above which the target is lowered, 0 ignores it
+}
+
+// Sample is one measurement of the pipeline
+type Sample struct {
+	InputKbps      float64 // what the encoder sends
+	ThroughputKbps float64 // what the bonded links carry, 0 when unknown
+	LossPercent    float64 // SRT loss, when measured
+	SendBufferMs   float64 // SRT send buffer, when measured
+}
+
+// Controller tracks the target bitrate. It is not safe for concurrent use.
+type Controller struct {
+	cfg          Config
+	target       int
+	lastDecrease time.Time
+	healthySince time.Time
+}
+
+// New starts at the maximum bitrate
+func New(cfg Config) *Controller {
+	return &Controller{cfg: cfg, target: cfg.MaxKbps}
+}
+
+// Target is the current target bitrate in kbps
+func (c *Controller) Target() int {
+	return c.target
+}
+
+// Update folds in a sample and returns the new target and why it changed,
+// or "" when it didn't
+func (c *Controller) Update(s Sample, now time.Time) (int, string) {
+	if reason := c.congestion(s); reason != "" {
+		c.healthySince = time.Time{}
+		if now.Sub(c.lastDecrease) < DecreaseInterval || c.target <= c.cfg.MinKbps {
+			return c.target, ""
+		}
+
+		next := int(float64(c.target) * decreaseFactor)
+		// go straight to what the links carry when that is lower still
+		if s.ThroughputKbps > 0 && int(s.ThroughputKbps*0.9) < next {
+			next = int(s.ThroughputKbps * 0.9)
+		}
+		c.target = c.clamp(next)
+		c.lastDecrease = now
+		return c.target, reason
+	}
+
+	if c.healthySince.IsZero() {
+		c.healthySince = now
+		return c.target, ""
+	}
+	if c.target >= c.cfg.MaxKbps || now.Sub(c.healthySince) < IncreaseAfter {
+		return c.target, ""
+	}
+	c.target = c.clamp(c.target + c.cfg.StepKbps)
+	c.healthySince = now
+	return c.target, fmt.Sprintf("links healthy for %s", IncreaseAfter)
+}
+
+// congestion returns why the links look congested, or ""
+func (c *Controller) congestion(s Sample) string {
+	switch {
+	case c.cfg.MaxLossPercent > 0 && s.LossPercent > c.cfg.MaxLossPercent:
+		return fmt.Sprintf("%.1f%% packet loss", s.LossPercent)
+	case c.cfg.MaxBufferMs > 0 && s.SendBufferMs > c.cfg.MaxBufferMs:
+		return fmt.Sprintf("%.0f ms in the send buffer", s.SendBufferMs)
+	case s.InputKbps > 0 && s.ThroughputKbps > 0 && s.ThroughputKbps < s.InputKbps*lagFactor:
+		return fmt.Sprintf("links carry %.0f of %.0f kbps", s.ThroughputKbps, s.InputKbps)
+	}
+	return ""
+}
+
+func (c *Controller) clamp(kbps int) int {
+	if kbps < c.cfg.MinKbps {
+		return c.cfg.MinKbps
+	}
+	if kbps > c.cfg.MaxKbps {
+		return c.cfg.MaxKbps
+	}
+	return kbps
+}
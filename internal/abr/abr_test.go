@@ -0,0 +1,74 @@
+package abr
+
+import (
+	"testing"
+	"time"
+)
+
+var testConfig = Config{MinKbps: 1000, MaxKbps: 6000, StepKbps: 500, MaxLossPercent: 2, MaxBufferMs: 1000}
+
+var healthy = Sample{InputKbps: 6000, ThroughputKbps: 6000}
+
+// Lagging links lower the target to what they carry, no faster than the
+// decrease interval
+func TestDecreaseOnLag(t *testing.T) {
+	c := New(testConfig)
+	now := time.Now()
+
+	target, reason := c.Update(Sample{InputKbps: 6000, ThroughputKbps: 3000}, now)
+	if target != 2700 || reason == "" {
+		t.Fatalf("Expected 2700 kbps with a reason, got %d (%q)", target, reason)
+	}
+
+	target, reason = c.Update(Sample{InputKbps: 2700, ThroughputKbps: 1000}, now.Add(time.Second))
+	if target != 2700 || reason != "" {
+		t.Errorf("Expected no change within the decrease interval, got %d (%q)", target, reason)
+	}
+
+	target, _ = c.Update(Sample{InputKbps: 2700, ThroughputKbps: 500}, now.Add(DecreaseInterval))
+	if target != testConfig.MinKbps {
+		t.Errorf("Expected the minimum, got %d", target)
+	}
+}
+
+// Loss and a full send buffer count as congestion even when the links keep up
+func TestDecreaseOnLossAndBuffer(t *testing.T) {
+	now := time.Now()
+
+	c := New(testConfig)
+	if target, _ := c.Update(Sample{InputKbps: 6000, ThroughputKbps: 6000, LossPercent: 5}, now); target != 4500 {
+		t.Errorf("Expected 4500 kbps on loss, got %d", target)
+	}
+
+	c = New(testConfig)
+	if target, _ := c.Update(Sample{InputKbps: 6000, ThroughputKbps: 6000, SendBufferMs: 1500}, now); target != 4500 {
+		t.Errorf("Expected 4500 kbps on a full buffer, got %d", target)
+	}
+}
+
+// Healthy links raise the target one step per interval, up to the maximum
+func TestIncrease(t *testing.T) {
+	c := New(testConfig)
+	now := time.Now()
+
+	c.Update(Sample{InputKbps: 6000, ThroughputKbps: 4000, LossPercent: 3}, now)
+	if c.Target() != 3600 {
+		t.Fatalf("Expected 3600 kbps, got %d", c.Target())
+	}
+
+	c.Update(healthy, now.Add(time.Second))
+	if target, _ := c.Update(healthy, now.Add(5*time.Second)); target != 3600 {
+		t.Errorf("Expected no increase before %s, got %d", IncreaseAfter, target)
+	}
+	target, reason := c.Update(healthy, now.Add(time.Second+IncreaseAfter))
+	if target != 4100 || reason == "" {
+		t.Errorf("Expected a step to 4100 kbps, got %d (%q)", target, reason)
+	}
+
+	for i := 2; i < 10; i++ {
+		c.Update(healthy, now.Add(time.Second+time.Duration(i)*IncreaseAfter))
+	}
+	if c.Target() != testConfig.MaxKbps {
+		t.Errorf("Expected the maximum, got %d", c.Target())
+	}
+}
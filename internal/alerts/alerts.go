@@ -0,0 +1,152 @@
+// Package alerts announces stream events on a local audio output, as beeps
+// or spoken prompts, for operators who can't watch a screen.
+package alerts
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Events that can be announced
+const (
+	EventStreamStarted  = "stream_started"
+	EventStreamStopped  = "stream_stopped"
+	EventLinkDown       = "link_down"
+	EventLinkUp         = "link_up"
+	EventInputLost      = "input_lost"
+	EventInputRestored  = "input_restored"
+	EventInputWeak      = "input_weak"
+	EventInputRecovered = "input_recovered"
+	EventModemHot       = "modem_hot"
+	EventModemCooled    = "modem_cooled"
+)
+
+// Events lists the values accepted in alerts.events
+var Events = []string{
+	EventStreamStarted, EventStreamStopped,
+	EventLinkDown, EventLinkUp,
+	EventInputLost, EventInputRestored,
+	EventInputWeak, EventInputRecovered,
+	EventModemHot, EventModemCooled,
+}
+
+// Alert is one announcement
+type Alert struct {
+	Event string `json:"event"`
+	Link  int    `json:"link,omitempty"` // 1-based bind IP position for link and modem events
+}
+
+var numberWords = []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine", "ten"}
+
+func linkWord(link int) string {
+	if link >= 0 && link < len(numberWords) {
+		return numberWords[link]
+	}
+	return fmt.Sprint(link)
+}
+
+// Text is the spoken prompt, e.g. "link two down"
+func (a Alert) Text() string {
+	switch a.Event {
+	case EventLinkDown, EventLinkUp:
+		state := "down"
+		if a.Event == EventLinkUp {
+			state = "up"
+		}
+		return "link " + linkWord(a.Link) + " " + state
+	case EventModemHot, EventModemCooled:
+		state := "hot"
+		if a.Event == EventModemCooled {
+			state = "cooled"
+		}
+		// a modem not used for a link has no number
+		if a.Link == 0 {
+			return "modem " + state
+		}
+		return "link " + linkWord(a.Link) + " " + state
+	case EventInputRestored:
+		return "camera back"
+	case EventInputLost:
+		return "camera lost"
+	case EventInputWeak:
+		return "camera signal weak"
+	case EventInputRecovered:
+		return "camera signal good"
+	default:
+		return strings.ReplaceAll(a.Event, "_", " ")
+	}
+}
+
+// Tone is a beep, or a pause when Hz is zero
+type Tone struct {
+	Hz       int
+	Duration time.Duration
+}
+
+const (
+	beep  = 120 * time.Millisecond
+	pip   = 60 * time.Millisecond
+	pause = 80 * time.Millisecond
+)
+
+// Tones is the beep pattern for the alert. Rising tones are good news and
+// falling ones bad; link events end with one pip per link number so they
+// can be told apart without voice prompts.
+func (a Alert) Tones() []Tone {
+	switch a.Event {
+	case EventStreamStarted:
+		return []Tone{{660, beep}, {0, pause}, {880, beep}, {0, pause}, {1320, beep * 2}}
+	case EventStreamStopped:
+		return []Tone{{1320, beep}, {0, pause}, {880, beep}, {0, pause}, {660, beep * 2}}
+	case EventLinkDown, EventLinkUp:
+		tones := []Tone{{880, beep}, {0, pause}, {440, beep}}
+		pipHz := 440
+		if a.Event == EventLinkUp {
+			tones = []Tone{{440, beep}, {0, pause}, {880, beep}}
+			pipHz = 880
+		}
+		tones = append(tones, Tone{0, pause * 3})
+		for i := 0; i < a.Link; i++ {
+			tones = append(tones, Tone{pipHz, pip}, Tone{0, pause})
+		}
+		return tones
+	case EventInputLost:
+		return []Tone{{330, beep * 3}, {0, pause}, {330, beep * 3}}
+	case EventInputRestored:
+		return []Tone{{660, beep}, {0, pause}, {660, beep}}
+	case EventInputWeak:
+		return []Tone{{440, beep}, {0, pause}, {330, beep}, {0, pause}, {330, beep}}
+	case EventInputRecovered:
+		return []Tone{{330, beep}, {0, pause}, {440, beep}, {0, pause}, {440, beep}}
+	case EventModemHot, EventModemCooled:
+		tones := []Tone{{1320, beep}, {0, pause}, {1320, beep}, {0, pause}, {660, beep}}
+		if a.Event == EventModemCooled {
+			tones = []Tone{{660, beep}, {0, pause}, {1320, beep}}
+		}
+		tones = append(tones, Tone{0, pause * 3})
+		for i := 0; i < a.Link; i++ {
+			tones = append(tones, Tone{660, pip}, Tone{0, pause})
+		}
+		return tones
+	default:
+		return []Tone{{880, beep}}
+	}
+}
+
+// ToneFilter builds an ffmpeg lavfi graph that renders tones as one mono
+// 48kHz stream
+func ToneFilter(tones []Tone) string {
+	var parts, labels []string
+	for i, t := range tones {
+		expr := "0"
+		if t.Hz > 0 {
+			expr = fmt.Sprintf("0.5*sin(2*PI*%d*t)", t.Hz)
+		}
+		label := fmt.Sprintf("[t%d]", i)
+		parts = append(parts, fmt.Sprintf("aevalsrc=%s:s=48000:d=%.3f%s", expr, t.Duration.Seconds(), label))
+		labels = append(labels, label)
+	}
+	return strings.Join(parts, ";") + ";" + strings.Join(labels, "") +
+		fmt.Sprintf("concat=n=%d:v=0:a=1", len(tones))
+}
@@ -0,0 +1,58 @@
+package alerts
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestText(t *testing.T) {
+	cases := []struct {
+		alert Alert
+		want  string
+	}{
+		{Alert{Event: EventStreamStarted}, "stream started"},
+		{Alert{Event: EventLinkDown, Link: 2}, "link two down"},
+		{Alert{Event: EventLinkUp, Link: 12}, "link 12 up"},
+		{Alert{Event: EventInputLost}, "camera lost"},
+		{Alert{Event: EventInputWeak}, "camera signal weak"},
+		{Alert{Event: EventModemHot, Link: 3}, "link three hot"},
+		{Alert{Event: EventModemCooled}, "modem cooled"},
+	}
+	for _, c := range cases {
+		if got := c.alert.Text(); got != c.want {
+			t.Errorf("%+v: got %q, want %q", c.alert, got, c.want)
+		}
+	}
+}
+
+func TestLinkTonesCountLink(t *testing.T) {
+	pips := func(a Alert) int {
+		n := 0
+		for _, tone := range a.Tones() {
+			if tone.Duration == pip {
+				n++
+			}
+		}
+		return n
+	}
+	if n := pips(Alert{Event: EventLinkDown, Link: 3}); n != 3 {
+		t.Errorf("link three down: got %d pips, want 3", n)
+	}
+	if n := pips(Alert{Event: EventModemHot, Link: 2}); n != 2 {
+		t.Errorf("link two hot: got %d pips, want 2", n)
+	}
+	if n := pips(Alert{Event: EventStreamStarted}); n != 0 {
+		t.Errorf("stream started: got %d pips, want 0", n)
+	}
+}
+
+func TestToneFilter(t *testing.T) {
+	got := ToneFilter([]Tone{{880, beep}, {0, pause}})
+	want := "aevalsrc=0.5*sin(2*PI*880*t):s=48000:d=0.120[t0];aevalsrc=0:s=48000:d=0.080[t1];[t0][t1]concat=n=2:v=0:a=1"
+	if got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+	if !strings.HasSuffix(ToneFilter(Alert{Event: EventLinkUp, Link: 1}.Tones()), "concat=n=6:v=0:a=1") {
+		t.Error("link one up should concatenate six tones")
+	}
+}
@@ -0,0 +1,156 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// queueSize bounds how many alerts can wait to be played
+	queueSize = 8
+	// maxAge drops alerts that waited so long they no longer describe the
+	// current state
+	maxAge = 10 * time.Second
+)
+
+type queued struct {
+	alert Alert
+	at    time.Time
+}
+
+// Player plays alerts one at a time on an ALSA device with ffmpeg. Voice
+// prompts are synthesised with espeak-ng (or espeak) when installed.
+type Player struct {
+	device string
+	volume float64
+	voice  bool
+	queue  chan queued
+
+	mu      sync.Mutex
+	lastErr string
+}
+
+// NewPlayer returns a player for device. volume is a linear gain.
+func NewPlayer(device string, volume float64, voice bool) *Player {
+	return &Player{
+		device: device,
+		volume: volume,
+		voice:  voice,
+		queue:  make(chan queued, queueSize),
+	}
+}
+
+// Play queues an alert. It reports false when the queue is full.
+func (p *Player) Play(a Alert) bool {
+	select {
+	case p.queue <- queued{a, time.Now()}:
+		return true
+	default:
+		return false
+	}
+}
+
+// LastError is why the last alert failed to play, if it did
+func (p *Player) LastError() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.lastErr
+}
+
+// Run plays queued alerts until ctx is cancelled
+func (p *Player) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case q := <-p.queue:
+			if time.Since(q.at) > maxAge {
+				continue
+			}
+			err := p.play(ctx, q.alert)
+			p.mu.Lock()
+			p.lastErr = ""
+			if err != nil && ctx.Err() == nil {
+				p.lastErr = err.Error()
+			}
+			p.mu.Unlock()
+		}
+	}
+}
+
+func (p *Player) play(ctx context.Context, a Alert) error {
+	if p.voice {
+		if speaker := speechCommand(); speaker != "" {
+			return p.speak(ctx, speaker, a.Text())
+		}
+		if err := p.beep(ctx, a); err != nil {
+			return err
+		}
+		return fmt.Errorf("espeak-ng is not installed, beeping instead")
+	}
+	return p.beep(ctx, a)
+}
+
+func (p *Player) output() []string {
+	return []string{"-af", fmt.Sprintf("volume=%.2f", p.volume), "-f", "alsa", p.device}
+}
+
+func (p *Player) beep(ctx context.Context, a Alert) error {
+	args := append([]string{"-hide_banner", "-loglevel", "error", "-f", "lavfi", "-i", ToneFilter(a.Tones())}, p.output()...)
+	return run(exec.CommandContext(ctx, "ffmpeg", args...))
+}
+
+// speak pipes the synthesised prompt into ffmpeg so it plays on the same
+// device, at the same volume, as the beeps
+func (p *Player) speak(ctx context.Context, speaker, text string) error {
+	synth := exec.CommandContext(ctx, speaker, "--stdout", "-s", "160", text)
+	args := append([]string{"-hide_banner", "-loglevel", "error", "-i", "pipe:0"}, p.output()...)
+	play := exec.CommandContext(ctx, "ffmpeg", args...)
+
+	wav, err := synth.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	play.Stdin = wav
+	if err := synth.Start(); err != nil {
+		return fmt.Errorf("%s: %w", speaker, err)
+	}
+	err = run(play)
+	if werr := synth.Wait(); werr != nil && err == nil {
+		err = fmt.Errorf("%s: %w", speaker, werr)
+	}
+	return err
+}
+
+// run runs a command, folding its output into the error
+func run(cmd *exec.Cmd) error {
+	var out bytes.Buffer
+	cmd.Stderr = &out
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(out.String()); msg != "" {
+			return fmt.Errorf("%s: %s", cmd.Args[0], msg)
+		}
+		return fmt.Errorf("%s: %w", cmd.Args[0], err)
+	}
+	return nil
+}
+
+// SpeechAvailable reports whether voice prompts can be synthesised
+func SpeechAvailable() bool {
+	return speechCommand() != ""
+}
+
+// speechCommand finds a speech synthesiser
+func speechCommand() string {
+	for _, name := range []string{"espeak-ng", "espeak"} {
+		if _, err := exec.LookPath(name); err == nil {
+			return name
+		}
+	}
+	return ""
+}
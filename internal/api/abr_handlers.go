@@ -0,0 +1,206 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"srtla-manager/internal/abr"
+	"srtla-manager/internal/config"
+	"srtla-manager/internal/events"
+	"srtla-manager/internal/logger"
+)
+
+// abrInterval is how often the adaptive bitrate controller samples the links
+const abrInterval = time.Second
+
+// ABRStatus reports the adaptive bitrate controller
+type ABRStatus struct {
+	Enabled        bool       `json:"enabled"`
+	Active         bool       `json:"active"` // adapting a running stream
+	TargetKbps     int        `json:"target_kbps,omitempty"`
+	InputKbps      float64    `json:"input_kbps"`
+	ThroughputKbps float64    `json:"throughput_kbps"`
+	LastChange     *time.Time `json:"last_change,omitempty"`
+	LastReason     string     `json:"last_reason,omitempty"`
+}
+
+// BitrateChange is published, and posted to the webhook, when the target
+// bitrate changes
+type BitrateChange struct {
+	BitrateKbps  int       `json:"bitrate_kbps"`
+	PreviousKbps int       `json:"previous_kbps"`
+	Reason       string    `json:"reason"`
+	Time         time.Time `json:"time"`
+}
+
+var abrWebhookClient = &http.Client{Timeout: 5 * time.Second}
+
+// startBitrateControl adapts the bitrate for as long as the stream runs
+func (h *Handler) startBitrateControl() {
+	h.abrMu.Lock()
+	h.abrSession++
+	session := h.abrSession
+	h.abrStatus = ABRStatus{}
+	h.abrMu.Unlock()
+
+	go h.monitorBitrate(session)
+}
+
+func (h *Handler) monitorBitrate(session int) {
+	ticker := time.NewTicker(abrInterval)
+	defer ticker.Stop()
+
+	var ctrl *abr.Controller
+	var bounds config.ABRConfig
+	for range ticker.C {
+		h.abrMu.Lock()
+		current := h.abrSession == session
+		h.abrMu.Unlock()
+		if !current || h.GetPipelineMode() != PipelineModeStreaming {
+			h.abrMu.Lock()
+			if h.abrSession == session {
+				h.abrStatus.Active = false
+			}
+			h.abrMu.Unlock()
+			return
+		}
+
+		cfg := h.config.Get()
+		if !cfg.ABR.Enabled {
+			ctrl = nil
+			h.abrMu.Lock()
+			h.abrStatus = ABRStatus{}
+			h.abrMu.Unlock()
+			continue
+		}
+		// start over from the maximum when the bounds change
+		if ctrl == nil || !abrBoundsEqual(bounds, cfg.ABR) {
+			bounds = cfg.ABR
+			ctrl = abr.New(abr.Config{
+				MinKbps:        cfg.ABR.MinKbps,
+				MaxKbps:        cfg.ABR.MaxKbps,
+				StepKbps:       cfg.ABR.StepKbps,
+				MaxLossPercent: cfg.ABR.MaxLossPercent,
+				MaxBufferMs:    float64(cfg.ABR.MaxBufferMs),
+			})
+		}
+
+		sample := h.bitrateSample()
+		previous := ctrl.Target()
+		target, reason := ctrl.Update(sample, time.Now())
+
+		h.abrMu.Lock()
+		h.abrStatus.Active = true
+		h.abrStatus.TargetKbps = target
+		h.abrStatus.InputKbps = sample.InputKbps
+		h.abrStatus.ThroughputKbps = sample.ThroughputKbps
+		h.abrMu.Unlock()
+
+		if reason == "" || target == previous {
+			continue
+		}
+
+		change := BitrateChange{BitrateKbps: target, PreviousKbps: previous, Reason: reason, Time: time.Now()}
+		h.abrMu.Lock()
+		h.abrStatus.LastChange = &change.Time
+		h.abrStatus.LastReason = reason
+		h.abrMu.Unlock()
+
+		h.logOutput("manager", fmt.Sprintf("[ABR] Bitrate %d -> %d kbps: %s", previous, target, reason))
+		events.Publish(h.bus, TopicBitrate, change)
+		go h.applyBitrate(cfg, change)
+	}
+}
+
+func abrBoundsEqual(a, b config.ABRConfig) bool {
+	return a.MinKbps == b.MinKbps && a.MaxKbps == b.MaxKbps && a.StepKbps == b.StepKbps &&
+		a.MaxLossPercent == b.MaxLossPercent && a.MaxBufferMs == b.MaxBufferMs
+}
+
+// bitrateSample measures the pipeline. Throughput is left at zero while the
+// bonding process reports nothing, so silence isn't taken for congestion.
+func (h *Handler) bitrateSample() abr.Sample {
+	sample := abr.Sample{InputKbps: h.ffmpeg.Stats().Bitrate}
+	if !h.srtla.IsStale(SRTLAStaleThreshold) {
+		sample.ThroughputKbps = h.srtla.Stats().TotalBitrate * 1000
+	}
+	if srtStats := h.SRTStats(); srtStats != nil && srtStats.Connected {
+		sample.LossPercent = srtStats.LossPercent
+		sample.SendBufferMs = srtStats.SendBufferMs
+	}
+	return sample
+}
+
+// applyBitrate tells the encoder about a new bitrate: the webhook, and a USB
+// camera's ffmpeg when it encodes
+func (h *Handler) applyBitrate(cfg config.Config, change BitrateChange) {
+	if cfg.ABR.WebhookURL != "" {
+		if err := postBitrateWebhook(cfg.ABR.WebhookURL, change); err != nil {
+			h.logOutput("manager", fmt.Sprintf("[ABR] Webhook failed: %v", err))
+		}
+	}
+	if cfg.ABR.USBCamera {
+		if err := h.reencodeUSBCamera(cfg, change.BitrateKbps); err != nil {
+			h.logOutput("manager", fmt.Sprintf("[ABR] Failed to re-encode USB camera: %v", err))
+		}
+	}
+}
+
+func postBitrateWebhook(url string, change BitrateChange) error {
+	body, err := json.Marshal(change)
+	if err != nil {
+		return err
+	}
+	resp, err := abrWebhookClient.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	return nil
+}
+
+// reencodeUSBCamera restarts the streaming USB camera's encoder at kbps.
+// Cameras passed through untouched are left alone. The restart costs about
+// a second of video, so it is skipped while another operation holds the
+// pipeline.
+func (h *Handler) reencodeUSBCamera(cfg config.Config, kbps int) error {
+	cameraID := h.usbCamController.GetActiveCamera()
+	if cameraID == "" {
+		return nil
+	}
+	state := h.usbCamController.GetCameraState(cameraID)
+	if state == nil || state.StreamConfig == nil || state.StreamConfig.Encoder == "copy" {
+		return nil
+	}
+
+	release, err := h.acquirePipeline("abr_reencode")
+	if err != nil {
+		logger.Debug("ABR: skipping re-encode: %v", err)
+		return nil
+	}
+	defer release()
+
+	streamConfig := *state.StreamConfig
+	streamConfig.Bitrate = kbps
+	if err := h.usbCamController.StopStreaming(cameraID); err != nil {
+		return err
+	}
+	return h.usbCamController.StartStreaming(cameraID, &streamConfig, h.srtOutputPort(&cfg), h.streamPreviewDir())
+}
+
+// HandleABRStatus handles GET /api/abr
+func (h *Handler) HandleABRStatus(w http.ResponseWriter, r *http.Request) {
+	h.abrMu.Lock()
+	status := h.abrStatus
+	h.abrMu.Unlock()
+	status.Enabled = h.config.Get().ABR.Enabled
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
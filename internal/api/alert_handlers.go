@@ -0,0 +1,355 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"srtla-manager/internal/alerts"
+	"srtla-manager/internal/events"
+	"srtla-manager/internal/modem"
+	"srtla-manager/internal/process"
+)
+
+// AlertsStatus describes the audible alerts and the last one played
+type AlertsStatus struct {
+	Enabled   bool          `json:"enabled"`
+	Device    string        `json:"device"`
+	Volume    float64       `json:"volume"`
+	Voice     bool          `json:"voice"`
+	Events    []string      `json:"events"`
+	Speech    bool          `json:"speech_available"` // espeak-ng is installed
+	LastAlert *alerts.Alert `json:"last_alert,omitempty"`
+	LastAt    *time.Time    `json:"last_at,omitempty"`
+	LastError string        `json:"last_error,omitempty"`
+}
+
+// ApplyAlertsConfig starts or stops the alert player to match the
+// configuration
+func (h *Handler) ApplyAlertsConfig() {
+	cfg := h.config.Get().Alerts
+
+	h.StopAlerts()
+	if !cfg.Enabled {
+		return
+	}
+
+	player := alerts.NewPlayer(cfg.Device, cfg.Volume, cfg.Voice)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	h.alertsMu.Lock()
+	h.alertPlayer = player
+	h.alertsCancel = cancel
+	h.alertLinks = nil
+	h.alertInputSeen = false
+	h.alertInputLost = false
+	h.alertsMu.Unlock()
+
+	go player.Run(ctx)
+	go events.Listen(ctx, h.bus, TopicPipelineMode, 4, h.alertPipelineMode)
+
+	mode := "beeps"
+	if cfg.Voice {
+		mode = "voice prompts"
+	}
+	h.logOutput("manager", fmt.Sprintf("[ALERT] Playing %s on %s", mode, cfg.Device))
+}
+
+// StopAlerts stops the alert player; an alert being played is cut off
+func (h *Handler) StopAlerts() {
+	h.alertsMu.Lock()
+	defer h.alertsMu.Unlock()
+	if h.alertsCancel != nil {
+		h.alertsCancel()
+		h.alertsCancel = nil
+	}
+	h.alertPlayer = nil
+}
+
+// alertPipelineMode announces the stream starting and stopping
+func (h *Handler) alertPipelineMode(change PipelineModeChange) {
+	a, ok := streamAlert(change)
+	if !ok {
+		return
+	}
+
+	h.alertsMu.Lock()
+	h.resetAlertStateLocked()
+	played := h.playAlertsLocked([]alerts.Alert{a})
+	h.alertsMu.Unlock()
+
+	h.logAlerts(played)
+}
+
+// streamAlert returns the alert for the stream starting or stopping
+func streamAlert(change PipelineModeChange) (alerts.Alert, bool) {
+	switch {
+	case change.To == PipelineModeStreaming:
+		return alerts.Alert{Event: alerts.EventStreamStarted}, true
+	case change.From == PipelineModeStreaming:
+		return alerts.Alert{Event: alerts.EventStreamStopped}, true
+	}
+	return alerts.Alert{}, false
+}
+
+// resetAlertStateLocked judges links and input afresh for each stream.
+// h.alertsMu must be held.
+func (h *Handler) resetAlertStateLocked() {
+	h.alertLinks = nil
+	h.alertInputSeen = false
+	h.alertInputLost = false
+}
+
+// CheckAlerts announces and sends notifications of changes in the stream's
+// links and the camera input since the last check. It is called from the
+// stats loop.
+func (h *Handler) CheckAlerts() {
+	cfg := h.config.Get()
+	streaming := h.GetPipelineMode() == PipelineModeStreaming
+
+	var up map[string]bool
+	var input bool
+	if streaming {
+		up = map[string]bool{}
+		for _, ip := range h.getAvailableBindIPs(&cfg) {
+			up[ip] = true
+		}
+		ff := h.ffmpeg.Stats()
+		// a camera expected to reconnect isn't announced as lost
+		input = ff.State == process.FFmpegConnected || ff.State == process.FFmpegStreaming || h.ffmpeg.PublisherInGrace()
+	}
+
+	notifying := h.notifying()
+	h.alertsMu.Lock()
+	if h.alertPlayer == nil && !notifying {
+		h.alertsMu.Unlock()
+		return
+	}
+
+	var fired []alerts.Alert
+	if streaming && cfg.SRTLA.Enabled {
+		// links are numbered by their position in the bind IP list
+		links := map[string]bool{}
+		n := 0
+		for _, ip := range h.bindIPs(&cfg) {
+			ip = strings.TrimSpace(ip)
+			if ip == "" {
+				continue
+			}
+			n++
+			if was, known := h.alertLinks[ip]; known && was != up[ip] {
+				event := alerts.EventLinkDown
+				if up[ip] {
+					event = alerts.EventLinkUp
+				}
+				fired = append(fired, alerts.Alert{Event: event, Link: n})
+			}
+			links[ip] = up[ip]
+		}
+		h.alertLinks = links
+	}
+
+	// the input drops while ffmpeg restarts on stream start, so only report
+	// it lost once it has been seen
+	if streaming {
+		switch {
+		case input && h.alertInputLost:
+			fired = append(fired, alerts.Alert{Event: alerts.EventInputRestored})
+			h.alertInputLost = false
+		case !input && h.alertInputSeen && !h.alertInputLost:
+			fired = append(fired, alerts.Alert{Event: alerts.EventInputLost})
+			h.alertInputLost = true
+		}
+		h.alertInputSeen = h.alertInputSeen || input
+	}
+
+	played := h.playAlertsLocked(fired)
+	h.alertsMu.Unlock()
+
+	h.logAlerts(played)
+	h.notifyAlerts(fired)
+}
+
+// CheckModemAlerts logs, announces and sends notifications of modems
+// starting and stopping thermal throttling. It is called each time the
+// modems are polled.
+func (h *Handler) CheckModemAlerts(modems []modem.ModemInfo) {
+	cfg := h.config.Get()
+
+	h.alertsMu.Lock()
+	hot := make(map[string]bool, len(modems))
+	var changed []modem.ModemInfo
+	for _, m := range modems {
+		if m.IMEI == "" {
+			continue
+		}
+		was := h.alertModemHot[m.IMEI]
+		hot[m.IMEI] = m.Hot(was)
+		if hot[m.IMEI] != was {
+			changed = append(changed, m)
+		}
+	}
+	h.alertModemHot = hot
+	h.alertsMu.Unlock()
+	if len(changed) == 0 {
+		return
+	}
+
+	// links are numbered by their position in the bind IP list
+	var links []string
+	for _, ip := range h.bindIPs(&cfg) {
+		if ip = strings.TrimSpace(ip); ip != "" {
+			links = append(links, ip)
+		}
+	}
+	var fired []alerts.Alert
+	for _, m := range changed {
+		a := alerts.Alert{Event: alerts.EventModemCooled}
+		if hot[m.IMEI] {
+			a.Event = alerts.EventModemHot
+		}
+		for _, ip := range modemBindIPs(m) {
+			if i := slices.Index(links, ip); i >= 0 {
+				a.Link = i + 1
+				break
+			}
+		}
+		fired = append(fired, a)
+
+		temp := "unknown temperature"
+		if m.TemperatureC != nil {
+			temp = fmt.Sprintf("%.1f°C", *m.TemperatureC)
+		}
+		state := "cooled down"
+		if hot[m.IMEI] {
+			state = "is throttling"
+		}
+		h.logOutput("manager", fmt.Sprintf("[MODEM] Modem %s (%s) %s: %s, thermal state %q", m.ID, m.IMEI, state, temp, m.Throttling))
+	}
+
+	h.alertsMu.Lock()
+	played := h.playAlertsLocked(fired)
+	h.alertsMu.Unlock()
+
+	h.logAlerts(played)
+	h.notifyAlerts(fired)
+}
+
+// playAlertsLocked queues the alerts for configured events and returns
+// those played. h.alertsMu must be held.
+func (h *Handler) playAlertsLocked(fired []alerts.Alert) []alerts.Alert {
+	if h.alertPlayer == nil {
+		return nil
+	}
+	enabled := h.config.Get().Alerts.Events
+	var played []alerts.Alert
+	for _, a := range fired {
+		if slices.Contains(enabled, a.Event) && h.alertPlayer.Play(a) {
+			played = append(played, a)
+			h.alertLast = &a
+			h.alertLastAt = time.Now()
+		}
+	}
+	return played
+}
+
+func (h *Handler) logAlerts(played []alerts.Alert) {
+	for _, a := range played {
+		h.logOutput("manager", fmt.Sprintf("[ALERT] %s", a.Text()))
+	}
+}
+
+func (h *Handler) alertsStatus() AlertsStatus {
+	cfg := h.config.Get().Alerts
+
+	h.alertsMu.Lock()
+	defer h.alertsMu.Unlock()
+
+	status := AlertsStatus{
+		Enabled: cfg.Enabled,
+		Device:  cfg.Device,
+		Volume:  cfg.Volume,
+		Voice:   cfg.Voice,
+		Events:  cfg.Events,
+		Speech:  alerts.SpeechAvailable(),
+	}
+	if status.Events == nil {
+		status.Events = []string{}
+	}
+	if h.alertLast != nil {
+		last, at := *h.alertLast, h.alertLastAt
+		status.LastAlert = &last
+		status.LastAt = &at
+	}
+	if h.alertPlayer != nil {
+		status.LastError = h.alertPlayer.LastError()
+	}
+	return status
+}
+
+// HandleAlertsStatus handles GET /api/alerts
+func (h *Handler) HandleAlertsStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.alertsStatus())
+}
+
+// HandleAlertsUpdate handles PUT /api/alerts. Fields missing from the body
+// keep their current values; an events list replaces the current one.
+func (h *Handler) HandleAlertsUpdate(w http.ResponseWriter, r *http.Request) {
+	cfg := h.config.Get()
+	// decode the list into a fresh slice rather than over the live config
+	current := cfg.Alerts.Events
+	cfg.Alerts.Events = nil
+	if err := json.NewDecoder(r.Body).Decode(&cfg.Alerts); err != nil {
+		jsonError(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+	if cfg.Alerts.Events == nil {
+		cfg.Alerts.Events = current
+	}
+
+	if err := h.config.Update(cfg); err != nil {
+		validationError(w, err)
+		return
+	}
+
+	h.ApplyAlertsConfig()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.alertsStatus())
+}
+
+// HandleAlertsTest handles POST /api/alerts/test, playing an alert so the
+// volume can be set. It plays stream_started unless the body names another.
+func (h *Handler) HandleAlertsTest(w http.ResponseWriter, r *http.Request) {
+	a := alerts.Alert{Event: alerts.EventStreamStarted}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&a); err != nil {
+			jsonError(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+	if !slices.Contains(alerts.Events, a.Event) {
+		jsonError(w, fmt.Sprintf("Unknown event %q", a.Event), http.StatusBadRequest)
+		return
+	}
+
+	h.alertsMu.Lock()
+	player := h.alertPlayer
+	h.alertsMu.Unlock()
+	if player == nil {
+		jsonError(w, "Alerts are disabled", http.StatusConflict)
+		return
+	}
+	if !player.Play(a) {
+		jsonError(w, "Too many alerts queued", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "queued", "text": a.Text()})
+}
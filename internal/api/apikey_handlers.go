@@ -0,0 +1,147 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"srtla-manager/internal/apikey"
+	"srtla-manager/internal/config"
+	"srtla-manager/internal/events"
+	"srtla-manager/internal/validate"
+)
+
+// APIKeyCreateRequest is the request body for POST /api/access/keys
+type APIKeyCreateRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	Role   string   `json:"role"` // viewer or operator, in place of scopes
+}
+
+// APIKeyCreated is a new key. Key is only ever returned here.
+type APIKeyCreated struct {
+	config.APIKey
+	Key string `json:"key"`
+}
+
+// APIKeyEvent records a key being created or revoked
+type APIKeyEvent struct {
+	Event     string     `json:"event"` // created, revoked or expired
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	NotBefore *time.Time `json:"not_before,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// lookupAPIKey returns the key in keys whose hash matches key
+func lookupAPIKey(keys []config.APIKey, key string) (config.APIKey, bool) {
+	hash := []byte(apikey.Hash(key))
+	for _, k := range keys {
+		if subtle.ConstantTimeCompare(hash, []byte(k.Hash)) == 1 {
+			return k, true
+		}
+	}
+	return config.APIKey{}, false
+}
+
+// HandleAPIKeyList handles GET /api/access/keys
+func (h *Handler) HandleAPIKeyList(w http.ResponseWriter, r *http.Request) {
+	keys := h.config.Get().Access.APIKeys
+	if keys == nil {
+		keys = []config.APIKey{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(keys)
+}
+
+// HandleAPIKeyCreate handles POST /api/access/keys
+func (h *Handler) HandleAPIKeyCreate(w http.ResponseWriter, r *http.Request) {
+	var req APIKeyCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	v := validate.New()
+	v.Required("name", req.Name)
+	switch {
+	case req.Role != "" && len(req.Scopes) > 0:
+		v.Addf("role", "cannot be set together with scopes")
+	case req.Role != "":
+		v.OneOf("role", req.Role, apikey.RoleViewer, apikey.RoleOperator)
+		req.Scopes = apikey.RoleScopes[req.Role]
+	case len(req.Scopes) == 0:
+		v.Addf("scopes", "at least one scope or a role is required")
+	}
+	for _, scope := range req.Scopes {
+		v.OneOf("scopes", scope, apikey.Scopes...)
+	}
+	if err := v.Err(); err != nil {
+		validationError(w, err)
+		return
+	}
+
+	id, key, err := apikey.Generate()
+	if err != nil {
+		jsonError(w, "Failed to generate key: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	created := config.APIKey{
+		ID:        id,
+		Name:      req.Name,
+		Scopes:    req.Scopes,
+		Role:      req.Role,
+		Hash:      apikey.Hash(key),
+		CreatedAt: time.Now().UTC(),
+	}
+
+	cfg := h.config.Get()
+	cfg.Access.APIKeys = append(append([]config.APIKey{}, cfg.Access.APIKeys...), created)
+	if err := h.config.Update(cfg); err != nil {
+		jsonError(w, fmt.Sprintf("Failed to save configuration: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	h.logOutput("manager", fmt.Sprintf("[ACCESS] Created API key %s (%s) with scopes %v", created.Name, created.ID, created.Scopes))
+	events.Publish(h.bus, TopicAPIKey, APIKeyEvent{Event: "created", ID: created.ID, Name: created.Name, Scopes: created.Scopes})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(APIKeyCreated{APIKey: created, Key: key})
+}
+
+// HandleAPIKeyRevoke handles DELETE /api/access/keys/{id}. Requests with the
+// key are refused straight away.
+func (h *Handler) HandleAPIKeyRevoke(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	cfg := h.config.Get()
+	keys := make([]config.APIKey, 0, len(cfg.Access.APIKeys))
+	var revoked *config.APIKey
+	for i, k := range cfg.Access.APIKeys {
+		if k.ID == id {
+			revoked = &cfg.Access.APIKeys[i]
+			continue
+		}
+		keys = append(keys, k)
+	}
+	if revoked == nil {
+		jsonError(w, "API key not found", http.StatusNotFound)
+		return
+	}
+
+	cfg.Access.APIKeys = keys
+	if err := h.config.Update(cfg); err != nil {
+		jsonError(w, fmt.Sprintf("Failed to save configuration: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	h.logOutput("manager", fmt.Sprintf("[ACCESS] Revoked API key %s (%s)", revoked.Name, revoked.ID))
+	events.Publish(h.bus, TopicAPIKey, APIKeyEvent{Event: "revoked", ID: revoked.ID, Name: revoked.Name, Scopes: revoked.Scopes})
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,158 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"srtla-manager/internal/config"
+	"srtla-manager/internal/logger"
+	"srtla-manager/internal/process"
+	"srtla-manager/internal/validate"
+)
+
+// BindSet is a named bind IP set
+type BindSet struct {
+	Name string   `json:"name"`
+	IPs  []string `json:"ips"`
+}
+
+// BindSetsResponse lists the bind IP sets, the one used by default and the
+// one the running stream was started with
+type BindSetsResponse struct {
+	Default string    `json:"default"`
+	Active  string    `json:"active"` // "" while not streaming or when using bind_ips
+	Sets    []BindSet `json:"sets"`
+}
+
+// bindIPs returns the bind IPs of the set the stream was started with, or
+// srtla.bind_ips when it wasn't started with one, less any on the VPN
+// tunnel
+func (h *Handler) bindIPs(cfg *config.Config) []string {
+	ips := cfg.SRTLA.BindIPs
+	if set, ok := cfg.SRTLA.BindSets[h.streamBindSet()]; ok {
+		ips = set
+	}
+	return withoutTunnelIPs(ips, cfg.VPN)
+}
+
+func (h *Handler) streamBindSet() string {
+	h.transportMu.Lock()
+	defer h.transportMu.Unlock()
+	return h.bindSet
+}
+
+func (h *Handler) setStreamBindSet(name string) {
+	h.transportMu.Lock()
+	defer h.transportMu.Unlock()
+	h.bindSet = name
+}
+
+// HandleBindSetList handles GET /api/srtla/bind-sets
+func (h *Handler) HandleBindSetList(w http.ResponseWriter, r *http.Request) {
+	cfg := h.config.Get()
+	resp := BindSetsResponse{
+		Default: cfg.SRTLA.BindSet,
+		Sets:    make([]BindSet, 0, len(cfg.SRTLA.BindSets)),
+	}
+	if h.GetPipelineMode() == PipelineModeStreaming {
+		resp.Active = h.streamBindSet()
+	}
+	for name, ips := range cfg.SRTLA.BindSets {
+		resp.Sets = append(resp.Sets, BindSet{Name: name, IPs: ips})
+	}
+	sort.Slice(resp.Sets, func(i, j int) bool { return resp.Sets[i].Name < resp.Sets[j].Name })
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// HandleBindSetSave handles PUT /api/srtla/bind-sets/{name}, creating or
+// replacing the set. A running stream using it switches to the new IPs.
+func (h *Handler) HandleBindSetSave(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	var req struct {
+		IPs []string `json:"ips"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	v := validate.New()
+	if !config.BindSetNamePattern.MatchString(name) {
+		v.Addf("name", "must be lowercase letters, digits, '-', '+' or '_'")
+	}
+	for i, ip := range req.IPs {
+		v.IP(fmt.Sprintf("ips[%d]", i), ip)
+	}
+	if err := v.Err(); err != nil {
+		validationError(w, err)
+		return
+	}
+
+	cfg := h.config.Get()
+	sets := make(map[string][]string, len(cfg.SRTLA.BindSets)+1)
+	for n, ips := range cfg.SRTLA.BindSets {
+		sets[n] = ips
+	}
+	if req.IPs == nil {
+		req.IPs = []string{}
+	}
+	sets[name] = req.IPs
+	cfg.SRTLA.BindSets = sets
+
+	if err := h.config.Update(cfg); err != nil {
+		validationError(w, err)
+		return
+	}
+
+	logger.Info("Saved bind IP set %s (%d IPs)", name, len(req.IPs))
+
+	if h.srtla.ProcessState() == process.StateRunning && h.streamBindSet() == name {
+		if err := h.reloadSRTLAIPs(h.getAvailableBindIPs(&cfg)); err != nil {
+			jsonError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(BindSet{Name: name, IPs: req.IPs})
+}
+
+// HandleBindSetDelete handles DELETE /api/srtla/bind-sets/{name}. The default
+// set and the set of the running stream can't be deleted.
+func (h *Handler) HandleBindSetDelete(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	cfg := h.config.Get()
+	if _, ok := cfg.SRTLA.BindSets[name]; !ok {
+		jsonError(w, "Bind IP set not found", http.StatusNotFound)
+		return
+	}
+	if cfg.SRTLA.BindSet == name {
+		jsonError(w, fmt.Sprintf("%s is the default bind IP set", name), http.StatusConflict)
+		return
+	}
+	if h.GetPipelineMode() == PipelineModeStreaming && h.streamBindSet() == name {
+		jsonError(w, fmt.Sprintf("%s is in use by the running stream", name), http.StatusConflict)
+		return
+	}
+
+	sets := make(map[string][]string, len(cfg.SRTLA.BindSets))
+	for n, ips := range cfg.SRTLA.BindSets {
+		if n != name {
+			sets[n] = ips
+		}
+	}
+	cfg.SRTLA.BindSets = sets
+
+	if err := h.config.Update(cfg); err != nil {
+		jsonError(w, fmt.Sprintf("Failed to save configuration: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
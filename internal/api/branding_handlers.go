@@ -0,0 +1,30 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"srtla-manager/internal/logger"
+	"srtla-manager/pkg/web"
+)
+
+// BrandingDir returns the configured branding pack directory
+func (h *Handler) BrandingDir() string {
+	return h.config.Get().Web.BrandingDir
+}
+
+// HandleBranding handles GET /api/branding. A broken branding.json falls
+// back to the default look rather than failing the dashboard.
+func (h *Handler) HandleBranding(w http.ResponseWriter, r *http.Request) {
+	branding := web.DefaultBranding()
+	if dir := h.BrandingDir(); dir != "" {
+		b, err := web.LoadBranding(dir)
+		if err != nil {
+			logger.Warn("Branding pack %s: %v", dir, err)
+		}
+		branding = b
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(branding)
+}
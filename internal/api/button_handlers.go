@@ -0,0 +1,295 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"time"
+
+	"srtla-manager/internal"
+	"srtla-manager/internal/buttons"
+	"srtla-manager/internal/config"
+	"srtla-manager/internal/events"
+	"srtla-manager/internal/gpio"
+	"srtla-manager/internal/logger"
+	"srtla-manager/internal/wifi"
+)
+
+const (
+	// ledInterval is the status LED update rate; blinking patterns are
+	// counted in these steps
+	ledInterval = 100 * time.Millisecond
+	// ledErrorFlash is how long the LED flashes after an action fails
+	ledErrorFlash = 2 * time.Second
+)
+
+// ButtonsStatus describes the hardware buttons and the last action they ran
+type ButtonsStatus struct {
+	Enabled    bool                  `json:"enabled"`
+	Buttons    []config.ButtonConfig `json:"buttons"`
+	LEDPin     int                   `json:"led_pin"`
+	Busy       bool                  `json:"busy"` // an action is running
+	LastAction string                `json:"last_action,omitempty"`
+	LastError  string                `json:"last_error,omitempty"` // why the last action failed
+	LastAt     *time.Time            `json:"last_at,omitempty"`
+	Errors     []string              `json:"errors,omitempty"` // pins that could not be set up or read
+}
+
+// ApplyButtonsConfig sets up the buttons and status LED from the
+// configuration
+func (h *Handler) ApplyButtonsConfig() {
+	cfg := h.config.Get().Buttons
+
+	h.StopButtons()
+
+	h.buttonsMu.Lock()
+	defer h.buttonsMu.Unlock()
+
+	h.buttonErrors = nil
+	if !cfg.Enabled {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	h.buttonsCancel = cancel
+
+	if cfg.LEDPin != 0 {
+		led, err := gpio.Output(cfg.LEDPin, cfg.LEDActiveLow)
+		if err != nil {
+			h.buttonErrors = append(h.buttonErrors, err.Error())
+			logger.Error("Buttons: %v", err)
+		} else {
+			h.buttonLED = led
+			go h.driveStatusLED(ctx, led)
+		}
+	}
+
+	for _, b := range cfg.Buttons {
+		pin, err := gpio.Input(b.Pin, b.ActiveLow)
+		if err != nil {
+			h.buttonErrors = append(h.buttonErrors, err.Error())
+			logger.Error("Buttons: %v", err)
+			continue
+		}
+		action := b.Action
+		hold := time.Duration(b.HoldMs) * time.Millisecond
+		go func() {
+			if err := buttons.Watch(ctx, pin, hold, func() { h.pressButton(action) }); err != nil {
+				h.buttonsMu.Lock()
+				h.buttonErrors = append(h.buttonErrors, err.Error())
+				h.buttonsMu.Unlock()
+				logger.Error("Buttons: %v", err)
+			}
+		}()
+	}
+
+	h.logOutput("manager", fmt.Sprintf("[BUTTON] Watching %d button(s)", len(cfg.Buttons)))
+}
+
+// StopButtons stops watching the buttons and turns the LED off
+func (h *Handler) StopButtons() {
+	h.buttonsMu.Lock()
+	defer h.buttonsMu.Unlock()
+	if h.buttonsCancel != nil {
+		h.buttonsCancel()
+		h.buttonsCancel = nil
+	}
+	if h.buttonLED != nil {
+		h.buttonLED.Set(false)
+		h.buttonLED = nil
+	}
+}
+
+// pressButton runs an action unless one is already running, so a held or
+// repeatedly pressed button can't queue up stream starts
+func (h *Handler) pressButton(action string) {
+	h.buttonsMu.Lock()
+	if h.buttonBusy {
+		h.buttonsMu.Unlock()
+		h.logOutput("manager", fmt.Sprintf("[BUTTON] Ignored %s: another action is running", action))
+		return
+	}
+	h.buttonBusy = true
+	h.buttonsMu.Unlock()
+
+	h.logOutput("manager", fmt.Sprintf("[BUTTON] %s", action))
+
+	go func() {
+		err := h.runButtonAction(action)
+
+		now := time.Now()
+		h.buttonsMu.Lock()
+		h.buttonBusy = false
+		h.buttonLastAction = action
+		h.buttonLastAt = now
+		h.buttonLastError = ""
+		if err != nil {
+			h.buttonLastError = err.Error()
+			h.buttonFlashUntil = now.Add(ledErrorFlash)
+		}
+		h.buttonsMu.Unlock()
+
+		if err != nil {
+			h.logOutput("manager", fmt.Sprintf("[BUTTON] %s failed: %v", action, err))
+		}
+		events.Publish(h.bus, TopicButton, h.buttonsStatus())
+	}()
+}
+
+func (h *Handler) runButtonAction(action string) error {
+	ctx := context.Background()
+	streaming := h.GetPipelineMode() == PipelineModeStreaming
+
+	switch action {
+	case buttons.ActionStreamToggle:
+		if streaming {
+			return h.stopStreaming(ctx)
+		}
+		_, err := h.startStreaming(ctx, StreamStartRequest{})
+		return err
+	case buttons.ActionStreamStart:
+		if streaming {
+			return nil
+		}
+		_, err := h.startStreaming(ctx, StreamStartRequest{})
+		return err
+	case buttons.ActionStreamStop:
+		if !streaming {
+			return nil
+		}
+		return h.stopStreaming(ctx)
+	case buttons.ActionHotspotToggle:
+		if h.wifiMgr.HotspotActive() {
+			return h.wifiMgr.StopHotspot()
+		}
+		hs := h.config.Get().Hotspot
+		if hs.SSID == "" {
+			return fmt.Errorf("no hotspot settings saved")
+		}
+		return h.startHotspot(wifi.HotspotConfig{SSID: hs.SSID, Password: hs.Password, Band: hs.Band, Channel: hs.Channel})
+	case buttons.ActionShutdown:
+		return h.shutdownSystem()
+	default:
+		return fmt.Errorf("unknown action %q", action)
+	}
+}
+
+// shutdownSystem ends the stream cleanly and powers the unit off, through
+// srtla-installer unless the manager is root
+func (h *Handler) shutdownSystem() error {
+	if h.GetPipelineMode() == PipelineModeStreaming {
+		if err := h.stopStreaming(context.Background()); err != nil {
+			return err
+		}
+	}
+	h.logOutput("manager", "[BUTTON] Powering off")
+	if os.Geteuid() == 0 {
+		if output, err := exec.Command("systemctl", "poweroff").CombinedOutput(); err != nil {
+			return fmt.Errorf("poweroff failed: %v: %s", err, output)
+		}
+		return nil
+	}
+	resp, err := internal.PowerOffWithInstaller()
+	if err != nil {
+		return fmt.Errorf("poweroff failed: %w", err)
+	}
+	if !resp.Success {
+		return fmt.Errorf("poweroff failed: %s", resp.Message)
+	}
+	return nil
+}
+
+// driveStatusLED shows the unit's state: on while streaming, a slow blink
+// while a button action runs and a fast flash after one fails
+func (h *Handler) driveStatusLED(ctx context.Context, led *gpio.Pin) {
+	ticker := time.NewTicker(ledInterval)
+	defer ticker.Stop()
+
+	lit := false
+	for step := 0; ; step++ {
+		h.buttonsMu.Lock()
+		busy := h.buttonBusy
+		flash := time.Now().Before(h.buttonFlashUntil)
+		h.buttonsMu.Unlock()
+
+		on := h.GetPipelineMode() == PipelineModeStreaming
+		switch {
+		case flash:
+			on = step%2 == 0
+		case busy:
+			on = step/3%2 == 0
+		}
+		if on != lit || step == 0 {
+			if err := led.Set(on); err != nil {
+				logger.Warn("Buttons: %v", err)
+			}
+			lit = on
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (h *Handler) buttonsStatus() ButtonsStatus {
+	cfg := h.config.Get().Buttons
+
+	h.buttonsMu.Lock()
+	defer h.buttonsMu.Unlock()
+
+	status := ButtonsStatus{
+		Enabled:    cfg.Enabled,
+		Buttons:    cfg.Buttons,
+		LEDPin:     cfg.LEDPin,
+		Busy:       h.buttonBusy,
+		LastAction: h.buttonLastAction,
+		LastError:  h.buttonLastError,
+		Errors:     append([]string(nil), h.buttonErrors...),
+	}
+	if status.Buttons == nil {
+		status.Buttons = []config.ButtonConfig{}
+	}
+	if !h.buttonLastAt.IsZero() {
+		at := h.buttonLastAt
+		status.LastAt = &at
+	}
+	return status
+}
+
+// HandleButtonsStatus handles GET /api/buttons
+func (h *Handler) HandleButtonsStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.buttonsStatus())
+}
+
+// HandleButtonsUpdate handles PUT /api/buttons. Fields missing from the body
+// keep their current values; a buttons list replaces the current one.
+func (h *Handler) HandleButtonsUpdate(w http.ResponseWriter, r *http.Request) {
+	cfg := h.config.Get()
+	// decode the list into a fresh slice rather than over the live config
+	current := cfg.Buttons.Buttons
+	cfg.Buttons.Buttons = nil
+	if err := json.NewDecoder(r.Body).Decode(&cfg.Buttons); err != nil {
+		jsonError(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+	if cfg.Buttons.Buttons == nil {
+		cfg.Buttons.Buttons = current
+	}
+
+	if err := h.config.Update(cfg); err != nil {
+		validationError(w, err)
+		return
+	}
+
+	h.ApplyButtonsConfig()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.buttonsStatus())
+}
@@ -0,0 +1,377 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"sync"
+
+	"srtla-manager/internal/config"
+	"srtla-manager/internal/jobs"
+	"srtla-manager/internal/logger"
+	"srtla-manager/internal/validate"
+)
+
+// Bulk actions that can be run across a camera group
+const (
+	GroupActionConnect   = "connect"
+	GroupActionConfigure = "configure"
+	GroupActionStart     = "start"
+	GroupActionStop      = "stop"
+)
+
+var groupActions = []string{GroupActionConnect, GroupActionConfigure, GroupActionStart, GroupActionStop}
+
+// Outcome of a bulk action on one camera
+const (
+	GroupResultOK      = "ok"
+	GroupResultSkipped = "skipped"
+	GroupResultFailed  = "failed"
+)
+
+var groupIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// CameraGroup is a configured group with its ID
+type CameraGroup struct {
+	ID string `json:"id"`
+	config.CameraGroupConfig
+}
+
+// CameraGroupActionRequest carries settings for configure and start. Fields
+// left empty fall back to each camera's saved settings.
+type CameraGroupActionRequest struct {
+	DJI CameraConfigRequest   `json:"dji"`
+	USB USBCameraStartRequest `json:"usb"`
+}
+
+// CameraGroupResult is the outcome of a bulk action on one camera
+type CameraGroupResult struct {
+	Camera  string `json:"camera"`
+	Kind    string `json:"kind"` // dji or usb
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// CameraGroupActionResult is the job result of a bulk action
+type CameraGroupActionResult struct {
+	Group     string              `json:"group"`
+	Action    string              `json:"action"`
+	Succeeded int                 `json:"succeeded"`
+	Failed    int                 `json:"failed"`
+	Results   []CameraGroupResult `json:"results"`
+}
+
+// HandleCameraGroupList handles GET /api/camera-groups
+func (h *Handler) HandleCameraGroupList(w http.ResponseWriter, r *http.Request) {
+	cfg := h.config.Get()
+	groups := make([]CameraGroup, 0, len(cfg.CameraGroups))
+	for id, group := range cfg.CameraGroups {
+		groups = append(groups, CameraGroup{ID: id, CameraGroupConfig: group})
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].ID < groups[j].ID })
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(groups)
+}
+
+// HandleCameraGroupSave handles PUT /api/camera-groups/{id}, creating or
+// replacing the group
+func (h *Handler) HandleCameraGroupSave(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	var group config.CameraGroupConfig
+	if err := json.NewDecoder(r.Body).Decode(&group); err != nil {
+		jsonError(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if !groupIDPattern.MatchString(id) {
+		v := validate.New()
+		v.Addf("id", "must be lowercase letters, digits, '-' or '_'")
+		validationError(w, v.Err())
+		return
+	}
+
+	cfg := h.config.Get()
+	groups := make(map[string]config.CameraGroupConfig, len(cfg.CameraGroups)+1)
+	for gid, g := range cfg.CameraGroups {
+		groups[gid] = g
+	}
+	groups[id] = group
+	cfg.CameraGroups = groups
+
+	if err := h.config.Update(cfg); err != nil {
+		validationError(w, err)
+		return
+	}
+
+	logger.Info("Saved camera group %s (%d DJI, %d USB)", id, len(group.DJI), len(group.USB))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(CameraGroup{ID: id, CameraGroupConfig: group})
+}
+
+// HandleCameraGroupDelete handles DELETE /api/camera-groups/{id}
+func (h *Handler) HandleCameraGroupDelete(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	cfg := h.config.Get()
+	if _, ok := cfg.CameraGroups[id]; !ok {
+		jsonError(w, "Camera group not found", http.StatusNotFound)
+		return
+	}
+	groups := make(map[string]config.CameraGroupConfig, len(cfg.CameraGroups))
+	for gid, g := range cfg.CameraGroups {
+		if gid != id {
+			groups[gid] = g
+		}
+	}
+	cfg.CameraGroups = groups
+
+	if err := h.config.Update(cfg); err != nil {
+		jsonError(w, fmt.Sprintf("Failed to save configuration: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleCameraGroupAction handles POST /api/camera-groups/{id}/{action},
+// running connect, configure, start or stop on every camera in the group as
+// a job. The job result reports the outcome per camera.
+func (h *Handler) HandleCameraGroupAction(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	action := r.PathValue("action")
+
+	group, ok := h.config.Get().CameraGroups[id]
+	if !ok {
+		jsonError(w, "Camera group not found", http.StatusNotFound)
+		return
+	}
+
+	var req CameraGroupActionRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			jsonError(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	v := validate.New()
+	v.OneOf("action", action, groupActions...)
+	if err := v.Err(); err != nil {
+		validationError(w, err)
+		return
+	}
+	if err := validateUSBCameraStart(req.USB); err != nil {
+		validationError(w, err)
+		return
+	}
+
+	// USB cameras take over the shared pipeline when they start or stop
+	release := func() {}
+	if len(group.USB) > 0 && (action == GroupActionStart || action == GroupActionStop) {
+		if h.usbCamController == nil {
+			jsonError(w, "USB camera support not initialized", http.StatusServiceUnavailable)
+			return
+		}
+		var err error
+		release, err = h.acquirePipeline("camera_group_" + action)
+		if err != nil {
+			pipelineError(w, err, http.StatusConflict)
+			return
+		}
+	}
+
+	h.logOutput("manager", fmt.Sprintf("[CAMERAS] Running %s on group %s (%d DJI, %d USB)", action, id, len(group.DJI), len(group.USB)))
+
+	job := h.jobs.Start("camera_group_"+action, func(ctx context.Context, report *jobs.Reporter) (interface{}, error) {
+		defer release()
+		return h.runCameraGroupAction(ctx, report, id, group, action, req), nil
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"status": "started",
+		"group":  id,
+		"action": action,
+		"job_id": job.ID,
+	})
+}
+
+// runCameraGroupAction applies the action to the DJI cameras in parallel,
+// since most of their time is spent waiting on the camera, and then to the
+// USB cameras one at a time as they share the ffmpeg pipeline.
+func (h *Handler) runCameraGroupAction(ctx context.Context, report *jobs.Reporter, id string, group config.CameraGroupConfig, action string, req CameraGroupActionRequest) CameraGroupActionResult {
+	total := len(group.DJI) + len(group.USB)
+	results := make([]CameraGroupResult, total)
+
+	var mu sync.Mutex
+	done := 0
+	record := func(i int, result CameraGroupResult) {
+		mu.Lock()
+		defer mu.Unlock()
+		results[i] = result
+		done++
+		report.Progress(done*100/max(total, 1), "%d of %d cameras done", done, total)
+	}
+
+	var wg sync.WaitGroup
+	for i, cameraID := range group.DJI {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			record(i, h.djiGroupAction(ctx, cameraID, action, req.DJI))
+		}()
+	}
+	wg.Wait()
+
+	for i, cameraID := range group.USB {
+		record(len(group.DJI)+i, h.usbGroupAction(cameraID, action, req.USB))
+	}
+
+	result := CameraGroupActionResult{Group: id, Action: action, Results: results}
+	for _, r := range results {
+		if r.Status == GroupResultFailed {
+			result.Failed++
+		} else {
+			result.Succeeded++
+		}
+	}
+	h.logOutput("manager", fmt.Sprintf("[CAMERAS] %s on group %s: %d ok, %d failed", action, id, result.Succeeded, result.Failed))
+	return result
+}
+
+func (h *Handler) djiGroupAction(ctx context.Context, cameraID, action string, overrides CameraConfigRequest) CameraGroupResult {
+	result := CameraGroupResult{Camera: cameraID, Kind: "dji", Status: GroupResultOK}
+	fail := func(err error) CameraGroupResult {
+		result.Status = GroupResultFailed
+		result.Message = err.Error()
+		return result
+	}
+
+	switch action {
+	case GroupActionConnect:
+		if h.djiController.GetDeviceState(cameraID) != nil {
+			result.Status = GroupResultSkipped
+			result.Message = "already connected"
+			return result
+		}
+		if err := h.djiController.ConnectDevice(cameraID); err != nil {
+			return fail(err)
+		}
+
+	case GroupActionConfigure, GroupActionStart:
+		// DJI cameras start streaming as soon as they are configured, so
+		// both actions send the saved settings with any overrides applied
+		req := h.groupCameraConfig(cameraID, overrides)
+		if err := validateCameraConfigRequest(req); err != nil {
+			return fail(fmt.Errorf("no usable saved configuration: %w", err))
+		}
+		if err := h.configureCamera(ctx, cameraID, req); err != nil {
+			return fail(err)
+		}
+		if err := h.awaitCameraStreaming(ctx, cameraID, nil); err != nil {
+			return fail(err)
+		}
+		result.Message = "streaming"
+
+	case GroupActionStop:
+		if err := h.djiController.StopStreaming(cameraID); err != nil {
+			return fail(err)
+		}
+	}
+	return result
+}
+
+// groupCameraConfig layers the non-empty bulk overrides over the camera's
+// saved configuration
+func (h *Handler) groupCameraConfig(cameraID string, overrides CameraConfigRequest) CameraConfigRequest {
+	var req CameraConfigRequest
+	if saved, ok := h.config.LoadCameraConfig(cameraID); ok {
+		req = CameraConfigRequest{
+			CameraName:   saved.Name,
+			WiFiSSID:     saved.WiFiSSID,
+			WiFiPassword: saved.WiFiPassword,
+			RTMPURL:      saved.RTMPUrl,
+		}
+	}
+	if overrides.WiFiSSID != "" {
+		req.WiFiSSID = overrides.WiFiSSID
+		req.WiFiPassword = overrides.WiFiPassword
+	}
+	if overrides.RTMPURL != "" {
+		req.RTMPURL = overrides.RTMPURL
+	}
+	req.Resolution = overrides.Resolution
+	req.FPS = overrides.FPS
+	req.BitrateKbps = overrides.BitrateKbps
+	req.Stabilization = overrides.Stabilization
+	return req
+}
+
+func (h *Handler) usbGroupAction(cameraID, action string, settings USBCameraStartRequest) CameraGroupResult {
+	result := CameraGroupResult{Camera: cameraID, Kind: "usb", Status: GroupResultOK}
+	if h.usbCamController == nil {
+		result.Status = GroupResultFailed
+		result.Message = "USB camera support not initialized"
+		return result
+	}
+	if h.usbCamController.GetCameraState(cameraID) == nil && action != GroupActionConfigure {
+		result.Status = GroupResultFailed
+		result.Message = "camera not found"
+		return result
+	}
+
+	var err error
+	switch action {
+	case GroupActionConnect:
+		result.Status = GroupResultSkipped
+		result.Message = "USB cameras need no connection"
+	case GroupActionConfigure:
+		err = h.saveUSBCameraSettings(cameraID, settings)
+	case GroupActionStart:
+		err = h.startUSBCamera(cameraID, settings)
+	case GroupActionStop:
+		err = h.stopUSBCamera(cameraID)
+	}
+	if err != nil {
+		result.Status = GroupResultFailed
+		result.Message = err.Error()
+	}
+	return result
+}
+
+// saveUSBCameraSettings stores the non-zero settings as the camera's
+// defaults for later starts
+func (h *Handler) saveUSBCameraSettings(cameraID string, settings USBCameraStartRequest) error {
+	cfg := h.config.Get()
+	cameras := make(map[string]config.USBCameraConfig, len(cfg.USBCameras)+1)
+	for id, cam := range cfg.USBCameras {
+		cameras[id] = cam
+	}
+
+	cam := cameras[cameraID]
+	if state := h.usbCamController.GetCameraState(cameraID); state != nil && state.Camera != nil && cam.Name == "" {
+		cam.Name = state.Camera.Name
+	}
+	if settings.Width != 0 || settings.Height != 0 {
+		cam.Width, cam.Height = settings.Width, settings.Height
+	}
+	if settings.FPS != 0 {
+		cam.FPS = settings.FPS
+	}
+	if settings.Bitrate != 0 {
+		cam.Bitrate = settings.Bitrate
+	}
+	if settings.Encoder != "" {
+		cam.Encoder = settings.Encoder
+	}
+	cameras[cameraID] = cam
+	cfg.USBCameras = cameras
+
+	return h.config.Update(cfg)
+}
@@ -1,12 +1,16 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"srtla-manager/internal/dji"
+	"srtla-manager/internal/jobs"
+	"srtla-manager/internal/validate"
 )
 
 // HandleCameraList returns list of discovered cameras
@@ -66,10 +70,36 @@ func (h *Handler) HandleCameraScan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	job := h.watchCameraScan(timeout)
+
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{
 		"status":  "scanning",
 		"timeout": timeout.String(),
+		"job_id":  job.ID,
+	})
+}
+
+// watchCameraScan tracks a running BLE scan as a job. Canceling the job
+// stops the scan.
+func (h *Handler) watchCameraScan(timeout time.Duration) jobs.Job {
+	return h.jobs.Start("camera_scan", func(ctx context.Context, report *jobs.Reporter) (interface{}, error) {
+		start := time.Now()
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+
+		for h.djiScanner.IsScanning() {
+			select {
+			case <-ctx.Done():
+				h.djiScanner.StopScanning()
+				return nil, ctx.Err()
+			case <-ticker.C:
+			}
+			found := len(h.djiScanner.GetDiscoveredDevices())
+			report.Progress(int(time.Since(start)*100/timeout), "Scanning, %d camera(s) found", found)
+		}
+
+		return map[string]int{"found": len(h.djiScanner.GetDiscoveredDevices())}, nil
 	})
 }
 
@@ -231,6 +261,16 @@ func (h *Handler) HandleDebugAddTestCamera(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	v := validate.New()
+	v.Required("id", req.ID)
+	if req.RSSI > 0 || req.RSSI < -127 {
+		v.Addf("rssi", "%d is out of range (-127 to 0 dBm)", req.RSSI)
+	}
+	if err := v.Err(); err != nil {
+		validationError(w, err)
+		return
+	}
+
 	model := parseDeviceModel(req.Model)
 	device := &dji.DiscoveredDevice{
 		ID:        req.ID,
@@ -1,14 +1,21 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"time"
 
+	"srtla-manager/internal/config"
+	"srtla-manager/internal/dji"
+	"srtla-manager/internal/jobs"
 	"srtla-manager/internal/process"
+	"srtla-manager/internal/tracing"
+	"srtla-manager/internal/validate"
 )
 
 // HandleCameraPreview starts a preview stream on a camera before full configuration
@@ -29,18 +36,20 @@ func (h *Handler) HandleCameraPreview(w http.ResponseWriter, r *http.Request) {
 
 	cameraID := parts[0]
 
-	// Parse preview request (WiFi details needed to connect)
-	var previewReq struct {
-		WiFiSSID     string `json:"wifi_ssid"`
-		WiFiPassword string `json:"wifi_password"`
-	}
+	// Parse preview request. WiFi details are needed to connect; the stream
+	// settings are only used when the preview goes to the live ingest.
+	var previewReq CameraConfigRequest
 	if err := json.NewDecoder(r.Body).Decode(&previewReq); err != nil {
 		jsonError(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
 		return
 	}
 
-	if previewReq.WiFiSSID == "" {
-		jsonError(w, "WiFi SSID is required for preview", http.StatusBadRequest)
+	v := validate.New()
+	v.Required("wifi_ssid", previewReq.WiFiSSID)
+	v.SSID("wifi_ssid", previewReq.WiFiSSID)
+	v.Passphrase("wifi_password", previewReq.WiFiPassword)
+	if err := v.Err(); err != nil {
+		validationError(w, err)
 		return
 	}
 
@@ -51,13 +60,7 @@ func (h *Handler) HandleCameraPreview(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Build preview RTMP URL with the device's actual IP that the camera can reach
-	// Use application "live" and stream key "live" (standard RTMP pattern)
-	previewRTMPURL := fmt.Sprintf("rtmp://%s:9999/live/live", deviceIP)
-
-	// Configure preview stream with low bitrate
-	// Use a different port (9999) for preview to avoid conflicts with main streaming
-	previewConfig := h.buildPreviewConfig(previewReq.WiFiSSID, previewReq.WiFiPassword, previewRTMPURL)
+	settings, mode := h.previewSettings(cameraID)
 
 	// Ensure device is connected before configuring preview
 	if h.djiController.GetDeviceState(cameraID) == nil {
@@ -68,12 +71,32 @@ func (h *Handler) HandleCameraPreview(w http.ResponseWriter, r *http.Request) {
 		time.Sleep(2 * time.Second)
 	}
 
+	release, err := h.acquirePipeline("camera_preview")
+	if err != nil {
+		pipelineError(w, err, http.StatusConflict)
+		return
+	}
+	defer release()
+
 	// Block preview if the main stream is actively running
 	if h.GetPipelineMode() == PipelineModeStreaming {
 		jsonError(w, "Cannot start preview while main stream is running. Please stop streaming first.", http.StatusConflict)
 		return
 	}
 
+	if mode != previewSeparate {
+		h.startReusedPreview(w, cameraID, deviceIP, previewReq, settings, mode)
+		return
+	}
+
+	// Build preview RTMP URL with the device's actual IP that the camera can reach
+	// Use application "live" and stream key "live" (standard RTMP pattern)
+	previewRTMPURL := fmt.Sprintf("rtmp://%s:9999/live/live", deviceIP)
+
+	// Configure preview stream with the dji_preview settings
+	// Use a different port (9999) for preview to avoid conflicts with main streaming
+	previewConfig := h.buildPreviewConfig(cameraID, previewReq.WiFiSSID, previewReq.WiFiPassword, previewRTMPURL)
+
 	// Stop FFmpeg (receive-only mode) so preview can bind to a different port
 	if h.ffmpeg.ProcessState() == process.StateRunning {
 		h.SetPipelineMode(PipelineModeIdle)
@@ -82,7 +105,7 @@ func (h *Handler) HandleCameraPreview(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Start receiving preview stream and convert to HLS
-	previewDir := "/tmp/srtla-preview-temp"
+	previewDir := cameraPreviewDir
 	os.RemoveAll(previewDir)
 	if err := os.MkdirAll(previewDir, 0777); err != nil {
 		jsonError(w, fmt.Sprintf("Failed to create preview directory: %v", err), http.StatusBadRequest)
@@ -107,7 +130,57 @@ func (h *Handler) HandleCameraPreview(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"status":      "preview_streaming",
 		"camera":      cameraID,
-		"preview_url": "/preview-temp/playlist.m3u8",
+		"preview_url": h.previewURL("/preview-temp/playlist.m3u8"),
+	})
+}
+
+// startReusedPreview points the camera at the main RTMP ingest and previews
+// the receive-mode HLS output. With previewReuse the camera sends its
+// full-quality settings, so configuring it afterwards with the same settings
+// goes live without another configure cycle. With previewWarm it sends the
+// preview settings, and going live only changes the stream quality in place.
+func (h *Handler) startReusedPreview(w http.ResponseWriter, cameraID, deviceIP string, req CameraConfigRequest, settings config.DJIPreviewConfig, mode previewMode) {
+	cfg := h.config.Get()
+	if req.RTMPURL == "" {
+		if saved, ok := h.config.LoadCameraConfig(cameraID); ok && saved.RTMPUrl != "" {
+			req.RTMPURL = saved.RTMPUrl
+		} else {
+			req.RTMPURL = fmt.Sprintf("rtmp://%s:%d/%s", deviceIP, cfg.RTMP.ListenPort, cfg.RTMP.StreamKey)
+		}
+	}
+	if err := validateCameraConfigRequest(req); err != nil {
+		validationError(w, err)
+		return
+	}
+	streamConfig := h.buildStreamConfig(req)
+	if mode == previewWarm {
+		streamConfig.Resolution = dji.StreamResolution(settings.Resolution)
+		streamConfig.FPS = settings.FPS
+		streamConfig.BitrateKbps = uint16(settings.BitrateKbps)
+	}
+
+	// The camera connects to the receive-mode ffmpeg, which writes the preview
+	if h.GetPipelineMode() == PipelineModeIdle {
+		if err := h.StartReceiveMode(); err != nil {
+			jsonError(w, fmt.Sprintf("Failed to start preview stream receiver: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if !h.djiController.IsStreamingWith(cameraID, streamConfig) {
+		if err := h.djiController.ConfigureStreaming(cameraID, streamConfig); err != nil {
+			jsonError(w, fmt.Sprintf("Failed to start preview: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":       "preview_streaming",
+		"camera":       cameraID,
+		"preview_url":  h.previewURL("/preview/playlist.m3u8"),
+		"reuse_stream": mode == previewReuse,
+		"warm_standby": mode == previewWarm,
 	})
 }
 
@@ -128,31 +201,83 @@ func (h *Handler) HandleCameraConfigure(w http.ResponseWriter, r *http.Request)
 
 	cameraID := parts[0]
 
+	ctx, span := tracing.Start(r.Context(), "camera.configure")
+	defer span.End()
+	span.SetAttribute("camera.id", cameraID)
+
 	var configReq CameraConfigRequest
 	if err := json.NewDecoder(r.Body).Decode(&configReq); err != nil {
 		jsonError(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
 		return
 	}
 
-	if configReq.WiFiSSID == "" || configReq.RTMPURL == "" {
-		jsonError(w, "WiFi SSID and RTMP URL are required", http.StatusBadRequest)
+	if err := validateCameraConfigRequest(configReq); err != nil {
+		validationError(w, err)
 		return
 	}
 
+	if err := h.configureCamera(ctx, cameraID, configReq); err != nil {
+		span.RecordError(err)
+		jsonError(w, fmt.Sprintf("Failed to configure: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	job := h.watchCameraSetup(cameraID)
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status": "configuring",
+		"camera": cameraID,
+		"config": configReq,
+		"job_id": job.ID,
+	})
+}
+
+// configureCamera connects the camera if needed, starts it streaming with
+// the requested settings and saves them. The span in ctx, if any, records
+// whether the reconfigure was skipped.
+func (h *Handler) configureCamera(ctx context.Context, cameraID string, configReq CameraConfigRequest) error {
+	span := tracing.FromContext(ctx)
 	streamConfig := h.buildStreamConfig(configReq)
 
 	// Ensure device is connected
 	if h.djiController.GetDeviceState(cameraID) == nil {
-		if err := h.djiController.ConnectDevice(cameraID); err != nil {
-			jsonError(w, fmt.Sprintf("Failed to connect to camera before configuring: %v", err), http.StatusBadRequest)
-			return
+		_, connectSpan := tracing.Start(ctx, "camera.connect")
+		err := h.djiController.ConnectDevice(cameraID)
+		if err == nil {
+			time.Sleep(2 * time.Second)
+		}
+		connectSpan.RecordError(err)
+		connectSpan.End()
+		if err != nil {
+			return fmt.Errorf("failed to connect to camera before configuring: %w", err)
 		}
-		time.Sleep(2 * time.Second)
 	}
 
-	if err := h.djiController.ConfigureStreaming(cameraID, streamConfig); err != nil {
-		jsonError(w, fmt.Sprintf("Failed to configure: %v", err), http.StatusBadRequest)
-		return
+	// A preview that reused the live stream already left the camera
+	// streaming with these settings, and a warm standby preview only needs
+	// its stream quality raised
+	switch {
+	case h.djiController.IsStreamingWith(cameraID, streamConfig):
+		span.SetAttribute("camera.reconfigure_skipped", true)
+		log.Printf("[DJI] Camera %s already streaming with these settings, skipping reconfigure\n", cameraID)
+	case h.djiController.CanReconfigure(cameraID, streamConfig):
+		span.SetAttribute("camera.reconfigure_in_place", true)
+		_, streamSpan := tracing.Start(ctx, "camera.reconfigure")
+		err := h.djiController.Reconfigure(cameraID, streamConfig)
+		streamSpan.RecordError(err)
+		streamSpan.End()
+		if err != nil {
+			return err
+		}
+	default:
+		_, streamSpan := tracing.Start(ctx, "camera.configure_streaming")
+		err := h.djiController.ConfigureStreaming(cameraID, streamConfig)
+		streamSpan.RecordError(err)
+		streamSpan.End()
+		if err != nil {
+			return err
+		}
 	}
 
 	// FFmpeg should already be running in receive mode — camera will connect to it.
@@ -166,18 +291,88 @@ func (h *Handler) HandleCameraConfigure(w http.ResponseWriter, r *http.Request)
 
 	// Save camera configuration
 	cameraConfig := h.configFromRequest(configReq)
+	if saved, ok := h.config.LoadCameraConfig(cameraID); ok {
+		cameraConfig.Preview = saved.Preview
+	}
 	if err := h.config.SaveCameraConfig(cameraID, cameraConfig); err != nil {
 		fmt.Printf("[ERROR] Failed to save camera config: %v\n", err)
 	}
+	return nil
+}
 
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"status": "configuring",
-		"camera": cameraID,
-		"config": configReq,
+// cameraSetupProgress maps the DJI setup states to a rough completion percentage
+var cameraSetupProgress = map[dji.ConnectionState]int{
+	dji.StateConnecting:      10,
+	dji.StatePairing:         20,
+	dji.StatePreparingStream: 30,
+	dji.StateSettingUpWiFi:   50,
+	dji.StateConfiguring:     70,
+	dji.StateStartingStream:  85,
+}
+
+// watchCameraSetup follows the camera's streaming state machine as a job
+// until it is streaming or fails. Canceling the job stops the camera.
+func (h *Handler) watchCameraSetup(cameraID string) jobs.Job {
+	return h.jobs.Start("camera_setup", func(ctx context.Context, report *jobs.Reporter) (interface{}, error) {
+		var last dji.ConnectionState
+		err := h.awaitCameraStreaming(ctx, cameraID, func(state dji.ConnectionState) {
+			if state == last {
+				return
+			}
+			last = state
+			pct, ok := cameraSetupProgress[last]
+			if !ok {
+				pct = -1
+			}
+			report.Progress(pct, "%s", last)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return map[string]string{"camera": cameraID, "state": string(dji.StateStreaming)}, nil
 	})
 }
 
+// awaitCameraStreaming polls the camera until it is streaming or fails,
+// passing each intermediate state to onState. Canceling ctx stops the camera.
+func (h *Handler) awaitCameraStreaming(ctx context.Context, cameraID string, onState func(dji.ConnectionState)) error {
+	// The controller gives up after StartStreamingTimeout; allow a little slack
+	ctx, cancel := context.WithTimeout(ctx, dji.StartStreamingTimeout+10*time.Second)
+	defer cancel()
+
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return fmt.Errorf("camera did not start streaming in time")
+			}
+			h.djiController.StopStreaming(cameraID)
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		state := h.djiController.GetDeviceState(cameraID)
+		if state == nil {
+			return fmt.Errorf("camera %s disappeared", cameraID)
+		}
+		switch state.ConnectionState {
+		case dji.StateStreaming:
+			return nil
+		case dji.StateError, dji.StateWiFiSetupFailed:
+			if state.LastError != "" {
+				return errors.New(state.LastError)
+			}
+			return fmt.Errorf("camera setup failed: %s", state.ConnectionState)
+		}
+		if onState != nil {
+			onState(state.ConnectionState)
+		}
+	}
+}
+
 // HandleCameraStop stops streaming on a camera
 func (h *Handler) HandleCameraStop(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,493 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"srtla-manager/internal/config"
+	"srtla-manager/internal/dji"
+	"srtla-manager/internal/usbcam"
+	"srtla-manager/internal/validate"
+)
+
+// Kinds of camera served by /api/v2/cameras
+const (
+	CameraKindDJI  = "dji"
+	CameraKindUSB  = "usb"
+	CameraKindRTMP = "rtmp" // any camera pushing to one of the RTMP stream keys
+)
+
+// CameraActionPreview starts a camera's preview. The other actions share
+// the camera group names.
+const CameraActionPreview = "preview"
+
+var cameraActions = []string{GroupActionConnect, GroupActionConfigure, GroupActionStart, GroupActionStop, CameraActionPreview}
+
+// errCameraUnsupported is returned for an action a kind of camera doesn't have
+var errCameraUnsupported = errors.New("not supported by this kind of camera")
+
+// CameraSettings are the settings of any kind of camera. Each kind uses the
+// fields it understands, and fields left empty fall back to the camera's
+// saved settings.
+type CameraSettings struct {
+	Resolution    string `json:"resolution,omitempty"` // 480p, 720p, 1080p or WIDTHxHEIGHT
+	FPS           int    `json:"fps,omitempty"`
+	BitrateKbps   int    `json:"bitrate_kbps,omitempty"`
+	Encoder       string `json:"encoder,omitempty"`       // USB only
+	Stabilization string `json:"stabilization,omitempty"` // DJI only
+	WiFiSSID      string `json:"wifi_ssid,omitempty"`     // DJI only
+	WiFiPassword  string `json:"wifi_password,omitempty"` // DJI only
+	RTMPURL       string `json:"rtmp_url,omitempty"`      // DJI only
+}
+
+// CameraStatus describes a camera the same way whatever its kind
+type CameraStatus struct {
+	ID        string   `json:"id"`
+	Kind      string   `json:"kind"`
+	Name      string   `json:"name"`
+	Connected bool     `json:"connected"`
+	Streaming bool     `json:"streaming"`
+	State     string   `json:"state"` // the kind's own state name
+	Error     string   `json:"error,omitempty"`
+	Actions   []string `json:"actions"` // the actions the camera supports
+}
+
+// Camera is a video source driven the same way whatever its kind. Actions a
+// kind doesn't have return errCameraUnsupported.
+type Camera interface {
+	Status() CameraStatus
+	Connect(ctx context.Context) error
+	// Configure saves settings for later starts without starting the camera
+	Configure(ctx context.Context, s CameraSettings) error
+	// Start streams with the saved settings, overridden by the non-empty s
+	Start(ctx context.Context, s CameraSettings) error
+	Stop(ctx context.Context) error
+	// Preview starts the camera's preview and answers r like the kind's own
+	// preview endpoint
+	Preview(w http.ResponseWriter, r *http.Request)
+}
+
+// resolutionPresets are the named resolutions and their sizes
+var resolutionPresets = map[string][2]int{
+	string(dji.Resolution480p):  {854, 480},
+	string(dji.Resolution720p):  {1280, 720},
+	string(dji.Resolution1080p): {1920, 1080},
+}
+
+// parseResolution returns the width and height of a named or WIDTHxHEIGHT
+// resolution, or zeros for ""
+func parseResolution(s string) (int, int, error) {
+	if s == "" {
+		return 0, 0, nil
+	}
+	if size, ok := resolutionPresets[s]; ok {
+		return size[0], size[1], nil
+	}
+	var width, height int
+	if n, err := fmt.Sscanf(s, "%dx%d", &width, &height); err != nil || n != 2 {
+		return 0, 0, fmt.Errorf("%q is not 480p, 720p, 1080p or WIDTHxHEIGHT", s)
+	}
+	return width, height, nil
+}
+
+// djiCamera is a DJI camera controlled over Bluetooth
+type djiCamera struct {
+	h  *Handler
+	id string
+}
+
+func (c djiCamera) Status() CameraStatus {
+	status := CameraStatus{
+		ID:      c.id,
+		Kind:    CameraKindDJI,
+		State:   string(dji.StateIdle),
+		Actions: cameraActions,
+	}
+	if saved, ok := c.h.config.LoadCameraConfig(c.id); ok {
+		status.Name = saved.Name
+	}
+	if state := c.h.djiController.GetDeviceState(c.id); state != nil {
+		status.Connected = true
+		status.Streaming = state.ConnectionState == dji.StateStreaming
+		status.State = string(state.ConnectionState)
+		status.Error = state.LastError
+		if status.Name == "" && state.Device != nil {
+			status.Name = state.Device.Name
+		}
+	}
+	return status
+}
+
+func (c djiCamera) Connect(ctx context.Context) error {
+	if c.h.djiController.GetDeviceState(c.id) != nil {
+		return nil
+	}
+	return c.h.djiController.ConnectDevice(c.id)
+}
+
+// Configure saves the WiFi network and RTMP URL the camera streams with.
+// Stream quality isn't saved for DJI cameras and is given on each start.
+func (c djiCamera) Configure(ctx context.Context, s CameraSettings) error {
+	saved, _ := c.h.config.LoadCameraConfig(c.id)
+	if s.WiFiSSID != "" {
+		saved.WiFiSSID, saved.WiFiPassword = s.WiFiSSID, s.WiFiPassword
+	}
+	if s.RTMPURL != "" {
+		saved.RTMPUrl = s.RTMPURL
+	}
+
+	v := validate.New()
+	v.SSID("wifi_ssid", saved.WiFiSSID)
+	v.Passphrase("wifi_password", saved.WiFiPassword)
+	v.URL("rtmp_url", saved.RTMPUrl, "rtmp", "rtmps")
+	if err := v.Err(); err != nil {
+		return err
+	}
+	return c.h.config.SaveCameraConfig(c.id, saved)
+}
+
+func (c djiCamera) Start(ctx context.Context, s CameraSettings) error {
+	resolution := s.Resolution
+	if _, height, err := parseResolution(s.Resolution); err == nil && height != 0 {
+		resolution = fmt.Sprintf("%dp", height)
+	}
+	// checked before it is narrowed to the camera's 16 bits
+	v := validate.New()
+	v.Bitrate("bitrate_kbps", s.BitrateKbps)
+	if err := v.Err(); err != nil {
+		return err
+	}
+	req := c.h.groupCameraConfig(c.id, CameraConfigRequest{
+		WiFiSSID:      s.WiFiSSID,
+		WiFiPassword:  s.WiFiPassword,
+		RTMPURL:       s.RTMPURL,
+		Resolution:    resolution,
+		FPS:           s.FPS,
+		BitrateKbps:   uint16(s.BitrateKbps),
+		Stabilization: s.Stabilization,
+	})
+	if err := validateCameraConfigRequest(req); err != nil {
+		return err
+	}
+	return c.h.configureCamera(ctx, c.id, req)
+}
+
+func (c djiCamera) Stop(ctx context.Context) error {
+	return c.h.djiController.StopStreaming(c.id)
+}
+
+func (c djiCamera) Preview(w http.ResponseWriter, r *http.Request) {
+	r = r.Clone(r.Context())
+	r.URL.Path = "/api/cameras/" + c.id + "/preview"
+	c.h.HandleCameraPreview(w, r)
+}
+
+// usbCamera is a USB webcam or capture card captured by ffmpeg
+type usbCamera struct {
+	h     *Handler
+	state *usbcam.CameraState
+}
+
+func (c usbCamera) Status() CameraStatus {
+	return CameraStatus{
+		ID:        c.state.Camera.ID,
+		Kind:      CameraKindUSB,
+		Name:      c.state.Camera.Name,
+		Connected: true,
+		Streaming: c.state.State == usbcam.StateStreaming,
+		State:     string(c.state.State),
+		Error:     c.state.LastError,
+		Actions:   cameraActions,
+	}
+}
+
+// Connect does nothing; a USB camera is usable as soon as it is found
+func (c usbCamera) Connect(ctx context.Context) error {
+	return nil
+}
+
+func (c usbCamera) Configure(ctx context.Context, s CameraSettings) error {
+	req, err := c.request(s)
+	if err != nil {
+		return err
+	}
+	return c.h.saveUSBCameraSettings(c.state.Camera.ID, req)
+}
+
+func (c usbCamera) Start(ctx context.Context, s CameraSettings) error {
+	req, err := c.request(s)
+	if err != nil {
+		return err
+	}
+	release, err := c.h.acquirePipeline("usbcam_start")
+	if err != nil {
+		return err
+	}
+	defer release()
+	return c.h.startUSBCamera(c.state.Camera.ID, req)
+}
+
+func (c usbCamera) Stop(ctx context.Context) error {
+	release, err := c.h.acquirePipeline("usbcam_stop")
+	if err != nil {
+		return err
+	}
+	defer release()
+	return c.h.stopUSBCamera(c.state.Camera.ID)
+}
+
+func (c usbCamera) Preview(w http.ResponseWriter, r *http.Request) {
+	r.SetPathValue("id", c.state.Camera.ID)
+	c.h.HandleUSBCameraPreview(w, r)
+}
+
+// request converts s to the USB camera start settings
+func (c usbCamera) request(s CameraSettings) (USBCameraStartRequest, error) {
+	width, height, err := parseResolution(s.Resolution)
+	if err != nil {
+		return USBCameraStartRequest{}, validate.Errors{{Field: "resolution", Message: err.Error()}}
+	}
+	req := USBCameraStartRequest{Width: width, Height: height, FPS: s.FPS, Bitrate: s.BitrateKbps, Encoder: s.Encoder}
+	return req, validateUSBCameraStart(req)
+}
+
+// rtmpCamera is whatever camera pushes to one of the RTMP stream keys. It
+// is started and stopped on the camera itself, so only its status and
+// preview are available here.
+type rtmpCamera struct {
+	h      *Handler
+	ingest config.RTMPIngestConfig
+}
+
+func (c rtmpCamera) Status() CameraStatus {
+	status := CameraStatus{
+		ID:      c.ingest.Name,
+		Kind:    CameraKindRTMP,
+		Name:    fmt.Sprintf("RTMP %s (port %d)", c.ingest.Name, c.ingest.ListenPort),
+		State:   "waiting",
+		Actions: []string{CameraActionPreview},
+	}
+	if c.pushing() {
+		status.Connected, status.Streaming = true, true
+		status.State = "pushing"
+	}
+	return status
+}
+
+// pushing reports whether a camera is sending to the stream key, from the
+// failover switch when it is running and from ffmpeg otherwise
+func (c rtmpCamera) pushing() bool {
+	c.h.failoverMu.Lock()
+	sw := c.h.failoverSwitch
+	c.h.failoverMu.Unlock()
+	if sw == nil {
+		return c.h.ffmpeg.Publishers().Connected
+	}
+	for _, src := range sw.Status().Sources {
+		if src.Name == c.ingest.Name {
+			return src.Live
+		}
+	}
+	return false
+}
+
+func (c rtmpCamera) Connect(ctx context.Context) error {
+	return errCameraUnsupported
+}
+
+func (c rtmpCamera) Configure(ctx context.Context, s CameraSettings) error {
+	return errCameraUnsupported
+}
+
+func (c rtmpCamera) Start(ctx context.Context, s CameraSettings) error {
+	return errCameraUnsupported
+}
+
+func (c rtmpCamera) Stop(ctx context.Context) error {
+	return errCameraUnsupported
+}
+
+// Preview points at the receive-mode HLS output, which shows the camera
+// while it is the one being forwarded
+func (c rtmpCamera) Preview(w http.ResponseWriter, r *http.Request) {
+	if !c.pushing() {
+		jsonError(w, "No camera is pushing to this stream key", http.StatusConflict)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":      "preview_streaming",
+		"camera":      c.ingest.Name,
+		"preview_url": c.h.previewURL("/preview/playlist.m3u8"),
+	})
+}
+
+// rtmpIngests returns the stream keys RTMP cameras can push to, the main one
+// first
+func (h *Handler) rtmpIngests() []config.RTMPIngestConfig {
+	return rtmpStreamKeys(h.config.Get())
+}
+
+// cameras returns every known camera: DJI cameras that were discovered,
+// paired or saved, the USB cameras found and one per RTMP stream key
+func (h *Handler) cameras() []Camera {
+	var cameras []Camera
+
+	seen := make(map[string]bool)
+	var djiIDs []string
+	addDJI := func(id string) {
+		if !seen[id] {
+			seen[id] = true
+			djiIDs = append(djiIDs, id)
+		}
+	}
+	for _, device := range h.djiScanner.GetDiscoveredDevices() {
+		addDJI(device.ID)
+	}
+	if paired, err := h.djiScanner.GetPairedDJIDevices(); err == nil {
+		for _, device := range paired {
+			addDJI(device.ID)
+		}
+	}
+	for id := range h.config.GetAllCameraConfigs() {
+		addDJI(id)
+	}
+	sort.Strings(djiIDs)
+	for _, id := range djiIDs {
+		cameras = append(cameras, djiCamera{h: h, id: id})
+	}
+
+	if h.usbCamController != nil {
+		for _, state := range h.usbCamController.GetCameras() {
+			if state.Camera != nil {
+				cameras = append(cameras, usbCamera{h: h, state: state})
+			}
+		}
+	}
+
+	for _, ingest := range h.rtmpIngests() {
+		cameras = append(cameras, rtmpCamera{h: h, ingest: ingest})
+	}
+	return cameras
+}
+
+// camera returns the camera of a kind with an ID. DJI cameras don't have to
+// have been seen yet, since connecting is how they are found.
+func (h *Handler) camera(kind, id string) (Camera, bool) {
+	switch kind {
+	case CameraKindDJI:
+		return djiCamera{h: h, id: id}, true
+	case CameraKindUSB:
+		if h.usbCamController == nil {
+			return nil, false
+		}
+		state := h.usbCamController.GetCameraState(id)
+		if state == nil || state.Camera == nil {
+			return nil, false
+		}
+		return usbCamera{h: h, state: state}, true
+	case CameraKindRTMP:
+		for _, ingest := range h.rtmpIngests() {
+			if ingest.Name == id {
+				return rtmpCamera{h: h, ingest: ingest}, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// HandleCameraV2List handles GET /api/v2/cameras
+func (h *Handler) HandleCameraV2List(w http.ResponseWriter, r *http.Request) {
+	cameras := h.cameras()
+	list := make([]CameraStatus, len(cameras))
+	for i, c := range cameras {
+		list[i] = c.Status()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// HandleCameraV2Get handles GET /api/v2/cameras/{kind}/{id}
+func (h *Handler) HandleCameraV2Get(w http.ResponseWriter, r *http.Request) {
+	c, ok := h.camera(r.PathValue("kind"), r.PathValue("id"))
+	if !ok {
+		jsonError(w, "Camera not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(c.Status())
+}
+
+// HandleCameraV2Action handles POST /api/v2/cameras/{kind}/{id}/{action},
+// running connect, configure, start, stop or preview on one camera. Except
+// for preview, the response is the camera's status afterwards.
+func (h *Handler) HandleCameraV2Action(w http.ResponseWriter, r *http.Request) {
+	kind, id, action := r.PathValue("kind"), r.PathValue("id"), r.PathValue("action")
+
+	v := validate.New()
+	v.OneOf("action", action, cameraActions...)
+	if err := v.Err(); err != nil {
+		validationError(w, err)
+		return
+	}
+	c, ok := h.camera(kind, id)
+	if !ok {
+		jsonError(w, "Camera not found", http.StatusNotFound)
+		return
+	}
+	if action == CameraActionPreview {
+		c.Preview(w, r)
+		return
+	}
+
+	var settings CameraSettings
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+			jsonError(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	var err error
+	switch action {
+	case GroupActionConnect:
+		err = c.Connect(r.Context())
+	case GroupActionConfigure:
+		err = c.Configure(r.Context(), settings)
+	case GroupActionStart:
+		err = c.Start(r.Context(), settings)
+	case GroupActionStop:
+		err = c.Stop(r.Context())
+	}
+	if err != nil {
+		cameraError(w, action, err)
+		return
+	}
+	h.logOutput("manager", fmt.Sprintf("[CAMERAS] %s on %s camera %s", action, kind, id))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(c.Status())
+}
+
+// cameraError reports a failed camera action with the status its cause calls for
+func cameraError(w http.ResponseWriter, action string, err error) {
+	var busy *PipelineBusyError
+	var fields validate.Errors
+	switch {
+	case errors.Is(err, errCameraUnsupported):
+		jsonError(w, strings.ToUpper(action[:1])+action[1:]+" is "+err.Error(), http.StatusNotImplemented)
+	case errors.As(err, &busy), errors.Is(err, errStreamActive), errors.Is(err, errFailoverSource):
+		pipelineError(w, err, http.StatusConflict)
+	case errors.As(err, &fields):
+		validationError(w, err)
+	default:
+		jsonError(w, fmt.Sprintf("Failed to %s: %v", action, err), http.StatusBadGateway)
+	}
+}
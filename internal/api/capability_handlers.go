@@ -0,0 +1,121 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"sort"
+
+	"srtla-manager/internal/capability"
+	"srtla-manager/internal/process"
+	"srtla-manager/internal/system"
+)
+
+// djiModes are the modes a DJI camera streams in. The pipeline passes its
+// H.264 through, so copy is the only encoder offered for it.
+var djiModes = func() []capability.Mode {
+	var modes []capability.Mode
+	for _, size := range [][2]int{{854, 480}, {1280, 720}, {1920, 1080}} {
+		for _, fps := range []int{25, 30} {
+			modes = append(modes, capability.Mode{PixelFormat: "H264", Width: size[0], Height: size[1], FPS: fps})
+		}
+	}
+	return modes
+}()
+
+// SourceCapabilities lists the combinations a source can be streamed with
+type SourceCapabilities struct {
+	Kind         string                   `json:"kind"` // usb or dji
+	ID           string                   `json:"id"`
+	Name         string                   `json:"name"`
+	Combinations []capability.Combination `json:"combinations"`
+}
+
+// CapabilitiesResponse is the response for GET /api/capabilities
+type CapabilitiesResponse struct {
+	Host    capability.Host      `json:"host"`
+	Sources []SourceCapabilities `json:"sources"`
+}
+
+// HostCapabilities returns the machine's CPU class and usable encoders
+func HostCapabilities() capability.Host {
+	model := system.CPUModel()
+	return capability.Host{
+		Model:    model,
+		Arch:     runtime.GOARCH,
+		Cores:    runtime.NumCPU(),
+		Class:    capability.Classify(model, runtime.NumCPU(), runtime.GOARCH),
+		Encoders: process.AvailableEncoders(),
+	}
+}
+
+// HandleCapabilities handles GET /api/capabilities. For each USB camera
+// found by the last scan and each known DJI camera it returns every
+// resolution, frame rate and encoder combination with whether it can be
+// streamed here. ?supported=true leaves out the ones that can't.
+func (h *Handler) HandleCapabilities(w http.ResponseWriter, r *http.Request) {
+	host := HostCapabilities()
+	onlySupported := r.URL.Query().Get("supported") == "true"
+	filter := func(combinations []capability.Combination) []capability.Combination {
+		if !onlySupported {
+			return combinations
+		}
+		supported := []capability.Combination{}
+		for _, c := range combinations {
+			if c.Supported {
+				supported = append(supported, c)
+			}
+		}
+		return supported
+	}
+
+	resp := CapabilitiesResponse{Host: host, Sources: []SourceCapabilities{}}
+
+	if h.usbCamController != nil {
+		for _, state := range h.usbCamController.GetCameras() {
+			if state.Camera == nil {
+				continue
+			}
+			var modes []capability.Mode
+			for _, f := range state.Camera.Formats {
+				for _, fps := range f.FPS {
+					modes = append(modes, capability.Mode{PixelFormat: f.PixelFormat, Width: f.Width, Height: f.Height, FPS: fps})
+				}
+			}
+			resp.Sources = append(resp.Sources, SourceCapabilities{
+				Kind:         "usb",
+				ID:           state.Camera.ID,
+				Name:         state.Camera.Name,
+				Combinations: filter(capability.Matrix(modes, process.Encoders, host)),
+			})
+		}
+	}
+
+	dji := make(map[string]string)
+	for _, device := range h.djiScanner.GetDiscoveredDevices() {
+		dji[device.ID] = device.Name
+	}
+	for id, cfg := range h.config.GetAllCameraConfigs() {
+		if cfg.Name != "" || dji[id] == "" {
+			dji[id] = cfg.Name
+		}
+	}
+	for id, name := range dji {
+		resp.Sources = append(resp.Sources, SourceCapabilities{
+			Kind:         "dji",
+			ID:           id,
+			Name:         name,
+			Combinations: filter(capability.Matrix(djiModes, []string{"copy"}, host)),
+		})
+	}
+
+	sort.SliceStable(resp.Sources, func(i, j int) bool {
+		if resp.Sources[i].Kind != resp.Sources[j].Kind {
+			return resp.Sources[i].Kind > resp.Sources[j].Kind
+		}
+		return resp.Sources[i].ID < resp.Sources[j].ID
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
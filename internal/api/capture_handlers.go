@@ -0,0 +1,102 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"srtla-manager/internal/capture"
+	"srtla-manager/internal/logger"
+)
+
+// CaptureResponse describes the passthrough capture
+type CaptureResponse struct {
+	Enabled bool `json:"enabled"`
+	capture.Stats
+}
+
+// ApplyCaptureConfig starts or stops the passthrough recorder to match the
+// configuration. ffmpeg picks up the change the next time it starts streaming.
+func (h *Handler) ApplyCaptureConfig() {
+	cfg := h.config.Get().Capture
+	window := time.Duration(cfg.Window) * time.Second
+
+	h.captureMu.Lock()
+	defer h.captureMu.Unlock()
+
+	if h.capture != nil && cfg.Enabled && h.captureDir == cfg.Dir && h.captureWindow == window {
+		return
+	}
+	if h.capture != nil {
+		h.capture.Stop()
+		h.capture = nil
+		h.ffmpeg.SetPassthroughCapture("")
+	}
+	if !cfg.Enabled {
+		return
+	}
+
+	recorder := capture.NewRecorder(cfg.Dir, window)
+	addr, err := recorder.Start()
+	if err != nil {
+		logger.Error("Capture: %v", err)
+		return
+	}
+	h.capture = recorder
+	h.captureDir = cfg.Dir
+	h.captureWindow = window
+	h.ffmpeg.SetPassthroughCapture(addr)
+
+	h.logOutput("manager", fmt.Sprintf("[CAPTURE] Keeping the last %ds of sent TS in %s (applies from the next stream start)", cfg.Window, cfg.Dir))
+}
+
+func (h *Handler) captureRecorder() *capture.Recorder {
+	h.captureMu.Lock()
+	defer h.captureMu.Unlock()
+	return h.capture
+}
+
+// HandleCaptureStatus handles GET /api/capture
+func (h *Handler) HandleCaptureStatus(w http.ResponseWriter, r *http.Request) {
+	resp := CaptureResponse{Stats: capture.Stats{Segments: []capture.Segment{}}}
+	if recorder := h.captureRecorder(); recorder != nil {
+		resp.Enabled = true
+		resp.Stats = recorder.Stats()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// HandleCaptureDownload handles GET /api/capture/download, returning the
+// captured window as a single TS file
+func (h *Handler) HandleCaptureDownload(w http.ResponseWriter, r *http.Request) {
+	recorder := h.captureRecorder()
+	if recorder == nil {
+		jsonError(w, "Passthrough capture is not enabled", http.StatusNotFound)
+		return
+	}
+	if len(recorder.Stats().Segments) == 0 {
+		jsonError(w, "Nothing has been captured yet", http.StatusNotFound)
+		return
+	}
+
+	filename := fmt.Sprintf("srtla-capture-%s.ts", time.Now().Format("20060102-150405"))
+	w.Header().Set("Content-Type", "video/mp2t")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	if _, err := recorder.WriteTo(w); err != nil {
+		logger.Warn("Capture: download interrupted: %v", err)
+	}
+}
+
+// HandleCaptureClear handles DELETE /api/capture
+func (h *Handler) HandleCaptureClear(w http.ResponseWriter, r *http.Request) {
+	recorder := h.captureRecorder()
+	if recorder == nil {
+		jsonError(w, "Passthrough capture is not enabled", http.StatusNotFound)
+		return
+	}
+	recorder.Clear()
+	w.WriteHeader(http.StatusNoContent)
+}
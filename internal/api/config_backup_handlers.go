@@ -0,0 +1,184 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"srtla-manager/internal/apikey"
+	"srtla-manager/internal/config"
+	"srtla-manager/internal/confighistory"
+	"srtla-manager/internal/logger"
+	"srtla-manager/internal/validate"
+)
+
+// maxConfigImport bounds the size of an imported configuration file
+const maxConfigImport = 1 << 20
+
+// ConfigImportResponse is the response of POST /api/config/import and of
+// a rollback
+type ConfigImportResponse struct {
+	Status string               `json:"status"`           // valid for a dry run, otherwise imported or rolled_back
+	Backup *confighistory.Entry `json:"backup,omitempty"` // the config that was replaced, kept in the history
+}
+
+// replaceConfig saves cfg in place of the current config, after keeping a
+// copy of the current one in the history. Nothing changes if cfg isn't
+// valid or the copy couldn't be kept.
+func (h *Handler) replaceConfig(cfg config.Config, reason string) (*confighistory.Entry, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	data, err := h.config.Marshal()
+	if err != nil {
+		return nil, fmt.Errorf("failed to back up the current config: %w", err)
+	}
+	entry, err := h.configHistory.Save(data, reason)
+	if entry.ID == "" {
+		return nil, fmt.Errorf("failed to back up the current config: %w", err)
+	}
+	if err != nil {
+		logger.Warn("Failed to prune the config history: %v", err)
+	}
+	return &entry, h.config.Update(cfg)
+}
+
+// configError writes the error of replaceConfig
+func configError(w http.ResponseWriter, err error) {
+	var fields validate.Errors
+	if errors.As(err, &fields) {
+		validationError(w, err)
+		return
+	}
+	jsonError(w, fmt.Sprintf("Failed to update config: %v", err), http.StatusInternalServerError)
+}
+
+// writeConfigFile sends a configuration file as a download
+func writeConfigFile(w http.ResponseWriter, data []byte, filename string) {
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.Write(data)
+}
+
+// HandleConfigExport handles GET /api/config/export: the whole configuration
+// file, camera settings and API key hashes included, to import on another
+// unit
+func (h *Handler) HandleConfigExport(w http.ResponseWriter, r *http.Request) {
+	data, err := h.config.Marshal()
+	if err != nil {
+		jsonError(w, fmt.Sprintf("Failed to export config: %v", err), http.StatusInternalServerError)
+		return
+	}
+	writeConfigFile(w, data, fmt.Sprintf("srtla-manager-config-%s.yaml", time.Now().Format("20060102-150405")))
+}
+
+// HandleConfigImport handles POST /api/config/import with an exported
+// configuration file as the body. The file must be valid as a whole; the
+// config it replaces is kept in the history first. With ?dry_run=true it is
+// only checked. API keys are only taken from the file when the request
+// carries no key itself, since no key may manage keys.
+func (h *Handler) HandleConfigImport(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxConfigImport))
+	if err != nil {
+		jsonError(w, fmt.Sprintf("Failed to read config: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	v := validate.New()
+	cfg, err := config.Parse(data)
+	if err != nil {
+		v.Addf("config", "%v", err)
+		validationError(w, v.Err())
+		return
+	}
+	if apikey.FromRequest(r) != "" {
+		cfg.Access.APIKeys = h.config.Get().Access.APIKeys
+	}
+
+	if r.URL.Query().Get("dry_run") == "true" {
+		if err := cfg.Validate(); err != nil {
+			validationError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(ConfigImportResponse{Status: "valid"})
+		return
+	}
+
+	backup, err := h.replaceConfig(cfg, "import")
+	if err != nil {
+		configError(w, err)
+		return
+	}
+	h.applyConfig(cfg)
+
+	h.logOutput("manager", fmt.Sprintf("[CONFIG] Imported configuration; the previous one is kept as %s", backup.ID))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ConfigImportResponse{Status: "imported", Backup: backup})
+}
+
+// HandleConfigHistory handles GET /api/config/history, newest first
+func (h *Handler) HandleConfigHistory(w http.ResponseWriter, r *http.Request) {
+	entries, err := h.configHistory.List()
+	if err != nil {
+		jsonError(w, fmt.Sprintf("Failed to list config history: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
+
+// HandleConfigHistoryGet handles GET /api/config/history/{id}, downloading
+// the config kept
+func (h *Handler) HandleConfigHistoryGet(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	data, err := h.configHistory.Read(id)
+	if errors.Is(err, confighistory.ErrNotFound) {
+		jsonError(w, "Config not found in the history", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		jsonError(w, fmt.Sprintf("Failed to read config: %v", err), http.StatusInternalServerError)
+		return
+	}
+	writeConfigFile(w, data, "srtla-manager-config-"+id+".yaml")
+}
+
+// HandleConfigRollback handles POST /api/config/history/{id}/rollback,
+// putting the config kept back in place. The current config is kept in the
+// history first, so a rollback can itself be undone. The current API keys
+// stay as they are, so a rollback can't bring back a revoked key.
+func (h *Handler) HandleConfigRollback(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	data, err := h.configHistory.Read(id)
+	if errors.Is(err, confighistory.ErrNotFound) {
+		jsonError(w, "Config not found in the history", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		jsonError(w, fmt.Sprintf("Failed to read config: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	cfg, err := config.Parse(data)
+	if err != nil {
+		jsonError(w, fmt.Sprintf("Config %s can't be read: %v", id, err), http.StatusConflict)
+		return
+	}
+	cfg.Access.APIKeys = h.config.Get().Access.APIKeys
+
+	backup, err := h.replaceConfig(cfg, "rollback")
+	if err != nil {
+		configError(w, err)
+		return
+	}
+	h.applyConfig(cfg)
+
+	h.logOutput("manager", fmt.Sprintf("[CONFIG] Rolled back to %s; the previous config is kept as %s", id, backup.ID))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ConfigImportResponse{Status: "rolled_back", Backup: backup})
+}
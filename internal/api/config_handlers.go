@@ -2,33 +2,68 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 
+	"srtla-manager/internal/config"
 	"srtla-manager/internal/process"
+	"srtla-manager/internal/validate"
 )
 
+// HandleSRTLAIPs handles GET and PUT /api/srtla/ips. A PUT with policies
+// replaces the link policies along with the IPs.
 func (h *Handler) HandleSRTLAIPs(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPut {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(h.srtlaIPsStatus())
+		return
+	case http.MethodPut:
+	default:
+		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
 	var req struct {
-		IPs []string `json:"ips"`
+		IPs      []string                     `json:"ips"`
+		Policies map[string]config.LinkPolicy `json:"policies"` // omitted keeps the current policies
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		jsonError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	if err := h.config.UpdateBindIPs(req.IPs); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	v := validate.New()
+	for i, ip := range req.IPs {
+		v.IP(fmt.Sprintf("ips[%d]", i), ip)
+	}
+	if err := v.Err(); err != nil {
+		validationError(w, err)
 		return
 	}
 
-	if h.srtla.ProcessState() == process.StateRunning {
-		if err := h.srtla.ReloadIPs(req.IPs); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+	if req.Policies != nil {
+		cfg := h.config.Get()
+		cfg.SRTLA.BindIPs = req.IPs
+		cfg.SRTLA.LinkPolicies = req.Policies
+		if err := h.config.Update(cfg); err != nil {
+			validationError(w, err)
+			return
+		}
+	} else if err := h.config.UpdateBindIPs(req.IPs); err != nil {
+		jsonError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// a stream started with a named set keeps using it
+	if h.srtla.ProcessState() == process.StateRunning && h.streamBindSet() == "" {
+		cfg := h.config.Get()
+		ips := h.getAvailableBindIPs(&cfg)
+		if len(ips) == 0 {
+			ips = req.IPs
+		}
+		if err := h.reloadSRTLAIPs(ips); err != nil {
+			jsonError(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 	}
@@ -53,12 +88,12 @@ func (h *Handler) HandleIPsFile(w http.ResponseWriter, r *http.Request) {
 			FilePath string `json:"file_path"`
 		}
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			jsonError(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 
 		if err := h.config.UpdateBindIPsFile(req.FilePath); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			jsonError(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 
@@ -66,19 +101,19 @@ func (h *Handler) HandleIPsFile(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(map[string]string{"status": "updated"})
 
 	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
 	}
 }
 
 func (h *Handler) HandleIPsFileLoad(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
 	ips, err := h.config.LoadBindIPsFromFile()
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		jsonError(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
@@ -91,12 +126,12 @@ func (h *Handler) HandleIPsFileLoad(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handler) HandleIPsFileSave(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
 	if err := h.config.SaveBindIPsToFile(); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		jsonError(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
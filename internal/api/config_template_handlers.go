@@ -0,0 +1,253 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"srtla-manager/internal/config"
+	"srtla-manager/internal/configtmpl"
+	"srtla-manager/internal/logger"
+	"srtla-manager/internal/validate"
+)
+
+// StagedTemplate is a rendered and validated configuration template waiting
+// to be applied. A controller stages a template on every unit first and only
+// applies it once all of them accepted it.
+type StagedTemplate struct {
+	ID        string    `json:"id"`
+	Sections  []string  `json:"sections"`  // top-level sections the template sets
+	Variables []string  `json:"variables"` // variables it was rendered with
+	StagedAt  time.Time `json:"staged_at"`
+
+	config config.Config
+}
+
+// TemplateResult reports applying or rolling back a template
+type TemplateResult struct {
+	Action     string          `json:"action"` // "apply" or "rollback"
+	ID         string          `json:"id,omitempty"`
+	Success    bool            `json:"success"`
+	RolledBack bool            `json:"rolled_back,omitempty"` // the self-test failed and the old config is back
+	Message    string          `json:"message"`
+	SelfTest   []SelfTestCheck `json:"self_test,omitempty"`
+	At         time.Time       `json:"at"`
+}
+
+// TemplateStatus is the response for GET /api/config/template
+type TemplateStatus struct {
+	Staged      *StagedTemplate `json:"staged,omitempty"`
+	CanRollback bool            `json:"can_rollback"`
+	Last        *TemplateResult `json:"last,omitempty"`
+}
+
+// TemplateStageRequest is the body of POST /api/config/template/stage
+type TemplateStageRequest struct {
+	Template  json.RawMessage   `json:"template"`
+	Variables map[string]string `json:"variables"`
+}
+
+// HandleTemplateStatus handles GET /api/config/template
+func (h *Handler) HandleTemplateStatus(w http.ResponseWriter, r *http.Request) {
+	h.templateMu.Lock()
+	status := TemplateStatus{Staged: h.templateStaged, CanRollback: h.templatePrev != nil, Last: h.templateLast}
+	h.templateMu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// HandleTemplateStage handles POST /api/config/template/stage. The template
+// is rendered with the unit's variables over the current config and
+// validated, but nothing changes until it is applied. Staging again
+// replaces what was staged.
+func (h *Handler) HandleTemplateStage(w http.ResponseWriter, r *http.Request) {
+	var req TemplateStageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+	v := validate.New()
+	v.Required("template", string(req.Template))
+	if err := v.Err(); err != nil {
+		validationError(w, err)
+		return
+	}
+
+	// the template is decoded over a copy, since the config Get returns
+	// shares its slices and maps with the live one
+	var cfg config.Config
+	var sections []string
+	rendered, err := configtmpl.Render(req.Template, req.Variables)
+	if err == nil {
+		cfg, sections, err = configtmpl.Overlay(h.config.Get(), rendered)
+	}
+	if err != nil {
+		v.Addf("template", "%v", err)
+		validationError(w, v.Err())
+		return
+	}
+	// API keys are only managed through /api/access/keys, as with PUT /api/config
+	cfg.Access.APIKeys = h.config.Get().Access.APIKeys
+	if err := cfg.Validate(); err != nil {
+		validationError(w, err)
+		return
+	}
+
+	id, err := newTemplateID()
+	if err != nil {
+		jsonError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	staged := &StagedTemplate{
+		ID:        id,
+		Variables: configtmpl.Variables(req.Template),
+		Sections:  sections,
+		StagedAt:  time.Now(),
+		config:    cfg,
+	}
+
+	h.templateMu.Lock()
+	h.templateStaged = staged
+	h.templateMu.Unlock()
+
+	logger.Info("Config template %s staged (%v)", id, staged.Sections)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(staged)
+}
+
+// HandleTemplateDiscard handles DELETE /api/config/template/stage
+func (h *Handler) HandleTemplateDiscard(w http.ResponseWriter, r *http.Request) {
+	h.templateMu.Lock()
+	h.templateStaged = nil
+	h.templateMu.Unlock()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleTemplateApply handles POST /api/config/template/apply. The staged
+// template with the given id is saved and applied, then the self-test
+// runs; if it fails the previous config is put back and applied.
+func (h *Handler) HandleTemplateApply(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	h.templateMu.Lock()
+	defer h.templateMu.Unlock()
+
+	staged := h.templateStaged
+	if staged == nil || staged.ID != req.ID {
+		jsonError(w, fmt.Sprintf("No staged template with id %q", req.ID), http.StatusConflict)
+		return
+	}
+	h.templateStaged = nil
+
+	prev := h.config.Get()
+	result := &TemplateResult{Action: "apply", ID: staged.ID, At: time.Now()}
+	if err := h.config.Update(staged.config); err != nil {
+		result.Message = fmt.Sprintf("Failed to save config: %v", err)
+		h.templateLast = result
+		h.writeTemplateResult(w, result)
+		return
+	}
+	h.applyConfig(staged.config)
+	h.templatePrev = &prev
+
+	result.SelfTest = h.configSelfTest()
+	if check, failed := failedCheck(result.SelfTest); failed {
+		result.RolledBack = h.rollbackTemplateLocked() == nil
+		result.Message = fmt.Sprintf("Self-test failed: %s %s", check.Name, check.Detail)
+		if !result.RolledBack {
+			result.Message += ", and the rollback failed"
+		}
+	} else {
+		result.Success = true
+		result.Message = "Applied"
+	}
+	h.templateLast = result
+	h.writeTemplateResult(w, result)
+}
+
+// HandleTemplateRollback handles POST /api/config/template/rollback. It
+// puts back the config from before the last applied template, so a
+// controller can undo units that applied a template another unit rejected.
+func (h *Handler) HandleTemplateRollback(w http.ResponseWriter, r *http.Request) {
+	h.templateMu.Lock()
+	defer h.templateMu.Unlock()
+
+	if h.templatePrev == nil {
+		jsonError(w, "No applied template to roll back", http.StatusConflict)
+		return
+	}
+
+	result := &TemplateResult{Action: "rollback", At: time.Now()}
+	if err := h.rollbackTemplateLocked(); err != nil {
+		result.Message = fmt.Sprintf("Failed to restore config: %v", err)
+	} else {
+		result.Success = true
+		result.Message = "Rolled back"
+	}
+	h.templateLast = result
+	h.writeTemplateResult(w, result)
+}
+
+// rollbackTemplateLocked saves and applies the config from before the last
+// template. h.templateMu must be held.
+func (h *Handler) rollbackTemplateLocked() error {
+	prev := *h.templatePrev
+	if err := h.config.Update(prev); err != nil {
+		var fields validate.Errors
+		if errors.As(err, &fields) {
+			logger.Error("Config template rollback failed: %v", err)
+			return err
+		}
+		// the old config is in effect but couldn't be written to disk
+		h.applyConfig(prev)
+		logger.Error("Config template rollback failed: %v", err)
+		return err
+	}
+	h.applyConfig(prev)
+	h.templatePrev = nil
+	logger.Warn("Config template rolled back")
+	return nil
+}
+
+func (h *Handler) writeTemplateResult(w http.ResponseWriter, result *TemplateResult) {
+	if result.Success {
+		logger.Info("Config template %s: %s", result.Action, result.Message)
+	} else {
+		logger.Warn("Config template %s: %s", result.Action, result.Message)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !result.Success {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	json.NewEncoder(w).Encode(result)
+}
+
+// failedCheck returns the first check that failed
+func failedCheck(checks []SelfTestCheck) (SelfTestCheck, bool) {
+	for _, check := range checks {
+		if !check.OK {
+			return check, true
+		}
+	}
+	return SelfTestCheck{}, false
+}
+
+func newTemplateID() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
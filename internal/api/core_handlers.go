@@ -2,24 +2,28 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"time"
 
 	"srtla-manager/internal/config"
 	"srtla-manager/internal/logger"
+	"srtla-manager/internal/process"
 	"srtla-manager/internal/system"
 )
 
 func (h *Handler) HandleStatus(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
 	ffStats := h.ffmpeg.Stats()
 	srtlaStats := h.srtla.Stats()
+	srtlaCaps := system.DetectSRTLA(h.config.Get().SRTLA.BinaryPath)
 
 	resp := StatusResponse{
 		Uptime:       int64(h.uptime().Seconds()),
@@ -30,6 +34,7 @@ func (h *Handler) HandleStatus(w http.ResponseWriter, r *http.Request) {
 			Bitrate:      ffStats.Bitrate,
 			FPS:          ffStats.FPS,
 			Stale:        h.ffmpeg.IsStale(FFmpegStaleThreshold),
+			Ancillary:    ffStats.Ancillary,
 		},
 		SRTLA: SRTLAStatus{
 			ProcessState: string(h.srtla.ProcessState()),
@@ -37,8 +42,20 @@ func (h *Handler) HandleStatus(w http.ResponseWriter, r *http.Request) {
 			Bitrate:      srtlaStats.TotalBitrate,
 			Connections:  srtlaStats.Connections,
 			Stale:        h.srtla.IsStale(SRTLAStaleThreshold),
+			Version:      srtlaCaps.Version,
+			Features:     srtlaCaps.Features,
+			Transport:    h.ActiveTransport(),
 		},
-		History: h.stats.History(),
+		History:     h.stats.History(),
+		Loudness:    h.LoudnessStatus(),
+		Audio:       h.AudioStatus(),
+		Operation:   h.CurrentPipelineOperation(),
+		Switcher:    h.statusSwitcher(),
+		Timecode:    h.statusTimecode(),
+		SRT:         h.SRTStats(),
+		TestPattern: h.testPattern(),
+		LinkHealth:  h.linkHealth.Last(),
+		Degraded:    h.degradedStart(),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -47,7 +64,7 @@ func (h *Handler) HandleStatus(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handler) HandleConfigGet(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
@@ -58,43 +75,83 @@ func (h *Handler) HandleConfigGet(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handler) HandleConfigUpdate(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPut {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
 	var cfg config.Config
 	if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
-		http.Error(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
+		jsonError(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
 		return
 	}
 
-	if err := h.config.Update(cfg); err != nil {
-		http.Error(w, fmt.Sprintf("Failed to update config: %v", err), http.StatusInternalServerError)
+	// API keys are only managed through /api/access/keys, so an admin key
+	// can't grant itself more
+	cfg.Access.APIKeys = h.config.Get().Access.APIKeys
+
+	if _, err := h.replaceConfig(cfg, "update"); err != nil {
+		configError(w, err)
 		return
 	}
 
+	h.applyConfig(cfg)
+
 	logger.Info("Configuration updated successfully")
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"status": "updated"})
 }
 
+// applyConfig brings the running services in line with a configuration
+// that has just been saved
+func (h *Handler) applyConfig(cfg config.Config) {
+	h.ffmpeg.SetLoudnessMonitoring(cfg.Loudness.Enabled)
+	h.ffmpeg.SetAudioMeters(cfg.Loudness.Meters)
+	h.ffmpeg.SetAudioResync(cfg.RTMP.AudioResync)
+	h.ffmpeg.SetReconnectGrace(time.Duration(cfg.RTMP.ReconnectGraceSeconds) * time.Second)
+	logger.SetProcessFiles(cfg.Logging.ProcessFiles)
+	h.applyPowerProfile()
+	h.ApplyAudioConfig()
+	h.ApplyMetricsConfig()
+	h.ApplyAuditConfig()
+	h.ApplyCaptureConfig()
+	h.ApplyRestreamConfig()
+	h.ApplyFailoverConfig()
+	h.ApplyTalkbackConfig()
+	h.ApplyReturnFeedConfig()
+	h.ApplyReceiverConfig()
+	h.ApplyTimecodeConfig()
+	h.ApplyButtonsConfig()
+	h.ApplyDisplayConfig()
+	h.ApplyAlertsConfig()
+	h.ApplyNotifyConfig()
+	h.ApplyQuotaConfig()
+	h.ApplySwitcherConfig()
+	h.ApplyTallyConfig()
+	h.ApplyRouting()
+	h.ApplyLinkProbeConfig()
+	h.ApplyVPNConfig()
+	h.ApplyDiscoveryConfig()
+}
+
 type DependenciesResponse struct {
-	FFmpeg system.DependencyStatus `json:"ffmpeg"`
-	SRTLA  system.DependencyStatus `json:"srtla"`
-	OS     string                  `json:"os"`
+	FFmpeg   system.DependencyStatus     `json:"ffmpeg"`
+	SRTLA    system.DependencyStatus     `json:"srtla"`
+	OS       string                      `json:"os"`
+	Platform system.PlatformCapabilities `json:"platform"`
 }
 
 func (h *Handler) HandleDependencies(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
 	cfg := h.config.Get()
 	resp := DependenciesResponse{
-		FFmpeg: system.CheckFFmpeg(),
-		SRTLA:  system.CheckSRTLA(cfg.SRTLA.BinaryPath),
-		OS:     system.GetOSInfo(),
+		FFmpeg:   system.CheckFFmpeg(),
+		SRTLA:    system.CheckSRTLA(cfg.SRTLA.BinaryPath),
+		OS:       system.GetOSInfo(),
+		Platform: system.GetPlatformCapabilities(),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -103,7 +160,7 @@ func (h *Handler) HandleDependencies(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handler) HandleInterfaces(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
@@ -143,7 +200,7 @@ func (h *Handler) HandleInterfaces(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handler) HandleLogs(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
@@ -152,23 +209,39 @@ func (h *Handler) HandleLogs(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(logs)
 }
 
+// HandleLogsDownload handles GET /api/logs/download. ?process=ffmpeg (or
+// srtla_send, srtla_rec, ...) downloads that process's own log when
+// logging.process_files is on.
 func (h *Handler) HandleLogsDownload(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
 	// Get the log file path from the logger
 	logFilePath := logger.Get().GetFilePath()
 	if logFilePath == "" {
-		http.Error(w, "File logging is not enabled", http.StatusNotFound)
+		jsonError(w, "File logging is not enabled", http.StatusNotFound)
 		return
 	}
+	filename := "srtla-manager.log"
+	if name := r.URL.Query().Get("process"); name != "" {
+		logFilePath = logger.Get().ProcessFilePath(name)
+		if logFilePath == "" || !process.IsProcess(name) {
+			jsonError(w, fmt.Sprintf("Unknown process %q", name), http.StatusBadRequest)
+			return
+		}
+		filename = name + ".log"
+	}
 
 	// Open the log file
 	file, err := os.Open(logFilePath)
+	if errors.Is(err, os.ErrNotExist) {
+		jsonError(w, "Nothing has been logged yet", http.StatusNotFound)
+		return
+	}
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to open log file: %v", err), http.StatusInternalServerError)
+		jsonError(w, fmt.Sprintf("Failed to open log file: %v", err), http.StatusInternalServerError)
 		return
 	}
 	defer file.Close()
@@ -176,13 +249,13 @@ func (h *Handler) HandleLogsDownload(w http.ResponseWriter, r *http.Request) {
 	// Get file info for size
 	fileInfo, err := file.Stat()
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to stat log file: %v", err), http.StatusInternalServerError)
+		jsonError(w, fmt.Sprintf("Failed to stat log file: %v", err), http.StatusInternalServerError)
 		return
 	}
 
 	// Set headers for download
 	w.Header().Set("Content-Type", "text/plain")
-	w.Header().Set("Content-Disposition", "attachment; filename=\"srtla-manager.log\"")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
 	w.Header().Set("Content-Length", fmt.Sprintf("%d", fileInfo.Size()))
 
 	// Stream the file to the response
@@ -206,7 +279,7 @@ func (h *Handler) HandleDebugMode(w http.ResponseWriter, r *http.Request) {
 			Debug bool `json:"debug"`
 		}
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
+			jsonError(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
 			return
 		}
 
@@ -219,10 +292,16 @@ func (h *Handler) HandleDebugMode(w http.ResponseWriter, r *http.Request) {
 		})
 
 	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
 	}
 }
 
 func (h *Handler) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 	h.wsHub.HandleConnection(w, r)
 }
+
+// HandleWSStats reports per-topic broadcast and per-client WebSocket metrics.
+func (h *Handler) HandleWSStats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.wsHub.Stats())
+}
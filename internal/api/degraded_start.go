@@ -0,0 +1,84 @@
+package api
+
+import (
+	"time"
+)
+
+// DegradedStart describes a stream started on fewer links than it was
+// configured with, so automation can decide whether to hold the show
+type DegradedStart struct {
+	ConfiguredLinks int           `json:"configured_links"`
+	ActiveLinks     int           `json:"active_links"`
+	Missing         []MissingLink `json:"missing"`
+	// Upload the last speed test measured over the configured links and
+	// over the links the stream started on; absent without a speed test
+	ExpectedKbps float64    `json:"expected_kbps,omitempty"`
+	ActualKbps   float64    `json:"actual_kbps,omitempty"`
+	MeasuredAt   *time.Time `json:"measured_at,omitempty"` // when that speed test finished
+	Since        time.Time  `json:"since"`
+}
+
+// MissingLink is a configured bind IP a stream started without
+type MissingLink struct {
+	BindIP string `json:"bind_ip"`
+	Reason string `json:"reason"`
+}
+
+// StreamStartResponse is the response of POST /api/stream/start
+type StreamStartResponse struct {
+	Status   string         `json:"status"`
+	Degraded *DegradedStart `json:"degraded,omitempty"` // the stream started on fewer links than configured
+}
+
+// newDegradedStart reports a stream started on active of the configured
+// bind IPs, or returns nil if none of them are missing
+func (h *Handler) newDegradedStart(configured, active []string) *DegradedStart {
+	if len(configured) == 0 || len(active) >= len(configured) {
+		return nil
+	}
+
+	up := make(map[string]bool, len(active))
+	for _, ip := range active {
+		up[ip] = true
+	}
+	d := &DegradedStart{
+		ConfiguredLinks: len(configured),
+		ActiveLinks:     len(active),
+		Missing:         []MissingLink{},
+		Since:           time.Now(),
+	}
+	for _, ip := range configured {
+		if !up[ip] {
+			d.Missing = append(d.Missing, MissingLink{BindIP: ip, Reason: "address not on any interface"})
+		}
+	}
+
+	if last := h.lastSpeedTest(); last != nil {
+		measured := last.measured()
+		for _, ip := range configured {
+			d.ExpectedKbps += measured[ip]
+			if up[ip] {
+				d.ActualKbps += measured[ip]
+			}
+		}
+		if d.ExpectedKbps > 0 {
+			at := last.FinishedAt
+			d.MeasuredAt = &at
+		}
+	}
+	return d
+}
+
+// degradedStart returns the report of the running stream's start, or nil
+// if it started on all its links
+func (h *Handler) degradedStart() *DegradedStart {
+	h.transportMu.Lock()
+	defer h.transportMu.Unlock()
+	return h.degraded
+}
+
+func (h *Handler) setDegradedStart(d *DegradedStart) {
+	h.transportMu.Lock()
+	defer h.transportMu.Unlock()
+	h.degraded = d
+}
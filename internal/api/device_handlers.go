@@ -2,10 +2,15 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strings"
 
+	"srtla-manager/internal/events"
 	"srtla-manager/internal/modem"
+	"srtla-manager/internal/usbnet"
+	"srtla-manager/internal/validate"
 )
 
 func (h *Handler) HandleModems(w http.ResponseWriter, r *http.Request) {
@@ -16,7 +21,7 @@ func (h *Handler) HandleModems(w http.ResponseWriter, r *http.Request) {
 		parts := strings.Split(path, "/")
 		if len(parts) == 2 && parts[1] == "ussd" {
 			if r.Method != http.MethodPost {
-				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
 				return
 			}
 
@@ -24,19 +29,24 @@ func (h *Handler) HandleModems(w http.ResponseWriter, r *http.Request) {
 				Code string `json:"code"`
 			}
 			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-				http.Error(w, err.Error(), http.StatusBadRequest)
+				jsonError(w, err.Error(), http.StatusBadRequest)
 				return
 			}
 
 			code := strings.TrimSpace(req.Code)
-			if code == "" {
-				http.Error(w, "USSD code required", http.StatusBadRequest)
+			v := validate.New()
+			v.Required("code", code)
+			if strings.Trim(code, "0123456789*#+") != "" {
+				v.Addf("code", "may only contain digits, '*', '#' and '+'")
+			}
+			if err := v.Err(); err != nil {
+				validationError(w, err)
 				return
 			}
 
 			resp, err := h.modem.DialUSSD(parts[0], code)
 			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
+				jsonError(w, err.Error(), http.StatusInternalServerError)
 				return
 			}
 
@@ -48,18 +58,39 @@ func (h *Handler) HandleModems(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		if len(parts) == 2 && (parts[1] == "connect" || parts[1] == "disconnect" || parts[1] == "reset") {
+			if r.Method != http.MethodPost {
+				jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			switch parts[1] {
+			case "connect":
+				h.handleModemConnect(w, r, parts[0])
+			case "disconnect":
+				h.handleModemDisconnect(w, r, parts[0])
+			default:
+				h.handleModemReset(w, r, parts[0])
+			}
+			return
+		}
+
 		if r.Method != http.MethodGet {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if len(parts) == 2 && parts[1] == "history" {
+			h.handleModemHistory(w, r, parts[0])
 			return
 		}
 
 		info, err := h.modem.GetModem(path)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			jsonError(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 		if info == nil {
-			http.Error(w, "Modem not found", http.StatusNotFound)
+			jsonError(w, "Modem not found", http.StatusNotFound)
 			return
 		}
 		w.Header().Set("Content-Type", "application/json")
@@ -68,13 +99,13 @@ func (h *Handler) HandleModems(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
 	modems, err := h.modem.ListModems()
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		jsonError(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
@@ -92,7 +123,7 @@ func (h *Handler) HandleModems(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handler) HandleUSBNet(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
@@ -100,3 +131,52 @@ func (h *Handler) HandleUSBNet(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(resp)
 }
+
+// USBNetRenameRequest names a USB network device
+type USBNetRenameRequest struct {
+	Name string `json:"name"` // "" forgets the device
+}
+
+// HandleUSBNetRename handles PUT /api/usbnet/{id}
+func (h *Handler) HandleUSBNetRename(w http.ResponseWriter, r *http.Request) {
+	var req USBNetRenameRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := usbnet.ValidateName(strings.TrimSpace(req.Name)); err != nil {
+		validationError(w, err)
+		return
+	}
+	id := r.PathValue("id")
+	if err := h.usb.Rename(id, req.Name); err != nil {
+		h.usbNetError(w, err)
+		return
+	}
+	h.logOutput("manager", fmt.Sprintf("[USBNET] Named %s %q", id, strings.TrimSpace(req.Name)))
+	events.Publish(h.bus, TopicUSBNet, h.GetUSBNetStatus())
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.GetUSBNetStatus())
+}
+
+// HandleUSBNetForget handles DELETE /api/usbnet/{id}
+func (h *Handler) HandleUSBNetForget(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if err := h.usb.Forget(id); err != nil {
+		h.usbNetError(w, err)
+		return
+	}
+	h.logOutput("manager", fmt.Sprintf("[USBNET] Forgot %s", id))
+	events.Publish(h.bus, TopicUSBNet, h.GetUSBNetStatus())
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.GetUSBNetStatus())
+}
+
+// usbNetError reports an error from renaming or forgetting a device
+func (h *Handler) usbNetError(w http.ResponseWriter, err error) {
+	if errors.Is(err, usbnet.ErrUnknownDevice) {
+		jsonError(w, "USB network device not found", http.StatusNotFound)
+		return
+	}
+	jsonError(w, err.Error(), http.StatusInternalServerError)
+}
@@ -0,0 +1,179 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"srtla-manager/internal/config"
+	"srtla-manager/internal/discovery"
+	"srtla-manager/internal/events"
+	"srtla-manager/internal/logger"
+)
+
+// Device classes discovery scans for
+const (
+	DiscoveryUSBCam = "usbcam"
+	DiscoveryModem  = "modem"
+	DiscoveryUSBNet = "usbnet"
+	DiscoveryDJI    = "dji"
+)
+
+// discoveryDefault is how a class is scanned when its config leaves it
+// empty, which is how it was scanned before it could be chosen
+type discoveryDefault struct {
+	continuous bool
+	interval   time.Duration
+}
+
+var discoveryDefaults = map[string]discoveryDefault{
+	DiscoveryUSBCam: {false, 10 * time.Second},
+	DiscoveryModem:  {false, 10 * time.Second},
+	DiscoveryUSBNet: {true, 3 * time.Second},
+	DiscoveryDJI:    {false, 2 * time.Minute},
+}
+
+// djiDiscoveryScan is how long a background BLE scan for DJI cameras runs
+const djiDiscoveryScan = 10 * time.Second
+
+// discoveryScanTimeout bounds a scan asked for over the API
+const discoveryScanTimeout = 30 * time.Second
+
+// initDiscovery adds each device class to the scheduler, all scanned only
+// when asked until ApplyDiscoveryConfig
+func (h *Handler) initDiscovery() {
+	h.discovery = discovery.New(func(c discovery.Change) {
+		h.logOutput("manager", fmt.Sprintf("[DISCOVERY] %s: added %v, removed %v", c.Class, c.Added, c.Removed))
+		events.Publish(h.bus, TopicDiscovery, c)
+	})
+	h.discovery.Add(DiscoveryUSBCam, h.scanUSBCameras)
+	h.discovery.Add(DiscoveryModem, h.scanModems)
+	h.discovery.Add(DiscoveryUSBNet, h.scanUSBNet)
+	h.discovery.Add(DiscoveryDJI, h.scanDJI)
+}
+
+func (h *Handler) scanUSBCameras(ctx context.Context) ([]string, error) {
+	if h.usbCamController == nil {
+		return nil, errors.New("USB camera support not initialized")
+	}
+	cameras, err := h.usbCamController.ScanCameras()
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(cameras))
+	for i, c := range cameras {
+		ids[i] = c.ID
+	}
+	return ids, nil
+}
+
+func (h *Handler) scanModems(ctx context.Context) ([]string, error) {
+	modems, err := h.modem.ListModems()
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(modems))
+	for i, m := range modems {
+		ids[i] = m.ID
+	}
+	return ids, nil
+}
+
+func (h *Handler) scanUSBNet(ctx context.Context) ([]string, error) {
+	if h.usb == nil {
+		return nil, errors.New("usbnet reconciler not running")
+	}
+	if err := h.usb.Scan(ctx); err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, d := range h.usb.Status() {
+		ids = append(ids, d.ID)
+	}
+	return ids, nil
+}
+
+// scanDJI runs a BLE scan, or waits for the one already running, and
+// returns the cameras it found
+func (h *Handler) scanDJI(ctx context.Context) ([]string, error) {
+	if err := h.djiScanner.StartScanning(djiDiscoveryScan); err != nil && !h.djiScanner.IsScanning() {
+		return nil, err
+	}
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for h.djiScanner.IsScanning() {
+		select {
+		case <-ctx.Done():
+			h.djiScanner.StopScanning()
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	var ids []string
+	for _, d := range h.djiScanner.GetDiscoveredDevices() {
+		ids = append(ids, d.ID)
+	}
+	return ids, nil
+}
+
+// discoverySettings resolves a class's config against its defaults
+func discoverySettings(name string, cfg config.DiscoveryClassConfig) discovery.Settings {
+	def := discoveryDefaults[name]
+	settings := discovery.Settings{Continuous: def.continuous, Interval: def.interval}
+	switch cfg.Mode {
+	case config.DiscoveryContinuous:
+		settings.Continuous = true
+	case config.DiscoveryManual:
+		settings.Continuous = false
+	}
+	if cfg.IntervalSeconds > 0 {
+		settings.Interval = time.Duration(cfg.IntervalSeconds) * time.Second
+	}
+	return settings
+}
+
+// ApplyDiscoveryConfig starts or stops scanning each device class in the
+// background to match the configuration
+func (h *Handler) ApplyDiscoveryConfig() {
+	cfg := h.config.Get().Discovery
+	for _, name := range []string{DiscoveryUSBCam, DiscoveryModem, DiscoveryUSBNet, DiscoveryDJI} {
+		if err := h.discovery.Set(name, discoverySettings(name, cfg.Class(name))); err != nil {
+			logger.Warn("Discovery: %v", err)
+		}
+	}
+}
+
+// StopDiscovery stops the background scans on shutdown
+func (h *Handler) StopDiscovery() {
+	h.discovery.Stop()
+}
+
+// HandleDiscovery handles GET /api/discovery, returning how each device
+// class is scanned and what its last scan found
+func (h *Handler) HandleDiscovery(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.discovery.Status())
+}
+
+// HandleDiscoveryScan handles POST /api/discovery/{class}/scan, scanning a
+// class now whether or not it is scanned in the background
+func (h *Handler) HandleDiscoveryScan(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), discoveryScanTimeout)
+	defer cancel()
+
+	devices, err := h.discovery.Scan(ctx, r.PathValue("class"))
+	switch {
+	case errors.Is(err, discovery.ErrUnknownClass):
+		jsonError(w, err.Error(), http.StatusNotFound)
+		return
+	case err != nil:
+		jsonError(w, fmt.Sprintf("Scan failed: %v", err), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string][]string{"devices": devices})
+}
@@ -0,0 +1,230 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"srtla-manager/internal/display"
+	"srtla-manager/internal/logger"
+	"srtla-manager/internal/process"
+	"srtla-manager/internal/system"
+)
+
+// DisplayStatus describes the status display and what it shows
+type DisplayStatus struct {
+	Enabled   bool     `json:"enabled"`
+	Type      string   `json:"type"`
+	Width     int      `json:"width,omitempty"`
+	Height    int      `json:"height,omitempty"`
+	Lines     []string `json:"lines"`
+	LastError string   `json:"last_error,omitempty"`
+}
+
+// ApplyDisplayConfig opens the status display from the configuration. A
+// display already running with the same settings is left alone, so saving
+// unrelated settings doesn't flash an e-paper panel.
+func (h *Handler) ApplyDisplayConfig() {
+	cfg := h.config.Get().Display
+
+	h.displayMu.Lock()
+	unchanged := h.displayApplied != nil && *h.displayApplied == cfg && h.displayError == ""
+	h.displayMu.Unlock()
+	if unchanged {
+		return
+	}
+
+	h.StopDisplay()
+	if !cfg.Enabled {
+		return
+	}
+
+	var d display.Display
+	var err error
+	switch cfg.Type {
+	case display.TypeEPD2in13:
+		d, err = display.NewEPD2in13(cfg.SPIDevice, cfg.DCPin, cfg.ResetPin, cfg.BusyPin, cfg.Flip)
+	default:
+		d, err = display.NewSSD1306(cfg.I2CBus, cfg.I2CAddress, cfg.Height, cfg.Flip)
+	}
+
+	h.displayMu.Lock()
+	defer h.displayMu.Unlock()
+	h.displayApplied = &cfg
+	if err != nil {
+		h.displayError = err.Error()
+		logger.Error("Display: %v", err)
+		return
+	}
+
+	every := time.Duration(cfg.RefreshSeconds) * time.Second
+	if every == 0 {
+		every = display.DefaultRefresh[cfg.Type]
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	h.display = d
+	h.displayCancel = cancel
+	h.displayDone = done
+	go func() {
+		defer close(done)
+		h.runDisplay(ctx, d, every)
+	}()
+
+	h.logOutput("manager", fmt.Sprintf("[DISPLAY] Showing status on %s (%dx%d)", cfg.Type, d.Width(), d.Height()))
+}
+
+// StopDisplay leaves a stopped notice on the display and releases it
+func (h *Handler) StopDisplay() {
+	h.displayMu.Lock()
+	d, cancel, done := h.display, h.displayCancel, h.displayDone
+	h.display, h.displayCancel, h.displayDone = nil, nil, nil
+	h.displayLines = nil
+	h.displayError = ""
+	h.displayApplied = nil
+	h.displayMu.Unlock()
+
+	if d == nil {
+		return
+	}
+	cancel()
+	<-done
+	// e-paper keeps its last picture, so don't leave a live status on it
+	d.Show(display.Render(d.Width(), d.Height(), []string{"srtla-manager", "stopped"}))
+	d.Close()
+}
+
+// runDisplay redraws the display whenever what it shows changes, at most
+// once per interval
+func (h *Handler) runDisplay(ctx context.Context, d display.Display, every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+
+	var shown *display.Frame
+	for {
+		lines := h.statusLines()
+		frame := display.Render(d.Width(), d.Height(), lines)
+		if !frame.Equal(shown) {
+			err := d.Show(frame)
+			h.displayMu.Lock()
+			h.displayError = ""
+			if err != nil {
+				h.displayError = err.Error()
+			}
+			h.displayMu.Unlock()
+			if err != nil {
+				logger.Warn("Display: %v", err)
+			} else {
+				shown = frame
+			}
+		}
+		h.displayMu.Lock()
+		h.displayLines = lines
+		h.displayMu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// statusLines is what a headless unit needs at a glance: the pipeline
+// state, the addresses to reach the web UI on, the camera input and, while
+// streaming, each bonded link
+func (h *Handler) statusLines() []string {
+	mode := h.GetPipelineMode()
+	ff := h.ffmpeg.Stats()
+
+	var lines []string
+	switch mode {
+	case PipelineModeStreaming:
+		lines = append(lines, fmt.Sprintf("LIVE %.0f kbps", ff.Bitrate))
+	case PipelineModeReceiving:
+		lines = append(lines, "READY")
+	default:
+		lines = append(lines, "IDLE")
+	}
+
+	names := map[string]string{}
+	addresses := 0
+	for _, iface := range system.ListNetworkInterfaces() {
+		if iface.IsLoopback || !iface.IsUp {
+			continue
+		}
+		for _, ip := range iface.IPs {
+			names[ip] = iface.Name
+			lines = append(lines, fmt.Sprintf("%s %s", iface.Name, ip))
+			addresses++
+		}
+	}
+	if addresses == 0 {
+		lines = append(lines, "No network")
+	}
+
+	if ff.State == process.FFmpegConnected || ff.State == process.FFmpegStreaming {
+		lines = append(lines, fmt.Sprintf("CAM %.0f fps", ff.FPS))
+	} else {
+		lines = append(lines, "CAM none")
+	}
+
+	if mode == PipelineModeStreaming {
+		for _, c := range h.srtla.Stats().Connections {
+			name := names[c.IP]
+			if name == "" {
+				name = c.IP
+			}
+			lines = append(lines, fmt.Sprintf("%s %.0fk %s", name, c.Bitrate, c.State))
+		}
+	}
+	return lines
+}
+
+func (h *Handler) displayStatus() DisplayStatus {
+	cfg := h.config.Get().Display
+
+	h.displayMu.Lock()
+	defer h.displayMu.Unlock()
+
+	status := DisplayStatus{
+		Enabled:   cfg.Enabled,
+		Type:      cfg.Type,
+		Lines:     append([]string{}, h.displayLines...),
+		LastError: h.displayError,
+	}
+	if h.display != nil {
+		status.Width = h.display.Width()
+		status.Height = h.display.Height()
+	}
+	return status
+}
+
+// HandleDisplayStatus handles GET /api/display
+func (h *Handler) HandleDisplayStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.displayStatus())
+}
+
+// HandleDisplayUpdate handles PUT /api/display. Fields missing from the body
+// keep their current values.
+func (h *Handler) HandleDisplayUpdate(w http.ResponseWriter, r *http.Request) {
+	cfg := h.config.Get()
+	if err := json.NewDecoder(r.Body).Decode(&cfg.Display); err != nil {
+		jsonError(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.config.Update(cfg); err != nil {
+		validationError(w, err)
+		return
+	}
+
+	h.ApplyDisplayConfig()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.displayStatus())
+}
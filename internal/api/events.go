@@ -0,0 +1,218 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"srtla-manager/internal/config"
+	"srtla-manager/internal/discovery"
+	"srtla-manager/internal/events"
+	"srtla-manager/internal/jobs"
+	"srtla-manager/internal/logger"
+	"srtla-manager/internal/maintenance"
+	"srtla-manager/internal/process"
+)
+
+// PipelineModeChange reports the pipeline switching modes
+type PipelineModeChange struct {
+	From PipelineMode `json:"from"`
+	To   PipelineMode `json:"to"`
+}
+
+// PairingEvent reports the standby unit taking over
+type PairingEvent struct {
+	Event string `json:"event"` // takeover or takeover_failed
+	Error string `json:"error,omitempty"`
+}
+
+// InstallMessage is a progress message from an update or srtla_send
+// install job
+type InstallMessage struct {
+	JobID   string `json:"job_id"`
+	Kind    string `json:"kind"`  // update or srtla_install
+	Phase   string `json:"phase"` // prepare, download, verify, backup, install, done or installer_update
+	Percent int    `json:"percent"`
+	Level   string `json:"level"`
+	Message string `json:"message"`
+}
+
+// StreamTimecode is the timecode the outbound stream started at
+type StreamTimecode struct {
+	StreamStart   string    `json:"stream_start"`
+	StreamStartAt time.Time `json:"stream_start_at"`
+}
+
+// Topics published by the handler. Audited topics record operator actions
+// and state changes worth keeping after the fact.
+var (
+	TopicPipelineStats = events.NewTopic[map[string]interface{}]("stats")
+	TopicModems        = events.NewTopic[ModemsResponse]("modems")
+	TopicUSBNet        = events.NewTopic[USBNetResponse]("usbnet")
+	TopicPipelineMode  = events.NewAuditedTopic[PipelineModeChange]("pipeline_mode")
+	TopicJob           = events.NewTopic[jobs.Job]("job")
+	TopicLoudnessAlert = events.NewTopic[LoudnessStatus]("loudness_alert")
+	TopicIngestAlert   = events.NewAuditedTopic[IngestStatus]("ingest_alert")
+	TopicMarker        = events.NewAuditedTopic[Marker]("marker")
+	TopicPairing       = events.NewAuditedTopic[PairingEvent]("pairing")
+	TopicPower         = events.NewTopic[PowerResponse]("power")
+	TopicSRTLAInstall  = events.NewAuditedTopic[InstallMessage]("srtla_install")
+	TopicTally         = events.NewTopic[TallyResponse]("tally")
+	TopicSwitcher      = events.NewTopic[*SwitcherStatus]("switcher")
+	TopicTimecode      = events.NewTopic[StreamTimecode]("timecode")
+	TopicButton        = events.NewAuditedTopic[ButtonsStatus]("button")
+	TopicService       = events.NewAuditedTopic[ServiceRestart]("service_restart")
+	TopicAPIKey        = events.NewAuditedTopic[APIKeyEvent]("api_key")
+	TopicBitrate       = events.NewTopic[BitrateChange]("bitrate")
+	TopicOSUpdate      = events.NewAuditedTopic[OSUpdateResult]("os_update")
+	TopicFailover      = events.NewAuditedTopic[FailoverSwitch]("failover")
+	TopicSRTIngest     = events.NewAuditedTopic[SRTIngestEvent]("srt_ingest")
+	TopicLinkSwap      = events.NewAuditedTopic[LinkSwap]("link_swap")
+	TopicRestream      = events.NewAuditedTopic[RestreamOutputEvent]("restream_output")
+	TopicGuestAction   = events.NewAuditedTopic[GuestAction]("guest_action")
+	TopicQuota         = events.NewAuditedTopic[QuotaEvent]("quota")
+	TopicModemConnect  = events.NewAuditedTopic[ModemConnectEvent]("modem_connect")
+	TopicLinkProbe     = events.NewAuditedTopic[LinkProbeStatus]("link_probe")
+	TopicHousekeeping  = events.NewAuditedTopic[maintenance.Report]("housekeeping")
+	TopicVPN           = events.NewAuditedTopic[VPNEvent]("vpn")
+	TopicDiscovery     = events.NewAuditedTopic[discovery.Change]("device_discovery")
+)
+
+// startEventConsumers forwards every event to WebSocket clients and records
+// log lines. They run for the life of the process.
+func (h *Handler) startEventConsumers() {
+	ws := h.bus.Subscribe("websocket", 256)
+	go func() {
+		for ev := range ws.C {
+			if h.wsHub != nil {
+				h.wsHub.Broadcast(ev.Topic, ev.Data)
+			}
+		}
+	}()
+
+	// an edit of the config file made by something else is applied as
+	// PUT /api/config would apply it
+	go events.Listen(context.Background(), h.bus, config.TopicUpdated, 16, func(c config.Change) {
+		if c.External {
+			h.logOutput("manager", fmt.Sprintf("[CONFIG] Reloaded the config file (%s)", strings.Join(c.Sections, ", ")))
+			h.applyConfig(h.config.Get())
+		}
+	})
+	go events.Listen(context.Background(), h.bus, config.TopicReloadFailed, 16, func(f config.ReloadFailure) {
+		h.logOutput("manager", "[CONFIG] Not reloading the edited config file, keeping the config in use: "+f.Error)
+	})
+
+	go events.Listen(context.Background(), h.bus, process.TopicLog, 256, func(line process.LogLine) {
+		if h.logs != nil {
+			h.logs.Add(line.Source, line.Line)
+		}
+		if process.IsProcess(line.Source) {
+			logger.Process(line.Source, line.Line)
+		} else {
+			logger.Printf("[%s] %s", line.Source, line.Line)
+		}
+	})
+}
+
+// PublishModemStatus publishes the modem and USB network status, adds the
+// modems' data use to their quotas, connects those that are offline and
+// reports those throttling
+func (h *Handler) PublishModemStatus() {
+	modems := h.GetModemStatus()
+	events.Publish(h.bus, TopicModems, modems)
+	events.Publish(h.bus, TopicUSBNet, h.GetUSBNetStatus())
+	h.CheckQuotas(modems.Modems)
+	h.AutoConnectModems(modems.Modems)
+	h.CheckModemAlerts(modems.Modems)
+	h.ApplyRouting()
+}
+
+// ApplyAuditConfig opens or closes the audit log to match the configuration
+func (h *Handler) ApplyAuditConfig() {
+	cfg := h.config.Get().Audit
+
+	h.auditMu.Lock()
+	defer h.auditMu.Unlock()
+	if h.auditApplied != nil && *h.auditApplied == cfg {
+		return
+	}
+	h.stopAuditLocked()
+	h.auditApplied = &cfg
+	if !cfg.Enabled {
+		return
+	}
+
+	audit, err := events.OpenAuditLog(cfg.Path, int64(cfg.MaxMB)<<20)
+	if err != nil {
+		logger.Error("Audit: %v", err)
+		h.auditApplied = nil // retry on the next apply
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	h.audit = audit
+	h.auditCancel = cancel
+	go audit.Run(ctx, h.bus)
+	h.logOutput("manager", fmt.Sprintf("[AUDIT] Recording events in %s", cfg.Path))
+}
+
+// StopAudit closes the audit log on shutdown
+func (h *Handler) StopAudit() {
+	h.auditMu.Lock()
+	defer h.auditMu.Unlock()
+	h.stopAuditLocked()
+	h.auditApplied = nil
+}
+
+func (h *Handler) stopAuditLocked() {
+	if h.auditCancel != nil {
+		h.auditCancel()
+		h.auditCancel = nil
+	}
+	if h.audit != nil {
+		h.audit.Close()
+		h.audit = nil
+	}
+}
+
+// HandleEventStats handles GET /api/events/stats
+func (h *Handler) HandleEventStats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.bus.Stats())
+}
+
+// HandleAuditLog handles GET /api/audit?since=. since is an RFC 3339 time or
+// a duration back from now and defaults to the last day.
+func (h *Handler) HandleAuditLog(w http.ResponseWriter, r *http.Request) {
+	cfg := h.config.Get().Audit
+	if !cfg.Enabled {
+		jsonError(w, "The audit log is disabled", http.StatusNotFound)
+		return
+	}
+
+	since := time.Now().Add(-24 * time.Hour)
+	if s := r.URL.Query().Get("since"); s != "" {
+		if t, err := time.Parse(time.RFC3339, s); err == nil {
+			since = t
+		} else if d, err := time.ParseDuration(s); err == nil {
+			since = time.Now().Add(-d)
+		} else {
+			jsonError(w, fmt.Sprintf("Invalid since %q: use an RFC 3339 time or a duration such as 30m", s), http.StatusBadRequest)
+			return
+		}
+	}
+
+	entries, err := events.ReadAuditLog(cfg.Path, since)
+	if err != nil {
+		jsonError(w, fmt.Sprintf("Failed to read audit log: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if entries == nil {
+		entries = []events.AuditEntry{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
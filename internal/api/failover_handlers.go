@@ -0,0 +1,473 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"srtla-manager/internal/config"
+	"srtla-manager/internal/events"
+	"srtla-manager/internal/failover"
+	"srtla-manager/internal/logger"
+	"srtla-manager/internal/process"
+	"srtla-manager/internal/srtgate"
+)
+
+// Loopback UDP ports of the ingest failover: ffmpeg reads the switch output
+// and each stream key's relay sends to the port after the one before it
+const (
+	failoverOutputPort = 6100
+	failoverRelayPort  = 6101
+)
+
+// srtIngestListenerPort is the loopback SRT port the relay of the first SRT
+// stream ID listens on, the others following it. The SRT gate hands each
+// camera on to its stream ID's port.
+const srtIngestListenerPort = 6300
+
+// failoverRetryInterval is how often relays whose camera left are started
+// again for the next one
+const failoverRetryInterval = 2 * time.Second
+
+// Defaults of the source failover's loss_seconds and restore_seconds
+const (
+	defaultSourceLoss    = 3 * time.Second
+	defaultSourceRestore = 5 * time.Second
+)
+
+// FailoverSwitch reports the ingest failover changing cameras
+type FailoverSwitch struct {
+	From string `json:"from"` // "" when no camera was pushing
+	To   string `json:"to"`   // "" when no camera is pushing
+}
+
+// FailoverSource is one source of the ingest failover
+type FailoverSource struct {
+	failover.SourceStatus
+	Kind       string        `json:"kind"`                  // rtmp, srt, rtsp, usb or slate
+	ListenPort int           `json:"listen_port,omitempty"` // for rtmp and srt
+	Relay      process.State `json:"relay"`
+}
+
+// FailoverResponse reports the ingest failover
+type FailoverResponse struct {
+	Enabled bool             `json:"enabled"`
+	Active  string           `json:"active"`
+	Sources []FailoverSource `json:"sources"`
+}
+
+// failoverInput is one source of the failover switch with what its relay
+// is started from
+type failoverInput struct {
+	Name     string
+	Kind     string
+	Priority int
+	RTMP     config.RTMPIngestConfig
+	SRT      srtIngest
+	RTSP     config.RTSPConfig
+	USB      process.USBCaptureConfig
+	Slate    string
+}
+
+// srtIngest is an SRT stream ID with where cameras call
+type srtIngest struct {
+	config.SRTStreamID
+	ListenPort int
+	LatencyMs  int
+}
+
+// rtmpStreamKeys returns every stream key cameras may push to, the main one
+// first
+func rtmpStreamKeys(cfg config.Config) []config.RTMPIngestConfig {
+	main := config.RTMPIngestConfig{Name: "main", StreamKey: cfg.RTMP.StreamKey, ListenPort: cfg.RTMP.ListenPort}
+	return append([]config.RTMPIngestConfig{main}, cfg.RTMP.Ingests...)
+}
+
+// failoverInputs returns the sources the switch should forward: the stream
+// keys and SRT stream IDs when further keys or any stream IDs are set up,
+// and with the source failover enabled its primary source ahead of its
+// backup. It returns nil when ffmpeg can listen for the camera directly.
+func (h *Handler) failoverInputs(cfg config.Config) []failoverInput {
+	if !cfg.Failover.Enabled {
+		if len(cfg.RTMP.Ingests) == 0 && len(cfg.SRTIngest.StreamIDs) == 0 {
+			return nil
+		}
+		inputs := h.failoverSourceInputs(cfg, config.SourceRTMP)
+		return append(inputs, h.failoverSourceInputs(cfg, config.SourceSRT)...)
+	}
+
+	primary, backup := cfg.Failover.Sources()
+	inputs := h.failoverSourceInputs(cfg, primary)
+	next := 0
+	for _, in := range inputs {
+		next = max(next, in.Priority+1)
+	}
+	// the backup's sources keep their order among themselves, after the primary's
+	backups := h.failoverSourceInputs(cfg, backup)
+	lowest := 0
+	for i, in := range backups {
+		if i == 0 || in.Priority < lowest {
+			lowest = in.Priority
+		}
+	}
+	for _, in := range backups {
+		in.Priority += next - lowest
+		inputs = append(inputs, in)
+	}
+	return inputs
+}
+
+// failoverSourceInputs returns the switch sources of one failover source.
+// A USB camera that isn't plugged in has none until it is.
+func (h *Handler) failoverSourceInputs(cfg config.Config, source string) []failoverInput {
+	switch source {
+	case config.SourceRTMP:
+		var inputs []failoverInput
+		for _, in := range rtmpStreamKeys(cfg) {
+			inputs = append(inputs, failoverInput{Name: in.Name, Kind: source, Priority: in.Priority, RTMP: in})
+		}
+		return inputs
+	case config.SourceSRT:
+		var inputs []failoverInput
+		for _, id := range cfg.SRTIngest.StreamIDs {
+			in := srtIngest{SRTStreamID: id, ListenPort: cfg.SRTIngest.Port(), LatencyMs: cfg.SRTIngest.LatencyMs}
+			inputs = append(inputs, failoverInput{Name: id.Name, Kind: source, Priority: id.Priority, SRT: in})
+		}
+		return inputs
+	case config.SourceRTSP:
+		return []failoverInput{{Name: source, Kind: source, RTSP: cfg.RTSP}}
+	case config.SourceUSB:
+		if capture, ok := h.failoverUSBCapture(cfg); ok {
+			return []failoverInput{{Name: source, Kind: source, USB: capture}}
+		}
+		return nil
+	}
+	return []failoverInput{{Name: source, Kind: source, Slate: cfg.Failover.SlateImage}}
+}
+
+// failoverUSBCapture returns how the failover's USB camera is captured,
+// with its saved settings and the camera's best matching format
+func (h *Handler) failoverUSBCapture(cfg config.Config) (process.USBCaptureConfig, bool) {
+	if h.usbCamController == nil {
+		return process.USBCaptureConfig{}, false
+	}
+	id := cfg.Failover.USBCamera
+	state := h.usbCamController.GetCameraState(id)
+	if state == nil || state.Camera == nil {
+		return process.USBCaptureConfig{}, false
+	}
+	req := usbStartDefaults(USBCameraStartRequest{}, cfg.USBCameras[id])
+	capture := process.USBCaptureConfig{
+		DevicePath:  state.Camera.DevicePath,
+		Width:       req.Width,
+		Height:      req.Height,
+		FPS:         req.FPS,
+		Encoder:     req.Encoder,
+		Bitrate:     req.Bitrate,
+		InputFormat: "mjpeg",
+	}
+	if format := state.Camera.GetBestFormat(req.Width, req.Height); format != nil {
+		capture.Width, capture.Height = format.Width, format.Height
+		switch format.PixelFormat {
+		case "H264":
+			capture.InputFormat = "h264"
+		case "YUYV":
+			capture.InputFormat = "yuyv422"
+		}
+	}
+	if capture.Encoder == "copy" && capture.InputFormat != "h264" {
+		capture.Encoder = "libx264"
+	}
+	return capture, true
+}
+
+// failoverTiming returns how long a source may go without sending before it
+// is dropped and how long a better one must send before it takes over
+func failoverTiming(cfg config.Config) (timeout, hold time.Duration) {
+	timeout, hold = failover.DefaultTimeout, failover.DefaultHold
+	if !cfg.Failover.Enabled {
+		return timeout, hold
+	}
+	timeout, hold = defaultSourceLoss, defaultSourceRestore
+	if cfg.Failover.LossSeconds > 0 {
+		timeout = time.Duration(cfg.Failover.LossSeconds) * time.Second
+	}
+	if cfg.Failover.RestoreSeconds > 0 {
+		hold = time.Duration(cfg.Failover.RestoreSeconds) * time.Second
+	}
+	return timeout, hold
+}
+
+// failoverUsesUSB reports whether the failover is capturing a USB camera
+func (h *Handler) failoverUsesUSB(cameraID string) bool {
+	if h.config.Get().Failover.USBCamera != cameraID {
+		return false
+	}
+	h.failoverMu.Lock()
+	defer h.failoverMu.Unlock()
+	for _, in := range h.failoverApplied {
+		if in.Kind == config.SourceUSB {
+			return true
+		}
+	}
+	return false
+}
+
+// ApplyFailoverConfig starts, restarts or stops the relays and the failover
+// switch to match the configuration. ffmpeg is moved between listening for
+// the camera itself and reading the switch; while the pipeline is busy the
+// change waits for the monitor's next pass.
+func (h *Handler) ApplyFailoverConfig() {
+	cfg := h.config.Get()
+	want := h.failoverInputs(cfg)
+	timeout, hold := failoverTiming(cfg)
+	bindAddr := h.getBindAddr()
+
+	h.failoverMu.Lock()
+	defer h.failoverMu.Unlock()
+
+	if want == nil && h.failoverApplied == nil {
+		return
+	}
+	// a test stream has ffmpeg read the test pattern until it stops
+	if h.testPattern() != nil {
+		return
+	}
+	if slices.Equal(want, h.failoverApplied) && bindAddr == h.failoverBind &&
+		timeout == h.failoverSwitch.Timeout && hold == h.failoverSwitch.Hold {
+		return
+	}
+	release, err := h.acquirePipeline("ingest_failover")
+	if err != nil {
+		return
+	}
+	defer release()
+
+	// ffmpeg has to leave the main port before its relay can listen there,
+	// and the relays have to be gone before it listens there again
+	listening := h.ffmpeg.Listening()
+	if listening {
+		_ = h.ffmpeg.Stop()
+	}
+	h.stopFailoverLocked()
+
+	if want == nil {
+		h.ffmpeg.SetSwitchedInput("")
+	} else {
+		h.startFailoverLocked(want, bindAddr, timeout, hold)
+	}
+
+	if listening {
+		if err := h.ffmpeg.Relisten(); err != nil {
+			h.logOutput("manager", fmt.Sprintf("[FAILOVER] Failed to restart ffmpeg: %v", err))
+		}
+	}
+}
+
+// startFailoverLocked starts the switch, a relay per source and, for SRT
+// stream IDs, the gate cameras call. h.failoverMu must be held.
+func (h *Handler) startFailoverLocked(inputs []failoverInput, bindAddr string, timeout, hold time.Duration) {
+	sources := make([]failover.Source, len(inputs))
+	routes := make(map[string]srtgate.Route)
+	gatePort := 0
+	for i, in := range inputs {
+		sources[i] = failover.Source{Name: in.Name, Priority: in.Priority, Port: failoverRelayPort + i}
+		if in.Kind == config.SourceSRT {
+			routes[in.SRT.StreamID] = srtgate.Route{Name: in.Name, Port: srtIngestListenerPort + i}
+			gatePort = in.SRT.ListenPort
+		}
+	}
+	sw := failover.New(sources, h.onFailoverSwitch)
+	sw.Timeout, sw.Hold = timeout, hold
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := sw.Run(ctx, failoverOutputPort); err != nil {
+			logger.Error("Ingest failover: %v", err)
+		}
+	}()
+	if len(routes) > 0 {
+		gate := srtgate.New(routes, h.onSRTGateEvent)
+		addr := net.JoinHostPort(bindAddr, strconv.Itoa(gatePort))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := gate.Run(ctx, addr); err != nil {
+				logger.Error("SRT ingest: %v", err)
+			}
+		}()
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	h.failoverSwitch = sw
+	h.failoverCancel = cancel
+	h.failoverDone = done
+	h.failoverApplied = inputs
+	h.failoverBind = bindAddr
+	h.failoverRelays = make([]*process.IngestRelay, len(inputs))
+	for i, in := range inputs {
+		relay := process.NewIngestRelay(in.Name)
+		relay.SetBus(h.bus)
+		if err := startFailoverRelay(relay, in, bindAddr, i); err != nil {
+			logger.Error("Ingest failover: source %s: %v", in.Name, err)
+		}
+		h.failoverRelays[i] = relay
+	}
+
+	// the switch output is a local stream ffmpeg reads without a camera
+	// handshake; overrun_nonfatal rides out a switch without exiting
+	h.ffmpeg.SetSwitchedInput(fmt.Sprintf("udp://127.0.0.1:%d?fifo_size=100000&overrun_nonfatal=1", failoverOutputPort))
+	h.logOutput("manager", fmt.Sprintf("[FAILOVER] Switching between %d sources", len(inputs)))
+}
+
+// startFailoverRelay starts the relay of the i-th source, sending it to
+// the switch
+func startFailoverRelay(relay *process.IngestRelay, in failoverInput, bindAddr string, i int) error {
+	udpPort := failoverRelayPort + i
+	switch in.Kind {
+	case config.SourceSRT:
+		return relay.StartSRT(srtIngestListenerPort+i, in.SRT.LatencyMs, in.SRT.Passphrase, udpPort)
+	case config.SourceRTSP:
+		return relay.StartRTSP(rtspCaptureConfig(in.RTSP, 0, ""), udpPort)
+	case config.SourceUSB:
+		return relay.StartUSB(in.USB, udpPort)
+	case config.SourceSlate:
+		return relay.StartSlate(in.Slate, udpPort)
+	}
+	return relay.Start(bindAddr, in.RTMP.ListenPort, in.RTMP.StreamKey, udpPort)
+}
+
+// stopFailoverLocked stops the relays and the switch. h.failoverMu must be
+// held.
+func (h *Handler) stopFailoverLocked() {
+	for _, relay := range h.failoverRelays {
+		_ = relay.Stop()
+	}
+	if h.failoverCancel != nil {
+		// wait for the switch to free its ports
+		h.failoverCancel()
+		<-h.failoverDone
+	}
+	h.failoverRelays = nil
+	h.failoverSwitch = nil
+	h.failoverCancel = nil
+	h.failoverDone = nil
+	h.failoverApplied = nil
+	h.failoverBind = ""
+}
+
+func (h *Handler) onFailoverSwitch(from, to string) {
+	switch {
+	case to == "":
+		h.logOutput("manager", fmt.Sprintf("[FAILOVER] %s stopped sending and no other source is", from))
+	case from == "":
+		h.logOutput("manager", fmt.Sprintf("[FAILOVER] Taking the stream from %s", to))
+	default:
+		h.logOutput("manager", fmt.Sprintf("[FAILOVER] Switched from %s to %s", from, to))
+	}
+	events.Publish(h.bus, TopicFailover, FailoverSwitch{From: from, To: to})
+}
+
+// SRTIngestEvent reports an SRT camera calling the ingest
+type SRTIngestEvent struct {
+	Event    string `json:"event"` // accepted, rejected or failed
+	Remote   string `json:"remote"`
+	Name     string `json:"name,omitempty"`      // the stream ID's name, unless rejected
+	StreamID string `json:"stream_id,omitempty"` // when rejected
+	Error    string `json:"error,omitempty"`
+}
+
+func (h *Handler) onSRTGateEvent(ev srtgate.Event) {
+	out := SRTIngestEvent{Remote: ev.Remote, Name: ev.Route}
+	switch {
+	case ev.Route == "":
+		out.Event, out.StreamID = "rejected", ev.StreamID
+		h.logOutput("manager", fmt.Sprintf("[SRT INGEST] Rejected %s: unknown stream ID %q", ev.Remote, ev.StreamID))
+	case ev.Err != nil:
+		out.Event, out.Error = "failed", ev.Err.Error()
+		h.logOutput("manager", fmt.Sprintf("[SRT INGEST] %s called for %s, whose relay isn't listening: %v", ev.Remote, ev.Route, ev.Err))
+	default:
+		out.Event = "accepted"
+		h.logOutput("manager", fmt.Sprintf("[SRT INGEST] Accepted %s as %s", ev.Remote, ev.Route))
+	}
+	events.Publish(h.bus, TopicSRTIngest, out)
+}
+
+// StartFailoverMonitor applies the ingest failover and starts relays again
+// once their source has gone, so the next camera can push and a lost camera
+// is picked up again when it returns
+func (h *Handler) StartFailoverMonitor(ctx context.Context) {
+	h.ApplyFailoverConfig()
+
+	go func() {
+		ticker := time.NewTicker(failoverRetryInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			h.ApplyFailoverConfig()
+
+			h.failoverMu.Lock()
+			for i, relay := range h.failoverRelays {
+				if relay.ProcessState() == process.StateRunning {
+					continue
+				}
+				in := h.failoverApplied[i]
+				if err := startFailoverRelay(relay, in, h.failoverBind, i); err != nil {
+					logger.Warn("Ingest failover: source %s: %v", in.Name, err)
+				}
+			}
+			h.failoverMu.Unlock()
+		}
+	}()
+}
+
+// StopFailover stops the relays and the switch on shutdown
+func (h *Handler) StopFailover() {
+	h.failoverMu.Lock()
+	defer h.failoverMu.Unlock()
+	h.stopFailoverLocked()
+}
+
+// HandleFailoverStatus handles GET /api/ingests
+func (h *Handler) HandleFailoverStatus(w http.ResponseWriter, r *http.Request) {
+	h.failoverMu.Lock()
+	resp := FailoverResponse{Enabled: h.failoverSwitch != nil, Sources: []FailoverSource{}}
+	if h.failoverSwitch != nil {
+		status := h.failoverSwitch.Status()
+		resp.Active = status.Active
+		for _, src := range status.Sources {
+			source := FailoverSource{SourceStatus: src}
+			for i, in := range h.failoverApplied {
+				if in.Name == src.Name {
+					source.Kind = in.Kind
+					source.ListenPort = in.RTMP.ListenPort
+					if in.Kind == config.SourceSRT {
+						source.ListenPort = in.SRT.ListenPort
+					}
+					source.Relay = h.failoverRelays[i].ProcessState()
+				}
+			}
+			resp.Sources = append(resp.Sources, source)
+		}
+	}
+	h.failoverMu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,191 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"srtla-manager/internal/apikey"
+	"srtla-manager/internal/config"
+	"srtla-manager/internal/events"
+	"srtla-manager/internal/validate"
+)
+
+// maxGuestWindow is the longest a guest key may be valid for
+const maxGuestWindow = 7 * 24 * time.Hour
+
+// guestExpiryInterval is how often expired guest keys are removed
+const guestExpiryInterval = 30 * time.Second
+
+// GuestCreateRequest is the request body for POST /api/access/guests. The
+// window ends at ends_at, or duration_minutes after it starts.
+type GuestCreateRequest struct {
+	Name            string     `json:"name"`
+	StartsAt        *time.Time `json:"starts_at"` // now if not set
+	EndsAt          *time.Time `json:"ends_at"`
+	DurationMinutes int        `json:"duration_minutes"`
+}
+
+// GuestAction records a request made with a guest operator key
+type GuestAction struct {
+	KeyID      string `json:"key_id"`
+	Name       string `json:"name"`
+	Method     string `json:"method"`
+	Path       string `json:"path"`
+	Status     int    `json:"status"`
+	RemoteAddr string `json:"remote_addr"`
+}
+
+// isGuestKey reports whether k is a guest operator key
+func isGuestKey(k config.APIKey) bool {
+	return slices.Contains(k.Scopes, apikey.ScopeGuest)
+}
+
+// HandleGuestList handles GET /api/access/guests
+func (h *Handler) HandleGuestList(w http.ResponseWriter, r *http.Request) {
+	guests := []config.APIKey{}
+	for _, k := range h.config.Get().Access.APIKeys {
+		if isGuestKey(k) {
+			guests = append(guests, k)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(guests)
+}
+
+// HandleGuestCreate handles POST /api/access/guests. The key it returns can
+// only start and stop the stream and watch the status and preview, and only
+// within its window. It is removed once the window ends and can be revoked
+// earlier with DELETE /api/access/keys/{id}.
+func (h *Handler) HandleGuestCreate(w http.ResponseWriter, r *http.Request) {
+	var req GuestCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now().UTC()
+	start := now
+	if req.StartsAt != nil {
+		start = req.StartsAt.UTC()
+	}
+
+	v := validate.New()
+	v.Required("name", req.Name)
+	var end time.Time
+	switch {
+	case req.EndsAt != nil && req.DurationMinutes != 0:
+		v.Addf("duration_minutes", "cannot be set together with ends_at")
+	case req.EndsAt != nil:
+		end = req.EndsAt.UTC()
+	case req.DurationMinutes > 0:
+		end = start.Add(time.Duration(req.DurationMinutes) * time.Minute)
+	default:
+		v.Addf("ends_at", "ends_at or a positive duration_minutes is required")
+	}
+	if !end.IsZero() {
+		if !end.After(start) {
+			v.Addf("ends_at", "must be after the start")
+		} else if !end.After(now) {
+			v.Addf("ends_at", "must be in the future")
+		} else if end.Sub(start) > maxGuestWindow {
+			v.Addf("ends_at", "the window may be at most %s", maxGuestWindow)
+		}
+	}
+	if err := v.Err(); err != nil {
+		validationError(w, err)
+		return
+	}
+
+	id, key, err := apikey.Generate()
+	if err != nil {
+		jsonError(w, "Failed to generate key: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	created := config.APIKey{
+		ID:        id,
+		Name:      req.Name,
+		Scopes:    []string{apikey.ScopeGuest},
+		Hash:      apikey.Hash(key),
+		CreatedAt: now,
+		NotBefore: &start,
+		ExpiresAt: &end,
+	}
+
+	cfg := h.config.Get()
+	cfg.Access.APIKeys = append(append([]config.APIKey{}, cfg.Access.APIKeys...), created)
+	if err := h.config.Update(cfg); err != nil {
+		jsonError(w, fmt.Sprintf("Failed to save configuration: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	h.logOutput("manager", fmt.Sprintf("[ACCESS] Created guest key %s (%s) valid from %s to %s",
+		created.Name, created.ID, start.Format(time.RFC3339), end.Format(time.RFC3339)))
+	events.Publish(h.bus, TopicAPIKey, APIKeyEvent{
+		Event:     "created",
+		ID:        created.ID,
+		Name:      created.Name,
+		Scopes:    created.Scopes,
+		NotBefore: created.NotBefore,
+		ExpiresAt: created.ExpiresAt,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(APIKeyCreated{APIKey: created, Key: key})
+}
+
+// StartGuestExpiry removes keys whose window has ended. The middleware
+// already refuses them; this keeps them from piling up in the config.
+func (h *Handler) StartGuestExpiry(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(guestExpiryInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			h.expireKeys(time.Now())
+		}
+	}()
+}
+
+// expireKeys removes the keys that expired by now
+func (h *Handler) expireKeys(now time.Time) {
+	cfg := h.config.Get()
+	keys := make([]config.APIKey, 0, len(cfg.Access.APIKeys))
+	var expired []config.APIKey
+	for _, k := range cfg.Access.APIKeys {
+		if k.ExpiresAt != nil && !now.Before(*k.ExpiresAt) {
+			expired = append(expired, k)
+			continue
+		}
+		keys = append(keys, k)
+	}
+	if len(expired) == 0 {
+		return
+	}
+
+	cfg.Access.APIKeys = keys
+	if err := h.config.Update(cfg); err != nil {
+		h.logOutput("manager", fmt.Sprintf("[ACCESS] Failed to remove expired keys: %v", err))
+		return
+	}
+	for _, k := range expired {
+		h.logOutput("manager", fmt.Sprintf("[ACCESS] API key %s (%s) expired", k.Name, k.ID))
+		events.Publish(h.bus, TopicAPIKey, APIKeyEvent{
+			Event:     "expired",
+			ID:        k.ID,
+			Name:      k.Name,
+			Scopes:    k.Scopes,
+			NotBefore: k.NotBefore,
+			ExpiresAt: k.ExpiresAt,
+		})
+	}
+}
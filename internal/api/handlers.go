@@ -1,23 +1,54 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"srtla-manager/internal"
+	"srtla-manager/internal/alerts"
+	"srtla-manager/internal/bondhealth"
+	"srtla-manager/internal/capture"
 	"srtla-manager/internal/config"
+	"srtla-manager/internal/confighistory"
+	"srtla-manager/internal/discovery"
+	"srtla-manager/internal/display"
 	"srtla-manager/internal/dji"
+	"srtla-manager/internal/events"
+	"srtla-manager/internal/failover"
+	"srtla-manager/internal/gpio"
+	"srtla-manager/internal/jobs"
+	"srtla-manager/internal/linkprobe"
+	"srtla-manager/internal/maintenance"
 	"srtla-manager/internal/modem"
+	"srtla-manager/internal/notify"
+	"srtla-manager/internal/pairing"
+	"srtla-manager/internal/power"
+	"srtla-manager/internal/previewtoken"
 	"srtla-manager/internal/process"
+	"srtla-manager/internal/quota"
+	"srtla-manager/internal/relay"
+	"srtla-manager/internal/restream"
+	"srtla-manager/internal/routing"
+	"srtla-manager/internal/srt"
 	"srtla-manager/internal/stats"
 	"srtla-manager/internal/system"
+	"srtla-manager/internal/tally"
+	"srtla-manager/internal/timecode"
+	"srtla-manager/internal/tlscert"
+	"srtla-manager/internal/transport"
+	"srtla-manager/internal/upload"
 	"srtla-manager/internal/usbcam"
 	"srtla-manager/internal/usbnet"
+	"srtla-manager/internal/validate"
 	"srtla-manager/internal/wifi"
 )
 
@@ -38,19 +69,37 @@ type Handler struct {
 	config        *config.Manager
 	ffmpeg        *process.FFmpegHandler
 	srtla         *process.SRTLAHandler
+	srtProbe      *srt.Probe
 	modem         *modem.Manager
 	usb           *usbnet.Service
 	stats         *stats.Collector
 	logs          *stats.LogBuffer
 	wsHub         *Hub
+	bus           *events.Bus
 	startTime     time.Time
 	wifiMgr       *wifi.Manager
 	pipelineMu    sync.RWMutex
 	pipelineMode  PipelineMode
+	pipelineOpMu  sync.Mutex
+	pipelineOp    *PipelineOperation
+	transportMu   sync.Mutex
+	transport     transport.Kind      // transport of the running bonding process
+	receiver      *transport.Receiver // last receiver probe
+	bindSet       string              // bind IP set of the running stream, "" for srtla.bind_ips
+	degraded      *DegradedStart      // the running stream started on fewer links than configured
+	srtlaLinks    []string            // bind IPs handed to the bonding process after link policies
+	onFailover    bool                // only failover-only links are up
 	activeBindIPs []string
+	linkCapsMu    sync.Mutex
+	linkCaps      map[string]int // interface egress caps in force, kbps
 	previewDir    string
 	appVersion    string
 
+	tlsStore   *tlscert.Store
+	tlsServing bool // the server was started with HTTPS
+
+	serviceRestartMu sync.Mutex // held while a system service restarts
+
 	djiScanner    *dji.Scanner
 	djiController *dji.Controller
 
@@ -60,6 +109,215 @@ type Handler struct {
 	restartTrackerMu sync.RWMutex
 	ffmpegRestarts   *RestartTracker
 	srtlaRestarts    *RestartTracker
+
+	session    *stats.Session // totals of the current or last time on air
+	linkHealth *bondhealth.Tracker
+
+	loopHeartbeat atomic.Int64 // unix nanos of the last stats loop tick
+
+	metricsMu      sync.Mutex
+	metricsApplied *config.MetricsConfig // settings the running sinks were set up with
+	prometheus     *stats.Prometheus
+
+	loudnessMu       sync.Mutex
+	loudnessAlerting bool
+
+	ingestMu       sync.Mutex
+	ingestLowSince time.Time // when the current run of problems started, zero if none
+	ingestDegraded bool
+
+	markersMu    sync.Mutex
+	markers      []*Marker
+	nextMarkerID uint32
+
+	pairingMonitor *pairing.Monitor
+	pairingMu      sync.Mutex
+	tookOver       *time.Time
+
+	sharesMu sync.Mutex
+	shares   map[string]*ShareLink
+
+	abrMu      sync.Mutex
+	abrSession int // bumped per stream so a stale controller loop exits
+	abrStatus  ABRStatus
+
+	restreamMu    sync.Mutex
+	twitchRegions []restream.Region // Twitch's ingest servers, nil until fetched
+	twitchFetched time.Time
+
+	speedTestMu      sync.Mutex
+	speedTestRunning bool
+	speedTestLast    *SpeedTestResult
+
+	relayMu      sync.Mutex
+	relayRunning bool
+	relayLast    *relay.Result
+
+	uploadsMu sync.Mutex
+	uploads   *upload.Store // opened on first use
+	// installDeb hands a package to the privileged installer
+	installDeb func(path string) (internal.InstallResponse, error)
+
+	testMu sync.Mutex
+	test   *TestPattern // streamed instead of a camera, nil for none
+
+	discovery *discovery.Scheduler
+
+	router      *Router // whose routes the OpenAPI document describes
+	openapiOnce sync.Once
+	openapiJSON []byte
+	openapiErr  error
+
+	restreamOutputsMu sync.Mutex
+	restreamOutputs   map[string]*restreamOutput // by destination, while streaming
+
+	failoverMu      sync.Mutex
+	failoverSwitch  *failover.Switch // nil unless further stream keys, SRT stream IDs or a source failover are set up
+	failoverCancel  context.CancelFunc
+	failoverDone    chan struct{} // closed once the switch has stopped
+	failoverApplied []failoverInput
+	failoverBind    string
+	failoverRelays  []*process.IngestRelay // in failoverApplied's order
+
+	swapMu     sync.Mutex
+	swap       *LinkSwap // modem being swapped, nil when none
+	swapCancel context.CancelFunc
+
+	templateMu     sync.Mutex
+	templateStaged *StagedTemplate
+	templatePrev   *config.Config  // config before the last template was applied, for rollback
+	templateLast   *TemplateResult // outcome of the last apply or rollback
+
+	configHistory *confighistory.Store // copies of the config taken before it is replaced
+
+	previewTokens *previewtoken.Signer // signs the tokens granting access to the previews
+
+	modemsMu     sync.Mutex
+	modems       []modem.ModemInfo // as last polled, for the modem metrics
+	modemHistory *stats.ModemHistory
+
+	interfaceHistory *stats.InterfaceHistory
+
+	quotaMu      sync.Mutex
+	quotaTracker *quota.Tracker
+	quotaPath    string
+	quotaSaved   time.Time
+	quotaDropped map[string]string // bind IP -> IMEI of a modem over its budget
+
+	probeMu      sync.Mutex
+	probeMonitor *linkprobe.Monitor
+	probeCancel  context.CancelFunc
+	probeApplied *config.LinkProbeConfig
+
+	routing        *routing.Manager
+	routingMu      sync.Mutex
+	routingMissing []string // bind IPs not on any interface at the last apply
+	routingErr     string   // of the last apply, so it is logged once
+
+	connectMu    sync.Mutex
+	connecting   map[string]bool      // modem ID -> connection being brought up
+	connectTried map[string]time.Time // modem ID -> last automatic attempt
+	connectHeld  map[string]bool      // modem ID -> disconnected through the API
+
+	rtspMu      sync.Mutex
+	rtspActive  bool
+	rtspSession int // bumped per start and stop so a stale monitor exits
+
+	maintenanceMu   sync.Mutex
+	osUpdating      bool
+	osUpdateLast    *OSUpdateResult
+	maintenanceDone time.Time // opening of the last window an update was run in
+
+	housekeeping     bool
+	housekeepingLast *maintenance.Report
+	housekeepingDone time.Time // opening of the last window housekeeping ran in
+
+	vpnMu      sync.Mutex
+	vpnApplied *config.VPNConfig
+
+	jobs *jobs.Manager
+
+	profileMu      sync.RWMutex
+	profile        power.Profile
+	powerMonitor   *power.Monitor
+	profileChanges chan power.Profile
+
+	captureMu     sync.Mutex
+	capture       *capture.Recorder
+	captureDir    string
+	captureWindow time.Duration
+
+	talkbackMu      sync.Mutex
+	talkback        *process.TalkbackHandler
+	talkbackApplied *process.TalkbackConfig // settings the running player was started with
+
+	returnMu      sync.Mutex
+	returnFeed    *process.ReturnFeedHandler
+	returnApplied *process.ReturnFeedConfig
+
+	receiverMu       sync.Mutex
+	srtlaRec         *process.SRTLARecHandler
+	receiverApplied  *config.ReceiverConfig // settings srtla_rec is running with, nil when stopped
+	receiverCancel   context.CancelFunc     // stops the restart monitor
+	receiverRestarts *RestartTracker
+
+	switcherMu        sync.Mutex
+	switcherCancel    context.CancelFunc
+	switcherState     tally.State
+	switcherConnected bool
+	switcherError     string
+	switcherSince     time.Time // when switcherState last changed
+
+	tallyMu       sync.Mutex
+	tallyCancel   context.CancelFunc
+	tallyLight    tally.Light
+	tallySource   tally.State
+	tallyOverride tally.State
+	tallyError    string
+
+	buttonsMu        sync.Mutex
+	buttonsCancel    context.CancelFunc
+	buttonLED        *gpio.Pin
+	buttonBusy       bool
+	buttonLastAction string
+	buttonLastError  string
+	buttonLastAt     time.Time
+	buttonFlashUntil time.Time // the LED flashes until then after a failed action
+	buttonErrors     []string
+
+	displayMu      sync.Mutex
+	display        display.Display
+	displayCancel  context.CancelFunc
+	displayDone    chan struct{}
+	displayApplied *config.DisplayConfig // settings the running display was opened with
+	displayLines   []string
+	displayError   string
+
+	alertsMu       sync.Mutex
+	alertsCancel   context.CancelFunc
+	alertPlayer    *alerts.Player
+	alertLinks     map[string]bool // bind IP -> up, while streaming
+	alertInputSeen bool            // the camera input was up during this stream
+	alertInputLost bool
+	alertModemHot  map[string]bool // modem IMEI -> throttling or too hot
+	alertLast      *alerts.Alert
+	alertLastAt    time.Time
+
+	notifyMu     sync.Mutex
+	notifyCancel context.CancelFunc
+	notifyQueue  *notify.Queue
+
+	timecodeMu       sync.Mutex
+	ltc              *process.LTCReader
+	clockSynced      bool
+	clockCheckedAt   time.Time
+	timecodeAnchorAt time.Time // output start the anchor was taken for
+	timecodeAnchor   timecode.Timecode
+
+	auditMu      sync.Mutex
+	audit        *events.AuditLog
+	auditCancel  context.CancelFunc
+	auditApplied *config.AuditConfig
 }
 
 // InstallDebResponse is the response from the installer
@@ -72,7 +330,7 @@ type InstallDebResponse struct {
 // HandleInstallDeb handles POST /api/system/install-deb
 func (h *Handler) HandleInstallDeb(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 	var req InstallDebRequest
@@ -80,8 +338,13 @@ func (h *Handler) HandleInstallDeb(w http.ResponseWriter, r *http.Request) {
 		jsonError(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
-	if req.DebPath == "" {
-		jsonError(w, "deb_path is required", http.StatusBadRequest)
+	v := validate.New()
+	v.Required("deb_path", req.DebPath)
+	if req.DebPath != "" && (!filepath.IsAbs(req.DebPath) || filepath.Ext(req.DebPath) != ".deb") {
+		v.Addf("deb_path", "must be an absolute path to a .deb file")
+	}
+	if err := v.Err(); err != nil {
+		validationError(w, err)
 		return
 	}
 	resp, err := internal.InstallDebPackage(req.DebPath)
@@ -113,7 +376,7 @@ const (
 	ResetFailureCountAfter = 60 * time.Second
 )
 
-func NewHandler(cfg *config.Manager, ff *process.FFmpegHandler, sr *process.SRTLAHandler, mm *modem.Manager, un *usbnet.Service, st *stats.Collector, lg *stats.LogBuffer, hub *Hub, wm *wifi.Manager) *Handler {
+func NewHandler(cfg *config.Manager, ff *process.FFmpegHandler, sr *process.SRTLAHandler, mm *modem.Manager, un *usbnet.Service, st *stats.Collector, lg *stats.LogBuffer, hub *Hub, bus *events.Bus, wm *wifi.Manager) *Handler {
 	djiScanner := dji.NewScanner()
 	usbCamScanner := usbcam.NewScanner()
 	usbCamController := usbcam.NewController(usbCamScanner)
@@ -127,6 +390,7 @@ func NewHandler(cfg *config.Manager, ff *process.FFmpegHandler, sr *process.SRTL
 		stats:            st,
 		logs:             lg,
 		wsHub:            hub,
+		bus:              bus,
 		startTime:        time.Now(),
 		wifiMgr:          wm,
 		djiScanner:       djiScanner,
@@ -134,12 +398,48 @@ func NewHandler(cfg *config.Manager, ff *process.FFmpegHandler, sr *process.SRTL
 		usbCamScanner:    usbCamScanner,
 		usbCamController: usbCamController,
 		previewDir:       "/tmp/srtla-preview",
+		configHistory:    confighistory.NewStore(filepath.Join(filepath.Dir(cfg.FilePath()), "config-history"), 0),
+		previewTokens:    previewtoken.NewSigner(),
 		ffmpegRestarts:   &RestartTracker{backoffDuration: InitialBackoff},
 		srtlaRestarts:    &RestartTracker{backoffDuration: InitialBackoff},
+		receiverRestarts: &RestartTracker{backoffDuration: InitialBackoff},
+		shares:           make(map[string]*ShareLink),
+		session:          stats.NewSession(),
+		linkHealth:       bondhealth.NewTracker(),
+		routing:          routing.NewManager(),
+		profile:          power.Normal,
+		profileChanges:   make(chan power.Profile, 1),
+		connecting:       make(map[string]bool),
+		connectTried:     make(map[string]time.Time),
+		connectHeld:      make(map[string]bool),
+		installDeb:       internal.InstallDebPackage,
 	}
 
+	h.startEventConsumers()
+	h.jobs = jobs.NewManager(func(job jobs.Job) { events.Publish(h.bus, TopicJob, job) })
+	h.registerMetrics()
+	h.registerReceiverMetrics()
+	h.registerModemMetrics()
+	h.registerInterfaceMetrics()
+
+	h.djiController.SetBus(bus)
+	usbCamController.SetBus(bus)
+
+	h.talkback = process.NewTalkbackHandler()
+	h.talkback.SetBus(bus)
+	h.returnFeed = process.NewReturnFeedHandler()
+	h.returnFeed.SetBus(bus)
+	h.srtlaRec = process.NewSRTLARecHandler()
+	h.srtlaRec.SetBus(bus)
+	h.srtProbe = srt.NewProbe()
+	h.srtProbe.SetBus(bus)
+
+	h.ltc = process.NewLTCReader()
+	h.ltc.SetBus(bus)
+
 	// Initialize USB camera controller with FFmpeg handlers
 	h.initUSBCamController()
+	h.initDiscovery()
 
 	return h
 }
@@ -147,27 +447,40 @@ func NewHandler(cfg *config.Manager, ff *process.FFmpegHandler, sr *process.SRTL
 // ========== Types ==========
 
 type StatusResponse struct {
-	Uptime       int64             `json:"uptime"`
-	PipelineMode PipelineMode      `json:"pipeline_mode"`
-	FFmpeg       FFmpegStatus      `json:"ffmpeg"`
-	SRTLA        SRTLAStatus       `json:"srtla"`
-	History      []stats.DataPoint `json:"history"`
+	Uptime       int64              `json:"uptime"`
+	PipelineMode PipelineMode       `json:"pipeline_mode"`
+	FFmpeg       FFmpegStatus       `json:"ffmpeg"`
+	SRTLA        SRTLAStatus        `json:"srtla"`
+	History      []stats.DataPoint  `json:"history"`
+	Loudness     LoudnessStatus     `json:"loudness"`
+	Audio        AudioStatus        `json:"audio"`
+	Operation    *PipelineOperation `json:"operation,omitempty"`
+	Switcher     *SwitcherStatus    `json:"switcher,omitempty"`
+	Timecode     *TimecodeStatus    `json:"timecode,omitempty"`
+	SRT          *srt.Stats         `json:"srt,omitempty"`
+	TestPattern  *TestPattern       `json:"test_pattern,omitempty"` // while a test stream runs
+	LinkHealth   *bondhealth.Score  `json:"link_health,omitempty"`  // unless SRTLA is off
+	Degraded     *DegradedStart     `json:"degraded_start,omitempty"`
 }
 
 type FFmpegStatus struct {
-	ProcessState string              `json:"process_state"`
-	State        process.FFmpegState `json:"state"`
-	Bitrate      float64             `json:"bitrate"`
-	FPS          float64             `json:"fps"`
-	Stale        bool                `json:"stale"`
+	ProcessState string                 `json:"process_state"`
+	State        process.FFmpegState    `json:"state"`
+	Bitrate      float64                `json:"bitrate"`
+	FPS          float64                `json:"fps"`
+	Stale        bool                   `json:"stale"`
+	Ancillary    process.AncillaryStats `json:"ancillary"`
 }
 
 type SRTLAStatus struct {
-	ProcessState string                    `json:"process_state"`
-	State        process.SRTLAState        `json:"state"`
-	Bitrate      float64                   `json:"bitrate"`
-	Connections  []process.ConnectionStats `json:"connections"`
-	Stale        bool                      `json:"stale"`
+	ProcessState string                       `json:"process_state"`
+	State        process.SRTLAState           `json:"state"`
+	Bitrate      float64                      `json:"bitrate"`
+	Connections  []process.ConnectionStats    `json:"connections"`
+	Stale        bool                         `json:"stale"`
+	Version      string                       `json:"version,omitempty"`
+	Features     map[system.SRTLAFeature]bool `json:"features,omitempty"`
+	Transport    transport.Kind               `json:"transport,omitempty"`
 }
 
 type CameraListResponse struct {
@@ -205,7 +518,15 @@ type ModemsResponse struct {
 }
 
 type USBNetResponse struct {
-	Devices []usbnet.DeviceStatus `json:"devices"`
+	Devices  []usbnet.DeviceStatus     `json:"devices"`
+	Mappings map[string]usbnet.Mapping `json:"mappings"` // named devices by ID, connected or not
+}
+
+// StreamStartRequest is the optional body of POST /api/stream/start
+type StreamStartRequest struct {
+	Profile string `json:"profile"`  // stream profile to apply first; "" keeps the current settings
+	BindSet string `json:"bind_set"` // named bind IP set; "" uses the profile's, then srtla.bind_set
+	Force   bool   `json:"force"`    // start even though start_policy isn't met
 }
 
 type IPsFileResponse struct {
@@ -214,17 +535,34 @@ type IPsFileResponse struct {
 }
 
 type WiFiNetworksResponse struct {
-	Available bool               `json:"available"`
-	Networks  []wifi.NetworkInfo `json:"networks"`
+	Available  bool               `json:"available"`
+	Networks   []wifi.NetworkInfo `json:"networks"`
+	Age        int                `json:"age"` // seconds since the scan
+	ScannedAt  time.Time          `json:"scanned_at"`
+	Refreshing bool               `json:"refreshing"`
+	Error      string             `json:"error,omitempty"`
 }
 
 type WiFiStatusResponse struct {
 	Connection *wifi.ConnectionInfo `json:"connection"`
+	Backend    string               `json:"backend"` // dbus or nmcli
 }
 
 type WiFiConnectRequest struct {
 	SSID     string `json:"ssid"`
 	Password string `json:"password"`
+	Hidden   bool   `json:"hidden"`
+	Security string `json:"security"` // wpa-psk (default) or wpa-eap
+
+	EAPMethod         string `json:"eap_method"`
+	Identity          string `json:"identity"`
+	AnonymousIdentity string `json:"anonymous_identity"`
+	Phase2Auth        string `json:"phase2_auth"`
+	CACertPath        string `json:"ca_cert_path"` // as returned by /api/wifi/ca-cert
+}
+
+type WiFiCACertResponse struct {
+	Path string `json:"path"`
 }
 
 type WiFiHotspotRequest struct {
@@ -241,12 +579,34 @@ type WiFiActionResponse struct {
 
 // ========== Helper Methods ==========
 
+// ErrorResponse is the envelope for every API error.
+type ErrorResponse struct {
+	Error     string          `json:"error"`
+	Code      int             `json:"code"`
+	RequestID string          `json:"request_id,omitempty"`
+	Fields    validate.Errors `json:"fields,omitempty"`  // per-field validation failures
+	Details   interface{}     `json:"details,omitempty"` // error-specific context, e.g. the operation holding a lock
+}
+
 func jsonError(w http.ResponseWriter, message string, code int) {
+	writeError(w, ErrorResponse{Error: message, Code: code})
+}
+
+// validationError reports err as a 400. Field-level details are included when
+// err wraps validate.Errors.
+func validationError(w http.ResponseWriter, err error) {
+	resp := ErrorResponse{Error: err.Error(), Code: http.StatusBadRequest}
+	if errors.As(err, &resp.Fields) {
+		resp.Error = "Invalid request"
+	}
+	writeError(w, resp)
+}
+
+func writeError(w http.ResponseWriter, resp ErrorResponse) {
+	resp.RequestID = w.Header().Get(RequestIDHeader)
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(code)
-	json.NewEncoder(w).Encode(map[string]string{
-		"error": message,
-	})
+	w.WriteHeader(resp.Code)
+	json.NewEncoder(w).Encode(resp)
 }
 
 func (h *Handler) uptime() time.Duration {
@@ -282,8 +642,20 @@ func (h *Handler) GetPipelineMode() PipelineMode {
 // SetPipelineMode sets the pipeline mode
 func (h *Handler) SetPipelineMode(mode PipelineMode) {
 	h.pipelineMu.Lock()
-	defer h.pipelineMu.Unlock()
+	from := h.pipelineMode
 	h.pipelineMode = mode
+	h.pipelineMu.Unlock()
+
+	if mode != from {
+		switch {
+		case mode == PipelineModeStreaming:
+			h.session.Start(time.Now())
+			h.linkHealth.Reset()
+		case from == PipelineModeStreaming:
+			h.session.End(time.Now())
+		}
+		events.Publish(h.bus, TopicPipelineMode, PipelineModeChange{From: from, To: mode})
+	}
 }
 
 // IsStreaming returns true if the pipeline is in streaming mode (backward compat)
@@ -324,6 +696,15 @@ func (h *Handler) getBindAddr() string {
 	return "0.0.0.0"
 }
 
+// streamPreviewDir returns the HLS preview directory for an ffmpeg that is also
+// streaming out, or "" when the power profile turns previews off
+func (h *Handler) streamPreviewDir() string {
+	if !h.PowerProfile().StreamPreviews {
+		return ""
+	}
+	return h.previewDir
+}
+
 func (h *Handler) cleanPreviewDir() {
 	if h.previewDir == "" {
 		return
@@ -332,18 +713,15 @@ func (h *Handler) cleanPreviewDir() {
 }
 
 func (h *Handler) logOutput(source string, line string) {
-	if h.logs != nil {
-		h.logs.Add(source, line)
-	}
-	if h.wsHub != nil {
-		h.wsHub.Broadcast("log", map[string]string{
-			"source": source,
-			"line":   line,
-		})
-	}
+	events.Publish(h.bus, process.TopicLog, process.LogLine{Timestamp: time.Now(), Source: source, Line: line})
 }
 
 func (h *Handler) getAvailableBindIPs(cfg *config.Config) []string {
+	return h.availableOf(h.bindIPs(cfg))
+}
+
+// availableOf returns the bind IPs of ips the bond may use now
+func (h *Handler) availableOf(ips []string) []string {
 	interfaces := system.ListNetworkInterfaces()
 	systemIPs := make(map[string]bool)
 	for _, iface := range interfaces {
@@ -352,19 +730,34 @@ func (h *Handler) getAvailableBindIPs(cfg *config.Config) []string {
 		}
 	}
 
+	// a link whose modem is being swapped or has used up its data budget
+	// stays out of the bond
+	draining := h.drainingLink()
+
 	var available []string
-	for _, ip := range cfg.SRTLA.BindIPs {
+	for _, ip := range ips {
 		ip = strings.TrimSpace(ip)
-		if ip != "" && systemIPs[ip] {
+		if ip != "" && systemIPs[ip] && ip != draining && !h.quotaDroppedIP(ip) {
 			available = append(available, ip)
 		}
 	}
-	return available
+	// nor does one without a way out, when links are probed
+	return h.linkProbeFilter(available)
 }
 
-func (h *Handler) shouldRestartWithBackoff(tracker *RestartTracker, reason, processName string) bool {
+// shouldRestartWithBackoff reports whether a process that stopped may be
+// restarted yet. Each restart it allows counts towards the session's total.
+func (h *Handler) shouldRestartWithBackoff(tracker *RestartTracker, reason, processName string) (restart bool) {
 	h.restartTrackerMu.Lock()
 	defer h.restartTrackerMu.Unlock()
+	defer func() {
+		if restart {
+			h.session.AddRestart()
+			if h.GetPipelineMode() == PipelineModeStreaming {
+				h.linkHealth.AddRestart(time.Now())
+			}
+		}
+	}()
 
 	now := time.Now()
 
@@ -427,32 +820,58 @@ func (h *Handler) startSRTLA(cfg *config.Config, bindIPs []string) error {
 		return nil
 	}
 
+	bindIPs = h.applyLinkPolicies(cfg, bindIPs)
 	if len(bindIPs) == 0 {
 		return fmt.Errorf("no bind IPs provided for SRTLA")
 	}
 
-	binaryPath := cfg.SRTLA.BinaryPath
-	if binaryPath == "" {
-		binaryPath = "srtla_send"
-	}
-	srtlaStatus := system.CheckSRTLA(binaryPath)
-	if !srtlaStatus.Installed {
-		return fmt.Errorf("SRTLA binary not found. Please install srtla_send or set the correct binary path in configuration. %s", srtlaStatus.InstallCommand)
+	kind, err := h.resolveTransport(context.Background(), cfg)
+	if err != nil {
+		return err
 	}
+	h.setActiveTransport(kind)
 
-	h.logOutput("manager", fmt.Sprintf("[SRTLA] Starting with %d bind IPs: %s", len(bindIPs), strings.Join(bindIPs, ", ")))
+	if kind == transport.SRTBonding {
+		h.logOutput("manager", fmt.Sprintf("[SRTLA] Starting SRT bonding with %d bind IPs: %s", len(bindIPs), strings.Join(bindIPs, ", ")))
+		if err := h.srtla.StartSRTBonding(system.FindSRTLiveTransmit(), cfg.SRT.LocalPort, cfg.SRTLA.RemoteHost, cfg.SRTLA.RemotePort, bindIPs); err != nil {
+			return fmt.Errorf("failed to start SRT bonding: %w", err)
+		}
+	} else {
+		binaryPath := cfg.SRTLA.BinaryPath
+		if binaryPath == "" {
+			binaryPath = "srtla_send"
+		}
+		srtlaStatus := system.CheckSRTLA(binaryPath)
+		if !srtlaStatus.Installed {
+			return fmt.Errorf("SRTLA binary not found. Please install srtla_send or set the correct binary path in configuration. %s", srtlaStatus.InstallCommand)
+		}
 
-	if err := h.srtla.Start(
-		cfg.SRTLA.BinaryPath,
-		cfg.SRT.LocalPort,
-		cfg.SRTLA.RemoteHost,
-		cfg.SRTLA.RemotePort,
-		bindIPs,
-		cfg.SRTLA.Classic,
-		cfg.SRTLA.NoQuality,
-		cfg.SRTLA.Exploration,
-	); err != nil {
-		return fmt.Errorf("failed to start SRTLA: %w", err)
+		h.logOutput("manager", fmt.Sprintf("[SRTLA] Starting %s with %d bind IPs: %s", kind, len(bindIPs), strings.Join(bindIPs, ", ")))
+
+		// Only pass flags the installed srtla_send understands; older releases
+		// exit immediately on unknown arguments
+		caps := system.DetectSRTLA(binaryPath)
+		classic := h.srtlaFlag(caps, system.SRTLAFeatureClassic, cfg.SRTLA.Classic)
+		noQuality := h.srtlaFlag(caps, system.SRTLAFeatureNoQuality, cfg.SRTLA.NoQuality)
+		exploration := h.srtlaFlag(caps, system.SRTLAFeatureExploration, cfg.SRTLA.Exploration)
+
+		// without a table per link the kernel sends every link out of the
+		// default route
+		h.ApplyRouting()
+
+		if err := h.srtla.Start(
+			cfg.SRTLA.BinaryPath,
+			cfg.SRT.LocalPort,
+			cfg.SRTLA.RemoteHost,
+			cfg.SRTLA.RemotePort,
+			bindIPs,
+			classic,
+			noQuality,
+			exploration,
+			kind == transport.SRTLA2,
+		); err != nil {
+			return fmt.Errorf("failed to start SRTLA: %w", err)
+		}
 	}
 
 	ready := false
@@ -478,6 +897,32 @@ func (h *Handler) startSRTLA(cfg *config.Config, bindIPs []string) error {
 	return nil
 }
 
+// srtlaFlag returns enabled if the installed srtla_send supports the feature,
+// logging when a configured flag has to be dropped
+func (h *Handler) srtlaFlag(caps system.SRTLACapabilities, feature system.SRTLAFeature, enabled bool) bool {
+	if enabled && !caps.Supports(feature) {
+		h.logOutput("manager", fmt.Sprintf("[SRTLA] srtla_send %s does not support %s, ignoring", caps.Version, feature))
+		return false
+	}
+	return enabled
+}
+
+// reloadSRTLAIPs hands a new bind IP list to the running transport. SRT
+// bonding and srtla_send releases without SIGHUP reload are restarted instead.
+func (h *Handler) reloadSRTLAIPs(ips []string) error {
+	cfg := h.config.Get()
+	if h.ActiveTransport() == transport.SRTBonding {
+		h.logOutput("manager", "[SRTLA] Restarting SRT bonding to apply new bind IPs")
+	} else if system.DetectSRTLA(cfg.SRTLA.BinaryPath).Supports(system.SRTLAFeatureIPsReload) {
+		return h.srtla.ReloadIPs(h.applyLinkPolicies(&cfg, ips))
+	} else {
+		h.logOutput("manager", "[SRTLA] srtla_send cannot reload IPs, restarting")
+	}
+
+	h.srtla.Stop()
+	return h.startSRTLA(&cfg, ips)
+}
+
 // ========== Camera Helper Methods ==========
 
 func getDeviceIP(h *Handler) string {
@@ -490,18 +935,86 @@ func getDeviceIP(h *Handler) string {
 	return ""
 }
 
-func (h *Handler) buildPreviewConfig(ssid, password, rtmpURL string) *dji.StreamConfig {
+// previewMode is where a DJI camera preview is sent
+type previewMode int
+
+const (
+	previewSeparate previewMode = iota // lighter stream to its own receiver on port 9999
+	previewReuse                       // full-quality live stream to the main ingest
+	previewWarm                        // lighter stream to the main ingest, promoted in place
+)
+
+// previewSettings merges the built-in preview defaults, the global
+// dji_preview settings and the camera's own override
+func (h *Handler) previewSettings(cameraID string) (settings config.DJIPreviewConfig, mode previewMode) {
+	settings = config.DJIPreviewConfig{Resolution: string(dji.Resolution720p), FPS: 15, BitrateKbps: 1000}
+	var reuse, warm bool
+
+	layers := []config.DJIPreviewConfig{h.config.Get().DJIPreview}
+	if cam, ok := h.config.LoadCameraConfig(cameraID); ok && cam.Preview != nil {
+		layers = append(layers, *cam.Preview)
+	}
+	for _, p := range layers {
+		if p.Resolution != "" {
+			settings.Resolution = p.Resolution
+		}
+		if p.FPS != 0 {
+			settings.FPS = p.FPS
+		}
+		if p.BitrateKbps != 0 {
+			settings.BitrateKbps = p.BitrateKbps
+		}
+		if p.ReuseStream != nil {
+			reuse = *p.ReuseStream
+		}
+		if p.WarmStandby != nil {
+			warm = *p.WarmStandby
+		}
+	}
+
+	switch {
+	case reuse:
+		mode = previewReuse
+	case warm:
+		mode = previewWarm
+	}
+	return settings, mode
+}
+
+func (h *Handler) buildPreviewConfig(cameraID, ssid, password, rtmpURL string) *dji.StreamConfig {
+	settings, _ := h.previewSettings(cameraID)
 	return &dji.StreamConfig{
 		WiFiSSID:      strings.TrimSpace(ssid),
 		WiFiPassword:  strings.TrimSpace(password),
 		RTMPURL:       strings.TrimSpace(rtmpURL),
-		Resolution:    dji.Resolution720p,
-		FPS:           15,
-		BitrateKbps:   1000,
+		Resolution:    dji.StreamResolution(settings.Resolution),
+		FPS:           settings.FPS,
+		BitrateKbps:   uint16(settings.BitrateKbps),
 		Stabilization: dji.StabilizationOff,
 	}
 }
 
+// validateCameraConfigRequest checks the settings sent to a DJI camera. Empty
+// optional fields fall back to the defaults in buildStreamConfig.
+func validateCameraConfigRequest(req CameraConfigRequest) error {
+	v := validate.New()
+	v.Required("wifi_ssid", req.WiFiSSID)
+	v.SSID("wifi_ssid", req.WiFiSSID)
+	v.Passphrase("wifi_password", req.WiFiPassword)
+	v.Required("rtmp_url", req.RTMPURL)
+	v.URL("rtmp_url", req.RTMPURL, "rtmp", "rtmps")
+	v.OneOf("resolution", req.Resolution,
+		string(dji.Resolution480p), string(dji.Resolution720p), string(dji.Resolution1080p))
+	if req.FPS != 0 && req.FPS != 25 && req.FPS != 30 {
+		v.Addf("fps", "%d is not supported (25 or 30)", req.FPS)
+	}
+	v.Bitrate("bitrate_kbps", int(req.BitrateKbps))
+	v.OneOf("stabilization", req.Stabilization,
+		string(dji.StabilizationOff), string(dji.StabilizationRockSteady), string(dji.StabilizationHorizonSteady),
+		string(dji.StabilizationRockSteadyPlus), string(dji.StabilizationHorizonBalance))
+	return v.Err()
+}
+
 func (h *Handler) buildStreamConfig(req CameraConfigRequest) *dji.StreamConfig {
 	config := &dji.StreamConfig{
 		WiFiSSID:      strings.TrimSpace(req.WiFiSSID),
@@ -521,6 +1034,10 @@ func (h *Handler) buildStreamConfig(req CameraConfigRequest) *dji.StreamConfig {
 	}
 	if config.BitrateKbps == 0 {
 		config.BitrateKbps = 6000
+		cfg := h.config.Get()
+		if p, ok := cfg.Profiles[cfg.Profile]; ok && p.CameraBitrateKbps != 0 {
+			config.BitrateKbps = uint16(p.CameraBitrateKbps)
+		}
 	}
 	if config.Stabilization == "" {
 		config.Stabilization = dji.StabilizationOff
@@ -615,11 +1132,14 @@ func (h *Handler) GetUSBNetStatus() USBNetResponse {
 	if devices == nil {
 		devices = []usbnet.DeviceStatus{}
 	}
-	return USBNetResponse{Devices: devices}
+	return USBNetResponse{Devices: devices, Mappings: h.usb.Mappings()}
 }
 
 func (h *Handler) GetModemStatus() ModemsResponse {
 	modems, _ := h.modem.ListModems()
+	h.modemsMu.Lock()
+	h.modems = modems
+	h.modemsMu.Unlock()
 	resp := ModemsResponse{
 		Available: h.modem.IsAvailable(),
 		Modems:    modems,
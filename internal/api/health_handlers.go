@@ -0,0 +1,125 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"srtla-manager/internal/system"
+)
+
+// HealthLoopThreshold is how long the stats loop may go without ticking
+// before /healthz reports the process as unhealthy.
+const HealthLoopThreshold = 5 * time.Second
+
+// HealthCheck is the result of a single readiness check
+type HealthCheck struct {
+	OK      bool   `json:"ok"`
+	Message string `json:"message,omitempty"`
+}
+
+// HealthResponse is returned by /healthz and /readyz
+type HealthResponse struct {
+	Status string                 `json:"status"`
+	Uptime int64                  `json:"uptime"`
+	Checks map[string]HealthCheck `json:"checks,omitempty"`
+}
+
+// RecordLoopTick marks the background stats loop as alive. Called once per tick.
+func (h *Handler) RecordLoopTick() {
+	h.loopHeartbeat.Store(time.Now().UnixNano())
+}
+
+// loopAlive reports whether the background loop has ticked recently.
+// During startup, before the first tick, the process is given the benefit of the doubt.
+func (h *Handler) loopAlive() bool {
+	last := h.loopHeartbeat.Load()
+	if last == 0 {
+		return h.uptime() < HealthLoopThreshold
+	}
+	return time.Since(time.Unix(0, last)) < HealthLoopThreshold
+}
+
+// HandleHealthz reports liveness (GET /healthz). Never spawns subprocesses.
+func (h *Handler) HandleHealthz(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	resp := HealthResponse{
+		Status: "ok",
+		Uptime: int64(h.uptime().Seconds()),
+	}
+	code := http.StatusOK
+	if !h.loopAlive() {
+		resp.Status = "unhealthy"
+		resp.Checks = map[string]HealthCheck{
+			"stats_loop": {OK: false, Message: "background loop has not ticked recently"},
+		}
+		code = http.StatusServiceUnavailable
+	}
+
+	writeHealth(w, code, resp)
+}
+
+// HandleReadyz reports whether a pipeline could start (GET /readyz).
+// Dependency checks only resolve paths; they never execute binaries.
+func (h *Handler) HandleReadyz(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	cfg := h.config.Get()
+	checks := make(map[string]HealthCheck)
+
+	if err := cfg.Validate(); err != nil {
+		checks["config"] = HealthCheck{OK: false, Message: err.Error()}
+	} else {
+		checks["config"] = HealthCheck{OK: true}
+	}
+
+	if system.IsFFmpegInstalled() {
+		checks["ffmpeg"] = HealthCheck{OK: true}
+	} else {
+		checks["ffmpeg"] = HealthCheck{OK: false, Message: "ffmpeg not found in PATH"}
+	}
+
+	if cfg.SRTLA.Enabled {
+		if system.FindSRTLA(cfg.SRTLA.BinaryPath) != "" {
+			checks["srtla"] = HealthCheck{OK: true}
+		} else {
+			checks["srtla"] = HealthCheck{OK: false, Message: "srtla_send binary not found"}
+		}
+	}
+
+	if h.loopAlive() {
+		checks["stats_loop"] = HealthCheck{OK: true}
+	} else {
+		checks["stats_loop"] = HealthCheck{OK: false, Message: "background loop has not ticked recently"}
+	}
+
+	resp := HealthResponse{
+		Status: "ready",
+		Uptime: int64(h.uptime().Seconds()),
+		Checks: checks,
+	}
+	code := http.StatusOK
+	for _, c := range checks {
+		if !c.OK {
+			resp.Status = "not_ready"
+			code = http.StatusServiceUnavailable
+			break
+		}
+	}
+
+	writeHealth(w, code, resp)
+}
+
+func writeHealth(w http.ResponseWriter, code int, resp HealthResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(resp)
+}
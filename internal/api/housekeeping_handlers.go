@@ -0,0 +1,197 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"srtla-manager/internal/capture"
+	"srtla-manager/internal/events"
+	"srtla-manager/internal/logger"
+	"srtla-manager/internal/maintenance"
+	"srtla-manager/internal/process"
+	"srtla-manager/internal/updates"
+)
+
+// cameraPreviewDir is where a camera preview is written before streaming
+const cameraPreviewDir = "/tmp/srtla-preview-temp"
+
+// runHousekeeping tidies up while the unit is idle: logs are rotated and
+// compressed, old backups, capture segments and previews removed, updates
+// looked for and the self-test run. The pipeline lock is held throughout,
+// so nothing starts while files go.
+func (h *Handler) runHousekeeping(trigger string) (maintenance.Report, error) {
+	if h.GetPipelineMode() != PipelineModeIdle || h.ffmpeg.ProcessState() == process.StateRunning {
+		return maintenance.Report{}, errors.New("the pipeline is not idle")
+	}
+	h.maintenanceMu.Lock()
+	if h.housekeeping {
+		h.maintenanceMu.Unlock()
+		return maintenance.Report{}, errors.New("housekeeping is already running")
+	}
+	release, err := h.acquirePipeline("housekeeping")
+	if err != nil {
+		h.maintenanceMu.Unlock()
+		return maintenance.Report{}, err
+	}
+	h.housekeeping = true
+	h.maintenanceMu.Unlock()
+	defer release()
+
+	h.logOutput("manager", fmt.Sprintf("[MAINTENANCE] Housekeeping started (%s)", trigger))
+	cfg := h.config.Get()
+	report := maintenance.Report{Trigger: trigger, StartedAt: time.Now()}
+	report.Tasks = []maintenance.Task{
+		housekeepingLogs(),
+		housekeepingBackups(cfg.Maintenance.KeepBackups),
+		h.housekeepingCapture(cfg.Capture.Dir, cfg.Maintenance.KeepDays),
+		h.housekeepingPreviews(),
+		h.housekeepingUpdateCheck(),
+		h.housekeepingSelfTest(),
+	}
+	report.FinishedAt = time.Now()
+
+	h.maintenanceMu.Lock()
+	h.housekeeping = false
+	h.housekeepingLast = &report
+	h.maintenanceMu.Unlock()
+	events.Publish(h.bus, TopicHousekeeping, report)
+
+	for _, line := range strings.Split(report.Text(), "\n") {
+		h.logOutput("manager", "[MAINTENANCE] "+line)
+	}
+	if cfg.Maintenance.Report && !h.notifyText("maintenance_report", report.Text()) {
+		h.logOutput("manager", "[MAINTENANCE] Report not sent: notifications are off")
+	}
+	return report, nil
+}
+
+func housekeepingLogs() maintenance.Task {
+	task := maintenance.Task{Name: "logs", OK: true}
+	rotated, saved, err := logger.Compact()
+	task.Freed = saved
+	task.Detail = fmt.Sprintf("%d rotated", rotated)
+	if err != nil {
+		task.OK, task.Detail = false, err.Error()
+	}
+	return task
+}
+
+func housekeepingBackups(keep int) maintenance.Task {
+	if keep <= 0 {
+		keep = maintenance.DefaultKeepBackups
+	}
+	task := maintenance.Task{Name: "backups", OK: true}
+	isBackup := func(name string) bool { return strings.HasSuffix(name, ".bak") }
+	removed, freed, err := maintenance.Prune(backupDir, isBackup, keep, time.Time{})
+	task.Removed, task.Freed = removed, freed
+	if err != nil {
+		task.OK, task.Detail = false, err.Error()
+	}
+	return task
+}
+
+// housekeepingCapture removes capture segments left behind in dir. While
+// capturing the recorder keeps its own window, so they are left alone.
+func (h *Handler) housekeepingCapture(dir string, keepDays int) maintenance.Task {
+	task := maintenance.Task{Name: "capture", OK: true}
+	h.captureMu.Lock()
+	capturing := h.capture != nil
+	h.captureMu.Unlock()
+	if dir == "" || capturing {
+		task.Detail = "skipped"
+		return task
+	}
+	if keepDays <= 0 {
+		keepDays = maintenance.DefaultKeepDays
+	}
+	cutoff := time.Now().AddDate(0, 0, -keepDays)
+	removed, freed, err := maintenance.Prune(dir, capture.IsSegment, 0, cutoff)
+	task.Removed, task.Freed = removed, freed
+	if err != nil {
+		task.OK, task.Detail = false, err.Error()
+	}
+	return task
+}
+
+// housekeepingPreviews removes the preview directories, which only hold
+// anything while a preview runs
+func (h *Handler) housekeepingPreviews() maintenance.Task {
+	task := maintenance.Task{Name: "previews", OK: true}
+	for _, dir := range []string{h.previewDir, cameraPreviewDir} {
+		if dir == "" {
+			continue
+		}
+		size := dirSize(dir)
+		if err := os.RemoveAll(dir); err != nil {
+			task.OK, task.Detail = false, err.Error()
+			continue
+		}
+		task.Freed += size
+	}
+	return task
+}
+
+func dirSize(dir string) int64 {
+	var size int64
+	filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			if info, err := d.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
+
+func (h *Handler) housekeepingUpdateCheck() maintenance.Task {
+	task := maintenance.Task{Name: "update check", OK: true}
+	version := h.GetVersion()
+	if version == "" {
+		version = "v0.0.0-dev"
+	}
+	info, err := updates.NewChecker(version).CheckForUpdates()
+	switch {
+	case err != nil:
+		task.OK, task.Detail = false, err.Error()
+	case info != nil && info.Available:
+		task.Detail = fmt.Sprintf("%s available", info.LatestVersion)
+	default:
+		task.Detail = "up to date"
+	}
+	return task
+}
+
+func (h *Handler) housekeepingSelfTest() maintenance.Task {
+	task := maintenance.Task{Name: "self-test", OK: true}
+	var failed []string
+	for _, check := range h.selfTest() {
+		if !check.OK {
+			failed = append(failed, strings.TrimSpace(check.Name+" "+check.Detail))
+		}
+	}
+	if len(failed) > 0 {
+		task.OK, task.Detail = false, strings.Join(failed, "; ")
+	}
+	return task
+}
+
+// HandleHousekeeping handles POST /api/maintenance/housekeeping, tidying
+// up now instead of waiting for the window
+func (h *Handler) HandleHousekeeping(w http.ResponseWriter, r *http.Request) {
+	report, err := h.runHousekeeping("manual")
+	if err != nil {
+		pipelineError(w, err, http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,138 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"srtla-manager/internal/alerts"
+	"srtla-manager/internal/config"
+	"srtla-manager/internal/events"
+	"srtla-manager/internal/logger"
+	"srtla-manager/internal/process"
+)
+
+// ingestStaleAfter is how long without progress from ffmpeg counts as the
+// camera having stopped sending
+const ingestStaleAfter = 5 * time.Second
+
+// IngestStatus is what the camera is delivering evaluated against the
+// configured minimums
+type IngestStatus struct {
+	process.IngestStats
+	Enabled       bool     `json:"enabled"`
+	MinBitrate    int      `json:"min_bitrate"`
+	MinFPSPercent int      `json:"min_fps_percent"`
+	Degraded      bool     `json:"degraded"` // problems have lasted hold_seconds
+	Problems      []string `json:"problems,omitempty"`
+}
+
+// IngestStatus evaluates the latest ingest measurement
+func (h *Handler) IngestStatus() IngestStatus {
+	cfg := h.config.Get().Ingest
+	ff := h.ffmpeg.Stats()
+	status := IngestStatus{
+		IngestStats:   ff.Ingest,
+		Enabled:       cfg.Enabled,
+		MinBitrate:    cfg.MinBitrate,
+		MinFPSPercent: cfg.MinFPSPercent,
+	}
+	status.Problems, _ = h.ingestProblems(cfg, ff)
+
+	h.ingestMu.Lock()
+	status.Degraded = h.ingestDegraded
+	h.ingestMu.Unlock()
+	return status
+}
+
+// ingestProblems lists what is wrong with the camera's input. It reports
+// false when there is nothing to judge: monitoring is off, the camera isn't
+// publishing or it is expected to reconnect.
+func (h *Handler) ingestProblems(cfg config.IngestConfig, ff process.FFmpegStats) ([]string, bool) {
+	in := ff.Ingest
+	if !cfg.Enabled || ff.State != process.FFmpegStreaming || !in.Valid || h.ffmpeg.PublisherInGrace() {
+		return nil, false
+	}
+
+	var problems []string
+	if idle := time.Since(in.LastUpdate); idle > ingestStaleAfter {
+		problems = append(problems, fmt.Sprintf("nothing received from the camera for %.0fs", idle.Seconds()))
+		return problems, true
+	}
+	if cfg.MinBitrate > 0 && in.Bitrate < float64(cfg.MinBitrate) {
+		problems = append(problems,
+			fmt.Sprintf("camera bitrate %.0f kbps is below %d kbps", in.Bitrate, cfg.MinBitrate))
+	}
+	if cfg.MinFPSPercent > 0 && in.DeclaredFPS > 0 && in.FPS < in.DeclaredFPS*float64(cfg.MinFPSPercent)/100 {
+		problems = append(problems,
+			fmt.Sprintf("camera frame rate %.1f fps is below %d%% of %.0f fps", in.FPS, cfg.MinFPSPercent, in.DeclaredFPS))
+	}
+	return problems, true
+}
+
+// CheckIngest evaluates the camera's input and broadcasts an ingest_alert
+// when it degrades for longer than hold_seconds or recovers. It is called
+// from the stats loop.
+func (h *Handler) CheckIngest() IngestStatus {
+	cfg := h.config.Get().Ingest
+	ff := h.ffmpeg.Stats()
+	problems, judged := h.ingestProblems(cfg, ff)
+	now := time.Now()
+
+	h.ingestMu.Lock()
+	was := h.ingestDegraded
+	switch {
+	case !judged:
+		// the stream stopping or the camera leaving isn't a recovery
+		h.ingestLowSince = time.Time{}
+		h.ingestDegraded = false
+		was = false
+	case len(problems) == 0:
+		h.ingestLowSince = time.Time{}
+		h.ingestDegraded = false
+	default:
+		if h.ingestLowSince.IsZero() {
+			h.ingestLowSince = now
+		}
+		h.ingestDegraded = now.Sub(h.ingestLowSince) >= time.Duration(cfg.HoldSeconds)*time.Second
+	}
+	degraded := h.ingestDegraded
+	h.ingestMu.Unlock()
+
+	status := IngestStatus{
+		IngestStats:   ff.Ingest,
+		Enabled:       cfg.Enabled,
+		MinBitrate:    cfg.MinBitrate,
+		MinFPSPercent: cfg.MinFPSPercent,
+		Degraded:      degraded,
+		Problems:      problems,
+	}
+
+	if degraded != was {
+		a := alerts.Alert{Event: alerts.EventInputWeak}
+		if degraded {
+			for _, p := range problems {
+				logger.Warn("Camera input degraded: %s", p)
+			}
+		} else {
+			a.Event = alerts.EventInputRecovered
+			logger.Info("Camera input recovered (%.0f kbps, %.1f fps)", status.Bitrate, status.FPS)
+		}
+		events.Publish(h.bus, TopicIngestAlert, status)
+
+		h.alertsMu.Lock()
+		played := h.playAlertsLocked([]alerts.Alert{a})
+		h.alertsMu.Unlock()
+		h.logAlerts(played)
+		h.notifyAlerts([]alerts.Alert{a})
+	}
+
+	return status
+}
+
+// HandleIngest handles GET /api/ingest
+func (h *Handler) HandleIngest(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.IngestStatus())
+}
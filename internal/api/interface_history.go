@@ -0,0 +1,101 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"srtla-manager/internal/stats"
+)
+
+// sinkInterfaceHistory is the in-memory interface traffic history
+const sinkInterfaceHistory = "interface_history"
+
+// InterfaceHistoryResponse is the response for GET
+// /api/system/interfaces/history
+type InterfaceHistoryResponse struct {
+	Interfaces map[string][]stats.InterfacePoint `json:"interfaces"`
+	Stored     bool                              `json:"stored"` // points older than the in-memory history came from the stats store
+}
+
+// registerInterfaceMetrics describes the interface counters, adds
+// /proc/net/dev as their source and keeps their history in memory
+func (h *Handler) registerInterfaceMetrics() {
+	h.stats.Register(
+		stats.Metric{Name: stats.MetricInterfaceRxBytes, Help: "Bytes each network interface has received", Kind: stats.Counter},
+		stats.Metric{Name: stats.MetricInterfaceTxBytes, Help: "Bytes each network interface has sent", Kind: stats.Counter},
+	)
+	h.stats.AddSource(interfaceMetrics)
+
+	h.interfaceHistory = stats.NewInterfaceHistory(stats.InterfaceHistorySize)
+	h.stats.SetSink(sinkInterfaceHistory, h.interfaceHistory)
+}
+
+// interfaceMetrics adds the counters of every interface, whether or not
+// the stream uses it
+func interfaceMetrics(add stats.AddFunc) {
+	for name, c := range stats.ReadNetDev() {
+		add(stats.MetricInterfaceRxBytes, float64(c.RxBytes), "interface", name)
+		add(stats.MetricInterfaceTxBytes, float64(c.TxBytes), "interface", name)
+	}
+}
+
+// HandleInterfaceHistory handles GET
+// /api/system/interfaces/history?since=&interface=. since is an RFC 3339
+// time or a duration back from now and defaults to the last 5 minutes;
+// interface limits the response to one. The last 10 minutes are kept in
+// memory at the stats interval; older points come from the stats store
+// when it is enabled.
+func (h *Handler) HandleInterfaceHistory(w http.ResponseWriter, r *http.Request) {
+	since := time.Now().Add(-5 * time.Minute)
+	if s := r.URL.Query().Get("since"); s != "" {
+		if t, err := time.Parse(time.RFC3339, s); err == nil {
+			since = t
+		} else if d, err := time.ParseDuration(s); err == nil {
+			since = time.Now().Add(-d)
+		} else {
+			jsonError(w, fmt.Sprintf("Invalid since %q: use an RFC 3339 time or a duration such as 30m", s), http.StatusBadRequest)
+			return
+		}
+	}
+
+	names := h.interfaceHistory.Interfaces()
+	if name := r.URL.Query().Get("interface"); name != "" {
+		names = []string{name}
+	}
+
+	resp := InterfaceHistoryResponse{Interfaces: make(map[string][]stats.InterfacePoint)}
+	var snaps []stats.Snapshot
+	store := h.config.Get().Metrics.Store
+	for _, name := range names {
+		points := h.interfaceHistory.History(name, since)
+		oldest, ok := h.interfaceHistory.Oldest(name)
+		if store.Enabled && (!ok || since.Before(oldest)) {
+			if snaps == nil {
+				var err error
+				if snaps, err = stats.ReadStore(store.Path, since); err != nil {
+					jsonError(w, fmt.Sprintf("Failed to read stats: %v", err), http.StatusInternalServerError)
+					return
+				}
+			}
+			var stored []stats.InterfacePoint
+			for _, snap := range snaps {
+				if p, found := stats.InterfacePoints(snap)[name]; found && (!ok || p.Timestamp.Before(oldest)) {
+					stored = append(stored, p)
+				}
+			}
+			if len(stored) > 0 {
+				stats.InterfaceRates(stored)
+				resp.Stored = true
+				points = append(stored, points...)
+			}
+		}
+		if len(points) > 0 {
+			resp.Interfaces[name] = points
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
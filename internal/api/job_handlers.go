@@ -0,0 +1,44 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"srtla-manager/internal/jobs"
+)
+
+// HandleJobList handles GET /api/jobs
+func (h *Handler) HandleJobList(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.jobs.List())
+}
+
+// HandleJobGet handles GET /api/jobs/{id}
+func (h *Handler) HandleJobGet(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.jobs.Get(r.PathValue("id"))
+	if !ok {
+		jsonError(w, "Job not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+// HandleJobCancel handles DELETE /api/jobs/{id}
+func (h *Handler) HandleJobCancel(w http.ResponseWriter, r *http.Request) {
+	job, err := h.jobs.Cancel(r.PathValue("id"))
+	switch {
+	case errors.Is(err, jobs.ErrNotFound):
+		jsonError(w, "Job not found", http.StatusNotFound)
+		return
+	case errors.Is(err, jobs.ErrFinished):
+		jsonError(w, "Job already finished", http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
@@ -0,0 +1,174 @@
+package api
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"srtla-manager/internal"
+	"srtla-manager/internal/config"
+	"srtla-manager/internal/system"
+)
+
+// SRTLAIPsResponse is the bind IP list with its link policies and what is
+// in force
+type SRTLAIPsResponse struct {
+	IPs      []string                     `json:"ips"`
+	Policies map[string]config.LinkPolicy `json:"policies"`
+	InUse    []string                     `json:"in_use"` // links handed to the bonding process
+	Failover bool                         `json:"failover"`
+	Caps     map[string]int               `json:"caps"` // interface caps in force, kbps
+}
+
+// selectLinks orders ips by priority, leaving out failover-only links while
+// any regular link is among them
+func selectLinks(policies map[string]config.LinkPolicy, ips []string) []string {
+	var regular, failover []string
+	for _, ip := range ips {
+		if policies[ip].FailoverOnly {
+			failover = append(failover, ip)
+		} else {
+			regular = append(regular, ip)
+		}
+	}
+
+	selected := regular
+	if len(selected) == 0 {
+		selected = failover
+	}
+	sort.SliceStable(selected, func(i, j int) bool {
+		return policies[selected[i]].Priority < policies[selected[j]].Priority
+	})
+	return selected
+}
+
+// applyLinkPolicies picks the links of ips the bonding process may use,
+// caps their interfaces and records the choice. It is called whenever the
+// bonding process is started or given new IPs.
+func (h *Handler) applyLinkPolicies(cfg *config.Config, ips []string) []string {
+	policies := cfg.SRTLA.LinkPolicies
+	selected := selectLinks(policies, ips)
+	failover := len(selected) > 0 && policies[selected[0]].FailoverOnly
+
+	h.transportMu.Lock()
+	was := h.onFailover
+	h.srtlaLinks = selected
+	h.onFailover = failover
+	h.transportMu.Unlock()
+
+	if failover && !was {
+		h.logOutput("manager", fmt.Sprintf("[SRTLA] No regular link is up, using failover links: %s", strings.Join(selected, ", ")))
+	} else if !failover && was {
+		h.logOutput("manager", "[SRTLA] Regular links are back, leaving failover links idle")
+	}
+
+	h.applyLinkCaps(policies, ips)
+	return selected
+}
+
+// linkSelectionChanged reports whether the policies pick different links
+// from available than the bonding process was last given, as when the last
+// regular link goes down and failover links have to take over
+func (h *Handler) linkSelectionChanged(cfg *config.Config, available []string) bool {
+	selected := selectLinks(cfg.SRTLA.LinkPolicies, available)
+
+	h.transportMu.Lock()
+	defer h.transportMu.Unlock()
+	if len(selected) != len(h.srtlaLinks) {
+		return true
+	}
+	inUse := make(map[string]bool, len(h.srtlaLinks))
+	for _, ip := range h.srtlaLinks {
+		inUse[ip] = true
+	}
+	for _, ip := range selected {
+		if !inUse[ip] {
+			return true
+		}
+	}
+	return false
+}
+
+// applyLinkCaps caps the interfaces of ips with a max_kbps policy through
+// the privileged installer, and lifts caps no longer configured. srtla_send
+// has no per-link limit; it backs off a link that drops packets, so shaping
+// the interface holds the link near its cap. Failures are logged and don't
+// stop the stream.
+func (h *Handler) applyLinkCaps(policies map[string]config.LinkPolicy, ips []string) {
+	ifaceOf := make(map[string]string)
+	for _, iface := range system.ListNetworkInterfaces() {
+		for _, ip := range iface.IPs {
+			ifaceOf[ip] = iface.Name
+		}
+	}
+
+	want := make(map[string]int)
+	for _, ip := range ips {
+		if p := policies[ip]; p.MaxKbps > 0 && ifaceOf[ip] != "" {
+			want[ifaceOf[ip]] = p.MaxKbps
+		}
+	}
+
+	h.linkCapsMu.Lock()
+	defer h.linkCapsMu.Unlock()
+	if h.linkCaps == nil {
+		h.linkCaps = make(map[string]int)
+	}
+
+	for iface := range h.linkCaps {
+		if _, ok := want[iface]; !ok {
+			want[iface] = 0
+		}
+	}
+	for iface, kbps := range want {
+		if h.linkCaps[iface] == kbps {
+			continue
+		}
+		resp, err := internal.ShapeInterfaceWithInstaller(iface, kbps)
+		if err == nil && !resp.Success {
+			err = fmt.Errorf("%s", resp.Message)
+		}
+		if err != nil {
+			h.logOutput("manager", fmt.Sprintf("[WARNING] Failed to cap %s at %d kbps: %v", iface, kbps, err))
+			continue
+		}
+		if kbps == 0 {
+			delete(h.linkCaps, iface)
+			h.logOutput("manager", fmt.Sprintf("[SRTLA] Removed cap on %s", iface))
+		} else {
+			h.linkCaps[iface] = kbps
+			h.logOutput("manager", fmt.Sprintf("[SRTLA] Capped %s at %d kbps", iface, kbps))
+		}
+	}
+}
+
+func (h *Handler) srtlaIPsStatus() SRTLAIPsResponse {
+	cfg := h.config.Get()
+	resp := SRTLAIPsResponse{
+		IPs:      cfg.SRTLA.BindIPs,
+		Policies: cfg.SRTLA.LinkPolicies,
+		InUse:    []string{},
+		Caps:     make(map[string]int),
+	}
+	if resp.IPs == nil {
+		resp.IPs = []string{}
+	}
+	if resp.Policies == nil {
+		resp.Policies = make(map[string]config.LinkPolicy)
+	}
+
+	streaming := h.GetPipelineMode() == PipelineModeStreaming
+	h.transportMu.Lock()
+	if streaming {
+		resp.InUse = append(resp.InUse, h.srtlaLinks...)
+		resp.Failover = h.onFailover
+	}
+	h.transportMu.Unlock()
+
+	h.linkCapsMu.Lock()
+	for iface, kbps := range h.linkCaps {
+		resp.Caps[iface] = kbps
+	}
+	h.linkCapsMu.Unlock()
+	return resp
+}
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"srtla-manager/internal/events"
+	"srtla-manager/internal/linkprobe"
+)
+
+// LinkProbeStatus is the reachability of one bind IP
+type LinkProbeStatus struct {
+	linkprobe.Status
+	Interface string `json:"interface,omitempty"`
+}
+
+// LinkProbeResponse is the response of GET /api/links/probe
+type LinkProbeResponse struct {
+	Enabled bool              `json:"enabled"`
+	Method  string            `json:"method,omitempty"`
+	Target  string            `json:"target,omitempty"`
+	Links   []LinkProbeStatus `json:"links"`
+}
+
+// ApplyLinkProbeConfig starts or stops probing the bind IPs to match the
+// configuration
+func (h *Handler) ApplyLinkProbeConfig() {
+	cfg := h.config.Get().LinkProbe
+
+	h.probeMu.Lock()
+	if h.probeApplied != nil && *h.probeApplied == cfg {
+		h.probeMu.Unlock()
+		return
+	}
+	wasProbing := h.stopLinkProbeLocked()
+	h.probeApplied = &cfg
+	if cfg.Enabled {
+		settings := linkprobe.Settings{
+			Method:    cfg.Method,
+			Target:    cfg.Target,
+			Interval:  time.Duration(cfg.IntervalSeconds) * time.Second,
+			Timeout:   time.Duration(cfg.TimeoutSeconds) * time.Second,
+			FailAfter: cfg.FailAfter,
+			PassAfter: cfg.PassAfter,
+		}
+		monitor := linkprobe.NewMonitor(linkprobe.New(settings), settings, h.onLinkProbeChange)
+		ctx, cancel := context.WithCancel(context.Background())
+		h.probeMonitor, h.probeCancel = monitor, cancel
+		go monitor.Run(ctx, h.linkProbeTargets, h.reloadProbedLinks)
+	}
+	h.probeMu.Unlock()
+
+	if wasProbing && !cfg.Enabled {
+		// links held out by their probes come back
+		h.reloadProbedLinks()
+	}
+}
+
+// StopLinkProbe stops probing on shutdown
+func (h *Handler) StopLinkProbe() {
+	h.probeMu.Lock()
+	defer h.probeMu.Unlock()
+	h.stopLinkProbeLocked()
+}
+
+// stopLinkProbeLocked reports whether links were being probed. h.probeMu
+// must be held.
+func (h *Handler) stopLinkProbeLocked() bool {
+	if h.probeCancel == nil {
+		return false
+	}
+	h.probeCancel()
+	h.probeMonitor, h.probeCancel = nil, nil
+	return true
+}
+
+// linkProbeTargets returns the bind IPs of the stream that are on an
+// interface that is up
+func (h *Handler) linkProbeTargets() []string {
+	cfg := h.config.Get()
+	up := systemIPv4s()
+	var ips []string
+	for _, ip := range h.bindIPs(&cfg) {
+		if ip = strings.TrimSpace(ip); up[ip] {
+			ips = append(ips, ip)
+		}
+	}
+	return ips
+}
+
+// linkProbeFilter drops the bind IPs whose probes don't pass. When none
+// pass, the problem is more likely the probe target than every link, so
+// all are kept rather than stopping the stream.
+func (h *Handler) linkProbeFilter(ips []string) []string {
+	h.probeMu.Lock()
+	monitor := h.probeMonitor
+	h.probeMu.Unlock()
+	if monitor == nil {
+		return ips
+	}
+	var passing []string
+	for _, ip := range ips {
+		if monitor.Passing(ip) {
+			passing = append(passing, ip)
+		}
+	}
+	if len(passing) == 0 {
+		return ips
+	}
+	return passing
+}
+
+// onLinkProbeChange logs and publishes a link going up or down
+func (h *Handler) onLinkProbeChange(st linkprobe.Status) {
+	ev := LinkProbeStatus{Status: st, Interface: interfaceOf(st.BindIP)}
+	msg := fmt.Sprintf("[LINK-PROBE] %s (%s) is %s", st.BindIP, ev.Interface, st.State)
+	if st.LastError != "" {
+		msg += ": " + st.LastError
+	}
+	h.logOutput("manager", msg)
+	events.Publish(h.bus, TopicLinkProbe, ev)
+}
+
+// reloadProbedLinks hands srtla_send the links that pass after a round of
+// probes changed some
+func (h *Handler) reloadProbedLinks() {
+	if err := h.reloadSwapLinks(); err != nil {
+		h.logOutput("manager", fmt.Sprintf("[LINK-PROBE] Failed to reload links: %v", err))
+	}
+}
+
+// HandleLinkProbe handles GET /api/links/probe
+func (h *Handler) HandleLinkProbe(w http.ResponseWriter, r *http.Request) {
+	cfg := h.config.Get().LinkProbe
+	resp := LinkProbeResponse{Enabled: cfg.Enabled, Links: []LinkProbeStatus{}}
+
+	h.probeMu.Lock()
+	monitor := h.probeMonitor
+	h.probeMu.Unlock()
+	if monitor != nil {
+		resp.Method, resp.Target = cfg.Method, cfg.Target
+		for _, st := range monitor.Statuses() {
+			resp.Links = append(resp.Links, LinkProbeStatus{Status: st, Interface: interfaceOf(st.BindIP)})
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
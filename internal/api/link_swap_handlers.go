@@ -0,0 +1,335 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"time"
+
+	"srtla-manager/internal/config"
+	"srtla-manager/internal/events"
+	"srtla-manager/internal/process"
+	"srtla-manager/internal/system"
+)
+
+// Steps of a modem swap, in order
+const (
+	SwapDraining     = "draining"                // taking the link out of the bond
+	SwapSafeToRemove = "safe_to_remove"          // the bond no longer uses it; unplug the modem
+	SwapWaiting      = "waiting_for_replacement" // the old modem is gone
+	SwapDetected     = "replacement_detected"    // a new link came up and is being set up
+	SwapCompleted    = "completed"               // the replacement took over the link's settings
+	SwapCancelled    = "cancelled"
+	SwapFailed       = "failed"
+)
+
+// swapPollInterval is how often a swap checks for the modem being removed
+// and its replacement
+const swapPollInterval = 2 * time.Second
+
+// swapTimeout is how long a swap may take before the old link is given back
+const swapTimeout = 15 * time.Minute
+
+// LinkSwap is a modem being replaced mid-stream. Each change of step is
+// published as a link_swap event.
+type LinkSwap struct {
+	OldIP     string             `json:"old_ip"`
+	NewIP     string             `json:"new_ip,omitempty"`
+	Interface string             `json:"interface,omitempty"` // of the old modem
+	Policy    *config.LinkPolicy `json:"policy,omitempty"`    // carried over to the replacement
+	Step      string             `json:"step"`
+	Message   string             `json:"message"`
+	StartedAt time.Time          `json:"started_at"`
+	UpdatedAt time.Time          `json:"updated_at"`
+
+	present map[string]bool // IPs up when the old modem went away
+}
+
+// done reports whether the swap has ended
+func (s *LinkSwap) done() bool {
+	return s.Step == SwapCompleted || s.Step == SwapCancelled || s.Step == SwapFailed
+}
+
+// drainingLink returns the IP of the link being swapped, "" for none
+func (h *Handler) drainingLink() string {
+	h.swapMu.Lock()
+	defer h.swapMu.Unlock()
+	if h.swap == nil || h.swap.done() {
+		return ""
+	}
+	return h.swap.OldIP
+}
+
+// HandleLinkSwapStatus handles GET /api/links/swap
+func (h *Handler) HandleLinkSwapStatus(w http.ResponseWriter, r *http.Request) {
+	h.swapMu.Lock()
+	var swap *LinkSwap
+	if h.swap != nil {
+		copied := *h.swap
+		swap = &copied
+	}
+	h.swapMu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]*LinkSwap{"swap": swap})
+}
+
+// HandleLinkSwapStart handles POST /api/links/{ip}/swap. The link is taken
+// out of the bond straight away; the swap then waits for its modem to be
+// unplugged and a replacement to come up, and gives the replacement the
+// old link's place in the bind IPs and its policy.
+func (h *Handler) HandleLinkSwapStart(w http.ResponseWriter, r *http.Request) {
+	ip := r.PathValue("ip")
+	cfg := h.config.Get()
+	if !slices.Contains(h.bindIPs(&cfg), ip) {
+		jsonError(w, fmt.Sprintf("%s is not a bind IP", ip), http.StatusNotFound)
+		return
+	}
+
+	streaming := h.srtla.ProcessState() == process.StateRunning
+	if streaming {
+		remaining := 0
+		for _, available := range h.getAvailableBindIPs(&cfg) {
+			if available != ip {
+				remaining++
+			}
+		}
+		if remaining == 0 {
+			jsonError(w, fmt.Sprintf("%s is the only link up; swapping it would drop the stream", ip), http.StatusConflict)
+			return
+		}
+	}
+
+	now := time.Now()
+	swap := &LinkSwap{OldIP: ip, Interface: interfaceOf(ip), StartedAt: now, UpdatedAt: now}
+	if policy, ok := cfg.SRTLA.LinkPolicies[ip]; ok {
+		swap.Policy = &policy
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), swapTimeout)
+	h.swapMu.Lock()
+	if h.swap != nil && !h.swap.done() {
+		busy := h.swap.OldIP
+		h.swapMu.Unlock()
+		cancel()
+		jsonError(w, fmt.Sprintf("%s is already being swapped", busy), http.StatusConflict)
+		return
+	}
+	h.swap = swap
+	h.swapCancel = cancel
+	h.swapMu.Unlock()
+
+	h.setSwapStep(SwapDraining, "", "Taking %s out of the bond", ip)
+	if err := h.reloadSwapLinks(); err != nil {
+		h.endLinkSwap(SwapFailed, "Failed to take %s out of the bond: %v", ip, err)
+		jsonError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.setSwapStep(SwapSafeToRemove, "", "%s is out of the bond, the modem can be unplugged", ip)
+
+	go h.watchLinkSwap(ctx, swap)
+
+	h.HandleLinkSwapStatus(w, r)
+}
+
+// HandleLinkSwapCancel handles DELETE /api/links/swap. The old link goes
+// back into the bond if it is still up.
+func (h *Handler) HandleLinkSwapCancel(w http.ResponseWriter, r *http.Request) {
+	h.swapMu.Lock()
+	active := h.swap != nil && !h.swap.done()
+	h.swapMu.Unlock()
+	if !active {
+		jsonError(w, "No modem swap in progress", http.StatusConflict)
+		return
+	}
+
+	h.endLinkSwap(SwapCancelled, "Swap cancelled")
+	h.HandleLinkSwapStatus(w, r)
+}
+
+// watchLinkSwap moves the swap along as the old modem goes away and its
+// replacement comes up
+func (h *Handler) watchLinkSwap(ctx context.Context, swap *LinkSwap) {
+	ticker := time.NewTicker(swapPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if ctx.Err() == context.DeadlineExceeded {
+				h.endLinkSwap(SwapFailed, "No replacement came up within %s", swapTimeout)
+			}
+			return
+		case <-ticker.C:
+		}
+
+		h.swapMu.Lock()
+		if h.swap != swap || swap.done() {
+			h.swapMu.Unlock()
+			return
+		}
+		step := swap.Step
+		present := swap.present
+		h.swapMu.Unlock()
+
+		up := systemIPv4s()
+		switch step {
+		case SwapSafeToRemove:
+			if !up[swap.OldIP] {
+				h.setSwapStep(SwapWaiting, "", "%s is gone, waiting for the replacement modem", swap.OldIP)
+				h.swapMu.Lock()
+				swap.present = up
+				h.swapMu.Unlock()
+			}
+		case SwapWaiting:
+			var added []string
+			for ip := range up {
+				if !present[ip] {
+					added = append(added, ip)
+				}
+			}
+			if len(added) > 0 {
+				slices.Sort(added)
+				h.completeLinkSwap(swap, added[0])
+				return
+			}
+		}
+	}
+}
+
+// completeLinkSwap puts newIP in the old link's place in the bind IPs and
+// bind sets, hands it the old link's policy and adds it to the bond
+func (h *Handler) completeLinkSwap(swap *LinkSwap, newIP string) {
+	h.setSwapStep(SwapDetected, newIP, "Replacement modem came up as %s on %s", newIP, interfaceOf(newIP))
+
+	cfg := h.config.Get()
+	cfg.SRTLA.BindIPs = replaceIP(cfg.SRTLA.BindIPs, swap.OldIP, newIP)
+	if len(cfg.SRTLA.BindSets) > 0 {
+		sets := make(map[string][]string, len(cfg.SRTLA.BindSets))
+		for name, ips := range cfg.SRTLA.BindSets {
+			sets[name] = replaceIP(ips, swap.OldIP, newIP)
+		}
+		cfg.SRTLA.BindSets = sets
+	}
+	if swap.Policy != nil {
+		policies := make(map[string]config.LinkPolicy, len(cfg.SRTLA.LinkPolicies))
+		for ip, policy := range cfg.SRTLA.LinkPolicies {
+			if ip != swap.OldIP {
+				policies[ip] = policy
+			}
+		}
+		policies[newIP] = *swap.Policy
+		cfg.SRTLA.LinkPolicies = policies
+	}
+	if err := h.config.Update(cfg); err != nil {
+		h.endLinkSwap(SwapFailed, "Failed to save %s in place of %s: %v", newIP, swap.OldIP, err)
+		return
+	}
+
+	h.endLinkSwap(SwapCompleted, "%s replaced %s", newIP, swap.OldIP)
+}
+
+// endLinkSwap finishes the swap and reloads the bond so it uses the old
+// link again or its replacement
+func (h *Handler) endLinkSwap(step, format string, args ...any) {
+	h.swapMu.Lock()
+	if h.swapCancel != nil {
+		h.swapCancel()
+		h.swapCancel = nil
+	}
+	h.swapMu.Unlock()
+
+	h.setSwapStep(step, "", format, args...)
+	if err := h.reloadSwapLinks(); err != nil {
+		h.logOutput("manager", fmt.Sprintf("[SWAP] Failed to reload links: %v", err))
+	}
+}
+
+// setSwapStep records and publishes a step of the current swap. A newIP
+// other than "" is recorded with it.
+func (h *Handler) setSwapStep(step, newIP, format string, args ...any) {
+	h.swapMu.Lock()
+	swap := h.swap
+	if swap == nil {
+		h.swapMu.Unlock()
+		return
+	}
+	swap.Step = step
+	if newIP != "" {
+		swap.NewIP = newIP
+	}
+	swap.Message = fmt.Sprintf(format, args...)
+	swap.UpdatedAt = time.Now()
+	copied := *swap
+	h.swapMu.Unlock()
+
+	h.logOutput("manager", "[SWAP] "+copied.Message)
+	events.Publish(h.bus, TopicLinkSwap, copied)
+}
+
+// reloadSwapLinks hands the bonding process the links that are up, leaving
+// out one being swapped and any over their data budget. It does nothing
+// while not streaming.
+func (h *Handler) reloadSwapLinks() error {
+	if h.GetPipelineMode() != PipelineModeStreaming || h.srtla.ProcessState() != process.StateRunning {
+		return nil
+	}
+	cfg := h.config.Get()
+	ips := h.getAvailableBindIPs(&cfg)
+	if len(ips) == 0 {
+		return fmt.Errorf("no bind IPs available")
+	}
+	if err := h.reloadSRTLAIPs(ips); err != nil {
+		return err
+	}
+	h.activeBindIPs = ips
+	return nil
+}
+
+// interfaceOf returns the interface with ip, "" if none has it
+func interfaceOf(ip string) string {
+	for _, iface := range system.ListNetworkInterfaces() {
+		if slices.Contains(iface.IPs, ip) {
+			return iface.Name
+		}
+	}
+	return ""
+}
+
+// systemIPv4s returns the IPv4 addresses of the interfaces that are up,
+// other than loopback
+func systemIPv4s() map[string]bool {
+	ips := make(map[string]bool)
+	for _, iface := range system.ListNetworkInterfaces() {
+		if iface.IsLoopback || !iface.IsUp {
+			continue
+		}
+		for _, ip := range iface.IPs {
+			if parsed := net.ParseIP(ip); parsed != nil && parsed.To4() != nil {
+				ips[ip] = true
+			}
+		}
+	}
+	return ips
+}
+
+// replaceIP returns ips with old replaced by ip, without duplicating ip.
+// A list without old is returned as it is.
+func replaceIP(ips []string, old, ip string) []string {
+	if !slices.Contains(ips, old) {
+		return ips
+	}
+	out := make([]string, 0, len(ips))
+	for _, existing := range ips {
+		if existing == old {
+			existing = ip
+		}
+		if !slices.Contains(out, existing) {
+			out = append(out, existing)
+		}
+	}
+	return out
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"srtla-manager/internal/events"
+	"srtla-manager/internal/logger"
+	"srtla-manager/internal/process"
+)
+
+// LoudnessStatus is the current R128 measurement evaluated against the configured targets
+type LoudnessStatus struct {
+	process.LoudnessStats
+	Enabled     bool     `json:"enabled"`
+	TargetLUFS  float64  `json:"target_lufs"`
+	ToleranceLU float64  `json:"tolerance_lu"`
+	MaxTruePeak float64  `json:"max_true_peak"`
+	Compliant   bool     `json:"compliant"`
+	Violations  []string `json:"violations,omitempty"`
+}
+
+// LoudnessStatus evaluates the latest ffmpeg loudness measurement
+func (h *Handler) LoudnessStatus() LoudnessStatus {
+	cfg := h.config.Get().Loudness
+	status := LoudnessStatus{
+		LoudnessStats: h.ffmpeg.Stats().Loudness,
+		Enabled:       cfg.Enabled,
+		TargetLUFS:    cfg.TargetLUFS,
+		ToleranceLU:   cfg.ToleranceLU,
+		MaxTruePeak:   cfg.MaxTruePeak,
+		Compliant:     true,
+	}
+
+	// Integrated loudness is meaningless until enough audio has been measured
+	if !cfg.Enabled || !status.Valid || status.Elapsed < float64(cfg.MinDuration) {
+		return status
+	}
+
+	if deviation := status.Integrated - cfg.TargetLUFS; math.Abs(deviation) > cfg.ToleranceLU {
+		status.Violations = append(status.Violations,
+			fmt.Sprintf("integrated loudness %.1f LUFS is outside %.1f ±%.1f LU", status.Integrated, cfg.TargetLUFS, cfg.ToleranceLU))
+	}
+	if status.TruePeak > cfg.MaxTruePeak {
+		status.Violations = append(status.Violations,
+			fmt.Sprintf("true peak %.1f dBTP exceeds %.1f dBTP", status.TruePeak, cfg.MaxTruePeak))
+	}
+	status.Compliant = len(status.Violations) == 0
+
+	return status
+}
+
+// audioSilenceLevel is the RMS level below which every channel must be for
+// the audio to count as silent
+const audioSilenceLevel = -60.0
+
+// audioLevelsStale is how old the levels may be before they are dropped,
+// since they stop updating rather than going quiet when audio stops
+const audioLevelsStale = 2 * time.Second
+
+// AudioStatus is the per-channel audio level for the VU meter
+type AudioStatus struct {
+	process.AudioLevels
+	Enabled bool `json:"enabled"`
+	Silent  bool `json:"silent"` // every channel is below -60 dBFS RMS
+}
+
+// AudioStatus returns the latest audio levels
+func (h *Handler) AudioStatus() AudioStatus {
+	status := AudioStatus{
+		AudioLevels: h.ffmpeg.Stats().Audio,
+		Enabled:     h.config.Get().Loudness.Meters,
+	}
+	if !status.Valid || time.Since(status.LastUpdate) > audioLevelsStale {
+		status.Valid, status.Channels = false, nil
+		return status
+	}
+
+	status.Silent = true
+	for _, ch := range status.Channels {
+		if ch.RMS >= audioSilenceLevel {
+			status.Silent = false
+		}
+	}
+	return status
+}
+
+// ApplyAudioConfig hands the audio source settings to ffmpeg, which picks
+// them up the next time the RTMP pipeline starts
+func (h *Handler) ApplyAudioConfig() {
+	cfg := h.config.Get().Audio
+	h.ffmpeg.SetAudioMix(process.AudioMixConfig{
+		Source:  cfg.Source,
+		Backend: cfg.Backend,
+		Device:  cfg.Device,
+		GainDB:  cfg.GainDB,
+		DelayMs: cfg.DelayMs,
+	})
+}
+
+// CheckLoudness evaluates loudness compliance and broadcasts a loudness_alert
+// whenever the feed moves in or out of spec. It is called from the stats loop.
+func (h *Handler) CheckLoudness() LoudnessStatus {
+	status := h.LoudnessStatus()
+
+	h.loudnessMu.Lock()
+	changed := status.Compliant == h.loudnessAlerting
+	h.loudnessAlerting = !status.Compliant
+	h.loudnessMu.Unlock()
+
+	if changed {
+		if status.Compliant {
+			logger.Info("Audio loudness back within target (%.1f LUFS)", status.Integrated)
+		} else {
+			for _, v := range status.Violations {
+				logger.Warn("Audio loudness out of spec: %s", v)
+			}
+		}
+		events.Publish(h.bus, TopicLoudnessAlert, status)
+	}
+
+	return status
+}
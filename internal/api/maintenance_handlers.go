@@ -0,0 +1,280 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"srtla-manager/internal"
+	"srtla-manager/internal/events"
+	"srtla-manager/internal/jobs"
+	"srtla-manager/internal/maintenance"
+	"srtla-manager/internal/process"
+	"srtla-manager/internal/system"
+)
+
+// maintenanceInterval is how often the scheduler checks the maintenance window
+const maintenanceInterval = time.Minute
+
+// selfTestServices must be active after an OS update
+var selfTestServices = []string{"NetworkManager", "ModemManager"}
+
+// SelfTestCheck is one check run after an OS update
+type SelfTestCheck struct {
+	Name   string `json:"name"`
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// OSUpdateResult reports an OS update and the self-test after it
+type OSUpdateResult struct {
+	Method         string          `json:"method"`
+	Trigger        string          `json:"trigger"` // "manual" or "schedule"
+	Success        bool            `json:"success"`
+	Message        string          `json:"message"`
+	RebootRequired bool            `json:"reboot_required"`
+	SelfTest       []SelfTestCheck `json:"self_test,omitempty"`
+	StartedAt      time.Time       `json:"started_at"`
+	FinishedAt     time.Time       `json:"finished_at"`
+}
+
+// MaintenanceStatus reports the maintenance window, the last OS update and
+// the last housekeeping
+type MaintenanceStatus struct {
+	OSUpdate         string              `json:"os_update"`
+	Scheduled        bool                `json:"scheduled"`
+	Housekeeping     bool                `json:"housekeeping"`
+	InWindow         bool                `json:"in_window"`
+	NextWindow       *time.Time          `json:"next_window,omitempty"`
+	Running          bool                `json:"running"`
+	Last             *OSUpdateResult     `json:"last,omitempty"`
+	LastHousekeeping *maintenance.Report `json:"last_housekeeping,omitempty"`
+}
+
+// StartMaintenanceScheduler tidies up and runs the configured OS update
+// once in each maintenance window. A window that finds the stream running
+// is retried until it closes.
+func (h *Handler) StartMaintenanceScheduler(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(maintenanceInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			h.checkMaintenanceWindow(time.Now())
+		}
+	}()
+}
+
+func (h *Handler) checkMaintenanceWindow(now time.Time) {
+	cfg := h.config.Get().Maintenance
+	update := cfg.Scheduled && cfg.OSUpdate != ""
+	if !update && !cfg.Housekeeping {
+		return
+	}
+	window, err := cfg.Window()
+	if err != nil {
+		return
+	}
+	opened, ok := window.Current(now)
+	if !ok {
+		return
+	}
+
+	h.maintenanceMu.Lock()
+	tidied := !h.housekeepingDone.Before(opened)
+	done := !h.maintenanceDone.Before(opened)
+	h.maintenanceMu.Unlock()
+
+	// tidy up first, so the update runs on a unit that is known to be well
+	if cfg.Housekeeping && !tidied {
+		if _, err := h.runHousekeeping("schedule"); err != nil {
+			h.logOutput("manager", fmt.Sprintf("[MAINTENANCE] Maintenance window open, housekeeping postponed: %v", err))
+			return
+		}
+		h.maintenanceMu.Lock()
+		h.housekeepingDone = opened
+		h.maintenanceMu.Unlock()
+	}
+	if !update || done {
+		return
+	}
+
+	if _, err := h.startOSUpdate(cfg.OSUpdate, "schedule"); err != nil {
+		h.logOutput("manager", fmt.Sprintf("[OSUPDATE] Maintenance window open, update postponed: %v", err))
+		return
+	}
+	h.maintenanceMu.Lock()
+	h.maintenanceDone = opened
+	h.maintenanceMu.Unlock()
+}
+
+// startOSUpdate runs an OS update as a job once the pre-checks pass. The
+// pipeline lock is held throughout, so nothing starts streaming while
+// packages are replaced.
+func (h *Handler) startOSUpdate(method, trigger string) (jobs.Job, error) {
+	if h.GetPipelineMode() == PipelineModeStreaming {
+		return jobs.Job{}, errors.New("the stream is running")
+	}
+	h.maintenanceMu.Lock()
+	if h.osUpdating {
+		h.maintenanceMu.Unlock()
+		return jobs.Job{}, errors.New("an OS update is already running")
+	}
+	release, err := h.acquirePipeline("os_update")
+	if err != nil {
+		h.maintenanceMu.Unlock()
+		return jobs.Job{}, err
+	}
+	h.osUpdating = true
+	h.maintenanceMu.Unlock()
+
+	h.logOutput("manager", fmt.Sprintf("[OSUPDATE] Starting %s update (%s)", method, trigger))
+
+	job := h.jobs.Start("os_update", func(ctx context.Context, report *jobs.Reporter) (interface{}, error) {
+		defer release()
+		result := h.runOSUpdate(method, trigger, report)
+
+		h.maintenanceMu.Lock()
+		h.osUpdating = false
+		h.osUpdateLast = &result
+		h.maintenanceMu.Unlock()
+		events.Publish(h.bus, TopicOSUpdate, result)
+
+		if !result.Success {
+			h.logOutput("manager", fmt.Sprintf("[OSUPDATE] Failed: %s", result.Message))
+			return result, errors.New(result.Message)
+		}
+		h.logOutput("manager", fmt.Sprintf("[OSUPDATE] %s", result.Message))
+		return result, nil
+	})
+	return job, nil
+}
+
+func (h *Handler) runOSUpdate(method, trigger string, report *jobs.Reporter) OSUpdateResult {
+	result := OSUpdateResult{Method: method, Trigger: trigger, StartedAt: time.Now()}
+	defer func() { result.FinishedAt = time.Now() }()
+
+	report.Progress(5, "Running %s update", method)
+	resp, err := internal.OSUpdateWithInstaller(method, func(line string) {
+		h.logOutput("osupdate", line)
+		report.Progress(50, "%s", line)
+	})
+	if err != nil {
+		result.Message = err.Error()
+		return result
+	}
+	result.RebootRequired = resp.RebootRequired
+	if !resp.Success {
+		result.Message = resp.Message
+		return result
+	}
+
+	report.Progress(90, "Running self-test")
+	result.SelfTest = h.selfTest()
+	result.Success = true
+	result.Message = resp.Message
+	for _, check := range result.SelfTest {
+		if !check.OK {
+			result.Success = false
+			result.Message = fmt.Sprintf("%s, but self-test failed: %s %s", resp.Message, check.Name, check.Detail)
+			break
+		}
+	}
+	report.Progress(100, "%s", result.Message)
+	return result
+}
+
+// selfTest checks the pieces an upgrade could have broken: the binaries the
+// pipeline runs, the network daemons, the configuration and the receiving
+// ffmpeg and srtla_rec
+func (h *Handler) selfTest() []SelfTestCheck {
+	var checks []SelfTestCheck
+	for _, unit := range selfTestServices {
+		state := system.ServiceState(unit)
+		checks = append(checks, SelfTestCheck{Name: unit, OK: state == "active", Detail: state})
+	}
+	return append(checks, h.configSelfTest()...)
+}
+
+// configSelfTest checks what a configuration change can break: the
+// binaries it points at, the configuration itself and the processes it
+// keeps running
+func (h *Handler) configSelfTest() []SelfTestCheck {
+	cfg := h.config.Get()
+	var checks []SelfTestCheck
+
+	for _, dep := range []system.DependencyStatus{system.CheckFFmpeg(), system.CheckSRTLA(cfg.SRTLA.BinaryPath)} {
+		check := SelfTestCheck{Name: dep.Name, OK: dep.Installed, Detail: dep.Version}
+		if !dep.Installed {
+			check.Detail = "not installed"
+		}
+		checks = append(checks, check)
+	}
+
+	check := SelfTestCheck{Name: "config", OK: true}
+	if err := cfg.Validate(); err != nil {
+		check.OK, check.Detail = false, err.Error()
+	}
+	checks = append(checks, check)
+
+	if h.GetPipelineMode() == PipelineModeReceiving {
+		state := h.ffmpeg.ProcessState()
+		checks = append(checks, SelfTestCheck{Name: "receiver", OK: state == process.StateRunning, Detail: string(state)})
+	}
+	if cfg.Receiver.Enabled {
+		state := h.srtlaRec.ProcessState()
+		checks = append(checks, SelfTestCheck{Name: "srtla_rec", OK: state == process.StateRunning, Detail: string(state)})
+	}
+	return checks
+}
+
+// HandleMaintenanceStatus handles GET /api/maintenance
+func (h *Handler) HandleMaintenanceStatus(w http.ResponseWriter, r *http.Request) {
+	cfg := h.config.Get().Maintenance
+	status := MaintenanceStatus{OSUpdate: cfg.OSUpdate, Scheduled: cfg.Scheduled, Housekeeping: cfg.Housekeeping}
+	if window, err := cfg.Window(); err == nil && (cfg.Scheduled || cfg.Housekeeping) {
+		now := time.Now()
+		_, status.InWindow = window.Current(now)
+		next := window.Next(now)
+		status.NextWindow = &next
+	}
+
+	h.maintenanceMu.Lock()
+	status.Running = h.osUpdating
+	status.Last = h.osUpdateLast
+	status.LastHousekeeping = h.housekeepingLast
+	h.maintenanceMu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// HandleOSUpdate handles POST /api/maintenance/os-update, running the
+// configured OS update now instead of waiting for the window
+func (h *Handler) HandleOSUpdate(w http.ResponseWriter, r *http.Request) {
+	method := h.config.Get().Maintenance.OSUpdate
+	if method == "" {
+		jsonError(w, "OS updates are disabled; set maintenance.os_update", http.StatusBadRequest)
+		return
+	}
+
+	job, err := h.startOSUpdate(method, "manual")
+	if err != nil {
+		pipelineError(w, err, http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"status": "started",
+		"method": method,
+		"job_id": job.ID,
+	})
+}
@@ -0,0 +1,185 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"srtla-manager/internal/events"
+	"srtla-manager/internal/logger"
+	"srtla-manager/internal/scte35"
+	"srtla-manager/internal/validate"
+)
+
+const (
+	MarkerCueOut = "cue_out"
+	MarkerCueIn  = "cue_in"
+
+	MarkerPending   = "pending"
+	MarkerAnnounced = "announced"
+	MarkerSkipped   = "skipped"
+
+	maxMarkerHistory  = 100
+	maxMarkerSchedule = 24 * time.Hour
+)
+
+// MarkerRequest is the request body for POST /api/markers. The splice fires
+// at At when given, otherwise OffsetMs after the request.
+type MarkerRequest struct {
+	Type            string    `json:"type"`
+	EventID         uint32    `json:"event_id"`
+	At              time.Time `json:"at"`
+	OffsetMs        int       `json:"offset_ms"`
+	DurationSeconds float64   `json:"duration_seconds"`
+	AutoReturn      bool      `json:"auto_return"`
+}
+
+// Marker is a scheduled SCTE-35 splice. It is announced, not injected: the
+// encoded splice_info_section goes out over the API and a "marker"
+// WebSocket message, while the MPEG-TS itself carries no cue, since the SRT
+// leg is written by ffmpeg directly and cannot be modified in flight.
+type Marker struct {
+	EventID     uint32     `json:"event_id"`
+	Type        string     `json:"type"`
+	Status      string     `json:"status"`
+	ScheduledAt time.Time  `json:"scheduled_at"`
+	AnnouncedAt *time.Time `json:"announced_at,omitempty"`
+	Duration    float64    `json:"duration_seconds,omitempty"`
+	Timecode    string     `json:"timecode,omitempty"` // SMPTE timecode when announced, if timecode is enabled
+	Section     string     `json:"section"`            // base64 splice_info_section
+}
+
+// HandleMarkerList handles GET /api/markers
+func (h *Handler) HandleMarkerList(w http.ResponseWriter, r *http.Request) {
+	h.markersMu.Lock()
+	markers := make([]Marker, 0, len(h.markers))
+	for _, m := range h.markers {
+		markers = append(markers, *m)
+	}
+	h.markersMu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(markers)
+}
+
+// HandleMarkerCreate handles POST /api/markers
+func (h *Handler) HandleMarkerCreate(w http.ResponseWriter, r *http.Request) {
+	var req MarkerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	v := validate.New()
+	v.Required("type", req.Type)
+	v.OneOf("type", req.Type, MarkerCueOut, MarkerCueIn)
+	if req.DurationSeconds < 0 {
+		v.Addf("duration_seconds", "must not be negative")
+	}
+	if req.OffsetMs < 0 {
+		v.Addf("offset_ms", "must not be negative")
+	}
+	if err := v.Err(); err != nil {
+		validationError(w, err)
+		return
+	}
+
+	now := time.Now()
+	at := now.Add(time.Duration(req.OffsetMs) * time.Millisecond)
+	if !req.At.IsZero() {
+		at = req.At
+	}
+	if at.Before(now.Add(-time.Second)) {
+		jsonError(w, "Requested time is in the past", http.StatusBadRequest)
+		return
+	}
+	if at.After(now.Add(maxMarkerSchedule)) {
+		jsonError(w, "Requested time is more than 24h ahead", http.StatusBadRequest)
+		return
+	}
+
+	if h.GetPipelineMode() != PipelineModeStreaming {
+		jsonError(w, "Stream is not running", http.StatusConflict)
+		return
+	}
+
+	h.markersMu.Lock()
+	if req.EventID == 0 {
+		for {
+			h.nextMarkerID++
+			if !h.markerIDUsedLocked(h.nextMarkerID) {
+				break
+			}
+		}
+		req.EventID = h.nextMarkerID
+	} else if h.markerIDUsedLocked(req.EventID) {
+		h.markersMu.Unlock()
+		jsonError(w, fmt.Sprintf("Event ID %d is already in use by a recent marker", req.EventID), http.StatusConflict)
+		return
+	}
+	splice := &scte35.SpliceInsert{
+		EventID:      req.EventID,
+		OutOfNetwork: req.Type == MarkerCueOut,
+		Duration:     time.Duration(req.DurationSeconds * float64(time.Second)),
+		AutoReturn:   req.AutoReturn,
+	}
+	m := &Marker{
+		EventID:     req.EventID,
+		Type:        req.Type,
+		Status:      MarkerPending,
+		ScheduledAt: at,
+		Duration:    req.DurationSeconds,
+		Section:     base64.StdEncoding.EncodeToString(splice.Encode()),
+	}
+	h.markers = append(h.markers, m)
+	if len(h.markers) > maxMarkerHistory {
+		h.markers = h.markers[len(h.markers)-maxMarkerHistory:]
+	}
+	snapshot := *m
+	h.markersMu.Unlock()
+
+	time.AfterFunc(time.Until(at), func() { h.emitMarker(m) })
+
+	logger.Info("Scheduled SCTE-35 %s (event %d) for %s", m.Type, m.EventID, at.Format(time.RFC3339))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(snapshot)
+}
+
+// markerIDUsedLocked reports whether a marker in the history has id
+func (h *Handler) markerIDUsedLocked(id uint32) bool {
+	for _, m := range h.markers {
+		if m.EventID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// emitMarker announces a due marker, skipping it if the stream has stopped
+func (h *Handler) emitMarker(m *Marker) {
+	streaming := h.GetPipelineMode() == PipelineModeStreaming
+
+	h.markersMu.Lock()
+	if streaming {
+		now := time.Now()
+		m.Status = MarkerAnnounced
+		m.AnnouncedAt = &now
+		m.Timecode = h.CurrentTimecode()
+	} else {
+		m.Status = MarkerSkipped
+	}
+	snapshot := *m
+	h.markersMu.Unlock()
+
+	if !streaming {
+		logger.Warn("Skipped SCTE-35 %s (event %d): stream is not running", m.Type, m.EventID)
+		return
+	}
+
+	logger.Info("Announced SCTE-35 %s (event %d) over the API; it is not in the stream", snapshot.Type, snapshot.EventID)
+	events.Publish(h.bus, TopicMarker, snapshot)
+}
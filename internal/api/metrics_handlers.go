@@ -0,0 +1,250 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"srtla-manager/internal/config"
+	"srtla-manager/internal/logger"
+	"srtla-manager/internal/stats"
+)
+
+// Names of the configurable stats sinks
+const (
+	sinkPrometheus = "prometheus"
+	sinkStore      = "store"
+	sinkMQTT       = "mqtt"
+)
+
+// MetricsStatus describes where stats are sent and the latest snapshot
+type MetricsStatus struct {
+	config.MetricsConfig
+	Errors map[string]string `json:"errors,omitempty"` // last error per failing sink
+	Latest *stats.Snapshot   `json:"latest,omitempty"`
+}
+
+// registerMetrics describes the pipeline metrics and adds them as a source
+func (h *Handler) registerMetrics() {
+	h.stats.Register(
+		stats.Metric{Name: "pipeline_streaming", Help: "1 while the stream is being sent", Kind: stats.Gauge},
+		stats.Metric{Name: stats.MetricFFmpegBitrate, Help: "Bitrate of the camera input in kbps", Kind: stats.Gauge},
+		stats.Metric{Name: stats.MetricFFmpegFPS, Help: "Frame rate of the camera input", Kind: stats.Gauge},
+		stats.Metric{Name: "ingest_bitrate_kbps", Help: "Bitrate received from the camera over the last few seconds", Kind: stats.Gauge},
+		stats.Metric{Name: "ingest_fps", Help: "Frames received from the camera per second over the last few seconds", Kind: stats.Gauge},
+		stats.Metric{Name: stats.MetricSRTLABitrate, Help: "Bitrate sent over the bonded links in kbps", Kind: stats.Gauge},
+		stats.Metric{Name: "srtla_link_bitrate_kbps", Help: "Bitrate sent over each link in kbps", Kind: stats.Gauge},
+		stats.Metric{Name: "srtla_link_rtt_ms", Help: "Round trip time of each link", Kind: stats.Gauge},
+		stats.Metric{Name: "srtla_link_naks_total", Help: "Packets each link had to resend", Kind: stats.Counter},
+		stats.Metric{Name: "bond_health_score", Help: "Health of the bonded uplink, 0 to 100", Kind: stats.Gauge},
+		stats.Metric{Name: "loudness_integrated_lufs", Help: "Integrated loudness of the outgoing audio", Kind: stats.Gauge},
+		stats.Metric{Name: "loudness_true_peak_dbtp", Help: "True peak of the outgoing audio", Kind: stats.Gauge},
+		stats.Metric{Name: "audio_peak_dbfs", Help: "Peak level of each channel of the outgoing audio", Kind: stats.Gauge},
+		stats.Metric{Name: "audio_rms_dbfs", Help: "RMS level of each channel of the outgoing audio", Kind: stats.Gauge},
+		stats.Metric{Name: "stream_drift_ms", Help: "Stream clock ahead of the system clock since the input started", Kind: stats.Gauge},
+		stats.Metric{Name: "uptime_seconds", Help: "Time since the manager started", Kind: stats.Counter},
+	)
+	h.stats.AddSource(h.pipelineMetrics)
+}
+
+func (h *Handler) pipelineMetrics(add stats.AddFunc) {
+	ff := h.ffmpeg.Stats()
+	sr := h.srtla.Stats()
+
+	streaming := 0.0
+	if h.GetPipelineMode() == PipelineModeStreaming {
+		streaming = 1
+	}
+	add("pipeline_streaming", streaming)
+	add(stats.MetricFFmpegBitrate, ff.Bitrate)
+	add(stats.MetricFFmpegFPS, ff.FPS)
+	if ff.Ingest.Valid {
+		add("ingest_bitrate_kbps", ff.Ingest.Bitrate)
+		add("ingest_fps", ff.Ingest.FPS)
+	}
+	add(stats.MetricSRTLABitrate, sr.TotalBitrate)
+	for _, c := range sr.Connections {
+		add("srtla_link_bitrate_kbps", c.Bitrate, "ip", c.IP)
+		add("srtla_link_rtt_ms", c.RTT, "ip", c.IP)
+		add("srtla_link_naks_total", float64(c.NAKs), "ip", c.IP)
+	}
+	if score := h.linkHealth.Last(); score != nil {
+		add("bond_health_score", float64(score.Score))
+	}
+	if ff.Loudness.Valid {
+		add("loudness_integrated_lufs", ff.Loudness.Integrated)
+		add("loudness_true_peak_dbtp", ff.Loudness.TruePeak)
+	}
+	if audio := h.AudioStatus(); audio.Valid {
+		for i, ch := range audio.Channels {
+			channel := strconv.Itoa(i + 1)
+			add("audio_peak_dbfs", ch.Peak, "channel", channel)
+			add("audio_rms_dbfs", ch.RMS, "channel", channel)
+		}
+	}
+	if ff.Drift.Valid {
+		add("stream_drift_ms", ff.Drift.DriftMs)
+	}
+	add("uptime_seconds", time.Since(h.startTime).Seconds())
+}
+
+// ApplyMetricsConfig adds, replaces or removes the configurable sinks. Sinks
+// whose settings didn't change are left running.
+func (h *Handler) ApplyMetricsConfig() {
+	cfg := h.config.Get().Metrics
+
+	h.metricsMu.Lock()
+	defer h.metricsMu.Unlock()
+
+	var applied config.MetricsConfig
+	if h.metricsApplied != nil {
+		applied = *h.metricsApplied
+	}
+	h.metricsApplied = &cfg
+
+	if cfg.Prometheus != applied.Prometheus {
+		h.prometheus = nil
+		if cfg.Prometheus {
+			h.prometheus = stats.NewPrometheus(h.stats)
+			h.stats.SetSink(sinkPrometheus, h.prometheus)
+		} else {
+			h.stats.SetSink(sinkPrometheus, nil)
+		}
+	}
+
+	if cfg.Store != applied.Store {
+		h.stats.SetSink(sinkStore, nil)
+		if cfg.Store.Enabled {
+			store, err := stats.NewStore(cfg.Store.Path,
+				time.Duration(cfg.Store.IntervalSeconds)*time.Second, int64(cfg.Store.MaxMB)<<20)
+			if err != nil {
+				logger.Error("Stats: %v", err)
+				cfg.Store = config.MetricsStoreConfig{} // retry on the next apply
+			} else {
+				h.stats.SetSink(sinkStore, store)
+				h.logOutput("manager", fmt.Sprintf("[STATS] Storing stats in %s", cfg.Store.Path))
+			}
+		}
+	}
+
+	if cfg.MQTT != applied.MQTT {
+		h.stats.SetSink(sinkMQTT, nil)
+		if cfg.MQTT.Enabled {
+			hostname, _ := os.Hostname()
+			m, err := stats.NewMQTT(stats.MQTTOptions{
+				Broker:   cfg.MQTT.Broker,
+				Topic:    cfg.MQTT.Topic,
+				ClientID: "srtla-manager-" + hostname,
+				Username: cfg.MQTT.Username,
+				Password: cfg.MQTT.Password,
+				Every:    time.Duration(cfg.MQTT.IntervalSeconds) * time.Second,
+			})
+			if err != nil {
+				logger.Error("Stats: %v", err)
+				cfg.MQTT = config.MetricsMQTTConfig{}
+			} else {
+				h.stats.SetSink(sinkMQTT, m)
+				h.logOutput("manager", fmt.Sprintf("[STATS] Publishing stats to %s on %s", cfg.MQTT.Broker, cfg.MQTT.Topic))
+			}
+		}
+	}
+}
+
+// StopMetrics closes the configurable sinks on shutdown
+func (h *Handler) StopMetrics() {
+	h.metricsMu.Lock()
+	defer h.metricsMu.Unlock()
+	for _, name := range []string{sinkPrometheus, sinkStore, sinkMQTT} {
+		h.stats.SetSink(name, nil)
+	}
+	h.prometheus = nil
+	h.metricsApplied = nil
+}
+
+func (h *Handler) metricsStatus() MetricsStatus {
+	status := MetricsStatus{
+		MetricsConfig: h.config.Get().Metrics,
+		Errors:        h.stats.SinkErrors(),
+	}
+	history := h.stats.Snapshots()
+	if len(history) > 0 {
+		status.Latest = &history[len(history)-1]
+	}
+	return status
+}
+
+// HandleMetrics handles GET /metrics for Prometheus
+func (h *Handler) HandleMetrics(w http.ResponseWriter, r *http.Request) {
+	h.metricsMu.Lock()
+	p := h.prometheus
+	h.metricsMu.Unlock()
+	if p == nil {
+		jsonError(w, "Prometheus metrics are disabled", http.StatusNotFound)
+		return
+	}
+	p.ServeHTTP(w, r)
+}
+
+// HandleMetricsStatus handles GET /api/metrics
+func (h *Handler) HandleMetricsStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.metricsStatus())
+}
+
+// HandleMetricsUpdate handles PUT /api/metrics. Fields missing from the body
+// keep their current values.
+func (h *Handler) HandleMetricsUpdate(w http.ResponseWriter, r *http.Request) {
+	cfg := h.config.Get()
+	if err := json.NewDecoder(r.Body).Decode(&cfg.Metrics); err != nil {
+		jsonError(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.config.Update(cfg); err != nil {
+		validationError(w, err)
+		return
+	}
+
+	h.ApplyMetricsConfig()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.metricsStatus())
+}
+
+// HandleMetricsStored handles GET /api/metrics/stored?since=, returning the
+// snapshots kept on disk. since is an RFC 3339 time or a duration back from
+// now and defaults to the last hour.
+func (h *Handler) HandleMetricsStored(w http.ResponseWriter, r *http.Request) {
+	cfg := h.config.Get().Metrics.Store
+	if !cfg.Enabled {
+		jsonError(w, "The stats store is disabled", http.StatusNotFound)
+		return
+	}
+
+	since := time.Now().Add(-time.Hour)
+	if s := r.URL.Query().Get("since"); s != "" {
+		if t, err := time.Parse(time.RFC3339, s); err == nil {
+			since = t
+		} else if d, err := time.ParseDuration(s); err == nil {
+			since = time.Now().Add(-d)
+		} else {
+			jsonError(w, fmt.Sprintf("Invalid since %q: use an RFC 3339 time or a duration such as 30m", s), http.StatusBadRequest)
+			return
+		}
+	}
+
+	snaps, err := stats.ReadStore(cfg.Path, since)
+	if err != nil {
+		jsonError(w, fmt.Sprintf("Failed to read stats: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if snaps == nil {
+		snaps = []stats.Snapshot{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(snaps)
+}
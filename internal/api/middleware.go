@@ -0,0 +1,296 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"srtla-manager/internal/apikey"
+	"srtla-manager/internal/events"
+	"srtla-manager/internal/logger"
+)
+
+// previewPathPrefixes are served under the more permissive preview allowlist,
+// together with the MJPEG preview stream of USB cameras
+var previewPathPrefixes = []string{"/preview/", "/preview-temp/", "/return/", "/share/", "/overlay/"}
+
+// AccessMiddleware restricts the API/UI to clients inside the configured
+// allowlist. Preview paths use their own (usually broader) list so a director
+// can watch without reaching the control surface. Loopback is always allowed
+// to avoid locking out local administration. A request carrying an API key is
+// let in from anywhere, but only to what the key's scopes allow. Once any key
+// exists and the allowlist is empty, a request without one is refused unless
+// it comes from loopback or is for a preview. With preview tokens on, the HLS
+// previews need a valid token from everyone else.
+func (h *Handler) AccessMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cfg := h.config.Get()
+
+		if key := apikey.FromRequest(r); key != "" {
+			k, ok := lookupAPIKey(cfg.Access.APIKeys, key)
+			if !ok {
+				logger.Warn("Rejected request from %s to %s: invalid API key", r.RemoteAddr, r.URL.Path)
+				jsonError(w, "Invalid API key", http.StatusUnauthorized)
+				return
+			}
+			guest := isGuestKey(k)
+			if err := apikey.Window(k.NotBefore, k.ExpiresAt, time.Now()); err != nil {
+				logger.Warn("Rejected %s %s with API key %s: %v", r.Method, r.URL.Path, k.Name, err)
+				if guest {
+					h.auditGuest(k.ID, k.Name, r, http.StatusUnauthorized)
+				}
+				jsonError(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+			if !apikey.Allows(k.Scopes, r.Method, r.URL.Path) {
+				logger.Warn("Rejected %s %s with API key %s: outside its scopes", r.Method, r.URL.Path, k.Name)
+				if guest {
+					h.auditGuest(k.ID, k.Name, r, http.StatusForbidden)
+				}
+				jsonError(w, "Forbidden: outside the API key's scopes", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+
+			// a guest's actions are recorded; polling the status and
+			// fetching preview segments would only bury them
+			if guest && r.Method != http.MethodGet && r.Method != http.MethodHead {
+				status := http.StatusOK
+				if rec, ok := w.(*responseRecorder); ok && rec.status != 0 {
+					status = rec.status
+				}
+				h.auditGuest(k.ID, k.Name, r, status)
+			}
+			return
+		}
+
+		// with preview tokens on, the token decides instead of the allowlist
+		if prefix := previewTokenPrefix(r.URL.Path); prefix != "" && cfg.Access.PreviewTokens {
+			if h.checkPreviewToken(w, r, prefix) {
+				next.ServeHTTP(w, r)
+			}
+			return
+		}
+
+		preview := isPreviewPath(r.URL.Path)
+		allowed := cfg.Access.AllowedCIDRs
+		if preview {
+			allowed = cfg.Access.PreviewAllowedCIDRs
+		}
+
+		ip := remoteIP(r)
+		local := ip != nil && ip.IsLoopback()
+		switch {
+		case len(allowed) > 0:
+			if !local && (ip == nil || !ipInList(ip, allowed)) {
+				logger.Warn("Rejected request from %s to %s: not in access allowlist", r.RemoteAddr, r.URL.Path)
+				jsonError(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+		case !preview && len(cfg.Access.APIKeys) > 0:
+			// once keys are handed out, leaving the key off mustn't get
+			// past what it allows, so only loopback goes without one
+			if !local {
+				logger.Warn("Rejected request from %s to %s: API key required", r.RemoteAddr, r.URL.Path)
+				jsonError(w, "API key required", http.StatusUnauthorized)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// auditGuest records a request made with a guest operator key
+func (h *Handler) auditGuest(id, name string, r *http.Request, status int) {
+	events.Publish(h.bus, TopicGuestAction, GuestAction{
+		KeyID:      id,
+		Name:       name,
+		Method:     r.Method,
+		Path:       r.URL.Path,
+		Status:     status,
+		RemoteAddr: r.RemoteAddr,
+	})
+}
+
+func isPreviewPath(path string) bool {
+	if strings.HasPrefix(path, "/api/usbcams/") && strings.HasSuffix(path, "/preview-stream") {
+		return true
+	}
+	for _, prefix := range previewPathPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// remoteIP returns the peer address of the connection. Forwarding headers are
+// deliberately ignored since they are trivially spoofed by the client.
+func remoteIP(r *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return net.ParseIP(host)
+}
+
+// ipInList reports whether ip matches any CIDR or bare IP in entries.
+func ipInList(ip net.IP, entries []string) bool {
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			if network.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if other := net.ParseIP(entry); other != nil && other.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// RequestIDHeader carries the request ID in both directions. A well-formed ID
+// from an upstream proxy is reused so logs can be correlated end to end.
+const RequestIDHeader = "X-Request-ID"
+
+// RequestMiddleware assigns every request an ID, logs it, recovers from
+// handler panics and turns plain-text API errors (such as the mux's own 404
+// and 405 responses) into the JSON error envelope.
+func RequestMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+
+		rec := &responseRecorder{
+			ResponseWriter: w,
+			api:            strings.HasPrefix(r.URL.Path, "/api/"),
+		}
+		start := time.Now()
+
+		defer func() {
+			if v := recover(); v != nil {
+				if v == http.ErrAbortHandler {
+					panic(v)
+				}
+				logger.Error("Panic serving %s %s [%s]: %v\n%s", r.Method, r.URL.Path, id, v, debug.Stack())
+				if rec.wroteHeader && !rec.rewrite {
+					// Too late for an error response; abort the connection
+					panic(http.ErrAbortHandler)
+				}
+				rec.rewrite = false
+				rec.status = http.StatusInternalServerError
+				jsonError(w, "Internal server error", http.StatusInternalServerError)
+			}
+
+			if rec.rewrite {
+				jsonError(w, strings.TrimSpace(rec.body.String()), rec.status)
+			}
+
+			status := rec.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			if status >= http.StatusInternalServerError {
+				logger.Warn("%s %s -> %d (%v) [%s]", r.Method, r.URL.Path, status, time.Since(start), id)
+			} else {
+				logger.Debug("%s %s -> %d (%v) [%s]", r.Method, r.URL.Path, status, time.Since(start), id)
+			}
+		}()
+
+		next.ServeHTTP(rec, r)
+	})
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// responseRecorder captures the status code and holds back plain-text error
+// bodies on API paths so they can be re-emitted as JSON.
+type responseRecorder struct {
+	http.ResponseWriter
+	api         bool
+	status      int
+	wroteHeader bool
+	rewrite     bool
+	body        bytes.Buffer
+}
+
+func (rec *responseRecorder) WriteHeader(code int) {
+	if rec.wroteHeader {
+		return
+	}
+	rec.wroteHeader = true
+	rec.status = code
+
+	if rec.api && code >= http.StatusBadRequest && strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain") {
+		rec.rewrite = true
+		return
+	}
+	rec.ResponseWriter.WriteHeader(code)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	if !rec.wroteHeader {
+		rec.WriteHeader(http.StatusOK)
+	}
+	if rec.rewrite {
+		return rec.body.Write(b)
+	}
+	return rec.ResponseWriter.Write(b)
+}
+
+// Flush keeps streaming responses (MJPEG preview, proxies) working.
+func (rec *responseRecorder) Flush() {
+	if rec.rewrite {
+		return
+	}
+	if f, ok := rec.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack is needed for the WebSocket upgrade.
+func (rec *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := rec.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	rec.wroteHeader = true
+	rec.status = http.StatusSwitchingProtocols
+	return h.Hijack()
+}
+
+func (rec *responseRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
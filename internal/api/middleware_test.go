@@ -0,0 +1,113 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"srtla-manager/internal/apikey"
+	"srtla-manager/internal/config"
+)
+
+// newAccessHandler returns a handler on a default config in a temporary
+// directory, changed by edit
+func newAccessHandler(t *testing.T, edit func(*config.Config)) *Handler {
+	t.Helper()
+	cfg := config.NewManager(filepath.Join(t.TempDir(), "config.yaml"))
+	if err := cfg.Load(); err != nil {
+		t.Fatal(err)
+	}
+	c := cfg.Get()
+	edit(&c)
+	if err := cfg.Update(c); err != nil {
+		t.Fatal(err)
+	}
+	return &Handler{config: cfg}
+}
+
+func accessStatus(h *Handler, method, path, remoteAddr, key string) int {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	req := httptest.NewRequest(method, path, nil)
+	req.RemoteAddr = remoteAddr
+	if key != "" {
+		req.Header.Set(apikey.Header, key)
+	}
+	rec := httptest.NewRecorder()
+	h.AccessMiddleware(next).ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestAccessWithoutKeys(t *testing.T) {
+	h := newAccessHandler(t, func(c *config.Config) {})
+	if got := accessStatus(h, http.MethodPost, "/api/stream/stop", "192.168.1.50:40000", ""); got != http.StatusOK {
+		t.Errorf("Expected the LAN let in with no keys or allowlist, got %d", got)
+	}
+}
+
+func TestAccessAllowlistWithKeys(t *testing.T) {
+	h := newAccessHandler(t, func(c *config.Config) {
+		c.Access.AllowedCIDRs = []string{"192.168.1.0/24"}
+		c.Access.APIKeys = []config.APIKey{{
+			ID: "v1", Name: "stage manager", Scopes: []string{apikey.ScopeViewer},
+			Hash: apikey.Hash("viewer-secret"), CreatedAt: time.Now(),
+		}}
+	})
+	if got := accessStatus(h, http.MethodPost, "/api/stream/stop", "192.168.1.50:40000", ""); got != http.StatusOK {
+		t.Errorf("Expected an allowlisted client let in without a key, got %d", got)
+	}
+	if got := accessStatus(h, http.MethodPost, "/api/stream/stop", "10.0.0.5:40000", ""); got != http.StatusForbidden {
+		t.Errorf("Expected a client outside the allowlist refused, got %d", got)
+	}
+	if got := accessStatus(h, http.MethodPost, "/api/stream/stop", "10.0.0.5:40000", "viewer-secret"); got != http.StatusForbidden {
+		t.Errorf("Expected a viewer refused the stream, got %d", got)
+	}
+}
+
+func TestAccessViewerCantDropKey(t *testing.T) {
+	h := newAccessHandler(t, func(c *config.Config) {
+		c.Access.APIKeys = []config.APIKey{{
+			ID: "v1", Name: "stage manager", Scopes: []string{apikey.ScopeViewer},
+			Hash: apikey.Hash("viewer-secret"), CreatedAt: time.Now(),
+		}}
+	})
+	if got := accessStatus(h, http.MethodPost, "/api/stream/stop", "192.168.1.50:40000", ""); got != http.StatusUnauthorized {
+		t.Errorf("Expected a keyless request from the LAN refused, got %d", got)
+	}
+	if got := accessStatus(h, http.MethodPost, "/api/stream/stop", "[::1]:40000", ""); got != http.StatusOK {
+		t.Errorf("Expected loopback let in without a key, got %d", got)
+	}
+}
+
+func TestAccessGuestCantDropKey(t *testing.T) {
+	const guestKey = "guest-secret"
+	now := time.Now()
+	start, end := now.Add(-time.Hour), now.Add(time.Hour)
+	h := newAccessHandler(t, func(c *config.Config) {
+		c.Access.APIKeys = []config.APIKey{{
+			ID: "g1", Name: "Saturday match", Scopes: []string{apikey.ScopeGuest},
+			Hash: apikey.Hash(guestKey), CreatedAt: now, NotBefore: &start, ExpiresAt: &end,
+		}}
+	})
+
+	for _, tc := range []struct {
+		name         string
+		method, path string
+		remoteAddr   string
+		key          string
+		want         int
+	}{
+		{"keyless from the LAN", http.MethodPost, "/api/stream/stop", "192.168.1.50:40000", "", http.StatusUnauthorized},
+		{"keyless config change", http.MethodPut, "/api/config", "192.168.1.50:40000", "", http.StatusUnauthorized},
+		{"keyless from loopback", http.MethodPost, "/api/stream/stop", "127.0.0.1:40000", "", http.StatusOK},
+		{"keyless preview", http.MethodGet, "/preview/playlist.m3u8", "192.168.1.50:40000", "", http.StatusOK},
+		{"guest in its scopes", http.MethodPost, "/api/stream/stop", "192.168.1.50:40000", guestKey, http.StatusOK},
+		{"guest outside its scopes", http.MethodPut, "/api/config", "192.168.1.50:40000", guestKey, http.StatusForbidden},
+		{"unknown key", http.MethodGet, "/api/status", "192.168.1.50:40000", "nope", http.StatusUnauthorized},
+	} {
+		if got := accessStatus(h, tc.method, tc.path, tc.remoteAddr, tc.key); got != tc.want {
+			t.Errorf("%s: got %d, want %d", tc.name, got, tc.want)
+		}
+	}
+}
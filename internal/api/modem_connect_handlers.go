@@ -0,0 +1,178 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"srtla-manager/internal/config"
+	"srtla-manager/internal/events"
+	"srtla-manager/internal/modem"
+	"srtla-manager/internal/validate"
+)
+
+// ModemConnectEvent reports a modem's data connection brought up or down
+type ModemConnectEvent struct {
+	ModemID string `json:"modem_id"`
+	IMEI    string `json:"imei,omitempty"`
+	APN     string `json:"apn,omitempty"`
+	Event   string `json:"event"` // connected, disconnected or failed
+	Auto    bool   `json:"auto"`  // by auto_connect rather than through the API
+	Error   string `json:"error,omitempty"`
+}
+
+// ModemConnectRequest overrides the APN picked by the SIM's IMSI
+type ModemConnectRequest struct {
+	APN      string `json:"apn"`
+	User     string `json:"user"`
+	Password string `json:"password"`
+	IPType   string `json:"ip_type"`
+}
+
+var errModemConnecting = errors.New("the modem is already connecting")
+
+// AutoConnectModems connects modems that are registered but not connected,
+// with the APN for their SIM. A modem is tried again after retry_seconds,
+// and one disconnected through the API is left alone until it is connected
+// again or replugged. It is called each time the modems are polled.
+func (h *Handler) AutoConnectModems(modems []modem.ModemInfo) {
+	cfg := h.config.Get().ModemConnect
+	if !cfg.AutoConnect {
+		return
+	}
+	retry := 30 * time.Second
+	if cfg.RetrySeconds > 0 {
+		retry = time.Duration(cfg.RetrySeconds) * time.Second
+	}
+	now := time.Now()
+
+	for _, m := range modems {
+		if !strings.HasPrefix(m.ID, "mmcli:") || !modem.CanConnect(m.State) {
+			continue
+		}
+		rule, ok := cfg.APNFor(m.IMSI)
+		if !ok {
+			continue
+		}
+
+		h.connectMu.Lock()
+		wait := h.connectHeld[m.ID] || h.connecting[m.ID] || now.Sub(h.connectTried[m.ID]) < retry
+		if !wait {
+			h.connectTried[m.ID] = now
+		}
+		h.connectMu.Unlock()
+		if wait {
+			continue
+		}
+
+		go h.connectModem(m, rule, true)
+	}
+}
+
+// connectModem brings up a modem's data connection and publishes the result
+func (h *Handler) connectModem(m modem.ModemInfo, rule config.APNRule, auto bool) error {
+	h.connectMu.Lock()
+	if h.connecting[m.ID] {
+		h.connectMu.Unlock()
+		return errModemConnecting
+	}
+	h.connecting[m.ID] = true
+	delete(h.connectHeld, m.ID)
+	h.connectMu.Unlock()
+
+	defer func() {
+		h.connectMu.Lock()
+		delete(h.connecting, m.ID)
+		h.connectMu.Unlock()
+	}()
+
+	h.logOutput("manager", fmt.Sprintf("[MODEM] Connecting %s with APN %s", m.ID, rule.APN))
+	err := h.modem.Connect(m.ID, modem.Settings{APN: rule.APN, User: rule.User, Password: rule.Password, IPType: rule.IPType})
+
+	ev := ModemConnectEvent{ModemID: m.ID, IMEI: m.IMEI, APN: rule.APN, Event: "connected", Auto: auto}
+	if err != nil {
+		ev.Event, ev.Error = "failed", err.Error()
+		h.logOutput("manager", fmt.Sprintf("[MODEM] Failed to connect %s: %v", m.ID, err))
+	} else {
+		h.logOutput("manager", fmt.Sprintf("[MODEM] Connected %s", m.ID))
+	}
+	events.Publish(h.bus, TopicModemConnect, ev)
+	return err
+}
+
+// handleModemConnect handles POST /api/modems/{id}/connect. Without an APN
+// in the body, the one for the modem's SIM is used.
+func (h *Handler) handleModemConnect(w http.ResponseWriter, r *http.Request, id string) {
+	var req ModemConnectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		jsonError(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	info, err := h.modem.GetModem(id)
+	if err != nil || info == nil {
+		jsonError(w, "Modem not found", http.StatusNotFound)
+		return
+	}
+	info.ID = id
+
+	rule := config.APNRule{APN: req.APN, User: req.User, Password: req.Password, IPType: req.IPType}
+	if req.APN == "" {
+		var ok bool
+		if rule, ok = h.config.Get().ModemConnect.APNFor(info.IMSI); !ok {
+			jsonError(w, fmt.Sprintf("No APN configured for IMSI %q", info.IMSI), http.StatusBadRequest)
+			return
+		}
+	}
+	v := validate.New()
+	for field, value := range map[string]string{"apn": rule.APN, "user": rule.User, "password": rule.Password} {
+		if strings.ContainsAny(value, ",='\"") {
+			v.Addf(field, "must not contain commas, '=' or quotes")
+		}
+	}
+	if rule.IPType != "" {
+		v.OneOf("ip_type", rule.IPType, "ipv4", "ipv6", "ipv4v6")
+	}
+	if err := v.Err(); err != nil {
+		validationError(w, err)
+		return
+	}
+
+	if err := h.connectModem(*info, rule, false); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, errModemConnecting) {
+			status = http.StatusConflict
+		}
+		jsonError(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "apn": rule.APN})
+}
+
+// handleModemDisconnect handles POST /api/modems/{id}/disconnect. The
+// modem is left out of auto_connect until it is connected again.
+func (h *Handler) handleModemDisconnect(w http.ResponseWriter, r *http.Request, id string) {
+	h.connectMu.Lock()
+	h.connectHeld[id] = true
+	h.connectMu.Unlock()
+
+	ev := ModemConnectEvent{ModemID: id, Event: "disconnected"}
+	if info, err := h.modem.GetModem(id); err == nil && info != nil {
+		ev.IMEI = info.IMEI
+	}
+	if err := h.modem.Disconnect(id); err != nil {
+		jsonError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.logOutput("manager", fmt.Sprintf("[MODEM] Disconnected %s", id))
+	events.Publish(h.bus, TopicModemConnect, ev)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{"success": true})
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"srtla-manager/internal/stats"
+)
+
+// sinkModemHistory is the in-memory modem signal history
+const sinkModemHistory = "modem_history"
+
+// ModemHistoryResponse is the response for GET /api/modems/{id}/history
+type ModemHistoryResponse struct {
+	ID     string             `json:"id"`
+	Points []stats.ModemPoint `json:"points"`
+	Stored bool               `json:"stored"` // points older than the in-memory history came from the stats store
+}
+
+// registerModemMetrics describes the modem metrics, adds the last polled
+// modems as their source and keeps their history in memory
+func (h *Handler) registerModemMetrics() {
+	h.stats.Register(
+		stats.Metric{Name: stats.MetricModemSignalPercent, Help: "Signal quality of each modem in percent", Kind: stats.Gauge},
+		stats.Metric{Name: stats.MetricModemSignalDBm, Help: "Signal strength of each modem in dBm", Kind: stats.Gauge},
+		stats.Metric{Name: stats.MetricModemNetworkType, Help: "1 labelled with the network type each modem is on", Kind: stats.Gauge},
+		stats.Metric{Name: stats.MetricModemTxBytes, Help: "Bytes each modem has sent", Kind: stats.Counter},
+		stats.Metric{Name: stats.MetricModemRxBytes, Help: "Bytes each modem has received", Kind: stats.Counter},
+	)
+	h.stats.AddSource(h.modemMetrics)
+
+	h.modemHistory = stats.NewModemHistory(stats.ModemHistorySize, stats.ModemHistoryInterval)
+	h.stats.SetSink(sinkModemHistory, h.modemHistory)
+}
+
+// modemMetrics adds the modems as last polled; polling them takes too long
+// to do on every snapshot
+func (h *Handler) modemMetrics(add stats.AddFunc) {
+	h.modemsMu.Lock()
+	modems := h.modems
+	h.modemsMu.Unlock()
+
+	for _, m := range modems {
+		add(stats.MetricModemSignalPercent, float64(m.SignalPercent), "modem", m.ID)
+		add(stats.MetricModemSignalDBm, float64(m.SignalDBm), "modem", m.ID)
+		add(stats.MetricModemNetworkType, 1, "modem", m.ID, "type", m.NetworkType)
+		add(stats.MetricModemTxBytes, float64(m.DataTx), "modem", m.ID)
+		add(stats.MetricModemRxBytes, float64(m.DataRx), "modem", m.ID)
+		if m.TemperatureC != nil {
+			add(stats.MetricModemTemperature, *m.TemperatureC, "modem", m.ID)
+		}
+	}
+}
+
+// handleModemHistory handles GET /api/modems/{id}/history?since=. since is
+// an RFC 3339 time or a duration back from now and defaults to the last
+// hour. The last hour is kept in memory; older points come from the stats
+// store when it is enabled.
+func (h *Handler) handleModemHistory(w http.ResponseWriter, r *http.Request, id string) {
+	since := time.Now().Add(-time.Hour)
+	if s := r.URL.Query().Get("since"); s != "" {
+		if t, err := time.Parse(time.RFC3339, s); err == nil {
+			since = t
+		} else if d, err := time.ParseDuration(s); err == nil {
+			since = time.Now().Add(-d)
+		} else {
+			jsonError(w, fmt.Sprintf("Invalid since %q: use an RFC 3339 time or a duration such as 30m", s), http.StatusBadRequest)
+			return
+		}
+	}
+
+	points, known := h.modemHistory.History(id, since)
+	resp := ModemHistoryResponse{ID: id, Points: points}
+
+	store := h.config.Get().Metrics.Store
+	oldest, ok := h.modemHistory.Oldest(id)
+	if store.Enabled && (!ok || since.Before(oldest)) {
+		snaps, err := stats.ReadStore(store.Path, since)
+		if err != nil {
+			jsonError(w, fmt.Sprintf("Failed to read stats: %v", err), http.StatusInternalServerError)
+			return
+		}
+		var stored []stats.ModemPoint
+		for _, snap := range snaps {
+			if p, found := stats.ModemPoints(snap)[id]; found && (!ok || p.Timestamp.Before(oldest)) {
+				stored = append(stored, p)
+			}
+		}
+		if len(stored) > 0 {
+			known = true
+			resp.Stored = true
+			resp.Points = append(stored, resp.Points...)
+		}
+	}
+
+	if !known {
+		jsonError(w, "No history for this modem", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
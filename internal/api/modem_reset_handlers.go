@@ -0,0 +1,186 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"srtla-manager/internal/jobs"
+	"srtla-manager/internal/modem"
+	"srtla-manager/internal/system"
+	"srtla-manager/internal/validate"
+)
+
+// modemResetTimeout is how long a reset modem has to bring its link back
+const modemResetTimeout = 3 * time.Minute
+
+// modemResetDownTimeout is how long a reset modem's link is watched for
+// going down before it is taken to have come back already
+const modemResetDownTimeout = 30 * time.Second
+
+// ModemResetRequest picks how a modem is reset; modem when empty
+type ModemResetRequest struct {
+	Method string `json:"method"` // modem or usb
+}
+
+// ModemResetResult is a modem reset that brought its link back
+type ModemResetResult struct {
+	ModemID    string   `json:"modem_id"`
+	Method     string   `json:"method"`
+	Interface  string   `json:"interface,omitempty"`
+	WaitingFor []string `json:"waiting_for,omitempty"` // bind IPs expected back
+	IPs        []string `json:"ips"`                   // the modem's addresses once back
+	Seconds    float64  `json:"seconds"`
+}
+
+// handleModemReset handles POST /api/modems/{id}/reset. It resets the
+// modem in a job, which succeeds once the modem's link is back.
+func (h *Handler) handleModemReset(w http.ResponseWriter, r *http.Request, id string) {
+	var req ModemResetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		jsonError(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+	if req.Method == "" {
+		req.Method = modem.ResetModem
+	}
+	v := validate.New()
+	v.OneOf("method", req.Method, modem.ResetMethods...)
+	if err := v.Err(); err != nil {
+		validationError(w, err)
+		return
+	}
+
+	info, err := h.modem.GetModem(id)
+	if err != nil || info == nil {
+		jsonError(w, "Modem not found", http.StatusNotFound)
+		return
+	}
+	info.ID = id
+	if req.Method == modem.ResetUSB && info.Interface == "" {
+		jsonError(w, "The modem has no network interface to find its USB port by", http.StatusBadRequest)
+		return
+	}
+
+	cfg := h.config.Get()
+	job := h.jobs.Start("modem_reset", func(ctx context.Context, report *jobs.Reporter) (interface{}, error) {
+		return h.resetModem(ctx, report, *info, req.Method, h.bindIPs(&cfg))
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"status":   "started",
+		"modem_id": id,
+		"job_id":   job.ID,
+	})
+}
+
+// resetModem resets a modem and waits for its link to go down and come
+// back. The link is back once one of its bind IPs is up again, or its
+// interface has an address.
+func (h *Handler) resetModem(ctx context.Context, report *jobs.Reporter, m modem.ModemInfo, method string, bindIPs []string) (*ModemResetResult, error) {
+	start := time.Now()
+	result := &ModemResetResult{ModemID: m.ID, Method: method, Interface: m.Interface}
+
+	// a stuck modem may have lost its address already; expect back the
+	// bind IPs that are down then
+	up := systemIPv4s()
+	for _, ip := range modemBindIPs(m) {
+		if up[ip] {
+			result.WaitingFor = append(result.WaitingFor, ip)
+		}
+	}
+	if len(result.WaitingFor) == 0 {
+		for _, ip := range bindIPs {
+			if ip = strings.TrimSpace(ip); ip != "" && !up[ip] {
+				result.WaitingFor = append(result.WaitingFor, ip)
+			}
+		}
+	}
+
+	report.Progress(5, "Resetting %s (%s)", m.ID, method)
+	h.logOutput("manager", fmt.Sprintf("[MODEM] Resetting %s by %s", m.ID, method))
+	var err error
+	if method == modem.ResetUSB {
+		err = modem.PowerCycle(m.Interface)
+	} else {
+		err = h.modem.Reset(m.ID)
+	}
+	if err != nil {
+		h.logOutput("manager", fmt.Sprintf("[MODEM] Failed to reset %s: %v", m.ID, err))
+		return nil, err
+	}
+
+	ticker := time.NewTicker(swapPollInterval)
+	defer ticker.Stop()
+	deadline := time.After(modemResetTimeout)
+	down := false
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-deadline:
+			err := fmt.Errorf("%s did not come back within %s", m.ID, modemResetTimeout)
+			h.logOutput("manager", fmt.Sprintf("[MODEM] %v", err))
+			return nil, err
+		case <-ticker.C:
+		}
+
+		ips := modemLinkIPs(m.Interface, result.WaitingFor)
+		elapsed := time.Since(start)
+		if !down && len(ips) > 0 && elapsed < modemResetDownTimeout {
+			report.Progress(20, "Waiting for %s to go down", m.ID)
+			continue
+		}
+		if len(ips) == 0 {
+			if !down {
+				down = true
+				report.Progress(40, "%s is down, waiting for it to come back", m.ID)
+			}
+			continue
+		}
+
+		result.IPs = ips
+		result.Seconds = elapsed.Seconds()
+		report.Progress(100, "%s is back on %s", m.ID, strings.Join(ips, ", "))
+		h.logOutput("manager", fmt.Sprintf("[MODEM] %s is back on %s after %.0fs", m.ID, strings.Join(ips, ", "), result.Seconds))
+		if err := h.reloadSwapLinks(); err != nil {
+			h.logOutput("manager", fmt.Sprintf("[MODEM] Failed to reload links: %v", err))
+		}
+		return result, nil
+	}
+}
+
+// modemLinkIPs returns which of want are up, or else the IPv4 addresses of
+// iface
+func modemLinkIPs(iface string, want []string) []string {
+	up := systemIPv4s()
+	var ips []string
+	for _, ip := range want {
+		if up[ip] {
+			ips = append(ips, ip)
+		}
+	}
+	if len(ips) > 0 || iface == "" {
+		return ips
+	}
+	for _, ni := range system.ListNetworkInterfaces() {
+		if ni.Name != iface || !ni.IsUp {
+			continue
+		}
+		for _, ip := range ni.IPs {
+			if parsed := net.ParseIP(ip); parsed != nil && parsed.To4() != nil {
+				ips = append(ips, ip)
+			}
+		}
+	}
+	slices.Sort(ips)
+	return ips
+}
@@ -0,0 +1,218 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"time"
+
+	"srtla-manager/internal/alerts"
+	"srtla-manager/internal/config"
+	"srtla-manager/internal/events"
+	"srtla-manager/internal/logger"
+	"srtla-manager/internal/notify"
+)
+
+// NotifyStatus describes the remote notifications and their queue
+type NotifyStatus struct {
+	config.NotifyConfig
+	Queue *notify.Status `json:"queue,omitempty"` // while enabled
+}
+
+// ApplyNotifyConfig opens the notification queue and starts delivering it
+// to match the configuration. Notifications still queued for a sender
+// that was removed are dropped.
+func (h *Handler) ApplyNotifyConfig() {
+	cfg := h.config.Get().Notify
+
+	h.StopNotify()
+	if !cfg.Enabled {
+		return
+	}
+
+	var senders []notify.Sender
+	if cfg.WebhookURL != "" {
+		senders = append(senders, notify.Webhook{URL: cfg.WebhookURL})
+	}
+	if cfg.Telegram.BotToken != "" {
+		senders = append(senders, notify.Telegram{Token: cfg.Telegram.BotToken, ChatID: cfg.Telegram.ChatID})
+	}
+	if cfg.Email.Host != "" {
+		e := cfg.Email
+		senders = append(senders, notify.Email{Host: e.Host, Port: e.Port, Username: e.Username, Password: e.Password, From: e.From, To: e.To})
+	}
+	queue, err := notify.Open(cfg.QueuePath, cfg.MaxQueued, senders...)
+	if err != nil {
+		logger.Error("Notifications: %v", err)
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	h.notifyMu.Lock()
+	h.notifyQueue = queue
+	h.notifyCancel = cancel
+	h.notifyMu.Unlock()
+
+	go queue.Run(ctx)
+	go events.Listen(ctx, h.bus, TopicPipelineMode, 4, h.notifyPipelineMode)
+
+	if pending := queue.Status().Pending; pending > 0 {
+		h.logOutput("manager", fmt.Sprintf("[NOTIFY] %d notifications waiting from before", pending))
+	}
+	h.logOutput("manager", fmt.Sprintf("[NOTIFY] Sending notifications to %d services", len(senders)))
+}
+
+// StopNotify stops delivering notifications. Those still queued stay on
+// disk for the next start.
+func (h *Handler) StopNotify() {
+	h.notifyMu.Lock()
+	defer h.notifyMu.Unlock()
+	if h.notifyCancel != nil {
+		h.notifyCancel()
+		h.notifyCancel = nil
+	}
+	h.notifyQueue = nil
+}
+
+// notifying reports whether notifications are sent
+func (h *Handler) notifying() bool {
+	h.notifyMu.Lock()
+	defer h.notifyMu.Unlock()
+	return h.notifyQueue != nil
+}
+
+// notifyPipelineMode sends the stream starting and stopping. Without
+// audible alerts it also starts the link and input checks afresh, as
+// alertPipelineMode does otherwise.
+func (h *Handler) notifyPipelineMode(change PipelineModeChange) {
+	a, ok := streamAlert(change)
+	if !ok {
+		return
+	}
+
+	h.alertsMu.Lock()
+	if h.alertPlayer == nil {
+		h.resetAlertStateLocked()
+	}
+	h.alertsMu.Unlock()
+
+	h.notifyAlerts([]alerts.Alert{a})
+}
+
+// notifyAlerts queues a notification for each alert of a configured event
+func (h *Handler) notifyAlerts(fired []alerts.Alert) {
+	h.notifyMu.Lock()
+	queue := h.notifyQueue
+	h.notifyMu.Unlock()
+	if queue == nil || len(fired) == 0 {
+		return
+	}
+
+	enabled := h.config.Get().Notify.Events
+	hostname, _ := os.Hostname()
+	for _, a := range fired {
+		if !slices.Contains(enabled, a.Event) {
+			continue
+		}
+		n := notify.Notification{ID: newRequestID(), Event: a.Event, Text: a.Text(), Host: hostname, Time: time.Now()}
+		if err := queue.Push(n); err != nil {
+			logger.Warn("Notifications: failed to queue %s: %v", a.Event, err)
+		}
+	}
+}
+
+// notifyText queues a notification that isn't one of the alert events, so
+// goes out whatever events are selected. It reports whether it was queued.
+func (h *Handler) notifyText(event, text string) bool {
+	h.notifyMu.Lock()
+	queue := h.notifyQueue
+	h.notifyMu.Unlock()
+	if queue == nil {
+		return false
+	}
+
+	hostname, _ := os.Hostname()
+	n := notify.Notification{ID: newRequestID(), Event: event, Text: text, Host: hostname, Time: time.Now()}
+	if err := queue.Push(n); err != nil {
+		logger.Warn("Notifications: failed to queue %s: %v", event, err)
+		return false
+	}
+	return true
+}
+
+func (h *Handler) notifyStatus() NotifyStatus {
+	status := NotifyStatus{NotifyConfig: h.config.Get().Notify}
+	if status.Events == nil {
+		status.Events = []string{}
+	}
+
+	h.notifyMu.Lock()
+	queue := h.notifyQueue
+	h.notifyMu.Unlock()
+	if queue != nil {
+		s := queue.Status()
+		status.Queue = &s
+	}
+	return status
+}
+
+// HandleNotifyStatus handles GET /api/notifications
+func (h *Handler) HandleNotifyStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.notifyStatus())
+}
+
+// HandleNotifyUpdate handles PUT /api/notifications. Fields missing from
+// the body keep their current values; an events list or a list of mail
+// recipients replaces the current one.
+func (h *Handler) HandleNotifyUpdate(w http.ResponseWriter, r *http.Request) {
+	cfg := h.config.Get()
+	// decode the list into a fresh slice rather than over the live config
+	current, currentTo := cfg.Notify.Events, cfg.Notify.Email.To
+	cfg.Notify.Events, cfg.Notify.Email.To = nil, nil
+	if err := json.NewDecoder(r.Body).Decode(&cfg.Notify); err != nil {
+		jsonError(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+	if cfg.Notify.Events == nil {
+		cfg.Notify.Events = current
+	}
+	if cfg.Notify.Email.To == nil {
+		cfg.Notify.Email.To = currentTo
+	}
+
+	if err := h.config.Update(cfg); err != nil {
+		validationError(w, err)
+		return
+	}
+
+	h.ApplyNotifyConfig()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.notifyStatus())
+}
+
+// HandleNotifyTest handles POST /api/notifications/test, queueing a
+// notification to check the services are set up right
+func (h *Handler) HandleNotifyTest(w http.ResponseWriter, r *http.Request) {
+	h.notifyMu.Lock()
+	queue := h.notifyQueue
+	h.notifyMu.Unlock()
+	if queue == nil {
+		jsonError(w, "Notifications are disabled", http.StatusConflict)
+		return
+	}
+
+	hostname, _ := os.Hostname()
+	n := notify.Notification{ID: newRequestID(), Event: "test", Text: "test notification", Host: hostname, Time: time.Now()}
+	if err := queue.Push(n); err != nil {
+		jsonError(w, fmt.Sprintf("Failed to queue the notification: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "queued", "id": n.ID})
+}
@@ -0,0 +1,300 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"srtla-manager/internal/apikey"
+	"srtla-manager/internal/config"
+	"srtla-manager/internal/confighistory"
+	"srtla-manager/internal/discovery"
+	"srtla-manager/internal/events"
+	"srtla-manager/internal/jobs"
+	"srtla-manager/internal/maintenance"
+	"srtla-manager/internal/openapi"
+	"srtla-manager/internal/restream"
+	"srtla-manager/internal/stats"
+	"srtla-manager/internal/system"
+	"srtla-manager/internal/updates"
+	"srtla-manager/internal/upload"
+)
+
+// routeDoc is what the OpenAPI document says of a route beyond its path and
+// method: the types its body is decoded into and its response encoded from
+type routeDoc struct {
+	summary  string
+	query    []string
+	request  any
+	response any
+}
+
+// routeDocs documents the API routes by their unversioned method and path.
+// A route left out is still in the document, without body schemas.
+var routeDocs = map[string]routeDoc{
+	"GET /api/status":               {response: StatusResponse{}},
+	"POST /api/stream/start":        {request: StreamStartRequest{}, response: StreamStartResponse{}},
+	"POST /api/stream/stop":         {response: map[string]string{}},
+	"GET /api/stream/preconditions": {query: []string{"bind_set"}, response: StartPolicyResponse{}},
+	"POST /api/stream/test":         {request: TestPatternRequest{}},
+	"GET /api/publishers":           {summary: "Publishers connected to the ingest"},
+	"GET /api/ingest":               {response: IngestStatus{}},
+	"GET /api/abr":                  {response: ABRStatus{}},
+
+	"GET /api/config":         {summary: "Configuration", response: config.Config{}},
+	"PUT /api/config":         {summary: "Replace the configuration", request: config.Config{}, response: map[string]string{}},
+	"GET /api/metrics":        {response: MetricsStatus{}},
+	"PUT /api/metrics":        {request: config.MetricsConfig{}, response: MetricsStatus{}},
+	"GET /api/metrics/stored": {query: []string{"since"}, response: []stats.Snapshot{}},
+	"GET /api/events/stats":   {response: events.Stats{}},
+	"GET /api/audit":          {query: []string{"since"}, response: []events.AuditEntry{}},
+
+	"GET /api/config/export":                 {summary: "Download the configuration file"},
+	"POST /api/config/import":                {summary: "Import a configuration file", query: []string{"dry_run"}, response: ConfigImportResponse{}},
+	"GET /api/config/history":                {response: []confighistory.Entry{}},
+	"GET /api/config/history/{id}":           {summary: "Download a config kept in the history"},
+	"POST /api/config/history/{id}/rollback": {summary: "Roll back to a config kept in the history", response: ConfigImportResponse{}},
+
+	"GET /api/receiver":        {response: ReceiverStatus{}},
+	"PUT /api/receiver":        {request: config.ReceiverConfig{}, response: ReceiverStatus{}},
+	"POST /api/receiver/start": {response: ReceiverStatus{}},
+	"POST /api/receiver/stop":  {response: ReceiverStatus{}},
+
+	"GET /api/srtla/ips":              {summary: "Bonding IPs", response: SRTLAIPsResponse{}},
+	"PUT /api/srtla/ips":              {summary: "Set the bonding IPs"},
+	"GET /api/srtla/ips/file":         {summary: "Bonding IPs file", response: IPsFileResponse{}},
+	"PUT /api/srtla/ips/file":         {summary: "Write the bonding IPs file"},
+	"GET /api/srtla/bind-sets":        {response: BindSetsResponse{}},
+	"GET /api/srtla/transport":        {response: TransportResponse{}},
+	"GET /api/links/probe":            {response: LinkProbeResponse{}},
+	"GET /api/links/swap":             {response: map[string]*LinkSwap{}},
+	"GET /api/profiles":               {response: ProfilesResponse{}},
+	"GET /api/profiles/{name}":        {response: Profile{}},
+	"PUT /api/profiles/{name}":        {request: config.StreamProfile{}, response: Profile{}},
+	"GET /api/restream":               {response: []RestreamDestination{}},
+	"GET /api/restream/platforms":     {response: []restream.Platform{}},
+	"POST /api/restream/url":          {request: RestreamURLRequest{}, response: RestreamURLResponse{}},
+	"PUT /api/restream/{name}":        {request: RestreamSaveRequest{}, response: RestreamDestination{}},
+	"GET /api/outputs":                {response: OutputsResponse{}},
+	"PUT /api/outputs/{name}/enabled": {request: OutputEnabledRequest{}, response: OutputStatus{}},
+	"GET /api/vpn":                    {response: VPNResponse{}},
+	"GET /api/relay":                  {response: RelayStatus{}},
+	"POST /api/relay/provision":       {request: RelayProvisionRequest{}, response: map[string]string{}},
+
+	"GET /api/system/dependencies":             {response: DependenciesResponse{}},
+	"POST /api/system/install-deb":             {request: InstallDebRequest{}, response: InstallDebResponse{}},
+	"POST /api/system/uploads":                 {request: UploadCreateRequest{}, response: upload.Upload{}},
+	"GET /api/system/uploads/{id}":             {response: upload.Upload{}},
+	"PATCH /api/system/uploads/{id}":           {summary: "Upload a chunk", response: upload.Upload{}},
+	"POST /api/system/uploads/{id}/install":    {response: InstallDebResponse{}},
+	"GET /api/system/interfaces":               {response: []system.NetworkInterface{}},
+	"GET /api/system/interfaces/history":       {response: InterfaceHistoryResponse{}},
+	"GET /api/system/services":                 {response: []ServiceStatus{}},
+	"POST /api/system/services/{name}/restart": {request: ServiceRestartRequest{}, response: map[string]string{}},
+	"GET /api/system/tls":                      {response: TLSStatus{}},
+	"PUT /api/system/tls":                      {request: TLSUploadRequest{}, response: TLSStatus{}},
+	"POST /api/system/tls/self-signed":         {response: TLSStatus{}},
+	"GET /api/network/speedtest":               {response: SpeedTestResult{}},
+	"POST /api/network/speedtest":              {request: SpeedTestRequest{}, response: map[string]string{}},
+	"POST /api/network/stun":                   {request: STUNRequest{}, response: []STUNMapping{}},
+	"GET /api/routing":                         {response: RoutingResponse{}},
+	"GET /api/maintenance":                     {response: MaintenanceStatus{}},
+	"POST /api/maintenance/housekeeping":       {response: maintenance.Report{}},
+	"GET /api/quotas":                          {response: []QuotaStatus{}},
+	"POST /api/quotas/{imei}/reset":            {response: []QuotaStatus{}},
+	"GET /api/capabilities":                    {response: CapabilitiesResponse{}},
+
+	"GET /api/modems":                  {summary: "Modems", response: ModemsResponse{}},
+	"GET /api/modems/{id}":             {summary: "Modem"},
+	"GET /api/modems/{id}/history":     {summary: "Modem signal history", response: ModemHistoryResponse{}},
+	"POST /api/modems/{id}/ussd":       {summary: "Dial a USSD code"},
+	"POST /api/modems/{id}/connect":    {summary: "Connect a modem", request: ModemConnectRequest{}},
+	"POST /api/modems/{id}/disconnect": {summary: "Disconnect a modem"},
+	"POST /api/modems/{id}/reset":      {summary: "Reset a modem", request: ModemResetRequest{}},
+	"GET /api/usbnet":                  {summary: "USB network devices", response: USBNetResponse{}},
+	"PUT /api/usbnet/{id}":             {request: USBNetRenameRequest{}, response: USBNetResponse{}},
+	"DELETE /api/usbnet/{id}":          {response: USBNetResponse{}},
+	"GET /api/discovery":               {response: []discovery.Status{}},
+	"POST /api/discovery/{class}/scan": {response: map[string][]string{}},
+	"GET /api/wifi/networks":           {summary: "Wi-Fi networks in range", response: WiFiNetworksResponse{}},
+	"GET /api/wifi/status":             {summary: "Wi-Fi connection", response: WiFiStatusResponse{}},
+	"POST /api/wifi/connect":           {summary: "Connect to a Wi-Fi network", request: WiFiConnectRequest{}, response: WiFiActionResponse{}},
+	"POST /api/wifi/disconnect":        {summary: "Disconnect from Wi-Fi", response: WiFiActionResponse{}},
+	"POST /api/wifi/hotspot":           {summary: "Start the hotspot", request: WiFiHotspotRequest{}, response: WiFiActionResponse{}},
+	"POST /api/wifi/hotspot/stop":      {summary: "Stop the hotspot", response: WiFiActionResponse{}},
+	"GET /api/wifi/hotspot/channel":    {summary: "Hotspot channel"},
+	"POST /api/wifi/hotspot/channel":   {summary: "Set the hotspot channel"},
+	"GET /api/wifi/hotspot/clients":    {summary: "Hotspot clients"},
+	"POST /api/wifi/forget":            {summary: "Forget a Wi-Fi network", response: WiFiActionResponse{}},
+	"POST /api/wifi/ca-cert":           {summary: "Upload a CA certificate for enterprise Wi-Fi", response: WiFiCACertResponse{}},
+
+	"GET /api/logs":                   {response: []stats.LogEntry{}},
+	"GET /api/updates/check":          {response: UpdateStatusResponse{}},
+	"GET /api/updates/releases":       {response: []updates.Release{}},
+	"POST /api/updates/perform":       {request: UpdateRequest{}, response: UpdateProgressResponse{}},
+	"POST /api/updates/rollback":      {response: UpdateProgressResponse{}},
+	"GET /api/updates/srtla/check":    {response: UpdateStatusResponse{}},
+	"GET /api/updates/srtla/releases": {response: []updates.Release{}},
+	"POST /api/updates/srtla/install": {request: UpdateRequest{}, response: UpdateProgressResponse{}},
+
+	"GET /api/pairing":           {response: PairingStatusResponse{}},
+	"GET /api/markers":           {response: []Marker{}},
+	"POST /api/markers":          {summary: "Schedule an SCTE-35 cue, announced over the API and WebSocket but not injected into the stream", request: MarkerRequest{}, response: Marker{}},
+	"GET /api/power":             {response: PowerResponse{}},
+	"PUT /api/power/profile":     {request: PowerProfileRequest{}, response: PowerResponse{}},
+	"GET /api/capture":           {response: CaptureResponse{}},
+	"GET /api/talkback":          {response: TalkbackResponse{}},
+	"PUT /api/talkback":          {request: TalkbackRequest{}, response: TalkbackResponse{}},
+	"GET /api/talkback/devices":  {response: []system.AudioDevice{}},
+	"GET /api/rtsp":              {response: RTSPResponse{}},
+	"PUT /api/rtsp":              {request: RTSPRequest{}, response: RTSPResponse{}},
+	"POST /api/rtsp/start":       {response: RTSPResponse{}},
+	"POST /api/rtsp/stop":        {response: RTSPResponse{}},
+	"GET /api/return":            {response: ReturnFeedResponse{}},
+	"PUT /api/return":            {request: ReturnFeedRequest{}, response: ReturnFeedResponse{}},
+	"GET /api/ingests":           {response: FailoverResponse{}},
+	"GET /api/display":           {response: DisplayStatus{}},
+	"GET /api/alerts":            {response: AlertsStatus{}},
+	"GET /api/notifications":     {response: NotifyStatus{}},
+	"GET /api/buttons":           {response: ButtonsStatus{}},
+	"GET /api/timecode":          {response: TimecodeStatus{}},
+	"GET /api/switcher":          {response: SwitcherStatus{}},
+	"GET /api/tally":             {response: TallyResponse{}},
+	"PUT /api/tally/override":    {request: TallyOverrideRequest{}, response: TallyResponse{}},
+	"DELETE /api/tally/override": {response: TallyResponse{}},
+
+	"GET /api/access/keys":    {response: []config.APIKey{}},
+	"POST /api/access/keys":   {request: APIKeyCreateRequest{}, response: APIKeyCreated{}},
+	"GET /api/access/guests":  {response: []config.APIKey{}},
+	"POST /api/access/guests": {request: GuestCreateRequest{}, response: APIKeyCreated{}},
+	"GET /api/jobs":           {response: []jobs.Job{}},
+	"GET /api/jobs/{id}":      {response: jobs.Job{}},
+	"DELETE /api/jobs/{id}":   {response: jobs.Job{}},
+	"GET /api/share":          {response: []ShareLink{}},
+	"POST /api/share":         {request: CreateShareRequest{}, response: ShareLink{}},
+	"GET /api/ws/stats":       {response: HubStats{}},
+
+	"POST /api/preview/token":   {summary: "Sign a token to embed a preview with", request: PreviewTokenRequest{}, response: PreviewTokenResponse{}},
+	"DELETE /api/preview/token": {summary: "Revoke every preview token"},
+
+	"GET /api/cameras":                          {summary: "DJI cameras", response: CameraListResponse{}},
+	"POST /api/cameras/scan":                    {summary: "Scan for DJI cameras"},
+	"POST /api/cameras/scan/stop":               {summary: "Stop scanning for DJI cameras"},
+	"POST /api/cameras/{id}/connect":            {summary: "Connect to a DJI camera"},
+	"POST /api/cameras/{id}/disconnect":         {summary: "Disconnect a DJI camera"},
+	"POST /api/cameras/{id}/preview":            {summary: "Preview a DJI camera's stream", request: CameraConfigRequest{}},
+	"POST /api/cameras/{id}/configure":          {summary: "Configure a DJI camera and start it streaming", request: CameraConfigRequest{}},
+	"POST /api/cameras/{id}/stop":               {summary: "Stop a DJI camera streaming"},
+	"POST /api/cameras/{id}/forget":             {summary: "Forget a DJI camera"},
+	"DELETE /api/cameras/{id}/forget":           {summary: "Forget a DJI camera"},
+	"POST /api/cameras/{id}/refresh":            {summary: "Refresh a DJI camera's state"},
+	"PUT /api/cameras/{id}/reservation":         {summary: "Reserve a DJI camera", request: CameraReservationRequest{}, response: CameraReservationResponse{}},
+	"DELETE /api/cameras/{id}/reservation":      {summary: "Release a DJI camera", response: CameraReservationResponse{}},
+	"GET /api/camera-groups":                    {response: []CameraGroup{}},
+	"PUT /api/camera-groups/{id}":               {request: config.CameraGroupConfig{}, response: CameraGroup{}},
+	"POST /api/camera-groups/{id}/{action}":     {request: CameraGroupActionRequest{}, response: map[string]string{}},
+	"GET /api/v2/cameras":                       {response: []CameraStatus{}},
+	"GET /api/v2/cameras/{kind}/{id}":           {response: CameraStatus{}},
+	"POST /api/v2/cameras/{kind}/{id}/{action}": {request: CameraSettings{}, response: CameraStatus{}},
+	"GET /api/usbcams":                          {response: USBCameraListResponse{}},
+	"POST /api/usbcams/scan":                    {response: USBCameraListResponse{}},
+	"POST /api/usbcams/{id}/start":              {request: USBCameraStartRequest{}},
+}
+
+// SetRouter sets the router whose routes the OpenAPI document describes
+func (h *Handler) SetRouter(rt *Router) {
+	h.router = rt
+}
+
+// HandleOpenAPI handles GET /api/v1/openapi.json, describing every API
+// route, what it takes and what it returns
+func (h *Handler) HandleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	h.openapiOnce.Do(func() {
+		h.openapiJSON, h.openapiErr = json.Marshal(h.openAPIDocument())
+	})
+	if h.openapiErr != nil {
+		jsonError(w, h.openapiErr.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(h.openapiJSON)
+}
+
+func (h *Handler) openAPIDocument() *openapi.Document {
+	doc := openapi.New("SRTLA Manager", h.GetVersion())
+	doc.Components.SecuritySchemes = map[string]*openapi.SecurityScheme{
+		"bearer": {Type: "http", Scheme: "bearer"},
+		"apiKey": {Type: "apiKey", In: "header", Name: apikey.Header},
+	}
+	if h.router == nil {
+		return doc
+	}
+
+	for _, route := range h.router.Routes() {
+		unversioned := "/api/" + strings.TrimPrefix(route.Path, APIv1)
+		d := routeDocs[route.Method+" "+unversioned]
+		summary := d.summary
+		if summary == "" {
+			summary = handlerSummary(route.Handler)
+		}
+		doc.Add(route.Method, route.Path, openapi.Endpoint{
+			OperationID: operationID(route.Method, route.Path),
+			Summary:     summary,
+			Tag:         routeTag(route.Path),
+			Query:       d.query,
+			Request:     d.request,
+			Response:    d.response,
+		})
+	}
+	doc.SetErrorSchema(ErrorResponse{})
+	return doc
+}
+
+// handlerSummary makes a summary of a handler's name: HandleUSBCameraList
+// is "USB camera list"
+func handlerSummary(name string) string {
+	name = strings.TrimPrefix(name, "Handle")
+	runes := []rune(name)
+	var words []string
+	start := 0
+	for i := 1; i <= len(runes); i++ {
+		if i < len(runes) && !(unicode.IsUpper(runes[i]) && (unicode.IsLower(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+			continue
+		}
+		word := string(runes[start:i])
+		if len(words) > 0 && word != strings.ToUpper(word) {
+			word = strings.ToLower(word)
+		}
+		words = append(words, word)
+		start = i
+	}
+	return strings.Join(words, " ")
+}
+
+// operationID makes an operation's ID of its method and path:
+// PUT /api/v1/outputs/{name}/enabled is putOutputsByNameEnabled
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	path = strings.TrimPrefix(path, APIv1)
+	path = strings.TrimPrefix(path, "/api/")
+	for _, seg := range strings.Split(path, "/") {
+		if strings.HasPrefix(seg, "{") {
+			b.WriteString("By")
+			seg = strings.TrimSuffix(strings.Trim(seg, "{}"), "...")
+		}
+		for _, part := range strings.FieldsFunc(seg, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+			b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+		}
+	}
+	return b.String()
+}
+
+// routeTag groups a route by the first segment of its path
+func routeTag(path string) string {
+	path = strings.TrimPrefix(path, APIv1)
+	path = strings.TrimPrefix(path, "/api/")
+	tag, _, _ := strings.Cut(path, "/")
+	return tag
+}
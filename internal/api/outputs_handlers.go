@@ -0,0 +1,163 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+
+	"srtla-manager/internal/config"
+	"srtla-manager/internal/process"
+)
+
+// Health of an output
+const (
+	OutputHealthy  = "ok"
+	OutputStarting = "starting"
+	OutputFailing  = "failing"
+	OutputIdle     = "idle"     // not streaming
+	OutputPending  = "pending"  // enabled since the stream started; sent from the next one
+	OutputDisabled = "disabled" // not sent
+)
+
+// OutputStatus is the state of one place the stream is sent to
+type OutputStatus struct {
+	Name     string                `json:"name"`
+	URL      string                `json:"url"`
+	Platform string                `json:"platform,omitempty"`
+	Enabled  bool                  `json:"enabled"`
+	Health   string                `json:"health"`
+	Error    string                `json:"error,omitempty"`
+	Output   *RestreamOutputStatus `json:"output,omitempty"` // restream destinations while streaming
+}
+
+// OutputsResponse is the response of GET /api/outputs: the SRT leg, then
+// every destination the stream is restreamed to, by name
+type OutputsResponse struct {
+	SRT      OutputStatus   `json:"srt"`
+	Restream []OutputStatus `json:"restream"`
+}
+
+// OutputEnabledRequest is the body of PUT /api/outputs/{name}/enabled
+type OutputEnabledRequest struct {
+	Enabled *bool `json:"enabled"`
+}
+
+// srtOutputStatus returns the state of the SRT leg, through srtla_send when
+// it is enabled and straight from ffmpeg otherwise
+func (h *Handler) srtOutputStatus(srtla config.SRTLAConfig) OutputStatus {
+	status := OutputStatus{Name: "srt", Enabled: true, Health: OutputIdle}
+	if srtla.Enabled {
+		status.URL = "srtla://" + net.JoinHostPort(srtla.RemoteHost, strconv.Itoa(srtla.RemotePort))
+	}
+	if h.GetPipelineMode() != PipelineModeStreaming {
+		return status
+	}
+
+	state, stale := h.ffmpeg.ProcessState(), h.ffmpeg.IsStale(FFmpegStaleThreshold)
+	if srtla.Enabled {
+		state, stale = h.srtla.ProcessState(), h.srtla.IsStale(SRTLAStaleThreshold)
+	}
+	switch {
+	case state == process.StateStarting:
+		status.Health = OutputStarting
+	case state != process.StateRunning:
+		status.Health, status.Error = OutputFailing, string(state)
+	case stale:
+		status.Health, status.Error = OutputFailing, "no statistics reported"
+	default:
+		status.Health = OutputHealthy
+	}
+	return status
+}
+
+// restreamOutputHealth returns the state of a restream destination
+func (h *Handler) restreamOutputHealth(name string, d config.RestreamConfig) OutputStatus {
+	status := OutputStatus{Name: name, URL: d.URL, Platform: d.Platform, Enabled: d.Enabled, Health: OutputIdle}
+	if !d.Enabled {
+		status.Health = OutputDisabled
+		return status
+	}
+	if h.GetPipelineMode() != PipelineModeStreaming || h.ffmpeg.ProcessState() != process.StateRunning {
+		return status
+	}
+
+	status.Output = h.restreamOutputStatus(name)
+	switch {
+	case status.Output != nil && status.Output.State == process.StateRunning:
+		status.Health = OutputHealthy
+	case status.Output != nil:
+		status.Health, status.Error = OutputFailing, status.Output.Error
+	case slices.ContainsFunc(h.ffmpeg.RestreamTargets(), func(t process.RestreamTarget) bool { return t.Name == name }):
+		// the monitor hasn't picked it up yet
+		status.Health = OutputStarting
+	default:
+		status.Health = OutputPending
+	}
+	return status
+}
+
+// HandleOutputs handles GET /api/outputs, returning every output with its
+// health
+func (h *Handler) HandleOutputs(w http.ResponseWriter, r *http.Request) {
+	cfg := h.config.Get()
+	resp := OutputsResponse{SRT: h.srtOutputStatus(cfg.SRTLA), Restream: make([]OutputStatus, 0, len(cfg.Restream))}
+	for name, d := range cfg.Restream {
+		resp.Restream = append(resp.Restream, h.restreamOutputHealth(name, d))
+	}
+	sort.Slice(resp.Restream, func(i, j int) bool { return resp.Restream[i].Name < resp.Restream[j].Name })
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// HandleOutputEnabled handles PUT /api/outputs/{name}/enabled, turning a
+// restream destination on or off. Turning one off while streaming stops it
+// at once; one turned on is sent again at once if the stream started with
+// it, and from the next stream otherwise.
+func (h *Handler) HandleOutputEnabled(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	var req OutputEnabledRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+	if req.Enabled == nil {
+		jsonError(w, "enabled is required", http.StatusBadRequest)
+		return
+	}
+
+	cfg := h.config.Get()
+	d, ok := cfg.Restream[name]
+	if !ok {
+		jsonError(w, "Output not found", http.StatusNotFound)
+		return
+	}
+	destinations := make(map[string]config.RestreamConfig, len(cfg.Restream))
+	for n, other := range cfg.Restream {
+		destinations[n] = other
+	}
+	d.Enabled = *req.Enabled
+	destinations[name] = d
+	cfg.Restream = destinations
+
+	if err := h.config.Update(cfg); err != nil {
+		validationError(w, err)
+		return
+	}
+	h.ApplyRestreamConfig()
+	h.checkRestreamOutputs()
+
+	state := "disabled"
+	if d.Enabled {
+		state = "enabled"
+	}
+	h.logOutput("manager", fmt.Sprintf("[RESTREAM] %s %s", name, state))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.restreamOutputHealth(name, d))
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"os"
+	"time"
+
+	"srtla-manager/internal/bondhealth"
+	"srtla-manager/pkg/web"
+)
+
+// OverlayStatus is what the studio overlay shows of the unit
+type OverlayStatus struct {
+	Label          string            `json:"label"`
+	PipelineMode   PipelineMode      `json:"pipeline_mode"`
+	Streaming      bool              `json:"streaming"`
+	BitrateKbps    float64           `json:"bitrate_kbps"`    // from the encoder
+	ThroughputKbps float64           `json:"throughput_kbps"` // over the links, 0 when unknown
+	Links          int               `json:"links"`
+	LinksUp        int               `json:"links_up"`
+	Health         *bondhealth.Score `json:"health,omitempty"` // unless SRTLA is off
+	Time           time.Time         `json:"time"`
+}
+
+// overlayAllowed checks that the overlay is enabled and the request carries
+// its token, answering the request when not
+func (h *Handler) overlayAllowed(w http.ResponseWriter, r *http.Request) bool {
+	cfg := h.config.Get().Overlay
+	if !cfg.Enabled {
+		http.NotFound(w, r)
+		return false
+	}
+	if cfg.Token != "" && subtle.ConstantTimeCompare([]byte(r.URL.Query().Get("token")), []byte(cfg.Token)) != 1 {
+		jsonError(w, "Invalid overlay token", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+// HandleOverlayPage handles GET /overlay/, the page to add as an OBS
+// browser source. It polls status.json with the same query.
+func (h *Handler) HandleOverlayPage(w http.ResponseWriter, r *http.Request) {
+	if !h.overlayAllowed(w, r) {
+		return
+	}
+	page, err := fs.ReadFile(web.FS, "assets/overlay.html")
+	if err != nil {
+		jsonError(w, "Overlay page missing: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(page)
+}
+
+// HandleOverlayStatus handles GET /overlay/status.json. Other origins may
+// read it, for graphics systems that draw their own overlay.
+func (h *Handler) HandleOverlayStatus(w http.ResponseWriter, r *http.Request) {
+	if !h.overlayAllowed(w, r) {
+		return
+	}
+	cfg := h.config.Get()
+
+	status := OverlayStatus{
+		Label:        cfg.Overlay.Label,
+		PipelineMode: h.GetPipelineMode(),
+		Streaming:    h.IsStreaming(),
+		Time:         time.Now(),
+	}
+	if status.Label == "" {
+		status.Label, _ = os.Hostname()
+	}
+	if status.Streaming {
+		sample := h.bitrateSample()
+		status.BitrateKbps = sample.InputKbps
+		status.ThroughputKbps = sample.ThroughputKbps
+	}
+	if cfg.SRTLA.Enabled {
+		// the stats broadcast updates the score every second
+		if health := h.linkHealth.Last(); health != nil {
+			status.Health = health
+			status.Links, status.LinksUp = health.Links, health.LinksUp
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	json.NewEncoder(w).Encode(status)
+}
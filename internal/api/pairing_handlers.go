@@ -0,0 +1,130 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"srtla-manager/internal/events"
+	"srtla-manager/internal/logger"
+	"srtla-manager/internal/pairing"
+)
+
+// PairingStatusResponse is the response for GET /api/pairing
+type PairingStatusResponse struct {
+	Enabled  bool            `json:"enabled"`
+	Role     string          `json:"role"`
+	TookOver *time.Time      `json:"took_over,omitempty"`
+	Peer     *pairing.Status `json:"peer,omitempty"`
+}
+
+// StartPairing begins watching the active unit when configured as the passive
+// side of a pair. The active side only answers state requests.
+func (h *Handler) StartPairing(ctx context.Context) {
+	cfg := h.config.Get().Pairing
+	if !cfg.Enabled || cfg.Role != pairing.RolePassive {
+		return
+	}
+
+	monitor := pairing.NewMonitor(cfg.PeerURL, cfg.Token, time.Duration(cfg.FailoverTimeout)*time.Second)
+	monitor.OnState(h.syncFromPeer)
+	monitor.OnLost(h.takeOverFromPeer)
+
+	h.pairingMu.Lock()
+	h.pairingMonitor = monitor
+	h.pairingMu.Unlock()
+
+	logger.Info("Pairing: passive unit watching %s", cfg.PeerURL)
+	go monitor.Run(ctx)
+}
+
+// syncFromPeer mirrors the active unit's shared configuration
+func (h *Handler) syncFromPeer(state pairing.PeerState) {
+	cfg := h.config.Get()
+	if !cfg.Pairing.SyncConfig || state.Role != pairing.RoleActive {
+		return
+	}
+	if !state.Config.Apply(&cfg) {
+		return
+	}
+	if err := h.config.Update(cfg); err != nil {
+		logger.Warn("Pairing: failed to apply config from peer: %v", err)
+		return
+	}
+	logger.Info("Pairing: synchronized config from active unit")
+}
+
+// takeOverFromPeer starts publishing when the active unit disappears while it
+// was streaming. The receiver treats this as a new SRTLA sender; the failover
+// timeout should exceed the receiver's idle timeout so the stale publisher is
+// gone before the new one connects. There is no automatic hand-back.
+func (h *Handler) takeOverFromPeer(last pairing.PeerState) {
+	logger.Warn("Pairing: active unit stopped responding (last mode: %s)", last.PipelineMode)
+
+	if last.PipelineMode != string(PipelineModeStreaming) || h.GetPipelineMode() == PipelineModeStreaming {
+		return
+	}
+
+	// the start policy is for operators; a backup taking over goes out on
+	// whatever links it has
+	if _, err := h.startStreaming(context.Background(), StreamStartRequest{Force: true}); err != nil {
+		logger.Error("Pairing: takeover failed: %v", err)
+		events.Publish(h.bus, TopicPairing, PairingEvent{Event: "takeover_failed", Error: err.Error()})
+		return
+	}
+
+	now := time.Now()
+	h.pairingMu.Lock()
+	h.tookOver = &now
+	h.pairingMu.Unlock()
+
+	logger.Info("Pairing: took over publishing from active unit")
+	events.Publish(h.bus, TopicPairing, PairingEvent{Event: "takeover"})
+}
+
+// HandlePairingStatus handles GET /api/pairing
+func (h *Handler) HandlePairingStatus(w http.ResponseWriter, r *http.Request) {
+	cfg := h.config.Get().Pairing
+
+	h.pairingMu.Lock()
+	resp := PairingStatusResponse{
+		Enabled:  cfg.Enabled,
+		Role:     cfg.Role,
+		TookOver: h.tookOver,
+	}
+	monitor := h.pairingMonitor
+	h.pairingMu.Unlock()
+
+	if monitor != nil {
+		status := monitor.Status()
+		resp.Peer = &status
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// HandlePairingState handles GET /api/pairing/state, polled by the peer unit
+func (h *Handler) HandlePairingState(w http.ResponseWriter, r *http.Request) {
+	cfg := h.config.Get()
+	if !cfg.Pairing.Enabled {
+		jsonError(w, "Pairing is not enabled", http.StatusNotFound)
+		return
+	}
+
+	token := r.Header.Get(pairing.TokenHeader)
+	if subtle.ConstantTimeCompare([]byte(token), []byte(cfg.Pairing.Token)) != 1 {
+		jsonError(w, "Invalid pairing token", http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pairing.PeerState{
+		Role:         cfg.Pairing.Role,
+		PipelineMode: string(h.GetPipelineMode()),
+		Version:      h.appVersion,
+		Config:       pairing.NewSharedConfig(cfg),
+	})
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// PipelineOperation describes the operation currently holding the pipeline lock
+type PipelineOperation struct {
+	Operation string    `json:"operation"`
+	Since     time.Time `json:"since"`
+	HeldFor   float64   `json:"held_for_seconds"`
+}
+
+// PipelineBusyError is returned when another operation holds the pipeline lock
+type PipelineBusyError struct {
+	Operation string
+	Since     time.Time
+}
+
+func (e *PipelineBusyError) Error() string {
+	return fmt.Sprintf("Pipeline busy: %s in progress for %s", e.Operation, time.Since(e.Since).Round(time.Second))
+}
+
+// acquirePipeline takes the pipeline lock for an operation that stops or
+// starts ffmpeg/srtla. It never blocks: if another operation holds the lock a
+// *PipelineBusyError is returned. The release func is safe to call more than once.
+func (h *Handler) acquirePipeline(operation string) (func(), error) {
+	h.pipelineOpMu.Lock()
+	defer h.pipelineOpMu.Unlock()
+
+	if h.pipelineOp != nil {
+		return nil, &PipelineBusyError{Operation: h.pipelineOp.Operation, Since: h.pipelineOp.Since}
+	}
+	h.pipelineOp = &PipelineOperation{Operation: operation, Since: time.Now()}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			h.pipelineOpMu.Lock()
+			h.pipelineOp = nil
+			h.pipelineOpMu.Unlock()
+		})
+	}, nil
+}
+
+// CurrentPipelineOperation returns the operation holding the pipeline lock, or nil
+func (h *Handler) CurrentPipelineOperation() *PipelineOperation {
+	h.pipelineOpMu.Lock()
+	defer h.pipelineOpMu.Unlock()
+
+	if h.pipelineOp == nil {
+		return nil
+	}
+	op := *h.pipelineOp
+	op.HeldFor = time.Since(op.Since).Seconds()
+	return &op
+}
+
+// pipelineError writes err as a 409 with the holding operation when it is a
+// *PipelineBusyError, as a 412 with the unmet checks when it is a
+// *StartPolicyError, and falls back to jsonError with code otherwise.
+func pipelineError(w http.ResponseWriter, err error, code int) {
+	var policy *StartPolicyError
+	if errors.As(err, &policy) {
+		writeError(w, ErrorResponse{Error: policy.Error(), Code: http.StatusPreconditionFailed, Details: policy.Unmet})
+		return
+	}
+	var busy *PipelineBusyError
+	if !errors.As(err, &busy) {
+		jsonError(w, err.Error(), code)
+		return
+	}
+	writeError(w, ErrorResponse{
+		Error: busy.Error(),
+		Code:  http.StatusConflict,
+		Details: &PipelineOperation{
+			Operation: busy.Operation,
+			Since:     busy.Since,
+			HeldFor:   time.Since(busy.Since).Seconds(),
+		},
+	})
+}
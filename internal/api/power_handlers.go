@@ -0,0 +1,154 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"srtla-manager/internal/events"
+	"srtla-manager/internal/logger"
+	"srtla-manager/internal/power"
+	"srtla-manager/internal/validate"
+)
+
+// powerPollInterval is how often the battery/UPS state is read
+const powerPollInterval = 10 * time.Second
+
+// PowerResponse describes the power source and the active profile
+type PowerResponse struct {
+	Mode    string        `json:"mode"` // configured: auto, normal or low_power
+	Profile power.Profile `json:"profile"`
+	Status  power.Status  `json:"status"`
+}
+
+// PowerProfileRequest sets the profile mode
+type PowerProfileRequest struct {
+	Profile string `json:"profile"`
+}
+
+// StartPowerMonitor begins watching the power source and applies the
+// matching profile whenever it switches
+func (h *Handler) StartPowerMonitor(ctx context.Context) {
+	cfg := h.config.Get().Power
+
+	monitor := power.NewMonitor(cfg.UPSName, powerPollInterval)
+	monitor.OnChange(func(status power.Status) {
+		logger.Info("Power: now on %s (capacity %d%%)", status.Source, status.Capacity)
+		h.applyPowerProfile()
+	})
+
+	h.profileMu.Lock()
+	h.powerMonitor = monitor
+	h.profileMu.Unlock()
+
+	// Honour a manual low_power override before the first poll
+	h.applyPowerProfile()
+	go monitor.Run(ctx)
+}
+
+// PowerProfile returns the active performance profile
+func (h *Handler) PowerProfile() power.Profile {
+	h.profileMu.RLock()
+	defer h.profileMu.RUnlock()
+	return h.profile
+}
+
+// PowerProfileChanges delivers the new profile whenever it changes. Only the
+// latest change is kept if the receiver falls behind.
+func (h *Handler) PowerProfileChanges() <-chan power.Profile {
+	return h.profileChanges
+}
+
+// applyPowerProfile re-selects the profile from the configured mode and the
+// current power status
+func (h *Handler) applyPowerProfile() {
+	mode := h.config.Get().Power.Profile
+
+	h.profileMu.Lock()
+	var status power.Status
+	if h.powerMonitor != nil {
+		status = h.powerMonitor.Status()
+	}
+	profile := power.Select(mode, status)
+	changed := profile.Name != h.profile.Name
+	h.profile = profile
+	h.profileMu.Unlock()
+
+	if !changed {
+		return
+	}
+
+	h.logOutput("manager", fmt.Sprintf("[POWER] Switched to %s profile", profile.Name))
+
+	select {
+	case <-h.profileChanges:
+	default:
+	}
+	select {
+	case h.profileChanges <- profile:
+	default:
+	}
+
+	events.Publish(h.bus, TopicPower, h.powerResponse())
+
+	// The return feed preview follows the profile's frame rate cap
+	h.ApplyReturnFeedConfig()
+}
+
+func (h *Handler) powerResponse() PowerResponse {
+	h.profileMu.RLock()
+	defer h.profileMu.RUnlock()
+
+	mode := h.config.Get().Power.Profile
+	if mode == "" {
+		mode = power.ModeAuto
+	}
+
+	resp := PowerResponse{
+		Mode:    mode,
+		Profile: h.profile,
+		Status:  power.Status{Source: power.SourceUnknown, Capacity: -1},
+	}
+	if h.powerMonitor != nil {
+		resp.Status = h.powerMonitor.Status()
+	}
+	return resp
+}
+
+// HandlePowerStatus handles GET /api/power
+func (h *Handler) HandlePowerStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.powerResponse())
+}
+
+// HandlePowerProfile handles PUT /api/power/profile, overriding the automatic
+// profile selection. "auto" hands control back to the power monitor.
+func (h *Handler) HandlePowerProfile(w http.ResponseWriter, r *http.Request) {
+	var req PowerProfileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	v := validate.New()
+	v.Required("profile", req.Profile)
+	v.OneOf("profile", req.Profile, power.Modes...)
+	if err := v.Err(); err != nil {
+		validationError(w, err)
+		return
+	}
+
+	cfg := h.config.Get()
+	cfg.Power.Profile = req.Profile
+	if err := h.config.Update(cfg); err != nil {
+		jsonError(w, fmt.Sprintf("Failed to save configuration: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	h.applyPowerProfile()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.powerResponse())
+}
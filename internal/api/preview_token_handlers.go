@@ -0,0 +1,197 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"srtla-manager/internal/logger"
+	"srtla-manager/internal/previewtoken"
+	"srtla-manager/internal/validate"
+)
+
+const (
+	DefaultPreviewTokenTTL = 12 * time.Hour
+	MaxPreviewTokenTTL     = 7 * 24 * time.Hour
+)
+
+// previewTokenPrefixes are the HLS previews that need a token when
+// access.preview_tokens is on; a token is valid for everything under one
+var previewTokenPrefixes = []string{"/preview/", "/preview-temp/", "/return/"}
+
+// PreviewTokenRequest is the request body for POST /api/preview/token
+type PreviewTokenRequest struct {
+	Path       string `json:"path"`        // playlist to embed; /preview/playlist.m3u8 when empty
+	TTLMinutes int    `json:"ttl_minutes"` // access.preview_token_ttl_minutes when 0
+}
+
+// PreviewTokenResponse is a signed token and the playlist URL carrying it
+type PreviewTokenResponse struct {
+	Token     string    `json:"token"`
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// previewTokenPrefix returns the preview path needing a token path is
+// under, or "" for none
+func previewTokenPrefix(path string) string {
+	for _, prefix := range previewTokenPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return prefix
+		}
+	}
+	return ""
+}
+
+// previewTokenTTL is the lifetime of the tokens in preview URLs the API
+// returns
+func (h *Handler) previewTokenTTL() time.Duration {
+	if m := h.config.Get().Access.PreviewTokenTTLMinutes; m > 0 {
+		return time.Duration(m) * time.Minute
+	}
+	return DefaultPreviewTokenTTL
+}
+
+// previewURL returns the URL to play a preview at, carrying a token when
+// the previews need one, so the UI needs nothing more to play it
+func (h *Handler) previewURL(path string) string {
+	if !h.config.Get().Access.PreviewTokens {
+		return path
+	}
+	token := h.previewTokens.Sign(previewTokenPrefix(path), time.Now().Add(h.previewTokenTTL()))
+	return previewtoken.AddToURL(path, token)
+}
+
+// HandlePreviewToken handles POST /api/preview/token, signing a token to
+// embed a preview with. It works whether or not tokens are required, so an
+// embed keeps working when they are turned on.
+func (h *Handler) HandlePreviewToken(w http.ResponseWriter, r *http.Request) {
+	var req PreviewTokenRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			jsonError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+	if req.Path == "" {
+		req.Path = "/preview/playlist.m3u8"
+	}
+
+	prefix := previewTokenPrefix(req.Path)
+	v := validate.New()
+	if prefix == "" || strings.Contains(req.Path, "..") || strings.ContainsAny(req.Path, "?#") {
+		v.Addf("path", "must be a file under %s", strings.Join(previewTokenPrefixes, ", "))
+	}
+	v.Range("ttl_minutes", req.TTLMinutes, 0, int(MaxPreviewTokenTTL/time.Minute))
+	if err := v.Err(); err != nil {
+		validationError(w, err)
+		return
+	}
+
+	ttl := h.previewTokenTTL()
+	if req.TTLMinutes > 0 {
+		ttl = time.Duration(req.TTLMinutes) * time.Minute
+	}
+	expires := time.Now().Add(ttl).Truncate(time.Second)
+	token := h.previewTokens.Sign(prefix, expires)
+
+	logger.Info("Signed a preview token for %s expiring at %s", prefix, expires.Format(time.RFC3339))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(PreviewTokenResponse{
+		Token:     token,
+		URL:       previewtoken.AddToURL(req.Path, token),
+		ExpiresAt: expires,
+	})
+}
+
+// HandlePreviewTokenRevoke handles DELETE /api/preview/token, invalidating
+// every preview token signed so far. Players in the UI pick up a new one
+// when the preview is opened again.
+func (h *Handler) HandlePreviewTokenRevoke(w http.ResponseWriter, r *http.Request) {
+	h.previewTokens.Rotate()
+	h.logOutput("manager", "[ACCESS] Revoked all preview tokens")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "revoked"})
+}
+
+// checkPreviewToken reports whether a request for a preview needing a token
+// carries a valid one, writing the error if not
+func (h *Handler) checkPreviewToken(w http.ResponseWriter, r *http.Request, prefix string) bool {
+	token := r.URL.Query().Get(previewtoken.Param)
+	if token == "" {
+		jsonError(w, "Preview token required", http.StatusUnauthorized)
+		return false
+	}
+	if err := h.previewTokens.Verify(token, prefix, time.Now()); err != nil {
+		logger.Debug("Rejected request from %s to %s: %v", r.RemoteAddr, r.URL.Path, err)
+		jsonError(w, err.Error(), http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+// TokenPlaylists serves the HLS playlists of next with the request's
+// preview token added to the URIs they list, so the player sends it along
+// for every segment
+func TokenPlaylists(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.URL.Query().Get(previewtoken.Param)
+		if token == "" || !strings.HasSuffix(r.URL.Path, ".m3u8") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// the playlist is rewritten whole, so ranges and revalidation
+		// don't apply
+		r = r.Clone(r.Context())
+		for _, name := range []string{"Range", "If-Range", "If-Modified-Since", "If-None-Match"} {
+			r.Header.Del(name)
+		}
+		buf := &playlistBuffer{header: http.Header{}}
+		next.ServeHTTP(buf, r)
+
+		for name, values := range buf.header {
+			w.Header()[name] = values
+		}
+		body := buf.body.Bytes()
+		if buf.status == 0 || buf.status == http.StatusOK {
+			body = previewtoken.AddToPlaylist(body, token)
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+			w.Header().Set("Cache-Control", "no-store")
+			w.Header().Del("Last-Modified")
+			w.Header().Del("Accept-Ranges")
+		}
+		if buf.status != 0 {
+			w.WriteHeader(buf.status)
+		}
+		w.Write(body)
+	})
+}
+
+// playlistBuffer holds a playlist response back to rewrite it
+type playlistBuffer struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *playlistBuffer) Header() http.Header { return b.header }
+
+func (b *playlistBuffer) WriteHeader(code int) {
+	if b.status == 0 {
+		b.status = code
+	}
+}
+
+func (b *playlistBuffer) Write(p []byte) (int, error) {
+	if b.status == 0 {
+		b.status = http.StatusOK
+	}
+	return b.body.Write(p)
+}
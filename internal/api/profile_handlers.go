@@ -0,0 +1,117 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"srtla-manager/internal/config"
+	"srtla-manager/internal/logger"
+)
+
+// Profile is a named stream profile
+type Profile struct {
+	Name string `json:"name"`
+	config.StreamProfile
+}
+
+// ProfilesResponse lists the stream profiles and the one last applied
+type ProfilesResponse struct {
+	Active   string    `json:"active"`
+	Profiles []Profile `json:"profiles"`
+}
+
+// HandleProfileList handles GET /api/profiles
+func (h *Handler) HandleProfileList(w http.ResponseWriter, r *http.Request) {
+	cfg := h.config.Get()
+	resp := ProfilesResponse{
+		Active:   cfg.Profile,
+		Profiles: make([]Profile, 0, len(cfg.Profiles)),
+	}
+	for name, p := range cfg.Profiles {
+		resp.Profiles = append(resp.Profiles, Profile{Name: name, StreamProfile: p})
+	}
+	sort.Slice(resp.Profiles, func(i, j int) bool { return resp.Profiles[i].Name < resp.Profiles[j].Name })
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// HandleProfileGet handles GET /api/profiles/{name}
+func (h *Handler) HandleProfileGet(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	p, ok := h.config.Get().Profiles[name]
+	if !ok {
+		jsonError(w, "Stream profile not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Profile{Name: name, StreamProfile: p})
+}
+
+// HandleProfileSave handles PUT /api/profiles/{name}, creating or replacing
+// the profile. It takes effect the next time a stream is started with it.
+func (h *Handler) HandleProfileSave(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	var req config.StreamProfile
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	cfg := h.config.Get()
+	profiles := make(map[string]config.StreamProfile, len(cfg.Profiles)+1)
+	for n, p := range cfg.Profiles {
+		profiles[n] = p
+	}
+	profiles[name] = req
+	cfg.Profiles = profiles
+
+	// config validation covers the name and the fields
+	if err := h.config.Update(cfg); err != nil {
+		validationError(w, err)
+		return
+	}
+
+	logger.Info("Saved stream profile %s (%s:%d)", name, req.RemoteHost, req.RemotePort)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Profile{Name: name, StreamProfile: req})
+}
+
+// HandleProfileDelete handles DELETE /api/profiles/{name}. The profile the
+// running stream was started with can't be deleted.
+func (h *Handler) HandleProfileDelete(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	cfg := h.config.Get()
+	if _, ok := cfg.Profiles[name]; !ok {
+		jsonError(w, "Stream profile not found", http.StatusNotFound)
+		return
+	}
+	if cfg.Profile == name && h.GetPipelineMode() == PipelineModeStreaming {
+		jsonError(w, fmt.Sprintf("%s is in use by the running stream", name), http.StatusConflict)
+		return
+	}
+
+	profiles := make(map[string]config.StreamProfile, len(cfg.Profiles))
+	for n, p := range cfg.Profiles {
+		if n != name {
+			profiles[n] = p
+		}
+	}
+	cfg.Profiles = profiles
+	if cfg.Profile == name {
+		cfg.Profile = ""
+	}
+
+	if err := h.config.Update(cfg); err != nil {
+		jsonError(w, fmt.Sprintf("Failed to save configuration: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,273 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"sort"
+	"time"
+
+	"srtla-manager/internal/config"
+	"srtla-manager/internal/events"
+	"srtla-manager/internal/logger"
+	"srtla-manager/internal/modem"
+	"srtla-manager/internal/quota"
+	"srtla-manager/internal/system"
+)
+
+// quotaSaveInterval is the least time between writes of the usage totals,
+// sparing the SD card; they are also written on shutdown
+const quotaSaveInterval = time.Minute
+
+// QuotaStatus is a modem's data use against its budget
+type QuotaStatus struct {
+	IMEI        string    `json:"imei"`
+	ModemID     string    `json:"modem_id,omitempty"` // while the modem is present
+	Period      string    `json:"period"`
+	PeriodStart time.Time `json:"period_start,omitempty"`
+	LimitBytes  int64     `json:"limit_bytes"`
+	UsedBytes   int64     `json:"used_bytes"`
+	Percent     float64   `json:"percent"`
+	Capped      bool      `json:"capped"`            // the budget is used up
+	Dropped     []string  `json:"dropped,omitempty"` // bind IPs left out of the bond for it
+}
+
+// QuotaEvent reports a modem passing a warning threshold, using up its
+// budget or starting a new period
+type QuotaEvent struct {
+	IMEI       string `json:"imei"`
+	ModemID    string `json:"modem_id"`
+	Event      string `json:"event"` // warning, cap_reached or reset
+	Percent    int    `json:"percent,omitempty"`
+	UsedBytes  int64  `json:"used_bytes"`
+	LimitBytes int64  `json:"limit_bytes"`
+}
+
+// ApplyQuotaConfig opens the usage totals when quotas are enabled
+func (h *Handler) ApplyQuotaConfig() {
+	cfg := h.config.Get().Quota
+
+	h.quotaMu.Lock()
+	defer h.quotaMu.Unlock()
+	if cfg.Enabled && h.quotaTracker != nil && h.quotaPath == cfg.Path {
+		return
+	}
+	h.stopQuotaLocked()
+	if !cfg.Enabled {
+		return
+	}
+
+	tracker, err := quota.Open(cfg.Path)
+	if err != nil {
+		logger.Error("Quota: %v", err)
+		return
+	}
+	h.quotaTracker = tracker
+	h.quotaPath = cfg.Path
+	h.logOutput("manager", fmt.Sprintf("[QUOTA] Tracking data use of %d modems in %s", len(cfg.Modems), cfg.Path))
+}
+
+// StopQuota writes the usage totals on shutdown
+func (h *Handler) StopQuota() {
+	h.quotaMu.Lock()
+	defer h.quotaMu.Unlock()
+	h.stopQuotaLocked()
+}
+
+func (h *Handler) stopQuotaLocked() {
+	if h.quotaTracker != nil {
+		if err := h.quotaTracker.Save(); err != nil {
+			logger.Error("Quota: %v", err)
+		}
+	}
+	h.quotaTracker = nil
+	h.quotaPath = ""
+	h.quotaDropped = nil
+}
+
+// CheckQuotas adds the modems' counters to their usage, publishes
+// thresholds passed and takes links whose budget is used up out of the
+// bond. It is called each time the modems are polled.
+func (h *Handler) CheckQuotas(modems []modem.ModemInfo) {
+	cfg := h.config.Get().Quota
+	now := time.Now()
+
+	h.quotaMu.Lock()
+	tracker := h.quotaTracker
+	if tracker == nil {
+		h.quotaMu.Unlock()
+		return
+	}
+
+	var fired []QuotaEvent
+	dropped := make(map[string]string)
+	for _, m := range modems {
+		q, ok := cfg.Modems[m.IMEI]
+		if !ok || m.IMEI == "" {
+			continue
+		}
+		limit := int64(q.LimitMB) << 20
+		before, known := tracker.Usage(m.IMEI)
+		after := tracker.Record(m.IMEI, m.DataTx, m.DataRx, quota.Period(q.Period), q.ResetDay, now)
+
+		ev := QuotaEvent{IMEI: m.IMEI, ModemID: m.ID, UsedBytes: after.Bytes, LimitBytes: limit}
+		if known && !before.PeriodStart.Equal(after.PeriodStart) {
+			before.Bytes = 0
+			ev.Event = "reset"
+			fired = append(fired, ev)
+		}
+		for _, pct := range quota.Crossed(q.WarnPercent, limit, before.Bytes, after.Bytes) {
+			ev.Event, ev.Percent = "warning", pct
+			fired = append(fired, ev)
+		}
+		if before.Bytes < limit && after.Bytes >= limit {
+			ev.Event, ev.Percent = "cap_reached", 100
+			fired = append(fired, ev)
+		}
+		if q.DropAtCap && after.Bytes >= limit {
+			for _, ip := range modemBindIPs(m) {
+				dropped[ip] = m.IMEI
+			}
+		}
+	}
+
+	changed := !maps.Equal(dropped, h.quotaDropped)
+	h.quotaDropped = dropped
+	if now.Sub(h.quotaSaved) >= quotaSaveInterval || len(fired) > 0 {
+		if err := tracker.Save(); err != nil {
+			logger.Error("Quota: %v", err)
+		}
+		h.quotaSaved = now
+	}
+	h.quotaMu.Unlock()
+
+	for _, ev := range fired {
+		msg := fmt.Sprintf("[QUOTA] Modem %s (%s) %s: %s of %s used", ev.ModemID, ev.IMEI, ev.Event, formatBytes(ev.UsedBytes), formatBytes(ev.LimitBytes))
+		if ev.Event == "warning" {
+			msg = fmt.Sprintf("[QUOTA] Modem %s (%s) passed %d%% of its data budget: %s of %s used", ev.ModemID, ev.IMEI, ev.Percent, formatBytes(ev.UsedBytes), formatBytes(ev.LimitBytes))
+		}
+		h.logOutput("manager", msg)
+		events.Publish(h.bus, TopicQuota, ev)
+	}
+
+	if changed {
+		ips := slices.Sorted(maps.Keys(dropped))
+		h.logOutput("manager", fmt.Sprintf("[QUOTA] Links out of the bond for their data budget: %v", ips))
+		if err := h.reloadSwapLinks(); err != nil {
+			h.logOutput("manager", fmt.Sprintf("[QUOTA] Failed to reload links: %v", err))
+		}
+	}
+}
+
+// quotaDroppedIP reports whether ip is out of the bond for its modem's
+// data budget
+func (h *Handler) quotaDroppedIP(ip string) bool {
+	h.quotaMu.Lock()
+	defer h.quotaMu.Unlock()
+	_, ok := h.quotaDropped[ip]
+	return ok
+}
+
+// modemBindIPs returns the addresses this unit sends from over a modem:
+// those of its interface, or the modem's own address
+func modemBindIPs(m modem.ModemInfo) []string {
+	if m.Interface != "" {
+		for _, iface := range system.ListNetworkInterfaces() {
+			if iface.Name == m.Interface && len(iface.IPs) > 0 {
+				return iface.IPs
+			}
+		}
+	}
+	if m.IPAddress != "" {
+		return []string{m.IPAddress}
+	}
+	return nil
+}
+
+func formatBytes(b int64) string {
+	switch {
+	case b >= 1<<30:
+		return fmt.Sprintf("%.2f GB", float64(b)/(1<<30))
+	default:
+		return fmt.Sprintf("%.1f MB", float64(b)/(1<<20))
+	}
+}
+
+func (h *Handler) quotaStatus(cfg config.QuotaConfig) []QuotaStatus {
+	h.modemsMu.Lock()
+	modems := h.modems
+	h.modemsMu.Unlock()
+	ids := make(map[string]string)
+	for _, m := range modems {
+		ids[m.IMEI] = m.ID
+	}
+
+	h.quotaMu.Lock()
+	defer h.quotaMu.Unlock()
+
+	list := make([]QuotaStatus, 0, len(cfg.Modems))
+	for imei, q := range cfg.Modems {
+		s := QuotaStatus{IMEI: imei, ModemID: ids[imei], Period: q.Period, LimitBytes: int64(q.LimitMB) << 20}
+		if h.quotaTracker != nil {
+			if u, ok := h.quotaTracker.Usage(imei); ok {
+				s.PeriodStart = u.PeriodStart
+				s.UsedBytes = u.Bytes
+			}
+		}
+		s.Percent = float64(s.UsedBytes) * 100 / float64(s.LimitBytes)
+		s.Capped = s.UsedBytes >= s.LimitBytes
+		for ip, owner := range h.quotaDropped {
+			if owner == imei {
+				s.Dropped = append(s.Dropped, ip)
+			}
+		}
+		sort.Strings(s.Dropped)
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].IMEI < list[j].IMEI })
+	return list
+}
+
+// HandleQuotas handles GET /api/quotas
+func (h *Handler) HandleQuotas(w http.ResponseWriter, r *http.Request) {
+	cfg := h.config.Get().Quota
+	if !cfg.Enabled {
+		jsonError(w, "Data quotas are disabled", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.quotaStatus(cfg))
+}
+
+// HandleQuotaReset handles POST /api/quotas/{imei}/reset, zeroing a
+// modem's usage for the rest of its period, e.g. after a top-up. Its link
+// rejoins the bond at the next check.
+func (h *Handler) HandleQuotaReset(w http.ResponseWriter, r *http.Request) {
+	imei := r.PathValue("imei")
+	cfg := h.config.Get().Quota
+	if _, ok := cfg.Modems[imei]; !ok {
+		jsonError(w, "No quota for this modem", http.StatusNotFound)
+		return
+	}
+
+	h.quotaMu.Lock()
+	tracker := h.quotaTracker
+	h.quotaMu.Unlock()
+	if tracker == nil {
+		jsonError(w, "Data quotas are disabled", http.StatusConflict)
+		return
+	}
+	tracker.Reset(imei)
+	if err := tracker.Save(); err != nil {
+		jsonError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.logOutput("manager", fmt.Sprintf("[QUOTA] Reset data use of %s", imei))
+	events.Publish(h.bus, TopicQuota, QuotaEvent{IMEI: imei, Event: "reset", LimitBytes: int64(cfg.Modems[imei].LimitMB) << 20})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.quotaStatus(cfg))
+}
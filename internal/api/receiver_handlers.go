@@ -0,0 +1,236 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"srtla-manager/internal/config"
+	"srtla-manager/internal/process"
+	"srtla-manager/internal/stats"
+)
+
+// ReceiverStatus describes the receiver settings and srtla_rec
+type ReceiverStatus struct {
+	config.ReceiverConfig
+	Running   bool                  `json:"running"`
+	Stats     process.ReceiverStats `json:"stats"`
+	LastError string                `json:"last_error,omitempty"`
+}
+
+// ApplyReceiverConfig starts, restarts or stops srtla_rec to match the
+// configuration. A receiver started by hand while disabled keeps running
+// until it is stopped.
+func (h *Handler) ApplyReceiverConfig() {
+	cfg := h.config.Get().Receiver
+
+	h.receiverMu.Lock()
+	defer h.receiverMu.Unlock()
+
+	applied := h.receiverApplied
+	if !cfg.Enabled {
+		if applied != nil && applied.Enabled {
+			h.stopReceiverLocked()
+			h.logOutput("manager", "[RECEIVER] Stopped srtla_rec")
+		}
+		return
+	}
+	if applied != nil {
+		running := *applied
+		running.Enabled = true
+		if running == cfg {
+			applied.Enabled = true
+			return
+		}
+	}
+	if err := h.startReceiverLocked(cfg); err != nil {
+		h.logOutput("manager", fmt.Sprintf("[RECEIVER] Failed to start srtla_rec: %v", err))
+	}
+}
+
+// startReceiverLocked (re)starts srtla_rec with cfg and watches it.
+// h.receiverMu must be held.
+func (h *Handler) startReceiverLocked(cfg config.ReceiverConfig) error {
+	h.stopReceiverLocked()
+
+	if err := h.srtlaRec.Start(cfg.BinaryPath, cfg.ListenPort, cfg.SRTHost, cfg.SRTPort); err != nil {
+		return err
+	}
+	h.receiverApplied = &cfg
+	h.recordRestartSuccess(h.receiverRestarts)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	h.receiverCancel = cancel
+	go h.monitorReceiver(ctx)
+
+	h.logOutput("manager", fmt.Sprintf("[RECEIVER] Receiving SRTLA on UDP port %d, forwarding to srt://%s:%d",
+		cfg.ListenPort, cfg.SRTHost, cfg.SRTPort))
+	return nil
+}
+
+// stopReceiverLocked stops srtla_rec and its monitor. h.receiverMu must be
+// held.
+func (h *Handler) stopReceiverLocked() {
+	if h.receiverCancel != nil {
+		h.receiverCancel()
+		h.receiverCancel = nil
+	}
+	if h.receiverApplied != nil {
+		h.srtlaRec.Stop()
+		h.receiverApplied = nil
+	}
+}
+
+// StopReceiver stops srtla_rec on shutdown
+func (h *Handler) StopReceiver() {
+	h.receiverMu.Lock()
+	defer h.receiverMu.Unlock()
+	h.stopReceiverLocked()
+}
+
+// monitorReceiver restarts srtla_rec if it exits, backing off like the
+// sender's processes do
+func (h *Handler) monitorReceiver(ctx context.Context) {
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if h.srtlaRec.ProcessState() == process.StateRunning {
+			continue
+		}
+		reason := "stopped unexpectedly"
+		if err := h.srtlaRec.LastError(); err != "" {
+			reason = fmt.Sprintf("exited (%s)", err)
+		}
+		if !h.shouldRestartWithBackoff(h.receiverRestarts, reason, "srtla_rec") {
+			continue
+		}
+
+		h.receiverMu.Lock()
+		cfg := h.receiverApplied
+		if ctx.Err() != nil || cfg == nil {
+			h.receiverMu.Unlock()
+			return
+		}
+		h.logOutput("manager", fmt.Sprintf("[AUTO-RESTART] srtla_rec %s, restarting...", reason))
+		if err := h.srtlaRec.Start(cfg.BinaryPath, cfg.ListenPort, cfg.SRTHost, cfg.SRTPort); err != nil {
+			h.recordRestartFailure(h.receiverRestarts)
+			h.logOutput("manager", fmt.Sprintf("[AUTO-RESTART] Failed to restart srtla_rec: %v", err))
+		} else {
+			h.recordRestartSuccess(h.receiverRestarts)
+		}
+		h.receiverMu.Unlock()
+	}
+}
+
+// registerReceiverMetrics describes the receiver metrics and adds them as a
+// source; nothing is reported while srtla_rec isn't running
+func (h *Handler) registerReceiverMetrics() {
+	h.stats.Register(
+		stats.Metric{Name: "receiver_links", Help: "Sender links registered with srtla_rec", Kind: stats.Gauge},
+		stats.Metric{Name: "receiver_groups", Help: "Bonded senders connected to srtla_rec", Kind: stats.Gauge},
+		stats.Metric{Name: "receiver_links_registered_total", Help: "Sender links registered since srtla_rec started", Kind: stats.Counter},
+		stats.Metric{Name: "receiver_links_removed_total", Help: "Sender links dropped since srtla_rec started", Kind: stats.Counter},
+	)
+	h.stats.AddSource(func(add stats.AddFunc) {
+		if h.srtlaRec.ProcessState() != process.StateRunning {
+			return
+		}
+		st := h.srtlaRec.Stats()
+		add("receiver_links", float64(len(st.Connections)))
+		add("receiver_groups", float64(st.Groups))
+		add("receiver_links_registered_total", float64(st.Registered))
+		add("receiver_links_removed_total", float64(st.Removed))
+	})
+}
+
+// ReceiverStats returns srtla_rec's links for the stats broadcast
+func (h *Handler) ReceiverStats() process.ReceiverStats {
+	return h.srtlaRec.Stats()
+}
+
+func (h *Handler) receiverStatus() ReceiverStatus {
+	h.receiverMu.Lock()
+	running := h.receiverApplied != nil
+	h.receiverMu.Unlock()
+
+	return ReceiverStatus{
+		ReceiverConfig: h.config.Get().Receiver,
+		Running:        running,
+		Stats:          h.srtlaRec.Stats(),
+		LastError:      h.srtlaRec.LastError(),
+	}
+}
+
+// HandleReceiverStatus handles GET /api/receiver
+func (h *Handler) HandleReceiverStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.receiverStatus())
+}
+
+// HandleReceiverUpdate handles PUT /api/receiver. Fields missing from the body
+// keep their current values.
+func (h *Handler) HandleReceiverUpdate(w http.ResponseWriter, r *http.Request) {
+	cfg := h.config.Get()
+	if err := json.NewDecoder(r.Body).Decode(&cfg.Receiver); err != nil {
+		jsonError(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.config.Update(cfg); err != nil {
+		validationError(w, err)
+		return
+	}
+
+	h.ApplyReceiverConfig()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.receiverStatus())
+}
+
+// HandleReceiverStart handles POST /api/receiver/start, running srtla_rec
+// with the configured settings until it is stopped or the manager restarts
+func (h *Handler) HandleReceiverStart(w http.ResponseWriter, r *http.Request) {
+	cfg := h.config.Get()
+	// the settings are only validated when enabled
+	check := cfg
+	check.Receiver.Enabled = true
+	if err := check.Validate(); err != nil {
+		validationError(w, err)
+		return
+	}
+
+	h.receiverMu.Lock()
+	err := h.startReceiverLocked(cfg.Receiver)
+	h.receiverMu.Unlock()
+	if err != nil {
+		jsonError(w, fmt.Sprintf("Failed to start srtla_rec: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.receiverStatus())
+}
+
+// HandleReceiverStop handles POST /api/receiver/stop
+func (h *Handler) HandleReceiverStop(w http.ResponseWriter, r *http.Request) {
+	h.receiverMu.Lock()
+	running := h.receiverApplied != nil
+	h.stopReceiverLocked()
+	h.receiverMu.Unlock()
+
+	if running {
+		h.logOutput("manager", "[RECEIVER] Stopped srtla_rec")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.receiverStatus())
+}
@@ -0,0 +1,210 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"srtla-manager/internal/jobs"
+	"srtla-manager/internal/relay"
+	"srtla-manager/internal/validate"
+)
+
+// Time allowed for a relay to be set up
+const (
+	relayCloudTimeout = 10 * time.Minute // for the server to come up
+	relaySSHTimeout   = 20 * time.Minute // for the packages and the build
+)
+
+var (
+	relayNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+	relayUserPattern = regexp.MustCompile(`^[a-z_][a-z0-9_-]{0,31}$`)
+)
+
+// RelayProvisionRequest is the body of POST /api/relay/provision. The
+// token is used for this request only and isn't stored.
+type RelayProvisionRequest struct {
+	Method string `json:"method"` // ssh, digitalocean or hetzner
+
+	// cloud
+	Token  string `json:"token"`
+	Name   string `json:"name"`   // of the server; srtla-relay-<time> when empty
+	Region string `json:"region"` // DigitalOcean region or Hetzner location
+	Size   string `json:"size"`   // DigitalOcean size or Hetzner server type
+
+	// ssh
+	Host    string `json:"host"`
+	Port    int    `json:"port"`
+	User    string `json:"user"`     // root when empty; others need passwordless sudo
+	KeyFile string `json:"key_file"` // private key; ssh's default when empty
+
+	SRTLAPort  int   `json:"srtla_port"`  // default 5000
+	OutputPort int   `json:"output_port"` // default 4000
+	Apply      *bool `json:"apply"`       // set srtla.remote_host and remote_port; default true
+}
+
+func (req RelayProvisionRequest) validate() error {
+	v := validate.New()
+	v.Required("method", req.Method)
+	v.OneOf("method", req.Method, relay.Methods...)
+	if req.Method == relay.MethodSSH {
+		v.Required("host", req.Host)
+		if strings.HasPrefix(req.Host, "-") || strings.ContainsAny(req.Host, " \t@/") {
+			v.Addf("host", "must be a host name or IP address")
+		}
+		if req.Port != 0 {
+			v.Port("port", req.Port)
+		}
+		if req.User != "" && !relayUserPattern.MatchString(req.User) {
+			v.Addf("user", "must be a user name")
+		}
+		if req.KeyFile != "" && !filepath.IsAbs(req.KeyFile) {
+			v.Addf("key_file", "must be an absolute path")
+		}
+	} else if req.Method != "" {
+		v.Required("token", req.Token)
+		if req.Name != "" && !relayNamePattern.MatchString(req.Name) {
+			v.Addf("name", "must be lowercase letters, digits and hyphens")
+		}
+	}
+	if req.SRTLAPort != 0 {
+		v.Port("srtla_port", req.SRTLAPort)
+	}
+	if req.OutputPort != 0 {
+		v.Port("output_port", req.OutputPort)
+	}
+	settings := req.settings()
+	if settings.SRTLAPort == settings.OutputPort {
+		v.Addf("output_port", "must differ from srtla_port")
+	}
+	return v.Err()
+}
+
+func (req RelayProvisionRequest) settings() relay.Settings {
+	return relay.NewSettings(req.SRTLAPort, req.OutputPort)
+}
+
+func (req RelayProvisionRequest) cloud() relay.Cloud {
+	if req.Method == relay.MethodHetzner {
+		return relay.Hetzner{Token: req.Token, Location: req.Region, ServerType: req.Size}
+	}
+	return relay.DigitalOcean{Token: req.Token, Region: req.Region, Size: req.Size}
+}
+
+// RelayStatus is the response of GET /api/relay
+type RelayStatus struct {
+	Running bool          `json:"running"`
+	Last    *relay.Result `json:"last,omitempty"`
+}
+
+// HandleRelay handles GET /api/relay, returning the last relay set up
+func (h *Handler) HandleRelay(w http.ResponseWriter, r *http.Request) {
+	h.relayMu.Lock()
+	status := RelayStatus{Running: h.relayRunning, Last: h.relayLast}
+	h.relayMu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// HandleRelayProvision handles POST /api/relay/provision. It sets up a
+// receiver on a VPS over SSH or on a new cloud server as a background job,
+// and points srtla_send at it.
+func (h *Handler) HandleRelayProvision(w http.ResponseWriter, r *http.Request) {
+	var req RelayProvisionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+	if err := req.validate(); err != nil {
+		validationError(w, err)
+		return
+	}
+	if req.Name == "" {
+		req.Name = fmt.Sprintf("srtla-relay-%d", time.Now().Unix())
+	}
+
+	h.relayMu.Lock()
+	if h.relayRunning {
+		h.relayMu.Unlock()
+		jsonError(w, "A relay is already being set up", http.StatusConflict)
+		return
+	}
+	h.relayRunning = true
+	h.relayMu.Unlock()
+
+	h.logOutput("manager", fmt.Sprintf("[RELAY] Setting up a relay with %s", req.Method))
+	job := h.jobs.Start("relay_provision", func(ctx context.Context, report *jobs.Reporter) (interface{}, error) {
+		result, err := h.provisionRelay(ctx, report, req)
+
+		h.relayMu.Lock()
+		h.relayRunning = false
+		if err == nil {
+			h.relayLast = &result
+		}
+		h.relayMu.Unlock()
+
+		if err != nil {
+			h.logOutput("manager", fmt.Sprintf("[RELAY] Failed: %v", err))
+			return nil, err
+		}
+		return result, nil
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"status": "started",
+		"job_id": job.ID,
+	})
+}
+
+func (h *Handler) provisionRelay(ctx context.Context, report *jobs.Reporter, req RelayProvisionRequest) (relay.Result, error) {
+	settings := req.settings()
+	var result relay.Result
+
+	if req.Method == relay.MethodSSH {
+		ctx, cancel := context.WithTimeout(ctx, relaySSHTimeout)
+		defer cancel()
+		report.Progress(5, "Installing the relay on %s", req.Host)
+		target := relay.SSH{Host: req.Host, Port: req.Port, User: req.User, KeyFile: req.KeyFile}
+		err := target.Install(ctx, settings, func(line string) {
+			h.logOutput("relay", line)
+			report.Progress(50, "%s", line)
+		})
+		if err != nil {
+			return result, err
+		}
+		result = relay.NewResult(req.Method, req.Host, settings)
+	} else {
+		ctx, cancel := context.WithTimeout(ctx, relayCloudTimeout)
+		defer cancel()
+		report.Progress(5, "Creating server %s at %s", req.Name, req.Method)
+		id, host, err := relay.Provision(ctx, req.cloud(), req.Name, settings)
+		if err != nil {
+			return result, err
+		}
+		h.logOutput("manager", fmt.Sprintf("[RELAY] Server %s is up at %s; installing the relay takes a few minutes more", id, host))
+		result = relay.NewResult(req.Method, host, settings)
+		result.ServerID, result.Installing = id, true
+	}
+
+	if req.Apply == nil || *req.Apply {
+		report.Progress(95, "Sending to %s:%d", result.Host, result.SRTLAPort)
+		cfg := h.config.Get()
+		cfg.SRTLA.RemoteHost, cfg.SRTLA.RemotePort = result.Host, result.SRTLAPort
+		if err := h.config.Update(cfg); err != nil {
+			return result, fmt.Errorf("relay is ready but the config was not updated: %w", err)
+		}
+		result.Applied = true
+	}
+
+	// the output URL holds the passphrase, so it stays out of the log
+	h.logOutput("manager", fmt.Sprintf("[RELAY] Relay at %s:%d, studio pulls from port %d", result.Host, result.SRTLAPort, settings.OutputPort))
+	report.Progress(100, "Relay at %s", result.Host)
+	return result, nil
+}
@@ -0,0 +1,172 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"srtla-manager/internal/config"
+	"srtla-manager/internal/logger"
+	"srtla-manager/internal/validate"
+	"srtla-manager/internal/wifi"
+)
+
+// CameraReservationRequest is the request body for PUT /api/cameras/{id}/reservation
+type CameraReservationRequest struct {
+	MAC string `json:"mac"`
+	IP  string `json:"ip"`
+}
+
+// CameraReservationResponse reports the stored reservation
+type CameraReservationResponse struct {
+	Camera          string `json:"camera"`
+	MAC             string `json:"mac"`
+	IP              string `json:"ip"`
+	RestartRequired bool   `json:"restart_required"` // hotspot must be restarted to apply
+}
+
+// HotspotClient is a DHCP lease, tagged with the camera reserved for its MAC
+type HotspotClient struct {
+	wifi.Lease
+	Camera string `json:"camera,omitempty"`
+}
+
+// dhcpReservations collects the camera reservations from the config
+func dhcpReservations(cfg config.Config) []wifi.Reservation {
+	var reservations []wifi.Reservation
+	for id, cam := range cfg.Cameras {
+		if cam.WiFiMAC == "" || cam.ReservedIP == "" {
+			continue
+		}
+		reservations = append(reservations, wifi.Reservation{Name: id, MAC: cam.WiFiMAC, IP: cam.ReservedIP})
+	}
+	return reservations
+}
+
+// applyDHCPReservations writes the camera reservations for the hotspot's DHCP server
+func (h *Handler) applyDHCPReservations() error {
+	if h.wifiMgr == nil {
+		return nil
+	}
+	return h.wifiMgr.SetReservations(dhcpReservations(h.config.Get()))
+}
+
+// HandleCameraReservation handles PUT/DELETE /api/cameras/{id}/reservation
+func (h *Handler) HandleCameraReservation(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPut && r.Method != http.MethodDelete {
+		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if h.wifiMgr == nil {
+		jsonError(w, "WiFi manager not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	parts := ParseCameraPath(r.URL.Path, "/reservation")
+	if len(parts) < 1 || parts[0] == "" {
+		jsonError(w, "Camera ID required", http.StatusBadRequest)
+		return
+	}
+	cameraID := parts[0]
+
+	var req CameraReservationRequest
+	if r.Method == http.MethodPut {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			jsonError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		req.MAC = strings.ToLower(strings.TrimSpace(req.MAC))
+		req.IP = strings.TrimSpace(req.IP)
+		v := validate.New()
+		v.Required("mac", req.MAC)
+		v.MAC("mac", req.MAC)
+		v.Required("ip", req.IP)
+		v.IPv4("ip", req.IP)
+		if hotspotIP := h.wifiMgr.GetHotspotIP(); hotspotIP != "" && req.IP != "" {
+			if req.IP == hotspotIP {
+				v.Addf("ip", "must not be the hotspot's own address")
+			} else if !sameSubnet24(req.IP, hotspotIP) {
+				v.Addf("ip", "must be in the hotspot subnet (%s/24)", hotspotIP)
+			}
+		}
+		if err := v.Err(); err != nil {
+			validationError(w, err)
+			return
+		}
+	}
+
+	cfg := h.config.Get()
+	cam, ok := cfg.Cameras[cameraID]
+	if !ok {
+		jsonError(w, "Camera not found", http.StatusNotFound)
+		return
+	}
+
+	// removing a reservation that isn't there changes nothing
+	if req.MAC == "" && cam.WiFiMAC == "" && cam.ReservedIP == "" {
+		json.NewEncoder(w).Encode(CameraReservationResponse{Camera: cameraID})
+		return
+	}
+
+	cameras := make(map[string]config.CameraConfig, len(cfg.Cameras))
+	for id, c := range cfg.Cameras {
+		cameras[id] = c
+	}
+	cam.WiFiMAC = req.MAC
+	cam.ReservedIP = req.IP
+	cameras[cameraID] = cam
+	cfg.Cameras = cameras
+
+	if err := h.config.Update(cfg); err != nil {
+		configError(w, err)
+		return
+	}
+	if err := h.applyDHCPReservations(); err != nil {
+		jsonError(w, "Failed to apply reservation: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if req.MAC == "" {
+		logger.Info("Removed DHCP reservation for camera %s", cameraID)
+	} else {
+		logger.Info("Reserved %s for camera %s (%s)", req.IP, cameraID, req.MAC)
+	}
+
+	json.NewEncoder(w).Encode(CameraReservationResponse{
+		Camera:          cameraID,
+		MAC:             req.MAC,
+		IP:              req.IP,
+		RestartRequired: h.wifiMgr.GetHotspotIP() != "",
+	})
+}
+
+// handleWiFiHotspotClients lists devices holding a lease from the hotspot so
+// a camera's MAC can be picked for a reservation
+func (h *Handler) handleWiFiHotspotClients(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	byMAC := make(map[string]string)
+	for _, res := range dhcpReservations(h.config.Get()) {
+		byMAC[strings.ToLower(res.MAC)] = res.Name
+	}
+
+	clients := []HotspotClient{}
+	for _, lease := range h.wifiMgr.HotspotLeases() {
+		clients = append(clients, HotspotClient{Lease: lease, Camera: byMAC[strings.ToLower(lease.MAC)]})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(clients)
+}
+
+func sameSubnet24(a, b string) bool {
+	ia := strings.LastIndex(a, ".")
+	ib := strings.LastIndex(b, ".")
+	return ia > 0 && ib > 0 && a[:ia] == b[:ib]
+}
@@ -0,0 +1,492 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"srtla-manager/internal/config"
+	"srtla-manager/internal/events"
+	"srtla-manager/internal/logger"
+	"srtla-manager/internal/process"
+	"srtla-manager/internal/restream"
+	"srtla-manager/internal/stun"
+)
+
+// twitchIngestsMaxAge is how long Twitch's ingest list is kept before it is
+// fetched again
+const twitchIngestsMaxAge = time.Hour
+
+var restreamClient = &http.Client{Timeout: 10 * time.Second}
+
+// restreamBasePort is the loopback UDP port ffmpeg tees the first enabled
+// destination to, in name order; the others follow it
+const restreamBasePort = 6200
+
+// restreamCheckInterval is how often the destinations' outputs are checked
+// and failed ones restarted
+const restreamCheckInterval = 2 * time.Second
+
+// RestreamDestination is a named restream destination
+type RestreamDestination struct {
+	Name string `json:"name"`
+	config.RestreamConfig
+	Output *RestreamOutputStatus `json:"output,omitempty"` // while streaming
+}
+
+// RestreamOutputStatus is the state of a destination's output
+type RestreamOutputStatus struct {
+	State      process.State `json:"state"`
+	Restarts   int           `json:"restarts"`
+	Error      string        `json:"error,omitempty"`      // why it last failed
+	Rendezvous *stun.Mapping `json:"rendezvous,omitempty"` // public address found before it last started
+}
+
+// RestreamOutputEvent reports a destination's output failing or being
+// restarted
+type RestreamOutputEvent struct {
+	Name   string `json:"name"`
+	Event  string `json:"event"` // failed, restarted, restart_failed or rendezvous
+	Error  string `json:"error,omitempty"`
+	Public string `json:"public,omitempty"` // rendezvous: the address the peer should meet
+}
+
+// restreamOutput is a destination's output with its own restart backoff, so
+// one destination failing over and over doesn't hold back the others
+type restreamOutput struct {
+	out      *process.RestreamOutput
+	restarts *RestartTracker
+	count    int
+	lastErr  string
+	failed   bool          // the failure was reported and a restart is pending
+	public   *stun.Mapping // rendezvous destinations only
+}
+
+// RestreamURLRequest asks for a platform's ingest URL, or a MediaMTX or SRS
+// server's
+type RestreamURLRequest struct {
+	Platform  string           `json:"platform"`
+	Region    string           `json:"region"`
+	StreamKey string           `json:"stream_key"`
+	Server    *restream.Server `json:"server"`
+}
+
+// RestreamURLResponse is a built ingest URL and whether its server answered
+type RestreamURLResponse struct {
+	URL       string `json:"url"`
+	Reachable bool   `json:"reachable"`
+	Error     string `json:"error,omitempty"`
+}
+
+// RestreamSaveRequest saves a destination, either from a full URL, from a
+// platform, region and stream key, or from a MediaMTX or SRS server
+type RestreamSaveRequest struct {
+	URL       string           `json:"url"`
+	Platform  string           `json:"platform"`
+	Region    string           `json:"region"`
+	StreamKey string           `json:"stream_key"`
+	Server    *restream.Server `json:"server"`
+	Enabled   *bool            `json:"enabled"` // defaults to true
+	SkipCheck bool             `json:"skip_check"`
+
+	Rendezvous bool   `json:"rendezvous"`
+	BindIP     string `json:"bind_ip"`
+	LocalPort  int    `json:"local_port"`
+
+	TLS config.RestreamTLS `json:"tls"` // rtmps:// only
+}
+
+// ApplyRestreamConfig hands the enabled destinations to ffmpeg, which picks
+// them up the next time it starts streaming
+func (h *Handler) ApplyRestreamConfig() {
+	cfg := h.config.Get()
+	var names []string
+	for name, d := range cfg.Restream {
+		if d.Enabled {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	targets := make([]process.RestreamTarget, len(names))
+	for i, name := range names {
+		d := cfg.Restream[name]
+		targets[i] = process.RestreamTarget{
+			Name:      name,
+			URL:       restreamOutputURL(d),
+			Port:      restreamBasePort + i,
+			TLSVerify: !d.TLS.Insecure,
+			CAFile:    d.TLS.CAFile,
+			CertFile:  d.TLS.CertFile,
+			KeyFile:   d.TLS.KeyFile,
+		}
+	}
+	h.ffmpeg.SetRestreamTargets(targets)
+}
+
+// restreamOutputURL is the URL a destination's output sends to. For a
+// rendezvous destination the local end is added to the peer's URL.
+func restreamOutputURL(d config.RestreamConfig) string {
+	if !d.Rendezvous {
+		return d.URL
+	}
+	u, err := url.Parse(d.URL)
+	if err != nil {
+		return d.URL
+	}
+	q := u.Query()
+	q.Set("mode", "rendezvous")
+	q.Set("localip", d.BindIP)
+	q.Set("localport", strconv.Itoa(d.LocalPort))
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// restreamTLS returns a destination's TLS settings for checking its server
+func restreamTLS(t config.RestreamTLS) restream.TLS {
+	return restream.TLS{Insecure: t.Insecure, CAFile: t.CAFile, CertFile: t.CertFile, KeyFile: t.KeyFile}
+}
+
+// StartRestreamMonitor runs an output per destination ffmpeg tees to while
+// streaming and restarts each one that fails on its own backoff, leaving
+// ffmpeg, the bond and the other destinations alone
+func (h *Handler) StartRestreamMonitor(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(restreamCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			h.checkRestreamOutputs()
+		}
+	}()
+}
+
+// StopRestreamOutputs stops every destination's output on shutdown
+func (h *Handler) StopRestreamOutputs() {
+	h.restreamOutputsMu.Lock()
+	defer h.restreamOutputsMu.Unlock()
+	for name, o := range h.restreamOutputs {
+		_ = o.out.Stop()
+		delete(h.restreamOutputs, name)
+	}
+}
+
+// checkRestreamOutputs starts and stops outputs to match the destinations
+// the running ffmpeg tees to, less those disabled since it started, and
+// supervises the rest
+func (h *Handler) checkRestreamOutputs() {
+	var want []process.RestreamTarget
+	if h.GetPipelineMode() == PipelineModeStreaming && h.ffmpeg.ProcessState() == process.StateRunning {
+		destinations := h.config.Get().Restream
+		for _, target := range h.ffmpeg.RestreamTargets() {
+			if destinations[target.Name].Enabled {
+				want = append(want, target)
+			}
+		}
+	}
+
+	h.restreamOutputsMu.Lock()
+	defer h.restreamOutputsMu.Unlock()
+
+	for name, o := range h.restreamOutputs {
+		if !slices.Contains(want, o.out.Target()) {
+			_ = o.out.Stop()
+			delete(h.restreamOutputs, name)
+		}
+	}
+	if h.restreamOutputs == nil {
+		h.restreamOutputs = make(map[string]*restreamOutput)
+	}
+
+	for _, target := range want {
+		o, ok := h.restreamOutputs[target.Name]
+		if !ok {
+			o = &restreamOutput{
+				out:      process.NewRestreamOutput(target),
+				restarts: &RestartTracker{backoffDuration: InitialBackoff},
+			}
+			o.out.SetBus(h.bus)
+			h.restreamOutputs[target.Name] = o
+			h.discoverRendezvous(o)
+			if err := o.out.Start(); err != nil {
+				o.failed = true
+				o.lastErr = err.Error()
+				h.recordRestartFailure(o.restarts)
+				h.publishRestreamOutput(target.Name, "failed", o.lastErr)
+			}
+			continue
+		}
+		h.superviseRestreamOutput(o)
+	}
+}
+
+// superviseRestreamOutput reports an output that stopped and restarts it
+// once its backoff has elapsed
+func (h *Handler) superviseRestreamOutput(o *restreamOutput) {
+	name := o.out.Target().Name
+	switch {
+	case o.out.ProcessState() == process.StateRunning:
+	case !o.failed:
+		o.failed = true
+		o.lastErr = o.out.LastError()
+		if o.lastErr == "" {
+			o.lastErr = "exited"
+		}
+		h.recordRestartFailure(o.restarts)
+		h.publishRestreamOutput(name, "failed", o.lastErr)
+	case h.restreamBackoffElapsed(o.restarts):
+		h.discoverRendezvous(o)
+		if err := o.out.Start(); err != nil {
+			o.lastErr = err.Error()
+			h.recordRestartFailure(o.restarts)
+			h.publishRestreamOutput(name, "restart_failed", o.lastErr)
+			return
+		}
+		o.failed = false
+		o.count++
+		h.publishRestreamOutput(name, "restarted", "")
+	}
+}
+
+// discoverRendezvous asks the STUN servers for the public address of a
+// rendezvous destination's local port just before its output starts, which
+// also opens the NAT mapping the peer comes in through. The peer may be on
+// the same network, so not getting an answer doesn't stop the output.
+func (h *Handler) discoverRendezvous(o *restreamOutput) {
+	name := o.out.Target().Name
+	cfg := h.config.Get()
+	d, ok := cfg.Restream[name]
+	if !ok || !d.Rendezvous {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), stun.Timeout)
+	defer cancel()
+	m := stun.Discover(ctx, cfg.STUN.Servers, d.BindIP, d.LocalPort)
+	o.public = &m
+
+	switch {
+	case m.Error != "":
+		h.logOutput("manager", fmt.Sprintf("[RESTREAM] %s: no public address for %s:%d: %s", name, d.BindIP, d.LocalPort, m.Error))
+	case m.Symmetric:
+		h.logOutput("manager", fmt.Sprintf("[RESTREAM] %s: the NAT on %s maps each peer to a different port, rendezvous is unlikely to connect", name, d.BindIP))
+		fallthrough
+	default:
+		h.logOutput("manager", fmt.Sprintf("[RESTREAM] %s rendezvous at %s", name, m.Public))
+		events.Publish(h.bus, TopicRestream, RestreamOutputEvent{Name: name, Event: "rendezvous", Public: m.Public})
+	}
+}
+
+// restreamBackoffElapsed reports whether an output may be restarted yet.
+// Unlike shouldRestartWithBackoff it doesn't count towards the session's
+// restarts, which are the pipeline's own.
+func (h *Handler) restreamBackoffElapsed(tracker *RestartTracker) bool {
+	h.restartTrackerMu.Lock()
+	defer h.restartTrackerMu.Unlock()
+
+	since := time.Since(tracker.latestFailTime)
+	if since > ResetFailureCountAfter {
+		tracker.failureCount = 0
+		tracker.backoffDuration = InitialBackoff
+	}
+	return since >= tracker.backoffDuration
+}
+
+func (h *Handler) publishRestreamOutput(name, event, errMsg string) {
+	msg := fmt.Sprintf("[RESTREAM] %s %s", name, event)
+	if errMsg != "" {
+		msg += ": " + errMsg
+	}
+	h.logOutput("manager", msg)
+	events.Publish(h.bus, TopicRestream, RestreamOutputEvent{Name: name, Event: event, Error: errMsg})
+}
+
+// restreamOutputStatus returns the state of a destination's output, nil
+// when it has none
+func (h *Handler) restreamOutputStatus(name string) *RestreamOutputStatus {
+	h.restreamOutputsMu.Lock()
+	defer h.restreamOutputsMu.Unlock()
+	o, ok := h.restreamOutputs[name]
+	if !ok {
+		return nil
+	}
+	return &RestreamOutputStatus{State: o.out.ProcessState(), Restarts: o.count, Error: o.lastErr, Rendezvous: o.public}
+}
+
+// restreamPlatforms returns the known platforms, with Twitch's regions
+// fetched at most once an hour. When Twitch can't be reached the last list,
+// or only its automatic server, is offered.
+func (h *Handler) restreamPlatforms(ctx context.Context) []restream.Platform {
+	h.restreamMu.Lock()
+	defer h.restreamMu.Unlock()
+
+	if time.Since(h.twitchFetched) > twitchIngestsMaxAge {
+		regions, err := restream.TwitchIngests(ctx, restreamClient, restream.TwitchIngestsURL)
+		if err != nil {
+			logger.Warn("Restream: failed to fetch Twitch ingest servers: %v", err)
+		} else {
+			h.twitchRegions = regions
+			h.twitchFetched = time.Now()
+		}
+	}
+	return restream.Platforms(h.twitchRegions)
+}
+
+// HandleRestreamPlatforms handles GET /api/restream/platforms
+func (h *Handler) HandleRestreamPlatforms(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.restreamPlatforms(r.Context()))
+}
+
+// HandleRestreamURL handles POST /api/restream/url, building a platform's
+// ingest URL and checking its server answers
+func (h *Handler) HandleRestreamURL(w http.ResponseWriter, r *http.Request) {
+	var req RestreamURLRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	var url string
+	var err error
+	if req.Server != nil {
+		url, err = req.Server.URL()
+	} else {
+		url, err = restream.Build(h.restreamPlatforms(r.Context()), req.Platform, req.Region, req.StreamKey)
+	}
+	if err != nil {
+		jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp := RestreamURLResponse{URL: url, Reachable: true}
+	// SRT runs over UDP, where nothing answers until the server does
+	if !strings.HasPrefix(url, "srt://") {
+		if err := restream.CheckReachable(r.Context(), url); err != nil {
+			resp.Reachable = false
+			resp.Error = err.Error()
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// HandleRestreamList handles GET /api/restream
+func (h *Handler) HandleRestreamList(w http.ResponseWriter, r *http.Request) {
+	cfg := h.config.Get()
+	list := make([]RestreamDestination, 0, len(cfg.Restream))
+	for name, d := range cfg.Restream {
+		list = append(list, RestreamDestination{Name: name, RestreamConfig: d, Output: h.restreamOutputStatus(name)})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// HandleRestreamSave handles PUT /api/restream/{name}, creating or replacing
+// a destination. The ingest server must answer unless skip_check is set. It
+// takes effect the next time a stream is started.
+func (h *Handler) HandleRestreamSave(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	var req RestreamSaveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	dest := config.RestreamConfig{
+		URL:        req.URL,
+		Enabled:    req.Enabled == nil || *req.Enabled,
+		Rendezvous: req.Rendezvous,
+		BindIP:     req.BindIP,
+		LocalPort:  req.LocalPort,
+		TLS:        req.TLS,
+	}
+	if req.Server != nil {
+		url, err := req.Server.URL()
+		if err != nil {
+			jsonError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		dest.URL, dest.Platform = url, req.Server.Kind
+	} else if req.Platform != "" {
+		url, err := restream.Build(h.restreamPlatforms(r.Context()), req.Platform, req.Region, req.StreamKey)
+		if err != nil {
+			jsonError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		dest.URL, dest.Platform = url, req.Platform
+	}
+
+	cfg := h.config.Get()
+	destinations := make(map[string]config.RestreamConfig, len(cfg.Restream)+1)
+	for n, d := range cfg.Restream {
+		destinations[n] = d
+	}
+	destinations[name] = dest
+	cfg.Restream = destinations
+
+	// config validation covers the name and the URL
+	if err := cfg.Validate(); err != nil {
+		validationError(w, err)
+		return
+	}
+	// SRT runs over UDP, where nothing answers until the peer does
+	if !req.SkipCheck && !strings.HasPrefix(dest.URL, "srt://") {
+		if err := restream.CheckReachableTLS(r.Context(), dest.URL, restreamTLS(dest.TLS)); err != nil {
+			jsonError(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+	}
+	if err := h.config.Update(cfg); err != nil {
+		validationError(w, err)
+		return
+	}
+	h.ApplyRestreamConfig()
+
+	logger.Info("Saved restream destination %s", name)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(RestreamDestination{Name: name, RestreamConfig: dest})
+}
+
+// HandleRestreamDelete handles DELETE /api/restream/{name}
+func (h *Handler) HandleRestreamDelete(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	cfg := h.config.Get()
+	if _, ok := cfg.Restream[name]; !ok {
+		jsonError(w, "Restream destination not found", http.StatusNotFound)
+		return
+	}
+	destinations := make(map[string]config.RestreamConfig, len(cfg.Restream))
+	for n, d := range cfg.Restream {
+		if n != name {
+			destinations[n] = d
+		}
+	}
+	cfg.Restream = destinations
+
+	if err := h.config.Update(cfg); err != nil {
+		jsonError(w, fmt.Sprintf("Failed to save configuration: %v", err), http.StatusInternalServerError)
+		return
+	}
+	h.ApplyRestreamConfig()
+
+	logger.Info("Deleted restream destination %s", name)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,171 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"srtla-manager/internal/logger"
+	"srtla-manager/internal/process"
+)
+
+// returnFeedDir holds the program return HLS playlist served at /return/
+const returnFeedDir = "/tmp/srtla-return"
+
+// returnFeedRetryInterval is how often a dropped return feed is reconnected
+const returnFeedRetryInterval = 5 * time.Second
+
+// ReturnFeedResponse describes the program return preview
+type ReturnFeedResponse struct {
+	Enabled     bool                     `json:"enabled"`
+	URL         string                   `json:"url"`
+	Width       int                      `json:"width"`
+	BitrateKbps int                      `json:"bitrate_kbps"`
+	Status      process.ReturnFeedStatus `json:"status"`
+	PreviewURL  string                   `json:"preview_url"`
+}
+
+// ReturnFeedRequest updates the return feed; omitted fields are unchanged
+type ReturnFeedRequest struct {
+	Enabled     *bool   `json:"enabled"`
+	URL         *string `json:"url"`
+	Width       *int    `json:"width"`
+	BitrateKbps int     `json:"bitrate_kbps,omitempty"`
+}
+
+// ReturnFeedDir returns the directory served at /return/
+func (h *Handler) ReturnFeedDir() string {
+	return returnFeedDir
+}
+
+// returnFeedConfig builds the player settings from the configuration; the
+// low-power profile caps the preview frame rate
+func (h *Handler) returnFeedConfig() process.ReturnFeedConfig {
+	cfg := h.config.Get().ReturnFeed
+	return process.ReturnFeedConfig{
+		URL:         cfg.URL,
+		Width:       cfg.Width,
+		BitrateKbps: cfg.BitrateKbps,
+		MaxFPS:      h.PowerProfile().PreviewFPS,
+		HLSDir:      returnFeedDir,
+	}
+}
+
+// ApplyReturnFeedConfig starts, restarts or stops the return feed to match
+// the configuration. A feed that dropped is started again.
+func (h *Handler) ApplyReturnFeedConfig() {
+	enabled := h.config.Get().ReturnFeed.Enabled
+	want := h.returnFeedConfig()
+
+	h.returnMu.Lock()
+	defer h.returnMu.Unlock()
+
+	running := h.returnFeed.Status().State == process.StateRunning
+	if enabled && running && h.returnApplied != nil && *h.returnApplied == want {
+		return
+	}
+	if running {
+		h.returnFeed.Stop()
+	}
+	wasApplied := h.returnApplied != nil && *h.returnApplied == want
+	h.returnApplied = nil
+	if !enabled {
+		return
+	}
+
+	if err := h.returnFeed.Start(want); err != nil {
+		logger.Error("Return feed: %v", err)
+		return
+	}
+	h.returnApplied = &want
+	if wasApplied {
+		h.logOutput("manager", "[RETURN] Reconnecting to program return")
+	} else {
+		h.logOutput("manager", fmt.Sprintf("[RETURN] Pulling program return from %s", want.URL))
+	}
+}
+
+// StartReturnFeedMonitor reconnects the return feed whenever its ffmpeg
+// exits, e.g. because the production restarted its output
+func (h *Handler) StartReturnFeedMonitor(ctx context.Context) {
+	h.ApplyReturnFeedConfig()
+
+	go func() {
+		ticker := time.NewTicker(returnFeedRetryInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if h.config.Get().ReturnFeed.Enabled && h.returnFeed.Status().State != process.StateRunning {
+				h.ApplyReturnFeedConfig()
+			}
+		}
+	}()
+}
+
+// StopReturnFeed stops the return feed on shutdown
+func (h *Handler) StopReturnFeed() {
+	h.returnMu.Lock()
+	defer h.returnMu.Unlock()
+	h.returnFeed.Stop()
+	h.returnApplied = nil
+}
+
+func (h *Handler) returnFeedResponse() ReturnFeedResponse {
+	cfg := h.config.Get().ReturnFeed
+	return ReturnFeedResponse{
+		Enabled:     cfg.Enabled,
+		URL:         cfg.URL,
+		Width:       cfg.Width,
+		BitrateKbps: cfg.BitrateKbps,
+		Status:      h.returnFeed.Status(),
+		PreviewURL:  h.previewURL("/return/playlist.m3u8"),
+	}
+}
+
+// HandleReturnFeedStatus handles GET /api/return
+func (h *Handler) HandleReturnFeedStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.returnFeedResponse())
+}
+
+// HandleReturnFeedUpdate handles PUT /api/return
+func (h *Handler) HandleReturnFeedUpdate(w http.ResponseWriter, r *http.Request) {
+	var req ReturnFeedRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	cfg := h.config.Get()
+	if req.Enabled != nil {
+		cfg.ReturnFeed.Enabled = *req.Enabled
+	}
+	if req.URL != nil {
+		cfg.ReturnFeed.URL = *req.URL
+	}
+	if req.Width != nil {
+		cfg.ReturnFeed.Width = *req.Width
+	}
+	if req.BitrateKbps != 0 {
+		cfg.ReturnFeed.BitrateKbps = req.BitrateKbps
+	}
+	if cfg.ReturnFeed.Width != 0 && cfg.ReturnFeed.BitrateKbps == 0 {
+		cfg.ReturnFeed.BitrateKbps = 800
+	}
+
+	if err := h.config.Update(cfg); err != nil {
+		validationError(w, err)
+		return
+	}
+
+	h.ApplyReturnFeedConfig()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.returnFeedResponse())
+}
@@ -0,0 +1,195 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// APIv1 is the prefix of the versioned API. Each route under /api/ is also
+// served under it, taking only the methods it handles.
+const APIv1 = "/api/v1/"
+
+// legacyRoutes are the methods of the routes registered without one, whose
+// handlers check the method themselves. A subtree route lists the routes
+// under it its handler dispatches to.
+var legacyRoutes = map[string][]string{
+	"/api/status":              {"GET /api/status"},
+	"/api/stream/start":        {"POST /api/stream/start"},
+	"/api/stream/stop":         {"POST /api/stream/stop"},
+	"/api/config":              {"GET /api/config", "PUT /api/config"},
+	"/api/srtla/ips":           {"GET /api/srtla/ips", "PUT /api/srtla/ips"},
+	"/api/srtla/ips/file":      {"GET /api/srtla/ips/file", "PUT /api/srtla/ips/file"},
+	"/api/srtla/ips/file/load": {"POST /api/srtla/ips/file/load"},
+	"/api/srtla/ips/file/save": {"POST /api/srtla/ips/file/save"},
+	"/api/system/dependencies": {"GET /api/system/dependencies"},
+	"/api/system/install-deb":  {"POST /api/system/install-deb"},
+	"/api/system/interfaces":   {"GET /api/system/interfaces"},
+	"/api/modems":              {"GET /api/modems"},
+	"/api/modems/": {
+		"GET /api/modems/{id}",
+		"GET /api/modems/{id}/history",
+		"POST /api/modems/{id}/ussd",
+		"POST /api/modems/{id}/connect",
+		"POST /api/modems/{id}/disconnect",
+		"POST /api/modems/{id}/reset",
+	},
+	"/api/usbnet":                 {"GET /api/usbnet"},
+	"/api/wifi/networks":          {"GET /api/wifi/networks"},
+	"/api/wifi/status":            {"GET /api/wifi/status"},
+	"/api/wifi/connect":           {"POST /api/wifi/connect"},
+	"/api/wifi/disconnect":        {"POST /api/wifi/disconnect"},
+	"/api/wifi/hotspot":           {"POST /api/wifi/hotspot"},
+	"/api/wifi/hotspot/stop":      {"POST /api/wifi/hotspot/stop"},
+	"/api/wifi/hotspot/channel":   {"GET /api/wifi/hotspot/channel", "POST /api/wifi/hotspot/channel"},
+	"/api/wifi/hotspot/clients":   {"GET /api/wifi/hotspot/clients"},
+	"/api/wifi/forget":            {"POST /api/wifi/forget"},
+	"/api/wifi/ca-cert":           {"POST /api/wifi/ca-cert"},
+	"/api/logs":                   {"GET /api/logs"},
+	"/api/logs/download":          {"GET /api/logs/download"},
+	"/api/debug":                  {"GET /api/debug", "POST /api/debug"},
+	"/api/updates/check":          {"GET /api/updates/check"},
+	"/api/updates/releases":       {"GET /api/updates/releases"},
+	"/api/updates/perform":        {"POST /api/updates/perform"},
+	"/api/updates/backups":        {"GET /api/updates/backups"},
+	"/api/updates/rollback":       {"POST /api/updates/rollback"},
+	"/api/updates/srtla/check":    {"GET /api/updates/srtla/check"},
+	"/api/updates/srtla/releases": {"GET /api/updates/srtla/releases"},
+	"/api/updates/srtla/install":  {"POST /api/updates/srtla/install"},
+	"/api/cameras":                {"GET /api/cameras"},
+	"/api/cameras/scan":           {"POST /api/cameras/scan"},
+	"/api/cameras/scan/stop":      {"POST /api/cameras/scan/stop"},
+	"/api/cameras/debug/add":      {"POST /api/cameras/debug/add"},
+	"/api/cameras/": {
+		"POST /api/cameras/{id}/connect",
+		"POST /api/cameras/{id}/disconnect",
+		"POST /api/cameras/{id}/preview",
+		"POST /api/cameras/{id}/configure",
+		"POST /api/cameras/{id}/stop",
+		"POST /api/cameras/{id}/forget",
+		"DELETE /api/cameras/{id}/forget",
+		"POST /api/cameras/{id}/refresh",
+		"PUT /api/cameras/{id}/reservation",
+		"DELETE /api/cameras/{id}/reservation",
+	},
+}
+
+// Route is an API route: a method on a path
+type Route struct {
+	Method  string
+	Path    string // as the client requests it, under /api/v1/ when versioned
+	Handler string // name of the handler's function
+}
+
+// Router is the ServeMux of the server, keeping a record of the API routes
+// registered on it so they can be served under /api/v1/ and documented
+type Router struct {
+	mux    *http.ServeMux
+	routes []Route
+}
+
+// NewRouter returns an empty router
+func NewRouter() *Router {
+	return &Router{mux: http.NewServeMux()}
+}
+
+// Handle registers handler for pattern, as http.ServeMux does. Routes under
+// /api/ are recorded; one without a method must be in legacyRoutes.
+func (rt *Router) Handle(pattern string, handler http.Handler) {
+	rt.mux.Handle(pattern, handler)
+
+	method, path, ok := strings.Cut(pattern, " ")
+	if !ok {
+		method, path = "", pattern
+	}
+	if !strings.HasPrefix(path, "/api/") {
+		return
+	}
+	name := handlerName(handler)
+
+	if strings.HasPrefix(path, "/api/v2/") {
+		// already versioned
+		rt.routes = append(rt.routes, Route{Method: method, Path: path, Handler: name})
+		return
+	}
+	if method != "" {
+		rt.routes = append(rt.routes, Route{Method: method, Path: versionedPath(path), Handler: name})
+		return
+	}
+	routes, ok := legacyRoutes[path]
+	if !ok {
+		panic(fmt.Sprintf("api: route %q has no method and none listed in legacyRoutes", pattern))
+	}
+	for _, r := range routes {
+		method, path, _ := strings.Cut(r, " ")
+		rt.routes = append(rt.routes, Route{Method: method, Path: versionedPath(path), Handler: name})
+	}
+}
+
+// HandleFunc registers handler for pattern, as http.ServeMux does
+func (rt *Router) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	rt.Handle(pattern, http.HandlerFunc(handler))
+}
+
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rt.mux.ServeHTTP(w, r)
+}
+
+// Routes returns the API routes registered, in order
+func (rt *Router) Routes() []Route {
+	return append([]Route(nil), rt.routes...)
+}
+
+// Versioned serves the routes under /api/v1/ by their method and path, and
+// everything else with next. A versioned request is passed on to next with
+// its unversioned path, so the access checks and API key scopes that go by
+// the path apply to both; a method a route doesn't take is refused with 405.
+// Routes must all be registered first.
+func (rt *Router) Versioned(next http.Handler) http.Handler {
+	v1 := http.NewServeMux()
+	unversion := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r2 := r.Clone(r.Context())
+		r2.URL.Path = "/api/" + strings.TrimPrefix(r.URL.Path, APIv1)
+		r2.URL.RawPath = ""
+		next.ServeHTTP(w, r2)
+	})
+	for _, route := range rt.routes {
+		if strings.HasPrefix(route.Path, APIv1) {
+			v1.Handle(route.Method+" "+route.Path, unversion)
+		}
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, APIv1) {
+			v1.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// versionedPath returns the path of an /api/ route under /api/v1/
+func versionedPath(path string) string {
+	return APIv1 + strings.TrimPrefix(path, "/api/")
+}
+
+// handlerName returns the name of a handler's function, such as
+// HandleStatus, or "" for a closure
+func handlerName(handler http.Handler) string {
+	fn, ok := handler.(http.HandlerFunc)
+	if !ok {
+		return ""
+	}
+	f := runtime.FuncForPC(reflect.ValueOf(fn).Pointer())
+	if f == nil {
+		return ""
+	}
+	name := strings.TrimSuffix(f.Name(), "-fm")
+	name = name[strings.LastIndexByte(name, '.')+1:]
+	if strings.HasPrefix(name, "func") {
+		return ""
+	}
+	return name
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+
+	"srtla-manager/internal/logger"
+	"srtla-manager/internal/routing"
+)
+
+// RoutingResponse is the policy routing set up for the bind IPs
+type RoutingResponse struct {
+	Enabled bool            `json:"enabled"`
+	Entries []routing.Entry `json:"entries"`
+	Missing []string        `json:"missing,omitempty"` // bind IPs not on any interface
+	Error   string          `json:"error,omitempty"`   // of the last apply
+}
+
+// ApplyRouting gives each bind IP that is on an interface its own table and
+// source rule, and removes those of bind IPs that went away. It is called
+// when the config changes, before SRTLA starts and each time the modems are
+// polled, so a modem that comes back with a new address or gateway is
+// routed again.
+func (h *Handler) ApplyRouting() {
+	if runtime.GOOS != "linux" {
+		return
+	}
+	cfg := h.config.Get()
+
+	var entries []routing.Entry
+	var missing []string
+	if !cfg.Routing.Disabled && cfg.SRTLA.Enabled {
+		var ips []string
+		for _, ip := range h.bindIPs(&cfg) {
+			if ip = strings.TrimSpace(ip); ip != "" {
+				ips = append(ips, ip)
+			}
+		}
+		var links []routing.Link
+		links, missing = routing.Discover(ips)
+		entries = routing.Plan(links, routing.Config{
+			TableBase:    cfg.Routing.TableBase,
+			RulePriority: cfg.Routing.RulePriority,
+		})
+	}
+
+	changed, err := h.routing.Apply(entries)
+
+	h.routingMu.Lock()
+	h.routingMissing = missing
+	lastErr := h.routingErr
+	h.routingErr = ""
+	if err != nil {
+		h.routingErr = err.Error()
+	}
+	h.routingMu.Unlock()
+
+	if err != nil && err.Error() != lastErr {
+		logger.Warn("Policy routing: %v", err)
+	}
+	if changed {
+		h.logOutput("manager", fmt.Sprintf("[ROUTING] %d bind IP(s) routed through their own table", len(h.routing.Applied())))
+	}
+}
+
+// StopRouting removes the tables and rules set up
+func (h *Handler) StopRouting() {
+	if err := h.routing.Clear(); err != nil {
+		logger.Warn("Policy routing: %v", err)
+	}
+}
+
+// HandleRouting handles GET /api/routing
+func (h *Handler) HandleRouting(w http.ResponseWriter, r *http.Request) {
+	cfg := h.config.Get()
+	h.routingMu.Lock()
+	resp := RoutingResponse{
+		Enabled: !cfg.Routing.Disabled,
+		Entries: h.routing.Applied(),
+		Missing: h.routingMissing,
+		Error:   h.routingErr,
+	}
+	h.routingMu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
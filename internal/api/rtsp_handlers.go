@@ -0,0 +1,288 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"srtla-manager/internal/config"
+	"srtla-manager/internal/process"
+)
+
+// errNoRTSPURL is returned when the RTSP source is started before it is set up
+var errNoRTSPURL = errors.New("no RTSP camera configured; set rtsp.url first")
+
+// RTSPResponse reports the RTSP camera source. The password is never
+// returned, only whether one is set.
+type RTSPResponse struct {
+	URL         string        `json:"url"`
+	Username    string        `json:"username"`
+	HasPassword bool          `json:"has_password"`
+	Transport   string        `json:"transport"`
+	Encoder     string        `json:"encoder"`
+	Bitrate     int           `json:"bitrate"`
+	Active      bool          `json:"active"`
+	State       process.State `json:"state"`
+}
+
+// RTSPRequest updates the RTSP camera source; omitted fields are kept
+type RTSPRequest struct {
+	URL       *string `json:"url"`
+	Username  *string `json:"username"`
+	Password  *string `json:"password"`
+	Transport *string `json:"transport"`
+	Encoder   *string `json:"encoder"`
+	Bitrate   *int    `json:"bitrate"`
+}
+
+func (h *Handler) rtspResponse() RTSPResponse {
+	cfg := h.config.Get().RTSP
+	active := h.rtspSourceActive()
+
+	resp := RTSPResponse{
+		URL:         cfg.URL,
+		Username:    cfg.Username,
+		HasPassword: cfg.Password != "",
+		Transport:   cfg.Transport,
+		Encoder:     cfg.Encoder,
+		Bitrate:     cfg.Bitrate,
+		Active:      active,
+		State:       process.StateStopped,
+	}
+	if active {
+		resp.State = h.ffmpeg.ProcessState()
+	}
+	return resp
+}
+
+// HandleRTSPStatus handles GET /api/rtsp
+func (h *Handler) HandleRTSPStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.rtspResponse())
+}
+
+// HandleRTSPUpdate handles PUT /api/rtsp. A running camera picks the new
+// settings up the next time it is started.
+func (h *Handler) HandleRTSPUpdate(w http.ResponseWriter, r *http.Request) {
+	var req RTSPRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	cfg := h.config.Get()
+	if req.URL != nil {
+		cfg.RTSP.URL = *req.URL
+	}
+	if req.Username != nil {
+		cfg.RTSP.Username = *req.Username
+	}
+	if req.Password != nil {
+		cfg.RTSP.Password = *req.Password
+	}
+	if req.Transport != nil {
+		cfg.RTSP.Transport = *req.Transport
+	}
+	if req.Encoder != nil {
+		cfg.RTSP.Encoder = *req.Encoder
+	}
+	if req.Bitrate != nil {
+		cfg.RTSP.Bitrate = *req.Bitrate
+	}
+
+	if err := h.config.Update(cfg); err != nil {
+		validationError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.rtspResponse())
+}
+
+// HandleRTSPStart handles POST /api/rtsp/start
+func (h *Handler) HandleRTSPStart(w http.ResponseWriter, r *http.Request) {
+	release, err := h.acquirePipeline("rtsp_start")
+	if err != nil {
+		pipelineError(w, err, http.StatusConflict)
+		return
+	}
+	defer release()
+
+	if err := h.startRTSPCamera(); err != nil {
+		switch {
+		case errors.Is(err, errNoRTSPURL):
+			jsonError(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, errStreamActive):
+			jsonError(w, err.Error(), http.StatusConflict)
+		default:
+			jsonError(w, "failed to start RTSP camera: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.rtspResponse())
+}
+
+// HandleRTSPStop handles POST /api/rtsp/stop
+func (h *Handler) HandleRTSPStop(w http.ResponseWriter, r *http.Request) {
+	release, err := h.acquirePipeline("rtsp_stop")
+	if err != nil {
+		pipelineError(w, err, http.StatusConflict)
+		return
+	}
+	defer release()
+
+	if !h.rtspSourceActive() {
+		jsonError(w, "RTSP camera is not running", http.StatusConflict)
+		return
+	}
+	h.stopRTSPCamera()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.rtspResponse())
+}
+
+// startRTSPCamera hands the pipeline to the RTSP camera, bonding it when
+// SRTLA is enabled and previewing it otherwise. The caller must hold the
+// pipeline lock.
+func (h *Handler) startRTSPCamera() error {
+	cfg := h.config.Get()
+	if cfg.RTSP.URL == "" {
+		return errNoRTSPURL
+	}
+	if h.GetPipelineMode() == PipelineModeStreaming {
+		return errStreamActive
+	}
+
+	// Stop FFmpeg (receive-only mode) so the RTSP pull can take over
+	h.SetPipelineMode(PipelineModeIdle)
+	_ = h.ffmpeg.Stop()
+	_ = h.srtla.Stop()
+
+	srtPort := h.startCaptureBonding(&cfg, "rtsp", "[RTSP]")
+	if err := h.ffmpeg.StartRTSPCapture(rtspCaptureConfig(cfg.RTSP, srtPort, h.rtspPreviewDir(srtPort))); err != nil {
+		if srtPort > 0 {
+			_ = h.srtla.Stop()
+			_ = h.srtProbe.Stop()
+		}
+		_ = h.StartReceiveMode() // restore receive mode
+		return err
+	}
+
+	h.rtspMu.Lock()
+	h.rtspActive = true
+	h.rtspSession++
+	session := h.rtspSession
+	h.rtspMu.Unlock()
+
+	if srtPort > 0 {
+		h.SetPipelineMode(PipelineModeStreaming)
+		h.startBitrateControl()
+	} else {
+		h.SetPipelineMode(PipelineModeReceiving)
+	}
+	go h.monitorRTSP(session, srtPort)
+
+	h.logOutput("rtsp", "[RTSP] Pulling "+cfg.RTSP.URL)
+	return nil
+}
+
+// stopRTSPCamera stops the RTSP pull and restores receive mode. The caller
+// must hold the pipeline lock.
+func (h *Handler) stopRTSPCamera() {
+	h.endRTSPSession()
+	_ = h.ffmpeg.Stop()
+	_ = h.srtla.Stop()
+	_ = h.srtProbe.Stop()
+
+	h.logOutput("rtsp", "[RTSP] Stopped")
+
+	if err := h.StartReceiveMode(); err != nil {
+		h.logOutput("rtsp", fmt.Sprintf("[RTSP] Warning: Failed to restore receive mode: %v", err))
+		h.SetPipelineMode(PipelineModeIdle)
+	}
+}
+
+// endRTSPSession marks the RTSP camera as no longer feeding the pipeline,
+// so its monitor stops pulling it
+func (h *Handler) endRTSPSession() {
+	h.rtspMu.Lock()
+	h.rtspActive = false
+	h.rtspSession++
+	h.rtspMu.Unlock()
+}
+
+// rtspSourceActive reports whether the RTSP camera feeds the pipeline
+func (h *Handler) rtspSourceActive() bool {
+	h.rtspMu.Lock()
+	defer h.rtspMu.Unlock()
+	return h.rtspActive
+}
+
+func rtspCaptureConfig(cfg config.RTSPConfig, srtPort int, hlsDir string) process.RTSPCaptureConfig {
+	encoder := cfg.Encoder
+	if encoder == "" {
+		encoder = "copy"
+	}
+	bitrate := cfg.Bitrate
+	if bitrate == 0 {
+		bitrate = 6000
+	}
+	return process.RTSPCaptureConfig{
+		URL:       cfg.URL,
+		Username:  cfg.Username,
+		Password:  cfg.Password,
+		Transport: cfg.Transport,
+		Encoder:   encoder,
+		Bitrate:   bitrate,
+		SRTPort:   srtPort,
+		HLSDir:    hlsDir,
+		Resync:    cfg.Resync,
+	}
+}
+
+func (h *Handler) rtspPreviewDir(srtPort int) string {
+	if srtPort > 0 {
+		return h.streamPreviewDir()
+	}
+	return h.previewDir
+}
+
+// monitorRTSP pulls the camera again when ffmpeg exits, which it does when
+// the camera reboots or drops off the network, backing off like the RTMP
+// pipeline
+func (h *Handler) monitorRTSP(session, srtPort int) {
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		h.rtspMu.Lock()
+		current := h.rtspActive && h.rtspSession == session
+		h.rtspMu.Unlock()
+		if !current {
+			return
+		}
+		if h.CurrentPipelineOperation() != nil || h.ffmpeg.ProcessState() == process.StateRunning {
+			continue
+		}
+		if !h.shouldRestartWithBackoff(h.ffmpegRestarts, "stopped unexpectedly", "RTSP") {
+			continue
+		}
+
+		cfg := h.config.Get()
+		port := srtPort
+		if port > 0 {
+			port = h.srtOutputPort(&cfg)
+		}
+		h.logOutput("rtsp", "[AUTO-RESTART] RTSP camera lost, pulling it again...")
+		if err := h.ffmpeg.StartRTSPCapture(rtspCaptureConfig(cfg.RTSP, port, h.rtspPreviewDir(port))); err != nil {
+			h.recordRestartFailure(h.ffmpegRestarts)
+			h.logOutput("rtsp", fmt.Sprintf("[AUTO-RESTART] Failed to pull RTSP camera: %v", err))
+		} else {
+			h.recordRestartSuccess(h.ffmpegRestarts)
+		}
+	}
+}
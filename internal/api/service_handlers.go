@@ -0,0 +1,118 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+
+	"srtla-manager/internal"
+	"srtla-manager/internal/events"
+	"srtla-manager/internal/jobs"
+	"srtla-manager/internal/system"
+)
+
+// systemServices are the daemons that may be restarted from the API, by the
+// name used in the URL. They regularly wedge on USB modem churn.
+var systemServices = map[string]string{
+	"modemmanager":   "ModemManager",
+	"networkmanager": "NetworkManager",
+	"bluetooth":      "bluetooth",
+}
+
+// ServiceStatus is the state of a restartable system service
+type ServiceStatus struct {
+	Name  string `json:"name"`
+	Unit  string `json:"unit"`
+	State string `json:"state"` // as reported by systemctl is-active
+}
+
+// ServiceRestartRequest is the optional body of a restart request
+type ServiceRestartRequest struct {
+	Force bool `json:"force"` // restart even while streaming
+}
+
+// ServiceRestart reports the outcome of a service restart
+type ServiceRestart struct {
+	Name    string `json:"name"`
+	Unit    string `json:"unit"`
+	Success bool   `json:"success"`
+	State   string `json:"state"`
+	Message string `json:"message"`
+}
+
+// HandleServiceList handles GET /api/system/services
+func (h *Handler) HandleServiceList(w http.ResponseWriter, r *http.Request) {
+	services := make([]ServiceStatus, 0, len(systemServices))
+	for name, unit := range systemServices {
+		services = append(services, ServiceStatus{Name: name, Unit: unit, State: system.ServiceState(unit)})
+	}
+	sort.Slice(services, func(i, j int) bool { return services[i].Name < services[j].Name })
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(services)
+}
+
+// HandleServiceRestart handles POST /api/system/services/{name}/restart. The
+// restart runs as a job through the privileged installer. Restarting drops
+// links, so it is refused while streaming unless forced, and only one
+// restart runs at a time.
+func (h *Handler) HandleServiceRestart(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	unit, ok := systemServices[name]
+	if !ok {
+		jsonError(w, fmt.Sprintf("Service %s can't be restarted", name), http.StatusNotFound)
+		return
+	}
+
+	var req ServiceRestartRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		jsonError(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if h.GetPipelineMode() == PipelineModeStreaming && !req.Force {
+		jsonError(w, fmt.Sprintf("Restarting %s while streaming may drop links; set force to restart anyway", unit), http.StatusConflict)
+		return
+	}
+	if !h.serviceRestartMu.TryLock() {
+		jsonError(w, "Another service restart is in progress", http.StatusConflict)
+		return
+	}
+
+	h.logOutput("manager", fmt.Sprintf("[SERVICE] Restarting %s", unit))
+
+	job := h.jobs.Start("service_restart", func(ctx context.Context, report *jobs.Reporter) (interface{}, error) {
+		defer h.serviceRestartMu.Unlock()
+		report.Progress(10, "Restarting %s", unit)
+
+		result := ServiceRestart{Name: name, Unit: unit}
+		resp, err := internal.RestartServiceWithInstaller(unit)
+		if err != nil {
+			result.State = system.ServiceState(unit)
+			result.Message = err.Error()
+		} else {
+			result.Success = resp.Success
+			result.State = resp.State
+			result.Message = resp.Message
+		}
+		events.Publish(h.bus, TopicService, result)
+
+		if !result.Success {
+			h.logOutput("manager", fmt.Sprintf("[SERVICE] Restart of %s failed: %s", unit, result.Message))
+			return result, errors.New(result.Message)
+		}
+		h.logOutput("manager", fmt.Sprintf("[SERVICE] %s restarted, now %s", unit, result.State))
+		return result, nil
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"status":  "started",
+		"service": name,
+		"job_id":  job.ID,
+	})
+}
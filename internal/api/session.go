@@ -0,0 +1,80 @@
+package api
+
+import (
+	"strings"
+	"time"
+
+	"srtla-manager/internal/bondhealth"
+	"srtla-manager/internal/stats"
+	"srtla-manager/internal/system"
+)
+
+// SessionStats returns the totals of the current or last time on air for
+// the stats broadcast, first adding what the bonded links have sent since
+// the last call and the stream's drift
+func (h *Handler) SessionStats() stats.SessionStats {
+	if h.GetPipelineMode() == PipelineModeStreaming {
+		h.transportMu.Lock()
+		inUse := make(map[string]bool, len(h.srtlaLinks))
+		for _, ip := range h.srtlaLinks {
+			inUse[ip] = true
+		}
+		h.transportMu.Unlock()
+
+		counters := make(map[string]uint64)
+		for _, iface := range system.ListNetworkInterfaces() {
+			for _, ip := range iface.IPs {
+				if !inUse[ip] {
+					continue
+				}
+				if n, err := system.InterfaceTxBytes(iface.Name); err == nil {
+					counters[iface.Name] = n
+				}
+				break
+			}
+		}
+		h.session.AddCounters(counters)
+
+		if drift := h.ffmpeg.Stats().Drift; drift.Valid {
+			h.session.AddDrift(drift.DriftMs)
+		}
+	}
+	return h.session.Stats(time.Now())
+}
+
+// UpdateLinkHealth scores the bonded uplink from the latest stats. It
+// returns nil when SRTLA is off.
+func (h *Handler) UpdateLinkHealth() *bondhealth.Score {
+	cfg := h.config.Get()
+	if !cfg.SRTLA.Enabled {
+		h.linkHealth.Reset()
+		return nil
+	}
+
+	var sample bondhealth.Sample
+	up := map[string]bool{}
+	for _, ip := range h.getAvailableBindIPs(&cfg) {
+		up[ip] = true
+	}
+	for _, ip := range h.bindIPs(&cfg) {
+		ip = strings.TrimSpace(ip)
+		if ip == "" {
+			continue
+		}
+		sample.Links++
+		if up[ip] {
+			sample.LinksUp++
+		}
+	}
+	if h.GetPipelineMode() == PipelineModeStreaming {
+		measured := h.bitrateSample()
+		sample.InputKbps = measured.InputKbps
+		sample.ThroughputKbps = measured.ThroughputKbps
+		if srtStats := h.SRTStats(); srtStats != nil && srtStats.Connected {
+			sample.CapacityKbps = srtStats.BandwidthMbps * 1000
+			sample.LossPercent, sample.LossMeasured = srtStats.LossPercent, true
+		}
+	}
+	score := h.linkHealth.Update(sample, time.Now())
+	return &score
+}
@@ -0,0 +1,200 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+
+	"srtla-manager/internal/logger"
+	"srtla-manager/internal/validate"
+)
+
+const (
+	ShareSourceHLS    = "hls"
+	ShareSourceUSBCam = "usbcam"
+
+	DefaultShareTTL = 60 * time.Minute
+	MaxShareTTL     = 24 * time.Hour
+)
+
+// ShareLink is a tokenized, expiring URL granting view-only access to a preview.
+// Links live in memory only and are dropped when the manager restarts.
+type ShareLink struct {
+	Token     string    `json:"token"`
+	Label     string    `json:"label,omitempty"`
+	Source    string    `json:"source"`
+	CameraID  string    `json:"camera_id,omitempty"`
+	URL       string    `json:"url"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// CreateShareRequest is the request body for POST /api/share
+type CreateShareRequest struct {
+	Label      string `json:"label"`
+	Source     string `json:"source"`
+	CameraID   string `json:"camera_id"`
+	TTLMinutes int    `json:"ttl_minutes"`
+}
+
+func (s *ShareLink) expired(now time.Time) bool {
+	return now.After(s.ExpiresAt)
+}
+
+func shareURL(token, source string) string {
+	if source == ShareSourceUSBCam {
+		return "/share/" + token + "/stream"
+	}
+	return "/share/" + token + "/playlist.m3u8"
+}
+
+func newShareToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// lookupShare returns the share for token if it exists and has not expired
+func (h *Handler) lookupShare(token string) (*ShareLink, bool) {
+	h.sharesMu.Lock()
+	defer h.sharesMu.Unlock()
+
+	link, ok := h.shares[token]
+	if !ok {
+		return nil, false
+	}
+	if link.expired(time.Now()) {
+		delete(h.shares, token)
+		return nil, false
+	}
+	return link, true
+}
+
+// HandleShareList handles GET /api/share
+func (h *Handler) HandleShareList(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+
+	h.sharesMu.Lock()
+	links := make([]ShareLink, 0, len(h.shares))
+	for token, link := range h.shares {
+		if link.expired(now) {
+			delete(h.shares, token)
+			continue
+		}
+		links = append(links, *link)
+	}
+	h.sharesMu.Unlock()
+
+	sort.Slice(links, func(i, j int) bool {
+		return links[i].CreatedAt.Before(links[j].CreatedAt)
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(links)
+}
+
+// HandleShareCreate handles POST /api/share
+func (h *Handler) HandleShareCreate(w http.ResponseWriter, r *http.Request) {
+	var req CreateShareRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Source == "" {
+		req.Source = ShareSourceHLS
+	}
+	v := validate.New()
+	v.OneOf("source", req.Source, ShareSourceHLS, ShareSourceUSBCam)
+	if req.Source == ShareSourceUSBCam {
+		v.Required("camera_id", req.CameraID)
+	}
+	v.Range("ttl_minutes", req.TTLMinutes, 0, int(MaxShareTTL/time.Minute))
+	if err := v.Err(); err != nil {
+		validationError(w, err)
+		return
+	}
+	if req.Source == ShareSourceHLS {
+		req.CameraID = ""
+	}
+
+	ttl := DefaultShareTTL
+	if req.TTLMinutes > 0 {
+		ttl = time.Duration(req.TTLMinutes) * time.Minute
+	}
+
+	token, err := newShareToken()
+	if err != nil {
+		jsonError(w, "Failed to generate token: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now()
+	link := &ShareLink{
+		Token:     token,
+		Label:     req.Label,
+		Source:    req.Source,
+		CameraID:  req.CameraID,
+		URL:       shareURL(token, req.Source),
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+
+	h.sharesMu.Lock()
+	h.shares[token] = link
+	h.sharesMu.Unlock()
+
+	logger.Info("Created %s preview share link '%s' expiring at %s", link.Source, link.Label, link.ExpiresAt.Format(time.RFC3339))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(link)
+}
+
+// HandleShareRevoke handles DELETE /api/share/{token}
+func (h *Handler) HandleShareRevoke(w http.ResponseWriter, r *http.Request) {
+	token := r.PathValue("token")
+
+	h.sharesMu.Lock()
+	_, ok := h.shares[token]
+	delete(h.shares, token)
+	h.sharesMu.Unlock()
+
+	if !ok {
+		jsonError(w, "Share link not found", http.StatusNotFound)
+		return
+	}
+
+	logger.Info("Revoked preview share link")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "revoked"})
+}
+
+// HandleShareView handles GET /share/{token}/{path...}, serving the shared
+// preview without requiring dashboard access
+func (h *Handler) HandleShareView(w http.ResponseWriter, r *http.Request) {
+	link, ok := h.lookupShare(r.PathValue("token"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+
+	switch link.Source {
+	case ShareSourceUSBCam:
+		if r.PathValue("path") != "stream" {
+			http.NotFound(w, r)
+			return
+		}
+		h.proxyUSBCameraPreview(w, link.CameraID)
+	default:
+		http.StripPrefix("/share/"+link.Token+"/", http.FileServer(http.Dir(h.previewDir))).ServeHTTP(w, r)
+	}
+}
@@ -0,0 +1,187 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"srtla-manager/internal/jobs"
+	"srtla-manager/internal/speedtest"
+	"srtla-manager/internal/validate"
+)
+
+// SpeedTestRequest is the optional body of POST /api/network/speedtest
+type SpeedTestRequest struct {
+	BindIPs    []string `json:"bind_ips"`    // links to test; none tests every bind IP that is up
+	URL        string   `json:"url"`         // overrides speedtest.url
+	Seconds    int      `json:"seconds"`     // per link, overrides speedtest.seconds
+	TargetKbps int      `json:"target_kbps"` // bitrate a link must sustain; 0 uses abr.max_kbps if set
+}
+
+// SpeedTestLink is the upload throughput of one link
+type SpeedTestLink struct {
+	speedtest.Result
+	Interface string `json:"interface,omitempty"`
+	Sustains  *bool  `json:"sustains,omitempty"` // reached the target bitrate, when there is one
+}
+
+// SpeedTestResult is the outcome of a speed test over each link in turn
+type SpeedTestResult struct {
+	URL        string          `json:"url"`
+	TargetKbps int             `json:"target_kbps,omitempty"`
+	Links      []SpeedTestLink `json:"links"`
+	TotalKbps  float64         `json:"total_kbps"` // sum over the links, each tested alone
+	StartedAt  time.Time       `json:"started_at"`
+	FinishedAt time.Time       `json:"finished_at"`
+}
+
+// measured returns the upload of each link the test reached, by bind IP.
+// It is empty for a nil result.
+func (r *SpeedTestResult) measured() map[string]float64 {
+	kbps := make(map[string]float64)
+	if r == nil {
+		return kbps
+	}
+	for _, l := range r.Links {
+		if l.Error == "" {
+			kbps[l.BindIP] = l.Kbps
+		}
+	}
+	return kbps
+}
+
+// lastSpeedTest returns the last finished speed test, or nil
+func (h *Handler) lastSpeedTest() *SpeedTestResult {
+	h.speedTestMu.Lock()
+	defer h.speedTestMu.Unlock()
+	return h.speedTestLast
+}
+
+// HandleSpeedTestGet handles GET /api/network/speedtest, returning the last
+// finished test
+func (h *Handler) HandleSpeedTestGet(w http.ResponseWriter, r *http.Request) {
+	last := h.lastSpeedTest()
+	if last == nil {
+		jsonError(w, "No speed test has run yet", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(last)
+}
+
+// HandleSpeedTestStart handles POST /api/network/speedtest. It uploads to
+// the test server from each bind IP in turn, so every link is measured on
+// its own, as a background job whose result is the SpeedTestResult.
+func (h *Handler) HandleSpeedTestStart(w http.ResponseWriter, r *http.Request) {
+	var req SpeedTestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		jsonError(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	cfg := h.config.Get()
+	if req.URL == "" {
+		req.URL = cfg.SpeedTest.URL
+	}
+	if req.URL == "" {
+		req.URL = speedtest.DefaultURL
+	}
+	if req.Seconds == 0 {
+		req.Seconds = cfg.SpeedTest.Seconds
+	}
+	duration := speedtest.DefaultDuration
+	if req.Seconds > 0 {
+		duration = time.Duration(req.Seconds) * time.Second
+	}
+	if req.TargetKbps == 0 && cfg.ABR.Enabled {
+		req.TargetKbps = cfg.ABR.MaxKbps
+	}
+
+	v := validate.New()
+	v.URL("url", req.URL, "http", "https")
+	v.Range("seconds", req.Seconds, 0, 60)
+	v.Range("target_kbps", req.TargetKbps, 0, validate.MaxBitrateKbps)
+	up := systemIPv4s()
+	for i, ip := range req.BindIPs {
+		if !up[ip] {
+			v.Addf(fmt.Sprintf("bind_ips[%d]", i), "%s is not an address of an interface that is up", ip)
+		}
+	}
+	if err := v.Err(); err != nil {
+		validationError(w, err)
+		return
+	}
+
+	ips := req.BindIPs
+	if len(ips) == 0 {
+		ips = h.getAvailableBindIPs(&cfg)
+	}
+	if len(ips) == 0 {
+		jsonError(w, "No bind IPs are up to test", http.StatusConflict)
+		return
+	}
+	// the stream would compete with the test, and the test with the stream
+	if h.GetPipelineMode() == PipelineModeStreaming {
+		jsonError(w, "Cannot run a speed test while streaming", http.StatusConflict)
+		return
+	}
+
+	h.speedTestMu.Lock()
+	if h.speedTestRunning {
+		h.speedTestMu.Unlock()
+		jsonError(w, "A speed test is already running", http.StatusConflict)
+		return
+	}
+	h.speedTestRunning = true
+	h.speedTestMu.Unlock()
+
+	h.logOutput("manager", fmt.Sprintf("[SPEEDTEST] Testing %d links against %s", len(ips), req.URL))
+	job := h.jobs.Start("speedtest", func(ctx context.Context, report *jobs.Reporter) (interface{}, error) {
+		result := h.runSpeedTest(ctx, report, req.URL, req.TargetKbps, ips, duration)
+
+		h.speedTestMu.Lock()
+		h.speedTestRunning = false
+		if ctx.Err() == nil {
+			h.speedTestLast = result
+		}
+		h.speedTestMu.Unlock()
+		return result, ctx.Err()
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"status": "started",
+		"job_id": job.ID,
+	})
+}
+
+// runSpeedTest tests the links one after the other
+func (h *Handler) runSpeedTest(ctx context.Context, report *jobs.Reporter, url string, targetKbps int, ips []string, d time.Duration) *SpeedTestResult {
+	result := &SpeedTestResult{URL: url, TargetKbps: targetKbps, Links: []SpeedTestLink{}, StartedAt: time.Now()}
+	for i, ip := range ips {
+		if ctx.Err() != nil {
+			break
+		}
+		report.Progress(i*100/len(ips), "Testing %s (%d of %d)", ip, i+1, len(ips))
+
+		link := SpeedTestLink{Result: speedtest.Upload(ctx, url, ip, d), Interface: interfaceOf(ip)}
+		if link.Error == "" {
+			result.TotalKbps += link.Kbps
+			if targetKbps > 0 {
+				sustains := link.Kbps >= float64(targetKbps)
+				link.Sustains = &sustains
+			}
+			h.logOutput("manager", fmt.Sprintf("[SPEEDTEST] %s (%s): %.0f kbps", ip, link.Interface, link.Kbps))
+		} else {
+			h.logOutput("manager", fmt.Sprintf("[SPEEDTEST] %s (%s) failed: %s", ip, link.Interface, link.Error))
+		}
+		result.Links = append(result.Links, link)
+	}
+	result.FinishedAt = time.Now()
+	report.Progress(100, "Tested %d links, %.0f kbps in total", len(result.Links), result.TotalKbps)
+	return result
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"fmt"
+
+	"srtla-manager/internal/config"
+	"srtla-manager/internal/process"
+	"srtla-manager/internal/srt"
+	"srtla-manager/internal/system"
+)
+
+// startSRTProbe starts the statistics relay when srt.probe is set and
+// returns the port ffmpeg should send to. Without srt-live-transmit the
+// stream goes straight to the bonding process as before.
+func (h *Handler) startSRTProbe(cfg *config.Config) int {
+	if !cfg.SRT.Probe {
+		return cfg.SRT.LocalPort
+	}
+	if err := h.srtProbe.Start(system.FindSRTLiveTransmit(), cfg.SRT.ProbePort, cfg.SRT.LocalPort); err != nil {
+		h.logOutput("manager", fmt.Sprintf("[WARNING] SRT statistics unavailable, sending straight to port %d: %v", cfg.SRT.LocalPort, err))
+		return cfg.SRT.LocalPort
+	}
+	h.logOutput("manager", fmt.Sprintf("[SRT] Relaying through port %d for link statistics", cfg.SRT.ProbePort))
+	return cfg.SRT.ProbePort
+}
+
+// srtOutputPort returns the port ffmpeg sends to when it is restarted,
+// bringing the relay back up first if it went down
+func (h *Handler) srtOutputPort(cfg *config.Config) int {
+	if cfg.SRT.Probe && h.srtProbe.ProcessState() == process.StateRunning {
+		return cfg.SRT.ProbePort
+	}
+	return h.startSRTProbe(cfg)
+}
+
+// StopSRTProbe stops the statistics relay
+func (h *Handler) StopSRTProbe() {
+	h.srtProbe.Stop()
+}
+
+// SRTStats returns the SRT link statistics, nil unless the relay is running
+func (h *Handler) SRTStats() *srt.Stats {
+	if h.srtProbe.ProcessState() != process.StateRunning {
+		return nil
+	}
+	stats := h.srtProbe.Stats()
+	return &stats
+}
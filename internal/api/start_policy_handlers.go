@@ -0,0 +1,111 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"srtla-manager/internal/config"
+	"srtla-manager/internal/startpolicy"
+)
+
+// StartPolicyResponse is the response of GET /api/stream/preconditions
+type StartPolicyResponse struct {
+	Enabled bool                `json:"enabled"`
+	Ready   bool                `json:"ready"` // every check is met
+	Checks  []startpolicy.Check `json:"checks"`
+	Links   []startpolicy.Link  `json:"links"` // the links up that the stream would bond over
+}
+
+// StartPolicyError is why a stream wasn't started: the start policy's
+// checks that weren't met
+type StartPolicyError struct {
+	Unmet []startpolicy.Check
+}
+
+func (e *StartPolicyError) Error() string {
+	return "Cannot start stream: " + startpolicy.Summary(e.Unmet) + " (start with force to override)"
+}
+
+func startPolicy(cfg config.StartPolicyConfig) startpolicy.Policy {
+	return startpolicy.Policy{
+		MinLinks:         cfg.MinLinks,
+		MinBandwidthKbps: cfg.MinBandwidthKbps,
+		RequiredLinks:    cfg.RequiredLinks,
+	}
+}
+
+// startPolicyLinks returns the links of ips that are up, with what the last
+// speed test measured of them if it is recent enough
+func (h *Handler) startPolicyLinks(cfg *config.Config, ips []string) []startpolicy.Link {
+	last := h.lastSpeedTest()
+	maxAge := time.Duration(cfg.StartPolicy.SpeedTestMaxAge) * time.Minute
+	if last != nil && maxAge > 0 && time.Since(last.FinishedAt) > maxAge {
+		last = nil
+	}
+	measured := last.measured()
+
+	links := []startpolicy.Link{}
+	for _, ip := range h.availableOf(ips) {
+		link := startpolicy.Link{BindIP: ip, Interface: interfaceOf(ip)}
+		link.Kbps, link.Tested = measured[ip]
+		links = append(links, link)
+	}
+	return links
+}
+
+// checkStartPolicy returns the start policy's unmet checks for a stream
+// bonding over the bind IPs of cfg's stream
+func (h *Handler) checkStartPolicy(cfg *config.Config) []startpolicy.Check {
+	policy := startPolicy(cfg.StartPolicy)
+	if !cfg.SRTLA.Enabled || !policy.Enabled() {
+		return nil
+	}
+	return startpolicy.Unmet(policy.Evaluate(h.startPolicyLinks(cfg, h.bindIPs(cfg))))
+}
+
+// HandleStartPolicy handles GET /api/stream/preconditions, checking the start
+// policy as a stream started now would be. bind_set checks a named bind IP
+// set in place of srtla.bind_set.
+func (h *Handler) HandleStartPolicy(w http.ResponseWriter, r *http.Request) {
+	cfg := h.config.Get()
+
+	name := r.URL.Query().Get("bind_set")
+	if name == "" {
+		name = cfg.SRTLA.BindSet
+	}
+	ips := cfg.SRTLA.BindIPs
+	if name != "" {
+		set, ok := cfg.SRTLA.BindSets[name]
+		if !ok {
+			jsonError(w, fmt.Sprintf("No bind IP set named %s", name), http.StatusNotFound)
+			return
+		}
+		ips = set
+	}
+
+	policy := startPolicy(cfg.StartPolicy)
+	resp := StartPolicyResponse{
+		Enabled: cfg.SRTLA.Enabled && policy.Enabled(),
+		Checks:  []startpolicy.Check{},
+		Links:   h.startPolicyLinks(&cfg, withoutTunnelIPs(ips, cfg.VPN)),
+	}
+	if resp.Enabled {
+		resp.Checks = policy.Evaluate(resp.Links)
+	}
+	resp.Ready = len(startpolicy.Unmet(resp.Checks)) == 0
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// logStartOverride records a stream started though the policy wasn't met
+func (h *Handler) logStartOverride(unmet []startpolicy.Check) {
+	names := make([]string, len(unmet))
+	for i, c := range unmet {
+		names[i] = c.Name
+	}
+	h.logOutput("manager", fmt.Sprintf("[START-POLICY] Starting despite %s: %s", strings.Join(names, ", "), startpolicy.Summary(unmet)))
+}
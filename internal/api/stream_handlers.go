@@ -1,39 +1,116 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"strings"
 	"time"
 
 	"srtla-manager/internal/process"
 	"srtla-manager/internal/system"
+	"srtla-manager/internal/tracing"
 )
 
 func (h *Handler) HandleStreamStart(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
+	// The body is optional; profile and bind_set pick the destination and the
+	// bind IP set for this stream
+	var req StreamStartRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		jsonError(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if code, err := h.startStreaming(r.Context(), req); err != nil {
+		pipelineError(w, err, code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(StreamStartResponse{Status: "started", Degraded: h.degradedStart()})
+}
+
+// startStreaming switches the pipeline from receive-only to streaming mode,
+// after applying the requested stream profile. It bonds over the requested
+// bind IP set, the profile's or srtla.bind_set, in that order. On failure it
+// returns the HTTP status code matching the error.
+func (h *Handler) startStreaming(ctx context.Context, req StreamStartRequest) (int, error) {
+	ctx, span := tracing.Start(ctx, "stream.start")
+	defer span.End()
+
+	release, err := h.acquirePipeline("stream_start")
+	if err != nil {
+		return http.StatusConflict, err
+	}
+	defer release()
+
 	cfg := h.config.Get()
+	span.SetAttribute("srtla.enabled", cfg.SRTLA.Enabled)
 
 	// Pre-flight validation before starting any processes
 	if err := cfg.Validate(); err != nil {
-		jsonError(w, fmt.Sprintf("Cannot start stream: invalid configuration - %v", err), http.StatusBadRequest)
-		return
+		span.RecordError(err)
+		return http.StatusBadRequest, fmt.Errorf("Cannot start stream: invalid configuration - %v", err)
 	}
 
 	// Check if already streaming
 	if h.GetPipelineMode() == PipelineModeStreaming {
-		jsonError(w, "Already streaming", http.StatusBadRequest)
-		return
+		return http.StatusBadRequest, fmt.Errorf("Already streaming")
+	}
+
+	if req.Profile != "" {
+		if err := cfg.ApplyProfile(req.Profile); err != nil {
+			return http.StatusBadRequest, fmt.Errorf("Cannot start stream: %v", err)
+		}
+		if err := h.config.Update(cfg); err != nil {
+			span.RecordError(err)
+			return http.StatusInternalServerError, fmt.Errorf("Cannot start stream: failed to apply profile %s: %v", req.Profile, err)
+		}
+		cfg = h.config.Get()
+		h.logOutput("manager", fmt.Sprintf("[PROFILE] Applied %s: %s:%d", req.Profile, cfg.SRTLA.RemoteHost, cfg.SRTLA.RemotePort))
+	}
+	span.SetAttribute("profile", cfg.Profile)
+
+	bindSet := req.BindSet
+	if bindSet == "" {
+		bindSet = cfg.Profiles[req.Profile].BindSet
+	}
+	if bindSet == "" {
+		bindSet = cfg.SRTLA.BindSet
+	}
+	if _, ok := cfg.SRTLA.BindSets[bindSet]; bindSet != "" && !ok {
+		return http.StatusBadRequest, fmt.Errorf("Cannot start stream: no bind IP set named %s", bindSet)
+	}
+	h.setStreamBindSet(bindSet)
+	defer func() {
+		if h.GetPipelineMode() != PipelineModeStreaming {
+			h.setStreamBindSet("")
+			h.setDegradedStart(nil)
+		}
+	}()
+	bindIPs := h.bindIPs(&cfg)
+	span.SetAttribute("srtla.bind_set", bindSet)
+
+	if unmet := h.checkStartPolicy(&cfg); len(unmet) > 0 {
+		if !req.Force {
+			err := &StartPolicyError{Unmet: unmet}
+			span.RecordError(err)
+			return http.StatusPreconditionFailed, err
+		}
+		h.logStartOverride(unmet)
 	}
 
 	// Determine available bind IPs if SRTLA is enabled
-	var availableIPs []string
-	if cfg.SRTLA.Enabled && len(cfg.SRTLA.BindIPs) > 0 {
+	var availableIPs, configuredIPs []string
+	if cfg.SRTLA.Enabled && len(bindIPs) > 0 {
 		interfaces := system.ListNetworkInterfaces()
 		systemIPs := make(map[string]bool)
 		for _, iface := range interfaces {
@@ -43,11 +120,12 @@ func (h *Handler) HandleStreamStart(w http.ResponseWriter, r *http.Request) {
 		}
 
 		var unavailableIPs []string
-		for _, ip := range cfg.SRTLA.BindIPs {
+		for _, ip := range bindIPs {
 			ip = strings.TrimSpace(ip)
 			if ip == "" {
 				continue
 			}
+			configuredIPs = append(configuredIPs, ip)
 			if systemIPs[ip] {
 				availableIPs = append(availableIPs, ip)
 			} else {
@@ -56,43 +134,57 @@ func (h *Handler) HandleStreamStart(w http.ResponseWriter, r *http.Request) {
 		}
 
 		// Require at least 1 IP available
+		span.SetAttribute("srtla.bind_ips.available", len(availableIPs))
+		span.SetAttribute("srtla.bind_ips.configured", len(bindIPs))
+
 		if len(availableIPs) == 0 {
-			jsonError(w, "Cannot start stream: no bind IPs available on system. Check modem/USB network status.", http.StatusBadRequest)
-			return
+			span.RecordError(fmt.Errorf("no bind IPs available"))
+			return http.StatusBadRequest, fmt.Errorf("Cannot start stream: no bind IPs available on system. Check modem/USB network status.")
 		}
 
 		// Warn about unavailable IPs (don't block)
 		if len(unavailableIPs) > 0 {
 			h.logOutput("manager", fmt.Sprintf("[WARNING] Starting with %d/%d IPs. Unavailable: %s",
-				len(availableIPs), len(cfg.SRTLA.BindIPs), strings.Join(unavailableIPs, ", ")))
+				len(availableIPs), len(bindIPs), strings.Join(unavailableIPs, ", ")))
 		}
 	}
 
 	// Check SRTLA not already running
 	if cfg.SRTLA.Enabled && h.srtla.ProcessState() == process.StateRunning {
-		jsonError(w, "Cannot start stream: SRTLA is already running", http.StatusBadRequest)
-		return
+		return http.StatusBadRequest, fmt.Errorf("Cannot start stream: SRTLA is already running")
 	}
 
 	// Start SRTLA first so it's listening on the SRT port before FFmpeg tries to connect
 	if cfg.SRTLA.Enabled && len(availableIPs) > 0 {
-		if err := h.startSRTLA(&cfg, availableIPs); err != nil {
-			jsonError(w, err.Error(), http.StatusInternalServerError)
-			return
+		_, srtlaSpan := tracing.Start(ctx, "srtla.start")
+		err := h.startSRTLA(&cfg, availableIPs)
+		srtlaSpan.RecordError(err)
+		srtlaSpan.End()
+		if err != nil {
+			span.RecordError(err)
+			return http.StatusInternalServerError, err
 		}
 		h.activeBindIPs = availableIPs
 	}
+	h.setDegradedStart(h.newDegradedStart(configuredIPs, availableIPs))
 
 	bindAddr := h.getBindAddr()
+	srtPort := h.startSRTProbe(&cfg)
 
 	// Stop current FFmpeg (receive-only mode) and restart with SRT output
+	_, ffSpan := tracing.Start(ctx, "ffmpeg.restart_streaming")
 	h.ffmpeg.Stop()
 	time.Sleep(300 * time.Millisecond)
 	h.cleanPreviewDir()
 
 	// Restart FFmpeg with SRT output (streaming mode)
-	if err := h.ffmpeg.StartWithPreview(cfg.RTMP.ListenPort, cfg.RTMP.StreamKey, cfg.SRT.LocalPort, bindAddr, h.previewDir); err != nil {
+	err = h.ffmpeg.StartWithPreview(cfg.RTMP.ListenPort, cfg.RTMP.StreamKey, srtPort, bindAddr, h.streamPreviewDir())
+	ffSpan.RecordError(err)
+	ffSpan.End()
+	if err != nil {
+		span.RecordError(err)
 		// If FFmpeg fails, stop SRTLA and try to restore receive mode
+		h.srtProbe.Stop()
 		if cfg.SRTLA.Enabled {
 			h.srtla.Stop()
 		}
@@ -100,33 +192,61 @@ func (h *Handler) HandleStreamStart(w http.ResponseWriter, r *http.Request) {
 		_ = h.ffmpeg.StartWithPreview(cfg.RTMP.ListenPort, cfg.RTMP.StreamKey, 0, bindAddr, h.previewDir)
 		h.SetPipelineMode(PipelineModeReceiving)
 		go h.monitorReceiveHealth(bindAddr)
-		jsonError(w, fmt.Sprintf("Failed to start FFmpeg with SRT: %v", err), http.StatusInternalServerError)
-		return
+		return http.StatusInternalServerError, fmt.Errorf("Failed to start FFmpeg with SRT: %v", err)
 	}
 
 	h.SetPipelineMode(PipelineModeStreaming)
 
 	// Start streaming-mode health monitor
 	go h.monitorPipelineHealth(bindAddr)
+	h.startBitrateControl()
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"status": "started"})
+	return http.StatusOK, nil
 }
 
 func (h *Handler) HandleStreamStop(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := h.stopStreaming(r.Context()); err != nil {
+		pipelineError(w, err, http.StatusConflict)
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "stopped"})
+}
+
+// stopStreaming stops the outbound stream and returns the pipeline to
+// receive-only mode. It fails only when the pipeline is busy.
+func (h *Handler) stopStreaming(ctx context.Context) error {
+	_, span := tracing.Start(ctx, "stream.stop")
+	defer span.End()
+
+	release, err := h.acquirePipeline("stream_stop")
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	cfg := h.config.Get()
 
 	// Signal health monitors to stop by transitioning mode first
 	h.SetPipelineMode(PipelineModeIdle)
+	h.endRTSPSession()
+	h.setStreamBindSet("")
+	h.setDegradedStart(nil)
+	h.transportMu.Lock()
+	h.srtlaLinks, h.onFailover = nil, false
+	h.transportMu.Unlock()
 
 	// Stop SRTLA and FFmpeg
 	h.srtla.Stop()
+	h.srtProbe.Stop()
 	h.ffmpeg.Stop()
+	h.stopTestPattern()
 	time.Sleep(300 * time.Millisecond)
 
 	// Restart FFmpeg in receive-only mode
@@ -134,6 +254,7 @@ func (h *Handler) HandleStreamStop(w http.ResponseWriter, r *http.Request) {
 	h.cleanPreviewDir()
 
 	if err := h.ffmpeg.StartWithPreview(cfg.RTMP.ListenPort, cfg.RTMP.StreamKey, 0, bindAddr, h.previewDir); err != nil {
+		span.RecordError(err)
 		h.logOutput("manager", fmt.Sprintf("[WARNING] Failed to restart FFmpeg in receive mode: %v", err))
 		h.SetPipelineMode(PipelineModeIdle)
 	} else {
@@ -141,9 +262,14 @@ func (h *Handler) HandleStreamStop(w http.ResponseWriter, r *http.Request) {
 		h.logOutput("manager", "[FFmpeg] Restarted in receive-only mode")
 		go h.monitorReceiveHealth(bindAddr)
 	}
+	return nil
+}
 
+// HandlePublishers handles GET /api/publishers, returning the camera
+// connections to the RTMP listener with per-client reconnect counts
+func (h *Handler) HandlePublishers(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"status": "stopped"})
+	json.NewEncoder(w).Encode(h.ffmpeg.Publishers())
 }
 
 // monitorReceiveHealth monitors FFmpeg in receive-only mode and restarts it if it crashes.
@@ -157,8 +283,8 @@ func (h *Handler) monitorReceiveHealth(bindAddr string) {
 	lastFailure := time.Time{}
 
 	for range ticker.C {
-		// Stop this monitor if mode has changed
-		if h.GetPipelineMode() != PipelineModeReceiving {
+		// Stop this monitor if mode has changed or an RTSP camera took over
+		if h.GetPipelineMode() != PipelineModeReceiving || h.rtspSourceActive() {
 			return
 		}
 
@@ -173,6 +299,16 @@ func (h *Handler) monitorReceiveHealth(bindAddr string) {
 				return
 			}
 
+			// Leave FFmpeg alone while an operation such as a stream start
+			// or USB capture owns the pipeline
+			if h.CurrentPipelineOperation() != nil {
+				continue
+			}
+
+			if h.relistenForPublisher() {
+				continue
+			}
+
 			// Apply backoff if we've had recent failures
 			if consecutiveFailures > 0 && !lastFailure.IsZero() && time.Since(lastFailure) < backoff {
 				continue // Skip this tick, wait for backoff to elapse
@@ -220,6 +356,23 @@ func (h *Handler) monitorReceiveHealth(bindAddr string) {
 	}
 }
 
+// relistenForPublisher restarts ffmpeg's listener straight away when it
+// exited because the camera dropped within the reconnect grace period. The
+// preview and stream state carry on and no restart backoff is counted, so a
+// brief WiFi blip doesn't look like a pipeline failure. It reports whether
+// it handled the exit.
+func (h *Handler) relistenForPublisher() bool {
+	if !h.ffmpeg.PublisherInGrace() {
+		return false
+	}
+	if err := h.ffmpeg.Relisten(); err != nil {
+		h.logOutput("manager", fmt.Sprintf("[PUBLISHER] Failed to listen for the camera again: %v", err))
+	} else {
+		h.logOutput("manager", "[PUBLISHER] Camera dropped, listening for it to reconnect")
+	}
+	return true
+}
+
 // monitorPipelineHealth monitors FFmpeg/SRTLA in streaming mode and restarts them if they crash or stall.
 // Uses exponential backoff to avoid hammering failed processes.
 // Also detects when new bind IPs become available and reloads SRTLA to use them.
@@ -233,9 +386,14 @@ func (h *Handler) monitorPipelineHealth(bindAddr string) {
 			return
 		}
 
+		// Don't race a stop or update that is tearing the pipeline down
+		if h.CurrentPipelineOperation() != nil {
+			continue
+		}
+
 		cfg := h.config.Get()
 
-		if cfg.SRTLA.Enabled && len(cfg.SRTLA.BindIPs) > 0 {
+		if cfg.SRTLA.Enabled && len(h.bindIPs(&cfg)) > 0 {
 			srtlaState := h.srtla.ProcessState()
 			srtlaStale := h.srtla.IsStale(SRTLAStaleThreshold)
 
@@ -284,11 +442,16 @@ func (h *Handler) monitorPipelineHealth(bindAddr string) {
 					}
 				}
 
-				// If new IPs available, reload SRTLA to include them
-				if len(newIPs) > 0 {
-					h.logOutput("manager", fmt.Sprintf("[IP-RECOVERY] Detected %d new IPs: %s. Reloading...",
-						len(newIPs), strings.Join(newIPs, ", ")))
-					if err := h.srtla.ReloadIPs(currentAvailable); err != nil {
+				// If new IPs available, reload SRTLA to include them. Links
+				// going away matter when link policies then pick others.
+				if len(newIPs) > 0 || (len(currentAvailable) > 0 && h.linkSelectionChanged(&cfg, currentAvailable)) {
+					if len(newIPs) > 0 {
+						h.logOutput("manager", fmt.Sprintf("[IP-RECOVERY] Detected %d new IPs: %s. Reloading...",
+							len(newIPs), strings.Join(newIPs, ", ")))
+					} else {
+						h.logOutput("manager", "[IP-RECOVERY] Links changed, reloading to apply link policies...")
+					}
+					if err := h.reloadSRTLAIPs(currentAvailable); err != nil {
 						h.logOutput("manager", fmt.Sprintf("[IP-RECOVERY] Reload failed: %v", err))
 					} else {
 						h.activeBindIPs = currentAvailable
@@ -301,6 +464,9 @@ func (h *Handler) monitorPipelineHealth(bindAddr string) {
 		// Check if FFmpeg is still running (in streaming mode, restart with SRT)
 		ffState := h.ffmpeg.ProcessState()
 		ffStale := h.ffmpeg.IsStale(FFmpegStaleThreshold)
+		if ffState != process.StateRunning && h.relistenForPublisher() {
+			continue
+		}
 		if ffState != process.StateRunning || ffStale {
 			reason := "stopped unexpectedly"
 			if ffStale {
@@ -310,7 +476,7 @@ func (h *Handler) monitorPipelineHealth(bindAddr string) {
 			if h.shouldRestartWithBackoff(h.ffmpegRestarts, reason, "FFmpeg") {
 				h.logOutput("manager", fmt.Sprintf("[AUTO-RESTART] FFmpeg %s, restarting in streaming mode...", reason))
 
-				if err := h.ffmpeg.StartWithPreview(cfg.RTMP.ListenPort, cfg.RTMP.StreamKey, cfg.SRT.LocalPort, bindAddr, h.previewDir); err != nil {
+				if err := h.ffmpeg.StartWithPreview(cfg.RTMP.ListenPort, cfg.RTMP.StreamKey, h.srtOutputPort(&cfg), bindAddr, h.streamPreviewDir()); err != nil {
 					h.recordRestartFailure(h.ffmpegRestarts)
 					h.logOutput("manager", fmt.Sprintf("[AUTO-RESTART] Failed to restart FFmpeg: %v", err))
 					// Will retry on next tick with backoff
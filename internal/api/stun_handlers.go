@@ -0,0 +1,77 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"srtla-manager/internal/stun"
+	"srtla-manager/internal/validate"
+)
+
+// STUNRequest is the optional body of POST /api/network/stun
+type STUNRequest struct {
+	BindIPs []string `json:"bind_ips"` // none asks from every bind IP that is up
+	Port    int      `json:"port"`     // local UDP port; 0 picks a free one per bind IP
+}
+
+// STUNMapping is the public address of one bind IP
+type STUNMapping struct {
+	stun.Mapping
+	Interface string `json:"interface,omitempty"`
+}
+
+// HandleSTUNDiscover handles POST /api/network/stun, asking the STUN
+// servers which public address each bind IP's port is seen from. This is
+// the address a rendezvous peer is given. A port a running rendezvous
+// output holds can't be asked about; its output reports it instead.
+func (h *Handler) HandleSTUNDiscover(w http.ResponseWriter, r *http.Request) {
+	var req STUNRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		jsonError(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	v := validate.New()
+	v.Range("port", req.Port, 0, 65535)
+	up := systemIPv4s()
+	for i, ip := range req.BindIPs {
+		if !up[ip] {
+			v.Addf(fmt.Sprintf("bind_ips[%d]", i), "%s is not an address of an interface that is up", ip)
+		}
+	}
+	if err := v.Err(); err != nil {
+		validationError(w, err)
+		return
+	}
+
+	cfg := h.config.Get()
+	ips := req.BindIPs
+	if len(ips) == 0 {
+		ips = h.getAvailableBindIPs(&cfg)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), stun.Timeout*3)
+	defer cancel()
+
+	mappings := make([]STUNMapping, len(ips))
+	var wg sync.WaitGroup
+	for i, ip := range ips {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			mappings[i] = STUNMapping{
+				Mapping:   stun.Discover(ctx, cfg.STUN.Servers, ip, req.Port),
+				Interface: interfaceOf(ip),
+			}
+		}()
+	}
+	wg.Wait()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(mappings)
+}
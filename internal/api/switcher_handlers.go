@@ -0,0 +1,180 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"srtla-manager/internal/events"
+	"srtla-manager/internal/logger"
+	"srtla-manager/internal/tally"
+)
+
+// SwitcherStatus describes what the vision switcher reports for this unit
+type SwitcherStatus struct {
+	Enabled      bool        `json:"enabled"`
+	Type         string      `json:"type"`
+	Host         string      `json:"host"`
+	Input        int         `json:"input"`
+	Connected    bool        `json:"connected"`
+	State        tally.State `json:"state"`
+	OnAir        bool        `json:"on_air"`
+	Since        *time.Time  `json:"since,omitempty"` // when State last changed
+	GateBoost    bool        `json:"gate_boost"`
+	BoostAllowed bool        `json:"boost_allowed"`
+	LastError    string      `json:"last_error,omitempty"`
+}
+
+// ApplySwitcherConfig reconnects to the switcher from the configuration
+func (h *Handler) ApplySwitcherConfig() {
+	cfg := h.config.Get().Switcher
+
+	h.switcherMu.Lock()
+	if h.switcherCancel != nil {
+		h.switcherCancel()
+		h.switcherCancel = nil
+	}
+	h.switcherState = tally.Off
+	h.switcherConnected = false
+	h.switcherError = ""
+	h.switcherSince = time.Time{}
+	if !cfg.Enabled {
+		h.switcherMu.Unlock()
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	h.switcherCancel = cancel
+	h.switcherMu.Unlock()
+
+	go tally.Watch(ctx, tally.WatcherFor(cfg.Type), cfg.Host, cfg.Input, func(state tally.State) {
+		if ctx.Err() == nil {
+			h.setSwitcherState(state, true)
+		}
+	}, func(err error) {
+		if ctx.Err() != nil {
+			return
+		}
+		h.switcherMu.Lock()
+		h.switcherConnected = false
+		h.switcherError = err.Error()
+		h.switcherMu.Unlock()
+		logger.Warn("Switcher: %v", err)
+		events.Publish(h.bus, TopicSwitcher, h.switcherStatus())
+	})
+
+	h.logOutput("manager", fmt.Sprintf("[SWITCHER] Following input %d on %s %s", cfg.Input, cfg.Type, cfg.Host))
+}
+
+// StopSwitcher disconnects from the switcher on shutdown
+func (h *Handler) StopSwitcher() {
+	h.switcherMu.Lock()
+	defer h.switcherMu.Unlock()
+	if h.switcherCancel != nil {
+		h.switcherCancel()
+		h.switcherCancel = nil
+	}
+}
+
+// setSwitcherState records what the switcher reports, announces changes and
+// passes them on to the tally light when it follows the switcher
+func (h *Handler) setSwitcherState(state tally.State, connected bool) {
+	h.switcherMu.Lock()
+	changed := state != h.switcherState || h.switcherSince.IsZero()
+	h.switcherState = state
+	h.switcherConnected = connected
+	h.switcherError = ""
+	if changed {
+		h.switcherSince = time.Now()
+	}
+	h.switcherMu.Unlock()
+
+	if h.config.Get().Tally.Source == tally.SourceSwitcher {
+		h.setTally(state)
+	}
+	if !changed {
+		return
+	}
+	h.logOutput("manager", fmt.Sprintf("[SWITCHER] Input is %s", state))
+	events.Publish(h.bus, TopicSwitcher, h.switcherStatus())
+}
+
+// SwitcherState is what the switcher last reported for this unit; off when
+// it isn't followed or can't be reached
+func (h *Handler) SwitcherState() tally.State {
+	h.switcherMu.Lock()
+	defer h.switcherMu.Unlock()
+	return h.switcherState
+}
+
+// BoostAllowed reports whether bitrate boosts may be applied. With
+// switcher.gate_boost set they are held back unless this unit is on program.
+func (h *Handler) BoostAllowed() bool {
+	cfg := h.config.Get().Switcher
+	if !cfg.Enabled || !cfg.GateBoost {
+		return true
+	}
+	return h.SwitcherState() == tally.Program
+}
+
+func (h *Handler) switcherStatus() *SwitcherStatus {
+	cfg := h.config.Get().Switcher
+
+	h.switcherMu.Lock()
+	defer h.switcherMu.Unlock()
+
+	status := &SwitcherStatus{
+		Enabled:      cfg.Enabled,
+		Type:         cfg.Type,
+		Host:         cfg.Host,
+		Input:        cfg.Input,
+		Connected:    h.switcherConnected,
+		State:        h.switcherState,
+		OnAir:        h.switcherState == tally.Program,
+		GateBoost:    cfg.GateBoost,
+		BoostAllowed: !cfg.Enabled || !cfg.GateBoost || h.switcherState == tally.Program,
+		LastError:    h.switcherError,
+	}
+	if !h.switcherSince.IsZero() {
+		since := h.switcherSince
+		status.Since = &since
+	}
+	return status
+}
+
+// statusSwitcher is the switcher section of /api/status, left out when no
+// switcher is configured
+func (h *Handler) statusSwitcher() *SwitcherStatus {
+	if !h.config.Get().Switcher.Enabled {
+		return nil
+	}
+	return h.switcherStatus()
+}
+
+// HandleSwitcherStatus handles GET /api/switcher
+func (h *Handler) HandleSwitcherStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.switcherStatus())
+}
+
+// HandleSwitcherUpdate handles PUT /api/switcher. Fields missing from the body
+// keep their current values.
+func (h *Handler) HandleSwitcherUpdate(w http.ResponseWriter, r *http.Request) {
+	cfg := h.config.Get()
+	if err := json.NewDecoder(r.Body).Decode(&cfg.Switcher); err != nil {
+		jsonError(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.config.Update(cfg); err != nil {
+		validationError(w, err)
+		return
+	}
+
+	h.ApplySwitcherConfig()
+	h.ApplyTallyConfig()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.switcherStatus())
+}
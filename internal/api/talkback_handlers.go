@@ -0,0 +1,157 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"srtla-manager/internal/config"
+	"srtla-manager/internal/logger"
+	"srtla-manager/internal/process"
+	"srtla-manager/internal/system"
+)
+
+// TalkbackResponse describes the talkback configuration and player
+type TalkbackResponse struct {
+	Enabled bool                   `json:"enabled"`
+	Port    int                    `json:"port"`
+	Device  string                 `json:"device"`
+	Volume  float64                `json:"volume"`
+	Status  process.TalkbackStatus `json:"status"`
+	SDP     string                 `json:"sdp"` // hand this to the director's sender
+}
+
+// TalkbackRequest updates talkback settings; omitted fields are unchanged
+type TalkbackRequest struct {
+	Enabled     *bool    `json:"enabled"`
+	Port        int      `json:"port,omitempty"`
+	PayloadType int      `json:"payload_type,omitempty"`
+	Device      string   `json:"device,omitempty"`
+	Volume      *float64 `json:"volume,omitempty"`
+}
+
+func (h *Handler) talkbackConfig() process.TalkbackConfig {
+	cfg := h.config.Get().Talkback
+	return process.TalkbackConfig{
+		Port:        cfg.Port,
+		PayloadType: cfg.PayloadType,
+		Device:      cfg.Device,
+		Volume:      cfg.Volume,
+	}
+}
+
+// ApplyTalkbackConfig starts, restarts or stops the talkback player to match
+// the configuration
+func (h *Handler) ApplyTalkbackConfig() {
+	enabled := h.config.Get().Talkback.Enabled
+	want := h.talkbackConfig()
+
+	h.talkbackMu.Lock()
+	defer h.talkbackMu.Unlock()
+
+	running := h.talkback.Status().State == process.StateRunning
+	if enabled && running && h.talkbackApplied != nil && *h.talkbackApplied == want {
+		return
+	}
+	if running {
+		h.talkback.Stop()
+		h.talkbackApplied = nil
+	}
+	if !enabled {
+		return
+	}
+
+	if err := h.talkback.Start(want); err != nil {
+		logger.Error("Talkback: %v", err)
+		return
+	}
+	h.talkbackApplied = &want
+	h.logOutput("manager", fmt.Sprintf("[TALKBACK] Playing RTP/Opus from UDP port %d on %s", want.Port, want.Device))
+}
+
+// StopTalkback stops the talkback player on shutdown
+func (h *Handler) StopTalkback() {
+	h.talkbackMu.Lock()
+	defer h.talkbackMu.Unlock()
+	h.talkback.Stop()
+	h.talkbackApplied = nil
+}
+
+func (h *Handler) talkbackResponse() TalkbackResponse {
+	cfg := h.config.Get().Talkback
+	host := getDeviceIP(h)
+	if host == "" {
+		host = "0.0.0.0"
+	}
+	return TalkbackResponse{
+		Enabled: cfg.Enabled,
+		Port:    cfg.Port,
+		Device:  cfg.Device,
+		Volume:  cfg.Volume,
+		Status:  h.talkback.Status(),
+		SDP:     process.TalkbackSDP(h.talkbackConfig(), host),
+	}
+}
+
+// HandleTalkbackStatus handles GET /api/talkback
+func (h *Handler) HandleTalkbackStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.talkbackResponse())
+}
+
+// HandleTalkbackUpdate handles PUT /api/talkback
+func (h *Handler) HandleTalkbackUpdate(w http.ResponseWriter, r *http.Request) {
+	var req TalkbackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	cfg := h.config.Get()
+	if req.Enabled != nil {
+		cfg.Talkback.Enabled = *req.Enabled
+	}
+	if req.Port != 0 {
+		cfg.Talkback.Port = req.Port
+	}
+	if req.PayloadType != 0 {
+		cfg.Talkback.PayloadType = req.PayloadType
+	}
+	if req.Device != "" {
+		cfg.Talkback.Device = req.Device
+	}
+	if req.Volume != nil {
+		cfg.Talkback.Volume = *req.Volume
+	}
+
+	// Configs written before talkback existed have no settings yet
+	defaults := config.DefaultConfig().Talkback
+	if cfg.Talkback.Port == 0 {
+		cfg.Talkback.Port = defaults.Port
+	}
+	if cfg.Talkback.PayloadType == 0 {
+		cfg.Talkback.PayloadType = defaults.PayloadType
+	}
+	if cfg.Talkback.Device == "" {
+		cfg.Talkback.Device = defaults.Device
+	}
+	if req.Volume == nil && cfg.Talkback.Volume == 0 {
+		cfg.Talkback.Volume = defaults.Volume
+	}
+
+	if err := h.config.Update(cfg); err != nil {
+		validationError(w, err)
+		return
+	}
+
+	h.ApplyTalkbackConfig()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.talkbackResponse())
+}
+
+// HandleTalkbackDevices handles GET /api/talkback/devices
+func (h *Handler) HandleTalkbackDevices(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(system.ListAudioDevices())
+}
@@ -0,0 +1,249 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"srtla-manager/internal/events"
+	"srtla-manager/internal/logger"
+	"srtla-manager/internal/process"
+	"srtla-manager/internal/tally"
+	"srtla-manager/internal/validate"
+)
+
+// tallyPollInterval is how often the pipeline source re-reads the pipeline
+const tallyPollInterval = time.Second
+
+// TallyResponse describes the tally light
+type TallyResponse struct {
+	Enabled     bool        `json:"enabled"`
+	Source      string      `json:"source"`
+	Output      string      `json:"output"`
+	State       tally.State `json:"state"`              // what the light shows
+	SourceState tally.State `json:"source_state"`       // what the source reports
+	Override    tally.State `json:"override,omitempty"` // forced from the API
+	LastError   string      `json:"last_error,omitempty"`
+}
+
+// TallyOverrideRequest forces the light, e.g. to test it
+type TallyOverrideRequest struct {
+	State string `json:"state"`
+}
+
+// ApplyTallyConfig restarts the tally source and output from the
+// configuration
+func (h *Handler) ApplyTallyConfig() {
+	cfg := h.config.Get().Tally
+
+	h.tallyMu.Lock()
+	defer h.tallyMu.Unlock()
+
+	if h.tallyCancel != nil {
+		h.tallyCancel()
+		h.tallyCancel = nil
+	}
+	if h.tallyLight != nil {
+		h.tallyLight.Close()
+		h.tallyLight = nil
+	}
+	h.tallySource = tally.Off
+	h.tallyOverride = ""
+	h.tallyError = ""
+	if !cfg.Enabled {
+		return
+	}
+
+	var light tally.Light
+	var err error
+	switch cfg.Output {
+	case tally.OutputSerial:
+		light, err = tally.NewSerialLight(cfg.SerialDevice, cfg.SerialBaud)
+	default:
+		light, err = tally.NewGPIOLight(cfg.GPIOPin, cfg.PreviewPin, cfg.ActiveLow)
+	}
+	if err != nil {
+		h.tallyError = err.Error()
+		logger.Error("Tally: %v", err)
+		return
+	}
+	h.tallyLight = light
+
+	ctx, cancel := context.WithCancel(context.Background())
+	h.tallyCancel = cancel
+
+	if cfg.Source == tally.SourceSwitcher {
+		// the switcher watcher feeds setTally; start from what it last reported
+		h.tallySource = h.SwitcherState()
+		if err := light.Set(h.tallySource); err != nil {
+			logger.Warn("Tally: %v", err)
+		}
+	} else {
+		go h.watchPipelineTally(ctx)
+	}
+
+	h.logOutput("manager", fmt.Sprintf("[TALLY] Driving %s tally from %s", cfg.Output, cfg.Source))
+}
+
+// StopTally turns the light off on shutdown
+func (h *Handler) StopTally() {
+	h.tallyMu.Lock()
+	defer h.tallyMu.Unlock()
+	if h.tallyCancel != nil {
+		h.tallyCancel()
+		h.tallyCancel = nil
+	}
+	if h.tallyLight != nil {
+		h.tallyLight.Close()
+		h.tallyLight = nil
+	}
+}
+
+// pipelineTally is program while the stream goes out and preview while a
+// camera feeds the receiver without sending
+func (h *Handler) pipelineTally() tally.State {
+	input := h.ffmpeg.Stats().State
+	live := input == process.FFmpegConnected || input == process.FFmpegStreaming
+	switch {
+	case live && h.GetPipelineMode() == PipelineModeStreaming:
+		return tally.Program
+	case live:
+		return tally.Preview
+	default:
+		return tally.Off
+	}
+}
+
+func (h *Handler) watchPipelineTally(ctx context.Context) {
+	ticker := time.NewTicker(tallyPollInterval)
+	defer ticker.Stop()
+	for {
+		h.setTally(h.pipelineTally())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// setTally records the source state and updates the light unless it is
+// overridden
+func (h *Handler) setTally(state tally.State) {
+	h.tallyMu.Lock()
+	changed := state != h.tallySource
+	h.tallySource = state
+	h.tallyError = "" // the source is reporting again
+	light := h.tallyLight
+	overridden := h.tallyOverride != ""
+	h.tallyMu.Unlock()
+
+	if !changed || light == nil {
+		return
+	}
+	if !overridden {
+		if err := light.Set(state); err != nil {
+			logger.Warn("Tally: %v", err)
+		}
+	}
+	events.Publish(h.bus, TopicTally, h.tallyResponse())
+}
+
+func (h *Handler) tallyResponse() TallyResponse {
+	cfg := h.config.Get().Tally
+
+	h.tallyMu.Lock()
+	defer h.tallyMu.Unlock()
+
+	resp := TallyResponse{
+		Enabled:     cfg.Enabled,
+		Source:      cfg.Source,
+		Output:      cfg.Output,
+		State:       h.tallySource,
+		SourceState: h.tallySource,
+		Override:    h.tallyOverride,
+		LastError:   h.tallyError,
+	}
+	if h.tallyOverride != "" {
+		resp.State = h.tallyOverride
+	}
+	if h.tallyLight == nil {
+		resp.State = tally.Off
+	}
+	return resp
+}
+
+// HandleTallyStatus handles GET /api/tally
+func (h *Handler) HandleTallyStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.tallyResponse())
+}
+
+// HandleTallyUpdate handles PUT /api/tally. Fields missing from the body keep
+// their current values.
+func (h *Handler) HandleTallyUpdate(w http.ResponseWriter, r *http.Request) {
+	cfg := h.config.Get()
+	if err := json.NewDecoder(r.Body).Decode(&cfg.Tally); err != nil {
+		jsonError(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.config.Update(cfg); err != nil {
+		validationError(w, err)
+		return
+	}
+
+	h.ApplyTallyConfig()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.tallyResponse())
+}
+
+// HandleTallyOverride handles PUT/DELETE /api/tally/override. A forced state
+// holds until it is cleared, so the light can be tested or cued by hand.
+func (h *Handler) HandleTallyOverride(w http.ResponseWriter, r *http.Request) {
+	var override tally.State
+	if r.Method == http.MethodPut {
+		var req TallyOverrideRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			jsonError(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+			return
+		}
+		v := validate.New()
+		v.Required("state", req.State)
+		v.OneOf("state", req.State, tally.States...)
+		if err := v.Err(); err != nil {
+			validationError(w, err)
+			return
+		}
+		override = tally.State(req.State)
+	}
+
+	h.tallyMu.Lock()
+	light := h.tallyLight
+	if light != nil {
+		h.tallyOverride = override
+	}
+	show := h.tallySource
+	h.tallyMu.Unlock()
+
+	if light == nil {
+		jsonError(w, "Tally is not enabled", http.StatusConflict)
+		return
+	}
+	if override != "" {
+		show = override
+	}
+	if err := light.Set(show); err != nil {
+		jsonError(w, fmt.Sprintf("Failed to set tally: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	resp := h.tallyResponse()
+	events.Publish(h.bus, TopicTally, resp)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,185 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"srtla-manager/internal/process"
+	"srtla-manager/internal/validate"
+)
+
+// testPatternPort is the loopback UDP port the test pattern is sent to for
+// ffmpeg to read in place of a camera
+const testPatternPort = 6190
+
+// Defaults of a test stream
+const (
+	defaultTestWidth   = 1280
+	defaultTestHeight  = 720
+	defaultTestFPS     = 30
+	defaultTestBitrate = 6000 // kbps, what DJI cameras are set to stream at
+	defaultTestToneHz  = 1000
+)
+
+// TestPatternRequest is the body of POST /api/stream/test. Zero values use
+// the defaults; profile and bind_set work as for a stream start.
+type TestPatternRequest struct {
+	StreamStartRequest
+	Width       int `json:"width"`
+	Height      int `json:"height"`
+	FPS         int `json:"fps"`
+	BitrateKbps int `json:"bitrate_kbps"`
+	ToneHz      int `json:"tone_hz"`
+}
+
+// TestPattern is the bars and tone being streamed instead of a camera
+type TestPattern struct {
+	Width       int           `json:"width"`
+	Height      int           `json:"height"`
+	FPS         int           `json:"fps"`
+	BitrateKbps int           `json:"bitrate_kbps"`
+	ToneHz      int           `json:"tone_hz"`
+	StartedAt   time.Time     `json:"started_at"`
+	Generator   process.State `json:"generator"`
+
+	relay *process.IngestRelay
+}
+
+// testPattern returns the test stream, nil when a camera is streamed
+func (h *Handler) testPattern() *TestPattern {
+	h.testMu.Lock()
+	defer h.testMu.Unlock()
+	if h.test == nil {
+		return nil
+	}
+	test := *h.test
+	test.Generator = test.relay.ProcessState()
+	return &test
+}
+
+// HandleTestPatternStart handles POST /api/stream/test. It streams SMPTE
+// bars with a sine tone through ffmpeg, the bond and the receiver like a
+// camera would, to check the links and the bitrate they carry before an
+// event. It is stopped like any stream.
+func (h *Handler) HandleTestPatternStart(w http.ResponseWriter, r *http.Request) {
+	var req TestPatternRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		jsonError(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+	if req.Width == 0 && req.Height == 0 {
+		req.Width, req.Height = defaultTestWidth, defaultTestHeight
+	}
+	if req.FPS == 0 {
+		req.FPS = defaultTestFPS
+	}
+	if req.BitrateKbps == 0 {
+		req.BitrateKbps = defaultTestBitrate
+	}
+	if req.ToneHz == 0 {
+		req.ToneHz = defaultTestToneHz
+	}
+	v := validate.New()
+	v.Resolution("width", "height", req.Width, req.Height)
+	v.FPS("fps", req.FPS)
+	v.Bitrate("bitrate_kbps", req.BitrateKbps)
+	v.Range("tone_hz", req.ToneHz, 20, 20000)
+	if err := v.Err(); err != nil {
+		validationError(w, err)
+		return
+	}
+
+	if h.GetPipelineMode() == PipelineModeStreaming {
+		jsonError(w, "Already streaming", http.StatusConflict)
+		return
+	}
+	h.failoverMu.Lock()
+	failover := h.failoverSwitch != nil
+	h.failoverMu.Unlock()
+	if failover {
+		jsonError(w, "The ingest failover is reading its sources; a test stream can't replace them", http.StatusConflict)
+		return
+	}
+
+	test := &TestPattern{
+		Width:       req.Width,
+		Height:      req.Height,
+		FPS:         req.FPS,
+		BitrateKbps: req.BitrateKbps,
+		ToneHz:      req.ToneHz,
+		StartedAt:   time.Now(),
+		relay:       process.NewIngestRelay("test"),
+	}
+	test.relay.SetBus(h.bus)
+	h.testMu.Lock()
+	if h.test != nil {
+		h.testMu.Unlock()
+		jsonError(w, "A test stream is already running", http.StatusConflict)
+		return
+	}
+	h.test = test
+	h.testMu.Unlock()
+
+	err := test.relay.StartTestPattern(process.TestPatternConfig{
+		Width:   req.Width,
+		Height:  req.Height,
+		FPS:     req.FPS,
+		Bitrate: req.BitrateKbps,
+		ToneHz:  req.ToneHz,
+	}, testPatternPort)
+	if err != nil {
+		h.stopTestPattern()
+		jsonError(w, fmt.Sprintf("Failed to start the test pattern: %v", err), http.StatusInternalServerError)
+		return
+	}
+	h.ffmpeg.SetSwitchedInput(fmt.Sprintf("udp://127.0.0.1:%d?fifo_size=100000&overrun_nonfatal=1", testPatternPort))
+
+	if code, err := h.startStreaming(r.Context(), req.StreamStartRequest); err != nil {
+		h.stopTestPattern()
+		if h.GetPipelineMode() == PipelineModeReceiving {
+			// receive mode was restored on the test input; listen again
+			if err := h.ffmpeg.Relisten(); err != nil {
+				h.logOutput("manager", fmt.Sprintf("[TEST] Failed to restart ffmpeg: %v", err))
+			}
+		}
+		pipelineError(w, err, code)
+		return
+	}
+	h.logOutput("manager", fmt.Sprintf("[TEST] Streaming bars and tone at %dx%d, %d kbps", req.Width, req.Height, req.BitrateKbps))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.testPattern())
+}
+
+// HandleTestPatternStop handles DELETE /api/stream/test
+func (h *Handler) HandleTestPatternStop(w http.ResponseWriter, r *http.Request) {
+	if h.testPattern() == nil {
+		jsonError(w, "No test stream running", http.StatusConflict)
+		return
+	}
+	if err := h.stopStreaming(r.Context()); err != nil {
+		pipelineError(w, err, http.StatusConflict)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "stopped"})
+}
+
+// stopTestPattern stops the test pattern and has ffmpeg listen for the
+// camera again the next time it starts. It does nothing without one.
+func (h *Handler) stopTestPattern() {
+	h.testMu.Lock()
+	test := h.test
+	h.test = nil
+	h.testMu.Unlock()
+	if test == nil {
+		return
+	}
+	_ = test.relay.Stop()
+	h.ffmpeg.SetSwitchedInput("")
+	h.logOutput("manager", "[TEST] Test stream stopped")
+}
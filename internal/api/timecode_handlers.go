@@ -0,0 +1,200 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"srtla-manager/internal/events"
+	"srtla-manager/internal/process"
+	"srtla-manager/internal/system"
+	"srtla-manager/internal/timecode"
+)
+
+const (
+	// ltcHoldover is how long timecode keeps running from the last LTC word
+	// when the signal drops out
+	ltcHoldover = 10 * time.Second
+	// ltcLockTimeout is how recent the last LTC word must be to count as locked
+	ltcLockTimeout = time.Second
+	// clockCheckInterval is how often NTP synchronisation is re-checked
+	clockCheckInterval = time.Minute
+)
+
+// TimecodeStatus describes the timecode published for the outbound stream
+type TimecodeStatus struct {
+	Enabled       bool       `json:"enabled"`
+	Source        string     `json:"source"`
+	FPS           int        `json:"fps"`
+	DropFrame     bool       `json:"drop_frame"`
+	Current       string     `json:"current,omitempty"`
+	Locked        bool       `json:"locked"`                    // NTP-synchronised clock or LTC heard within the last second
+	StreamStart   string     `json:"stream_start,omitempty"`    // timecode of the first frame sent
+	StreamStartAt *time.Time `json:"stream_start_at,omitempty"` // wall clock of the first frame sent
+	LastError     string     `json:"last_error,omitempty"`
+}
+
+// ApplyTimecodeConfig starts or stops LTC capture from the configuration
+func (h *Handler) ApplyTimecodeConfig() {
+	cfg := h.config.Get().Timecode
+
+	h.timecodeMu.Lock()
+	h.timecodeAnchorAt = time.Time{}
+	h.clockCheckedAt = time.Time{}
+	h.timecodeMu.Unlock()
+
+	if !cfg.Enabled || cfg.Source != timecode.SourceLTC {
+		h.ltc.Stop()
+	} else {
+		h.ltc.Start(cfg.LTCDevice)
+	}
+	if cfg.Enabled {
+		h.logOutput("manager", fmt.Sprintf("[TIMECODE] Publishing %d fps timecode from %s", cfg.FPS, cfg.Source))
+	}
+}
+
+// StopTimecode stops LTC capture on shutdown
+func (h *Handler) StopTimecode() {
+	h.ltc.Stop()
+}
+
+func timecodeRate(fps int, dropFrame bool) timecode.Rate {
+	return timecode.Rate{FPS: fps, DropFrame: dropFrame && fps == 30}
+}
+
+// timecodeAt is the timecode at wall clock t, false when it isn't known
+func (h *Handler) timecodeAt(t time.Time) (timecode.Timecode, bool) {
+	cfg := h.config.Get().Timecode
+	if !cfg.Enabled {
+		return timecode.Timecode{}, false
+	}
+	rate := timecodeRate(cfg.FPS, cfg.DropFrame)
+
+	if cfg.Source != timecode.SourceLTC {
+		return timecode.At(t, rate), true
+	}
+
+	last, at := h.ltc.Last()
+	if at.IsZero() || t.Sub(at) > ltcHoldover || at.Sub(t) > ltcHoldover {
+		return timecode.Timecode{}, false
+	}
+	// a word is complete as the frame it labels ends
+	frame := time.Duration(float64(time.Second) / rate.Frequency())
+	return last.Add(t.Sub(at)+frame, rate), true
+}
+
+// CurrentTimecode is the timecode right now, "" when it isn't known
+func (h *Handler) CurrentTimecode() string {
+	tc, ok := h.timecodeAt(time.Now())
+	if !ok {
+		return ""
+	}
+	return tc.String()
+}
+
+// timecodeLocked reports whether the timecode source is trustworthy
+func (h *Handler) timecodeLocked(source string) bool {
+	if source == timecode.SourceLTC {
+		_, at := h.ltc.Last()
+		return !at.IsZero() && time.Since(at) < ltcLockTimeout
+	}
+
+	h.timecodeMu.Lock()
+	defer h.timecodeMu.Unlock()
+	if time.Since(h.clockCheckedAt) > clockCheckInterval {
+		h.clockSynced = system.ClockSynchronized()
+		h.clockCheckedAt = time.Now()
+	}
+	return h.clockSynced
+}
+
+// streamStartTimecode is the timecode of the first frame of the running
+// outbound stream. It is taken once per stream and announced when taken.
+func (h *Handler) streamStartTimecode() (timecode.Timecode, time.Time, bool) {
+	first := h.ffmpeg.Stats().FirstFrame
+	if first.IsZero() || h.ffmpeg.Mode() != process.FFmpegModeStreaming {
+		return timecode.Timecode{}, time.Time{}, false
+	}
+
+	h.timecodeMu.Lock()
+	if h.timecodeAnchorAt.Equal(first) {
+		tc := h.timecodeAnchor
+		h.timecodeMu.Unlock()
+		return tc, first, true
+	}
+	h.timecodeMu.Unlock()
+
+	tc, ok := h.timecodeAt(first)
+	if !ok {
+		return timecode.Timecode{}, time.Time{}, false
+	}
+
+	h.timecodeMu.Lock()
+	h.timecodeAnchorAt = first
+	h.timecodeAnchor = tc
+	h.timecodeMu.Unlock()
+
+	h.logOutput("manager", fmt.Sprintf("[TIMECODE] Stream started at %s", tc))
+	events.Publish(h.bus, TopicTimecode, StreamTimecode{StreamStart: tc.String(), StreamStartAt: first})
+	return tc, first, true
+}
+
+func (h *Handler) timecodeStatus() *TimecodeStatus {
+	cfg := h.config.Get().Timecode
+	status := &TimecodeStatus{
+		Enabled:   cfg.Enabled,
+		Source:    cfg.Source,
+		FPS:       cfg.FPS,
+		DropFrame: cfg.DropFrame,
+	}
+	if !cfg.Enabled {
+		return status
+	}
+
+	status.Current = h.CurrentTimecode()
+	status.Locked = h.timecodeLocked(cfg.Source)
+	if cfg.Source == timecode.SourceLTC {
+		status.LastError = h.ltc.LastError()
+	}
+	if tc, at, ok := h.streamStartTimecode(); ok {
+		status.StreamStart = tc.String()
+		status.StreamStartAt = &at
+	}
+	return status
+}
+
+// statusTimecode is the timecode section of /api/status, left out when
+// timecode is disabled
+func (h *Handler) statusTimecode() *TimecodeStatus {
+	if !h.config.Get().Timecode.Enabled {
+		return nil
+	}
+	return h.timecodeStatus()
+}
+
+// HandleTimecodeStatus handles GET /api/timecode
+func (h *Handler) HandleTimecodeStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.timecodeStatus())
+}
+
+// HandleTimecodeUpdate handles PUT /api/timecode. Fields missing from the body
+// keep their current values.
+func (h *Handler) HandleTimecodeUpdate(w http.ResponseWriter, r *http.Request) {
+	cfg := h.config.Get()
+	if err := json.NewDecoder(r.Body).Decode(&cfg.Timecode); err != nil {
+		jsonError(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.config.Update(cfg); err != nil {
+		validationError(w, err)
+		return
+	}
+
+	h.ApplyTimecodeConfig()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.timecodeStatus())
+}
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"srtla-manager/internal/logger"
+	"srtla-manager/internal/system"
+	"srtla-manager/internal/tlscert"
+)
+
+// TLSStatus describes how the web UI is served
+type TLSStatus struct {
+	Enabled         bool          `json:"enabled"` // in the config
+	Serving         bool          `json:"serving"` // what the server was started with
+	RestartRequired bool          `json:"restart_required"`
+	CertFile        string        `json:"cert_file"`
+	KeyFile         string        `json:"key_file"`
+	Certificate     *tlscert.Info `json:"certificate,omitempty"`
+}
+
+// TLSUploadRequest is a PEM certificate (chain) and its private key
+type TLSUploadRequest struct {
+	Cert string `json:"cert"`
+	Key  string `json:"key"`
+}
+
+// SetTLSStore sets the certificate store and whether the server was started
+// with HTTPS
+func (h *Handler) SetTLSStore(store *tlscert.Store, serving bool) {
+	h.tlsStore = store
+	h.tlsServing = serving
+}
+
+// EnsureTLSCertificate loads the certificate, generating a self-signed one
+// on first boot
+func (h *Handler) EnsureTLSCertificate() error {
+	generated, err := h.tlsStore.EnsureSelfSigned(tlsHosts())
+	if err != nil {
+		return err
+	}
+	if generated {
+		info := h.tlsStore.Info()
+		logger.Info("Generated a self-signed TLS certificate (SHA256 %s)", info.SHA256)
+	}
+	return nil
+}
+
+// tlsHosts lists the names and addresses a generated certificate covers:
+// the hostname, localhost, the hotspot gateway and every interface address
+func tlsHosts() []string {
+	hosts := []string{"localhost", "127.0.0.1", "10.42.0.1"}
+	if name, err := os.Hostname(); err == nil && name != "" {
+		hosts = append(hosts, name, name+".local")
+	}
+
+	seen := map[string]bool{}
+	for _, h := range hosts {
+		seen[h] = true
+	}
+	for _, iface := range system.ListNetworkInterfaces() {
+		if iface.IsLoopback {
+			continue
+		}
+		for _, ip := range iface.IPs {
+			if !seen[ip] {
+				seen[ip] = true
+				hosts = append(hosts, ip)
+			}
+		}
+	}
+	return hosts
+}
+
+func (h *Handler) tlsStatus() TLSStatus {
+	cfg := h.config.Get().Web.TLS
+	return TLSStatus{
+		Enabled:         cfg.Enabled,
+		Serving:         h.tlsServing,
+		RestartRequired: cfg.Enabled != h.tlsServing,
+		CertFile:        cfg.CertFile,
+		KeyFile:         cfg.KeyFile,
+		Certificate:     h.tlsStore.Info(),
+	}
+}
+
+// HandleTLSStatus handles GET /api/system/tls
+func (h *Handler) HandleTLSStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.tlsStatus())
+}
+
+// HandleTLSUpload handles PUT /api/system/tls. The new certificate is served
+// to new connections straight away.
+func (h *Handler) HandleTLSUpload(w http.ResponseWriter, r *http.Request) {
+	var req TLSUploadRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, 256*1024)).Decode(&req); err != nil {
+		jsonError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Cert == "" || req.Key == "" {
+		jsonError(w, "cert and key are required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.tlsStore.Install([]byte(req.Cert), []byte(req.Key)); err != nil {
+		jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	status := h.tlsStatus()
+	h.logOutput("manager", fmt.Sprintf("[TLS] Installed certificate for %s (SHA256 %s)",
+		status.Certificate.Subject, status.Certificate.SHA256))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// HandleTLSSelfSigned handles POST /api/system/tls/self-signed, replacing the
+// certificate with a newly generated one
+func (h *Handler) HandleTLSSelfSigned(w http.ResponseWriter, r *http.Request) {
+	if err := h.tlsStore.GenerateSelfSigned(tlsHosts()); err != nil {
+		jsonError(w, fmt.Sprintf("Failed to generate certificate: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	status := h.tlsStatus()
+	h.logOutput("manager", fmt.Sprintf("[TLS] Generated self-signed certificate (SHA256 %s)", status.Certificate.SHA256))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"srtla-manager/internal/config"
+	"srtla-manager/internal/process"
+	"srtla-manager/internal/system"
+	"srtla-manager/internal/transport"
+)
+
+const (
+	// receiverProbeTTL is how long a receiver probe answer is reused
+	receiverProbeTTL = 5 * time.Minute
+	// receiverRetryTTL is how long an unanswered probe is reused
+	receiverRetryTTL = 30 * time.Second
+)
+
+// TransportResponse describes the configured and running bonding transport
+type TransportResponse struct {
+	Configured transport.Kind      `json:"configured"`
+	Active     transport.Kind      `json:"active,omitempty"`
+	Selected   transport.Kind      `json:"selected,omitempty"` // what the next start would use
+	Error      string              `json:"error,omitempty"`
+	Local      transport.Local     `json:"local"`
+	Receiver   *transport.Receiver `json:"receiver,omitempty"`
+}
+
+// HandleSRTLATransport handles GET /api/srtla/transport. Pass ?probe=true to
+// re-probe the receiver instead of using the cached result.
+func (h *Handler) HandleSRTLATransport(w http.ResponseWriter, r *http.Request) {
+	cfg := h.config.Get()
+	configured := configuredTransport(&cfg)
+
+	recv := h.probeReceiver(r.Context(), cfg.SRTLA.RemoteHost, cfg.SRTLA.RemotePort, r.URL.Query().Get("probe") == "true")
+	local := h.localTransports(&cfg)
+
+	resp := TransportResponse{
+		Configured: configured,
+		Active:     h.ActiveTransport(),
+		Local:      local,
+		Receiver:   &recv,
+	}
+	if kind, err := transport.Select(configured, local, recv); err != nil {
+		resp.Error = err.Error()
+	} else {
+		resp.Selected = kind
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// ActiveTransport returns the transport of the running bonding process, or
+// "" when it is stopped
+func (h *Handler) ActiveTransport() transport.Kind {
+	if h.srtla.ProcessState() == process.StateStopped {
+		return ""
+	}
+	h.transportMu.Lock()
+	defer h.transportMu.Unlock()
+	return h.transport
+}
+
+func (h *Handler) setActiveTransport(kind transport.Kind) {
+	h.transportMu.Lock()
+	h.transport = kind
+	h.transportMu.Unlock()
+}
+
+// resolveTransport picks the transport for the next start. Classic SRTLA needs
+// no receiver probe; everything else is checked against the receiver.
+func (h *Handler) resolveTransport(ctx context.Context, cfg *config.Config) (transport.Kind, error) {
+	configured := configuredTransport(cfg)
+	if configured == transport.SRTLA {
+		return transport.SRTLA, nil
+	}
+
+	recv := h.probeReceiver(ctx, cfg.SRTLA.RemoteHost, cfg.SRTLA.RemotePort, false)
+	kind, err := transport.Select(configured, h.localTransports(cfg), recv)
+	if err != nil {
+		return "", err
+	}
+	if configured == transport.Auto {
+		h.logOutput("manager", "[SRTLA] Auto-selected transport: "+string(kind))
+	}
+	return kind, nil
+}
+
+// probeReceiver returns the cached probe for host:port, probing again when it
+// is stale, the destination changed or force is set
+func (h *Handler) probeReceiver(ctx context.Context, host string, port int, force bool) transport.Receiver {
+	h.transportMu.Lock()
+	cached := h.receiver
+	h.transportMu.Unlock()
+
+	if !force && cached != nil && cached.Host == host && cached.Port == port {
+		ttl := receiverProbeTTL
+		if !cached.Reachable {
+			ttl = receiverRetryTTL
+		}
+		if time.Since(cached.ProbedAt) < ttl {
+			return *cached
+		}
+	}
+
+	recv := transport.Probe(ctx, host, port)
+
+	h.transportMu.Lock()
+	h.receiver = &recv
+	h.transportMu.Unlock()
+	return recv
+}
+
+// localTransports reports which transports this unit can send with. srtla2 is
+// only offered when the srtla_send version is known to support it.
+func (h *Handler) localTransports(cfg *config.Config) transport.Local {
+	caps := system.DetectSRTLA(cfg.SRTLA.BinaryPath)
+	return transport.Local{
+		SRTLA2:     caps.Version != "" && caps.Supports(system.SRTLAFeatureSRTLA2),
+		SRTBonding: system.FindSRTLiveTransmit() != "",
+	}
+}
+
+func configuredTransport(cfg *config.Config) transport.Kind {
+	if cfg.SRTLA.Transport == "" {
+		return transport.Auto
+	}
+	return transport.Kind(cfg.SRTLA.Transport)
+}
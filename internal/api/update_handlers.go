@@ -1,9 +1,11 @@
 package api
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -15,8 +17,12 @@ import (
 	"time"
 
 	internal "srtla-manager/internal"
+	"srtla-manager/internal/events"
+	"srtla-manager/internal/jobs"
 	"srtla-manager/internal/logger"
+	"srtla-manager/internal/tracing"
 	"srtla-manager/internal/updates"
+	"srtla-manager/internal/validate"
 )
 
 const (
@@ -55,12 +61,13 @@ type UpdateProgressResponse struct {
 	Status  string `json:"status"`
 	Message string `json:"message"`
 	Error   string `json:"error,omitempty"`
+	JobID   string `json:"job_id,omitempty"` // poll GET /api/jobs/{id} for progress
 }
 
 // HandleCheckUpdates checks for available updates (GET /api/updates/check)
 func (h *Handler) HandleCheckUpdates(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
@@ -73,13 +80,13 @@ func (h *Handler) HandleCheckUpdates(w http.ResponseWriter, r *http.Request) {
 	updateInfo, err := checker.CheckForUpdates()
 	if err != nil {
 		logger.Error("Failed to check for updates: %v", err)
-		http.Error(w, fmt.Sprintf("Failed to check for updates: %v", err), http.StatusInternalServerError)
+		jsonError(w, fmt.Sprintf("Failed to check for updates: %v", err), http.StatusInternalServerError)
 		return
 	}
 
 	if updateInfo == nil {
 		logger.Error("No update information available")
-		http.Error(w, "No update information available", http.StatusInternalServerError)
+		jsonError(w, "No update information available", http.StatusInternalServerError)
 		return
 	}
 
@@ -101,7 +108,7 @@ func (h *Handler) HandleCheckUpdates(w http.ResponseWriter, r *http.Request) {
 // HandleGetReleases gets recent releases (GET /api/updates/releases)
 func (h *Handler) HandleGetReleases(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
@@ -114,7 +121,7 @@ func (h *Handler) HandleGetReleases(w http.ResponseWriter, r *http.Request) {
 	releases, err := checker.GetAllReleases(20)
 	if err != nil {
 		logger.Error("Failed to fetch releases: %v", err)
-		http.Error(w, fmt.Sprintf("Failed to fetch releases: %v", err), http.StatusInternalServerError)
+		jsonError(w, fmt.Sprintf("Failed to fetch releases: %v", err), http.StatusInternalServerError)
 		return
 	}
 
@@ -127,47 +134,68 @@ func (h *Handler) HandleGetReleases(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(releases)
 }
 
+// validateVersion checks a release tag before it is used in download URLs and
+// file names.
+func validateVersion(version string) error {
+	v := validate.New()
+	v.Required("version", version)
+	v.Token("version", version)
+	return v.Err()
+}
+
 // HandlePerformUpdate performs the actual update (POST /api/updates/perform)
 func (h *Handler) HandlePerformUpdate(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
 	var req UpdateRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+		jsonError(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
 		return
 	}
 
-	if req.Version == "" {
-		http.Error(w, "Version is required", http.StatusBadRequest)
+	if err := validateVersion(req.Version); err != nil {
+		validationError(w, err)
 		return
 	}
 
 	// Set content type for streaming updates
 	w.Header().Set("Content-Type", "application/json")
 
+	// The installer restarts the service and the pipeline with it, so hold
+	// the lock for the lifetime of the job
+	release, err := h.acquirePipeline("update")
+	if err != nil {
+		pipelineError(w, err, http.StatusConflict)
+		return
+	}
+
 	// Perform update in background and stream progress
-	go performUpdate(req.Version, h)
+	job := h.jobs.Start("update", func(ctx context.Context, report *jobs.Reporter) (interface{}, error) {
+		defer release()
+		return nil, performUpdate(ctx, req.Version, h, report)
+	})
 
 	json.NewEncoder(w).Encode(UpdateProgressResponse{
 		Status:  "started",
 		Message: fmt.Sprintf("Starting update to %s", req.Version),
+		JobID:   job.ID,
 	})
 }
 
 // HandleGetBackups gets available backup versions (GET /api/updates/backups)
 func (h *Handler) HandleGetBackups(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
 	backups, err := getBackupsList()
 	if err != nil {
 		logger.Error("Failed to get backups: %v", err)
-		http.Error(w, fmt.Sprintf("Failed to get backups: %v", err), http.StatusInternalServerError)
+		jsonError(w, fmt.Sprintf("Failed to get backups: %v", err), http.StatusInternalServerError)
 		return
 	}
 
@@ -178,7 +206,7 @@ func (h *Handler) HandleGetBackups(w http.ResponseWriter, r *http.Request) {
 // HandleRollback restores a previous version (POST /api/updates/rollback)
 func (h *Handler) HandleRollback(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
@@ -187,25 +215,25 @@ func (h *Handler) HandleRollback(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+		jsonError(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
 		return
 	}
 
 	if req.Timestamp == 0 {
-		http.Error(w, "Timestamp is required", http.StatusBadRequest)
+		jsonError(w, "Timestamp is required", http.StatusBadRequest)
 		return
 	}
 
 	backupFile := filepath.Join(backupDir, fmt.Sprintf("srtla-manager.%d.bak", req.Timestamp))
 	if _, err := os.Stat(backupFile); err != nil {
-		http.Error(w, "Backup not found", http.StatusNotFound)
+		jsonError(w, "Backup not found", http.StatusNotFound)
 		return
 	}
 
 	// Perform rollback
 	if err := performRollback(backupFile); err != nil {
 		logger.Error("Rollback failed: %v", err)
-		http.Error(w, fmt.Sprintf("Rollback failed: %v", err), http.StatusInternalServerError)
+		jsonError(w, fmt.Sprintf("Rollback failed: %v", err), http.StatusInternalServerError)
 		return
 	}
 
@@ -216,27 +244,47 @@ func (h *Handler) HandleRollback(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// performUpdate downloads and installs the new version
-func performUpdate(version string, h *Handler) {
+// performUpdate downloads and installs the new version. It can be canceled
+// up to the point where the binary is handed to the installer.
+func performUpdate(ctx context.Context, version string, h *Handler, report *jobs.Reporter) error {
+	ctx, span := tracing.Start(ctx, "update.perform")
+	defer span.End()
+	span.SetAttribute("update.version", version)
+
+	committed := false // past the point where canceling is safe
+	p := h.newInstallProgress("update", report)
+	progress := func(pct int, phase, msg string) {
+		span.AddEvent(msg)
+		p.step(pct, phase, msg)
+	}
+	fail := func(msg string) error {
+		if err := ctx.Err(); err != nil && !committed {
+			p.send("error", "Update canceled")
+			return err
+		}
+		span.RecordError(errors.New(msg))
+		p.send("error", msg)
+		return errors.New(msg)
+	}
+
 	currentVersion := h.GetVersion()
 	if currentVersion == "" {
 		currentVersion = "v0.0.0-dev"
 	}
 
-	h.broadcastSRTLAInstallProgress("info", fmt.Sprintf("Starting update from %s to %s", currentVersion, version))
+	progress(0, "prepare", fmt.Sprintf("Starting update from %s to %s", currentVersion, version))
 
 	checker := updates.NewChecker(currentVersion)
 
 	// Get release info
-	h.broadcastSRTLAInstallProgress("info", "Fetching release information...")
+	progress(5, "prepare", "Fetching release information...")
 	releases, err := checker.GetAllReleases(100)
 	if err != nil {
-		h.broadcastSRTLAInstallProgress("error", fmt.Sprintf("Failed to fetch releases: %v", err))
-		return
+		return fail(fmt.Sprintf("Failed to fetch releases: %v", err))
 	}
 
 	// Find the target release
-	h.broadcastSRTLAInstallProgress("info", fmt.Sprintf("Looking for release %s", version))
+	progress(10, "prepare", fmt.Sprintf("Looking for release %s", version))
 	var targetRelease *updates.Release
 	for i := range releases {
 		if releases[i].TagName == version {
@@ -246,16 +294,14 @@ func performUpdate(version string, h *Handler) {
 	}
 
 	if targetRelease == nil {
-		h.broadcastSRTLAInstallProgress("error", fmt.Sprintf("Release %s not found", version))
-		return
+		return fail(fmt.Sprintf("Release %s not found", version))
 	}
 
 	// Create temp directory
-	h.broadcastSRTLAInstallProgress("info", "Creating temporary directory...")
+	progress(15, "prepare", "Creating temporary directory...")
 	tempDir, err := os.MkdirTemp("", "srtla-update-")
 	if err != nil {
-		h.broadcastSRTLAInstallProgress("error", fmt.Sprintf("Failed to create temp directory: %v", err))
-		return
+		return fail(fmt.Sprintf("Failed to create temp directory: %v", err))
 	}
 	defer os.RemoveAll(tempDir)
 
@@ -263,7 +309,7 @@ func performUpdate(version string, h *Handler) {
 	tempChecksum := filepath.Join(tempDir, "srtla-manager.sha256")
 
 	// Find download URLs
-	h.broadcastSRTLAInstallProgress("info", "Finding download URLs...")
+	progress(20, "prepare", "Finding download URLs...")
 	downloadURL := ""
 	checksumURL := ""
 	for _, asset := range targetRelease.Assets {
@@ -276,70 +322,77 @@ func performUpdate(version string, h *Handler) {
 	}
 
 	if downloadURL == "" {
-		h.broadcastSRTLAInstallProgress("error", "No download URL found for binary in release assets")
-		return
+		return fail("No download URL found for binary in release assets")
 	}
 
 	// Download binary
-	h.broadcastSRTLAInstallProgress("info", "Downloading binary...")
-	if err := downloadFile(downloadURL, tempBinary); err != nil {
-		h.broadcastSRTLAInstallProgress("error", fmt.Sprintf("Failed to download binary: %v", err))
-		return
+	progress(25, "download", "Downloading binary...")
+	_, dlSpan := tracing.Start(ctx, "update.download")
+	err = downloadFile(ctx, downloadURL, tempBinary, p.download(25, 60))
+	dlSpan.RecordError(err)
+	dlSpan.End()
+	if err != nil {
+		return fail(fmt.Sprintf("Failed to download binary: %v", err))
 	}
 
 	// Download and verify checksum if available
-	h.broadcastSRTLAInstallProgress("info", "Verifying checksum...")
+	progress(60, "verify", "Verifying checksum...")
 	if checksumURL != "" {
-		if err := downloadFile(checksumURL, tempChecksum); err == nil {
+		if err := downloadFile(ctx, checksumURL, tempChecksum, nil); err == nil {
 			if err := verifyChecksum(tempBinary, tempChecksum); err != nil {
-				h.broadcastSRTLAInstallProgress("error", fmt.Sprintf("Checksum verification failed: %v", err))
-				return
+				return fail(fmt.Sprintf("Checksum verification failed: %v", err))
 			}
 		}
 	}
 
 	// Create backup
-	h.broadcastSRTLAInstallProgress("info", "Creating backup of current binary...")
+	progress(70, "backup", "Creating backup of current binary...")
 	if err := os.MkdirAll(backupDir, 0755); err != nil {
-		h.broadcastSRTLAInstallProgress("error", fmt.Sprintf("Failed to create backup directory: %v", err))
-		return
+		return fail(fmt.Sprintf("Failed to create backup directory: %v", err))
 	}
 
 	backupFile := filepath.Join(backupDir, fmt.Sprintf("srtla-manager.%d.bak", time.Now().Unix()))
 	if err := copyFile(binPath, backupFile); err != nil {
-		h.broadcastSRTLAInstallProgress("error", fmt.Sprintf("Failed to create backup: %v", err))
-		return
+		return fail(fmt.Sprintf("Failed to create backup: %v", err))
+	}
+
+	// Last chance to cancel; the installer step can't be interrupted safely
+	if ctx.Err() != nil {
+		return fail("Update canceled")
 	}
+	committed = true
 
 	// Use privileged installer to handle binary replacement
-	h.broadcastSRTLAInstallProgress("info", "Requesting privileged binary update...")
+	progress(80, "install", "Requesting privileged binary update...")
+	_, installSpan := tracing.Start(ctx, "update.replace_binary")
 	updateResp, err := internal.UpdateBinaryWithInstaller(tempBinary, binPath, "srtla-manager", backupFile)
+	installSpan.RecordError(err)
+	installSpan.End()
 	if err != nil {
-		h.broadcastSRTLAInstallProgress("error", fmt.Sprintf("Failed to communicate with installer: %v", err))
-		return
+		return fail(fmt.Sprintf("Failed to communicate with installer: %v", err))
 	}
 
 	if !updateResp.Success {
-		h.broadcastSRTLAInstallProgress("error", fmt.Sprintf("Binary update failed: %s", updateResp.Error))
-		return
+		return fail(fmt.Sprintf("Binary update failed: %s", updateResp.Error))
 	}
 
-	h.broadcastSRTLAInstallProgress("info", "Binary replaced successfully, verifying service...")
+	progress(90, "verify", "Binary replaced successfully, verifying service...")
 
 	// Wait a moment and verify
 	time.Sleep(2 * time.Second)
-	h.broadcastSRTLAInstallProgress("info", "Verifying service is running...")
+	progress(95, "verify", "Verifying service is running...")
 	if err := exec.Command("sudo", "systemctl", "is-active", "--quiet", "srtla-manager").Run(); err != nil {
-		h.broadcastSRTLAInstallProgress("error", "Service failed to start after update, rolling back...")
+		err := fail("Service failed to start after update, rolling back...")
 		// Perform rollback via installer
 		internal.UpdateBinaryWithInstaller(backupFile, binPath, "srtla-manager", "")
-		return
+		return err
 	}
 
-	h.broadcastSRTLAInstallProgress("success", fmt.Sprintf("Successfully updated to version %s", version))
+	p.done(fmt.Sprintf("Successfully updated to version %s", version))
 
 	// Check for and perform srtla-installer updates if available
-	h.updateSrtlaInstallerIfNeeded()
+	h.updateSrtlaInstallerIfNeeded(p)
+	return nil
 }
 
 // performRollback restores a previous version
@@ -403,8 +456,14 @@ func getBackupsList() ([]BackupInfo, error) {
 
 // Helper functions
 
-func downloadFile(url, filepath string) error {
-	resp, err := http.Get(url)
+// downloadFile fetches url to filepath. onProgress, if set, is called as the
+// body arrives with the bytes so far and the length, or -1 when unknown.
+func downloadFile(ctx context.Context, url, filepath string, onProgress func(done, total int64)) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return err
 	}
@@ -420,10 +479,29 @@ func downloadFile(url, filepath string) error {
 	}
 	defer file.Close()
 
-	_, err = io.Copy(file, resp.Body)
+	var w io.Writer = file
+	if onProgress != nil {
+		w = &progressWriter{w: file, total: resp.ContentLength, fn: onProgress}
+	}
+	_, err = io.Copy(w, resp.Body)
 	return err
 }
 
+// progressWriter counts the bytes written through it
+type progressWriter struct {
+	w     io.Writer
+	done  int64
+	total int64
+	fn    func(done, total int64)
+}
+
+func (pw *progressWriter) Write(b []byte) (int, error) {
+	n, err := pw.w.Write(b)
+	pw.done += int64(n)
+	pw.fn(pw.done, pw.total)
+	return n, err
+}
+
 func verifyChecksum(filePath, checksumFile string) error {
 	checksumData, err := os.ReadFile(checksumFile)
 	if err != nil {
@@ -481,7 +559,7 @@ func hasSuffix(name, suffix string) bool {
 // HandleCheckSRTLASendUpdates checks for available srtla_send updates (GET /api/updates/srtla/check)
 func (h *Handler) HandleCheckSRTLASendUpdates(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
@@ -489,13 +567,13 @@ func (h *Handler) HandleCheckSRTLASendUpdates(w http.ResponseWriter, r *http.Req
 	updateInfo, err := checker.CheckForUpdates()
 	if err != nil {
 		logger.Error("Failed to check for srtla_send updates: %v", err)
-		http.Error(w, fmt.Sprintf("Failed to check for srtla_send updates: %v", err), http.StatusInternalServerError)
+		jsonError(w, fmt.Sprintf("Failed to check for srtla_send updates: %v", err), http.StatusInternalServerError)
 		return
 	}
 
 	if updateInfo == nil {
 		logger.Error("No srtla_send update information available")
-		http.Error(w, "No update information available", http.StatusInternalServerError)
+		jsonError(w, "No update information available", http.StatusInternalServerError)
 		return
 	}
 
@@ -517,7 +595,7 @@ func (h *Handler) HandleCheckSRTLASendUpdates(w http.ResponseWriter, r *http.Req
 // HandleGetSRTLASendReleases gets recent srtla_send releases (GET /api/updates/srtla/releases)
 func (h *Handler) HandleGetSRTLASendReleases(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
@@ -525,7 +603,7 @@ func (h *Handler) HandleGetSRTLASendReleases(w http.ResponseWriter, r *http.Requ
 	releases, err := checker.GetAllReleases(20)
 	if err != nil {
 		logger.Error("Failed to fetch srtla_send releases: %v", err)
-		http.Error(w, fmt.Sprintf("Failed to fetch srtla_send releases: %v", err), http.StatusInternalServerError)
+		jsonError(w, fmt.Sprintf("Failed to fetch srtla_send releases: %v", err), http.StatusInternalServerError)
 		return
 	}
 
@@ -541,44 +619,73 @@ func (h *Handler) HandleGetSRTLASendReleases(w http.ResponseWriter, r *http.Requ
 // HandleInstallSRTLASend downloads and installs srtla_send (POST /api/updates/srtla/install)
 func (h *Handler) HandleInstallSRTLASend(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
 	var req UpdateRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+		jsonError(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
 		return
 	}
 
-	if req.Version == "" {
-		http.Error(w, "Version is required", http.StatusBadRequest)
+	if err := validateVersion(req.Version); err != nil {
+		validationError(w, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 
+	release, err := h.acquirePipeline("srtla_install")
+	if err != nil {
+		pipelineError(w, err, http.StatusConflict)
+		return
+	}
+
 	// Perform installation in background
-	go h.performSRTLASendInstall(req.Version)
+	job := h.jobs.Start("srtla_install", func(ctx context.Context, report *jobs.Reporter) (interface{}, error) {
+		defer release()
+		return nil, h.performSRTLASendInstall(ctx, req.Version, report)
+	})
 
 	json.NewEncoder(w).Encode(UpdateProgressResponse{
 		Status:  "started",
 		Message: fmt.Sprintf("Downloading and installing srtla_send %s...", req.Version),
+		JobID:   job.ID,
 	})
 }
 
-// performSRTLASendInstall downloads and installs srtla_send
-func (h *Handler) performSRTLASendInstall(version string) {
-	h.broadcastSRTLAInstallProgress("info", fmt.Sprintf("Starting installation of srtla_send %s", version))
+// performSRTLASendInstall downloads and installs srtla_send. It can be
+// canceled until the package is handed to the installer.
+func (h *Handler) performSRTLASendInstall(ctx context.Context, version string, report *jobs.Reporter) error {
+	ctx, span := tracing.Start(ctx, "update.srtla_send_install")
+	defer span.End()
+	span.SetAttribute("update.version", version)
+
+	p := h.newInstallProgress("srtla_install", report)
+	progress := func(pct int, phase, msg string) {
+		span.AddEvent(msg)
+		p.step(pct, phase, msg)
+	}
+	fail := func(msg string) error {
+		if err := ctx.Err(); err != nil {
+			p.send("error", "Installation canceled")
+			return err
+		}
+		span.RecordError(errors.New(msg))
+		p.send("error", msg)
+		return errors.New(msg)
+	}
+
+	progress(0, "prepare", fmt.Sprintf("Starting installation of srtla_send %s", version))
 
 	checker := updates.NewSRTLASendChecker()
 
 	// Get releases to find the target version
-	h.broadcastSRTLAInstallProgress("info", "Fetching release information...")
+	progress(5, "prepare", "Fetching release information...")
 	releases, err := checker.GetAllReleases(100)
 	if err != nil {
-		h.broadcastSRTLAInstallProgress("error", fmt.Sprintf("Failed to fetch releases: %v", err))
-		return
+		return fail(fmt.Sprintf("Failed to fetch releases: %v", err))
 	}
 
 	var targetRelease *updates.Release
@@ -590,18 +697,16 @@ func (h *Handler) performSRTLASendInstall(version string) {
 	}
 
 	if targetRelease == nil {
-		h.broadcastSRTLAInstallProgress("error", fmt.Sprintf("Release %s not found", version))
-		return
+		return fail(fmt.Sprintf("Release %s not found", version))
 	}
 
 	// Detect system architecture
-	h.broadcastSRTLAInstallProgress("info", "Detecting system architecture...")
+	progress(10, "prepare", "Detecting system architecture...")
 	arch := detectArchitecture()
 	if arch == "" {
-		h.broadcastSRTLAInstallProgress("error", "Failed to detect system architecture")
-		return
+		return fail("Failed to detect system architecture")
 	}
-	h.broadcastSRTLAInstallProgress("info", fmt.Sprintf("Detected architecture: %s", arch))
+	progress(15, "prepare", fmt.Sprintf("Detected architecture: %s", arch))
 
 	// Find the appropriate .deb file
 	var debURL string
@@ -612,34 +717,44 @@ func (h *Handler) performSRTLASendInstall(version string) {
 	}
 
 	if debURL == "" {
-		h.broadcastSRTLAInstallProgress("error", fmt.Sprintf("No .deb package found for %s", arch))
-		return
+		return fail(fmt.Sprintf("No .deb package found for %s", arch))
 	}
 
-	h.broadcastSRTLAInstallProgress("info", fmt.Sprintf("Found package: %s", debURL))
+	progress(20, "prepare", fmt.Sprintf("Found package: %s", debURL))
 
 	// Create download directory
 	if err := os.MkdirAll(srtlaSendDownloadDir, 0755); err != nil {
-		h.broadcastSRTLAInstallProgress("error", fmt.Sprintf("Failed to create download directory: %v", err))
-		return
+		return fail(fmt.Sprintf("Failed to create download directory: %v", err))
 	}
 
 	// Download the .deb file
 	debFile := filepath.Join(srtlaSendDownloadDir, fmt.Sprintf("srtla_%s_%s.deb", strings.TrimPrefix(version, "v"), arch))
-	h.broadcastSRTLAInstallProgress("info", fmt.Sprintf("Downloading to %s...", debFile))
-	if err := downloadFile(debURL, debFile); err != nil {
-		h.broadcastSRTLAInstallProgress("error", fmt.Sprintf("Download failed: %v", err))
-		return
+	progress(25, "download", fmt.Sprintf("Downloading to %s...", debFile))
+	_, dlSpan := tracing.Start(ctx, "update.download")
+	err = downloadFile(ctx, debURL, debFile, p.download(25, 80))
+	dlSpan.RecordError(err)
+	dlSpan.End()
+	if err != nil {
+		return fail(fmt.Sprintf("Download failed: %v", err))
+	}
+	p.send("success", "Download complete!")
+
+	// Last chance to cancel; dpkg must not be interrupted
+	if ctx.Err() != nil {
+		return fail("Installation canceled")
 	}
-	h.broadcastSRTLAInstallProgress("success", "Download complete!")
 
 	// Install the package
-	h.broadcastSRTLAInstallProgress("info", "Installing package...")
-	if err := h.installDebPackage(debFile); err != nil {
-		h.broadcastSRTLAInstallProgress("error", fmt.Sprintf("Installation failed: %v", err))
-		return
+	progress(80, "install", "Installing package...")
+	_, installSpan := tracing.Start(ctx, "update.install_deb")
+	err = installDebPackage(p, debFile)
+	installSpan.RecordError(err)
+	installSpan.End()
+	if err != nil {
+		return fail(fmt.Sprintf("Installation failed: %v", err))
 	}
-	h.broadcastSRTLAInstallProgress("success", fmt.Sprintf("srtla_send %s installed successfully!", version))
+	p.done(fmt.Sprintf("srtla_send %s installed successfully!", version))
+	return nil
 }
 
 // detectArchitecture detects the system architecture
@@ -666,19 +781,19 @@ func detectArchitecture() string {
 }
 
 // installDebPackage installs a .deb package
-func (h *Handler) installDebPackage(debFile string) error {
-	h.broadcastSRTLAInstallProgress("info", "Attempting to install .deb package via privileged backend...")
+func installDebPackage(p *installProgress, debFile string) error {
+	p.send("info", "Attempting to install .deb package via privileged backend...")
 
 	resp, err := internal.InstallDebPackage(debFile)
 	if err != nil {
-		h.broadcastSRTLAInstallProgress("error", fmt.Sprintf("Privileged install error: %v", err))
+		p.send("error", fmt.Sprintf("Privileged install error: %v", err))
 		return fmt.Errorf("privileged install error: %w", err)
 	}
 
 	if resp.Success {
-		h.broadcastSRTLAInstallProgress("success", "Package installed successfully!")
+		p.send("success", "Package installed successfully!")
 		if resp.Output != "" {
-			h.broadcastSRTLAInstallProgress("info", resp.Output)
+			p.send("info", resp.Output)
 		}
 		return nil
 	}
@@ -687,80 +802,132 @@ func (h *Handler) installDebPackage(debFile string) error {
 	if msg == "" {
 		msg = "Unknown error from privileged installer."
 	}
-	h.broadcastSRTLAInstallProgress("error", msg)
+	p.send("error", msg)
 	return fmt.Errorf("privileged install failed: %s", msg)
 }
 
-// broadcastSRTLAInstallProgress broadcasts installation progress to connected clients
-func (h *Handler) broadcastSRTLAInstallProgress(level, message string) {
-	// Log to console/file
+// installProgress reports the progress of one update or install job, to the
+// job and as srtla_install messages carrying its ID, so clients can tell
+// concurrent operations apart
+type installProgress struct {
+	h       *Handler
+	report  *jobs.Reporter
+	jobID   string
+	kind    string
+	phase   string
+	percent int
+}
+
+func (h *Handler) newInstallProgress(kind string, report *jobs.Reporter) *installProgress {
+	return &installProgress{h: h, report: report, jobID: report.ID(), kind: kind}
+}
+
+// step starts a phase at percent, reporting msg
+func (p *installProgress) step(percent int, phase, msg string) {
+	p.phase, p.percent = phase, percent
+	p.report.Progress(percent, "%s", msg)
+	p.send("info", msg)
+}
+
+// done reports the job complete
+func (p *installProgress) done(msg string) {
+	p.phase, p.percent = "done", 100
+	p.report.Progress(100, "%s", msg)
+	p.send("success", msg)
+}
+
+// download returns a downloadFile callback spreading the download over
+// from to to percent, reporting each percent gained. Without a length from
+// the server it reports nothing.
+func (p *installProgress) download(from, to int) func(done, total int64) {
+	return func(done, total int64) {
+		if total <= 0 {
+			return
+		}
+		pct := from + int(int64(to-from)*min(done, total)/total)
+		if pct == p.percent {
+			return
+		}
+		p.percent = pct
+		msg := fmt.Sprintf("Downloaded %s of %s", formatBytes(done), formatBytes(total))
+		p.report.Progress(pct, "%s", msg)
+		p.send("info", msg)
+	}
+}
+
+// send logs a message and broadcasts it to connected clients at the
+// current phase and percentage
+func (p *installProgress) send(level, message string) {
 	switch level {
 	case "error":
-		logger.Error("[SRTLA_INSTALL] %s", message)
+		logger.Error("[SRTLA_INSTALL] %s: %s", p.jobID, message)
 	case "success":
-		logger.Info("[SRTLA_INSTALL] %s", message)
+		logger.Info("[SRTLA_INSTALL] %s: %s", p.jobID, message)
 	default:
-		logger.Printf("[SRTLA_INSTALL] %s", message)
+		logger.Printf("[SRTLA_INSTALL] %s: %s", p.jobID, message)
 	}
 
-	// Broadcast via websocket
-	if h.wsHub != nil {
-		h.wsHub.Broadcast("srtla_install", map[string]string{
-			"level":   level,
-			"message": message,
-		})
-	}
+	events.Publish(p.h.bus, TopicSRTLAInstall, InstallMessage{
+		JobID:   p.jobID,
+		Kind:    p.kind,
+		Phase:   p.phase,
+		Percent: p.percent,
+		Level:   level,
+		Message: message,
+	})
 }
 
-// updateSrtlaInstallerIfNeeded checks for and performs srtla-installer updates
-func (h *Handler) updateSrtlaInstallerIfNeeded() {
+// updateSrtlaInstallerIfNeeded checks for and performs srtla-installer
+// updates, reporting under the update's job
+func (h *Handler) updateSrtlaInstallerIfNeeded(p *installProgress) {
 	const installerPath = "/usr/local/bin/srtla-installer"
 
-	h.broadcastSRTLAInstallProgress("info", "Checking for srtla-installer updates...")
+	p.phase = "installer_update"
+	p.send("info", "Checking for srtla-installer updates...")
 
 	checker := updates.NewInstallerChecker("v0.0.0-dev") // Placeholder version
 	latestRelease, downloadURL, err := checker.GetLatestRelease()
 	if err != nil {
-		h.broadcastSRTLAInstallProgress("info", fmt.Sprintf("Unable to check installer updates: %v", err))
+		p.send("info", fmt.Sprintf("Unable to check installer updates: %v", err))
 		return
 	}
 
 	if latestRelease == nil || downloadURL == "" {
-		h.broadcastSRTLAInstallProgress("info", "No installer update available")
+		p.send("info", "No installer update available")
 		return
 	}
 
-	h.broadcastSRTLAInstallProgress("info", fmt.Sprintf("Found installer update: %s", latestRelease.TagName))
+	p.send("info", fmt.Sprintf("Found installer update: %s", latestRelease.TagName))
 
 	// Download the new installer
 	tempDir, err := os.MkdirTemp("", "srtla-installer-update-")
 	if err != nil {
-		h.broadcastSRTLAInstallProgress("error", fmt.Sprintf("Failed to create temp dir: %v", err))
+		p.send("error", fmt.Sprintf("Failed to create temp dir: %v", err))
 		return
 	}
 	defer os.RemoveAll(tempDir)
 
 	tempBinary := filepath.Join(tempDir, "srtla-installer")
-	h.broadcastSRTLAInstallProgress("info", "Downloading new srtla-installer...")
-	if err := downloadFile(downloadURL, tempBinary); err != nil {
-		h.broadcastSRTLAInstallProgress("error", fmt.Sprintf("Failed to download installer: %v", err))
+	p.send("info", "Downloading new srtla-installer...")
+	if err := downloadFile(context.Background(), downloadURL, tempBinary, nil); err != nil {
+		p.send("error", fmt.Sprintf("Failed to download installer: %v", err))
 		return
 	}
 
 	os.Chmod(tempBinary, 0755)
 
 	// Request the installer to update itself
-	h.broadcastSRTLAInstallProgress("info", "Requesting installer to self-update...")
+	p.send("info", "Requesting installer to self-update...")
 	resp, err := internal.UpdateInstallerSelf(tempBinary, installerPath)
 	if err != nil {
-		h.broadcastSRTLAInstallProgress("error", fmt.Sprintf("Failed to communicate with installer: %v", err))
+		p.send("error", fmt.Sprintf("Failed to communicate with installer: %v", err))
 		return
 	}
 
 	if !resp.Success {
-		h.broadcastSRTLAInstallProgress("error", fmt.Sprintf("Installer self-update failed: %s", resp.Error))
+		p.send("error", fmt.Sprintf("Installer self-update failed: %s", resp.Error))
 		return
 	}
 
-	h.broadcastSRTLAInstallProgress("success", fmt.Sprintf("srtla-installer updated to %s", latestRelease.TagName))
+	p.send("success", fmt.Sprintf("srtla-installer updated to %s", latestRelease.TagName))
 }
@@ -0,0 +1,243 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"time"
+
+	"srtla-manager/internal/upload"
+	"srtla-manager/internal/validate"
+)
+
+const (
+	// uploadDir is the quarantine uploads wait in until they are installed
+	uploadDir = "/opt/srtla-manager/uploads"
+	// maxUploadChunk limits the body of one chunk
+	maxUploadChunk = 8 << 20
+	// uploadExpiry is how long an upload nothing is written to is kept
+	uploadExpiry = 24 * time.Hour
+)
+
+var (
+	sha256Pattern      = regexp.MustCompile(`^[0-9a-fA-F]{64}$`)
+	debPackagePattern  = regexp.MustCompile(`^[a-z0-9][a-z0-9+.-]+$`)
+	errUploadsDisabled = errors.New("uploads are unavailable")
+)
+
+// UploadCreateRequest is the body of POST /api/system/uploads
+type UploadCreateRequest struct {
+	Filename string `json:"filename"`
+	Size     int64  `json:"size"`
+	SHA256   string `json:"sha256"`
+	Package  string `json:"package"` // name the .deb must declare
+}
+
+// uploadStore opens the quarantine on first use
+func (h *Handler) uploadStore() (*upload.Store, error) {
+	h.uploadsMu.Lock()
+	defer h.uploadsMu.Unlock()
+	if h.uploads == nil {
+		store, err := upload.Open(uploadDir, 0)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", errUploadsDisabled, err)
+		}
+		h.uploads = store
+	}
+	return h.uploads, nil
+}
+
+// HandleUploadCreate handles POST /api/system/uploads, starting a chunked
+// upload of a .deb
+func (h *Handler) HandleUploadCreate(w http.ResponseWriter, r *http.Request) {
+	var req UploadCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+	v := validate.New()
+	v.Required("filename", req.Filename)
+	if req.Filename != "" && filepath.Ext(req.Filename) != ".deb" {
+		v.Addf("filename", "must be a .deb file")
+	}
+	if !sha256Pattern.MatchString(req.SHA256) {
+		v.Addf("sha256", "must be the file's SHA-256 in hex")
+	}
+	v.Required("package", req.Package)
+	if req.Package != "" && !debPackagePattern.MatchString(req.Package) {
+		v.Addf("package", "must be a Debian package name")
+	}
+	if req.Size <= 0 {
+		v.Addf("size", "must be the file's size in bytes")
+	}
+	if err := v.Err(); err != nil {
+		validationError(w, err)
+		return
+	}
+
+	store, err := h.uploadStore()
+	if err != nil {
+		jsonError(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	store.Expire(uploadExpiry)
+	u, err := store.Create(req.Filename, req.Size, req.SHA256, req.Package)
+	if err != nil {
+		jsonError(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	h.logOutput("manager", fmt.Sprintf("[UPLOAD] Receiving %s (%d bytes) as %s", u.Filename, u.Size, u.ID))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(u)
+}
+
+// lookupUpload returns the store and the upload named in the path, writing
+// the error when there is none
+func (h *Handler) lookupUpload(w http.ResponseWriter, r *http.Request) (*upload.Store, upload.Upload, bool) {
+	store, err := h.uploadStore()
+	if err != nil {
+		jsonError(w, err.Error(), http.StatusServiceUnavailable)
+		return nil, upload.Upload{}, false
+	}
+	u, ok := store.Get(r.PathValue("id"))
+	if !ok {
+		jsonError(w, upload.ErrNotFound.Error(), http.StatusNotFound)
+		return nil, upload.Upload{}, false
+	}
+	return store, u, true
+}
+
+// HandleUploadStatus handles GET /api/system/uploads/{id}, telling a client
+// resuming an upload where to go on from
+func (h *Handler) HandleUploadStatus(w http.ResponseWriter, r *http.Request) {
+	_, u, ok := h.lookupUpload(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Upload-Offset", strconv.FormatInt(u.Offset, 10))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(u)
+}
+
+// HandleUploadChunk handles PATCH /api/system/uploads/{id}. The body is the
+// next chunk, of up to 8 MiB, and the Upload-Offset header where it starts
+// in the file. A chunk that doesn't start where the upload stopped is
+// refused with 409 and the offset to go on from.
+func (h *Handler) HandleUploadChunk(w http.ResponseWriter, r *http.Request) {
+	store, u, ok := h.lookupUpload(w, r)
+	if !ok {
+		return
+	}
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		jsonError(w, "Upload-Offset must be the chunk's offset in bytes", http.StatusBadRequest)
+		return
+	}
+
+	u, err = store.Write(u.ID, offset, http.MaxBytesReader(w, r.Body, maxUploadChunk))
+	w.Header().Set("Upload-Offset", strconv.FormatInt(u.Offset, 10))
+	var offErr *upload.OffsetError
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &offErr):
+		writeError(w, ErrorResponse{Error: err.Error(), Code: http.StatusConflict, Details: u})
+		return
+	case errors.Is(err, upload.ErrBusy):
+		writeError(w, ErrorResponse{Error: err.Error(), Code: http.StatusConflict, Details: u})
+		return
+	case errors.Is(err, upload.ErrTooLarge), errors.As(err, &tooLarge):
+		writeError(w, ErrorResponse{Error: err.Error(), Code: http.StatusRequestEntityTooLarge, Details: u})
+		return
+	case err != nil:
+		// what arrived before the connection dropped is kept
+		writeError(w, ErrorResponse{Error: err.Error(), Code: http.StatusBadRequest, Details: u})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(u)
+}
+
+// HandleUploadDelete handles DELETE /api/system/uploads/{id}
+func (h *Handler) HandleUploadDelete(w http.ResponseWriter, r *http.Request) {
+	store, u, ok := h.lookupUpload(w, r)
+	if !ok {
+		return
+	}
+	if err := store.Remove(u.ID); err != nil {
+		jsonError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleUploadInstall handles POST /api/system/uploads/{id}/install. The
+// upload must be complete, match its checksum and declare the package it
+// was created for before the privileged installer gets it. It is removed
+// once installed, or when it turns out not to be the file declared.
+func (h *Handler) HandleUploadInstall(w http.ResponseWriter, r *http.Request) {
+	store, u, ok := h.lookupUpload(w, r)
+	if !ok {
+		return
+	}
+
+	path, err := store.Verify(u.ID)
+	var sumErr *upload.ChecksumError
+	switch {
+	case errors.Is(err, upload.ErrIncomplete):
+		jsonError(w, fmt.Sprintf("Upload is not complete: %d of %d bytes", u.Offset, u.Size), http.StatusConflict)
+		return
+	case errors.As(err, &sumErr):
+		store.Remove(u.ID)
+		h.logOutput("manager", fmt.Sprintf("[UPLOAD] Rejected %s: %v", u.Filename, err))
+		jsonError(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	case err != nil:
+		jsonError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	pkg, err := upload.DebPackage(path)
+	if err != nil || pkg != u.Package {
+		store.Remove(u.ID)
+		msg := fmt.Sprintf("package is %q, not %q", pkg, u.Package)
+		if err != nil {
+			msg = err.Error()
+		}
+		h.logOutput("manager", fmt.Sprintf("[UPLOAD] Rejected %s: %s", u.Filename, msg))
+		jsonError(w, msg, http.StatusUnprocessableEntity)
+		return
+	}
+
+	// the installer only takes paths ending in .deb
+	path, err = store.Stage(u.ID)
+	if err != nil {
+		jsonError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.logOutput("manager", fmt.Sprintf("[UPLOAD] Installing %s (%s)", u.Filename, pkg))
+	resp, err := h.installDeb(path)
+	if err != nil {
+		jsonError(w, "Install error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if resp.Success {
+		store.Remove(u.ID)
+	} else {
+		h.logOutput("manager", fmt.Sprintf("[UPLOAD] Installing %s failed: %s", u.Filename, resp.Error))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(InstallDebResponse{
+		Success: resp.Success,
+		Output:  resp.Output,
+		Error:   resp.Error,
+	})
+}
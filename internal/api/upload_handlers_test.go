@@ -0,0 +1,95 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"srtla-manager/internal"
+	"srtla-manager/internal/upload"
+)
+
+// buildDeb builds a minimal package named pkg and returns its contents
+func buildDeb(t *testing.T, pkg string) []byte {
+	t.Helper()
+	if _, err := exec.LookPath("dpkg-deb"); err != nil {
+		t.Skip("dpkg-deb not installed")
+	}
+	root := filepath.Join(t.TempDir(), pkg)
+	if err := os.MkdirAll(filepath.Join(root, "DEBIAN"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	control := "Package: " + pkg + "\nVersion: 1.0\nArchitecture: all\nMaintainer: test <test@example.com>\nDescription: test\n"
+	if err := os.WriteFile(filepath.Join(root, "DEBIAN", "control"), []byte(control), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out := root + ".deb"
+	if output, err := exec.Command("dpkg-deb", "--build", root, out).CombinedOutput(); err != nil {
+		t.Fatalf("dpkg-deb: %v: %s", err, output)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// installerCheck refuses a path the way srtla-installer's handleConn does
+// before running dpkg on it
+func installerCheck(path string) (internal.InstallResponse, error) {
+	if !strings.HasSuffix(path, ".deb") {
+		return internal.InstallResponse{Success: false, Error: "Invalid .deb file path"}, nil
+	}
+	if _, err := os.Stat(path); err != nil {
+		return internal.InstallResponse{Success: false, Error: "File not found: " + err.Error()}, nil
+	}
+	return internal.InstallResponse{Success: true}, nil
+}
+
+func TestUploadInstall(t *testing.T) {
+	deb := buildDeb(t, "srtla-send")
+	sum := sha256.Sum256(deb)
+
+	store, err := upload.Open(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := store.Create("srtla-send_1.0_all.deb", int64(len(deb)), hex.EncodeToString(sum[:]), "srtla-send")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Write(u.ID, 0, strings.NewReader(string(deb))); err != nil {
+		t.Fatal(err)
+	}
+
+	var installed string
+	h := &Handler{uploads: store, installDeb: func(path string) (internal.InstallResponse, error) {
+		installed = path
+		return installerCheck(path)
+	}}
+	req := httptest.NewRequest(http.MethodPost, "/api/system/uploads/"+u.ID+"/install", nil)
+	req.SetPathValue("id", u.ID)
+	rec := httptest.NewRecorder()
+	h.HandleUploadInstall(rec, req)
+
+	var resp InstallDebResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusOK || !resp.Success {
+		t.Fatalf("Expected the installer to accept the upload, got %d %+v for %q", rec.Code, resp, installed)
+	}
+	if _, ok := store.Get(u.ID); ok {
+		t.Error("Expected the upload removed once installed")
+	}
+	if _, err := os.Stat(installed); !os.IsNotExist(err) {
+		t.Errorf("Expected the staged package removed, got %v", err)
+	}
+}
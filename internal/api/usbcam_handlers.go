@@ -2,14 +2,17 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os/exec"
 	"strings"
 
+	"srtla-manager/internal/config"
 	"srtla-manager/internal/process"
 	"srtla-manager/internal/usbcam"
+	"srtla-manager/internal/validate"
 )
 
 // USBCameraListResponse is the response for listing USB cameras
@@ -112,33 +115,62 @@ func (h *Handler) HandleUSBCameraStart(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Apply defaults
-	if req.Width == 0 {
-		req.Width = 1920
-	}
-	if req.Height == 0 {
-		req.Height = 1080
-	}
-	if req.FPS == 0 {
-		req.FPS = 30
-	}
-	if req.Bitrate == 0 {
-		req.Bitrate = 6000
+	if err := validateUSBCameraStart(req); err != nil {
+		validationError(w, err)
+		return
 	}
-	if req.Encoder == "" {
-		req.Encoder = "libx264"
+
+	release, err := h.acquirePipeline("usbcam_start")
+	if err != nil {
+		pipelineError(w, err, http.StatusConflict)
+		return
 	}
+	defer release()
 
-	// Validate encoder
-	if !usbcam.ValidateEncoder(req.Encoder) {
-		jsonError(w, "invalid encoder: "+req.Encoder, http.StatusBadRequest)
+	if err := h.startUSBCamera(cameraID, req); err != nil {
+		if errors.Is(err, errStreamActive) || errors.Is(err, errFailoverSource) {
+			jsonError(w, err.Error(), http.StatusConflict)
+			return
+		}
+		jsonError(w, "failed to start streaming: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	state := h.usbCamController.GetCameraState(cameraID)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(state)
+}
+
+// errStreamActive is returned when a USB camera cannot take over the pipeline
+var errStreamActive = errors.New("another stream is already active")
+
+// errFailoverSource is returned when the failover is capturing the USB camera
+var errFailoverSource = errors.New("camera is a failover source")
+
+func validateUSBCameraStart(req USBCameraStartRequest) error {
+	v := validate.New()
+	v.Resolution("width", "height", req.Width, req.Height)
+	v.FPS("fps", req.FPS)
+	v.Bitrate("bitrate", req.Bitrate)
+	if req.Encoder != "" && !usbcam.ValidateEncoder(req.Encoder) {
+		v.Addf("encoder", "'%s' is not a supported encoder", req.Encoder)
+	}
+	return v.Err()
+}
+
+// startUSBCamera hands the pipeline to a USB camera. Settings missing from
+// req come from the camera's saved usb_cameras entry, then the defaults.
+// The caller must hold the pipeline lock.
+func (h *Handler) startUSBCamera(cameraID string, req USBCameraStartRequest) error {
+	cfg := h.config.Get()
+	req = usbStartDefaults(req, cfg.USBCameras[cameraID])
+
 	// Check if we're already streaming
 	if h.GetPipelineMode() == PipelineModeStreaming {
-		jsonError(w, "another stream is already active", http.StatusConflict)
-		return
+		return errStreamActive
+	}
+	if h.failoverUsesUSB(cameraID) {
+		return errFailoverSource
 	}
 
 	// Stop FFmpeg (receive-only mode) so USB capture can take over
@@ -146,8 +178,6 @@ func (h *Handler) HandleUSBCameraStart(w http.ResponseWriter, r *http.Request) {
 	_ = h.ffmpeg.Stop()
 	_ = h.srtla.Stop()
 
-	cfg := h.config.Get()
-
 	// Build stream config
 	streamConfig := &usbcam.StreamConfig{
 		Width:   req.Width,
@@ -158,44 +188,107 @@ func (h *Handler) HandleUSBCameraStart(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Start SRTLA if enabled and bind IPs are available
-	srtPort := 0
-	srtlaStarted := false
-	if cfg.SRTLA.Enabled {
-		bindIPs := h.getAvailableBindIPs(&cfg)
-		if len(bindIPs) > 0 {
-			if err := h.startSRTLA(&cfg, bindIPs); err != nil {
-				h.logOutput("usbcam", fmt.Sprintf("[USBCam] Warning: Failed to start SRTLA: %v (continuing without outbound streaming)", err))
-			} else {
-				srtPort = cfg.SRT.LocalPort
-				srtlaStarted = true
-				h.activeBindIPs = bindIPs
-			}
-		} else {
-			h.logOutput("usbcam", "[USBCam] No bind IPs available, starting capture without outbound streaming")
-		}
-	}
+	srtPort := h.startCaptureBonding(&cfg, "usbcam", "[USBCam]")
+	srtlaStarted := srtPort > 0
 
 	// Start USB camera capture (srtPort=0 means capture + preview only, no outbound SRT)
-	if err := h.usbCamController.StartStreaming(cameraID, streamConfig, srtPort, h.previewDir); err != nil {
+	previewDir := h.previewDir
+	if srtPort > 0 {
+		previewDir = h.streamPreviewDir()
+	}
+	if err := h.usbCamController.StartStreaming(cameraID, streamConfig, srtPort, previewDir); err != nil {
 		if srtlaStarted {
 			_ = h.srtla.Stop()
+			_ = h.srtProbe.Stop()
 		}
 		_ = h.StartReceiveMode() // restore receive mode
-		jsonError(w, "failed to start streaming: "+err.Error(), http.StatusInternalServerError)
-		return
+		return err
 	}
 
 	if srtlaStarted {
 		h.SetPipelineMode(PipelineModeStreaming)
+		h.startBitrateControl()
 	} else {
 		h.SetPipelineMode(PipelineModeReceiving)
 	}
 
 	h.logOutput("usbcam", "[USBCam] Started streaming from camera "+cameraID)
+	return nil
+}
 
-	state := h.usbCamController.GetCameraState(cameraID)
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(state)
+// usbStartDefaults fills the settings missing from req from the camera's
+// saved settings, then the defaults
+func usbStartDefaults(req USBCameraStartRequest, saved config.USBCameraConfig) USBCameraStartRequest {
+	if req.Width == 0 && req.Height == 0 {
+		req.Width, req.Height = saved.Width, saved.Height
+	}
+	if req.FPS == 0 {
+		req.FPS = saved.FPS
+	}
+	if req.Bitrate == 0 {
+		req.Bitrate = saved.Bitrate
+	}
+	if req.Encoder == "" {
+		req.Encoder = saved.Encoder
+	}
+	if req.Width == 0 {
+		req.Width = 1920
+	}
+	if req.Height == 0 {
+		req.Height = 1080
+	}
+	if req.FPS == 0 {
+		req.FPS = 30
+	}
+	if req.Bitrate == 0 {
+		req.Bitrate = 6000
+	}
+	if req.Encoder == "" {
+		req.Encoder = "libx264"
+	}
+	return req
+}
+
+// startCaptureBonding starts SRTLA for a camera captured by ffmpeg itself
+// and returns the port to send to, or 0 to capture for the preview only
+// when bonding is off or can't start
+func (h *Handler) startCaptureBonding(cfg *config.Config, source, tag string) int {
+	if !cfg.SRTLA.Enabled {
+		return 0
+	}
+	bindIPs := h.getAvailableBindIPs(cfg)
+	if len(bindIPs) == 0 {
+		h.logOutput(source, tag+" No bind IPs available, starting capture without outbound streaming")
+		return 0
+	}
+	if err := h.startSRTLA(cfg, bindIPs); err != nil {
+		h.logOutput(source, fmt.Sprintf("%s Warning: Failed to start SRTLA: %v (continuing without outbound streaming)", tag, err))
+		return 0
+	}
+	h.activeBindIPs = bindIPs
+	return h.startSRTProbe(cfg)
+}
+
+// stopUSBCamera stops a USB camera and restores receive mode. The caller must
+// hold the pipeline lock.
+func (h *Handler) stopUSBCamera(cameraID string) error {
+	if err := h.usbCamController.StopStreaming(cameraID); err != nil {
+		return err
+	}
+
+	// Stop FFmpeg and SRTLA
+	_ = h.ffmpeg.Stop()
+	_ = h.srtla.Stop()
+	_ = h.srtProbe.Stop()
+
+	h.logOutput("usbcam", "[USBCam] Stopped streaming from camera "+cameraID)
+
+	// Restore receive mode
+	if err := h.StartReceiveMode(); err != nil {
+		h.logOutput("usbcam", fmt.Sprintf("[USBCam] Warning: Failed to restore receive mode: %v", err))
+		h.SetPipelineMode(PipelineModeIdle)
+	}
+	return nil
 }
 
 // HandleUSBCameraStop stops streaming from a USB camera
@@ -212,22 +305,16 @@ func (h *Handler) HandleUSBCameraStop(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Stop the camera streaming
-	if err := h.usbCamController.StopStreaming(cameraID); err != nil {
-		jsonError(w, "failed to stop streaming: "+err.Error(), http.StatusInternalServerError)
+	release, err := h.acquirePipeline("usbcam_stop")
+	if err != nil {
+		pipelineError(w, err, http.StatusConflict)
 		return
 	}
+	defer release()
 
-	// Stop FFmpeg and SRTLA
-	_ = h.ffmpeg.Stop()
-	_ = h.srtla.Stop()
-
-	h.logOutput("usbcam", "[USBCam] Stopped streaming from camera "+cameraID)
-
-	// Restore receive mode
-	if err := h.StartReceiveMode(); err != nil {
-		h.logOutput("usbcam", fmt.Sprintf("[USBCam] Warning: Failed to restore receive mode: %v", err))
-		h.SetPipelineMode(PipelineModeIdle)
+	if err := h.stopUSBCamera(cameraID); err != nil {
+		jsonError(w, "failed to stop streaming: "+err.Error(), http.StatusInternalServerError)
+		return
 	}
 
 	state := h.usbCamController.GetCameraState(cameraID)
@@ -286,12 +373,8 @@ func (h *Handler) HandleUSBCameraPreview(w http.ResponseWriter, r *http.Request)
 
 	camera := cameraState.Camera
 
-	// Stop any existing preview before starting a new one
-	if h.ffmpeg.GetPreviewPort(cameraID) > 0 {
-		_ = h.ffmpeg.StopPreview(cameraID)
-	}
-
-	// Parse request body to get desired resolution and bitrate
+	// Parse request body to get desired resolution and bitrate (all optional;
+	// a resolution the camera doesn't offer falls back to auto-select below)
 	type PreviewRequest struct {
 		Width   int `json:"width"`
 		Height  int `json:"height"`
@@ -299,19 +382,22 @@ func (h *Handler) HandleUSBCameraPreview(w http.ResponseWriter, r *http.Request)
 		Bitrate int `json:"bitrate"` // kbps, optional
 	}
 	var req PreviewRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err == nil && req.Width > 0 && req.Height > 0 {
-		// User provided specific resolution - validate it
-		foundFormat := false
-		for i := range camera.Formats {
-			if camera.Formats[i].Width == req.Width &&
-				camera.Formats[i].Height == req.Height {
-				foundFormat = true
-				break
-			}
-		}
-		if !foundFormat {
-			// Format not found - allow anyway and let FFmpeg handle it
-		}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		jsonError(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	v := validate.New()
+	v.Resolution("width", "height", req.Width, req.Height)
+	v.FPS("fps", req.FPS)
+	v.Bitrate("bitrate", req.Bitrate)
+	if err := v.Err(); err != nil {
+		validationError(w, err)
+		return
+	}
+
+	// Stop any existing preview before starting a new one
+	if h.ffmpeg.GetPreviewPort(cameraID) > 0 {
+		_ = h.ffmpeg.StopPreview(cameraID)
 	}
 
 	// Select resolution and format - prioritize MJPEG for reliable previews
@@ -395,6 +481,7 @@ func (h *Handler) HandleUSBCameraPreview(w http.ResponseWriter, r *http.Request)
 		InputFormat: inputFormat,
 		SRTPort:     0,
 		HLSDir:      "",
+		MaxFPS:      h.PowerProfile().PreviewFPS,
 	})
 	if err != nil {
 		jsonError(w, "failed to start preview: "+err.Error(), http.StatusInternalServerError)
@@ -416,14 +503,19 @@ func (h *Handler) HandleUSBCameraPreview(w http.ResponseWriter, r *http.Request)
 func (h *Handler) HandleUSBCameraPreviewStream(w http.ResponseWriter, r *http.Request) {
 	cameraID := r.PathValue("id")
 	if cameraID == "" {
-		http.Error(w, "camera ID required", http.StatusBadRequest)
+		jsonError(w, "camera ID required", http.StatusBadRequest)
 		return
 	}
 
+	h.proxyUSBCameraPreview(w, cameraID)
+}
+
+// proxyUSBCameraPreview relays the MJPEG broadcast of a camera to the client
+func (h *Handler) proxyUSBCameraPreview(w http.ResponseWriter, cameraID string) {
 	// Get the port where the broadcast server is running for this camera
 	port := h.ffmpeg.GetPreviewPort(cameraID)
 	if port == 0 {
-		http.Error(w, "preview not active", http.StatusNotFound)
+		jsonError(w, "preview not active", http.StatusNotFound)
 		return
 	}
 
@@ -431,7 +523,7 @@ func (h *Handler) HandleUSBCameraPreviewStream(w http.ResponseWriter, r *http.Re
 	targetURL := fmt.Sprintf("http://127.0.0.1:%d/stream-%d", port, port)
 	resp, err := http.Get(targetURL)
 	if err != nil {
-		http.Error(w, "failed to connect to preview stream: "+err.Error(), http.StatusInternalServerError)
+		jsonError(w, "failed to connect to preview stream: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 	defer resp.Body.Close()
@@ -474,20 +566,20 @@ func (h *Handler) HandleUSBCameraPreviewStop(w http.ResponseWriter, r *http.Requ
 // OLD HandleUSBCameraMJPEG - deprecated, kept for reference
 func (h *Handler) HandleUSBCameraMJPEGOld(w http.ResponseWriter, r *http.Request) {
 	if h.usbCamController == nil {
-		http.Error(w, "USB camera support not initialized", http.StatusServiceUnavailable)
+		jsonError(w, "USB camera support not initialized", http.StatusServiceUnavailable)
 		return
 	}
 
 	// Extract camera ID from URL path
 	cameraID := r.PathValue("id")
 	if cameraID == "" {
-		http.Error(w, "camera ID required", http.StatusBadRequest)
+		jsonError(w, "camera ID required", http.StatusBadRequest)
 		return
 	}
 
 	cameraState := h.usbCamController.GetCameraState(cameraID)
 	if cameraState == nil || cameraState.Camera == nil {
-		http.Error(w, "camera not found", http.StatusNotFound)
+		jsonError(w, "camera not found", http.StatusNotFound)
 		return
 	}
 
@@ -0,0 +1,161 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"srtla-manager/internal/config"
+	"srtla-manager/internal/events"
+	"srtla-manager/internal/vpn"
+)
+
+// vpnTimeout bounds bringing the tunnel up or down and reading its status
+const vpnTimeout = 30 * time.Second
+
+// VPNResponse is the response of the /api/vpn endpoints
+type VPNResponse struct {
+	Enabled bool `json:"enabled"`
+	vpn.Status
+	Error string `json:"error,omitempty"`
+}
+
+// VPNEvent reports the tunnel being brought up or down
+type VPNEvent struct {
+	Action    string `json:"action"` // "up" or "down"
+	Provider  string `json:"provider"`
+	Interface string `json:"interface"`
+	Trigger   string `json:"trigger"` // "config" or "manual"
+	Error     string `json:"error,omitempty"`
+}
+
+func vpnConfig(cfg config.VPNConfig) vpn.Config {
+	return vpn.Config{Provider: cfg.Provider, Interface: cfg.Interface, AuthKey: cfg.AuthKey, Hostname: cfg.Hostname}
+}
+
+// ApplyVPNConfig brings the tunnel up when it is enabled, and takes down
+// the one it brought up before when that is no longer enabled. A tunnel
+// started by hand is left alone.
+func (h *Handler) ApplyVPNConfig() {
+	if runtime.GOOS != "linux" {
+		return
+	}
+	cfg := h.config.Get().VPN
+
+	h.vpnMu.Lock()
+	previous := h.vpnApplied
+	if previous != nil && *previous == cfg {
+		h.vpnMu.Unlock()
+		return
+	}
+	h.vpnApplied = &cfg
+	h.vpnMu.Unlock()
+
+	go func() {
+		if previous != nil && previous.Enabled && (!cfg.Enabled || previous.Provider != cfg.Provider || previous.Interface != cfg.Interface) {
+			h.setVPN(*previous, false, "config")
+		}
+		if cfg.Enabled {
+			h.setVPN(cfg, true, "config")
+		}
+	}()
+}
+
+// setVPN brings the tunnel up or down, logging and publishing the outcome
+func (h *Handler) setVPN(cfg config.VPNConfig, up bool, trigger string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), vpnTimeout)
+	defer cancel()
+
+	tunnel := vpn.New(vpnConfig(cfg), nil)
+	ev := VPNEvent{Action: "down", Provider: cfg.Provider, Interface: vpnConfig(cfg).InterfaceName(), Trigger: trigger}
+	var err error
+	if up {
+		ev.Action = "up"
+		err = tunnel.Up(ctx)
+	} else {
+		err = tunnel.Down(ctx)
+	}
+	if err != nil {
+		ev.Error = err.Error()
+		h.logOutput("manager", fmt.Sprintf("[VPN] Failed to bring %s %s: %v", ev.Interface, ev.Action, err))
+	} else {
+		h.logOutput("manager", fmt.Sprintf("[VPN] %s tunnel %s is %s", cfg.Provider, ev.Interface, ev.Action))
+	}
+	events.Publish(h.bus, TopicVPN, ev)
+	return err
+}
+
+// withoutTunnelIPs drops the addresses on the tunnel interface. The tunnel
+// runs over the links, so bonding it would send the stream through itself.
+func withoutTunnelIPs(ips []string, cfg config.VPNConfig) []string {
+	if cfg.Provider == "" {
+		return ips
+	}
+	tunnel := vpn.InterfaceIPs(vpnConfig(cfg).InterfaceName())
+	if len(tunnel) == 0 {
+		return ips
+	}
+	return slices.DeleteFunc(slices.Clone(ips), func(ip string) bool {
+		return slices.Contains(tunnel, strings.TrimSpace(ip))
+	})
+}
+
+func (h *Handler) vpnStatus(ctx context.Context) VPNResponse {
+	cfg := h.config.Get().VPN
+	resp := VPNResponse{Enabled: cfg.Enabled, Status: vpn.Status{Peers: []vpn.Peer{}}}
+	if cfg.Provider == "" {
+		return resp
+	}
+	st, err := vpn.New(vpnConfig(cfg), nil).Status(ctx)
+	resp.Status = st
+	if err != nil {
+		resp.Error = err.Error()
+	}
+	return resp
+}
+
+// HandleVPN handles GET /api/vpn
+func (h *Handler) HandleVPN(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), vpnTimeout)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.vpnStatus(ctx))
+}
+
+// HandleVPNStart handles POST /api/vpn/start
+func (h *Handler) HandleVPNStart(w http.ResponseWriter, r *http.Request) {
+	h.handleVPNSwitch(w, r, true)
+}
+
+// HandleVPNStop handles POST /api/vpn/stop. A caller reaching the manager
+// through the tunnel loses it before the response arrives.
+func (h *Handler) HandleVPNStop(w http.ResponseWriter, r *http.Request) {
+	h.handleVPNSwitch(w, r, false)
+}
+
+func (h *Handler) handleVPNSwitch(w http.ResponseWriter, r *http.Request, up bool) {
+	cfg := h.config.Get().VPN
+	if cfg.Provider == "" {
+		jsonError(w, "No tunnel is configured; set vpn.provider", http.StatusBadRequest)
+		return
+	}
+	if runtime.GOOS != "linux" {
+		jsonError(w, "Tunnels are only supported on Linux", http.StatusNotImplemented)
+		return
+	}
+	if err := h.setVPN(cfg, up, "manual"); err != nil {
+		jsonError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), vpnTimeout)
+	defer cancel()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.vpnStatus(ctx))
+}
@@ -1,12 +1,20 @@
 package api
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"log"
 	"net/http"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"srtla-manager/internal/cbor"
+	"srtla-manager/internal/process"
+
 	"github.com/gorilla/websocket"
 )
 
@@ -23,62 +31,480 @@ type WSMessage struct {
 	Data interface{} `json:"data"`
 }
 
+// binaryTopics are the high-rate topics sent as CBOR to clients that connect
+// with ?encoding=cbor. Everything else stays JSON text.
+var binaryTopics = map[string]bool{
+	"stats": true,
+}
+
+// Replay of the messages a client missed while reconnecting with its
+// session token
+const (
+	replayBufferSize = 200             // recent messages kept for replay
+	sessionTTL       = 5 * time.Minute // how long a dropped session can be resumed
+	maxSessions      = 64
+)
+
+// Backpressure on a client's send queue
+const (
+	clientQueueSize = 256
+	// snapshotHighWater is how far behind a client may be before messages
+	// of snapshotTopics are skipped for it; the next one it gets supersedes
+	// them. A client whose queue fills up is evicted.
+	snapshotHighWater = clientQueueSize / 2
+)
+
+// snapshotTopics each carry a complete state, so only the latest of each is
+// replayed rather than every one missed
+var snapshotTopics = map[string]bool{
+	"stats":    true,
+	"modems":   true,
+	"usbnet":   true,
+	"power":    true,
+	"tally":    true,
+	"switcher": true,
+	"timecode": true,
+}
+
+// unreplayedTopics are never replayed; log lines would crowd out the events
+// and GET /api/logs has them
+var unreplayedTopics = map[string]bool{
+	"log": true,
+}
+
+// SessionInfo is the first message on every connection, of type "session".
+// A client that reconnects with ?session=<token> within sessionTTL is sent
+// the messages it missed right after it.
+type SessionInfo struct {
+	Token    string `json:"token"`
+	Resumed  bool   `json:"resumed"`
+	Replayed int    `json:"replayed"`
+	Gap      bool   `json:"gap"` // some missed messages were no longer kept; reload the state
+}
+
+// DroppedInfo is sent, as a message of type "dropped", to the clients
+// subscribed to topics whose messages the hub had to drop, by topic
+type DroppedInfo struct {
+	Topics map[string]uint64 `json:"topics"`
+}
+
+// wsSession lets a client that reconnects pick up where it left off
+type wsSession struct {
+	client       *Client // nil while disconnected
+	lastSeq      uint64  // of the last message written to the client
+	disconnected time.Time
+}
+
 type Client struct {
-	hub  *Hub
-	conn *websocket.Conn
-	send chan []byte
+	hub    *Hub
+	conn   *websocket.Conn
+	send   chan frame
+	binary bool // client negotiated CBOR frames
+
+	session string        // token of the client's session, the requested one until registered
+	acked   atomic.Uint64 // seq of the last message written to the connection
+
+	// guarded by hub.mu
+	topics      map[string]bool // subscriptions; "*" for every topic not set false
+	closeReason string          // sent in the close frame when evicted
+
+	header   http.Header   // of the upgrade request, which commands' requests are made with
+	requests chan struct{} // commands' requests running
+	tail     *logTail      // log lines the client asked for; guarded by hub.mu
+
+	remoteAddr  string
+	connectedAt time.Time
+	sent        uint64 // guarded by hub.mu
+	skipped     uint64 // snapshot messages skipped while behind; guarded by hub.mu
+}
+
+// wants reports whether the client is subscribed to a topic. hub.mu must be
+// held.
+func (c *Client) wants(topic string) bool {
+	if on, ok := c.topics[topic]; ok {
+		return on
+	}
+	return c.topics["*"]
+}
+
+// subscriptions returns the topics the client is subscribed to, "*" for
+// all of them, and those it left since. hub.mu must be held.
+func (c *Client) subscriptions() (topics, excluded []string) {
+	topics, excluded = []string{}, []string{}
+	for t, on := range c.topics {
+		if on {
+			topics = append(topics, t)
+		} else {
+			excluded = append(excluded, t)
+		}
+	}
+	sort.Strings(topics)
+	sort.Strings(excluded)
+	return topics, excluded
+}
+
+// parseTopics splits a comma-separated list of topics
+func parseTopics(list string) []string {
+	var topics []string
+	for _, t := range strings.Split(list, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			topics = append(topics, t)
+		}
+	}
+	return topics
+}
+
+// hubMessage is an encoded message queued for broadcast, tagged with its
+// topic for accounting. binary is only set for binaryTopics while CBOR
+// clients are connected.
+type hubMessage struct {
+	topic  string
+	data   []byte
+	binary []byte
+	seq    uint64 // assigned by Run
+}
+
+// frame is a single WebSocket message queued for a client.
+type frame struct {
+	data   []byte
+	binary bool
+	seq    uint64 // 0 for the session message
+}
+
+// frameFor returns the frame carrying m to client
+func frameFor(client *Client, m hubMessage) frame {
+	if client.binary && m.binary != nil {
+		return frame{data: m.binary, binary: true, seq: m.seq}
+	}
+	return frame{data: m.data, seq: m.seq}
+}
+
+// TopicStats counts broadcasts for a single message type.
+type TopicStats struct {
+	Broadcasts    uint64    `json:"broadcasts"`
+	Delivered     uint64    `json:"delivered"`
+	Dropped       uint64    `json:"dropped"`        // hub queue full
+	ClientDrops   uint64    `json:"client_drops"`   // client buffer full, client evicted
+	Skipped       uint64    `json:"skipped"`        // snapshots not sent to clients behind
+	LastBroadcast time.Time `json:"last_broadcast"` // zero if never delivered
+}
+
+// ClientStats describes a connected WebSocket client.
+type ClientStats struct {
+	RemoteAddr  string    `json:"remote_addr"`
+	ConnectedAt time.Time `json:"connected_at"`
+	Encoding    string    `json:"encoding"`
+	Queued      int       `json:"queued"`
+	Sent        uint64    `json:"sent"`
+	Skipped     uint64    `json:"skipped"`
+	Topics      []string  `json:"topics"`
+}
+
+// HubStats is a snapshot of the hub's metrics.
+type HubStats struct {
+	Clients       int                   `json:"clients"`
+	QueueLength   int                   `json:"queue_length"`
+	QueueCapacity int                   `json:"queue_capacity"`
+	Evicted       uint64                `json:"evicted"`
+	Sessions      int                   `json:"sessions"` // connected and resumable
+	Topics        map[string]TopicStats `json:"topics"`
+	ClientStats   []ClientStats         `json:"client_stats"`
 }
 
 type Hub struct {
 	mu         sync.RWMutex
 	clients    map[*Client]bool
-	broadcast  chan []byte
+	binary     int // clients using CBOR frames
+	broadcast  chan hubMessage
 	register   chan *Client
 	unregister chan *Client
+
+	// guarded by mu
+	seq      uint64                // of the last broadcast
+	recent   []hubMessage          // the last replayBufferSize replayed messages
+	latest   map[string]hubMessage // last message of each snapshot topic
+	lost     uint64                // seq of the last message dropped from recent
+	sessions map[string]*wsSession
+
+	handler http.Handler // serves commands' requests; guarded by mu
+
+	statsMu    sync.Mutex
+	topics     map[string]*TopicStats
+	evicted    uint64
+	unreported map[string]uint64 // dropped from the queue since clients were last told, by topic
 }
 
 func NewHub() *Hub {
 	return &Hub{
 		clients:    make(map[*Client]bool),
-		broadcast:  make(chan []byte, 256),
+		broadcast:  make(chan hubMessage, 256),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
+		latest:     make(map[string]hubMessage),
+		sessions:   make(map[string]*wsSession),
+		topics:     make(map[string]*TopicStats),
+		unreported: make(map[string]uint64),
 	}
 }
 
+// SetRequestHandler sets the handler the "request" command is served by,
+// normally the one the server serves, so that requests over the WebSocket
+// pass the same access checks
+func (h *Hub) SetRequestHandler(handler http.Handler) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.handler = handler
+}
+
 func (h *Hub) Run() {
 	for {
 		select {
 		case client := <-h.register:
 			h.mu.Lock()
+			h.startSessionLocked(client)
 			h.clients[client] = true
+			if client.binary {
+				h.binary++
+			}
 			h.mu.Unlock()
 
 		case client := <-h.unregister:
 			h.mu.Lock()
-			if _, ok := h.clients[client]; ok {
-				delete(h.clients, client)
-				close(client.send)
-			}
+			h.removeClient(client)
 			h.mu.Unlock()
 
 		case message := <-h.broadcast:
-			h.mu.RLock()
+			var delivered, dropped, skipped uint64
+			h.statsMu.Lock()
+			unreported := h.unreported
+			if len(unreported) > 0 {
+				h.unreported = make(map[string]uint64)
+			}
+			h.statsMu.Unlock()
+
+			h.mu.Lock()
+			if len(unreported) > 0 {
+				h.reportDroppedLocked(unreported)
+			}
+			h.seq++
+			message.seq = h.seq
+			h.keepLocked(message)
 			for client := range h.clients {
+				if !client.wants(message.topic) {
+					continue
+				}
+				if snapshotTopics[message.topic] && len(client.send) >= snapshotHighWater {
+					client.skipped++
+					skipped++
+					continue
+				}
 				select {
-				case client.send <- message:
+				case client.send <- frameFor(client, message):
+					client.sent++
+					delivered++
 				default:
-					go func(c *Client) {
-						h.unregister <- c
-					}(client)
+					// A client that can't keep up is evicted rather than
+					// allowed to stall everyone else
+					h.evictLocked(client)
+					dropped++
 				}
 			}
-			h.mu.RUnlock()
+			h.mu.Unlock()
+
+			h.statsMu.Lock()
+			ts := h.topic(message.topic)
+			ts.Delivered += delivered
+			ts.ClientDrops += dropped
+			ts.Skipped += skipped
+			ts.LastBroadcast = time.Now()
+			h.evicted += dropped
+			h.statsMu.Unlock()
+		}
+	}
+}
+
+// reportDroppedLocked tells each client which of its topics lost messages
+// in the hub's queue. A client too far behind to be told will be evicted
+// soon anyway. h.mu must be held for writing.
+func (h *Hub) reportDroppedLocked(dropped map[string]uint64) {
+	for client := range h.clients {
+		info := DroppedInfo{Topics: make(map[string]uint64)}
+		for topic, n := range dropped {
+			if client.wants(topic) {
+				info.Topics[topic] = n
+			}
+		}
+		if len(info.Topics) == 0 {
+			continue
+		}
+		data, _ := json.Marshal(WSMessage{Type: "dropped", Data: info})
+		select {
+		case client.send <- frame{data: data}:
+		default:
 		}
 	}
 }
 
+// evictLocked disconnects a client that can't keep up, telling it why in
+// the close frame. h.mu must be held for writing.
+func (h *Hub) evictLocked(client *Client) {
+	log.Printf("WebSocket client %s too slow, disconnecting", client.remoteAddr)
+	client.closeReason = "too slow: send queue full"
+	h.removeClient(client)
+}
+
+// deliver queues a frame for a client that is still connected, evicting it
+// if its queue is full
+func (h *Hub) deliver(client *Client, f frame) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.clients[client] {
+		return
+	}
+	select {
+	case client.send <- f:
+	default:
+		h.evictLocked(client)
+		h.statsMu.Lock()
+		h.evicted++
+		h.statsMu.Unlock()
+	}
+}
+
+// removeClient must be called with h.mu held for writing.
+func (h *Hub) removeClient(client *Client) {
+	if _, ok := h.clients[client]; ok {
+		delete(h.clients, client)
+		close(client.send)
+		if client.binary {
+			h.binary--
+		}
+		if sess := h.sessions[client.session]; sess != nil && sess.client == client {
+			sess.client = nil
+			sess.lastSeq = client.acked.Load()
+			sess.disconnected = time.Now()
+		}
+	}
+}
+
+// startSessionLocked resumes the session the client asked for, queueing the
+// messages it missed, or starts a new one. Either way the client is sent the
+// session message first. h.mu must be held for writing.
+func (h *Hub) startSessionLocked(client *Client) {
+	h.pruneSessionsLocked()
+
+	var replay []hubMessage
+	info := SessionInfo{Token: client.session}
+	sess, ok := h.sessions[client.session]
+	if ok && sess.client == nil {
+		info.Resumed = true
+		replay, info.Gap = h.replayLocked(sess.lastSeq)
+		info.Replayed = len(replay)
+	} else {
+		info.Token = newSessionToken()
+		sess = &wsSession{lastSeq: h.seq}
+		h.sessions[info.Token] = sess
+	}
+	sess.client = client
+	client.session = info.Token
+	client.acked.Store(sess.lastSeq)
+
+	// the send buffer is empty and larger than the replay
+	data, _ := json.Marshal(WSMessage{Type: "session", Data: info})
+	client.send <- frame{data: data}
+	for _, m := range replay {
+		if !client.wants(m.topic) {
+			continue
+		}
+		client.send <- frameFor(client, m)
+		client.sent++
+	}
+}
+
+// replayLocked returns the kept messages after seq in order, and whether
+// any after it were dropped from the buffer since. h.mu must be held.
+func (h *Hub) replayLocked(seq uint64) ([]hubMessage, bool) {
+	var replay []hubMessage
+	for _, m := range h.recent {
+		if m.seq > seq {
+			replay = append(replay, m)
+		}
+	}
+	for _, m := range h.latest {
+		if m.seq > seq {
+			replay = append(replay, m)
+		}
+	}
+	sort.Slice(replay, func(i, j int) bool { return replay[i].seq < replay[j].seq })
+	return replay, h.lost > seq
+}
+
+// keepLocked keeps a broadcast message for replay. h.mu must be held for
+// writing.
+func (h *Hub) keepLocked(m hubMessage) {
+	switch {
+	case unreplayedTopics[m.topic]:
+	case snapshotTopics[m.topic]:
+		h.latest[m.topic] = m
+	default:
+		if len(h.recent) == replayBufferSize {
+			h.lost = h.recent[0].seq
+			h.recent = append(h.recent[:0], h.recent[1:]...)
+		}
+		h.recent = append(h.recent, m)
+	}
+}
+
+// pruneSessionsLocked forgets sessions dropped longer than sessionTTL ago,
+// and the longest dropped ones beyond maxSessions. h.mu must be held for
+// writing.
+func (h *Hub) pruneSessionsLocked() {
+	var dropped []string
+	for token, sess := range h.sessions {
+		if sess.client != nil {
+			continue
+		}
+		if time.Since(sess.disconnected) > sessionTTL {
+			delete(h.sessions, token)
+			continue
+		}
+		dropped = append(dropped, token)
+	}
+	if len(h.sessions) < maxSessions {
+		return
+	}
+	sort.Slice(dropped, func(i, j int) bool {
+		return h.sessions[dropped[i]].disconnected.Before(h.sessions[dropped[j]].disconnected)
+	})
+	for _, token := range dropped {
+		if len(h.sessions) < maxSessions {
+			break
+		}
+		delete(h.sessions, token)
+	}
+}
+
+func newSessionToken() string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// topic must be called with h.statsMu held.
+func (h *Hub) topic(name string) *TopicStats {
+	ts, ok := h.topics[name]
+	if !ok {
+		ts = &TopicStats{}
+		h.topics[name] = ts
+	}
+	return ts
+}
+
 func (h *Hub) Broadcast(msgType string, data interface{}) {
+	if line, ok := data.(process.LogLine); ok {
+		h.tailLine(line)
+	}
+
 	msg := WSMessage{
 		Type: msgType,
 		Data: data,
@@ -89,13 +515,77 @@ func (h *Hub) Broadcast(msgType string, data interface{}) {
 		return
 	}
 
+	message := hubMessage{topic: msgType, data: jsonData}
+	if binaryTopics[msgType] && h.hasBinaryClients() {
+		if message.binary, err = cbor.Marshal(msg); err != nil {
+			log.Printf("Error encoding websocket message as CBOR: %v", err)
+		}
+	}
+
 	select {
-	case h.broadcast <- jsonData:
+	case h.broadcast <- message:
+		h.statsMu.Lock()
+		h.topic(msgType).Broadcasts++
+		h.statsMu.Unlock()
 	default:
+		h.statsMu.Lock()
+		h.topic(msgType).Dropped++
+		h.unreported[msgType]++
+		h.statsMu.Unlock()
 		log.Println("Broadcast channel full, dropping message")
 	}
 }
 
+func (h *Hub) hasBinaryClients() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.binary > 0
+}
+
+// Stats returns a snapshot of per-topic and per-client metrics.
+func (h *Hub) Stats() HubStats {
+	stats := HubStats{
+		QueueLength:   len(h.broadcast),
+		QueueCapacity: cap(h.broadcast),
+		Topics:        make(map[string]TopicStats),
+		ClientStats:   []ClientStats{},
+	}
+
+	h.mu.RLock()
+	stats.Clients = len(h.clients)
+	stats.Sessions = len(h.sessions)
+	for client := range h.clients {
+		encoding := "json"
+		if client.binary {
+			encoding = "cbor"
+		}
+		topics, _ := client.subscriptions()
+		stats.ClientStats = append(stats.ClientStats, ClientStats{
+			RemoteAddr:  client.remoteAddr,
+			ConnectedAt: client.connectedAt,
+			Encoding:    encoding,
+			Queued:      len(client.send),
+			Sent:        client.sent,
+			Skipped:     client.skipped,
+			Topics:      topics,
+		})
+	}
+	h.mu.RUnlock()
+
+	sort.Slice(stats.ClientStats, func(i, j int) bool {
+		return stats.ClientStats[i].ConnectedAt.Before(stats.ClientStats[j].ConnectedAt)
+	})
+
+	h.statsMu.Lock()
+	for name, ts := range h.topics {
+		stats.Topics[name] = *ts
+	}
+	stats.Evicted = h.evicted
+	h.statsMu.Unlock()
+
+	return stats
+}
+
 func (h *Hub) HandleConnection(w http.ResponseWriter, r *http.Request) {
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
@@ -106,7 +596,26 @@ func (h *Hub) HandleConnection(w http.ResponseWriter, r *http.Request) {
 	client := &Client{
 		hub:  h,
 		conn: conn,
-		send: make(chan []byte, 256),
+		send: make(chan frame, clientQueueSize),
+
+		// JSON stays the default; CBOR is opt-in per connection
+		binary:  r.URL.Query().Get("encoding") == "cbor",
+		session: r.URL.Query().Get("session"),
+
+		// every topic unless ?topics= lists some
+		topics: map[string]bool{"*": true},
+
+		header:   requestHeader(r.Header),
+		requests: make(chan struct{}, maxClientRequests),
+
+		remoteAddr:  r.RemoteAddr,
+		connectedAt: time.Now(),
+	}
+	if topics := parseTopics(r.URL.Query().Get("topics")); len(topics) > 0 {
+		client.topics = make(map[string]bool)
+		for _, t := range topics {
+			client.topics[t] = true
+		}
 	}
 
 	h.register <- client
@@ -127,7 +636,7 @@ func (c *Client) readPump() {
 		c.conn.Close()
 	}()
 
-	c.conn.SetReadLimit(512)
+	c.conn.SetReadLimit(maxCommandSize)
 	c.conn.SetReadDeadline(time.Now().Add(60 * time.Second))
 	c.conn.SetPongHandler(func(string) error {
 		c.conn.SetReadDeadline(time.Now().Add(60 * time.Second))
@@ -135,13 +644,16 @@ func (c *Client) readPump() {
 	})
 
 	for {
-		_, _, err := c.conn.ReadMessage()
+		kind, data, err := c.conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				log.Printf("WebSocket error: %v", err)
 			}
 			break
 		}
+		if kind == websocket.TextMessage {
+			c.handleCommand(data)
+		}
 	}
 }
 
@@ -157,19 +669,23 @@ func (c *Client) writePump() {
 		case message, ok := <-c.send:
 			c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
 			if !ok {
-				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				var msg []byte
+				if c.closeReason != "" {
+					msg = websocket.FormatCloseMessage(websocket.CloseTryAgainLater, c.closeReason)
+				}
+				c.conn.WriteMessage(websocket.CloseMessage, msg)
 				return
 			}
 
 			// Send each message as a separate WebSocket frame to avoid parsing issues
-			if err := c.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+			if err := c.write(message); err != nil {
 				return
 			}
 
 			// Drain any queued messages, sending each separately
 			n := len(c.send)
 			for i := 0; i < n; i++ {
-				if err := c.conn.WriteMessage(websocket.TextMessage, <-c.send); err != nil {
+				if err := c.write(<-c.send); err != nil {
 					return
 				}
 			}
@@ -182,3 +698,16 @@ func (c *Client) writePump() {
 		}
 	}
 }
+
+func (c *Client) write(f frame) error {
+	var err error
+	if f.binary {
+		err = c.conn.WriteMessage(websocket.BinaryMessage, f.data)
+	} else {
+		err = c.conn.WriteMessage(websocket.TextMessage, f.data)
+	}
+	if err == nil && f.seq > 0 {
+		c.acked.Store(f.seq)
+	}
+	return err
+}
@@ -0,0 +1,290 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Limits on the commands a WebSocket client sends
+const (
+	maxCommandSize        = 64 << 10         // of a command message
+	maxClientRequests     = 4                // "request" commands running at once per client
+	commandRequestTimeout = 30 * time.Second // for a "request" command's handler
+	maxCommandResponse    = 1 << 20          // of a "request" command's response body
+)
+
+// JSON-RPC error codes, for commands that never got as far as a request.
+// Failed requests carry their HTTP status as the code.
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+)
+
+// WSCommand is a command a client sends over the WebSocket. It is answered
+// by a message of type "response" with the same ID.
+type WSCommand struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"` // subscribe, unsubscribe, topics, request, tail, untail or ping
+	Params json.RawMessage `json:"params"`
+}
+
+// WSResponse answers a command, with its result or its error
+type WSResponse struct {
+	Type   string          `json:"type"` // always "response"
+	ID     json.RawMessage `json:"id"`
+	Result interface{}     `json:"result,omitempty"`
+	Error  *WSError        `json:"error,omitempty"`
+}
+
+// WSError is why a command failed
+type WSError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"` // the response body of a failed request
+}
+
+// WSTopicsParams are the params of subscribe and unsubscribe
+type WSTopicsParams struct {
+	Topics []string `json:"topics"` // "*" for every topic
+}
+
+// WSTopicsResult is the result of subscribe, unsubscribe and topics
+type WSTopicsResult struct {
+	Topics   []string `json:"topics"`             // "*" when subscribed to every topic
+	Excluded []string `json:"excluded,omitempty"` // left since subscribing to every topic
+}
+
+// WSRequestParams are the params of request, an API call made as the
+// client that opened the WebSocket
+type WSRequestParams struct {
+	Method string          `json:"method"` // GET when empty
+	Path   string          `json:"path"`   // under /api/, with any query
+	Body   json.RawMessage `json:"body"`
+}
+
+// WSRequestResult is the result of a request that succeeded
+type WSRequestResult struct {
+	Status int             `json:"status"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+// handleCommand answers a command, running requests in the background so
+// the connection keeps being read
+func (c *Client) handleCommand(data []byte) {
+	var cmd WSCommand
+	if err := json.Unmarshal(data, &cmd); err != nil {
+		c.respond(WSResponse{Error: &WSError{Code: rpcParseError, Message: err.Error()}})
+		return
+	}
+	resp := WSResponse{ID: cmd.ID}
+
+	switch cmd.Method {
+	case "ping":
+		resp.Result = "pong"
+	case "topics":
+		resp.Result = c.hub.subscribe(c, nil, true)
+	case "subscribe", "unsubscribe":
+		var params WSTopicsParams
+		if err := json.Unmarshal(cmd.Params, &params); err != nil || len(params.Topics) == 0 {
+			resp.Error = &WSError{Code: rpcInvalidParams, Message: "params must list the topics"}
+			break
+		}
+		resp.Result = c.hub.subscribe(c, params.Topics, cmd.Method == "subscribe")
+	case "request":
+		var params WSRequestParams
+		if err := json.Unmarshal(cmd.Params, &params); err != nil {
+			resp.Error = &WSError{Code: rpcInvalidParams, Message: err.Error()}
+			break
+		}
+		select {
+		case c.requests <- struct{}{}:
+		default:
+			resp.Error = &WSError{Code: http.StatusTooManyRequests, Message: fmt.Sprintf("at most %d requests may run at once", maxClientRequests)}
+			c.respond(resp)
+			return
+		}
+		go func() {
+			defer func() { <-c.requests }()
+			resp.Result, resp.Error = c.hub.serveRequest(c, params)
+			c.respond(resp)
+		}()
+		return
+	case "tail":
+		var params WSTailParams
+		if len(cmd.Params) > 0 {
+			if err := json.Unmarshal(cmd.Params, &params); err != nil {
+				resp.Error = &WSError{Code: rpcInvalidParams, Message: err.Error()}
+				break
+			}
+		}
+		tail, err := newLogTail(&params)
+		if err != nil {
+			resp.Error = &WSError{Code: rpcInvalidParams, Message: err.Error()}
+			break
+		}
+		c.hub.setTail(c, tail)
+		resp.Result = params
+	case "untail":
+		var stats WSTailStats
+		if tail := c.hub.setTail(c, nil); tail != nil {
+			stats = tail.totals()
+		}
+		resp.Result = stats
+	case "":
+		resp.Error = &WSError{Code: rpcInvalidRequest, Message: "method is required"}
+	default:
+		resp.Error = &WSError{Code: rpcMethodNotFound, Message: fmt.Sprintf("unknown method %q", cmd.Method)}
+	}
+	c.respond(resp)
+}
+
+func (c *Client) respond(resp WSResponse) {
+	resp.Type = "response"
+	if resp.ID == nil {
+		resp.ID = json.RawMessage("null")
+	}
+	data, err := json.Marshal(resp)
+	if err != nil {
+		data, _ = json.Marshal(WSResponse{Type: "response", ID: resp.ID, Error: &WSError{Code: http.StatusInternalServerError, Message: err.Error()}})
+	}
+	c.hub.deliver(c, frame{data: data})
+}
+
+// subscribe adds topics to a client's subscriptions, or removes them, and
+// returns them. "*" subscribes to every topic, or to none.
+func (h *Hub) subscribe(client *Client, topics []string, on bool) WSTopicsResult {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, t := range topics {
+		switch {
+		case t == "*":
+			client.topics = make(map[string]bool)
+			if on {
+				client.topics[t] = true
+			}
+		case on == client.topics["*"]:
+			// back to what every topic gets
+			delete(client.topics, t)
+		default:
+			client.topics[t] = on
+		}
+	}
+	var result WSTopicsResult
+	result.Topics, result.Excluded = client.subscriptions()
+	return result
+}
+
+// requestHeader returns the headers of a WebSocket's upgrade request that
+// its commands' requests are made with: those that say who the client is,
+// not those of the upgrade
+func requestHeader(upgrade http.Header) http.Header {
+	header := upgrade.Clone()
+	for name := range header {
+		if strings.HasPrefix(name, "Sec-Websocket-") {
+			header.Del(name)
+		}
+	}
+	for _, name := range []string{"Connection", "Upgrade", "Content-Length", "Content-Type", "Accept-Encoding"} {
+		header.Del(name)
+	}
+	return header
+}
+
+// serveRequest makes an API request as the client, through the handler
+// the server serves, so its access checks, scopes and audit apply
+func (h *Hub) serveRequest(client *Client, params WSRequestParams) (result interface{}, rpcErr *WSError) {
+	if params.Method == "" {
+		params.Method = http.MethodGet
+	}
+	if !strings.HasPrefix(params.Path, "/api/") {
+		return nil, &WSError{Code: rpcInvalidParams, Message: "path must be under /api/"}
+	}
+	h.mu.RLock()
+	handler := h.handler
+	h.mu.RUnlock()
+	if handler == nil {
+		return nil, &WSError{Code: http.StatusServiceUnavailable, Message: "requests are not served over this connection"}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), commandRequestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, strings.ToUpper(params.Method), params.Path, bytes.NewReader(params.Body))
+	if err != nil {
+		return nil, &WSError{Code: rpcInvalidParams, Message: err.Error()}
+	}
+	req.Header = client.header.Clone()
+	if len(params.Body) > 0 {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.RemoteAddr = client.remoteAddr
+
+	rw := &commandResponseWriter{header: make(http.Header)}
+	defer func() {
+		// RequestMiddleware aborts a handler that panicked after writing
+		if v := recover(); v != nil {
+			rpcErr = &WSError{Code: http.StatusInternalServerError, Message: "Internal server error"}
+		}
+	}()
+	handler.ServeHTTP(rw, req)
+
+	status := rw.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	body := bytes.TrimSpace(rw.body.Bytes())
+	if len(body) > 0 && !json.Valid(body) {
+		body, _ = json.Marshal(string(body))
+	}
+	if status >= http.StatusBadRequest {
+		var e ErrorResponse
+		json.Unmarshal(body, &e)
+		if e.Error == "" {
+			e.Error = http.StatusText(status)
+		}
+		return nil, &WSError{Code: status, Message: e.Error, Data: body}
+	}
+	if rw.truncated {
+		return nil, &WSError{Code: http.StatusInsufficientStorage, Message: fmt.Sprintf("response is larger than %d bytes; make the request over HTTP", maxCommandResponse)}
+	}
+	return WSRequestResult{Status: status, Body: body}, nil
+}
+
+var errCommandResponseTooLarge = errors.New("response too large")
+
+// commandResponseWriter keeps a command's response in memory, up to
+// maxCommandResponse
+type commandResponseWriter struct {
+	header    http.Header
+	status    int
+	body      bytes.Buffer
+	truncated bool
+}
+
+func (w *commandResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *commandResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *commandResponseWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if w.body.Len()+len(p) > maxCommandResponse {
+		w.truncated = true
+		return 0, errCommandResponseTooLarge
+	}
+	return w.body.Write(p)
+}
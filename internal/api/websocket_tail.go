@@ -0,0 +1,158 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"srtla-manager/internal/process"
+)
+
+// Limits on a client's log tail
+const (
+	defaultTailRate = 10  // lines a second
+	maxTailRate     = 50  // lines a second
+	maxTailPattern  = 512 // bytes of the pattern
+)
+
+// WSTailParams are the params of tail, which sends the client the log lines
+// matching a filter as messages of type "tail", so watching for one error
+// doesn't mean receiving every line
+type WSTailParams struct {
+	Pattern string   `json:"pattern"` // regular expression (RE2) a line must match; every line when empty
+	Sources []string `json:"sources"` // sources such as ffmpeg, srtla_send or manager; every source when empty
+	Rate    int      `json:"rate"`    // lines a second at most; 10 when 0, up to 50
+}
+
+// WSTailLine is a log line sent to a tailing client
+type WSTailLine struct {
+	Timestamp  time.Time `json:"timestamp"`
+	Source     string    `json:"source"`
+	Line       string    `json:"line"`
+	Suppressed uint64    `json:"suppressed,omitempty"` // matching lines left out since the last one sent, over the rate
+}
+
+// WSTailStats is the result of untail: what the tail that stopped matched
+type WSTailStats struct {
+	Matched    uint64 `json:"matched"`
+	Sent       uint64 `json:"sent"`
+	Suppressed uint64 `json:"suppressed"`
+}
+
+// logTail filters the log lines for one client and limits their rate
+type logTail struct {
+	re      *regexp.Regexp // nil for every line
+	sources map[string]bool
+	rate    int
+
+	mu      sync.Mutex
+	tokens  float64
+	last    time.Time
+	pending uint64 // lines suppressed since the last one sent
+	stats   WSTailStats
+}
+
+// newLogTail checks params and returns the tail they describe, with the
+// rate it was given
+func newLogTail(params *WSTailParams) (*logTail, error) {
+	switch {
+	case params.Rate < 0 || params.Rate > maxTailRate:
+		return nil, fmt.Errorf("rate must be between 1 and %d lines a second", maxTailRate)
+	case params.Rate == 0:
+		params.Rate = defaultTailRate
+	}
+	if len(params.Pattern) > maxTailPattern {
+		return nil, fmt.Errorf("pattern must be at most %d bytes", maxTailPattern)
+	}
+
+	t := &logTail{rate: params.Rate, tokens: float64(params.Rate)}
+	if params.Pattern != "" {
+		re, err := regexp.Compile(params.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern: %v", err)
+		}
+		t.re = re
+	}
+	if len(params.Sources) > 0 {
+		t.sources = make(map[string]bool, len(params.Sources))
+		for _, s := range params.Sources {
+			t.sources[s] = true
+		}
+	}
+	return t, nil
+}
+
+// accept reports whether line matches the tail and is within its rate,
+// returning what to send
+func (t *logTail) accept(line process.LogLine, now time.Time) (WSTailLine, bool) {
+	if t.sources != nil && !t.sources[line.Source] {
+		return WSTailLine{}, false
+	}
+	if t.re != nil && !t.re.MatchString(line.Line) {
+		return WSTailLine{}, false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stats.Matched++
+	if !t.last.IsZero() {
+		t.tokens = min(t.tokens+now.Sub(t.last).Seconds()*float64(t.rate), float64(t.rate))
+	}
+	t.last = now
+	if t.tokens < 1 {
+		t.pending++
+		t.stats.Suppressed++
+		return WSTailLine{}, false
+	}
+	t.tokens--
+	t.stats.Sent++
+	out := WSTailLine{Timestamp: line.Timestamp, Source: line.Source, Line: line.Line, Suppressed: t.pending}
+	t.pending = 0
+	return out, true
+}
+
+func (t *logTail) totals() WSTailStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.stats
+}
+
+// setTail starts a client's log tail in place of any it had, or stops it
+// for a nil tail, returning the one it had
+func (h *Hub) setTail(client *Client, tail *logTail) *logTail {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	prev := client.tail
+	client.tail = tail
+	return prev
+}
+
+// tailLine sends a log line to the clients whose tail it matches
+func (h *Hub) tailLine(line process.LogLine) {
+	type match struct {
+		client *Client
+		line   WSTailLine
+	}
+	now := time.Now()
+	var matches []match
+	h.mu.RLock()
+	for client := range h.clients {
+		if client.tail == nil {
+			continue
+		}
+		if out, ok := client.tail.accept(line, now); ok {
+			matches = append(matches, match{client, out})
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, m := range matches {
+		data, err := json.Marshal(WSMessage{Type: "tail", Data: m.line})
+		if err != nil {
+			continue
+		}
+		h.deliver(m.client, frame{data: data})
+	}
+}
@@ -2,14 +2,20 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
+	"path/filepath"
+	"time"
 
+	"srtla-manager/internal/logger"
+	"srtla-manager/internal/validate"
 	"srtla-manager/internal/wifi"
 )
 
 func (h *Handler) HandleWiFi(w http.ResponseWriter, r *http.Request) {
 	if h.wifiMgr == nil {
-		http.Error(w, "WiFi manager not available", http.StatusServiceUnavailable)
+		jsonError(w, "WiFi manager not available", http.StatusServiceUnavailable)
 		return
 	}
 
@@ -24,29 +30,49 @@ func (h *Handler) HandleWiFi(w http.ResponseWriter, r *http.Request) {
 		h.handleWiFiDisconnect(w, r)
 	case "/api/wifi/hotspot":
 		h.handleWiFiHotspot(w, r)
+	case "/api/wifi/hotspot/clients":
+		h.handleWiFiHotspotClients(w, r)
+	case "/api/wifi/hotspot/channel":
+		h.handleWiFiHotspotChannel(w, r)
 	case "/api/wifi/hotspot/stop":
 		h.handleWiFiHotspotStop(w, r)
 	case "/api/wifi/forget":
 		h.handleWiFiForget(w, r)
+	case "/api/wifi/ca-cert":
+		h.handleWiFiCACert(w, r)
 	default:
-		http.Error(w, "Not found", http.StatusNotFound)
+		jsonError(w, "Not found", http.StatusNotFound)
 	}
 }
 
 func (h *Handler) handleWiFiNetworks(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	networks, err := h.wifiMgr.ListNetworks()
-	if err != nil {
+	// Serve the cached scan unless an explicit blocking rescan is requested
+	var result wifi.ScanResult
+	if r.URL.Query().Get("force") == "true" {
+		result = h.wifiMgr.Scan(true)
+	} else {
+		result = h.wifiMgr.CachedNetworks()
+	}
+
+	networks := result.Networks
+	if networks == nil {
 		networks = []wifi.NetworkInfo{}
 	}
 
 	resp := WiFiNetworksResponse{
-		Available: h.wifiMgr.IsAvailable(),
-		Networks:  networks,
+		Available:  h.wifiMgr.IsAvailable(),
+		Networks:   networks,
+		Age:        int(time.Since(result.ScannedAt).Seconds()),
+		ScannedAt:  result.ScannedAt,
+		Refreshing: result.Refreshing,
+	}
+	if result.Err != nil {
+		resp.Error = result.Err.Error()
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -55,12 +81,12 @@ func (h *Handler) handleWiFiNetworks(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handler) handleWiFiStatus(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
 	conn := h.wifiMgr.GetConnectionStatus()
-	resp := WiFiStatusResponse{Connection: conn}
+	resp := WiFiStatusResponse{Connection: conn, Backend: h.wifiMgr.Backend()}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(resp)
@@ -68,22 +94,45 @@ func (h *Handler) handleWiFiStatus(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handler) handleWiFiConnect(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
 	var req WiFiConnectRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request", http.StatusBadRequest)
+		jsonError(w, "Invalid request", http.StatusBadRequest)
 		return
 	}
 
-	if req.SSID == "" {
-		http.Error(w, "SSID required", http.StatusBadRequest)
+	v := validate.New()
+	v.Required("ssid", req.SSID)
+	v.SSID("ssid", req.SSID)
+	v.OneOf("security", req.Security, wifi.SecurityPSK, wifi.SecurityEnterprise)
+	if req.Security == wifi.SecurityEnterprise {
+		v.Required("identity", req.Identity)
+		v.OneOf("eap_method", req.EAPMethod, "peap", "ttls")
+	} else {
+		v.Passphrase("password", req.Password)
+	}
+	if req.CACertPath != "" && filepath.Dir(req.CACertPath) != wifi.CertDir {
+		v.Addf("ca_cert_path", "must be an uploaded certificate")
+	}
+	if err := v.Err(); err != nil {
+		validationError(w, err)
 		return
 	}
 
-	err := h.wifiMgr.Connect(req.SSID, req.Password)
+	err := h.wifiMgr.ConnectWithOptions(wifi.ConnectOptions{
+		SSID:              req.SSID,
+		Password:          req.Password,
+		Hidden:            req.Hidden,
+		Security:          req.Security,
+		EAPMethod:         req.EAPMethod,
+		Identity:          req.Identity,
+		AnonymousIdentity: req.AnonymousIdentity,
+		Phase2Auth:        req.Phase2Auth,
+		CACertPath:        req.CACertPath,
+	})
 	resp := WiFiActionResponse{
 		Success: err == nil,
 		Message: "",
@@ -100,7 +149,7 @@ func (h *Handler) handleWiFiConnect(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handler) handleWiFiDisconnect(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
@@ -121,18 +170,27 @@ func (h *Handler) handleWiFiDisconnect(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handler) handleWiFiHotspot(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
 	var req WiFiHotspotRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request", http.StatusBadRequest)
+		jsonError(w, "Invalid request", http.StatusBadRequest)
 		return
 	}
 
-	if req.SSID == "" || req.Password == "" {
-		http.Error(w, "SSID and password required", http.StatusBadRequest)
+	v := validate.New()
+	v.Required("ssid", req.SSID)
+	v.SSID("ssid", req.SSID)
+	v.Required("password", req.Password)
+	v.Passphrase("password", req.Password)
+	v.OneOf("band", req.Band, "2.4", "5")
+	if req.Channel != 0 {
+		v.Range("channel", req.Channel, 1, 196)
+	}
+	if err := v.Err(); err != nil {
+		validationError(w, err)
 		return
 	}
 
@@ -143,7 +201,10 @@ func (h *Handler) handleWiFiHotspot(w http.ResponseWriter, r *http.Request) {
 		Channel:  req.Channel,
 	}
 
-	err := h.wifiMgr.CreateHotspot(config)
+	err := h.startHotspot(config)
+	if err == nil {
+		h.rememberHotspot(config)
+	}
 	resp := WiFiActionResponse{
 		Success: err == nil,
 		Message: "",
@@ -152,15 +213,59 @@ func (h *Handler) handleWiFiHotspot(w http.ResponseWriter, r *http.Request) {
 		resp.Message = err.Error()
 	} else {
 		resp.Message = "Hotspot created: " + req.SSID
+		if active := h.wifiMgr.ChannelReport().Active; active != nil && active.Channel != 0 {
+			resp.Message += fmt.Sprintf(" on channel %d", active.Channel)
+		}
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(resp)
 }
 
+// startHotspot brings the hotspot up with the camera DHCP reservations
+func (h *Handler) startHotspot(config wifi.HotspotConfig) error {
+	// dnsmasq reads the camera reservations when the hotspot comes up
+	if err := h.applyDHCPReservations(); err != nil {
+		logger.Warn("Failed to write DHCP reservations: %v", err)
+	}
+	return h.wifiMgr.CreateHotspot(config)
+}
+
+// rememberHotspot saves the hotspot settings so it can be started again
+// without the UI, e.g. from a button
+func (h *Handler) rememberHotspot(config wifi.HotspotConfig) {
+	cfg := h.config.Get()
+	cfg.Hotspot.SSID = config.SSID
+	cfg.Hotspot.Password = config.Password
+	cfg.Hotspot.Band = config.Band
+	cfg.Hotspot.Channel = config.Channel
+	if err := h.config.Update(cfg); err != nil {
+		logger.Warn("Failed to save hotspot settings: %v", err)
+	}
+}
+
+// handleWiFiHotspotChannel reports the hotspot channel decisions. POST scans
+// again first, which isn't possible while the hotspot is running.
+func (h *Handler) handleWiFiHotspotChannel(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		if err := h.wifiMgr.ScanChannels(); err != nil {
+			jsonError(w, err.Error(), http.StatusConflict)
+			return
+		}
+	default:
+		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.wifiMgr.ChannelReport())
+}
+
 func (h *Handler) handleWiFiHotspotStop(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
@@ -181,7 +286,7 @@ func (h *Handler) handleWiFiHotspotStop(w http.ResponseWriter, r *http.Request)
 
 func (h *Handler) handleWiFiForget(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
@@ -189,12 +294,12 @@ func (h *Handler) handleWiFiForget(w http.ResponseWriter, r *http.Request) {
 		SSID string `json:"ssid"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request", http.StatusBadRequest)
+		jsonError(w, "Invalid request", http.StatusBadRequest)
 		return
 	}
 
 	if req.SSID == "" {
-		http.Error(w, "SSID required", http.StatusBadRequest)
+		jsonError(w, "SSID required", http.StatusBadRequest)
 		return
 	}
 
@@ -212,3 +317,27 @@ func (h *Handler) handleWiFiForget(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(resp)
 }
+
+// handleWiFiCACert stores a PEM CA certificate for WPA-Enterprise networks.
+// The body is the raw PEM; ?name= sets the file name.
+func (h *Handler) handleWiFiCACert(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r.Body, 64*1024))
+	if err != nil {
+		jsonError(w, "Failed to read certificate", http.StatusBadRequest)
+		return
+	}
+
+	path, err := h.wifiMgr.SaveCACert(r.URL.Query().Get("name"), data)
+	if err != nil {
+		jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(WiFiCACertResponse{Path: path})
+}
@@ -0,0 +1,213 @@
+// Package apikey generates API keys for automation clients and decides what
+// a key's scopes allow. Keys are stored as SHA-256 hashes; the key itself is
+// only shown once, when it is created.
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Scopes a key can be given. Each includes what the ones before it allow.
+const (
+	ScopeStats  = "stats"  // read-only status and statistics
+	ScopeStream = "stream" // start and stop the stream, markers, tally override
+	ScopeAdmin  = "admin"  // everything, including configuration
+)
+
+// ScopeGuest is given to guest operator keys: stream start and stop, the
+// status and the HLS preview, usually for a limited time window
+const ScopeGuest = "guest"
+
+// ScopeViewer watches the stream and everything about it without being able
+// to change anything: the status, stats, logs and previews
+const ScopeViewer = "viewer"
+
+// Scopes lists the values accepted for a key's scopes
+var Scopes = []string{ScopeStats, ScopeStream, ScopeAdmin, ScopeGuest, ScopeViewer}
+
+// Roles a key can be created with, in place of a list of scopes
+const (
+	RoleViewer   = "viewer"   // monitoring only, for event staff
+	RoleOperator = "operator" // runs the stream and configures the unit
+)
+
+// RoleScopes are the scopes each role is given
+var RoleScopes = map[string][]string{
+	RoleViewer:   {ScopeViewer},
+	RoleOperator: {ScopeAdmin},
+}
+
+// Header carries a key when the Authorization header can't be used
+const Header = "X-API-Key"
+
+// Prefix starts every key, so a leaked one is easy to recognise
+const Prefix = "srtla_"
+
+// ManagementPath is where keys are managed. No key, not even an admin one,
+// is allowed there, so a key can't mint itself more access.
+const ManagementPath = "/api/access/keys"
+
+// GuestPath is where guest operator keys are handed out, out of reach of
+// keys for the same reason
+const GuestPath = "/api/access/guests"
+
+// Errors for a key used outside its time window
+var (
+	ErrNotYetValid = errors.New("API key is not valid yet")
+	ErrExpired     = errors.New("API key has expired")
+)
+
+// route is a method and a path, matching the path and anything below it
+type route struct {
+	methods []string
+	path    string
+}
+
+var read = []string{http.MethodGet, http.MethodHead}
+
+var statsRoutes = []route{
+	{read, "/healthz"},
+	{read, "/readyz"},
+	{read, "/metrics"},
+	{read, "/api/metrics"},
+	{read, "/api/status"},
+	{read, "/api/ingest"},
+	{read, "/api/abr"},
+	{read, "/api/publishers"},
+	{read, "/api/events/stats"},
+	{read, "/api/srtla/transport"},
+	{read, "/api/power"},
+	{read, "/api/tally"},
+	{read, "/api/jobs"},
+}
+
+var streamRoutes = []route{
+	{[]string{http.MethodPost}, "/api/stream/start"},
+	{[]string{http.MethodPost}, "/api/stream/stop"},
+	{read, "/api/profiles"},
+	{[]string{http.MethodGet, http.MethodPost}, "/api/markers"},
+	{[]string{http.MethodPut, http.MethodDelete}, "/api/tally/override"},
+}
+
+// guestRoutes is what a guest operator may do: run the stream and watch it
+var guestRoutes = []route{
+	{[]string{http.MethodPost}, "/api/stream/start"},
+	{[]string{http.MethodPost}, "/api/stream/stop"},
+	{read, "/api/status"},
+	{read, "/preview"},
+	{[]string{http.MethodPost}, "/api/preview/token"},
+}
+
+// viewerRoutes is what a viewer may see, on top of the stats routes. The
+// configuration is left out, since it holds stream keys and passwords.
+var viewerRoutes = []route{
+	{read, "/ws"},
+	{read, "/api/ws/stats"},
+	{read, "/api/logs"},
+	{read, "/api/alerts"},
+	{read, "/api/stream/preconditions"},
+	{read, "/api/outputs"},
+	{read, "/api/srtla/bind-sets"},
+	{read, "/api/links/probe"},
+	{read, "/api/modems"},
+	{read, "/api/system/interfaces"},
+	{read, "/api/cameras"},
+	{read, "/api/v2/cameras"},
+	{read, "/api/usbcams"},
+	{read, "/api/timecode"},
+	{read, "/api/switcher"},
+	{read, "/preview"},
+	{read, "/preview-temp"},
+	{read, "/return"},
+	{[]string{http.MethodPost}, "/api/preview/token"},
+}
+
+// Generate returns a new key and an ID to refer to it by
+func Generate() (id, key string, err error) {
+	buf := make([]byte, 28)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	return hex.EncodeToString(buf[:4]), Prefix + hex.EncodeToString(buf[4:]), nil
+}
+
+// Hash is what is stored in place of key
+func Hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// FromRequest returns the key a request carries as a bearer token or in the
+// X-API-Key header, or "" when it carries none
+func FromRequest(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); len(auth) > 7 && strings.EqualFold(auth[:7], "Bearer ") {
+		return strings.TrimSpace(auth[7:])
+	}
+	return strings.TrimSpace(r.Header.Get(Header))
+}
+
+// Allows reports whether a key with scopes may make a request
+func Allows(scopes []string, method, path string) bool {
+	if under(path, ManagementPath) || under(path, GuestPath) {
+		return false
+	}
+	for _, s := range scopes {
+		switch s {
+		case ScopeAdmin:
+			return true
+		case ScopeStream:
+			if matches(streamRoutes, method, path) || matches(statsRoutes, method, path) {
+				return true
+			}
+		case ScopeStats:
+			if matches(statsRoutes, method, path) {
+				return true
+			}
+		case ScopeGuest:
+			if matches(guestRoutes, method, path) {
+				return true
+			}
+		case ScopeViewer:
+			if matches(viewerRoutes, method, path) || matches(statsRoutes, method, path) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func matches(routes []route, method, path string) bool {
+	for _, r := range routes {
+		if under(path, r.path) {
+			for _, m := range r.methods {
+				if m == method {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// under reports whether path is prefix or below it
+func under(path, prefix string) bool {
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// Window checks now against a key's time window. Either end may be nil for
+// a window open on that side.
+func Window(notBefore, expiresAt *time.Time, now time.Time) error {
+	if notBefore != nil && now.Before(*notBefore) {
+		return ErrNotYetValid
+	}
+	if expiresAt != nil && !now.Before(*expiresAt) {
+		return ErrExpired
+	}
+	return nil
+}
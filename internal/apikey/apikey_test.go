@@ -0,0 +1,106 @@
+package apikey
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAllows(t *testing.T) {
+	tests := []struct {
+		scopes []string
+		method string
+		path   string
+		want   bool
+	}{
+		{[]string{ScopeStats}, http.MethodGet, "/api/status", true},
+		{[]string{ScopeStats}, http.MethodGet, "/api/jobs/abc", true},
+		{[]string{ScopeStats}, http.MethodGet, "/api/statusx", false},
+		{[]string{ScopeStats}, http.MethodPost, "/api/stream/start", false},
+		{[]string{ScopeStats}, http.MethodGet, "/api/config", false},
+		{[]string{ScopeStream}, http.MethodPost, "/api/stream/start", true},
+		{[]string{ScopeStream}, http.MethodGet, "/metrics", true},
+		{[]string{ScopeStream}, http.MethodDelete, "/api/profiles/main", false},
+		{[]string{ScopeStream}, http.MethodPut, "/api/config", false},
+		{[]string{ScopeStats, ScopeStream}, http.MethodPut, "/api/tally/override", true},
+		{[]string{ScopeAdmin}, http.MethodPut, "/api/config", true},
+		{[]string{ScopeAdmin}, http.MethodPost, ManagementPath, false},
+		{[]string{ScopeAdmin}, http.MethodDelete, ManagementPath + "/abcd", false},
+		{[]string{ScopeGuest}, http.MethodPost, "/api/stream/stop", true},
+		{[]string{ScopeGuest}, http.MethodGet, "/preview/playlist.m3u8", true},
+		{[]string{ScopeGuest}, http.MethodGet, "/api/jobs", false},
+		{[]string{ScopeGuest}, http.MethodPost, "/api/markers", false},
+		{[]string{ScopeAdmin}, http.MethodPost, GuestPath, false},
+		{[]string{ScopeViewer}, http.MethodGet, "/api/status", true},
+		{[]string{ScopeViewer}, http.MethodGet, "/api/logs/download", true},
+		{[]string{ScopeViewer}, http.MethodGet, "/preview/playlist.m3u8", true},
+		{[]string{ScopeViewer}, http.MethodGet, "/api/usbcams/cam0/preview-stream", true},
+		{[]string{ScopeViewer}, http.MethodGet, "/ws", true},
+		{[]string{ScopeViewer}, http.MethodPost, "/api/stream/start", false},
+		{[]string{ScopeViewer}, http.MethodPost, "/api/stream/stop", false},
+		{[]string{ScopeViewer}, http.MethodGet, "/api/config", false},
+		{[]string{ScopeViewer}, http.MethodPut, "/api/config", false},
+		{[]string{ScopeViewer}, http.MethodPost, "/api/updates/perform", false},
+		{[]string{ScopeViewer}, http.MethodPost, "/api/preview/token", true},
+		{[]string{ScopeViewer}, http.MethodDelete, "/api/preview/token", false},
+		{[]string{ScopeGuest}, http.MethodPost, "/api/preview/token", true},
+		{RoleScopes[RoleOperator], http.MethodPost, "/api/updates/perform", true},
+		{nil, http.MethodGet, "/api/status", false},
+	}
+
+	for _, tt := range tests {
+		if got := Allows(tt.scopes, tt.method, tt.path); got != tt.want {
+			t.Errorf("Allows(%v, %s, %s) = %v, want %v", tt.scopes, tt.method, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	id, key, err := Generate()
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if len(id) != 8 || !strings.HasPrefix(key, Prefix) {
+		t.Errorf("Unexpected id %q or key %q", id, key)
+	}
+	if Hash(key) == key || Hash(key) != Hash(key) {
+		t.Error("Expected a stable hash that differs from the key")
+	}
+}
+
+func TestFromRequest(t *testing.T) {
+	r, _ := http.NewRequest(http.MethodGet, "/api/status", nil)
+	if FromRequest(r) != "" {
+		t.Error("Expected no key")
+	}
+
+	r.Header.Set("Authorization", "Bearer srtla_abc")
+	if got := FromRequest(r); got != "srtla_abc" {
+		t.Errorf("Expected the bearer token, got %q", got)
+	}
+
+	r.Header.Del("Authorization")
+	r.Header.Set(Header, "srtla_def")
+	if got := FromRequest(r); got != "srtla_def" {
+		t.Errorf("Expected the X-API-Key header, got %q", got)
+	}
+}
+
+func TestWindow(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	start, end := now.Add(-time.Hour), now.Add(time.Hour)
+
+	if err := Window(nil, nil, now); err != nil {
+		t.Errorf("Expected a key without a window to be valid, got %v", err)
+	}
+	if err := Window(&start, &end, now); err != nil {
+		t.Errorf("Expected a key inside its window to be valid, got %v", err)
+	}
+	if err := Window(&end, nil, now); err != ErrNotYetValid {
+		t.Errorf("Expected ErrNotYetValid, got %v", err)
+	}
+	if err := Window(nil, &now, now); err != ErrExpired {
+		t.Errorf("Expected a key to expire at its end, got %v", err)
+	}
+}
@@ -0,0 +1,209 @@
+// Package bondhealth sums up the bonded uplink in one score from 0 to 100
+// and a traffic light, so the state of the links can be read at a glance.
+// Points are taken off for links that are down, too little spare capacity
+// for the bitrate, packet loss and recent restarts of the pipeline.
+package bondhealth
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Level is the traffic light of a score
+type Level string
+
+const (
+	Green Level = "green"
+	Amber Level = "amber"
+	Red   Level = "red"
+)
+
+// Scores from which a level is shown
+const (
+	GreenFrom = 75
+	AmberFrom = 45
+)
+
+// RestartWindow is how far back restarts of the pipeline count against
+// the score
+const RestartWindow = 15 * time.Minute
+
+// lossSmoothing weighs each loss reading against the ones before, so one
+// bad report doesn't flip the light
+const lossSmoothing = 0.3
+
+// lagFactor is how far throughput may fall behind the input before the
+// links count as falling behind
+const lagFactor = 0.8
+
+// Sample is one reading of the pipeline
+type Sample struct {
+	Links          int     // bonded links set up
+	LinksUp        int     // of them, those with an address
+	InputKbps      float64 // what the encoder sends, 0 when idle
+	ThroughputKbps float64 // what the links carry, 0 when unknown
+	CapacityKbps   float64 // estimated capacity of the links, 0 when unknown
+	LossPercent    float64 // SRT loss, when measured
+	LossMeasured   bool
+}
+
+// Score is the summary shown to the operator
+type Score struct {
+	Score           int      `json:"score"`
+	Level           Level    `json:"level"`
+	Links           int      `json:"links"`
+	LinksUp         int      `json:"links_up"`
+	HeadroomPercent *float64 `json:"headroom_percent,omitempty"` // spare capacity over the bitrate, when known
+	LossPercent     *float64 `json:"loss_percent,omitempty"`     // smoothed, when measured
+	Restarts        int      `json:"restarts"`                   // within RestartWindow
+	Reasons         []string `json:"reasons,omitempty"`          // what cost points, worst first
+}
+
+// penalty is points taken off and why
+type penalty struct {
+	points int
+	reason string
+}
+
+// Compute scores a sample with its smoothed loss and recent restarts
+func Compute(s Sample, restarts int) Score {
+	score := Score{Links: s.Links, LinksUp: s.LinksUp, Restarts: restarts}
+	if s.LossMeasured {
+		loss := s.LossPercent
+		score.LossPercent = &loss
+	}
+
+	var penalties []penalty
+	switch {
+	case s.LinksUp == 0:
+		penalties = append(penalties, penalty{100, "no link is up"})
+	case s.LinksUp < s.Links:
+		down := s.Links - s.LinksUp
+		penalties = append(penalties, penalty{40 * down / s.Links, fmt.Sprintf("%d of %d links down", down, s.Links)})
+	}
+	if s.LinksUp == 1 {
+		penalties = append(penalties, penalty{20, "a single link, with nothing to fall back on"})
+	}
+
+	if s.InputKbps > 0 {
+		if s.CapacityKbps > 0 {
+			headroom := (s.CapacityKbps - s.InputKbps) / s.InputKbps * 100
+			score.HeadroomPercent = &headroom
+			switch {
+			case headroom < 0:
+				penalties = append(penalties, penalty{40, "bitrate above what the links can carry"})
+			case headroom < 20:
+				penalties = append(penalties, penalty{25, fmt.Sprintf("only %.0f%% headroom", headroom)})
+			case headroom < 50:
+				penalties = append(penalties, penalty{10, fmt.Sprintf("%.0f%% headroom", headroom)})
+			}
+		} else if s.ThroughputKbps > 0 && s.ThroughputKbps < s.InputKbps*lagFactor {
+			penalties = append(penalties, penalty{30, "links falling behind the bitrate"})
+		}
+	}
+
+	if s.LossMeasured {
+		switch {
+		case s.LossPercent >= 5:
+			penalties = append(penalties, penalty{35, fmt.Sprintf("%.1f%% packet loss", s.LossPercent)})
+		case s.LossPercent >= 1:
+			penalties = append(penalties, penalty{15, fmt.Sprintf("%.1f%% packet loss", s.LossPercent)})
+		case s.LossPercent >= 0.1:
+			penalties = append(penalties, penalty{5, fmt.Sprintf("%.1f%% packet loss", s.LossPercent)})
+		}
+	}
+
+	switch {
+	case restarts >= 3:
+		penalties = append(penalties, penalty{35, fmt.Sprintf("%d restarts in %d minutes", restarts, int(RestartWindow.Minutes()))})
+	case restarts > 0:
+		penalties = append(penalties, penalty{10 * restarts, fmt.Sprintf("%d restart(s) in %d minutes", restarts, int(RestartWindow.Minutes()))})
+	}
+
+	sort.SliceStable(penalties, func(i, j int) bool { return penalties[i].points > penalties[j].points })
+	score.Score = 100
+	for _, p := range penalties {
+		score.Score -= p.points
+		score.Reasons = append(score.Reasons, p.reason)
+	}
+	score.Score = max(score.Score, 0)
+
+	switch {
+	case s.LinksUp == 0 || score.Score < AmberFrom:
+		score.Level = Red
+	case score.Score < GreenFrom:
+		score.Level = Amber
+	default:
+		score.Level = Green
+	}
+	return score
+}
+
+// Tracker smooths loss across samples and remembers restarts and the last
+// score
+type Tracker struct {
+	mu       sync.Mutex
+	loss     float64
+	lossSeen bool
+	restarts []time.Time
+	last     *Score
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{}
+}
+
+// AddRestart records a restart of the pipeline
+func (t *Tracker) AddRestart(now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.restarts = append(t.restarts, now)
+}
+
+// Reset forgets the loss seen so far and the last score, for a new
+// stream or when the links aren't bonded
+func (t *Tracker) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.loss, t.lossSeen = 0, false
+	t.last = nil
+}
+
+// Update folds in a sample and returns the score
+func (t *Tracker) Update(s Sample, now time.Time) Score {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if s.LossMeasured {
+		if t.lossSeen {
+			t.loss += lossSmoothing * (s.LossPercent - t.loss)
+		} else {
+			t.loss, t.lossSeen = s.LossPercent, true
+		}
+		s.LossPercent = t.loss
+	}
+
+	recent := t.restarts[:0]
+	for _, at := range t.restarts {
+		if now.Sub(at) < RestartWindow {
+			recent = append(recent, at)
+		}
+	}
+	t.restarts = recent
+	score := Compute(s, len(recent))
+	t.last = &score
+	return score
+}
+
+// Last returns the score of the last update, nil before the first
+func (t *Tracker) Last() *Score {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.last == nil {
+		return nil
+	}
+	score := *t.last
+	return &score
+}
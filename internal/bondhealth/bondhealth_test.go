@@ -0,0 +1,78 @@
+package bondhealth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestComputeHealthy(t *testing.T) {
+	s := Compute(Sample{Links: 3, LinksUp: 3, InputKbps: 6000, CapacityKbps: 12000, LossMeasured: true}, 0)
+	if s.Score != 100 || s.Level != Green {
+		t.Errorf("Expected 100 green, got %d %s (%v)", s.Score, s.Level, s.Reasons)
+	}
+	if s.HeadroomPercent == nil || *s.HeadroomPercent != 100 {
+		t.Errorf("Expected 100%% headroom, got %v", s.HeadroomPercent)
+	}
+}
+
+func TestComputeLevels(t *testing.T) {
+	tests := []struct {
+		name     string
+		sample   Sample
+		restarts int
+		want     Level
+	}{
+		{"one of three links down", Sample{Links: 3, LinksUp: 2}, 0, Green},
+		{"single link", Sample{Links: 1, LinksUp: 1}, 0, Green},
+		{"low headroom", Sample{Links: 2, LinksUp: 2, InputKbps: 6000, CapacityKbps: 6600}, 0, Green},
+		{"low headroom and loss", Sample{Links: 2, LinksUp: 2, InputKbps: 6000, CapacityKbps: 6600, LossPercent: 2, LossMeasured: true}, 0, Amber},
+		{"over capacity", Sample{Links: 2, LinksUp: 2, InputKbps: 6000, CapacityKbps: 5000}, 0, Amber},
+		{"falling behind", Sample{Links: 2, LinksUp: 2, InputKbps: 6000, ThroughputKbps: 3000}, 0, Amber},
+		{"restarting", Sample{Links: 2, LinksUp: 2}, 3, Amber},
+		{"over capacity with heavy loss", Sample{Links: 2, LinksUp: 2, InputKbps: 6000, CapacityKbps: 5000, LossPercent: 8, LossMeasured: true}, 0, Red},
+		{"no links", Sample{Links: 2}, 0, Red},
+	}
+	for _, tt := range tests {
+		s := Compute(tt.sample, tt.restarts)
+		if s.Level != tt.want {
+			t.Errorf("%s: expected %s, got %s at %d (%v)", tt.name, tt.want, s.Level, s.Score, s.Reasons)
+		}
+		if s.Score < 0 || s.Score > 100 {
+			t.Errorf("%s: score %d out of range", tt.name, s.Score)
+		}
+	}
+}
+
+func TestComputeReasonsWorstFirst(t *testing.T) {
+	s := Compute(Sample{Links: 2, LinksUp: 2, InputKbps: 6000, CapacityKbps: 5000, LossPercent: 0.5, LossMeasured: true}, 0)
+	if len(s.Reasons) != 2 || s.Reasons[0] != "bitrate above what the links can carry" {
+		t.Errorf("Unexpected reasons %v", s.Reasons)
+	}
+}
+
+func TestTrackerSmoothsLossAndExpiresRestarts(t *testing.T) {
+	tr := NewTracker()
+	if tr.Last() != nil {
+		t.Fatal("Expected no score before the first update")
+	}
+	now := time.Now()
+	tr.AddRestart(now.Add(-RestartWindow - time.Minute))
+	tr.AddRestart(now.Add(-time.Minute))
+
+	tr.Update(Sample{Links: 2, LinksUp: 2, LossMeasured: true}, now)
+	s := tr.Update(Sample{Links: 2, LinksUp: 2, LossPercent: 10, LossMeasured: true}, now)
+	if s.LossPercent == nil || *s.LossPercent != 3 {
+		t.Errorf("Expected loss smoothed to 3%%, got %v", s.LossPercent)
+	}
+	if s.Restarts != 1 {
+		t.Errorf("Expected only the recent restart to count, got %d", s.Restarts)
+	}
+	if last := tr.Last(); last == nil || last.Score != s.Score {
+		t.Errorf("Expected the last score to be kept, got %+v", last)
+	}
+
+	tr.Reset()
+	if tr.Last() != nil {
+		t.Error("Expected Reset to clear the last score")
+	}
+}
@@ -0,0 +1,79 @@
+// Package buttons turns GPIO push buttons into actions so the unit can be run
+// without the UI.
+package buttons
+
+import (
+	"context"
+	"time"
+
+	"srtla-manager/internal/gpio"
+)
+
+// Actions a button can trigger
+const (
+	ActionStreamToggle  = "stream_toggle"
+	ActionStreamStart   = "stream_start"
+	ActionStreamStop    = "stream_stop"
+	ActionHotspotToggle = "hotspot_toggle"
+	ActionShutdown      = "shutdown"
+)
+
+// Actions lists the values accepted for a button's action
+var Actions = []string{ActionStreamToggle, ActionStreamStart, ActionStreamStop, ActionHotspotToggle, ActionShutdown}
+
+const (
+	// pollInterval is how often buttons are sampled
+	pollInterval = 20 * time.Millisecond
+	// debounceSamples is how many equal samples make a state change
+	debounceSamples = 3
+)
+
+// button debounces samples and fires once per press when the button has been
+// held for hold
+type button struct {
+	hold    time.Duration
+	pressed bool // debounced state
+	run     int  // samples disagreeing with the debounced state
+	since   time.Time
+	fired   bool
+}
+
+// sample feeds one reading and reports whether the press should fire now
+func (b *button) sample(down bool, now time.Time) bool {
+	if down == b.pressed {
+		b.run = 0
+	} else if b.run++; b.run >= debounceSamples {
+		b.pressed = down
+		b.run = 0
+		b.since = now
+		b.fired = false
+	}
+	if b.pressed && !b.fired && now.Sub(b.since) >= b.hold {
+		b.fired = true
+		return true
+	}
+	return false
+}
+
+// Watch samples pin until ctx is done, calling press each time the button is
+// held down for hold. It returns early if the pin cannot be read.
+func Watch(ctx context.Context, pin *gpio.Pin, hold time.Duration, press func()) error {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	b := &button{hold: hold}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			down, err := pin.Get()
+			if err != nil {
+				return err
+			}
+			if b.sample(down, now) {
+				press()
+			}
+		}
+	}
+}
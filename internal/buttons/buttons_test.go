@@ -0,0 +1,57 @@
+package buttons
+
+import (
+	"testing"
+	"time"
+)
+
+// feed samples the button every pollInterval and counts the presses fired
+func feed(b *button, start time.Time, samples []bool) (fired int) {
+	for i, down := range samples {
+		if b.sample(down, start.Add(time.Duration(i)*pollInterval)) {
+			fired++
+		}
+	}
+	return fired
+}
+
+func repeat(down bool, n int) []bool {
+	s := make([]bool, n)
+	for i := range s {
+		s[i] = down
+	}
+	return s
+}
+
+func TestButtonDebounce(t *testing.T) {
+	start := time.Now()
+
+	// contact bounce on press and release fires once
+	samples := []bool{true, false, true, true, true, true, true, false, true, false, false, false}
+	if fired := feed(&button{}, start, samples); fired != 1 {
+		t.Errorf("bouncing press fired %d times, want 1", fired)
+	}
+
+	// a single noisy sample never fires
+	samples = []bool{false, true, false, false, true, false}
+	if fired := feed(&button{}, start, samples); fired != 0 {
+		t.Errorf("noise fired %d times, want 0", fired)
+	}
+}
+
+func TestButtonHold(t *testing.T) {
+	start := time.Now()
+	hold := time.Second
+
+	// released before the hold time: nothing
+	samples := append(repeat(true, 30), repeat(false, 5)...)
+	if fired := feed(&button{hold: hold}, start, samples); fired != 0 {
+		t.Errorf("short press fired %d times, want 0", fired)
+	}
+
+	// held past the hold time fires once however long it is held
+	samples = append(repeat(true, 200), repeat(false, 5)...)
+	if fired := feed(&button{hold: hold}, start, samples); fired != 1 {
+		t.Errorf("long press fired %d times, want 1", fired)
+	}
+}
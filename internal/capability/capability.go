@@ -0,0 +1,113 @@
+// Package capability works out which resolution, frame rate and encoder
+// combinations a source can be streamed with on this machine, from the
+// modes the source offers, the encoders ffmpeg can use and how much the CPU
+// can encode in software.
+package capability
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Class is how much video the CPU can encode in software
+type Class string
+
+const (
+	ClassLow  Class = "low"  // single core or ARMv6 boards such as the Pi Zero
+	ClassMid  Class = "mid"  // ARM boards and small x86 machines
+	ClassHigh Class = "high" // x86 machines with more than four cores
+)
+
+// softwarePixelRate is the most pixels per second a class encodes in
+// software in real time: 360p30, 720p30 and 1080p60
+var softwarePixelRate = map[Class]int{
+	ClassLow:  640 * 360 * 30,
+	ClassMid:  1280 * 720 * 30,
+	ClassHigh: 1920 * 1080 * 60,
+}
+
+// hardwarePixelRate is the most pixels per second left to a GPU encoder,
+// 2160p60
+const hardwarePixelRate = 3840 * 2160 * 60
+
+// softwareEncoders encode on the CPU
+var softwareEncoders = map[string]bool{
+	"libx264":     true,
+	"libopenh264": true,
+}
+
+// Host is what the machine offers for encoding
+type Host struct {
+	Model    string          `json:"model,omitempty"`
+	Arch     string          `json:"arch"`
+	Cores    int             `json:"cores"`
+	Class    Class           `json:"cpu_class"`
+	Encoders map[string]bool `json:"encoders"` // usable encoders
+}
+
+// Mode is a resolution and frame rate a source delivers in a pixel format
+type Mode struct {
+	PixelFormat string `json:"pixel_format,omitempty"` // H264 when the video can be passed through as it is
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	FPS         int    `json:"fps"`
+}
+
+// Combination is a mode with an encoder and whether it works here
+type Combination struct {
+	Mode
+	Encoder   string `json:"encoder"`
+	Supported bool   `json:"supported"`
+	Reason    string `json:"reason,omitempty"` // why it isn't supported
+}
+
+// Classify returns the class of a CPU from its model, core count and
+// GOARCH
+func Classify(model string, cores int, arch string) Class {
+	switch {
+	case cores <= 1, arch == "arm" && strings.Contains(model, "Zero"):
+		return ClassLow
+	case arch == "arm", arch == "arm64", cores <= 4:
+		return ClassMid
+	default:
+		return ClassHigh
+	}
+}
+
+// Matrix returns every combination of the modes with the encoders, in that
+// order, with whether the host can stream it
+func Matrix(modes []Mode, encoders []string, host Host) []Combination {
+	combinations := make([]Combination, 0, len(modes)*len(encoders))
+	for _, mode := range modes {
+		for _, encoder := range encoders {
+			c := Combination{Mode: mode, Encoder: encoder}
+			c.Reason = check(mode, encoder, host)
+			c.Supported = c.Reason == ""
+			combinations = append(combinations, c)
+		}
+	}
+	return combinations
+}
+
+// check returns why the host can't stream mode with encoder, "" if it can
+func check(mode Mode, encoder string, host Host) string {
+	if encoder == "copy" {
+		if mode.PixelFormat != "H264" {
+			return fmt.Sprintf("%s video can't be passed through", mode.PixelFormat)
+		}
+		return ""
+	}
+	if !host.Encoders[encoder] {
+		return encoder + " is not available"
+	}
+
+	rate := mode.Width * mode.Height * mode.FPS
+	limit, name := hardwarePixelRate, "the hardware encoder"
+	if softwareEncoders[encoder] {
+		limit, name = softwarePixelRate[host.Class], fmt.Sprintf("a %s-class CPU", host.Class)
+	}
+	if rate > limit {
+		return fmt.Sprintf("%dx%d at %d fps is too much for %s", mode.Width, mode.Height, mode.FPS, name)
+	}
+	return ""
+}
@@ -0,0 +1,56 @@
+package capability
+
+import "testing"
+
+func TestClassify(t *testing.T) {
+	cases := []struct {
+		model string
+		cores int
+		arch  string
+		want  Class
+	}{
+		{"Raspberry Pi Zero W Rev 1.1", 1, "arm", ClassLow},
+		{"Raspberry Pi Zero 2 W Rev 1.0", 4, "arm", ClassLow},
+		{"Raspberry Pi 4 Model B Rev 1.4", 4, "arm64", ClassMid},
+		{"Intel(R) Celeron(R) N4020 CPU @ 1.10GHz", 2, "amd64", ClassMid},
+		{"AMD Ryzen 7 5800U", 16, "amd64", ClassHigh},
+		{"", 1, "amd64", ClassLow},
+	}
+	for _, c := range cases {
+		if got := Classify(c.model, c.cores, c.arch); got != c.want {
+			t.Errorf("Classify(%q, %d, %s) = %s, want %s", c.model, c.cores, c.arch, got, c.want)
+		}
+	}
+}
+
+func TestMatrix(t *testing.T) {
+	host := Host{Class: ClassMid, Encoders: map[string]bool{"copy": true, "libx264": true, "h264_vaapi": true}}
+	modes := []Mode{
+		{PixelFormat: "H264", Width: 1920, Height: 1080, FPS: 30},
+		{PixelFormat: "MJPG", Width: 1280, Height: 720, FPS: 30},
+	}
+	got := Matrix(modes, []string{"copy", "libx264", "h264_vaapi", "h264_nvenc"}, host)
+	if len(got) != 8 {
+		t.Fatalf("Matrix() returned %d combinations, want 8", len(got))
+	}
+
+	want := []struct {
+		supported bool
+		reason    string
+	}{
+		{true, ""},
+		{false, "1920x1080 at 30 fps is too much for a mid-class CPU"},
+		{true, ""},
+		{false, "h264_nvenc is not available"},
+		{false, "MJPG video can't be passed through"},
+		{true, ""},
+		{true, ""},
+		{false, "h264_nvenc is not available"},
+	}
+	for i, c := range got {
+		if c.Supported != want[i].supported || c.Reason != want[i].reason {
+			t.Errorf("%dx%d %s %s: supported %v (%q), want %v (%q)", c.Width, c.Height, c.PixelFormat, c.Encoder,
+				c.Supported, c.Reason, want[i].supported, want[i].reason)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+// Package capture keeps a rolling on-disk copy of the MPEG-TS sent to srtla.
+// ffmpeg duplicates the muxed SRT leg to a loopback UDP port with the tee
+// protocol, so the bytes recorded here are exactly the bytes srtla received.
+package capture
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SegmentDuration is how much TS goes into each file before rotating
+const SegmentDuration = 10 * time.Second
+
+const segmentPrefix = "capture-"
+
+// Segment is one file of the rolling capture
+type Segment struct {
+	Name  string    `json:"name"`
+	Size  int64     `json:"size"`
+	Start time.Time `json:"start"`
+}
+
+// Stats describes the recorder
+type Stats struct {
+	Active   bool      `json:"active"`
+	Addr     string    `json:"addr,omitempty"`
+	Window   float64   `json:"window_seconds"`
+	Bytes    int64     `json:"bytes"`
+	Packets  int64     `json:"packets"`
+	LastData time.Time `json:"last_data,omitempty"`
+	Segments []Segment `json:"segments"`
+}
+
+// Recorder receives the teed TS over UDP and writes it into rotating segment
+// files, pruning anything older than the window
+type Recorder struct {
+	dir    string
+	window time.Duration
+
+	mu       sync.Mutex
+	conn     *net.UDPConn
+	file     *os.File
+	fileAt   time.Time
+	bytes    int64
+	packets  int64
+	lastData time.Time
+}
+
+// NewRecorder creates a recorder that keeps roughly window worth of TS in dir
+func NewRecorder(dir string, window time.Duration) *Recorder {
+	return &Recorder{dir: dir, window: window}
+}
+
+// Start listens on a loopback UDP port and returns its address for ffmpeg
+func (r *Recorder) Start() (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.conn != nil {
+		return r.conn.LocalAddr().String(), nil
+	}
+	if err := os.MkdirAll(r.dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create capture directory: %w", err)
+	}
+
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		return "", fmt.Errorf("failed to listen for capture: %w", err)
+	}
+	// Bursts at stream start can outrun the writer; a large socket buffer
+	// keeps the kernel from dropping datagrams
+	conn.SetReadBuffer(4 << 20)
+	r.conn = conn
+
+	go r.run(conn)
+	return conn.LocalAddr().String(), nil
+}
+
+// Stop closes the listener and the current segment. Recorded segments stay
+// on disk until they age out of the window on the next start.
+func (r *Recorder) Stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.conn != nil {
+		r.conn.Close()
+		r.conn = nil
+	}
+	r.closeSegment()
+}
+
+func (r *Recorder) run(conn *net.UDPConn) {
+	buf := make([]byte, 65536)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return
+		}
+		r.write(buf[:n])
+	}
+}
+
+func (r *Recorder) write(data []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if r.file == nil || now.Sub(r.fileAt) >= SegmentDuration {
+		r.closeSegment()
+		name := filepath.Join(r.dir, fmt.Sprintf("%s%d.ts", segmentPrefix, now.UnixNano()))
+		f, err := os.Create(name)
+		if err != nil {
+			return
+		}
+		r.file = f
+		r.fileAt = now
+		r.prune(now)
+	}
+
+	if _, err := r.file.Write(data); err == nil {
+		r.bytes += int64(len(data))
+		r.packets++
+		r.lastData = now
+	}
+}
+
+func (r *Recorder) closeSegment() {
+	if r.file != nil {
+		r.file.Close()
+		r.file = nil
+	}
+}
+
+// IsSegment reports whether a file name is that of a capture segment
+func IsSegment(name string) bool {
+	return strings.HasPrefix(name, segmentPrefix) && strings.HasSuffix(name, ".ts")
+}
+
+// prune removes segments that ended before the window
+func (r *Recorder) prune(now time.Time) {
+	for _, seg := range r.segments() {
+		if now.Sub(seg.Start) > r.window+SegmentDuration {
+			os.Remove(filepath.Join(r.dir, seg.Name))
+		}
+	}
+}
+
+// segments lists the capture files oldest first
+func (r *Recorder) segments() []Segment {
+	entries, err := os.ReadDir(r.dir)
+	if err != nil {
+		return nil
+	}
+
+	segs := []Segment{}
+	for _, e := range entries {
+		var nanos int64
+		if _, err := fmt.Sscanf(strings.TrimSuffix(e.Name(), ".ts"), segmentPrefix+"%d", &nanos); err != nil {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		segs = append(segs, Segment{Name: e.Name(), Size: info.Size(), Start: time.Unix(0, nanos)})
+	}
+	sort.Slice(segs, func(i, j int) bool { return segs[i].Start.Before(segs[j].Start) })
+	return segs
+}
+
+// Stats returns the recorder state and the segments currently on disk
+func (r *Recorder) Stats() Stats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats := Stats{
+		Active:   r.conn != nil,
+		Window:   r.window.Seconds(),
+		Bytes:    r.bytes,
+		Packets:  r.packets,
+		LastData: r.lastData,
+		Segments: r.segments(),
+	}
+	if r.conn != nil {
+		stats.Addr = r.conn.LocalAddr().String()
+	}
+	return stats
+}
+
+// WriteTo streams every segment in order to w. Segments are cut on datagram
+// boundaries, so the result is the contiguous TS as sent.
+func (r *Recorder) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	if r.file != nil {
+		r.file.Sync()
+	}
+	segs := r.segments()
+	r.mu.Unlock()
+
+	var total int64
+	for _, seg := range segs {
+		f, err := os.Open(filepath.Join(r.dir, seg.Name))
+		if err != nil {
+			continue // pruned since listing
+		}
+		n, err := io.Copy(w, f)
+		f.Close()
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// Clear deletes all segments and starts a fresh one on the next datagram
+func (r *Recorder) Clear() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.closeSegment()
+	for _, seg := range r.segments() {
+		os.Remove(filepath.Join(r.dir, seg.Name))
+	}
+	r.bytes = 0
+	r.packets = 0
+}
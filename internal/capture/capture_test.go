@@ -0,0 +1,54 @@
+package capture
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+)
+
+// Datagrams sent to the recorder come back byte-for-byte in order
+func TestRecorderRoundTrip(t *testing.T) {
+	r := NewRecorder(t.TempDir(), time.Minute)
+	addr, err := r.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Stop()
+
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	var sent bytes.Buffer
+	for i := 0; i < 20; i++ {
+		pkt := bytes.Repeat([]byte{0x47, byte(i)}, 1316/2)
+		sent.Write(pkt)
+		if _, err := conn.Write(pkt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for r.Stats().Packets < 20 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := r.Stats().Packets; got != 20 {
+		t.Fatalf("Expected 20 datagrams, got %d", got)
+	}
+
+	var got bytes.Buffer
+	if _, err := r.WriteTo(&got); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got.Bytes(), sent.Bytes()) {
+		t.Errorf("Captured %d bytes differ from the %d sent", got.Len(), sent.Len())
+	}
+
+	r.Clear()
+	if segs := r.Stats().Segments; len(segs) != 0 {
+		t.Errorf("Expected no segments after Clear, got %d", len(segs))
+	}
+}
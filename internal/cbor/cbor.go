@@ -0,0 +1,142 @@
+// Package cbor implements a minimal CBOR (RFC 8949) encoder for pushing
+// WebSocket payloads to clients that asked for binary frames.
+package cbor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+)
+
+const (
+	majorUnsigned = 0 << 5
+	majorNegative = 1 << 5
+	majorBytes    = 2 << 5
+	majorText     = 3 << 5
+	majorArray    = 4 << 5
+	majorMap      = 5 << 5
+
+	simpleFalse   = 0xf4
+	simpleTrue    = 0xf5
+	simpleNull    = 0xf6
+	simpleFloat32 = 0xfa
+	simpleFloat64 = 0xfb
+)
+
+// Marshal encodes v as CBOR. v goes through encoding/json first so struct
+// tags, omitempty and custom marshalers behave exactly as they do for the
+// JSON frames. Floats are narrowed to float32 when that loses nothing.
+func Marshal(v interface{}) ([]byte, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var generic interface{}
+	if err := dec.Decode(&generic); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := encode(&buf, generic); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func encode(buf *bytes.Buffer, v interface{}) error {
+	switch val := v.(type) {
+	case nil:
+		buf.WriteByte(simpleNull)
+	case bool:
+		if val {
+			buf.WriteByte(simpleTrue)
+		} else {
+			buf.WriteByte(simpleFalse)
+		}
+	case json.Number:
+		if i, err := val.Int64(); err == nil {
+			encodeInt(buf, i)
+			return nil
+		}
+		f, err := val.Float64()
+		if err != nil {
+			return fmt.Errorf("invalid number %q", val)
+		}
+		encodeFloat(buf, f)
+	case string:
+		writeHead(buf, majorText, uint64(len(val)))
+		buf.WriteString(val)
+	case []byte:
+		writeHead(buf, majorBytes, uint64(len(val)))
+		buf.Write(val)
+	case []interface{}:
+		writeHead(buf, majorArray, uint64(len(val)))
+		for _, item := range val {
+			if err := encode(buf, item); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		// Sorted keys keep the output deterministic
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		writeHead(buf, majorMap, uint64(len(val)))
+		for _, k := range keys {
+			writeHead(buf, majorText, uint64(len(k)))
+			buf.WriteString(k)
+			if err := encode(buf, val[k]); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported type %T", v)
+	}
+	return nil
+}
+
+func encodeInt(buf *bytes.Buffer, i int64) {
+	if i >= 0 {
+		writeHead(buf, majorUnsigned, uint64(i))
+	} else {
+		writeHead(buf, majorNegative, uint64(-(i + 1)))
+	}
+}
+
+func encodeFloat(buf *bytes.Buffer, f float64) {
+	if f32 := float32(f); float64(f32) == f {
+		buf.WriteByte(simpleFloat32)
+		binary.Write(buf, binary.BigEndian, math.Float32bits(f32))
+		return
+	}
+	buf.WriteByte(simpleFloat64)
+	binary.Write(buf, binary.BigEndian, math.Float64bits(f))
+}
+
+// writeHead writes a major type and its argument using the shortest form.
+func writeHead(buf *bytes.Buffer, major byte, n uint64) {
+	switch {
+	case n < 24:
+		buf.WriteByte(major | byte(n))
+	case n <= math.MaxUint8:
+		buf.WriteByte(major | 24)
+		buf.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(major | 25)
+		binary.Write(buf, binary.BigEndian, uint16(n))
+	case n <= math.MaxUint32:
+		buf.WriteByte(major | 26)
+		binary.Write(buf, binary.BigEndian, uint32(n))
+	default:
+		buf.WriteByte(major | 27)
+		binary.Write(buf, binary.BigEndian, n)
+	}
+}
@@ -0,0 +1,56 @@
+package cbor
+
+import (
+	"bytes"
+	"testing"
+)
+
+// Vectors from RFC 8949 Appendix A
+func TestMarshalVectors(t *testing.T) {
+	tests := []struct {
+		in   interface{}
+		want []byte
+	}{
+		{0, []byte{0x00}},
+		{23, []byte{0x17}},
+		{24, []byte{0x18, 0x18}},
+		{1000, []byte{0x19, 0x03, 0xe8}},
+		{-1, []byte{0x20}},
+		{-1000, []byte{0x39, 0x03, 0xe7}},
+		{1.5, []byte{0xfa, 0x3f, 0xc0, 0x00, 0x00}},
+		{1.1, []byte{0xfb, 0x3f, 0xf1, 0x99, 0x99, 0x99, 0x99, 0x99, 0x9a}},
+		{true, []byte{0xf5}},
+		{nil, []byte{0xf6}},
+		{"IETF", []byte{0x64, 0x49, 0x45, 0x54, 0x46}},
+		{[]int{1, 2, 3}, []byte{0x83, 0x01, 0x02, 0x03}},
+		{map[string]string{"b": "B", "a": "A"}, []byte{0xa2, 0x61, 0x61, 0x61, 0x41, 0x61, 0x62, 0x61, 0x42}},
+	}
+
+	for _, tt := range tests {
+		got, err := Marshal(tt.in)
+		if err != nil {
+			t.Errorf("Marshal(%v) failed: %v", tt.in, err)
+			continue
+		}
+		if !bytes.Equal(got, tt.want) {
+			t.Errorf("Marshal(%v) = %x, want %x", tt.in, got, tt.want)
+		}
+	}
+}
+
+// Struct tags are honoured the same way as for JSON frames
+func TestMarshalStructTags(t *testing.T) {
+	v := struct {
+		Name  string `json:"n"`
+		Empty string `json:"e,omitempty"`
+	}{Name: "x"}
+
+	got, err := Marshal(v)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	want := []byte{0xa1, 0x61, 'n', 0x61, 'x'}
+	if !bytes.Equal(got, want) {
+		t.Errorf("got %x, want %x", got, want)
+	}
+}
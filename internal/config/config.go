@@ -2,33 +2,340 @@ package config
 
 import (
 	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
 	"fmt"
-	"net"
+	"maps"
+	"net/mail"
+	"net/url"
 	"os"
 	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
 	"strings"
 	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"srtla-manager/internal/alerts"
+	"srtla-manager/internal/apikey"
+	"srtla-manager/internal/buttons"
+	"srtla-manager/internal/display"
+	"srtla-manager/internal/events"
+	"srtla-manager/internal/linkprobe"
+	"srtla-manager/internal/maintenance"
+	"srtla-manager/internal/power"
+	"srtla-manager/internal/process"
+	"srtla-manager/internal/quota"
+	"srtla-manager/internal/stun"
+	"srtla-manager/internal/tally"
+	"srtla-manager/internal/timecode"
+	"srtla-manager/internal/transport"
+	"srtla-manager/internal/validate"
+	"srtla-manager/internal/vpn"
 
 	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
-	RTMP       RTMPConfig                 `yaml:"rtmp" json:"rtmp"`
-	SRT        SRTConfig                  `yaml:"srt" json:"srt"`
-	SRTLA      SRTLAConfig                `yaml:"srtla" json:"srtla"`
-	Web        WebConfig                  `yaml:"web" json:"web"`
-	Logging    LoggingConfig              `yaml:"logging" json:"logging"`
-	Cameras    map[string]CameraConfig    `yaml:"cameras" json:"cameras"`
-	USBCameras map[string]USBCameraConfig `yaml:"usb_cameras" json:"usb_cameras"`
+	RTMP         RTMPConfig                   `yaml:"rtmp" json:"rtmp"`
+	SRTIngest    SRTIngestConfig              `yaml:"srt_ingest" json:"srt_ingest"`
+	SRT          SRTConfig                    `yaml:"srt" json:"srt"`
+	SRTLA        SRTLAConfig                  `yaml:"srtla" json:"srtla"`
+	Receiver     ReceiverConfig               `yaml:"receiver" json:"receiver"`
+	Web          WebConfig                    `yaml:"web" json:"web"`
+	Access       AccessConfig                 `yaml:"access" json:"access"`
+	Logging      LoggingConfig                `yaml:"logging" json:"logging"`
+	Tracing      TracingConfig                `yaml:"tracing" json:"tracing"`
+	Metrics      MetricsConfig                `yaml:"metrics" json:"metrics"`
+	Audit        AuditConfig                  `yaml:"audit" json:"audit"`
+	Loudness     LoudnessConfig               `yaml:"loudness" json:"loudness"`
+	Audio        AudioConfig                  `yaml:"audio" json:"audio"`
+	Ingest       IngestConfig                 `yaml:"ingest" json:"ingest"`
+	ABR          ABRConfig                    `yaml:"abr" json:"abr"`
+	Maintenance  MaintenanceConfig            `yaml:"maintenance" json:"maintenance"`
+	Pairing      PairingConfig                `yaml:"pairing" json:"pairing"`
+	Power        PowerConfig                  `yaml:"power" json:"power"`
+	Capture      CaptureConfig                `yaml:"capture" json:"capture"`
+	DJIPreview   DJIPreviewConfig             `yaml:"dji_preview" json:"dji_preview"`
+	Talkback     TalkbackConfig               `yaml:"talkback" json:"talkback"`
+	ReturnFeed   ReturnFeedConfig             `yaml:"return_feed" json:"return_feed"`
+	Switcher     SwitcherConfig               `yaml:"switcher" json:"switcher"`
+	Tally        TallyConfig                  `yaml:"tally" json:"tally"`
+	Timecode     TimecodeConfig               `yaml:"timecode" json:"timecode"`
+	Buttons      ButtonsConfig                `yaml:"buttons" json:"buttons"`
+	Display      DisplayConfig                `yaml:"display" json:"display"`
+	Alerts       AlertsConfig                 `yaml:"alerts" json:"alerts"`
+	Notify       NotifyConfig                 `yaml:"notifications" json:"notifications"`
+	Quota        QuotaConfig                  `yaml:"quota" json:"quota"`
+	ModemConnect ModemConnectConfig           `yaml:"modem_connect" json:"modem_connect"`
+	Routing      RoutingConfig                `yaml:"routing" json:"routing"`
+	Overlay      OverlayConfig                `yaml:"overlay" json:"overlay"`
+	LinkProbe    LinkProbeConfig              `yaml:"link_probe" json:"link_probe"`
+	VPN          VPNConfig                    `yaml:"vpn" json:"vpn"`
+	Discovery    DiscoveryConfig              `yaml:"discovery" json:"discovery"`
+	Hotspot      HotspotConfig                `yaml:"hotspot" json:"hotspot"`
+	Cameras      map[string]CameraConfig      `yaml:"cameras" json:"cameras"`
+	USBCameras   map[string]USBCameraConfig   `yaml:"usb_cameras" json:"usb_cameras"`
+	RTSP         RTSPConfig                   `yaml:"rtsp" json:"rtsp"`
+	CameraGroups map[string]CameraGroupConfig `yaml:"camera_groups" json:"camera_groups"`
+	Profiles     map[string]StreamProfile     `yaml:"profiles" json:"profiles"`
+	Profile      string                       `yaml:"profile" json:"profile"` // profile last applied; "" when none was
+	Restream     map[string]RestreamConfig    `yaml:"restream" json:"restream"`
+	Failover     FailoverConfig               `yaml:"failover" json:"failover"`
+	SpeedTest    SpeedTestConfig              `yaml:"speedtest" json:"speedtest"`
+	STUN         STUNConfig                   `yaml:"stun" json:"stun"`
+	StartPolicy  StartPolicyConfig            `yaml:"start_policy" json:"start_policy"`
 }
 
 type RTMPConfig struct {
 	ListenPort int    `yaml:"listen_port" json:"listen_port"`
 	StreamKey  string `yaml:"stream_key" json:"stream_key"`
+	// How long a camera that dropped is waited for before ffmpeg is
+	// restarted from scratch; 0 restarts straight away
+	ReconnectGraceSeconds int `yaml:"reconnect_grace_seconds" json:"reconnect_grace_seconds"`
+	// Further stream keys a backup camera can push to. While any are set
+	// the stream of the best priority camera that is pushing goes out.
+	Ingests []RTMPIngestConfig `yaml:"ingests,omitempty" json:"ingests,omitempty"`
+	// Resample the camera's audio to its timestamps instead of copying it,
+	// for sources whose audio drifts on long streams
+	AudioResync bool `yaml:"audio_resync" json:"audio_resync"`
+}
+
+// RTMPIngestConfig is a further stream key with its own listen port. The
+// main stream_key has priority 0; a lower priority is preferred.
+type RTMPIngestConfig struct {
+	Name       string `yaml:"name" json:"name"`
+	StreamKey  string `yaml:"stream_key" json:"stream_key"`
+	ListenPort int    `yaml:"listen_port" json:"listen_port"`
+	Priority   int    `yaml:"priority" json:"priority"`
+}
+
+// SRTIngestConfig lets cameras push SRT to one UDP port, told apart by the
+// streamid they call with as RTMP cameras are by their stream key. Callers
+// whose streamid isn't listed are rejected during the handshake.
+type SRTIngestConfig struct {
+	ListenPort int           `yaml:"listen_port" json:"listen_port"` // UDP; 0 uses 9000
+	LatencyMs  int           `yaml:"latency_ms" json:"latency_ms"`   // 0 uses SRT's 120
+	StreamIDs  []SRTStreamID `yaml:"stream_ids,omitempty" json:"stream_ids,omitempty"`
+}
+
+// DefaultSRTIngestPort is where SRT cameras call when listen_port is 0
+const DefaultSRTIngestPort = 9000
+
+// Port returns the listen port with the default applied
+func (c SRTIngestConfig) Port() int {
+	if c.ListenPort == 0 {
+		return DefaultSRTIngestPort
+	}
+	return c.ListenPort
+}
+
+// SRTStreamID is a streamid an SRT camera may push with. It also matches
+// the resource of SRT's access control syntax, "#!::r=<stream_id>,m=publish".
+// A lower priority is preferred.
+type SRTStreamID struct {
+	Name       string `yaml:"name" json:"name"`
+	StreamID   string `yaml:"stream_id" json:"stream_id"`
+	Passphrase string `yaml:"passphrase,omitempty" json:"passphrase,omitempty"` // the camera must encrypt with the same one
+	Priority   int    `yaml:"priority" json:"priority"`
+}
+
+// Sources the failover can switch between
+const (
+	SourceRTMP  = "rtmp" // the RTMP stream keys, by their priority
+	SourceSRT   = "srt"  // the SRT stream IDs, by their priority
+	SourceRTSP  = "rtsp"
+	SourceUSB   = "usb"
+	SourceSlate = "slate" // a still image, or color bars
+)
+
+// SpeedTestConfig is where the per-link upload test sends its data
+type SpeedTestConfig struct {
+	URL     string `yaml:"url" json:"url"`         // takes and discards HTTP POST uploads; "" uses Cloudflare's
+	Seconds int    `yaml:"seconds" json:"seconds"` // per link; 0 uses 10
+}
+
+// StartPolicyConfig is what the links have to meet before a stream may be
+// started, unless the start overrides it. Zero values check nothing.
+type StartPolicyConfig struct {
+	MinLinks         int      `yaml:"min_links" json:"min_links"`                                 // bind IPs up and usable
+	MinBandwidthKbps int      `yaml:"min_bandwidth_kbps" json:"min_bandwidth_kbps"`               // summed over the last speed test of the links up
+	SpeedTestMaxAge  int      `yaml:"speedtest_max_age_minutes" json:"speedtest_max_age_minutes"` // older tests don't count; 0 for any age
+	RequiredLinks    []string `yaml:"required_links" json:"required_links"`                       // interface names or bind IPs that must be up
+}
+
+// QuotaConfig budgets each modem's data use, by IMEI, so bonded cellular
+// doesn't run into overage charges
+type QuotaConfig struct {
+	Enabled bool                  `yaml:"enabled" json:"enabled"`
+	Path    string                `yaml:"path" json:"path"`     // usage so far, kept across restarts
+	Modems  map[string]ModemQuota `yaml:"modems" json:"modems"` // by IMEI
+}
+
+// ModemQuota is one modem's data budget
+type ModemQuota struct {
+	Period      string `yaml:"period" json:"period"`             // daily or monthly
+	LimitMB     int    `yaml:"limit_mb" json:"limit_mb"`         // sent and received
+	ResetDay    int    `yaml:"reset_day" json:"reset_day"`       // monthly: day of the month the budget starts again, 1-28
+	WarnPercent []int  `yaml:"warn_percent" json:"warn_percent"` // usage that is warned about, e.g. [80, 90]
+	DropAtCap   bool   `yaml:"drop_at_cap" json:"drop_at_cap"`   // take the modem's link out of the bond once the budget is used
+}
+
+// ModemConnectConfig brings cellular modems onto the network through
+// ModemManager, picking each one's APN by its SIM's IMSI
+type ModemConnectConfig struct {
+	AutoConnect  bool      `yaml:"auto_connect" json:"auto_connect"`   // connect modems that are registered but not connected
+	RetrySeconds int       `yaml:"retry_seconds" json:"retry_seconds"` // between attempts on the same modem; default 30
+	APNs         []APNRule `yaml:"apns" json:"apns"`
+}
+
+// RoutingConfig gives each bind IP its own routing table and a rule sending
+// traffic from the address there, so each link leaves through its own
+// modem. It is on unless disabled, for setups that route by hand.
+type RoutingConfig struct {
+	Disabled     bool `yaml:"disabled" json:"disabled"`
+	TableBase    int  `yaml:"table_base" json:"table_base"`       // table of the first bind IP; 0 uses 100
+	RulePriority int  `yaml:"rule_priority" json:"rule_priority"` // priority of the first rule; 0 uses 1000
+}
+
+// OverlayConfig serves a health overlay for an OBS browser source at the
+// studio. It is reached under the preview allowlist.
+type OverlayConfig struct {
+	Enabled bool   `yaml:"enabled" json:"enabled"`
+	Token   string `yaml:"token" json:"token"` // required as ?token= when set
+	Label   string `yaml:"label" json:"label"` // name of the unit shown; the hostname when empty
+}
+
+// LinkProbeConfig checks that each bind IP reaches the internet, through
+// its own address, and keeps links that fail out of the bond
+type LinkProbeConfig struct {
+	Enabled         bool   `yaml:"enabled" json:"enabled"`
+	Method          string `yaml:"method" json:"method"`                     // icmp or http; icmp when empty
+	Target          string `yaml:"target" json:"target"`                     // host to ping or URL to fetch; 1.1.1.1 or a connectivity check URL when empty
+	IntervalSeconds int    `yaml:"interval_seconds" json:"interval_seconds"` // between probes; default 5
+	TimeoutSeconds  int    `yaml:"timeout_seconds" json:"timeout_seconds"`   // per probe; default 2
+	FailAfter       int    `yaml:"fail_after" json:"fail_after"`             // failures in a row that drop a link; default 3
+	PassAfter       int    `yaml:"pass_after" json:"pass_after"`             // successes in a row that bring it back; default 2
+}
+
+// Discovery modes of a device class
+const (
+	DiscoveryContinuous = "continuous" // scan in the background
+	DiscoveryManual     = "manual"     // scan only when asked
+)
+
+// DiscoveryModes are the values DiscoveryClassConfig.Mode accepts
+var DiscoveryModes = []string{DiscoveryContinuous, DiscoveryManual}
+
+// DiscoveryConfig chooses, per device class, between finding devices in
+// the background, which costs battery, and only when a scan is asked for
+type DiscoveryConfig struct {
+	USBCam DiscoveryClassConfig `yaml:"usbcam" json:"usbcam"`
+	Modem  DiscoveryClassConfig `yaml:"modem" json:"modem"`
+	USBNet DiscoveryClassConfig `yaml:"usbnet" json:"usbnet"`
+	DJI    DiscoveryClassConfig `yaml:"dji" json:"dji"`
+}
+
+// Class returns the settings of a device class: usbcam, modem, usbnet or
+// dji
+func (d DiscoveryConfig) Class(name string) DiscoveryClassConfig {
+	switch name {
+	case "usbcam":
+		return d.USBCam
+	case "modem":
+		return d.Modem
+	case "usbnet":
+		return d.USBNet
+	case "dji":
+		return d.DJI
+	}
+	return DiscoveryClassConfig{}
+}
+
+// DiscoveryClassConfig is how one class of device is found
+type DiscoveryClassConfig struct {
+	Mode            string `yaml:"mode" json:"mode"`                         // continuous or manual; the class's default when empty
+	IntervalSeconds int    `yaml:"interval_seconds" json:"interval_seconds"` // between background scans; the class's default when 0
+}
+
+// VPNConfig is a WireGuard or Tailscale tunnel for reaching the manager in
+// the field. Addresses on the tunnel interface are never used as bind IPs.
+type VPNConfig struct {
+	Enabled   bool   `yaml:"enabled" json:"enabled"`     // bring the tunnel up at start
+	Provider  string `yaml:"provider" json:"provider"`   // wireguard or tailscale
+	Interface string `yaml:"interface" json:"interface"` // WireGuard config in /etc/wireguard; wg0 when empty
+	AuthKey   string `yaml:"auth_key" json:"auth_key"`   // Tailscale pre-auth key for the first login
+	Hostname  string `yaml:"hostname" json:"hostname"`   // Tailscale machine name; the system hostname when empty
+}
+
+// wgInterfacePattern is what wg-quick accepts as an interface name
+var wgInterfacePattern = regexp.MustCompile(`^[a-zA-Z0-9_=+.-]{1,15}$`)
+
+// hostnamePattern is a single DNS label
+var hostnamePattern = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?$`)
+
+// APNRule is the APN for SIMs whose IMSI starts with a prefix
+type APNRule struct {
+	IMSIPrefix string `yaml:"imsi_prefix" json:"imsi_prefix"` // MCC and MNC, e.g. 23410; empty matches any SIM
+	APN        string `yaml:"apn" json:"apn"`
+	User       string `yaml:"user,omitempty" json:"user,omitempty"`
+	Password   string `yaml:"password,omitempty" json:"password,omitempty"`
+	IPType     string `yaml:"ip_type,omitempty" json:"ip_type,omitempty"` // ipv4, ipv6 or ipv4v6; the modem's default when empty
+}
+
+// APNFor returns the rule with the longest prefix of imsi
+func (c ModemConnectConfig) APNFor(imsi string) (APNRule, bool) {
+	var best APNRule
+	found := false
+	for _, r := range c.APNs {
+		if strings.HasPrefix(imsi, r.IMSIPrefix) && (!found || len(r.IMSIPrefix) > len(best.IMSIPrefix)) {
+			best, found = r, true
+		}
+	}
+	return best, found
+}
+
+// imeiPattern is what an IMEI looks like
+var imeiPattern = regexp.MustCompile(`^[0-9]{14,16}$`)
+
+// STUNConfig lists the servers asked for the public address of a bind IP,
+// for SRT rendezvous destinations
+type STUNConfig struct {
+	Servers []string `yaml:"servers" json:"servers"` // host:port; two or more also detect symmetric NATs. Empty uses Google's.
+}
+
+// FailoverConfig switches the stream to a backup source when the primary
+// one loses its signal, and back once it has returned
+type FailoverConfig struct {
+	Enabled        bool   `yaml:"enabled" json:"enabled"`
+	Primary        string `yaml:"primary" json:"primary"`                             // rtmp (default), srt, rtsp or usb
+	Backup         string `yaml:"backup" json:"backup"`                               // slate (default), rtmp, srt, rtsp or usb
+	USBCamera      string `yaml:"usb_camera,omitempty" json:"usb_camera,omitempty"`   // usb_cameras ID for a usb source
+	SlateImage     string `yaml:"slate_image,omitempty" json:"slate_image,omitempty"` // color bars when empty
+	LossSeconds    int    `yaml:"loss_seconds" json:"loss_seconds"`                   // without signal before switching; default 3
+	RestoreSeconds int    `yaml:"restore_seconds" json:"restore_seconds"`             // the primary must be back this long; default 5
+}
+
+// Sources returns the primary and backup source with defaults applied
+func (f FailoverConfig) Sources() (primary, backup string) {
+	primary, backup = f.Primary, f.Backup
+	if primary == "" {
+		primary = SourceRTMP
+	}
+	if backup == "" {
+		backup = SourceSlate
+	}
+	return primary, backup
 }
 
 type SRTConfig struct {
-	LocalPort int `yaml:"local_port" json:"local_port"`
+	LocalPort int  `yaml:"local_port" json:"local_port"`
+	Probe     bool `yaml:"probe" json:"probe"`           // relay through srt-live-transmit for link statistics
+	ProbePort int  `yaml:"probe_port" json:"probe_port"` // where ffmpeg sends when probing
 }
 
 type SRTLAConfig struct {
@@ -41,10 +348,68 @@ type SRTLAConfig struct {
 	Classic     bool     `yaml:"classic" json:"classic"`
 	NoQuality   bool     `yaml:"no_quality" json:"no_quality"`
 	Exploration bool     `yaml:"exploration" json:"exploration"`
+	Transport   string   `yaml:"transport" json:"transport"` // auto, srtla, srtla2 or srt_bonding
+
+	// Named bind IP sets such as "cellular-only"; a stream can be started
+	// with one instead of bind_ips
+	BindSets map[string][]string `yaml:"bind_sets" json:"bind_sets"`
+	BindSet  string              `yaml:"bind_set" json:"bind_set"` // set used when a stream is started without one; "" uses bind_ips
+
+	LinkPolicies map[string]LinkPolicy `yaml:"link_policies" json:"link_policies"` // by bind IP
+}
+
+// LinkPolicy controls how the bonding process uses one bind IP. Bind IPs
+// without a policy are regular links with priority 0.
+type LinkPolicy struct {
+	Priority     int    `yaml:"priority" json:"priority"`               // lower is preferred; links are handed over in this order
+	MaxKbps      int    `yaml:"max_kbps" json:"max_kbps"`               // egress cap on the link's interface; 0 is unlimited
+	FailoverOnly bool   `yaml:"failover_only" json:"failover_only"`     // only used while no regular link is up
+	Label        string `yaml:"label,omitempty" json:"label,omitempty"` // name shown for the link, e.g. its carrier
 }
 
 type WebConfig struct {
-	Port int `yaml:"port" json:"port"`
+	Port int       `yaml:"port" json:"port"`
+	TLS  TLSConfig `yaml:"tls" json:"tls"`
+
+	// BrandingDir is a branding pack: files in it replace the embedded web
+	// assets of the same path, and branding.json sets the product name, logo
+	// and colors. Empty uses the embedded look.
+	BrandingDir string `yaml:"branding_dir" json:"branding_dir"`
+}
+
+// TLSConfig serves the web UI over HTTPS. A self-signed certificate is
+// generated on first boot when CertFile doesn't exist yet; a custom one can
+// be uploaded through /api/system/tls. Changing Enabled needs a restart.
+type TLSConfig struct {
+	Enabled  bool   `yaml:"enabled" json:"enabled"`
+	CertFile string `yaml:"cert_file" json:"cert_file"`
+	KeyFile  string `yaml:"key_file" json:"key_file"`
+}
+
+// AccessConfig restricts which client addresses may reach the web server.
+// Entries are CIDRs (10.0.0.0/8) or bare IPs; an empty list allows everyone.
+type AccessConfig struct {
+	AllowedCIDRs        []string `yaml:"allowed_cidrs" json:"allowed_cidrs"`                 // API and UI
+	PreviewAllowedCIDRs []string `yaml:"preview_allowed_cidrs" json:"preview_allowed_cidrs"` // HLS preview paths
+	APIKeys             []APIKey `yaml:"api_keys" json:"api_keys"`                           // automation clients, managed through /api/access/keys
+	// Require a signed, expiring token, obtained through POST
+	// /api/preview/token, to fetch the HLS previews; a valid token is let in
+	// from anywhere, regardless of preview_allowed_cidrs
+	PreviewTokens          bool `yaml:"preview_tokens" json:"preview_tokens"`
+	PreviewTokenTTLMinutes int  `yaml:"preview_token_ttl_minutes" json:"preview_token_ttl_minutes"` // lifetime of the tokens in preview URLs the API returns; 720 when 0
+}
+
+// APIKey is a scoped key for an automation client. Only its hash is kept.
+type APIKey struct {
+	ID        string    `yaml:"id" json:"id"`
+	Name      string    `yaml:"name" json:"name"`
+	Scopes    []string  `yaml:"scopes" json:"scopes"`
+	Role      string    `yaml:"role,omitempty" json:"role,omitempty"` // viewer or operator, when created with a role
+	Hash      string    `yaml:"hash" json:"-"`
+	CreatedAt time.Time `yaml:"created_at" json:"created_at"`
+	// Window the key is valid in; either end may be left open
+	NotBefore *time.Time `yaml:"not_before,omitempty" json:"not_before,omitempty"`
+	ExpiresAt *time.Time `yaml:"expires_at,omitempty" json:"expires_at,omitempty"`
 }
 
 type LoggingConfig struct {
@@ -52,13 +417,315 @@ type LoggingConfig struct {
 	FilePath   string `yaml:"file_path" json:"file_path"`
 	MaxSizeMB  int    `yaml:"max_size_mb" json:"max_size_mb"`
 	MaxBackups int    `yaml:"max_backups" json:"max_backups"`
+	// Write each child process's output to <name>.log next to file_path
+	// instead of the main log
+	ProcessFiles bool `yaml:"process_files" json:"process_files"`
+}
+
+// TracingConfig configures OpenTelemetry span export for pipeline operations
+type TracingConfig struct {
+	Enabled     bool   `yaml:"enabled" json:"enabled"`
+	Endpoint    string `yaml:"endpoint" json:"endpoint"` // OTLP/HTTP base URL, e.g. http://collector:4318
+	ServiceName string `yaml:"service_name" json:"service_name"`
+}
+
+// MetricsConfig sends stats to sinks besides the in-memory history shown in
+// the UI
+type MetricsConfig struct {
+	Prometheus bool               `yaml:"prometheus" json:"prometheus"` // serve /metrics
+	Store      MetricsStoreConfig `yaml:"store" json:"store"`
+	MQTT       MetricsMQTTConfig  `yaml:"mqtt" json:"mqtt"`
+}
+
+// MetricsStoreConfig keeps stats on disk so they survive restarts
+type MetricsStoreConfig struct {
+	Enabled         bool   `yaml:"enabled" json:"enabled"`
+	Path            string `yaml:"path" json:"path"`                         // JSON lines file
+	IntervalSeconds int    `yaml:"interval_seconds" json:"interval_seconds"` // least time between stored snapshots
+	MaxMB           int    `yaml:"max_mb" json:"max_mb"`                     // size before the file is rotated
+}
+
+// MetricsMQTTConfig publishes stats to an MQTT broker
+type MetricsMQTTConfig struct {
+	Enabled         bool   `yaml:"enabled" json:"enabled"`
+	Broker          string `yaml:"broker" json:"broker"` // mqtt://host:1883 or mqtts://host:8883
+	Topic           string `yaml:"topic" json:"topic"`
+	Username        string `yaml:"username" json:"username"`
+	Password        string `yaml:"password" json:"password"`
+	IntervalSeconds int    `yaml:"interval_seconds" json:"interval_seconds"`
+}
+
+// ReceiverConfig runs srtla_rec so this unit can be the bonding endpoint
+// for other senders. Each bonded sender is forwarded as one SRT stream to the
+// SRT server at srt_host:srt_port.
+type ReceiverConfig struct {
+	Enabled    bool   `yaml:"enabled" json:"enabled"` // run srtla_rec with the manager
+	BinaryPath string `yaml:"binary_path" json:"binary_path"`
+	ListenPort int    `yaml:"listen_port" json:"listen_port"` // UDP port senders connect to
+	SRTHost    string `yaml:"srt_host" json:"srt_host"`
+	SRTPort    int    `yaml:"srt_port" json:"srt_port"`
+}
+
+// AuditConfig keeps a log of state changes and operator actions published
+// on the event bus
+type AuditConfig struct {
+	Enabled bool   `yaml:"enabled" json:"enabled"`
+	Path    string `yaml:"path" json:"path"`     // JSON lines file
+	MaxMB   int    `yaml:"max_mb" json:"max_mb"` // size before the file is rotated
+}
+
+// LoudnessConfig configures EBU R128 compliance monitoring of the outgoing audio
+type LoudnessConfig struct {
+	Enabled     bool    `yaml:"enabled" json:"enabled"`
+	TargetLUFS  float64 `yaml:"target_lufs" json:"target_lufs"`     // integrated loudness target
+	ToleranceLU float64 `yaml:"tolerance_lu" json:"tolerance_lu"`   // allowed deviation from target
+	MaxTruePeak float64 `yaml:"max_true_peak" json:"max_true_peak"` // dBTP ceiling
+	MinDuration int     `yaml:"min_duration" json:"min_duration"`   // seconds of audio before alerting
+	// Per-channel peak and RMS levels for the VU meter. Like the R128
+	// measurement this needs the camera to send audio.
+	Meters bool `yaml:"meters" json:"meters"`
+}
+
+// Where the stream's audio comes from
+const (
+	AudioCamera  = "camera"
+	AudioReplace = "replace"
+	AudioMix     = "mix"
+)
+
+// AudioConfig picks the stream's audio: the camera's, a local input device
+// such as a USB audio interface instead, or both mixed. Anything but the
+// camera's audio as it is gets re-encoded to AAC.
+type AudioConfig struct {
+	Source  string  `yaml:"source" json:"source"`     // camera (default), replace or mix
+	Backend string  `yaml:"backend" json:"backend"`   // alsa (default) or pulse
+	Device  string  `yaml:"device" json:"device"`     // input device, e.g. plughw:CARD=Device
+	GainDB  float64 `yaml:"gain_db" json:"gain_db"`   // applied to the result
+	DelayMs int     `yaml:"delay_ms" json:"delay_ms"` // delays the audio to line it up with the video
+}
+
+// IngestConfig sets when the camera's own uplink to the box counts as
+// degraded, as opposed to the bonded links
+type IngestConfig struct {
+	Enabled       bool `yaml:"enabled" json:"enabled"`
+	MinBitrate    int  `yaml:"min_bitrate" json:"min_bitrate"`         // kbps; 0 disables the check
+	MinFPSPercent int  `yaml:"min_fps_percent" json:"min_fps_percent"` // of the camera's announced frame rate; 0 disables the check
+	HoldSeconds   int  `yaml:"hold_seconds" json:"hold_seconds"`       // how long a problem must last before alerting
+}
+
+// ABRConfig adapts the video bitrate to what the bonded links can carry
+type ABRConfig struct {
+	Enabled        bool    `yaml:"enabled" json:"enabled"`
+	MinKbps        int     `yaml:"min_kbps" json:"min_kbps"`
+	MaxKbps        int     `yaml:"max_kbps" json:"max_kbps"`
+	StepKbps       int     `yaml:"step_kbps" json:"step_kbps"`               // increase per step once the links recover
+	MaxLossPercent float64 `yaml:"max_loss_percent" json:"max_loss_percent"` // SRT loss that lowers the bitrate; needs srt.probe
+	MaxBufferMs    int     `yaml:"max_buffer_ms" json:"max_buffer_ms"`       // SRT send buffer that lowers the bitrate; needs srt.probe
+	WebhookURL     string  `yaml:"webhook_url" json:"webhook_url"`           // told each new bitrate, e.g. a camera or encoder bridge
+	USBCamera      bool    `yaml:"usb_camera" json:"usb_camera"`             // re-encode a USB camera at the new bitrate
+}
+
+// MaintenanceConfig schedules OS updates through the privileged installer
+// and housekeeping into a maintenance window
+type MaintenanceConfig struct {
+	OSUpdate        string   `yaml:"os_update" json:"os_update"`               // "apt" or "script"; "" disables OS updates
+	Scheduled       bool     `yaml:"scheduled" json:"scheduled"`               // update once in each window
+	Days            []string `yaml:"days" json:"days"`                         // sun..sat; empty means every day
+	Start           string   `yaml:"start" json:"start"`                       // local time the window opens, HH:MM
+	DurationMinutes int      `yaml:"duration_minutes" json:"duration_minutes"` // how long the window stays open
+	Housekeeping    bool     `yaml:"housekeeping" json:"housekeeping"`         // tidy up once in each window while idle
+	KeepBackups     int      `yaml:"keep_backups" json:"keep_backups"`         // binary backups kept; default 3
+	KeepDays        int      `yaml:"keep_days" json:"keep_days"`               // days capture segments left behind are kept; default 14
+	Report          bool     `yaml:"report" json:"report"`                     // send a summary through the notifications
+}
+
+// Window returns the maintenance window
+func (m MaintenanceConfig) Window() (maintenance.Window, error) {
+	return maintenance.ParseWindow(m.Days, m.Start, time.Duration(m.DurationMinutes)*time.Minute)
+}
+
+// PairingConfig links two units as an active/passive pair over the LAN
+type PairingConfig struct {
+	Enabled         bool   `yaml:"enabled" json:"enabled"`
+	Role            string `yaml:"role" json:"role"`         // "active" or "passive"
+	PeerURL         string `yaml:"peer_url" json:"peer_url"` // web UI base URL of the other unit
+	Token           string `yaml:"token" json:"token"`       // shared secret, identical on both units
+	SyncConfig      bool   `yaml:"sync_config" json:"sync_config"`
+	FailoverTimeout int    `yaml:"failover_timeout" json:"failover_timeout"` // seconds without contact before takeover
+}
+
+// PowerConfig selects the performance profile and where battery state comes from
+type PowerConfig struct {
+	Profile string `yaml:"profile" json:"profile"`   // auto, normal or low_power
+	UPSName string `yaml:"ups_name" json:"ups_name"` // NUT UPS to query with upsc; sysfs when empty
+}
+
+// CaptureConfig keeps a rolling copy of the exact TS sent to srtla for
+// debugging downstream complaints
+type CaptureConfig struct {
+	Enabled bool   `yaml:"enabled" json:"enabled"`
+	Dir     string `yaml:"dir" json:"dir"`
+	Window  int    `yaml:"window" json:"window"` // seconds of TS kept on disk
+}
+
+// TalkbackConfig plays the director's return audio (RTP/Opus) out of a
+// local audio device for the camera operators
+type TalkbackConfig struct {
+	Enabled     bool    `yaml:"enabled" json:"enabled"`
+	Port        int     `yaml:"port" json:"port"`                 // UDP port for the incoming RTP stream
+	PayloadType int     `yaml:"payload_type" json:"payload_type"` // RTP payload type used for Opus
+	Device      string  `yaml:"device" json:"device"`             // ALSA playback device
+	Volume      float64 `yaml:"volume" json:"volume"`             // linear gain, 1.0 = unchanged
+}
+
+// ReturnFeedConfig pulls the production's program output for a local
+// low-latency preview so operators can see what's on air
+type ReturnFeedConfig struct {
+	Enabled     bool   `yaml:"enabled" json:"enabled"`
+	URL         string `yaml:"url" json:"url"`                   // srt:// or rtmp(s):// program output
+	Width       int    `yaml:"width" json:"width"`               // preview width; 0 passes the stream through
+	BitrateKbps int    `yaml:"bitrate_kbps" json:"bitrate_kbps"` // preview bitrate when scaling
+}
+
+// DisplayConfig drives a small status display showing the unit's address,
+// pipeline state and links
+type DisplayConfig struct {
+	Enabled        bool   `yaml:"enabled" json:"enabled"`
+	Type           string `yaml:"type" json:"type"`                       // ssd1306 or epd2in13
+	I2CBus         int    `yaml:"i2c_bus" json:"i2c_bus"`                 // ssd1306: /dev/i2c-N
+	I2CAddress     int    `yaml:"i2c_address" json:"i2c_address"`         // ssd1306: usually 0x3C (60)
+	Height         int    `yaml:"height" json:"height"`                   // ssd1306: 64 or 32
+	SPIDevice      string `yaml:"spi_device" json:"spi_device"`           // epd2in13
+	DCPin          int    `yaml:"dc_pin" json:"dc_pin"`                   // epd2in13 data/command GPIO
+	ResetPin       int    `yaml:"reset_pin" json:"reset_pin"`             // epd2in13 reset GPIO
+	BusyPin        int    `yaml:"busy_pin" json:"busy_pin"`               // epd2in13 busy GPIO
+	Flip           bool   `yaml:"flip" json:"flip"`                       // rotate 180 degrees
+	RefreshSeconds int    `yaml:"refresh_seconds" json:"refresh_seconds"` // 0 picks the type's default
+}
+
+// AlertsConfig announces stream events as beeps or spoken prompts on a
+// local audio output, for operators who can't watch a screen
+type AlertsConfig struct {
+	Enabled bool     `yaml:"enabled" json:"enabled"`
+	Device  string   `yaml:"device" json:"device"` // ALSA playback device
+	Volume  float64  `yaml:"volume" json:"volume"` // linear gain, 1.0 = unchanged
+	Voice   bool     `yaml:"voice" json:"voice"`   // speak prompts instead of beeping
+	Events  []string `yaml:"events" json:"events"` // events to announce
+}
+
+// NotifyConfig sends the same events as the audible alerts to a webhook
+// and/or a Telegram chat. They wait in a queue on disk while the uplink is
+// down and are delivered, marked as delayed, once it is back.
+type NotifyConfig struct {
+	Enabled    bool           `yaml:"enabled" json:"enabled"`
+	Events     []string       `yaml:"events" json:"events"`           // events to send
+	WebhookURL string         `yaml:"webhook_url" json:"webhook_url"` // POSTed each notification as JSON
+	Telegram   TelegramConfig `yaml:"telegram" json:"telegram"`
+	Email      EmailConfig    `yaml:"email" json:"email"`
+	QueuePath  string         `yaml:"queue_path" json:"queue_path"`
+	MaxQueued  int            `yaml:"max_queued" json:"max_queued"` // deliveries kept while offline; the oldest make way
+}
+
+// TelegramConfig is a bot and the chat it posts to
+type TelegramConfig struct {
+	BotToken string `yaml:"bot_token" json:"bot_token"`
+	ChatID   string `yaml:"chat_id" json:"chat_id"`
+}
+
+// EmailConfig is an SMTP server notifications are mailed through
+type EmailConfig struct {
+	Host     string   `yaml:"host" json:"host"` // "" sends no mail
+	Port     int      `yaml:"port" json:"port"` // 587 when 0; 465 uses TLS from the start
+	Username string   `yaml:"username" json:"username"`
+	Password string   `yaml:"password" json:"password"`
+	From     string   `yaml:"from" json:"from"`
+	To       []string `yaml:"to" json:"to"`
+}
+
+// minEPDRefreshSeconds limits e-paper full refreshes, which flash and wear
+// the panel
+const minEPDRefreshSeconds = 30
+
+// ButtonsConfig maps GPIO push buttons to actions for running the unit
+// without the UI, with an optional status LED
+type ButtonsConfig struct {
+	Enabled      bool           `yaml:"enabled" json:"enabled"`
+	Buttons      []ButtonConfig `yaml:"buttons" json:"buttons"`
+	LEDPin       int            `yaml:"led_pin" json:"led_pin"` // 0 when unused
+	LEDActiveLow bool           `yaml:"led_active_low" json:"led_active_low"`
+}
+
+// ButtonConfig is one push button
+type ButtonConfig struct {
+	Pin       int    `yaml:"pin" json:"pin"`
+	Action    string `yaml:"action" json:"action"`
+	HoldMs    int    `yaml:"hold_ms" json:"hold_ms"`       // how long it must be held to fire
+	ActiveLow bool   `yaml:"active_low" json:"active_low"` // pressed pulls the line low
+}
+
+// minShutdownHoldMs keeps a knock against the rig from powering it off
+const minShutdownHoldMs = 2000
+
+// HotspotConfig is the hotspot last started from the UI, reused when it is
+// started without it
+type HotspotConfig struct {
+	SSID     string `yaml:"ssid" json:"ssid"`
+	Password string `yaml:"password" json:"password"`
+	Band     string `yaml:"band" json:"band"`
+	Channel  int    `yaml:"channel" json:"channel"`
+}
+
+// TimecodeConfig publishes SMPTE time-of-day timecode for the outbound
+// stream so feeds from several units can be lined up in post
+type TimecodeConfig struct {
+	Enabled   bool   `yaml:"enabled" json:"enabled"`
+	Source    string `yaml:"source" json:"source"`         // clock or ltc
+	FPS       int    `yaml:"fps" json:"fps"`               // 24, 25 or 30
+	DropFrame bool   `yaml:"drop_frame" json:"drop_frame"` // 29.97 drop-frame, only with fps 30
+	LTCDevice string `yaml:"ltc_device" json:"ltc_device"` // ALSA capture device carrying LTC
+}
+
+// SwitcherConfig follows a vision switcher to learn whether this unit's
+// input is on program or preview
+type SwitcherConfig struct {
+	Enabled   bool   `yaml:"enabled" json:"enabled"`
+	Type      string `yaml:"type" json:"type"`             // atem or vmix
+	Host      string `yaml:"host" json:"host"`             // switcher address
+	Input     int    `yaml:"input" json:"input"`           // switcher input carrying this unit (1-based)
+	GateBoost bool   `yaml:"gate_boost" json:"gate_boost"` // only allow bitrate boosts while on program
+}
+
+// TallyConfig drives an on-camera tally light from the pipeline state or the
+// switcher
+type TallyConfig struct {
+	Enabled      bool   `yaml:"enabled" json:"enabled"`
+	Source       string `yaml:"source" json:"source"`           // pipeline or switcher
+	Output       string `yaml:"output" json:"output"`           // gpio or serial
+	GPIOPin      int    `yaml:"gpio_pin" json:"gpio_pin"`       // lit on program
+	PreviewPin   int    `yaml:"preview_pin" json:"preview_pin"` // lit on preview; 0 when unused
+	ActiveLow    bool   `yaml:"active_low" json:"active_low"`
+	SerialDevice string `yaml:"serial_device" json:"serial_device"`
+	SerialBaud   int    `yaml:"serial_baud" json:"serial_baud"`
+}
+
+// DJIPreviewConfig sets the RTMP stream a DJI camera sends while previewing.
+// In a camera's override, zero fields inherit the global setting.
+type DJIPreviewConfig struct {
+	Resolution  string `yaml:"resolution,omitempty" json:"resolution,omitempty"` // 480p, 720p or 1080p
+	FPS         int    `yaml:"fps,omitempty" json:"fps,omitempty"`
+	BitrateKbps int    `yaml:"bitrate_kbps,omitempty" json:"bitrate_kbps,omitempty"`
+	ReuseStream *bool  `yaml:"reuse_stream,omitempty" json:"reuse_stream,omitempty"` // preview the full-quality live ingest instead
+	WarmStandby *bool  `yaml:"warm_standby,omitempty" json:"warm_standby,omitempty"` // send the preview to the live ingest
 }
 
 type CameraConfig struct {
-	Name         string `yaml:"name" json:"name"`
-	RTMPUrl      string `yaml:"rtmp_url" json:"rtmp_url"`
-	WiFiSSID     string `yaml:"wifi_ssid" json:"wifi_ssid"`
-	WiFiPassword string `yaml:"wifi_password" json:"wifi_password"`
+	Name         string            `yaml:"name" json:"name"`
+	RTMPUrl      string            `yaml:"rtmp_url" json:"rtmp_url"`
+	WiFiSSID     string            `yaml:"wifi_ssid" json:"wifi_ssid"`
+	WiFiPassword string            `yaml:"wifi_password" json:"wifi_password"`
+	WiFiMAC      string            `yaml:"wifi_mac,omitempty" json:"wifi_mac,omitempty"`       // camera's WiFi MAC on the hotspot
+	ReservedIP   string            `yaml:"reserved_ip,omitempty" json:"reserved_ip,omitempty"` // fixed DHCP address on the hotspot
+	Preview      *DJIPreviewConfig `yaml:"preview,omitempty" json:"preview,omitempty"`         // overrides dji_preview for this camera
 }
 
 // USBCameraConfig stores configuration for USB webcams
@@ -71,10 +738,120 @@ type USBCameraConfig struct {
 	Encoder string `yaml:"encoder" json:"encoder"` // libx264, h264_vaapi, h264_nvenc, copy
 }
 
+// RTSPConfig is an IP camera pulled over RTSP, for PTZ cameras that can't
+// push RTMP
+type RTSPConfig struct {
+	URL       string `yaml:"url" json:"url"` // rtsp:// or rtsps://, without credentials
+	Username  string `yaml:"username" json:"username"`
+	Password  string `yaml:"password" json:"password"`
+	Transport string `yaml:"transport" json:"transport"`       // tcp (default) or udp
+	Encoder   string `yaml:"encoder" json:"encoder"`           // copy (default) passes the camera's H.264 through
+	Bitrate   int    `yaml:"bitrate" json:"bitrate"`           // kbps when re-encoding
+	Resync    bool   `yaml:"audio_resync" json:"audio_resync"` // resample the audio to its timestamps
+}
+
+// CameraGroupConfig names a set of DJI and USB cameras operated together
+type CameraGroupConfig struct {
+	Name string   `yaml:"name" json:"name"`
+	DJI  []string `yaml:"dji" json:"dji"` // DJI camera IDs (BLE address)
+	USB  []string `yaml:"usb" json:"usb"` // USB camera IDs
+}
+
+// StreamProfile is a named stream destination. Starting a stream with a
+// profile copies its settings into rtmp, srt, srtla and ingest, so the rest of
+// the pipeline sees them like hand-edited ones.
+type StreamProfile struct {
+	StreamKey    string `yaml:"stream_key" json:"stream_key"`
+	SRTLocalPort int    `yaml:"srt_local_port" json:"srt_local_port"`
+	RemoteHost   string `yaml:"remote_host" json:"remote_host"`
+	RemotePort   int    `yaml:"remote_port" json:"remote_port"`
+	BindSet      string `yaml:"bind_set,omitempty" json:"bind_set,omitempty"` // bind IP set; "" uses srtla.bind_set
+
+	// Bitrate targets: what DJI cameras are set to stream at (0 uses 6000)
+	// and the ingest alert threshold (0 keeps ingest.min_bitrate)
+	CameraBitrateKbps int `yaml:"camera_bitrate_kbps,omitempty" json:"camera_bitrate_kbps,omitempty"`
+	MinBitrateKbps    int `yaml:"min_bitrate_kbps,omitempty" json:"min_bitrate_kbps,omitempty"`
+}
+
+// RestreamConfig is an RTMP server or SRT peer the camera's stream is
+// also sent to, straight from ffmpeg, while streaming
+type RestreamConfig struct {
+	URL      string `yaml:"url" json:"url"`                               // full ingest URL, stream key included
+	Platform string `yaml:"platform,omitempty" json:"platform,omitempty"` // platform the URL was built for, if any
+	Enabled  bool   `yaml:"enabled" json:"enabled"`
+
+	// Rendezvous meets the srt:// URL's peer, which is its public address,
+	// in SRT rendezvous mode from BindIP:LocalPort, so neither end needs a
+	// port forwarded. The peer must do the same towards this unit's public
+	// address for that port.
+	Rendezvous bool   `yaml:"rendezvous,omitempty" json:"rendezvous,omitempty"`
+	BindIP     string `yaml:"bind_ip,omitempty" json:"bind_ip,omitempty"`
+	LocalPort  int    `yaml:"local_port,omitempty" json:"local_port,omitempty"`
+
+	TLS RestreamTLS `yaml:"tls,omitempty" json:"tls"` // rtmps:// only
+}
+
+// RestreamTLS checks an rtmps:// destination's server certificate, which
+// is always done unless Insecure is set, and holds the client certificate
+// for ingests that require one
+type RestreamTLS struct {
+	Insecure bool   `yaml:"insecure,omitempty" json:"insecure,omitempty"`   // don't check the server's certificate
+	CAFile   string `yaml:"ca_file,omitempty" json:"ca_file,omitempty"`     // PEM CAs the server must chain to; the system's when empty
+	CertFile string `yaml:"cert_file,omitempty" json:"cert_file,omitempty"` // PEM client certificate
+	KeyFile  string `yaml:"key_file,omitempty" json:"key_file,omitempty"`   // PEM key of the client certificate
+}
+
+// ProfileNamePattern is what a stream profile may be called
+var ProfileNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// ApplyProfile copies the named profile into the stream settings
+func (c *Config) ApplyProfile(name string) error {
+	p, ok := c.Profiles[name]
+	if !ok {
+		return fmt.Errorf("no stream profile named %s", name)
+	}
+	c.RTMP.StreamKey = p.StreamKey
+	c.SRT.LocalPort = p.SRTLocalPort
+	c.SRTLA.RemoteHost = p.RemoteHost
+	c.SRTLA.RemotePort = p.RemotePort
+	if p.MinBitrateKbps != 0 {
+		c.Ingest.MinBitrate = p.MinBitrateKbps
+	}
+	c.Profile = name
+	return nil
+}
+
+// Change lists the top-level sections changed by an update
+type Change struct {
+	Sections []string `json:"sections"`
+	External bool     `json:"external,omitempty"` // the file was edited by something else and reloaded
+}
+
+// ReloadFailure reports a file edited by something else that couldn't be
+// loaded; the config in use stays as it was
+type ReloadFailure struct {
+	Error string `json:"error"`
+}
+
+// TopicUpdated carries the sections changed by each update
+var TopicUpdated = events.NewAuditedTopic[Change]("config")
+
+// TopicReloadFailed carries each edit of the file that couldn't be loaded
+var TopicReloadFailed = events.NewAuditedTopic[ReloadFailure]("config_reload_failed")
+
+// WatchSettle is how long Watch waits after the last change to the file
+// before reading it, so a file being written isn't loaded half way
+const WatchSettle = 500 * time.Millisecond
+
 type Manager struct {
 	mu       sync.RWMutex
 	config   *Config
 	filePath string
+	bus      *events.Bus
+
+	// hash of the file as last loaded, saved or reloaded, so Watch only
+	// reloads edits made by something else
+	sum [sha256.Size]byte
 }
 
 func NewManager(filePath string) *Manager {
@@ -96,15 +873,50 @@ func (m *Manager) Load() error {
 		return err
 	}
 
-	var cfg Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
+	cfg, err := decode(data)
+	if err != nil {
 		return err
 	}
 
-	m.config = &cfg
+	m.config = cfg
+	m.seen(data)
 	return nil
 }
 
+// decode reads a config file over the defaults, so a section or setting the
+// file leaves out keeps its default rather than its zero value
+func decode(data []byte) (*Config, error) {
+	cfg := DefaultConfig()
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Parse decodes a configuration file, refusing fields it doesn't know so
+// a misspelt setting isn't silently dropped. It doesn't validate it.
+func Parse(data []byte) (Config, error) {
+	var cfg Config
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// Marshal returns the configuration as it is saved to the file
+func (m *Manager) Marshal() ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return yaml.Marshal(m.config)
+}
+
+// FilePath returns the path of the configuration file
+func (m *Manager) FilePath() string {
+	return m.filePath
+}
+
 func (m *Manager) Save() error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -117,7 +929,96 @@ func (m *Manager) saveUnsafe() error {
 		return err
 	}
 	// Use 0600 permissions since config may contain sensitive data like stream keys
-	return os.WriteFile(m.filePath, data, 0600)
+	if err := os.WriteFile(m.filePath, data, 0600); err != nil {
+		return err
+	}
+	m.seen(data)
+	return nil
+}
+
+// seen records data as the file's contents. m.mu must be held for writing.
+func (m *Manager) seen(data []byte) {
+	m.sum = sha256.Sum256(data)
+}
+
+// Watch reloads the file when something else edits it, such as a
+// provisioning tool, until ctx is done. It watches the directory rather
+// than the file, so a file replaced by renaming another over it, as
+// Ansible does, is still seen. The file is read once settle has passed
+// since its last change. An edit that doesn't validate is reported on
+// TopicReloadFailed and the config in use is kept; one that does is
+// published on TopicUpdated as external.
+func (m *Manager) Watch(ctx context.Context, settle time.Duration) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+	if err := watcher.Add(filepath.Dir(m.filePath)); err != nil {
+		return err
+	}
+	name := filepath.Clean(m.filePath)
+
+	timer := time.NewTimer(settle)
+	timer.Stop()
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case ev, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if filepath.Clean(ev.Name) == name {
+				timer.Reset(settle)
+			}
+		case _, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			// events were lost, so check the file anyway
+			timer.Reset(settle)
+		case <-timer.C:
+			m.reload()
+		}
+	}
+}
+
+// reload loads the file if it changed since it was last seen
+func (m *Manager) reload() {
+	m.mu.Lock()
+	data, err := os.ReadFile(m.filePath)
+	if err != nil {
+		m.mu.Unlock()
+		return
+	}
+	sum := sha256.Sum256(data)
+	if sum == m.sum {
+		m.mu.Unlock()
+		return
+	}
+	m.sum = sum
+
+	cfg, err := decode(data)
+	if err == nil {
+		err = cfg.Validate()
+	}
+	var changed []string
+	if err == nil {
+		changed = changedSections(m.config, cfg)
+		m.config = cfg
+	}
+	bus := m.bus
+	m.mu.Unlock()
+
+	if err != nil {
+		events.Publish(bus, TopicReloadFailed, ReloadFailure{Error: err.Error()})
+		return
+	}
+	if len(changed) > 0 {
+		events.Publish(bus, TopicUpdated, Change{Sections: changed, External: true})
+	}
 }
 
 func (m *Manager) Get() Config {
@@ -126,14 +1027,45 @@ func (m *Manager) Get() Config {
 	return *m.config
 }
 
+// SetBus publishes the changed sections on bus after each update
+func (m *Manager) SetBus(bus *events.Bus) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.bus = bus
+}
+
 func (m *Manager) Update(cfg Config) error {
 	if err := cfg.Validate(); err != nil {
 		return err
 	}
 	m.mu.Lock()
-	defer m.mu.Unlock()
+	changed := changedSections(m.config, &cfg)
 	m.config = &cfg
-	return m.saveUnsafe()
+	err := m.saveUnsafe()
+	bus := m.bus
+	m.mu.Unlock()
+
+	if len(changed) > 0 {
+		events.Publish(bus, TopicUpdated, Change{Sections: changed})
+	}
+	return err
+}
+
+// changedSections returns the yaml names of the top-level sections that
+// differ, leaving the values themselves out of the event
+func changedSections(old, cfg *Config) []string {
+	if old == nil {
+		return nil
+	}
+	var changed []string
+	a, b := reflect.ValueOf(*old), reflect.ValueOf(*cfg)
+	for i := 0; i < a.NumField(); i++ {
+		if !reflect.DeepEqual(a.Field(i).Interface(), b.Field(i).Interface()) {
+			name, _, _ := strings.Cut(a.Type().Field(i).Tag.Get("yaml"), ",")
+			changed = append(changed, name)
+		}
+	}
+	return changed
 }
 
 func (m *Manager) UpdateBindIPs(ips []string) error {
@@ -208,74 +1140,716 @@ func (m *Manager) SaveBindIPsToFile() error {
 	return os.WriteFile(filePath, []byte(content), 0644)
 }
 
+// BindSetNamePattern is what a bind IP set may be called
+var BindSetNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_+-]{0,63}$`)
+
+// validateBindIPs checks a list of bind IPs, skipping blank entries
+func validateBindIPs(v *validate.Validator, field string, ips []string) {
+	seen := make(map[string]bool)
+	for i, ip := range ips {
+		ip = strings.TrimSpace(ip)
+		if ip == "" {
+			continue
+		}
+		f := fmt.Sprintf("%s[%d]", field, i)
+		v.IP(f, ip)
+		if seen[ip] {
+			v.Addf(f, "duplicate bind IP %s", ip)
+		}
+		seen[ip] = true
+	}
+}
+
 // Validate checks if the configuration is valid and returns detailed errors
 func (c *Config) Validate() error {
-	var errors []string
+	v := validate.New()
+
+	v.Port("rtmp.listen_port", c.RTMP.ListenPort)
+	v.Range("rtmp.reconnect_grace_seconds", c.RTMP.ReconnectGraceSeconds, 0, 120)
+	ingestPorts := map[int]bool{c.RTMP.ListenPort: true}
+	ingestNames := map[string]bool{"main": true}
+	for i, in := range c.RTMP.Ingests {
+		prefix := fmt.Sprintf("rtmp.ingests[%d]", i)
+		if !ProfileNamePattern.MatchString(in.Name) {
+			v.Addf(prefix+".name", "name must be lowercase letters, digits, '-' or '_'")
+		} else if ingestNames[in.Name] {
+			v.Addf(prefix+".name", "%q is already used", in.Name)
+		}
+		ingestNames[in.Name] = true
+		v.Required(prefix+".stream_key", in.StreamKey)
+		v.Port(prefix+".listen_port", in.ListenPort)
+		if ingestPorts[in.ListenPort] {
+			v.Addf(prefix+".listen_port", "port %d is already used by another stream key", in.ListenPort)
+		}
+		ingestPorts[in.ListenPort] = true
+	}
+	if len(c.SRTIngest.StreamIDs) > 0 {
+		port := c.SRTIngest.Port()
+		v.Port("srt_ingest.listen_port", port)
+		if port == c.SRT.LocalPort || (c.SRT.Probe && port == c.SRT.ProbePort) ||
+			(c.Receiver.Enabled && port == c.Receiver.ListenPort) {
+			v.Addf("srt_ingest.listen_port", "port %d is already used by the video pipeline", port)
+		}
+		if c.SRTIngest.LatencyMs != 0 {
+			v.Range("srt_ingest.latency_ms", c.SRTIngest.LatencyMs, 20, 8000)
+		}
+		streamIDs := map[string]bool{}
+		for i, in := range c.SRTIngest.StreamIDs {
+			prefix := fmt.Sprintf("srt_ingest.stream_ids[%d]", i)
+			if !ProfileNamePattern.MatchString(in.Name) {
+				v.Addf(prefix+".name", "name must be lowercase letters, digits, '-' or '_'")
+			} else if ingestNames[in.Name] {
+				v.Addf(prefix+".name", "%q is already used", in.Name)
+			}
+			ingestNames[in.Name] = true
+			v.Required(prefix+".stream_id", in.StreamID)
+			if len(in.StreamID) > 512 {
+				v.Addf(prefix+".stream_id", "must be at most 512 characters")
+			} else if streamIDs[in.StreamID] {
+				v.Addf(prefix+".stream_id", "is already used by another camera")
+			}
+			streamIDs[in.StreamID] = true
+			if in.Passphrase != "" && (len(in.Passphrase) < 10 || len(in.Passphrase) > 79) {
+				v.Addf(prefix+".passphrase", "must be 10 to 79 characters")
+			} else if strings.ContainsAny(in.Passphrase, "&#") {
+				v.Addf(prefix+".passphrase", "must not contain '&' or '#'")
+			}
+		}
+	}
+	v.Port("srt.local_port", c.SRT.LocalPort)
+	if c.SRT.Probe {
+		v.Port("srt.probe_port", c.SRT.ProbePort)
+		if c.SRT.ProbePort == c.SRT.LocalPort {
+			v.Addf("srt.probe_port", "must differ from srt.local_port")
+		}
+	}
+	v.Port("web.port", c.Web.Port)
+	if c.Web.BrandingDir != "" && !filepath.IsAbs(c.Web.BrandingDir) {
+		v.Addf("web.branding_dir", "must be an absolute path")
+	}
+	if c.Web.TLS.Enabled {
+		v.Required("web.tls.cert_file", c.Web.TLS.CertFile)
+		v.Required("web.tls.key_file", c.Web.TLS.KeyFile)
+	}
+
+	// Validate SRTLA configuration when enabled
+	// Note: we don't validate the binary path here - it will be checked at runtime
+	// when SRTLA is actually started
+	if c.SRTLA.Enabled {
+		v.Required("srtla.remote_host", c.SRTLA.RemoteHost)
+		v.Port("srtla.remote_port", c.SRTLA.RemotePort)
+		v.OneOf("srtla.transport", c.SRTLA.Transport, transport.Names...)
+
+		validateBindIPs(v, "srtla.bind_ips", c.SRTLA.BindIPs)
+		for name, ips := range c.SRTLA.BindSets {
+			if !BindSetNamePattern.MatchString(name) {
+				v.Addf("srtla.bind_sets."+name, "name must be lowercase letters, digits, '-', '+' or '_'")
+			}
+			validateBindIPs(v, "srtla.bind_sets."+name, ips)
+		}
+		if _, ok := c.SRTLA.BindSets[c.SRTLA.BindSet]; c.SRTLA.BindSet != "" && !ok {
+			v.Addf("srtla.bind_set", "no bind IP set named %s", c.SRTLA.BindSet)
+		}
+		for ip, p := range c.SRTLA.LinkPolicies {
+			f := "srtla.link_policies." + ip
+			v.IP(f, ip)
+			v.Range(f+".priority", p.Priority, 0, 100)
+			if p.MaxKbps != 0 {
+				v.Range(f+".max_kbps", p.MaxKbps, 100, 1000000)
+			}
+		}
+	}
 
-	// Validate RTMP port
-	if c.RTMP.ListenPort < 1 || c.RTMP.ListenPort > 65535 {
-		errors = append(errors, fmt.Sprintf("RTMP port %d is invalid (must be 1-65535)", c.RTMP.ListenPort))
+	v.OneOf("power.profile", c.Power.Profile, power.Modes...)
+
+	if c.Capture.Enabled {
+		v.Required("capture.dir", c.Capture.Dir)
+		v.Range("capture.window", c.Capture.Window, 10, 3600)
 	}
 
-	// Validate SRT port
-	if c.SRT.LocalPort < 1 || c.SRT.LocalPort > 65535 {
-		errors = append(errors, fmt.Sprintf("SRT port %d is invalid (must be 1-65535)", c.SRT.LocalPort))
+	// Validate access allowlists
+	for i, entry := range c.Access.AllowedCIDRs {
+		v.CIDROrIP(fmt.Sprintf("access.allowed_cidrs[%d]", i), entry)
+	}
+	for i, entry := range c.Access.PreviewAllowedCIDRs {
+		v.CIDROrIP(fmt.Sprintf("access.preview_allowed_cidrs[%d]", i), entry)
+	}
+	v.Range("access.preview_token_ttl_minutes", c.Access.PreviewTokenTTLMinutes, 0, 10080)
+	for i, key := range c.Access.APIKeys {
+		v.Required(fmt.Sprintf("access.api_keys[%d].name", i), key.Name)
+		if len(key.Scopes) == 0 {
+			v.Addf(fmt.Sprintf("access.api_keys[%d].scopes", i), "at least one scope is required")
+		}
+		for _, scope := range key.Scopes {
+			v.OneOf(fmt.Sprintf("access.api_keys[%d].scopes", i), scope, apikey.Scopes...)
+		}
+		if key.Role != "" {
+			v.OneOf(fmt.Sprintf("access.api_keys[%d].role", i), key.Role, apikey.RoleViewer, apikey.RoleOperator)
+		}
+		if key.NotBefore != nil && key.ExpiresAt != nil && !key.ExpiresAt.After(*key.NotBefore) {
+			v.Addf(fmt.Sprintf("access.api_keys[%d].expires_at", i), "must be after not_before")
+		}
 	}
 
-	// Validate Web port
-	if c.Web.Port < 1 || c.Web.Port > 65535 {
-		errors = append(errors, fmt.Sprintf("Web port %d is invalid (must be 1-65535)", c.Web.Port))
+	// Validate loudness targets
+	if c.Loudness.Enabled {
+		if c.Loudness.TargetLUFS > 0 || c.Loudness.TargetLUFS < -70 {
+			v.Addf("loudness.target_lufs", "%.1f LUFS is out of range (-70 to 0)", c.Loudness.TargetLUFS)
+		}
+		if c.Loudness.ToleranceLU <= 0 {
+			v.Addf("loudness.tolerance_lu", "must be greater than 0 LU")
+		}
+		if c.Loudness.MinDuration < 0 {
+			v.Addf("loudness.min_duration", "must not be negative")
+		}
 	}
 
-	// Validate SRTLA configuration when enabled
-	if c.SRTLA.Enabled {
-		// Validate remote host
-		if c.SRTLA.RemoteHost == "" {
-			errors = append(errors, "SRTLA remote host is required when SRTLA is enabled")
+	// Validate ingest thresholds
+	if c.Ingest.Enabled {
+		if c.Ingest.MinBitrate < 0 {
+			v.Addf("ingest.min_bitrate", "must not be negative")
+		}
+		if c.Ingest.MinFPSPercent < 0 || c.Ingest.MinFPSPercent > 100 {
+			v.Addf("ingest.min_fps_percent", "%d is out of range (0 to 100)", c.Ingest.MinFPSPercent)
+		}
+		if c.Ingest.HoldSeconds < 0 {
+			v.Addf("ingest.hold_seconds", "must not be negative")
 		}
+	}
 
-		// Validate remote port
-		if c.SRTLA.RemotePort < 1 || c.SRTLA.RemotePort > 65535 {
-			errors = append(errors, fmt.Sprintf("SRTLA remote port %d is invalid (must be 1-65535)", c.SRTLA.RemotePort))
+	// Validate adaptive bitrate
+	if c.ABR.Enabled {
+		v.Range("abr.min_kbps", c.ABR.MinKbps, validate.MinBitrateKbps, validate.MaxBitrateKbps)
+		v.Range("abr.max_kbps", c.ABR.MaxKbps, validate.MinBitrateKbps, validate.MaxBitrateKbps)
+		if c.ABR.MinKbps > c.ABR.MaxKbps {
+			v.Addf("abr.min_kbps", "must not exceed abr.max_kbps")
+		}
+		v.Range("abr.step_kbps", c.ABR.StepKbps, 50, 5000)
+		if c.ABR.MaxLossPercent < 0 || c.ABR.MaxLossPercent > 100 {
+			v.Addf("abr.max_loss_percent", "%.1f is out of range (0 to 100)", c.ABR.MaxLossPercent)
+		}
+		v.Range("abr.max_buffer_ms", c.ABR.MaxBufferMs, 0, 10000)
+		if c.ABR.WebhookURL != "" {
+			v.URL("abr.webhook_url", c.ABR.WebhookURL, "http", "https")
 		}
 	}
 
-	// Validate bind IPs if SRTLA is enabled and bind IPs are configured
-	// Note: we don't validate the binary path here - it will be checked at runtime
-	// when SRTLA is actually started
-	if c.SRTLA.Enabled && len(c.SRTLA.BindIPs) > 0 {
-		// Validate bind IP addresses
-		for i, ip := range c.SRTLA.BindIPs {
-			ip = strings.TrimSpace(ip)
-			if ip == "" {
-				continue
+	// Validate maintenance
+	v.OneOf("maintenance.os_update", c.Maintenance.OSUpdate, maintenance.Methods...)
+	if c.Maintenance.Scheduled {
+		v.Required("maintenance.os_update", c.Maintenance.OSUpdate)
+	}
+	if c.Maintenance.Scheduled || c.Maintenance.Housekeeping {
+		if _, err := c.Maintenance.Window(); err != nil {
+			v.Addf("maintenance", "%v", err)
+		}
+	}
+	v.Range("maintenance.keep_backups", c.Maintenance.KeepBackups, 0, 50)
+	v.Range("maintenance.keep_days", c.Maintenance.KeepDays, 0, 365)
+	if c.Maintenance.Report && !c.Notify.Enabled {
+		v.Addf("maintenance.report", "needs notifications.enabled")
+	}
+
+	// Validate pairing
+	if c.Pairing.Enabled {
+		v.Required("pairing.role", c.Pairing.Role)
+		v.OneOf("pairing.role", c.Pairing.Role, "active", "passive")
+		v.Required("pairing.peer_url", c.Pairing.PeerURL)
+		v.URL("pairing.peer_url", c.Pairing.PeerURL, "http", "https")
+		v.Required("pairing.token", c.Pairing.Token)
+		if c.Pairing.FailoverTimeout < 3 {
+			v.Addf("pairing.failover_timeout", "must be at least 3 seconds")
+		}
+	}
+
+	validateDJIPreview(v, "dji_preview", c.DJIPreview)
+
+	if c.Buttons.Enabled {
+		c.validateButtons(v)
+	}
+
+	if c.Display.Enabled {
+		c.validateDisplay(v)
+	}
+
+	c.validateGPIOPins(v)
+
+	if c.Alerts.Enabled {
+		v.Required("alerts.device", c.Alerts.Device)
+		if c.Alerts.Volume < 0 || c.Alerts.Volume > 4 {
+			v.Addf("alerts.volume", "%.2f is out of range (0 to 4)", c.Alerts.Volume)
+		}
+		for i, e := range c.Alerts.Events {
+			v.OneOf(fmt.Sprintf("alerts.events[%d]", i), e, alerts.Events...)
+		}
+	}
+
+	if c.Notify.Enabled {
+		v.URL("notifications.webhook_url", c.Notify.WebhookURL, "http", "https")
+		if (c.Notify.Telegram.BotToken == "") != (c.Notify.Telegram.ChatID == "") {
+			v.Addf("notifications.telegram", "bot_token and chat_id go together")
+		}
+		if c.Notify.Email.Host != "" {
+			if c.Notify.Email.Port != 0 {
+				v.Port("notifications.email.port", c.Notify.Email.Port)
+			}
+			if _, err := mail.ParseAddress(c.Notify.Email.From); err != nil {
+				v.Addf("notifications.email.from", "must be an email address")
+			}
+			if len(c.Notify.Email.To) == 0 {
+				v.Addf("notifications.email.to", "must list at least one address")
+			}
+			for i, to := range c.Notify.Email.To {
+				if _, err := mail.ParseAddress(to); err != nil {
+					v.Addf(fmt.Sprintf("notifications.email.to[%d]", i), "must be an email address")
+				}
+			}
+		}
+		if c.Notify.WebhookURL == "" && c.Notify.Telegram.BotToken == "" && c.Notify.Email.Host == "" {
+			v.Addf("notifications", "set webhook_url, telegram or email")
+		}
+		v.Required("notifications.queue_path", c.Notify.QueuePath)
+		v.Range("notifications.max_queued", c.Notify.MaxQueued, 1, 10000)
+		for i, e := range c.Notify.Events {
+			v.OneOf(fmt.Sprintf("notifications.events[%d]", i), e, alerts.Events...)
+		}
+	}
+
+	if c.Timecode.Enabled {
+		v.Required("timecode.source", c.Timecode.Source)
+		v.OneOf("timecode.source", c.Timecode.Source, timecode.Sources...)
+		if c.Timecode.FPS != 24 && c.Timecode.FPS != 25 && c.Timecode.FPS != 30 {
+			v.Addf("timecode.fps", "%d is not supported (24, 25 or 30)", c.Timecode.FPS)
+		}
+		if c.Timecode.DropFrame && c.Timecode.FPS != 30 {
+			v.Addf("timecode.drop_frame", "drop-frame is only defined for 30 fps")
+		}
+		if c.Timecode.Source == timecode.SourceLTC {
+			v.Required("timecode.ltc_device", c.Timecode.LTCDevice)
+		}
+	}
+
+	if c.Switcher.Enabled {
+		v.Required("switcher.type", c.Switcher.Type)
+		v.OneOf("switcher.type", c.Switcher.Type, tally.Switchers...)
+		v.Required("switcher.host", c.Switcher.Host)
+		v.Range("switcher.input", c.Switcher.Input, 1, 1000)
+	}
+
+	if c.Tally.Enabled {
+		v.Required("tally.source", c.Tally.Source)
+		v.OneOf("tally.source", c.Tally.Source, tally.Sources...)
+		if c.Tally.Source == tally.SourceSwitcher && !c.Switcher.Enabled {
+			v.Addf("tally.source", "the switcher must be enabled to drive tally from it")
+		}
+		v.Required("tally.output", c.Tally.Output)
+		v.OneOf("tally.output", c.Tally.Output, tally.Outputs...)
+		switch c.Tally.Output {
+		case tally.OutputGPIO:
+			v.Range("tally.gpio_pin", c.Tally.GPIOPin, 1, 1023)
+			if c.Tally.PreviewPin != 0 {
+				v.Range("tally.preview_pin", c.Tally.PreviewPin, 1, 1023)
+				if c.Tally.PreviewPin == c.Tally.GPIOPin {
+					v.Addf("tally.preview_pin", "must differ from gpio_pin")
+				}
 			}
-			if net.ParseIP(ip) == nil {
-				errors = append(errors, fmt.Sprintf("bind IP #%d '%s' is not a valid IP address", i+1, ip))
+		case tally.OutputSerial:
+			v.Required("tally.serial_device", c.Tally.SerialDevice)
+			v.Range("tally.serial_baud", c.Tally.SerialBaud, 1200, 921600)
+		}
+	}
+
+	if c.ReturnFeed.Enabled {
+		v.Required("return_feed.url", c.ReturnFeed.URL)
+		v.URL("return_feed.url", c.ReturnFeed.URL, "srt", "rtmp", "rtmps")
+		if c.ReturnFeed.Width != 0 {
+			v.Range("return_feed.width", c.ReturnFeed.Width, 160, 1920)
+			v.Range("return_feed.bitrate_kbps", c.ReturnFeed.BitrateKbps, 100, 10000)
+		}
+	}
+
+	if c.Talkback.Enabled {
+		v.Port("talkback.port", c.Talkback.Port)
+		v.Range("talkback.payload_type", c.Talkback.PayloadType, 96, 127)
+		v.Required("talkback.device", c.Talkback.Device)
+		if c.Talkback.Volume < 0 || c.Talkback.Volume > 4 {
+			v.Addf("talkback.volume", "%.2f is out of range (0 to 4)", c.Talkback.Volume)
+		}
+		if c.Talkback.Port == c.RTMP.ListenPort || c.Talkback.Port == c.SRT.LocalPort {
+			v.Addf("talkback.port", "port %d is already used by the video pipeline", c.Talkback.Port)
+		}
+	}
+
+	// Validate camera DHCP reservations
+	reservedIPs := make(map[string]string)
+	for id, cam := range c.Cameras {
+		if cam.Preview != nil {
+			validateDJIPreview(v, fmt.Sprintf("cameras.%s.preview", id), *cam.Preview)
+		}
+		if cam.WiFiMAC == "" && cam.ReservedIP == "" {
+			continue
+		}
+		macField := fmt.Sprintf("cameras.%s.wifi_mac", id)
+		ipField := fmt.Sprintf("cameras.%s.reserved_ip", id)
+		v.Required(macField, cam.WiFiMAC)
+		v.MAC(macField, cam.WiFiMAC)
+		v.Required(ipField, cam.ReservedIP)
+		v.IPv4(ipField, cam.ReservedIP)
+		if other, dup := reservedIPs[cam.ReservedIP]; dup && cam.ReservedIP != "" {
+			v.Addf(ipField, "%s is already used by camera %s", cam.ReservedIP, other)
+		} else {
+			reservedIPs[cam.ReservedIP] = id
+		}
+	}
+
+	// Validate saved USB camera encoding settings
+	for id, cam := range c.USBCameras {
+		prefix := "usb_cameras." + id
+		v.Resolution(prefix+".width", prefix+".height", cam.Width, cam.Height)
+		v.FPS(prefix+".fps", cam.FPS)
+		v.Bitrate(prefix+".bitrate", cam.Bitrate)
+	}
+
+	// Validate the RTSP camera
+	if c.RTSP.URL != "" {
+		v.URL("rtsp.url", c.RTSP.URL, "rtsp", "rtsps")
+		if u, err := url.Parse(c.RTSP.URL); err == nil && u.User != nil {
+			v.Addf("rtsp.url", "put the credentials in rtsp.username and rtsp.password")
+		}
+	}
+	v.OneOf("rtsp.transport", c.RTSP.Transport, process.RTSPTransports...)
+	v.OneOf("rtsp.encoder", c.RTSP.Encoder, process.Encoders...)
+	v.Bitrate("rtsp.bitrate", c.RTSP.Bitrate)
+
+	// Validate camera groups
+	for id, group := range c.CameraGroups {
+		prefix := "camera_groups." + id
+		v.Required(prefix+".name", group.Name)
+		for _, members := range []struct {
+			kind string
+			ids  []string
+		}{{"dji", group.DJI}, {"usb", group.USB}} {
+			seen := make(map[string]bool)
+			for i, cam := range members.ids {
+				if seen[cam] {
+					v.Addf(fmt.Sprintf("%s.%s[%d]", prefix, members.kind, i), "camera %s is listed twice", cam)
+				}
+				seen[cam] = true
 			}
 		}
+	}
 
-		// Check for duplicate bind IPs
-		seenIPs := make(map[string]bool)
-		for _, ip := range c.SRTLA.BindIPs {
-			ip = strings.TrimSpace(ip)
-			if ip == "" {
-				continue
+	// Validate restream destinations
+	rendezvousPorts := make(map[string]string)
+	for _, name := range slices.Sorted(maps.Keys(c.Restream)) {
+		d := c.Restream[name]
+		prefix := "restream." + name
+		if !ProfileNamePattern.MatchString(name) {
+			v.Addf(prefix, "name must be lowercase letters, digits, '-' or '_'")
+		}
+		v.Required(prefix+".url", d.URL)
+		if d.Rendezvous {
+			v.URL(prefix+".url", d.URL, "srt")
+			v.Required(prefix+".bind_ip", d.BindIP)
+			v.IP(prefix+".bind_ip", d.BindIP)
+			v.Range(prefix+".local_port", d.LocalPort, 1024, 65535)
+			local := fmt.Sprintf("%s:%d", d.BindIP, d.LocalPort)
+			if other, ok := rendezvousPorts[local]; ok {
+				v.Addf(prefix+".local_port", "%s is already used by restream.%s", local, other)
 			}
-			if seenIPs[ip] {
-				errors = append(errors, fmt.Sprintf("duplicate bind IP: %s", ip))
+			rendezvousPorts[local] = name
+		} else {
+			v.URL(prefix+".url", d.URL, "rtmp", "rtmps", "srt")
+		}
+		if d.TLS != (RestreamTLS{}) && !strings.HasPrefix(d.URL, "rtmps://") {
+			v.Addf(prefix+".tls", "only applies to rtmps:// URLs")
+		}
+		if (d.TLS.CertFile == "") != (d.TLS.KeyFile == "") {
+			v.Addf(prefix+".tls", "cert_file and key_file must be set together")
+		}
+	}
+	if c.Quota.Enabled {
+		v.Required("quota.path", c.Quota.Path)
+	}
+	for imei, q := range c.Quota.Modems {
+		prefix := "quota.modems." + imei
+		if !imeiPattern.MatchString(imei) {
+			v.Addf(prefix, "must be keyed by the modem's IMEI")
+		}
+		v.OneOf(prefix+".period", q.Period, quota.Periods...)
+		if q.LimitMB < 1 {
+			v.Addf(prefix+".limit_mb", "must be at least 1")
+		}
+		v.Range(prefix+".reset_day", q.ResetDay, 0, 28)
+		for i, pct := range q.WarnPercent {
+			v.Range(fmt.Sprintf("%s.warn_percent[%d]", prefix, i), pct, 1, 99)
+		}
+	}
+	if c.ModemConnect.RetrySeconds != 0 {
+		v.Range("modem_connect.retry_seconds", c.ModemConnect.RetrySeconds, 5, 3600)
+	}
+	if c.Overlay.Token != "" && len(c.Overlay.Token) < 16 {
+		v.Addf("overlay.token", "must be at least 16 characters")
+	}
+	if len([]rune(c.Overlay.Label)) > 64 {
+		v.Addf("overlay.label", "must be at most 64 characters")
+	}
+	if c.LinkProbe.Enabled {
+		v.OneOf("link_probe.method", c.LinkProbe.Method, linkprobe.Methods...)
+		if c.LinkProbe.Method == linkprobe.MethodHTTP {
+			v.URL("link_probe.target", c.LinkProbe.Target, "http", "https")
+		} else if strings.HasPrefix(c.LinkProbe.Target, "-") || strings.ContainsAny(c.LinkProbe.Target, " \t/") {
+			v.Addf("link_probe.target", "must be a host name or IP address")
+		}
+		if c.LinkProbe.IntervalSeconds != 0 {
+			v.Range("link_probe.interval_seconds", c.LinkProbe.IntervalSeconds, 1, 300)
+		}
+		if c.LinkProbe.TimeoutSeconds != 0 {
+			v.Range("link_probe.timeout_seconds", c.LinkProbe.TimeoutSeconds, 1, 30)
+		}
+		v.Range("link_probe.fail_after", c.LinkProbe.FailAfter, 0, 20)
+		v.Range("link_probe.pass_after", c.LinkProbe.PassAfter, 0, 20)
+	}
+	v.OneOf("vpn.provider", c.VPN.Provider, vpn.Providers...)
+	if c.VPN.Enabled {
+		v.Required("vpn.provider", c.VPN.Provider)
+	}
+	if c.VPN.Interface != "" && !wgInterfacePattern.MatchString(c.VPN.Interface) {
+		v.Addf("vpn.interface", "must be a WireGuard interface name of up to 15 characters")
+	}
+	if c.VPN.Hostname != "" && !hostnamePattern.MatchString(c.VPN.Hostname) {
+		v.Addf("vpn.hostname", "must be a host name of letters, digits and hyphens")
+	}
+	if strings.ContainsAny(c.VPN.AuthKey, " \t\n") {
+		v.Addf("vpn.auth_key", "must not contain whitespace")
+	}
+	for _, name := range []string{"usbcam", "modem", "usbnet", "dji"} {
+		d := c.Discovery.Class(name)
+		v.OneOf("discovery."+name+".mode", d.Mode, DiscoveryModes...)
+		if d.IntervalSeconds != 0 {
+			v.Range("discovery."+name+".interval_seconds", d.IntervalSeconds, 1, 3600)
+		}
+	}
+	if c.Routing.TableBase != 0 {
+		v.Range("routing.table_base", c.Routing.TableBase, 1, 200)
+	}
+	if c.Routing.RulePriority != 0 {
+		v.Range("routing.rule_priority", c.Routing.RulePriority, 1, 32000)
+	}
+	apnPrefixes := make(map[string]bool)
+	for i, r := range c.ModemConnect.APNs {
+		prefix := fmt.Sprintf("modem_connect.apns[%d]", i)
+		if strings.Trim(r.IMSIPrefix, "0123456789") != "" || len(r.IMSIPrefix) > 15 {
+			v.Addf(prefix+".imsi_prefix", "must be up to 15 digits")
+		}
+		if apnPrefixes[r.IMSIPrefix] {
+			v.Addf(prefix+".imsi_prefix", "%q is already used by another APN", r.IMSIPrefix)
+		}
+		apnPrefixes[r.IMSIPrefix] = true
+		v.Required(prefix+".apn", r.APN)
+		// mmcli takes the settings as a comma separated key=value list
+		for field, value := range map[string]string{"apn": r.APN, "user": r.User, "password": r.Password} {
+			if strings.ContainsAny(value, ",='\"") {
+				v.Addf(prefix+"."+field, "must not contain commas, '=' or quotes")
 			}
-			seenIPs[ip] = true
 		}
+		if r.IPType != "" {
+			v.OneOf(prefix+".ip_type", r.IPType, "ipv4", "ipv6", "ipv4v6")
+		}
+	}
+	for i, server := range c.STUN.Servers {
+		if !stun.ValidServer(server) {
+			v.Addf(fmt.Sprintf("stun.servers[%d]", i), "must be host:port")
+		}
+	}
+
+	// Validate the audio source
+	v.OneOf("audio.source", c.Audio.Source, AudioCamera, AudioReplace, AudioMix)
+	v.OneOf("audio.backend", c.Audio.Backend, "alsa", "pulse")
+	if c.Audio.Source == AudioReplace || c.Audio.Source == AudioMix {
+		v.Required("audio.device", c.Audio.Device)
+	}
+	if c.Audio.GainDB < -30 || c.Audio.GainDB > 30 {
+		v.Addf("audio.gain_db", "%.1f dB is out of range (-30 to 30)", c.Audio.GainDB)
+	}
+	v.Range("audio.delay_ms", c.Audio.DelayMs, 0, 5000)
+
+	// Validate the source failover
+	if c.Failover.Enabled {
+		primary, backup := c.Failover.Sources()
+		v.OneOf("failover.primary", primary, SourceRTMP, SourceSRT, SourceRTSP, SourceUSB)
+		v.OneOf("failover.backup", backup, SourceSlate, SourceRTMP, SourceSRT, SourceRTSP, SourceUSB)
+		if primary == backup {
+			v.Addf("failover.backup", "must differ from the primary source")
+		}
+		if primary == SourceRTSP || backup == SourceRTSP {
+			v.Required("rtsp.url", c.RTSP.URL)
+		}
+		if primary == SourceUSB || backup == SourceUSB {
+			v.Required("failover.usb_camera", c.Failover.USBCamera)
+		}
+		if (primary == SourceSRT || backup == SourceSRT) && len(c.SRTIngest.StreamIDs) == 0 {
+			v.Addf("srt_ingest.stream_ids", "at least one is needed for the srt failover source")
+		}
+		v.Range("failover.loss_seconds", c.Failover.LossSeconds, 0, 60)
+		v.Range("failover.restore_seconds", c.Failover.RestoreSeconds, 0, 600)
+	}
+
+	// Validate the link speed test
+	v.URL("speedtest.url", c.SpeedTest.URL, "http", "https")
+	v.Range("speedtest.seconds", c.SpeedTest.Seconds, 0, 60)
+
+	// Validate the start policy
+	v.Range("start_policy.min_links", c.StartPolicy.MinLinks, 0, 64)
+	v.Range("start_policy.min_bandwidth_kbps", c.StartPolicy.MinBandwidthKbps, 0, 1000000)
+	v.Range("start_policy.speedtest_max_age_minutes", c.StartPolicy.SpeedTestMaxAge, 0, 10080)
+	for i, name := range c.StartPolicy.RequiredLinks {
+		v.Required(fmt.Sprintf("start_policy.required_links[%d]", i), strings.TrimSpace(name))
+	}
+
+	// Validate stream profiles
+	for name, p := range c.Profiles {
+		prefix := "profiles." + name
+		if !ProfileNamePattern.MatchString(name) {
+			v.Addf(prefix, "name must be lowercase letters, digits, '-' or '_'")
+		}
+		v.Required(prefix+".stream_key", p.StreamKey)
+		v.Port(prefix+".srt_local_port", p.SRTLocalPort)
+		v.Required(prefix+".remote_host", p.RemoteHost)
+		v.Port(prefix+".remote_port", p.RemotePort)
+		if _, ok := c.SRTLA.BindSets[p.BindSet]; p.BindSet != "" && !ok {
+			v.Addf(prefix+".bind_set", "no bind IP set named %s", p.BindSet)
+		}
+		v.Bitrate(prefix+".camera_bitrate_kbps", p.CameraBitrateKbps)
+		if p.MinBitrateKbps < 0 {
+			v.Addf(prefix+".min_bitrate_kbps", "must not be negative")
+		}
+	}
+	if _, ok := c.Profiles[c.Profile]; c.Profile != "" && !ok {
+		v.Addf("profile", "no stream profile named %s", c.Profile)
+	}
+
+	// Validate tracing endpoint when tracing is enabled
+	if c.Tracing.Enabled {
+		v.Required("tracing.endpoint", c.Tracing.Endpoint)
+		v.URL("tracing.endpoint", c.Tracing.Endpoint)
+	}
+
+	if c.Metrics.Store.Enabled {
+		v.Required("metrics.store.path", c.Metrics.Store.Path)
+		v.Range("metrics.store.interval_seconds", c.Metrics.Store.IntervalSeconds, 1, 3600)
+		v.Range("metrics.store.max_mb", c.Metrics.Store.MaxMB, 1, 1024)
+	}
+
+	if c.Metrics.MQTT.Enabled {
+		v.Required("metrics.mqtt.broker", c.Metrics.MQTT.Broker)
+		v.URL("metrics.mqtt.broker", c.Metrics.MQTT.Broker, "mqtt", "mqtts")
+		v.Required("metrics.mqtt.topic", c.Metrics.MQTT.Topic)
+		v.Range("metrics.mqtt.interval_seconds", c.Metrics.MQTT.IntervalSeconds, 1, 3600)
 	}
 
-	if len(errors) > 0 {
-		return fmt.Errorf("configuration validation failed:\n  - %s", strings.Join(errors, "\n  - "))
+	if c.Receiver.Enabled {
+		v.Required("receiver.binary_path", c.Receiver.BinaryPath)
+		v.Port("receiver.listen_port", c.Receiver.ListenPort)
+		v.Required("receiver.srt_host", c.Receiver.SRTHost)
+		v.Port("receiver.srt_port", c.Receiver.SRTPort)
+	}
+
+	if c.Audit.Enabled {
+		v.Required("audit.path", c.Audit.Path)
+		v.Range("audit.max_mb", c.Audit.MaxMB, 1, 1024)
+	}
+
+	if err := v.Err(); err != nil {
+		return fmt.Errorf("configuration validation failed: %w", err)
 	}
 
 	return nil
 }
 
+// validateDJIPreview checks preview settings; zero values are left to inherit
+func (c *Config) validateButtons(v *validate.Validator) {
+	if c.Buttons.LEDPin != 0 {
+		v.Range("buttons.led_pin", c.Buttons.LEDPin, 1, 1023)
+	}
+	for i, b := range c.Buttons.Buttons {
+		prefix := fmt.Sprintf("buttons.buttons[%d]", i)
+		v.Range(prefix+".pin", b.Pin, 1, 1023)
+
+		v.Required(prefix+".action", b.Action)
+		v.OneOf(prefix+".action", b.Action, buttons.Actions...)
+		v.Range(prefix+".hold_ms", b.HoldMs, 0, 10000)
+		if b.Action == buttons.ActionShutdown && b.HoldMs < minShutdownHoldMs {
+			v.Addf(prefix+".hold_ms", "shutdown must be held for at least %d ms", minShutdownHoldMs)
+		}
+		if b.Action == buttons.ActionHotspotToggle && c.Hotspot.SSID == "" {
+			v.Addf(prefix+".action", "start the hotspot once from the UI so its settings are known")
+		}
+	}
+}
+
+func (c *Config) validateDisplay(v *validate.Validator) {
+	d := c.Display
+	v.Required("display.type", d.Type)
+	v.OneOf("display.type", d.Type, display.Types...)
+	v.Range("display.refresh_seconds", d.RefreshSeconds, 0, 3600)
+	switch d.Type {
+	case display.TypeSSD1306:
+		v.Range("display.i2c_bus", d.I2CBus, 0, 255)
+		v.Range("display.i2c_address", d.I2CAddress, 0x03, 0x77)
+		if d.Height != 64 && d.Height != 32 {
+			v.Addf("display.height", "%d is not supported (64 or 32)", d.Height)
+		}
+	case display.TypeEPD2in13:
+		v.Required("display.spi_device", d.SPIDevice)
+		v.Range("display.dc_pin", d.DCPin, 1, 1023)
+		v.Range("display.reset_pin", d.ResetPin, 1, 1023)
+		v.Range("display.busy_pin", d.BusyPin, 1, 1023)
+		if d.RefreshSeconds != 0 && d.RefreshSeconds < minEPDRefreshSeconds {
+			v.Addf("display.refresh_seconds", "e-paper must not refresh more often than every %d seconds", minEPDRefreshSeconds)
+		}
+	}
+}
+
+// validateGPIOPins rejects GPIO lines claimed by more than one enabled
+// feature
+func (c *Config) validateGPIOPins(v *validate.Validator) {
+	used := map[int]string{}
+	claim := func(field string, pin int) {
+		if other, ok := used[pin]; ok {
+			v.Addf(field, "GPIO %d is already used by %s", pin, other)
+		}
+		used[pin] = field
+	}
+	if c.Tally.Enabled && c.Tally.Output == tally.OutputGPIO {
+		claim("tally.gpio_pin", c.Tally.GPIOPin)
+		if c.Tally.PreviewPin != 0 {
+			claim("tally.preview_pin", c.Tally.PreviewPin)
+		}
+	}
+	if c.Buttons.Enabled {
+		if c.Buttons.LEDPin != 0 {
+			claim("buttons.led_pin", c.Buttons.LEDPin)
+		}
+		for i, b := range c.Buttons.Buttons {
+			claim(fmt.Sprintf("buttons.buttons[%d].pin", i), b.Pin)
+		}
+	}
+	if c.Display.Enabled && c.Display.Type == display.TypeEPD2in13 {
+		claim("display.dc_pin", c.Display.DCPin)
+		claim("display.reset_pin", c.Display.ResetPin)
+		claim("display.busy_pin", c.Display.BusyPin)
+	}
+}
+
+func validateDJIPreview(v *validate.Validator, prefix string, p DJIPreviewConfig) {
+	v.OneOf(prefix+".resolution", p.Resolution, "480p", "720p", "1080p")
+	if p.FPS != 0 && p.FPS != 15 && p.FPS != 25 && p.FPS != 30 {
+		v.Addf(prefix+".fps", "%d is not supported (15, 25 or 30)", p.FPS)
+	}
+	v.Bitrate(prefix+".bitrate_kbps", p.BitrateKbps)
+}
+
 // SaveCameraConfig saves or updates camera configuration by MAC address
 func (m *Manager) SaveCameraConfig(address string, cfg CameraConfig) error {
 	m.mu.Lock()
@@ -389,11 +1963,16 @@ func (m *Manager) DeleteUSBCameraConfig(deviceID string) error {
 func DefaultConfig() *Config {
 	return &Config{
 		RTMP: RTMPConfig{
-			ListenPort: 1935,
-			StreamKey:  "live",
+			ListenPort:            1935,
+			ReconnectGraceSeconds: 10,
+			StreamKey:             "live",
+		},
+		SRTIngest: SRTIngestConfig{
+			ListenPort: DefaultSRTIngestPort,
 		},
 		SRT: SRTConfig{
 			LocalPort: 6000,
+			ProbePort: 6010,
 		},
 		SRTLA: SRTLAConfig{
 			Enabled:    true,
@@ -401,17 +1980,180 @@ func DefaultConfig() *Config {
 			RemoteHost: "localhost",
 			RemotePort: 5000,
 			BindIPs:    []string{},
+			BindSets:   map[string][]string{},
+			Transport:  string(transport.Auto),
 		},
 		Web: WebConfig{
 			Port: 8080,
+			TLS: TLSConfig{
+				CertFile: "/var/lib/srtla-manager/tls/cert.pem",
+				KeyFile:  "/var/lib/srtla-manager/tls/key.pem",
+			},
+		},
+		Access: AccessConfig{
+			AllowedCIDRs:        []string{},
+			PreviewAllowedCIDRs: []string{},
 		},
 		Logging: LoggingConfig{
-			Debug:      false,
-			FilePath:   "logs/srtla-manager.log",
-			MaxSizeMB:  10,
-			MaxBackups: 3,
+			Debug:        false,
+			FilePath:     "logs/srtla-manager.log",
+			MaxSizeMB:    10,
+			MaxBackups:   3,
+			ProcessFiles: true,
+		},
+		Tracing: TracingConfig{
+			Enabled:     false,
+			Endpoint:    "http://localhost:4318",
+			ServiceName: "srtla-manager",
+		},
+		Metrics: MetricsConfig{
+			Store: MetricsStoreConfig{
+				Path:            "/var/lib/srtla-manager/stats.jsonl",
+				IntervalSeconds: 10,
+				MaxMB:           16,
+			},
+			MQTT: MetricsMQTTConfig{
+				Topic:           "srtla-manager/stats",
+				IntervalSeconds: 5,
+			},
+		},
+		Receiver: ReceiverConfig{
+			BinaryPath: "srtla_rec",
+			ListenPort: 5000,
+			SRTHost:    "127.0.0.1",
+			SRTPort:    4001,
+		},
+		Audit: AuditConfig{
+			Path:  "/var/lib/srtla-manager/audit.jsonl",
+			MaxMB: 4,
+		},
+		Loudness: LoudnessConfig{
+			Enabled:     false,
+			TargetLUFS:  -23,
+			ToleranceLU: 1,
+			MaxTruePeak: -1,
+			MinDuration: 10,
+			Meters:      true,
+		},
+		Ingest: IngestConfig{
+			Enabled:       true,
+			MinBitrate:    1000,
+			MinFPSPercent: 80,
+			HoldSeconds:   5,
+		},
+		ABR: ABRConfig{
+			MinKbps:        1000,
+			MaxKbps:        6000,
+			StepKbps:       500,
+			MaxLossPercent: 2,
+			MaxBufferMs:    1000,
+		},
+		Maintenance: MaintenanceConfig{
+			OSUpdate:        maintenance.MethodApt,
+			Start:           "03:00",
+			DurationMinutes: 120,
+			KeepBackups:     maintenance.DefaultKeepBackups,
+			KeepDays:        maintenance.DefaultKeepDays,
+		},
+		Pairing: PairingConfig{
+			Enabled:         false,
+			Role:            "active",
+			SyncConfig:      true,
+			FailoverTimeout: 10,
+		},
+		Power: PowerConfig{
+			Profile: power.ModeAuto,
+		},
+		Capture: CaptureConfig{
+			Enabled: false,
+			Dir:     "captures",
+			Window:  300,
+		},
+		Talkback: TalkbackConfig{
+			Enabled:     false,
+			Port:        5004,
+			PayloadType: 96,
+			Device:      "default",
+			Volume:      1.0,
+		},
+		ReturnFeed: ReturnFeedConfig{
+			Enabled:     false,
+			Width:       640,
+			BitrateKbps: 800,
+		},
+		Display: DisplayConfig{
+			Enabled:    false,
+			Type:       display.TypeSSD1306,
+			I2CBus:     1,
+			I2CAddress: 0x3C,
+			Height:     64,
+			SPIDevice:  "/dev/spidev0.0",
+			DCPin:      25,
+			ResetPin:   17,
+			BusyPin:    24,
+		},
+		Alerts: AlertsConfig{
+			Enabled: false,
+			Device:  "default",
+			Volume:  1.0,
+			Events:  append([]string(nil), alerts.Events...),
+		},
+		Quota: QuotaConfig{
+			Path:   "/var/lib/srtla-manager/data_usage.json",
+			Modems: map[string]ModemQuota{},
+		},
+		LinkProbe: LinkProbeConfig{
+			Method:          linkprobe.MethodICMP,
+			Target:          linkprobe.DefaultICMPTarget,
+			IntervalSeconds: 5,
+			TimeoutSeconds:  2,
+			FailAfter:       linkprobe.DefaultFailAfter,
+			PassAfter:       linkprobe.DefaultPassAfter,
+		},
+		ModemConnect: ModemConnectConfig{
+			RetrySeconds: 30,
+		},
+		// new units find cameras and modems as they are plugged in; DJI's
+		// BLE scans cost the most and are left to the user
+		Discovery: DiscoveryConfig{
+			USBCam: DiscoveryClassConfig{Mode: DiscoveryContinuous, IntervalSeconds: 10},
+			Modem:  DiscoveryClassConfig{Mode: DiscoveryContinuous, IntervalSeconds: 10},
+			USBNet: DiscoveryClassConfig{Mode: DiscoveryContinuous, IntervalSeconds: 3},
+			DJI:    DiscoveryClassConfig{Mode: DiscoveryManual, IntervalSeconds: 120},
+		},
+		Notify: NotifyConfig{
+			Enabled:   false,
+			Events:    []string{alerts.EventStreamStarted, alerts.EventStreamStopped, alerts.EventLinkDown, alerts.EventInputLost, alerts.EventInputRestored},
+			QueuePath: "/var/lib/srtla-manager/notifications.json",
+			MaxQueued: 500,
+		},
+		Timecode: TimecodeConfig{
+			Enabled:   false,
+			Source:    timecode.SourceClock,
+			FPS:       25,
+			LTCDevice: "default",
+		},
+		Switcher: SwitcherConfig{
+			Enabled: false,
+			Type:    tally.SwitcherATEM,
+			Input:   1,
+		},
+		Tally: TallyConfig{
+			Enabled:      false,
+			Source:       tally.SourcePipeline,
+			Output:       tally.OutputGPIO,
+			GPIOPin:      17,
+			SerialDevice: "/dev/ttyACM0",
+			SerialBaud:   9600,
+		},
+		DJIPreview: DJIPreviewConfig{
+			Resolution:  "720p",
+			FPS:         15,
+			BitrateKbps: 1000,
 		},
-		Cameras:    make(map[string]CameraConfig),
-		USBCameras: make(map[string]USBCameraConfig),
+		Cameras:      make(map[string]CameraConfig),
+		USBCameras:   make(map[string]USBCameraConfig),
+		CameraGroups: make(map[string]CameraGroupConfig),
+		Profiles:     make(map[string]StreamProfile),
 	}
 }
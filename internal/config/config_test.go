@@ -0,0 +1,160 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"srtla-manager/internal/events"
+)
+
+// newWatchedManager returns a manager of a default config file in a
+// temporary directory, and a subscription to what reloading it publishes
+func newWatchedManager(t *testing.T) (*Manager, *events.Subscription) {
+	t.Helper()
+	m := NewManager(filepath.Join(t.TempDir(), "config.yaml"))
+	if err := m.Load(); err != nil {
+		t.Fatal(err)
+	}
+	bus := events.NewBus()
+	m.SetBus(bus)
+	sub := bus.Subscribe("test", 8, TopicUpdated.Name(), TopicReloadFailed.Name())
+	t.Cleanup(sub.Close)
+	return m, sub
+}
+
+// replaceFile writes cfg to a temporary file and renames it over path, as
+// Ansible does
+func replaceFile(t *testing.T, path string, cfg Config) {
+	t.Helper()
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReload(t *testing.T) {
+	m, sub := newWatchedManager(t)
+
+	// the manager's own save is published once, and not reloaded
+	cfg := m.Get()
+	cfg.RTMP.StreamKey = "saved"
+	if err := m.Update(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if ev := <-sub.C; ev.Data.(Change).External {
+		t.Errorf("Expected the save published as internal, got %+v", ev.Data)
+	}
+	m.reload()
+	if len(sub.C) != 0 {
+		t.Errorf("Expected no reload of the manager's own save, got %+v", <-sub.C)
+	}
+
+	// a valid edit is applied
+	cfg.RTMP.StreamKey = "edited"
+	replaceFile(t, m.FilePath(), cfg)
+	m.reload()
+	if len(sub.C) != 1 {
+		t.Fatalf("Expected one event for a valid edit, got %d", len(sub.C))
+	}
+	if change, ok := (<-sub.C).Data.(Change); !ok || !change.External || !slices.Contains(change.Sections, "rtmp") {
+		t.Errorf("Unexpected change %+v", change)
+	}
+	if got := m.Get().RTMP.StreamKey; got != "edited" {
+		t.Errorf("Expected the edited stream key, got %q", got)
+	}
+	m.reload()
+	if len(sub.C) != 0 {
+		t.Errorf("Expected an edit to be applied once, got %+v", <-sub.C)
+	}
+
+	// an invalid edit is reported and the config in use kept
+	cfg.RTMP.ListenPort = 70000
+	replaceFile(t, m.FilePath(), cfg)
+	m.reload()
+	if len(sub.C) != 1 {
+		t.Fatalf("Expected one event for an invalid edit, got %d", len(sub.C))
+	}
+	if failure, ok := (<-sub.C).Data.(ReloadFailure); !ok || failure.Error == "" {
+		t.Errorf("Expected a reload failure, got %+v", failure)
+	}
+	if got := m.Get().RTMP.ListenPort; got != 1935 {
+		t.Errorf("Expected the listen port kept, got %d", got)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	m, sub := newWatchedManager(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- m.Watch(ctx, 10*time.Millisecond) }()
+	defer func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Watch: %v", err)
+		}
+	}()
+
+	// give the watcher time to start before editing the file
+	time.Sleep(100 * time.Millisecond)
+	cfg := m.Get()
+	cfg.RTMP.StreamKey = "pushed"
+	replaceFile(t, m.FilePath(), cfg)
+
+	select {
+	case ev := <-sub.C:
+		if change, ok := ev.Data.(Change); !ok || !change.External {
+			t.Errorf("Unexpected event %+v", ev.Data)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the replaced file to be reloaded")
+	}
+	if got := m.Get().RTMP.StreamKey; got != "pushed" {
+		t.Errorf("Expected the pushed stream key, got %q", got)
+	}
+}
+
+func TestLoadKeepsDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("rtmp:\n    listen_port: 1936\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	m := NewManager(path)
+	if err := m.Load(); err != nil {
+		t.Fatal(err)
+	}
+	cfg, def := m.Get(), DefaultConfig()
+	if cfg.RTMP.ListenPort != 1936 || cfg.RTMP.StreamKey != def.RTMP.StreamKey {
+		t.Errorf("Expected the file's port over the default stream key, got %+v", cfg.RTMP)
+	}
+	if cfg.Tracing != def.Tracing {
+		t.Errorf("Expected the default tracing settings, got %+v", cfg.Tracing)
+	}
+}
+
+func TestSampleConfigValidates(t *testing.T) {
+	data, err := os.ReadFile("../../config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("The sample config.yaml doesn't validate: %v", err)
+	}
+}
@@ -0,0 +1,172 @@
+// Package confighistory keeps copies of the configuration file taken before
+// it is replaced, in a directory next to it, so a change that went wrong
+// can be rolled back. The oldest copies are removed once there are more
+// than the store keeps.
+package confighistory
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultKeep is how many copies a store keeps when not told otherwise
+const DefaultKeep = 20
+
+// idLayout starts each entry's ID; IDs sort in the order they were taken
+const idLayout = "20060102T150405.000Z"
+
+// ErrNotFound is returned for an ID with no entry
+var ErrNotFound = errors.New("no such config in the history")
+
+// Entry is a copy of the configuration
+type Entry struct {
+	ID        string    `json:"id"`
+	Reason    string    `json:"reason"` // what replaced it, such as import or rollback
+	CreatedAt time.Time `json:"created_at"`
+	Size      int64     `json:"size"`
+}
+
+// Store is a directory of configuration copies
+type Store struct {
+	dir  string
+	keep int
+}
+
+// NewStore returns a store in dir keeping the keep newest copies, or
+// DefaultKeep if keep isn't positive. dir is created on the first save.
+func NewStore(dir string, keep int) *Store {
+	if keep <= 0 {
+		keep = DefaultKeep
+	}
+	return &Store{dir: dir, keep: keep}
+}
+
+// Dir returns the directory of the store
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// Save keeps a copy of data, the configuration about to be replaced for
+// reason, and removes the copies past the ones kept
+func (s *Store) Save(data []byte, reason string) (Entry, error) {
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return Entry{}, err
+	}
+
+	reason = cleanReason(reason)
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	// two saves within a millisecond each get their own copy
+	for {
+		if _, err := os.Stat(s.path(now.Format(idLayout) + "-" + reason)); errors.Is(err, os.ErrNotExist) {
+			break
+		}
+		now = now.Add(time.Millisecond)
+	}
+	entry := Entry{
+		ID:        now.Format(idLayout) + "-" + reason,
+		Reason:    reason,
+		CreatedAt: now,
+		Size:      int64(len(data)),
+	}
+	// copies hold stream keys, like the configuration itself
+	if err := os.WriteFile(s.path(entry.ID), data, 0600); err != nil {
+		return Entry{}, err
+	}
+	return entry, s.prune()
+}
+
+// List returns the copies, newest first. A missing directory holds none.
+func (s *Store) List() ([]Entry, error) {
+	files, err := os.ReadDir(s.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []Entry{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []Entry{}
+	for _, f := range files {
+		id, ok := strings.CutSuffix(f.Name(), ".yaml")
+		if f.IsDir() || !ok {
+			continue
+		}
+		entry, ok := parseID(id)
+		if !ok {
+			continue
+		}
+		if info, err := f.Info(); err == nil {
+			entry.Size = info.Size()
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID > entries[j].ID })
+	return entries, nil
+}
+
+// Read returns the copy with id
+func (s *Store) Read(id string) ([]byte, error) {
+	if _, ok := parseID(id); !ok {
+		return nil, ErrNotFound
+	}
+	data, err := os.ReadFile(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+func (s *Store) prune() error {
+	entries, err := s.List()
+	if err != nil || len(entries) <= s.keep {
+		return err
+	}
+	var errs []error
+	for _, e := range entries[s.keep:] {
+		if err := os.Remove(s.path(e.ID)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *Store) path(id string) string {
+	return filepath.Join(s.dir, id+".yaml")
+}
+
+// parseID returns the entry an ID names, reporting whether it is one
+func parseID(id string) (Entry, bool) {
+	if len(id) < len(idLayout)+2 || id[len(idLayout)] != '-' {
+		return Entry{}, false
+	}
+	at, err := time.Parse(idLayout, id[:len(idLayout)])
+	reason := id[len(idLayout)+1:]
+	if err != nil || reason != cleanReason(reason) {
+		return Entry{}, false
+	}
+	return Entry{ID: id, Reason: reason, CreatedAt: at}, true
+}
+
+// cleanReason keeps reason to lowercase letters, digits and dashes, so it
+// is safe in a file name
+func cleanReason(reason string) string {
+	clean := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + 'a' - 'A'
+		case r == ' ' || r == '_':
+			return '-'
+		}
+		return -1
+	}, reason)
+	if clean == "" {
+		return "change"
+	}
+	return clean
+}
@@ -0,0 +1,68 @@
+package confighistory
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveListRead(t *testing.T) {
+	s := NewStore(filepath.Join(t.TempDir(), "history"), 3)
+
+	list, err := s.List()
+	if err != nil || len(list) != 0 {
+		t.Fatalf("List of a missing dir = %v, %v; want none", list, err)
+	}
+
+	var saved []Entry
+	for _, reason := range []string{"import", "Pre Rollback", "update", "update"} {
+		e, err := s.Save([]byte("web:\n  port: 8080\n"), reason)
+		if err != nil {
+			t.Fatalf("Save(%q): %v", reason, err)
+		}
+		saved = append(saved, e)
+	}
+	if saved[1].Reason != "pre-rollback" {
+		t.Errorf("Reason = %q, want pre-rollback", saved[1].Reason)
+	}
+	if saved[2].ID == saved[3].ID {
+		t.Errorf("Expected two saves to get their own IDs, both got %s", saved[2].ID)
+	}
+
+	list, err = s.List()
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(list) != 3 || list[0].ID != saved[3].ID || list[2].ID != saved[1].ID {
+		t.Fatalf("Expected the 3 newest, newest first, got %+v", list)
+	}
+	if list[0].Size == 0 || list[0].CreatedAt.IsZero() {
+		t.Errorf("Expected a size and time, got %+v", list[0])
+	}
+
+	data, err := s.Read(saved[3].ID)
+	if err != nil || string(data) != "web:\n  port: 8080\n" {
+		t.Errorf("Read = %q, %v", data, err)
+	}
+	if _, err := s.Read(saved[0].ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected the pruned copy to be gone, got %v", err)
+	}
+
+	info, err := os.Stat(filepath.Join(s.Dir(), saved[3].ID+".yaml"))
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected a copy only its owner can read, got %v, %v", info, err)
+	}
+}
+
+func TestReadRejectsPaths(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "secret.yaml"), []byte("x"), 0600)
+	s := NewStore(filepath.Join(dir, "history"), 0)
+
+	for _, id := range []string{"", "../secret", "20261015T120000.000Z-../../secret", "20261015T120000.000Z-"} {
+		if _, err := s.Read(id); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Read(%q) = %v, want ErrNotFound", id, err)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+// Package configtmpl renders configuration templates pushed to a unit. A
+// template is a JSON document in the shape of the configuration, usually
+// only the sections being set, whose strings may refer to per-unit
+// variables as ${name}.
+package configtmpl
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// variablePattern matches a ${name} reference
+var variablePattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// Render substitutes vars into every string of the template. A reference to
+// a variable that isn't given is an error, so a unit never ends up with a
+// literal ${stream_key}.
+func Render(tmpl json.RawMessage, vars map[string]string) (json.RawMessage, error) {
+	var doc any
+	if err := json.Unmarshal(tmpl, &doc); err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+	if _, ok := doc.(map[string]any); !ok {
+		return nil, fmt.Errorf("invalid template: must be a JSON object")
+	}
+
+	missing := make(map[string]bool)
+	doc = substitute(doc, vars, missing)
+	if len(missing) > 0 {
+		names := make([]string, 0, len(missing))
+		for name := range missing {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("template uses undefined variables: %s", strings.Join(names, ", "))
+	}
+	return json.Marshal(doc)
+}
+
+// Overlay decodes a rendered template over a copy of base, returning the
+// result and the top-level sections the template sets. base is copied
+// through JSON first, so its slices and maps are never written to; fields
+// hidden from JSON come back zero and are up to the caller.
+func Overlay[T any](base T, rendered json.RawMessage) (T, []string, error) {
+	var out T
+	data, err := json.Marshal(base)
+	if err != nil {
+		return out, nil, err
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return out, nil, err
+	}
+
+	var sections map[string]json.RawMessage
+	if err := json.Unmarshal(rendered, &sections); err != nil {
+		return out, nil, err
+	}
+	if err := json.Unmarshal(rendered, &out); err != nil {
+		return out, nil, err
+	}
+	names := make([]string, 0, len(sections))
+	for name := range sections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return out, names, nil
+}
+
+// Variables returns the names of the variables a template refers to
+func Variables(tmpl json.RawMessage) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, m := range variablePattern.FindAllSubmatch(tmpl, -1) {
+		if name := string(m[1]); !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func substitute(v any, vars map[string]string, missing map[string]bool) any {
+	switch v := v.(type) {
+	case string:
+		return variablePattern.ReplaceAllStringFunc(v, func(ref string) string {
+			name := variablePattern.FindStringSubmatch(ref)[1]
+			value, ok := vars[name]
+			if !ok {
+				missing[name] = true
+			}
+			return value
+		})
+	case map[string]any:
+		for k, elem := range v {
+			v[k] = substitute(elem, vars, missing)
+		}
+	case []any:
+		for i, elem := range v {
+			v[i] = substitute(elem, vars, missing)
+		}
+	}
+	return v
+}
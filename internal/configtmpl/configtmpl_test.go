@@ -0,0 +1,101 @@
+package configtmpl
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"srtla-manager/internal/config"
+)
+
+func TestRender(t *testing.T) {
+	tmpl := json.RawMessage(`{
+		"rtmp": {"stream_key": "${stream_key}", "listen_port": 1935},
+		"display": {"title": "Unit ${unit_name} ($5)"},
+		"srtla": {"bind_ips": ["${ip}"]}
+	}`)
+	out, err := Render(tmpl, map[string]string{"stream_key": "cam-${x}", "unit_name": "van 2", "ip": "10.0.0.2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got map[string]any
+	if err := json.Unmarshal(out, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"rtmp":    map[string]any{"stream_key": "cam-${x}", "listen_port": 1935.0},
+		"display": map[string]any{"title": "Unit van 2 ($5)"},
+		"srtla":   map[string]any{"bind_ips": []any{"10.0.0.2"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Render() = %v, want %v", got, want)
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	if _, err := Render(json.RawMessage(`{"rtmp": {"stream_key": "${key}", "x": "${a}${key}"}}`), nil); err == nil ||
+		err.Error() != "template uses undefined variables: a, key" {
+		t.Errorf("Expected the undefined variables, got %v", err)
+	}
+	if _, err := Render(json.RawMessage(`["${a}"]`), map[string]string{"a": "1"}); err == nil {
+		t.Error("Expected a template that isn't an object to be rejected")
+	}
+	if _, err := Render(json.RawMessage(`{`), nil); err == nil {
+		t.Error("Expected invalid JSON to be rejected")
+	}
+}
+
+func TestVariables(t *testing.T) {
+	got := Variables(json.RawMessage(`{"a": "${unit_name}-${stream_key}", "b": "${unit_name}", "c": "$plain"}`))
+	if want := []string{"stream_key", "unit_name"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Variables() = %v, want %v", got, want)
+	}
+}
+
+func TestOverlayLeavesBaseAlone(t *testing.T) {
+	base := config.DefaultConfig()
+	base.SRTLA.BindIPs = []string{"10.0.0.1", "10.0.0.2"}
+	base.Cameras = map[string]config.CameraConfig{"cam0": {}}
+	base.Profiles = map[string]config.StreamProfile{}
+
+	rendered, err := Render(json.RawMessage(`{
+		"srtla": {"bind_ips": ["${ip}"]},
+		"cameras": {"cam1": {}},
+		"profiles": {"night": {}}
+	}`), map[string]string{"ip": "192.168.8.2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, sections, err := Overlay(*base, rendered)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"cameras", "profiles", "srtla"}; !reflect.DeepEqual(sections, want) {
+		t.Errorf("sections = %v, want %v", sections, want)
+	}
+	if !reflect.DeepEqual(got.SRTLA.BindIPs, []string{"192.168.8.2"}) || len(got.Cameras) != 2 || len(got.Profiles) != 1 {
+		t.Errorf("overlay = %v, %v, %v", got.SRTLA.BindIPs, got.Cameras, got.Profiles)
+	}
+	if got.SRTLA.RemotePort != base.SRTLA.RemotePort {
+		t.Errorf("remote_port = %d, want %d kept from the base", got.SRTLA.RemotePort, base.SRTLA.RemotePort)
+	}
+
+	// the live config the base came from is unchanged
+	if !reflect.DeepEqual(base.SRTLA.BindIPs, []string{"10.0.0.1", "10.0.0.2"}) {
+		t.Errorf("base bind_ips changed to %v", base.SRTLA.BindIPs)
+	}
+	if _, ok := base.Cameras["cam1"]; ok || len(base.Cameras) != 1 {
+		t.Errorf("base cameras changed to %v", base.Cameras)
+	}
+	if len(base.Profiles) != 0 {
+		t.Errorf("base profiles changed to %v", base.Profiles)
+	}
+}
+
+func TestOverlayErrors(t *testing.T) {
+	if _, _, err := Overlay(config.Config{}, json.RawMessage(`{"srtla": {"bind_ips": "x"}}`)); err == nil {
+		t.Error("no error for a mistyped section")
+	}
+}
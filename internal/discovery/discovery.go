@@ -0,0 +1,233 @@
+// Package discovery runs the scans that find each class of device, either
+// in the background at an interval or only when asked, and reports the
+// devices that came and went between scans. Scanning all the time finds a
+// device as soon as it is plugged in but costs battery; scanning only when
+// asked leaves it to the user to ask.
+package discovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ErrUnknownClass is returned for a class that wasn't added
+var ErrUnknownClass = errors.New("unknown device class")
+
+// ScanFunc scans for a class's devices, returning their IDs
+type ScanFunc func(ctx context.Context) ([]string, error)
+
+// Settings choose how a class is scanned
+type Settings struct {
+	Continuous bool          // scan in the background, not only when asked
+	Interval   time.Duration // between background scans
+}
+
+// Change is the devices that appeared and went between two scans
+type Change struct {
+	Class   string   `json:"class"`
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+// Status is the state of a class's scanning
+type Status struct {
+	Class           string    `json:"class"`
+	Continuous      bool      `json:"continuous"`
+	IntervalSeconds int       `json:"interval_seconds"`
+	Scanning        bool      `json:"scanning"`
+	LastScan        time.Time `json:"last_scan"` // zero until the first scan
+	Devices         []string  `json:"devices"`
+	Error           string    `json:"error,omitempty"` // of the last scan
+}
+
+type class struct {
+	name   string
+	scan   ScanFunc
+	scanMu sync.Mutex // one scan at a time
+
+	// under Scheduler.mu
+	settings Settings
+	cancel   context.CancelFunc // of the background scans
+	scanning bool
+	scanned  bool
+	lastScan time.Time
+	devices  []string
+	err      string
+}
+
+// Scheduler scans each class of device as its settings say
+type Scheduler struct {
+	onChange func(Change)
+
+	mu      sync.Mutex
+	classes map[string]*class
+	order   []string
+	wg      sync.WaitGroup
+}
+
+// New returns a scheduler that calls onChange, when not nil, for each scan
+// that found devices appear or go. The first scan of a class only finds
+// what is there.
+func New(onChange func(Change)) *Scheduler {
+	return &Scheduler{onChange: onChange, classes: make(map[string]*class)}
+}
+
+// Add adds a class, scanned only when asked until Set says otherwise
+func (s *Scheduler) Add(name string, scan ScanFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.classes[name]; !ok {
+		s.order = append(s.order, name)
+	}
+	s.classes[name] = &class{name: name, scan: scan}
+}
+
+// Set changes how a class is scanned. A class turned continuous is scanned
+// at once, then at its interval.
+func (s *Scheduler) Set(name string, settings Settings) error {
+	if settings.Continuous && settings.Interval <= 0 {
+		return fmt.Errorf("%s: interval must be positive", name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.classes[name]
+	if !ok {
+		return fmt.Errorf("%w %q", ErrUnknownClass, name)
+	}
+	if c.settings == settings && (c.cancel != nil) == settings.Continuous {
+		return nil
+	}
+	if c.cancel != nil {
+		c.cancel()
+		c.cancel = nil
+	}
+	c.settings = settings
+	if settings.Continuous {
+		ctx, cancel := context.WithCancel(context.Background())
+		c.cancel = cancel
+		s.wg.Add(1)
+		go s.loop(ctx, c, settings.Interval)
+	}
+	return nil
+}
+
+func (s *Scheduler) loop(ctx context.Context, c *class, interval time.Duration) {
+	defer s.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		s.scan(ctx, c)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Scan scans a class now, whatever its settings, and returns the devices
+// found. A scan already running is waited for and then run again.
+func (s *Scheduler) Scan(ctx context.Context, name string) ([]string, error) {
+	s.mu.Lock()
+	c, ok := s.classes[name]
+	s.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownClass, name)
+	}
+	return s.scan(ctx, c)
+}
+
+func (s *Scheduler) scan(ctx context.Context, c *class) ([]string, error) {
+	c.scanMu.Lock()
+	defer c.scanMu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	c.scanning = true
+	s.mu.Unlock()
+
+	devices, err := c.scan(ctx)
+	devices = slices.Clone(devices)
+	slices.Sort(devices)
+	devices = slices.Compact(devices)
+
+	s.mu.Lock()
+	c.scanning = false
+	c.lastScan = time.Now()
+	if err != nil {
+		c.err = err.Error()
+		s.mu.Unlock()
+		return nil, err
+	}
+	change := Change{Class: c.name}
+	if c.scanned {
+		change.Added, change.Removed = diff(c.devices, devices)
+	}
+	c.scanned, c.devices, c.err = true, devices, ""
+	s.mu.Unlock()
+
+	if s.onChange != nil && (len(change.Added) > 0 || len(change.Removed) > 0) {
+		s.onChange(change)
+	}
+	return devices, nil
+}
+
+// diff returns what is in after but not before, and what is in before but
+// not after, both sorted
+func diff(before, after []string) (added, removed []string) {
+	for _, id := range after {
+		if !slices.Contains(before, id) {
+			added = append(added, id)
+		}
+	}
+	for _, id := range before {
+		if !slices.Contains(after, id) {
+			removed = append(removed, id)
+		}
+	}
+	return added, removed
+}
+
+// Status returns the state of each class, in the order they were added
+func (s *Scheduler) Status() []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	statuses := make([]Status, 0, len(s.order))
+	for _, name := range s.order {
+		c := s.classes[name]
+		devices := slices.Clone(c.devices)
+		if devices == nil {
+			devices = []string{}
+		}
+		statuses = append(statuses, Status{
+			Class:           name,
+			Continuous:      c.settings.Continuous,
+			IntervalSeconds: int(c.settings.Interval / time.Second),
+			Scanning:        c.scanning,
+			LastScan:        c.lastScan,
+			Devices:         devices,
+			Error:           c.err,
+		})
+	}
+	return statuses
+}
+
+// Stop stops the background scans and waits for them to finish
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	for _, c := range s.classes {
+		if c.cancel != nil {
+			c.cancel()
+			c.cancel = nil
+		}
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
@@ -0,0 +1,119 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClass returns the device lists it is given, one per scan, repeating
+// the last
+type fakeClass struct {
+	mu    sync.Mutex
+	lists [][]string
+	scans int
+	err   error
+}
+
+func (f *fakeClass) scan(ctx context.Context) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.scans++
+	if f.err != nil {
+		return nil, f.err
+	}
+	list := f.lists[0]
+	if len(f.lists) > 1 {
+		f.lists = f.lists[1:]
+	}
+	return list, nil
+}
+
+func (f *fakeClass) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.scans
+}
+
+func TestScanReportsChanges(t *testing.T) {
+	var changes []Change
+	s := New(func(c Change) { changes = append(changes, c) })
+	f := &fakeClass{lists: [][]string{{"video1", "video0"}, {"video0", "video2"}, {"video2", "video0"}}}
+	s.Add("usbcam", f.scan)
+
+	for i := 0; i < 3; i++ {
+		if _, err := s.Scan(context.Background(), "usbcam"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []Change{{Class: "usbcam", Added: []string{"video2"}, Removed: []string{"video1"}}}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("Expected only the second scan to report a change, got %+v", changes)
+	}
+	st := s.Status()
+	if len(st) != 1 || !reflect.DeepEqual(st[0].Devices, []string{"video0", "video2"}) || st[0].LastScan.IsZero() || st[0].Continuous {
+		t.Errorf("Unexpected status %+v", st)
+	}
+}
+
+func TestScanError(t *testing.T) {
+	s := New(nil)
+	f := &fakeClass{lists: [][]string{{"a"}}}
+	s.Add("modem", f.scan)
+	s.Scan(context.Background(), "modem")
+
+	f.err = errors.New("mmcli not found")
+	if _, err := s.Scan(context.Background(), "modem"); err == nil {
+		t.Fatal("Expected the scan's error")
+	}
+	st := s.Status()[0]
+	if st.Error != "mmcli not found" || !reflect.DeepEqual(st.Devices, []string{"a"}) {
+		t.Errorf("Expected the error and the last devices found, got %+v", st)
+	}
+
+	if _, err := s.Scan(context.Background(), "dji"); !errors.Is(err, ErrUnknownClass) {
+		t.Errorf("Expected ErrUnknownClass, got %v", err)
+	}
+}
+
+func TestContinuous(t *testing.T) {
+	s := New(nil)
+	defer s.Stop()
+	f := &fakeClass{lists: [][]string{{"usb0"}}}
+	s.Add("usbnet", f.scan)
+
+	if f.count() != 0 {
+		t.Fatal("Expected a class not to be scanned until asked")
+	}
+	if err := s.Set("usbnet", Settings{Continuous: true}); err == nil {
+		t.Error("Expected continuous scanning without an interval to be refused")
+	}
+	if err := s.Set("usbnet", Settings{Continuous: true, Interval: 10 * time.Millisecond}); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for f.count() < 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected background scans, got %d", f.count())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if err := s.Set("usbnet", Settings{Interval: 10 * time.Millisecond}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	n := f.count()
+	time.Sleep(50 * time.Millisecond)
+	if f.count() != n {
+		t.Errorf("Expected scans to stop once manual, went from %d to %d", n, f.count())
+	}
+	if st := s.Status()[0]; st.Continuous || st.IntervalSeconds != 0 {
+		t.Errorf("Unexpected status %+v", st)
+	}
+}
@@ -0,0 +1,56 @@
+//go:build linux
+
+package display
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// ioctl requests from linux/i2c-dev.h and linux/spi/spidev.h
+const (
+	i2cSlave           = 0x0703
+	spiIOCWrMode       = 0x40016b01
+	spiIOCWrMaxSpeedHz = 0x40046b04
+)
+
+func ioctl(f *os.File, req, arg uintptr) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), req, arg); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// openI2C opens an I2C bus addressed to one device
+func openI2C(bus, addr int) (*os.File, error) {
+	f, err := os.OpenFile(fmt.Sprintf("/dev/i2c-%d", bus), os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	if err := ioctl(f, i2cSlave, uintptr(addr)); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to address I2C device 0x%02x: %w", addr, err)
+	}
+	return f, nil
+}
+
+// openSPI opens a spidev device in mode 0. Writes to it are write-only
+// transfers.
+func openSPI(device string, speedHz uint32) (*os.File, error) {
+	f, err := os.OpenFile(device, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	mode := uint8(0)
+	if err := ioctl(f, spiIOCWrMode, uintptr(unsafe.Pointer(&mode))); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to set SPI mode: %w", err)
+	}
+	if err := ioctl(f, spiIOCWrMaxSpeedHz, uintptr(unsafe.Pointer(&speedHz))); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to set SPI speed: %w", err)
+	}
+	return f, nil
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+
+package display
+
+import (
+	"fmt"
+	"os"
+)
+
+func openI2C(bus, addr int) (*os.File, error) {
+	return nil, fmt.Errorf("I2C displays are only supported on Linux")
+}
+
+func openSPI(device string, speedHz uint32) (*os.File, error) {
+	return nil, fmt.Errorf("SPI displays are only supported on Linux")
+}
@@ -0,0 +1,126 @@
+// Package display draws text status screens on small monochrome displays:
+// SSD1306 OLEDs over I2C and SSD1680 e-paper panels over SPI.
+package display
+
+import "time"
+
+// Supported displays
+const (
+	TypeSSD1306  = "ssd1306"  // 128x64 or 128x32 OLED on I2C
+	TypeEPD2in13 = "epd2in13" // 250x122 SSD1680 e-paper on SPI (Waveshare 2.13" V2/V3)
+)
+
+// Types lists the values accepted for display.type
+var Types = []string{TypeSSD1306, TypeEPD2in13}
+
+// DefaultRefresh is how often each type is redrawn unless configured.
+// E-paper flashes on every full refresh and wears with use.
+var DefaultRefresh = map[string]time.Duration{
+	TypeSSD1306:  time.Second,
+	TypeEPD2in13: time.Minute,
+}
+
+// Display is a monochrome panel
+type Display interface {
+	Width() int
+	Height() int
+	Show(*Frame) error
+	Close() error // blanks the panel and releases it
+}
+
+const (
+	glyphWidth = 6 // five columns and a gap
+	lineHeight = 8 // seven rows and a gap
+)
+
+// Frame is a monochrome image, true meaning a lit (or inked) pixel
+type Frame struct {
+	W, H int
+	pix  []bool
+}
+
+// NewFrame returns a blank frame
+func NewFrame(w, h int) *Frame {
+	return &Frame{W: w, H: h, pix: make([]bool, w*h)}
+}
+
+// Set lights a pixel; pixels outside the frame are ignored
+func (f *Frame) Set(x, y int, on bool) {
+	if x >= 0 && x < f.W && y >= 0 && y < f.H {
+		f.pix[y*f.W+x] = on
+	}
+}
+
+// At reports whether a pixel is lit
+func (f *Frame) At(x, y int) bool {
+	if x < 0 || x >= f.W || y < 0 || y >= f.H {
+		return false
+	}
+	return f.pix[y*f.W+x]
+}
+
+// Text draws s with its top-left corner at x, y. Characters outside
+// printable ASCII are drawn as '?'.
+func (f *Frame) Text(x, y int, s string) {
+	for _, r := range s {
+		if r < ' ' || r > '~' {
+			r = '?'
+		}
+		glyph := font[r-' ']
+		for col, bits := range glyph {
+			for row := 0; row < 7; row++ {
+				if bits&(1<<row) != 0 {
+					f.Set(x+col, y+row, true)
+				}
+			}
+		}
+		x += glyphWidth
+	}
+}
+
+// Invert flips the pixels in a rectangle, e.g. to highlight a line
+func (f *Frame) Invert(x, y, w, h int) {
+	for yy := y; yy < y+h; yy++ {
+		for xx := x; xx < x+w; xx++ {
+			f.Set(xx, yy, !f.At(xx, yy))
+		}
+	}
+}
+
+// Equal reports whether two frames show the same image
+func (f *Frame) Equal(o *Frame) bool {
+	if o == nil || f.W != o.W || f.H != o.H {
+		return false
+	}
+	for i := range f.pix {
+		if f.pix[i] != o.pix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Columns is how many characters fit on a line of a w pixel wide display
+func Columns(w int) int {
+	return w / glyphWidth
+}
+
+// Render draws one line of text per row of the font, as many as fit. The
+// first line is the heading and is shown inverted.
+func Render(w, h int, lines []string) *Frame {
+	f := NewFrame(w, h)
+	for i, line := range lines {
+		y := i * lineHeight
+		if y+lineHeight > h {
+			break
+		}
+		if len(line) > Columns(w) {
+			line = line[:Columns(w)]
+		}
+		f.Text(1, y, line)
+		if i == 0 {
+			f.Invert(0, y, w, lineHeight-1)
+		}
+	}
+	return f
+}
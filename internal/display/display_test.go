@@ -0,0 +1,36 @@
+package display
+
+import "testing"
+
+func TestRender(t *testing.T) {
+	f := Render(128, 32, []string{"LIVE", "I", "this line is far too long to fit", "a", "dropped"})
+
+	// heading is inverted: the background is lit and the glyph gaps are not
+	if !f.At(0, 0) || !f.At(127, 6) {
+		t.Error("heading background not lit")
+	}
+	// 'I' on the second line has its stem in its middle column
+	if !f.At(1+2, 8) || f.At(1, 8) {
+		t.Error("second line glyph not drawn where expected")
+	}
+	// long lines are cut at the right edge rather than wrapping
+	if f.At(127, 16) {
+		t.Error("long line drawn past the last column")
+	}
+	// only four 8-pixel lines fit in 32 rows
+	if !f.Equal(Render(128, 32, []string{"LIVE", "I", "this line is far too long to fit", "a"})) {
+		t.Error("line beyond the bottom of the display was drawn")
+	}
+}
+
+func TestFrameEqual(t *testing.T) {
+	a := Render(128, 64, []string{"x"})
+	b := Render(128, 64, []string{"x"})
+	if !a.Equal(b) {
+		t.Error("identical frames not equal")
+	}
+	b.Set(100, 50, true)
+	if a.Equal(b) {
+		t.Error("different frames equal")
+	}
+}
@@ -0,0 +1,185 @@
+package display
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"srtla-manager/internal/gpio"
+)
+
+const (
+	// the panel is 122x250 in portrait; it is drawn in landscape
+	epdWidth     = 250
+	epdHeight    = 122
+	epdRowBytes  = (epdHeight + 7) / 8
+	epdSPISpeed  = 4000000
+	epdBusyLimit = 10 * time.Second
+	// spidev's default transfer size limit
+	epdSPIChunk = 4096
+)
+
+// EPD2in13 is a 2.13" SSD1680 e-paper panel (Waveshare V2/V3 and clones) on
+// SPI, with data/command, reset and busy lines on GPIO
+type EPD2in13 struct {
+	spi  *os.File
+	dc   *gpio.Pin
+	rst  *gpio.Pin
+	busy *gpio.Pin
+	flip bool
+}
+
+// NewEPD2in13 opens and initialises the panel. flip rotates the picture 180
+// degrees.
+func NewEPD2in13(spiDevice string, dcPin, resetPin, busyPin int, flip bool) (*EPD2in13, error) {
+	spi, err := openSPI(spiDevice, epdSPISpeed)
+	if err != nil {
+		return nil, fmt.Errorf("e-paper: %w", err)
+	}
+	d := &EPD2in13{spi: spi, flip: flip}
+	if d.dc, err = gpio.Output(dcPin, false); err == nil {
+		if d.rst, err = gpio.Output(resetPin, false); err == nil {
+			d.busy, err = gpio.Input(busyPin, false)
+		}
+	}
+	if err == nil {
+		err = d.init()
+	}
+	if err != nil {
+		spi.Close()
+		return nil, fmt.Errorf("e-paper: %w", err)
+	}
+	return d, nil
+}
+
+func (d *EPD2in13) init() error {
+	// hardware reset
+	steps := []struct {
+		level bool
+		wait  time.Duration
+	}{{true, 20 * time.Millisecond}, {false, 2 * time.Millisecond}, {true, 20 * time.Millisecond}}
+	for _, s := range steps {
+		if err := d.rst.Set(s.level); err != nil {
+			return err
+		}
+		time.Sleep(s.wait)
+	}
+	if err := d.waitIdle(); err != nil {
+		return err
+	}
+
+	cmds := []struct {
+		cmd  byte
+		data []byte
+	}{
+		{0x12, nil},                                    // software reset
+		{0x01, []byte{epdWidth - 1, 0x00, 0x00}},       // gate lines
+		{0x11, []byte{0x03}},                           // x and y increment
+		{0x44, []byte{0x00, epdRowBytes - 1}},          // RAM x range
+		{0x45, []byte{0x00, 0x00, epdWidth - 1, 0x00}}, // RAM y range
+		{0x3C, []byte{0x05}},                           // white border
+		{0x21, []byte{0x00, 0x80}},                     // display update control
+		{0x18, []byte{0x80}},                           // internal temperature sensor
+	}
+	for i, c := range cmds {
+		if err := d.command(c.cmd, c.data...); err != nil {
+			return err
+		}
+		if i == 0 {
+			if err := d.waitIdle(); err != nil {
+				return err
+			}
+		}
+	}
+	return d.waitIdle()
+}
+
+func (d *EPD2in13) Width() int  { return epdWidth }
+func (d *EPD2in13) Height() int { return epdHeight }
+
+// command sends a command byte and its data
+func (d *EPD2in13) command(cmd byte, data ...byte) error {
+	if err := d.dc.Set(false); err != nil {
+		return err
+	}
+	if _, err := d.spi.Write([]byte{cmd}); err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	if err := d.dc.Set(true); err != nil {
+		return err
+	}
+	for i := 0; i < len(data); i += epdSPIChunk {
+		if _, err := d.spi.Write(data[i:min(i+epdSPIChunk, len(data))]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// waitIdle waits for the busy line to drop
+func (d *EPD2in13) waitIdle() error {
+	deadline := time.Now().Add(epdBusyLimit)
+	for {
+		busy, err := d.busy.Get()
+		if err != nil {
+			return err
+		}
+		if !busy {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("panel stayed busy")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// Show writes the frame and runs a full refresh, which takes a few seconds.
+// RAM rows run along the long edge; a set bit is white.
+func (d *EPD2in13) Show(f *Frame) error {
+	buf := make([]byte, epdWidth*epdRowBytes)
+	for i := range buf {
+		buf[i] = 0xFF
+	}
+	for y := 0; y < epdHeight; y++ {
+		for x := 0; x < epdWidth; x++ {
+			lit := f.At(x, y)
+			if d.flip {
+				lit = f.At(epdWidth-1-x, epdHeight-1-y)
+			}
+			if lit {
+				col := epdHeight - 1 - y
+				buf[x*epdRowBytes+col/8] &^= 0x80 >> (col % 8)
+			}
+		}
+	}
+
+	steps := []struct {
+		cmd  byte
+		data []byte
+	}{
+		{0x4E, []byte{0x00}},       // RAM x counter
+		{0x4F, []byte{0x00, 0x00}}, // RAM y counter
+		{0x24, buf},
+		{0x22, []byte{0xF7}}, // full update sequence
+		{0x20, nil},          // run it
+	}
+	for _, s := range steps {
+		if err := d.command(s.cmd, s.data...); err != nil {
+			return fmt.Errorf("e-paper: %w", err)
+		}
+	}
+	if err := d.waitIdle(); err != nil {
+		return fmt.Errorf("e-paper: %w", err)
+	}
+	return nil
+}
+
+// Close puts the panel into deep sleep; the last picture stays visible
+func (d *EPD2in13) Close() error {
+	d.command(0x10, 0x01)
+	return d.spi.Close()
+}
@@ -0,0 +1,102 @@
+package display
+
+import (
+	"fmt"
+	"os"
+)
+
+const ssd1306Width = 128
+
+// SSD1306 is a 128x64 or 128x32 OLED on an I2C bus
+type SSD1306 struct {
+	f      *os.File
+	height int
+}
+
+// NewSSD1306 opens and initialises the panel. flip rotates the picture 180
+// degrees for panels mounted upside down.
+func NewSSD1306(bus, addr, height int, flip bool) (*SSD1306, error) {
+	if height != 64 && height != 32 {
+		return nil, fmt.Errorf("SSD1306 height must be 64 or 32, not %d", height)
+	}
+	f, err := openI2C(bus, addr)
+	if err != nil {
+		return nil, fmt.Errorf("SSD1306: %w", err)
+	}
+	d := &SSD1306{f: f, height: height}
+
+	comPins := byte(0x12)
+	if height == 32 {
+		comPins = 0x02
+	}
+	segRemap, comScan := byte(0xA1), byte(0xC8)
+	if flip {
+		segRemap, comScan = 0xA0, 0xC0
+	}
+	err = d.command(
+		0xAE,       // display off
+		0xD5, 0x80, // clock divide
+		0xA8, byte(height-1), // multiplex
+		0xD3, 0x00, // no display offset
+		0x40,       // start line 0
+		0x8D, 0x14, // charge pump on
+		0x20, 0x00, // horizontal addressing
+		segRemap, comScan,
+		0xDA, comPins,
+		0x81, 0xCF, // contrast
+		0xD9, 0xF1, // precharge
+		0xDB, 0x40, // VCOMH level
+		0xA4, // show RAM
+		0xA6, // not inverted
+		0xAF, // display on
+	)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *SSD1306) Width() int  { return ssd1306Width }
+func (d *SSD1306) Height() int { return d.height }
+
+// command sends a run of command bytes
+func (d *SSD1306) command(cmds ...byte) error {
+	if _, err := d.f.Write(append([]byte{0x00}, cmds...)); err != nil {
+		return fmt.Errorf("SSD1306: %w", err)
+	}
+	return nil
+}
+
+// Show writes the whole frame. Each byte of display RAM is a column of eight
+// pixels, top in bit 0.
+func (d *SSD1306) Show(f *Frame) error {
+	pages := d.height / 8
+	buf := make([]byte, ssd1306Width*pages)
+	for y := 0; y < d.height; y++ {
+		for x := 0; x < ssd1306Width; x++ {
+			if f.At(x, y) {
+				buf[y/8*ssd1306Width+x] |= 1 << (y % 8)
+			}
+		}
+	}
+
+	if err := d.command(0x21, 0, ssd1306Width-1, 0x22, 0, byte(pages-1)); err != nil {
+		return err
+	}
+	// keep transfers short; some I2C adapters cap the message size
+	const chunk = 32
+	for i := 0; i < len(buf); i += chunk {
+		end := min(i+chunk, len(buf))
+		if _, err := d.f.Write(append([]byte{0x40}, buf[i:end]...)); err != nil {
+			return fmt.Errorf("SSD1306: %w", err)
+		}
+	}
+	return nil
+}
+
+// Close switches the panel off and releases the bus
+func (d *SSD1306) Close() error {
+	d.command(0xAE)
+	return d.f.Close()
+}
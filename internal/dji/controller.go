@@ -8,6 +8,8 @@ import (
 	"sync"
 	"time"
 
+	"srtla-manager/internal/events"
+
 	"tinygo.org/x/bluetooth"
 )
 
@@ -54,6 +56,7 @@ type DeviceState struct {
 	responseChan      chan *DjiMessage               // Channel for receiving parsed responses
 	stopNotify        chan struct{}                  // Signal to stop notification listener
 	bleDevice         bluetooth.Device               // The connected BLE device
+	bleConnected      bool                           // Whether bleDevice holds a live connection
 	fff3Char          bluetooth.DeviceCharacteristic // FFF3 characteristic (possible response channel)
 	fff4Char          bluetooth.DeviceCharacteristic // FFF4 characteristic for notifications
 	fff5Char          bluetooth.DeviceCharacteristic // FFF5 characteristic for writing
@@ -65,18 +68,36 @@ type Controller struct {
 	mu           sync.RWMutex
 	scanner      *Scanner
 	deviceStates map[string]*DeviceState
-	updateChan   chan *DeviceState
+	bus          *events.Bus
+}
+
+// StateChange reports a device's connection state after it changes
+type StateChange struct {
+	ID      string          `json:"id"`
+	Name    string          `json:"name"`
+	State   ConnectionState `json:"state"`
+	Error   string          `json:"error,omitempty"`
+	Battery int             `json:"battery"` // -1 if unknown
 }
 
+// TopicDeviceState carries device connection state changes
+var TopicDeviceState = events.NewTopic[StateChange]("dji")
+
 // NewController creates a new DJI device controller
 func NewController(scanner *Scanner) *Controller {
 	return &Controller{
 		scanner:      scanner,
 		deviceStates: make(map[string]*DeviceState),
-		updateChan:   make(chan *DeviceState, 10),
 	}
 }
 
+// SetBus publishes device state changes on bus
+func (c *Controller) SetBus(bus *events.Bus) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.bus = bus
+}
+
 // ConnectDevice initiates connection to a discovered device
 func (c *Controller) ConnectDevice(deviceID string) error {
 	discovered := c.scanner.GetDevice(deviceID)
@@ -134,6 +155,7 @@ func (c *Controller) connectAndSetup(deviceID string, discovered *DiscoveredDevi
 
 	c.mu.Lock()
 	state.bleDevice = device
+	state.bleConnected = true
 	c.mu.Unlock()
 
 	// Step 2: Discover services
@@ -661,6 +683,59 @@ func (c *Controller) runStreamingStateMachine(deviceID string, state *DeviceStat
 	c.updateState(deviceID, StateStreaming, "")
 }
 
+// CanReconfigure reports whether a streaming device can switch to config
+// with Reconfigure
+func (c *Controller) CanReconfigure(deviceID string, config *StreamConfig) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	state, ok := c.deviceStates[deviceID]
+	return ok && state.ConnectionState == StateStreaming && state.StreamConfig.SameSession(config)
+}
+
+// Reconfigure switches a streaming device to a new resolution, frame rate
+// and bitrate by sending only the start streaming message again. The WiFi
+// and RTMP session stay up, which takes seconds instead of the full setup
+// sequence. See CanReconfigure for when it applies.
+func (c *Controller) Reconfigure(deviceID string, config *StreamConfig) error {
+	if !c.CanReconfigure(deviceID, config) {
+		return fmt.Errorf("device %s is not streaming in the same session", deviceID)
+	}
+
+	c.mu.RLock()
+	state := c.deviceStates[deviceID]
+	c.mu.RUnlock()
+
+	isOA5Plus := state.Device.Model == ModelOsmoAction5Pro || state.Device.Model == ModelOsmoAction6
+	config.IsOA5Plus = isOA5Plus
+
+	log.Printf("[DJI] Reconfiguring %s in place: %s %dfps %dkbps\n",
+		state.Device.Name, config.Resolution, config.FPS, config.BitrateKbps)
+
+	c.updateState(deviceID, StateStartingStream, "")
+	go func() {
+		streamMsg, err := CreateStartStreamMessage(*config)
+		if err != nil {
+			c.updateState(deviceID, StateError, err.Error())
+			return
+		}
+		if _, err := c.sendAndWaitForResponse(deviceID, streamMsg, StartStreamingTransactionID, ResponseTimeout); err != nil {
+			log.Printf("[DJI] Reconfigure response: %v (continuing anyway)\n", err)
+		}
+		if isOA5Plus {
+			if err := c.sendBLEMessage(deviceID, CreateConfirmStartStreamingMessage()); err != nil {
+				log.Printf("[DJI] Failed to send confirm: %v\n", err)
+			}
+		}
+
+		c.mu.Lock()
+		state.StreamConfig = config
+		c.mu.Unlock()
+
+		c.updateState(deviceID, StateStreaming, "")
+	}()
+	return nil
+}
+
 // StopStreaming stops streaming on a device
 func (c *Controller) StopStreaming(deviceID string) error {
 	c.mu.RLock()
@@ -717,8 +792,9 @@ func (c *Controller) DisconnectDevice(deviceID string) error {
 			close(state.stopNotify)
 		}
 		// Disconnect BLE device
-		if state.bleDevice != (bluetooth.Device{}) {
+		if state.bleConnected {
 			state.bleDevice.Disconnect()
+			state.bleConnected = false
 		}
 	}
 	delete(c.deviceStates, deviceID)
@@ -742,6 +818,15 @@ func (c *Controller) GetDeviceState(deviceID string) *DeviceState {
 	return c.deviceStates[deviceID]
 }
 
+// IsStreamingWith reports whether the device is already streaming with a
+// config equivalent to the given one
+func (c *Controller) IsStreamingWith(deviceID string, config *StreamConfig) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	state, ok := c.deviceStates[deviceID]
+	return ok && state.ConnectionState == StateStreaming && state.StreamConfig.Matches(config)
+}
+
 // GetAllDeviceStates returns all device states
 func (c *Controller) GetAllDeviceStates() []*DeviceState {
 	c.mu.RLock()
@@ -766,17 +851,19 @@ func (c *Controller) updateState(deviceID string, state ConnectionState, errMsg
 	deviceState.ConnectionState = state
 	deviceState.LastError = errMsg
 	deviceState.LastUpdate = time.Now()
+	change := StateChange{
+		ID:      deviceID,
+		State:   state,
+		Error:   errMsg,
+		Battery: deviceState.BatteryPercentage,
+	}
+	if deviceState.Device != nil {
+		change.Name = deviceState.Device.Name
+	}
+	bus := c.bus
 	c.mu.Unlock()
 
 	log.Printf("[DJI] %s state -> %s\n", deviceID, state)
 
-	select {
-	case c.updateChan <- deviceState:
-	default:
-	}
-}
-
-// SubscribeUpdates returns a channel for device state updates
-func (c *Controller) SubscribeUpdates() <-chan *DeviceState {
-	return c.updateChan
+	events.Publish(bus, TopicDeviceState, change)
 }
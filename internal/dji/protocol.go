@@ -72,6 +72,34 @@ type StreamConfig struct {
 	IsOA5Plus     bool // true for OA5Pro and OA6
 }
 
+// Matches reports whether two configs would produce the same stream on the
+// camera. The model flag is derived from the device and is ignored.
+func (s *StreamConfig) Matches(other *StreamConfig) bool {
+	if s == nil || other == nil {
+		return false
+	}
+	return s.WiFiSSID == other.WiFiSSID &&
+		s.WiFiPassword == other.WiFiPassword &&
+		s.RTMPURL == other.RTMPURL &&
+		s.Resolution == other.Resolution &&
+		s.FPS == other.FPS &&
+		s.BitrateKbps == other.BitrateKbps &&
+		s.Stabilization == other.Stabilization
+}
+
+// SameSession reports whether other only changes the resolution, frame rate
+// or bitrate of s. Such a change keeps the camera on the same WiFi network
+// and RTMP session, so it can be applied without the full setup sequence.
+func (s *StreamConfig) SameSession(other *StreamConfig) bool {
+	if s == nil || other == nil {
+		return false
+	}
+	return s.WiFiSSID == other.WiFiSSID &&
+		s.WiFiPassword == other.WiFiPassword &&
+		s.RTMPURL == other.RTMPURL &&
+		s.Stabilization == other.Stabilization
+}
+
 // resolutionToByte converts resolution to DJI protocol byte
 func resolutionToByte(res StreamResolution) uint8 {
 	switch res {
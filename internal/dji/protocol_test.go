@@ -221,3 +221,58 @@ func TestBatteryPercentage(t *testing.T) {
 		t.Error("Expected -1 for non-battery message")
 	}
 }
+
+// Test stream config comparison used to skip redundant reconfigures
+func TestStreamConfigMatches(t *testing.T) {
+	a := &StreamConfig{
+		WiFiSSID:      "TestNetwork",
+		RTMPURL:       "rtmp://192.168.1.1:1935/live",
+		Resolution:    Resolution1080p,
+		FPS:           30,
+		BitrateKbps:   6000,
+		Stabilization: StabilizationOff,
+	}
+	b := *a
+	b.IsOA5Plus = true
+
+	if !a.Matches(&b) {
+		t.Error("Expected configs differing only by model flag to match")
+	}
+
+	b.BitrateKbps = 1000
+	if a.Matches(&b) {
+		t.Error("Expected configs with different bitrates not to match")
+	}
+	if a.Matches(nil) {
+		t.Error("Expected nil config not to match")
+	}
+}
+
+func TestStreamConfigSameSession(t *testing.T) {
+	live := &StreamConfig{
+		WiFiSSID:      "TestNetwork",
+		RTMPURL:       "rtmp://192.168.1.1:1935/live",
+		Resolution:    Resolution1080p,
+		FPS:           30,
+		BitrateKbps:   6000,
+		Stabilization: StabilizationOff,
+	}
+	preview := *live
+	preview.Resolution, preview.FPS, preview.BitrateKbps = Resolution720p, 25, 1000
+
+	if !preview.SameSession(live) {
+		t.Error("Expected a quality change to keep the session")
+	}
+
+	moved := preview
+	moved.RTMPURL = "rtmp://192.168.1.1:9999/live/live"
+	if moved.SameSession(live) {
+		t.Error("Expected a different RTMP URL to need the full setup")
+	}
+
+	steadier := *live
+	steadier.Stabilization = StabilizationRockSteady
+	if steadier.SameSession(live) {
+		t.Error("Expected a stabilization change to need the full setup")
+	}
+}
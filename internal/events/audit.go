@@ -0,0 +1,78 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"srtla-manager/internal/jsonl"
+	"srtla-manager/internal/logger"
+)
+
+// auditBuffer is how many audited events can wait to be written
+const auditBuffer = 64
+
+// AuditLog keeps events from audited topics in a JSON lines file, as a
+// record of who started, stopped and changed what
+type AuditLog struct {
+	w *jsonl.Writer
+}
+
+// OpenAuditLog opens the audit log at path, rotating it at maxBytes
+func OpenAuditLog(path string, maxBytes int64) (*AuditLog, error) {
+	w, err := jsonl.Open(path, maxBytes)
+	if err != nil {
+		return nil, err
+	}
+	return &AuditLog{w: w}, nil
+}
+
+// Record appends an event
+func (a *AuditLog) Record(ev Event) error {
+	return a.w.Append(ev)
+}
+
+// Run records every audited event published on the bus until ctx is done
+func (a *AuditLog) Run(ctx context.Context, b *Bus) {
+	sub := b.Subscribe("audit", auditBuffer)
+	defer sub.Close()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-sub.C:
+			if !ev.Audit {
+				continue
+			}
+			if err := a.Record(ev); err != nil {
+				logger.Warn("Audit log: %v", err)
+			}
+		}
+	}
+}
+
+func (a *AuditLog) Close() error {
+	return a.w.Close()
+}
+
+// AuditEntry is an event read back from the audit log
+type AuditEntry struct {
+	Topic string          `json:"topic"`
+	Time  time.Time       `json:"time"`
+	Data  json.RawMessage `json:"data"`
+}
+
+// ReadAuditLog returns the entries recorded at or after since, oldest first
+func ReadAuditLog(path string, since time.Time) ([]AuditEntry, error) {
+	var entries []AuditEntry
+	err := jsonl.Read(path, func(line []byte) {
+		var e AuditEntry
+		if json.Unmarshal(line, &e) != nil {
+			return
+		}
+		if !e.Time.Before(since) {
+			entries = append(entries, e)
+		}
+	})
+	return entries, err
+}
@@ -0,0 +1,185 @@
+// Package events is the in-process event bus. Subsystems publish typed
+// values to named topics; consumers such as the WebSocket hub, alerts and
+// the audit log subscribe with their own buffer, so a slow consumer drops
+// its own events instead of holding up the publisher or anyone else.
+package events
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Event is a value published on the bus
+type Event struct {
+	Topic string    `json:"topic"`
+	Time  time.Time `json:"time"`
+	Data  any       `json:"data"`
+	Audit bool      `json:"-"` // the topic is kept in the audit log
+}
+
+// Topic names a stream of values of one type
+type Topic[T any] struct {
+	name  string
+	audit bool
+}
+
+// NewTopic declares a topic
+func NewTopic[T any](name string) Topic[T] {
+	return Topic[T]{name: name}
+}
+
+// NewAuditedTopic declares a topic whose events are also kept in the audit
+// log
+func NewAuditedTopic[T any](name string) Topic[T] {
+	return Topic[T]{name: name, audit: true}
+}
+
+func (t Topic[T]) Name() string { return t.name }
+
+// Subscription receives events on C until it is closed
+type Subscription struct {
+	C <-chan Event
+
+	name    string
+	ch      chan Event
+	topics  map[string]bool // nil receives every topic
+	bus     *Bus
+	dropped uint64 // guarded by bus.mu
+}
+
+// TopicStats counts events on one topic
+type TopicStats struct {
+	Published uint64    `json:"published"`
+	Dropped   uint64    `json:"dropped"` // deliveries skipped because a subscriber was full
+	Last      time.Time `json:"last"`
+}
+
+// SubscriberStats describes one subscription
+type SubscriberStats struct {
+	Name     string `json:"name"`
+	Queued   int    `json:"queued"`
+	Capacity int    `json:"capacity"`
+	Dropped  uint64 `json:"dropped"`
+}
+
+// Stats is a snapshot of the bus
+type Stats struct {
+	Topics      map[string]TopicStats `json:"topics"`
+	Subscribers []SubscriberStats     `json:"subscribers"`
+}
+
+// Bus delivers published events to subscriptions
+type Bus struct {
+	mu     sync.Mutex
+	subs   map[*Subscription]struct{}
+	topics map[string]*TopicStats
+}
+
+func NewBus() *Bus {
+	return &Bus{
+		subs:   make(map[*Subscription]struct{}),
+		topics: make(map[string]*TopicStats),
+	}
+}
+
+// Subscribe returns a subscription to topics, or to every topic when none
+// are given. name identifies it in Stats.
+func (b *Bus) Subscribe(name string, buffer int, topics ...string) *Subscription {
+	ch := make(chan Event, buffer)
+	s := &Subscription{C: ch, name: name, ch: ch, bus: b}
+	if len(topics) > 0 {
+		s.topics = make(map[string]bool, len(topics))
+		for _, t := range topics {
+			s.topics[t] = true
+		}
+	}
+
+	b.mu.Lock()
+	b.subs[s] = struct{}{}
+	b.mu.Unlock()
+	return s
+}
+
+// Close stops delivery and closes C
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	if _, ok := s.bus.subs[s]; ok {
+		delete(s.bus.subs, s)
+		close(s.ch)
+	}
+}
+
+// publish never blocks: a subscriber whose buffer is full misses the event
+func (b *Bus) publish(ev Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ts, ok := b.topics[ev.Topic]
+	if !ok {
+		ts = &TopicStats{}
+		b.topics[ev.Topic] = ts
+	}
+	ts.Published++
+	ts.Last = ev.Time
+
+	for s := range b.subs {
+		if s.topics != nil && !s.topics[ev.Topic] {
+			continue
+		}
+		select {
+		case s.ch <- ev:
+		default:
+			s.dropped++
+			ts.Dropped++
+		}
+	}
+}
+
+// Stats returns per-topic and per-subscriber counts
+func (b *Bus) Stats() Stats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	stats := Stats{Topics: make(map[string]TopicStats, len(b.topics))}
+	for name, ts := range b.topics {
+		stats.Topics[name] = *ts
+	}
+	for s := range b.subs {
+		stats.Subscribers = append(stats.Subscribers, SubscriberStats{
+			Name:     s.name,
+			Queued:   len(s.ch),
+			Capacity: cap(s.ch),
+			Dropped:  s.dropped,
+		})
+	}
+	sort.Slice(stats.Subscribers, func(i, j int) bool {
+		return stats.Subscribers[i].Name < stats.Subscribers[j].Name
+	})
+	return stats
+}
+
+// Publish sends v to the topic's subscribers. A nil bus discards it, so
+// subsystems work without one.
+func Publish[T any](b *Bus, t Topic[T], v T) {
+	if b == nil {
+		return
+	}
+	b.publish(Event{Topic: t.name, Time: time.Now(), Data: v, Audit: t.audit})
+}
+
+// Listen calls fn with each value published on the topic until ctx is done
+func Listen[T any](ctx context.Context, b *Bus, t Topic[T], buffer int, fn func(T)) {
+	sub := b.Subscribe(t.name, buffer, t.name)
+	defer sub.Close()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-sub.C:
+			fn(ev.Data.(T))
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package events
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type linkChange struct {
+	Link int
+	Up   bool
+}
+
+var (
+	topicLink  = NewTopic[linkChange]("link")
+	topicStart = NewAuditedTopic[string]("stream_start")
+)
+
+func TestSubscribeFiltersTopics(t *testing.T) {
+	b := NewBus()
+	all := b.Subscribe("all", 4)
+	links := b.Subscribe("links", 4, topicLink.Name())
+
+	Publish(b, topicLink, linkChange{Link: 2})
+	Publish(b, topicStart, "button")
+
+	if got := len(all.C); got != 2 {
+		t.Errorf("all received %d events, want 2", got)
+	}
+	if got := len(links.C); got != 1 {
+		t.Fatalf("links received %d events, want 1", got)
+	}
+	ev := <-links.C
+	if change, ok := ev.Data.(linkChange); !ok || change.Link != 2 || ev.Audit {
+		t.Errorf("got %+v", ev)
+	}
+}
+
+func TestFullSubscriberDrops(t *testing.T) {
+	b := NewBus()
+	slow := b.Subscribe("slow", 1)
+	fast := b.Subscribe("fast", 8)
+
+	for i := 0; i < 3; i++ {
+		Publish(b, topicLink, linkChange{Link: i})
+	}
+
+	stats := b.Stats()
+	if got := stats.Topics["link"]; got.Published != 3 || got.Dropped != 2 {
+		t.Errorf("topic stats %+v, want 3 published and 2 dropped", got)
+	}
+	if len(fast.C) != 3 {
+		t.Errorf("fast subscriber got %d events, want 3", len(fast.C))
+	}
+	if first := (<-slow.C).Data.(linkChange); first.Link != 0 {
+		t.Errorf("slow subscriber kept %+v, want the first event", first)
+	}
+
+	slow.Close()
+	Publish(b, topicLink, linkChange{})
+	if len(b.Stats().Subscribers) != 1 {
+		t.Error("closed subscription still listed")
+	}
+}
+
+func TestListen(t *testing.T) {
+	b := NewBus()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	got := make(chan linkChange, 1)
+	go Listen(ctx, b, topicLink, 4, func(c linkChange) { got <- c })
+
+	// wait for the listener to subscribe
+	for len(b.Stats().Subscribers) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	Publish(b, topicLink, linkChange{Link: 3, Up: true})
+
+	select {
+	case c := <-got:
+		if c.Link != 3 || !c.Up {
+			t.Errorf("got %+v", c)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("listener not called")
+	}
+}
+
+func TestAuditLogKeepsAuditedTopics(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	log, err := OpenAuditLog(path, 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := NewBus()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		log.Run(ctx, b)
+		close(done)
+	}()
+	for len(b.Stats().Subscribers) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	start := time.Now().Add(-time.Second)
+	Publish(b, topicLink, linkChange{Link: 1})
+	Publish(b, topicStart, "button")
+	for b.Stats().Subscribers[0].Queued > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+	log.Close()
+
+	entries, err := ReadAuditLog(path, start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Topic != "stream_start" || string(entries[0].Data) != `"button"` {
+		t.Errorf("got %+v", entries)
+	}
+}
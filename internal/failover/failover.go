@@ -0,0 +1,221 @@
+// Package failover forwards one of several MPEG-TS streams arriving over
+// loopback UDP to a single output, preferring the source with the best
+// priority that is sending. Sources are switched between datagrams, so the
+// consumer sees one continuous stream with a discontinuity at each switch.
+package failover
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Defaults for how quickly sources are dropped and taken back
+const (
+	DefaultTimeout = time.Second     // a source without packets this long is down
+	DefaultHold    = 3 * time.Second // a better source must send this long before it takes over
+)
+
+// maxDatagram fits the largest UDP datagram
+const maxDatagram = 65536
+
+// Source is one stream the switch may forward
+type Source struct {
+	Name     string
+	Priority int // lower is preferred
+	Port     int // loopback UDP port the source is sent to
+}
+
+// SourceStatus reports one source
+type SourceStatus struct {
+	Name       string     `json:"name"`
+	Priority   int        `json:"priority"`
+	Live       bool       `json:"live"`
+	LastPacket *time.Time `json:"last_packet,omitempty"`
+}
+
+// Status reports the switch
+type Status struct {
+	Active  string         `json:"active"` // "" while no source is sending
+	Sources []SourceStatus `json:"sources"`
+}
+
+// source is a Source and when it last sent
+type source struct {
+	Source
+	last      time.Time // last packet, zero if none yet
+	liveSince time.Time // first packet after the last gap
+}
+
+// name returns the source's name, "" for none
+func (s *source) name() string {
+	if s == nil {
+		return ""
+	}
+	return s.Name
+}
+
+func (s *source) live(now time.Time, timeout time.Duration) bool {
+	return !s.last.IsZero() && now.Sub(s.last) < timeout
+}
+
+// Switch forwards the preferred live source to an output address
+type Switch struct {
+	Timeout time.Duration
+	Hold    time.Duration
+
+	mu       sync.Mutex
+	sources  []*source // by priority
+	active   *source
+	onSwitch func(from, to string)
+}
+
+// New returns a switch over sources. onSwitch, if not nil, is called with
+// the names of the old and new active source after each switch.
+func New(sources []Source, onSwitch func(from, to string)) *Switch {
+	s := &Switch{Timeout: DefaultTimeout, Hold: DefaultHold, onSwitch: onSwitch}
+	for _, src := range sources {
+		s.sources = append(s.sources, &source{Source: src})
+	}
+	sort.SliceStable(s.sources, func(i, j int) bool { return s.sources[i].Priority < s.sources[j].Priority })
+	return s
+}
+
+// Run listens for the sources and forwards to the loopback UDP port out
+// until ctx is done
+func (s *Switch) Run(ctx context.Context, out int) error {
+	dst, err := net.DialUDP("udp", nil, &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: out})
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	conns := make([]*net.UDPConn, 0, len(s.sources))
+	defer func() {
+		for _, c := range conns {
+			c.Close()
+		}
+	}()
+	for _, src := range s.sources {
+		c, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: src.Port})
+		if err != nil {
+			return fmt.Errorf("source %s: %w", src.Name, err)
+		}
+		conns = append(conns, c)
+	}
+
+	var wg sync.WaitGroup
+	for i, src := range s.sources {
+		wg.Add(1)
+		go func(src *source, c *net.UDPConn) {
+			defer wg.Done()
+			s.forward(src, c, dst)
+		}(src, conns[i])
+	}
+
+	ticker := time.NewTicker(s.Timeout / 4)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			for _, c := range conns {
+				c.Close()
+			}
+			wg.Wait()
+			return nil
+		case now := <-ticker.C:
+			s.update(now)
+		}
+	}
+}
+
+// forward copies the datagrams of src that arrive while it is active
+func (s *Switch) forward(src *source, c *net.UDPConn, dst *net.UDPConn) {
+	buf := make([]byte, maxDatagram)
+	for {
+		n, err := c.Read(buf)
+		if err != nil {
+			return
+		}
+		now := time.Now()
+
+		s.mu.Lock()
+		if !src.live(now, s.Timeout) {
+			src.liveSince = now
+		}
+		src.last = now
+		first := s.active == nil
+		if first {
+			// the first source to send doesn't wait for the next tick
+			s.active = src
+		}
+		active := s.active == src
+		s.mu.Unlock()
+
+		if first {
+			s.switched("", src.Name)
+		}
+		if active {
+			dst.Write(buf[:n])
+		}
+	}
+}
+
+// update switches to the best source if the active one went quiet or a
+// better one has been sending for the hold time
+func (s *Switch) update(now time.Time) {
+	s.mu.Lock()
+	prev, next := s.active, s.choose(now)
+	s.active = next
+	s.mu.Unlock()
+
+	if next != prev {
+		s.switched(prev.name(), next.name())
+	}
+}
+
+// choose returns the source that should be active. s.mu must be held.
+func (s *Switch) choose(now time.Time) *source {
+	activeLive := s.active != nil && s.active.live(now, s.Timeout)
+	for _, src := range s.sources {
+		if activeLive && src == s.active {
+			return src
+		}
+		if !src.live(now, s.Timeout) {
+			continue
+		}
+		// with the active source down, the best live one takes over at
+		// once; otherwise a better one must prove itself first
+		if !activeLive || now.Sub(src.liveSince) >= s.Hold {
+			return src
+		}
+	}
+	return nil
+}
+
+func (s *Switch) switched(from, to string) {
+	if s.onSwitch != nil {
+		s.onSwitch(from, to)
+	}
+}
+
+// Status reports the active source and when each source last sent
+func (s *Switch) Status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	st := Status{Active: s.active.name()}
+	for _, src := range s.sources {
+		ss := SourceStatus{Name: src.Name, Priority: src.Priority, Live: src.live(now, s.Timeout)}
+		if !src.last.IsZero() {
+			last := src.last
+			ss.LastPacket = &last
+		}
+		st.Sources = append(st.Sources, ss)
+	}
+	return st
+}
@@ -0,0 +1,121 @@
+package failover
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestChoose(t *testing.T) {
+	s := New([]Source{{Name: "backup", Priority: 10}, {Name: "main", Priority: 0}}, nil)
+	main, backup := s.sources[0], s.sources[1]
+	t0 := time.Now()
+
+	send := func(src *source, at time.Time) {
+		if !src.live(at, s.Timeout) {
+			src.liveSince = at
+		}
+		src.last = at
+	}
+
+	send(backup, t0)
+	s.update(t0)
+	if s.active != backup {
+		t.Fatalf("Expected the only live source to be active, got %q", s.active.name())
+	}
+
+	// main comes up but must send for the hold time first
+	for at := t0.Add(500 * time.Millisecond); !at.After(t0.Add(3 * time.Second)); at = at.Add(500 * time.Millisecond) {
+		send(backup, at)
+		send(main, at)
+		s.update(at)
+		if s.active != backup {
+			t.Fatalf("Expected main to wait out the hold time, switched at %v", at.Sub(t0))
+		}
+	}
+	at := t0.Add(3500 * time.Millisecond)
+	send(backup, at)
+	send(main, at)
+	s.update(at)
+	if s.active != main {
+		t.Fatalf("Expected main to take over after the hold time, got %q", s.active.name())
+	}
+
+	// main drops: backup takes over as soon as main times out
+	at = at.Add(s.Timeout)
+	send(backup, at)
+	s.update(at)
+	if s.active != backup {
+		t.Fatalf("Expected backup to take over at once, got %q", s.active.name())
+	}
+
+	at = at.Add(s.Timeout)
+	s.update(at)
+	if s.active != nil {
+		t.Fatalf("Expected no source with nothing sending, got %q", s.active.name())
+	}
+}
+
+func freeUDPPort(t *testing.T) int {
+	t.Helper()
+	c, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	return c.LocalAddr().(*net.UDPAddr).Port
+}
+
+func TestRunForwardsActiveSource(t *testing.T) {
+	out, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+
+	mainPort, backupPort := freeUDPPort(t), freeUDPPort(t)
+	switched := make(chan string, 4)
+	s := New([]Source{{Name: "main", Port: mainPort}, {Name: "backup", Priority: 1, Port: backupPort}},
+		func(from, to string) { switched <- to })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx, out.LocalAddr().(*net.UDPAddr).Port) }()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	backup, err := net.Dial("udp", net.JoinHostPort("127.0.0.1", strconv.Itoa(backupPort)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backup.Close()
+
+	// the switch may not be listening yet, so keep sending until it forwards
+	buf := make([]byte, 16)
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the backup stream to be forwarded")
+		}
+		backup.Write([]byte("backup"))
+		out.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+		n, err := out.Read(buf)
+		if err != nil {
+			continue
+		}
+		if string(buf[:n]) != "backup" {
+			t.Fatalf("Forwarded %q", buf[:n])
+		}
+		break
+	}
+	if to := <-switched; to != "backup" {
+		t.Errorf("Expected a switch to backup, got %q", to)
+	}
+	if st := s.Status(); st.Active != "backup" || st.Sources[0].Live {
+		t.Errorf("Unexpected status %+v", st)
+	}
+}
@@ -0,0 +1,78 @@
+// Package gpio drives and reads GPIO lines through the sysfs interface.
+package gpio
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const sysfsRoot = "/sys/class/gpio"
+
+// Pin is an exported GPIO line. With activeLow set, on means the line is
+// driven or read low.
+type Pin struct {
+	num       int
+	activeLow bool
+}
+
+// Output exports the pin as an output, initially off
+func Output(num int, activeLow bool) (*Pin, error) {
+	p := &Pin{num: num, activeLow: activeLow}
+	if err := p.export("out"); err != nil {
+		return nil, err
+	}
+	return p, p.Set(false)
+}
+
+// Input exports the pin as an input. sysfs cannot enable pull resistors, so
+// buttons need an external pull-up or one set in the device tree.
+func Input(num int, activeLow bool) (*Pin, error) {
+	p := &Pin{num: num, activeLow: activeLow}
+	return p, p.export("in")
+}
+
+// Num is the GPIO number
+func (p *Pin) Num() int {
+	return p.num
+}
+
+func (p *Pin) dir() string {
+	return filepath.Join(sysfsRoot, fmt.Sprintf("gpio%d", p.num))
+}
+
+func (p *Pin) export(direction string) error {
+	if _, err := os.Stat(p.dir()); errors.Is(err, os.ErrNotExist) {
+		if err := os.WriteFile(filepath.Join(sysfsRoot, "export"), []byte(strconv.Itoa(p.num)), 0200); err != nil {
+			return fmt.Errorf("failed to export GPIO %d: %w", p.num, err)
+		}
+		// udev fixes up permissions on the new directory asynchronously
+		time.Sleep(100 * time.Millisecond)
+	}
+	if err := os.WriteFile(filepath.Join(p.dir(), "direction"), []byte(direction), 0200); err != nil {
+		return fmt.Errorf("failed to configure GPIO %d: %w", p.num, err)
+	}
+	return nil
+}
+
+// Set turns an output on or off
+func (p *Pin) Set(on bool) error {
+	value := "0"
+	if on != p.activeLow {
+		value = "1"
+	}
+	return os.WriteFile(filepath.Join(p.dir(), "value"), []byte(value), 0200)
+}
+
+// Get reads whether an input is on
+func (p *Pin) Get() (bool, error) {
+	data, err := os.ReadFile(filepath.Join(p.dir(), "value"))
+	if err != nil {
+		return false, fmt.Errorf("failed to read GPIO %d: %w", p.num, err)
+	}
+	return (strings.TrimSpace(string(data)) == "1") != p.activeLow, nil
+}
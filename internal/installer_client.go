@@ -49,6 +49,131 @@ type UpdateInstallerResponse struct {
 	Error   string `json:"error,omitempty"`
 }
 
+// RestartServiceRequest requests the privileged installer to restart a system
+// service such as ModemManager
+type RestartServiceRequest struct {
+	Token   string `json:"token"`
+	Service string `json:"restart_service"`
+}
+
+// RestartServiceResponse indicates success/failure of a service restart and
+// the state the service was verified in afterwards
+type RestartServiceResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	State   string `json:"state"`
+	Error   string `json:"error,omitempty"`
+}
+
+// ShapeInterfaceRequest requests the privileged installer to cap the egress
+// rate of a network interface; RateKbps 0 removes the cap
+type ShapeInterfaceRequest struct {
+	Token     string `json:"token"`
+	Interface string `json:"shape_interface"`
+	RateKbps  int    `json:"rate_kbps"`
+}
+
+// ShapeInterfaceResponse indicates success/failure of an interface cap
+type ShapeInterfaceResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	Error   string `json:"error,omitempty"`
+}
+
+// RouteRequest requests the privileged installer to run one ip route or ip
+// rule command of the bind IP routing tables
+type RouteRequest struct {
+	Token string   `json:"token"`
+	Args  []string `json:"ip_route"`
+}
+
+// RouteResponse indicates success/failure of the ip command
+type RouteResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	Error   string `json:"error,omitempty"`
+}
+
+// VPNRequest requests the privileged installer to run a wg-quick, wg or
+// tailscale command of the VPN tunnel. The auth key goes in its own field,
+// never on a command line.
+type VPNRequest struct {
+	Token   string   `json:"token"`
+	Command string   `json:"vpn_command"`
+	Args    []string `json:"args"`
+	AuthKey string   `json:"auth_key,omitempty"`
+}
+
+// VPNResponse carries the output of the command
+type VPNResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	Output  string `json:"output"`
+	Error   string `json:"error,omitempty"`
+}
+
+// PowerCycleRequest requests the privileged installer to switch a USB port
+// off and back on with uhubctl
+type PowerCycleRequest struct {
+	Token      string `json:"token"`
+	Hub        string `json:"usb_power_cycle"`
+	Port       string `json:"port"`
+	OffSeconds int    `json:"off_seconds"`
+}
+
+// PowerCycleResponse indicates success/failure of the power cycle
+type PowerCycleResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	Error   string `json:"error,omitempty"`
+}
+
+// LinkAliasRequest requests the privileged installer to set the alias of a
+// network interface; an empty Alias clears it
+type LinkAliasRequest struct {
+	Token     string `json:"token"`
+	Interface string `json:"link_alias"`
+	Alias     string `json:"alias"`
+}
+
+// LinkAliasResponse indicates success/failure of setting the alias
+type LinkAliasResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	Error   string `json:"error,omitempty"`
+}
+
+// PowerOffRequest requests the privileged installer to power the unit off
+type PowerOffRequest struct {
+	Token    string `json:"token"`
+	PowerOff bool   `json:"poweroff"`
+}
+
+// PowerOffResponse indicates whether the power-off was started
+type PowerOffResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	Error   string `json:"error,omitempty"`
+}
+
+// OSUpdateRequest requests the privileged installer to upgrade the operating
+// system with apt or its update script
+type OSUpdateRequest struct {
+	Token  string `json:"token"`
+	Method string `json:"os_update"`
+}
+
+// OSUpdateResponse is one line of an OS update: a line of output, or the
+// outcome once Done is set
+type OSUpdateResponse struct {
+	Output         string `json:"output,omitempty"`
+	Done           bool   `json:"done,omitempty"`
+	Success        bool   `json:"success"`
+	Message        string `json:"message,omitempty"`
+	RebootRequired bool   `json:"reboot_required,omitempty"`
+	Error          string `json:"error,omitempty"`
+}
+
 // InstallDebPackage contacts the privileged installer daemon to install a .deb file
 func InstallDebPackage(debPath string) (InstallResponse, error) {
 	conn, err := net.Dial("unix", installerSocket)
@@ -124,3 +249,194 @@ func UpdateInstallerSelf(sourcePath, targetPath string) (UpdateInstallerResponse
 	}
 	return resp, nil
 }
+
+// RestartServiceWithInstaller requests the srtla-installer daemon to restart a
+// system service and verify it came back
+func RestartServiceWithInstaller(service string) (RestartServiceResponse, error) {
+	conn, err := net.Dial("unix", installerSocket)
+	if err != nil {
+		return RestartServiceResponse{}, fmt.Errorf("connect to installer: %w", err)
+	}
+	defer conn.Close()
+
+	enc := json.NewEncoder(conn)
+	dec := json.NewDecoder(conn)
+
+	if err := enc.Encode(RestartServiceRequest{Token: "", Service: service}); err != nil {
+		return RestartServiceResponse{}, fmt.Errorf("encode: %w", err)
+	}
+
+	var resp RestartServiceResponse
+	if err := dec.Decode(&resp); err != nil {
+		return RestartServiceResponse{}, fmt.Errorf("decode: %w", err)
+	}
+	return resp, nil
+}
+
+// ShapeInterfaceWithInstaller requests the srtla-installer daemon to cap the
+// egress rate of a network interface
+func ShapeInterfaceWithInstaller(iface string, rateKbps int) (ShapeInterfaceResponse, error) {
+	conn, err := net.Dial("unix", installerSocket)
+	if err != nil {
+		return ShapeInterfaceResponse{}, fmt.Errorf("connect to installer: %w", err)
+	}
+	defer conn.Close()
+
+	enc := json.NewEncoder(conn)
+	dec := json.NewDecoder(conn)
+
+	if err := enc.Encode(ShapeInterfaceRequest{Token: "", Interface: iface, RateKbps: rateKbps}); err != nil {
+		return ShapeInterfaceResponse{}, fmt.Errorf("encode: %w", err)
+	}
+
+	var resp ShapeInterfaceResponse
+	if err := dec.Decode(&resp); err != nil {
+		return ShapeInterfaceResponse{}, fmt.Errorf("decode: %w", err)
+	}
+	return resp, nil
+}
+
+// RouteWithInstaller requests the srtla-installer daemon to run ip with args
+func RouteWithInstaller(args []string) (RouteResponse, error) {
+	conn, err := net.Dial("unix", installerSocket)
+	if err != nil {
+		return RouteResponse{}, fmt.Errorf("connect to installer: %w", err)
+	}
+	defer conn.Close()
+
+	enc := json.NewEncoder(conn)
+	dec := json.NewDecoder(conn)
+
+	if err := enc.Encode(RouteRequest{Token: "", Args: args}); err != nil {
+		return RouteResponse{}, fmt.Errorf("encode: %w", err)
+	}
+
+	var resp RouteResponse
+	if err := dec.Decode(&resp); err != nil {
+		return RouteResponse{}, fmt.Errorf("decode: %w", err)
+	}
+	return resp, nil
+}
+
+// VPNWithInstaller requests the srtla-installer daemon to run a VPN
+// command, handing it authKey to pass to tailscale in a file
+func VPNWithInstaller(command string, args []string, authKey string) (VPNResponse, error) {
+	conn, err := net.Dial("unix", installerSocket)
+	if err != nil {
+		return VPNResponse{}, fmt.Errorf("connect to installer: %w", err)
+	}
+	defer conn.Close()
+
+	enc := json.NewEncoder(conn)
+	dec := json.NewDecoder(conn)
+
+	if err := enc.Encode(VPNRequest{Token: "", Command: command, Args: args, AuthKey: authKey}); err != nil {
+		return VPNResponse{}, fmt.Errorf("encode: %w", err)
+	}
+
+	var resp VPNResponse
+	if err := dec.Decode(&resp); err != nil {
+		return VPNResponse{}, fmt.Errorf("decode: %w", err)
+	}
+	return resp, nil
+}
+
+// PowerCycleWithInstaller requests the srtla-installer daemon to power-cycle
+// port of a USB hub
+func PowerCycleWithInstaller(hub, port string, offSeconds int) (PowerCycleResponse, error) {
+	conn, err := net.Dial("unix", installerSocket)
+	if err != nil {
+		return PowerCycleResponse{}, fmt.Errorf("connect to installer: %w", err)
+	}
+	defer conn.Close()
+
+	enc := json.NewEncoder(conn)
+	dec := json.NewDecoder(conn)
+
+	if err := enc.Encode(PowerCycleRequest{Token: "", Hub: hub, Port: port, OffSeconds: offSeconds}); err != nil {
+		return PowerCycleResponse{}, fmt.Errorf("encode: %w", err)
+	}
+
+	var resp PowerCycleResponse
+	if err := dec.Decode(&resp); err != nil {
+		return PowerCycleResponse{}, fmt.Errorf("decode: %w", err)
+	}
+	return resp, nil
+}
+
+// PowerOffWithInstaller requests the srtla-installer daemon to power the
+// unit off
+func PowerOffWithInstaller() (PowerOffResponse, error) {
+	conn, err := net.Dial("unix", installerSocket)
+	if err != nil {
+		return PowerOffResponse{}, fmt.Errorf("connect to installer: %w", err)
+	}
+	defer conn.Close()
+
+	enc := json.NewEncoder(conn)
+	dec := json.NewDecoder(conn)
+
+	if err := enc.Encode(PowerOffRequest{Token: "", PowerOff: true}); err != nil {
+		return PowerOffResponse{}, fmt.Errorf("encode: %w", err)
+	}
+
+	var resp PowerOffResponse
+	if err := dec.Decode(&resp); err != nil {
+		return PowerOffResponse{}, fmt.Errorf("decode: %w", err)
+	}
+	return resp, nil
+}
+
+// OSUpdateWithInstaller requests the srtla-installer daemon to upgrade the
+// operating system, passing each line of output to onOutput as it arrives.
+// It returns once the update has finished.
+func OSUpdateWithInstaller(method string, onOutput func(string)) (OSUpdateResponse, error) {
+	conn, err := net.Dial("unix", installerSocket)
+	if err != nil {
+		return OSUpdateResponse{}, fmt.Errorf("connect to installer: %w", err)
+	}
+	defer conn.Close()
+
+	enc := json.NewEncoder(conn)
+	dec := json.NewDecoder(conn)
+
+	if err := enc.Encode(OSUpdateRequest{Token: "", Method: method}); err != nil {
+		return OSUpdateResponse{}, fmt.Errorf("encode: %w", err)
+	}
+
+	for {
+		var resp OSUpdateResponse
+		if err := dec.Decode(&resp); err != nil {
+			return OSUpdateResponse{}, fmt.Errorf("decode: %w", err)
+		}
+		if resp.Done {
+			return resp, nil
+		}
+		if onOutput != nil {
+			onOutput(resp.Output)
+		}
+	}
+}
+
+// LinkAliasWithInstaller requests the srtla-installer daemon to set the
+// alias of iface
+func LinkAliasWithInstaller(iface, alias string) (LinkAliasResponse, error) {
+	conn, err := net.Dial("unix", installerSocket)
+	if err != nil {
+		return LinkAliasResponse{}, fmt.Errorf("connect to installer: %w", err)
+	}
+	defer conn.Close()
+
+	enc := json.NewEncoder(conn)
+	dec := json.NewDecoder(conn)
+
+	if err := enc.Encode(LinkAliasRequest{Token: "", Interface: iface, Alias: alias}); err != nil {
+		return LinkAliasResponse{}, fmt.Errorf("encode: %w", err)
+	}
+
+	var resp LinkAliasResponse
+	if err := dec.Decode(&resp); err != nil {
+		return LinkAliasResponse{}, fmt.Errorf("decode: %w", err)
+	}
+	return resp, nil
+}
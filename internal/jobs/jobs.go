@@ -0,0 +1,222 @@
+// Package jobs tracks long-running operations (updates, installs, scans) so
+// clients can poll their progress and cancel them over plain HTTP.
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// State of a job
+type State string
+
+const (
+	StateRunning   State = "running"
+	StateSucceeded State = "succeeded"
+	StateFailed    State = "failed"
+	StateCanceled  State = "canceled"
+)
+
+// Finished jobs are kept this long so late pollers can read the result
+const Retention = time.Hour
+
+var (
+	ErrNotFound = errors.New("job not found")
+	ErrFinished = errors.New("job already finished")
+)
+
+// Job is a snapshot of a long-running operation.
+type Job struct {
+	ID         string      `json:"id"`
+	Kind       string      `json:"kind"`
+	State      State       `json:"state"`
+	Progress   int         `json:"progress"` // percent, 0-100
+	Message    string      `json:"message"`
+	Result     interface{} `json:"result,omitempty"`
+	Error      string      `json:"error,omitempty"`
+	CreatedAt  time.Time   `json:"created_at"`
+	UpdatedAt  time.Time   `json:"updated_at"`
+	FinishedAt *time.Time  `json:"finished_at,omitempty"`
+}
+
+// Finished reports whether the job has reached a terminal state.
+func (j Job) Finished() bool {
+	return j.State != StateRunning
+}
+
+// Func is the body of a job. It should return promptly once ctx is canceled;
+// returning an error wrapping context.Canceled marks the job canceled.
+type Func func(ctx context.Context, report *Reporter) (interface{}, error)
+
+// Reporter publishes progress for a running job.
+type Reporter struct {
+	m  *Manager
+	id string
+}
+
+// ID returns the ID of the job being reported on
+func (r *Reporter) ID() string {
+	return r.id
+}
+
+// Progress sets the completion percentage and status message. A negative
+// percent leaves the current value unchanged.
+func (r *Reporter) Progress(percent int, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	r.m.update(r.id, func(j *Job) {
+		if percent >= 0 {
+			j.Progress = min(percent, 100)
+		}
+		j.Message = msg
+	})
+}
+
+type entry struct {
+	job    Job
+	cancel context.CancelFunc
+}
+
+// Manager runs jobs and keeps their state.
+type Manager struct {
+	mu       sync.Mutex
+	jobs     map[string]*entry
+	onUpdate func(Job)
+}
+
+// NewManager creates a Manager. onUpdate, if set, is called with a snapshot
+// every time a job changes.
+func NewManager(onUpdate func(Job)) *Manager {
+	return &Manager{
+		jobs:     make(map[string]*entry),
+		onUpdate: onUpdate,
+	}
+}
+
+// Start runs fn in the background and returns the new job.
+func (m *Manager) Start(kind string, fn Func) Job {
+	ctx, cancel := context.WithCancel(context.Background())
+	now := time.Now()
+	job := Job{
+		ID:        newID(),
+		Kind:      kind,
+		State:     StateRunning,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	m.mu.Lock()
+	m.pruneLocked(now)
+	m.jobs[job.ID] = &entry{job: job, cancel: cancel}
+	m.mu.Unlock()
+	m.notify(job)
+
+	go func() {
+		defer cancel()
+		result, err := fn(ctx, &Reporter{m: m, id: job.ID})
+		m.update(job.ID, func(j *Job) {
+			finished := time.Now()
+			j.FinishedAt = &finished
+			switch {
+			case errors.Is(err, context.Canceled):
+				j.State = StateCanceled
+				j.Error = "canceled"
+			case err != nil:
+				j.State = StateFailed
+				j.Error = err.Error()
+			default:
+				j.State = StateSucceeded
+				j.Progress = 100
+				j.Result = result
+			}
+		})
+	}()
+
+	return job
+}
+
+// Get returns the job with the given ID.
+func (m *Manager) Get(id string) (Job, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return e.job, true
+}
+
+// List returns all retained jobs, newest first.
+func (m *Manager) List() []Job {
+	m.mu.Lock()
+	list := make([]Job, 0, len(m.jobs))
+	for _, e := range m.jobs {
+		list = append(list, e.job)
+	}
+	m.mu.Unlock()
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt.After(list[j].CreatedAt)
+	})
+	return list
+}
+
+// Cancel asks a running job to stop. The job reports StateCanceled once its
+// function has returned.
+func (m *Manager) Cancel(id string) (Job, error) {
+	m.mu.Lock()
+	e, ok := m.jobs[id]
+	if !ok {
+		m.mu.Unlock()
+		return Job{}, ErrNotFound
+	}
+	job := e.job
+	m.mu.Unlock()
+
+	if job.Finished() {
+		return job, ErrFinished
+	}
+	e.cancel()
+	return job, nil
+}
+
+func (m *Manager) update(id string, fn func(*Job)) {
+	m.mu.Lock()
+	e, ok := m.jobs[id]
+	if !ok || e.job.Finished() {
+		m.mu.Unlock()
+		return
+	}
+	fn(&e.job)
+	e.job.UpdatedAt = time.Now()
+	job := e.job
+	m.mu.Unlock()
+
+	m.notify(job)
+}
+
+func (m *Manager) notify(job Job) {
+	if m.onUpdate != nil {
+		m.onUpdate(job)
+	}
+}
+
+// pruneLocked drops finished jobs past the retention period.
+func (m *Manager) pruneLocked(now time.Time) {
+	for id, e := range m.jobs {
+		if e.job.FinishedAt != nil && now.Sub(*e.job.FinishedAt) > Retention {
+			delete(m.jobs, id)
+		}
+	}
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
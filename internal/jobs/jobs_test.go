@@ -0,0 +1,71 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func waitFinished(t *testing.T, m *Manager, id string) Job {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if job, ok := m.Get(id); ok && job.Finished() {
+			return job
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish", id)
+	return Job{}
+}
+
+// A job that completes reports its result and full progress
+func TestJobSucceeds(t *testing.T) {
+	m := NewManager(nil)
+
+	var reported string
+	job := m.Start("test", func(ctx context.Context, report *Reporter) (interface{}, error) {
+		reported = report.ID()
+		report.Progress(50, "halfway")
+		return "done", nil
+	})
+
+	job = waitFinished(t, m, job.ID)
+	if reported != job.ID {
+		t.Errorf("Expected the reporter's ID %q to be the job's, got %q", job.ID, reported)
+	}
+	if job.State != StateSucceeded {
+		t.Fatalf("Expected state %s, got %s", StateSucceeded, job.State)
+	}
+	if job.Progress != 100 || job.Result != "done" {
+		t.Errorf("Expected progress 100 and result 'done', got %d and %v", job.Progress, job.Result)
+	}
+	if job.FinishedAt == nil {
+		t.Error("Expected finished_at to be set")
+	}
+}
+
+// Canceling stops the job and a second cancel reports it as finished
+func TestJobCancel(t *testing.T) {
+	m := NewManager(nil)
+
+	job := m.Start("test", func(ctx context.Context, report *Reporter) (interface{}, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	if _, err := m.Cancel(job.ID); err != nil {
+		t.Fatalf("Cancel failed: %v", err)
+	}
+	job = waitFinished(t, m, job.ID)
+	if job.State != StateCanceled {
+		t.Errorf("Expected state %s, got %s", StateCanceled, job.State)
+	}
+
+	if _, err := m.Cancel(job.ID); err != ErrFinished {
+		t.Errorf("Expected ErrFinished, got %v", err)
+	}
+	if _, err := m.Cancel("missing"); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,125 @@
+// Package jsonl appends JSON values to a file, one per line, keeping its
+// size bounded for SD cards.
+package jsonl
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// maxLine is the longest line Read accepts
+const maxLine = 1024 * 1024
+
+// Writer appends lines to a file. When the file passes its size limit it is
+// moved to <path>.1, replacing the previous one, so at most twice the limit
+// is kept on disk.
+type Writer struct {
+	path     string
+	maxBytes int64
+
+	mu   sync.Mutex
+	f    *os.File
+	size int64
+}
+
+// Open opens path for appending, creating its directory
+func Open(path string, maxBytes int64) (*Writer, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+	w := &Writer{path: path, maxBytes: maxBytes}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *Writer) open() error {
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", w.path, err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to open %s: %w", w.path, err)
+	}
+	w.f = f
+	w.size = info.Size()
+	return nil
+}
+
+// Append writes v as one line
+func (w *Writer) Append(v any) error {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.f == nil {
+		return fmt.Errorf("%s is closed", w.path)
+	}
+	if w.size+int64(len(line)) > w.maxBytes {
+		w.f.Close()
+		w.f = nil
+		if err := os.Rename(w.path, w.path+".1"); err != nil {
+			return fmt.Errorf("failed to rotate %s: %w", w.path, err)
+		}
+		if err := w.open(); err != nil {
+			return err
+		}
+	}
+
+	n, err := w.f.Write(line)
+	w.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", w.path, err)
+	}
+	return nil
+}
+
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.f == nil {
+		return nil
+	}
+	err := w.f.Close()
+	w.f = nil
+	return err
+}
+
+// Read calls fn with each line of the rotated file and then the current
+// one, oldest first. Missing files are skipped.
+func Read(path string, fn func(line []byte)) error {
+	for _, p := range []string{path + ".1", path} {
+		f, err := os.Open(p)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 64*1024), maxLine)
+		for scanner.Scan() {
+			fn(scanner.Bytes())
+		}
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
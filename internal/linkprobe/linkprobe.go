@@ -0,0 +1,306 @@
+// Package linkprobe checks that each bind IP actually reaches the internet,
+// by pinging or fetching a URL from the address, so a link with an address
+// but nothing behind it (a modem without service, a hotel WiFi login page
+// that swallows traffic) can be kept out of the bond. A link changes state
+// only after several probes in a row agree, so one lost ping doesn't drop
+// it.
+package linkprobe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Methods of probing
+const (
+	MethodICMP = "icmp"
+	MethodHTTP = "http"
+)
+
+// Methods lists the valid methods
+var Methods = []string{MethodICMP, MethodHTTP}
+
+// Defaults for the zero values of Settings
+const (
+	DefaultICMPTarget = "1.1.1.1"
+	DefaultHTTPTarget = "http://connectivitycheck.gstatic.com/generate_204"
+	DefaultInterval   = 5 * time.Second
+	DefaultTimeout    = 2 * time.Second
+	DefaultFailAfter  = 3
+	DefaultPassAfter  = 2
+)
+
+// State of a link
+type State string
+
+const (
+	Pending State = "pending" // not probed yet
+	Up      State = "up"
+	Down    State = "down"
+)
+
+// Settings are how and how often links are probed
+type Settings struct {
+	Method    string
+	Target    string // host to ping or URL to fetch
+	Interval  time.Duration
+	Timeout   time.Duration
+	FailAfter int // failures in a row that take an up link down
+	PassAfter int // successes in a row that bring a down link up
+}
+
+func (s Settings) withDefaults() Settings {
+	if s.Method == "" {
+		s.Method = MethodICMP
+	}
+	if s.Target == "" {
+		s.Target = DefaultICMPTarget
+		if s.Method == MethodHTTP {
+			s.Target = DefaultHTTPTarget
+		}
+	}
+	if s.Interval <= 0 {
+		s.Interval = DefaultInterval
+	}
+	if s.Timeout <= 0 {
+		s.Timeout = DefaultTimeout
+	}
+	if s.FailAfter <= 0 {
+		s.FailAfter = DefaultFailAfter
+	}
+	if s.PassAfter <= 0 {
+		s.PassAfter = DefaultPassAfter
+	}
+	return s
+}
+
+// Prober checks one bind IP, returning nil when the target answered
+type Prober func(ctx context.Context, bindIP string) error
+
+// ICMP pings target from bindIP with the system ping, which holds the
+// privilege raw sockets need
+func ICMP(target string) Prober {
+	return func(ctx context.Context, bindIP string) error {
+		wait := 2
+		if deadline, ok := ctx.Deadline(); ok {
+			wait = max(int(time.Until(deadline).Seconds()), 1)
+		}
+		cmd := exec.CommandContext(ctx, "ping", "-n", "-q", "-c", "1", "-W", strconv.Itoa(wait), "-I", bindIP, target)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			if ctx.Err() != nil {
+				return fmt.Errorf("no reply from %s", target)
+			}
+			if exitErr := (*exec.ExitError)(nil); errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+				return fmt.Errorf("no reply from %s", target)
+			}
+			return fmt.Errorf("ping %s: %w: %s", target, err, strings.TrimSpace(string(output)))
+		}
+		return nil
+	}
+}
+
+// HTTP fetches url from bindIP. Any answer counts, even an error status;
+// what matters is that the server was reached.
+func HTTP(url string) Prober {
+	return func(ctx context.Context, bindIP string) error {
+		ip := net.ParseIP(bindIP)
+		if ip == nil {
+			return fmt.Errorf("invalid bind IP %q", bindIP)
+		}
+		dialer := &net.Dialer{LocalAddr: &net.TCPAddr{IP: ip}}
+		client := &http.Client{
+			Transport: &http.Transport{DialContext: dialer.DialContext, DisableKeepAlives: true},
+			// a login page redirecting elsewhere is still an answer
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		resp.Body.Close()
+		return nil
+	}
+}
+
+// New returns the prober for the settings' method and target
+func New(s Settings) Prober {
+	s = s.withDefaults()
+	if s.Method == MethodHTTP {
+		return HTTP(s.Target)
+	}
+	return ICMP(s.Target)
+}
+
+// Status is the state of one link
+type Status struct {
+	BindIP    string    `json:"bind_ip"`
+	State     State     `json:"state"`
+	Streak    int       `json:"streak"`          // probes in a row with the same outcome
+	LastError string    `json:"error,omitempty"` // of the last probe, when it failed
+	LastProbe time.Time `json:"last_probe,omitempty"`
+	Changed   time.Time `json:"changed,omitempty"` // when it last went up or down
+}
+
+// Monitor probes the bind IPs and keeps the state of each
+type Monitor struct {
+	probe    Prober
+	settings Settings
+	onChange func(Status) // called outside the lock when a link goes up or down
+
+	mu    sync.Mutex
+	links map[string]*link
+}
+
+type link struct {
+	Status
+	passed bool // outcome of the last probe
+}
+
+// NewMonitor returns a monitor probing with probe. onChange may be nil.
+func NewMonitor(probe Prober, s Settings, onChange func(Status)) *Monitor {
+	return &Monitor{
+		probe:    probe,
+		settings: s.withDefaults(),
+		onChange: onChange,
+		links:    make(map[string]*link),
+	}
+}
+
+// Run probes the IPs ips returns every interval until ctx is done, calling
+// changed after a round in which a link went up or down
+func (m *Monitor) Run(ctx context.Context, ips func() []string, changed func()) {
+	ticker := time.NewTicker(m.settings.Interval)
+	defer ticker.Stop()
+	for {
+		if m.ProbeAll(ctx, ips()) && changed != nil {
+			changed()
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProbeAll probes ips at once and records the outcomes, reporting whether
+// a link went up or down. Links no longer in ips are forgotten.
+func (m *Monitor) ProbeAll(ctx context.Context, ips []string) bool {
+	m.retain(ips)
+	var wg sync.WaitGroup
+	var changed atomic.Bool
+	for _, ip := range ips {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pctx, cancel := context.WithTimeout(ctx, m.settings.Timeout)
+			err := m.probe(pctx, ip)
+			cancel()
+			if ctx.Err() != nil {
+				return
+			}
+			if m.Record(ip, err, time.Now()) {
+				changed.Store(true)
+			}
+		}()
+	}
+	wg.Wait()
+	return changed.Load()
+}
+
+func (m *Monitor) retain(ips []string) {
+	keep := make(map[string]bool, len(ips))
+	for _, ip := range ips {
+		keep[ip] = true
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for ip := range m.links {
+		if !keep[ip] {
+			delete(m.links, ip)
+		}
+	}
+}
+
+// Record folds in the outcome of a probe of ip and reports whether the
+// link went up or down. The first probe of a link decides its state
+// straight away.
+func (m *Monitor) Record(ip string, err error, now time.Time) bool {
+	m.mu.Lock()
+	st, ok := m.links[ip]
+	if !ok {
+		st = &link{Status: Status{BindIP: ip, State: Pending}}
+		m.links[ip] = st
+	}
+	passed := err == nil
+	if st.Streak > 0 && passed == st.passed {
+		st.Streak++
+	} else {
+		st.Streak = 1
+	}
+	st.passed = passed
+	st.LastProbe = now
+	st.LastError = ""
+	if !passed {
+		st.LastError = err.Error()
+	}
+
+	next := st.State
+	switch {
+	case st.State == Pending && passed:
+		next = Up
+	case st.State == Pending:
+		next = Down
+	case st.State == Up && !passed && st.Streak >= m.settings.FailAfter:
+		next = Down
+	case st.State == Down && passed && st.Streak >= m.settings.PassAfter:
+		next = Up
+	}
+	changed := next != st.State
+	if changed {
+		st.State, st.Changed = next, now
+	}
+	snapshot := st.Status
+	m.mu.Unlock()
+
+	if changed && m.onChange != nil {
+		m.onChange(snapshot)
+	}
+	return changed
+}
+
+// Passing reports whether ip's last state is up
+func (m *Monitor) Passing(ip string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	st, ok := m.links[ip]
+	return ok && st.State == Up
+}
+
+// Statuses returns the state of each link, by bind IP
+func (m *Monitor) Statuses() []Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]Status, 0, len(m.links))
+	for _, st := range m.links {
+		out = append(out, st.Status)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].BindIP < out[j].BindIP })
+	return out
+}
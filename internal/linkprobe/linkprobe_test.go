@@ -0,0 +1,100 @@
+package linkprobe
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var errNoReply = errors.New("no reply")
+
+func TestRecordHysteresis(t *testing.T) {
+	var changes []Status
+	m := NewMonitor(nil, Settings{FailAfter: 3, PassAfter: 2}, func(s Status) { changes = append(changes, s) })
+	now := time.Now()
+	ip := "10.0.0.2"
+
+	if m.Passing(ip) {
+		t.Fatal("Expected a link never probed not to pass")
+	}
+	if !m.Record(ip, nil, now) || !m.Passing(ip) {
+		t.Fatal("Expected the first probe to bring the link up")
+	}
+
+	// two failures are not enough, and a pass in between starts over
+	m.Record(ip, errNoReply, now)
+	m.Record(ip, errNoReply, now)
+	m.Record(ip, nil, now)
+	m.Record(ip, errNoReply, now)
+	m.Record(ip, errNoReply, now)
+	if !m.Passing(ip) {
+		t.Fatal("Expected the link to stay up without 3 failures in a row")
+	}
+	if !m.Record(ip, errNoReply, now) || m.Passing(ip) {
+		t.Fatal("Expected the third failure in a row to take the link down")
+	}
+
+	if m.Record(ip, nil, now) || m.Passing(ip) {
+		t.Fatal("Expected one pass not to bring the link back")
+	}
+	if !m.Record(ip, nil, now) || !m.Passing(ip) {
+		t.Fatal("Expected the second pass in a row to bring the link back")
+	}
+
+	if len(changes) != 3 || changes[1].State != Down || changes[1].LastError != "no reply" || changes[2].State != Up {
+		t.Errorf("Unexpected changes %+v", changes)
+	}
+}
+
+func TestRecordFirstFailure(t *testing.T) {
+	m := NewMonitor(nil, Settings{}, nil)
+	if !m.Record("10.0.0.2", errNoReply, time.Now()) {
+		t.Fatal("Expected the first probe to decide the state")
+	}
+	if st := m.Statuses(); len(st) != 1 || st[0].State != Down {
+		t.Errorf("Expected the link down, got %+v", st)
+	}
+}
+
+func TestProbeAllForgetsRemovedLinks(t *testing.T) {
+	probe := func(ctx context.Context, ip string) error {
+		if ip == "10.0.1.2" {
+			return errNoReply
+		}
+		return nil
+	}
+	m := NewMonitor(probe, Settings{}, nil)
+	m.ProbeAll(context.Background(), []string{"10.0.0.2", "10.0.1.2"})
+	if !m.Passing("10.0.0.2") || m.Passing("10.0.1.2") {
+		t.Fatalf("Unexpected states %+v", m.Statuses())
+	}
+
+	m.ProbeAll(context.Background(), []string{"10.0.0.2"})
+	if st := m.Statuses(); len(st) != 1 || st[0].BindIP != "10.0.0.2" {
+		t.Errorf("Expected the removed link forgotten, got %+v", st)
+	}
+}
+
+func TestHTTPCountsAnyAnswer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://login.example/", http.StatusFound)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := HTTP(server.URL)(ctx, "127.0.0.1"); err != nil {
+		t.Errorf("Expected a redirect to count as reached, got %v", err)
+	}
+
+	server.Close()
+	if err := HTTP(server.URL)(ctx, "127.0.0.1"); err == nil {
+		t.Error("Expected an error once the server is gone")
+	}
+	if err := HTTP(server.URL)(ctx, "not-an-ip"); err == nil {
+		t.Error("Expected an invalid bind IP to fail")
+	}
+}
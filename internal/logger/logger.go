@@ -1,26 +1,36 @@
 package logger
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"regexp"
 	"sync"
+	"time"
 )
 
-// Logger wraps the standard logger and adds file rotation and debug mode
+// Logger wraps the standard logger and adds file rotation and debug mode.
+// Child process output can be split into a rotated file per process next
+// to the main log.
 type Logger struct {
-	mu          sync.RWMutex
-	debug       bool
-	file        *os.File
-	filePath    string
-	maxSizeMB   int
-	maxBackups  int
-	currentSize int64
-	stdLogger   *log.Logger
+	mu           sync.RWMutex
+	debug        bool
+	file         *rotatingFile
+	filePath     string
+	maxSizeMB    int
+	maxBackups   int
+	stdLogger    *log.Logger
+	stdout       *log.Logger
+	processFiles bool
+	processes    map[string]*rotatingFile
 }
 
+// processNamePattern keeps process names usable as file names
+var processNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
 // New creates a new logger instance
 func New(filePath string, maxSizeMB, maxBackups int, debug bool) (*Logger, error) {
 	l := &Logger{
@@ -28,6 +38,8 @@ func New(filePath string, maxSizeMB, maxBackups int, debug bool) (*Logger, error
 		filePath:   filePath,
 		maxSizeMB:  maxSizeMB,
 		maxBackups: maxBackups,
+		stdout:     log.New(os.Stdout, "", log.LstdFlags),
+		processes:  make(map[string]*rotatingFile),
 	}
 
 	// Create log directory if it doesn't exist
@@ -38,97 +50,89 @@ func New(filePath string, maxSizeMB, maxBackups int, debug bool) (*Logger, error
 		}
 
 		// Open log file
-		if err := l.openFile(); err != nil {
+		file, err := openRotating(filePath, maxSizeMB, maxBackups)
+		if err != nil {
 			return nil, fmt.Errorf("failed to open log file: %w", err)
 		}
+		l.file = file
 
 		// Create multi-writer for both stdout and file
 		multiWriter := io.MultiWriter(os.Stdout, l.file)
 		l.stdLogger = log.New(multiWriter, "", log.LstdFlags)
 	} else {
 		// No file logging, just stdout
-		l.stdLogger = log.New(os.Stdout, "", log.LstdFlags)
+		l.stdLogger = l.stdout
 	}
 
 	return l, nil
 }
 
-// openFile opens the log file for writing
-func (l *Logger) openFile() error {
-	file, err := os.OpenFile(l.filePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
-	if err != nil {
-		return err
-	}
+// write is the internal write method; the file rotates itself
+func (l *Logger) write(prefix, format string, v ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
 
-	// Get current file size
-	info, err := file.Stat()
-	if err != nil {
-		file.Close()
-		return err
+	msg := fmt.Sprintf(format, v...)
+	if prefix != "" {
+		msg = prefix + " " + msg
 	}
 
-	l.file = file
-	l.currentSize = info.Size()
-	return nil
+	l.stdLogger.Println(msg)
 }
 
-// rotateIfNeeded checks if rotation is needed and performs it
-func (l *Logger) rotateIfNeeded() error {
-	if l.filePath == "" || l.maxSizeMB <= 0 {
-		return nil
-	}
-
-	maxBytes := int64(l.maxSizeMB) * 1024 * 1024
-	if l.currentSize < maxBytes {
-		return nil
-	}
-
-	// Close current file
-	if l.file != nil {
-		l.file.Close()
-	}
-
-	// Rotate backups
-	for i := l.maxBackups - 1; i >= 1; i-- {
-		oldPath := fmt.Sprintf("%s.%d", l.filePath, i)
-		newPath := fmt.Sprintf("%s.%d", l.filePath, i+1)
-		os.Rename(oldPath, newPath)
-	}
-
-	// Move current file to .1
-	if l.maxBackups > 0 {
-		os.Rename(l.filePath, fmt.Sprintf("%s.1", l.filePath))
-	}
-
-	// Open new file
-	if err := l.openFile(); err != nil {
-		return err
+// SetProcessFiles turns writing each child process's output to its own
+// file on or off. It has no effect without file logging.
+func (l *Logger) SetProcessFiles(enabled bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.processFiles = enabled
+	if !enabled {
+		for name, f := range l.processes {
+			f.Close()
+			delete(l.processes, name)
+		}
 	}
-
-	// Update writer
-	multiWriter := io.MultiWriter(os.Stdout, l.file)
-	l.stdLogger.SetOutput(multiWriter)
-
-	return nil
 }
 
-// write is the internal write method that handles rotation
-func (l *Logger) write(prefix, format string, v ...interface{}) {
+// Process writes a line of a child process's output. With process files on
+// it goes to the process's file and stdout, otherwise to the main log.
+func (l *Logger) Process(name, line string) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
-	msg := fmt.Sprintf(format, v...)
-	if prefix != "" {
-		msg = prefix + " " + msg
+	msg := fmt.Sprintf("[%s] %s", name, line)
+	if !l.processFiles || l.filePath == "" || !processNamePattern.MatchString(name) {
+		l.stdLogger.Println(msg)
+		return
 	}
 
-	l.stdLogger.Println(msg)
+	f, ok := l.processes[name]
+	if !ok {
+		var err error
+		if f, err = openRotating(l.processPath(name), l.maxSizeMB, l.maxBackups); err != nil {
+			l.stdLogger.Printf("[ERROR] Failed to open log file of %s: %v", name, err)
+			l.stdLogger.Println(msg)
+			return
+		}
+		l.processes[name] = f
+	}
+	l.stdout.Println(msg)
+	fmt.Fprintf(f, "%s %s\n", time.Now().Format("2006/01/02 15:04:05"), line)
+}
 
-	// Update size
-	if l.file != nil {
-		l.currentSize += int64(len(msg) + 1) // +1 for newline
-		l.rotateIfNeeded()
+// ProcessFilePath returns the log file of a child process, or "" when the
+// name can't be one
+func (l *Logger) ProcessFilePath(name string) string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.filePath == "" || !processNamePattern.MatchString(name) {
+		return ""
 	}
+	return l.processPath(name)
+}
+
+func (l *Logger) processPath(name string) string {
+	return filepath.Join(filepath.Dir(l.filePath), name+".log")
 }
 
 // Printf writes a formatted message
@@ -138,7 +142,7 @@ func (l *Logger) Printf(format string, v ...interface{}) {
 
 // Println writes a message with newline
 func (l *Logger) Println(v ...interface{}) {
-	l.write("", fmt.Sprint(v...))
+	l.write("", "%s", fmt.Sprint(v...))
 }
 
 // Debug writes a debug message (only if debug mode is enabled)
@@ -204,12 +208,47 @@ func (l *Logger) Close() error {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
+	for _, f := range l.processes {
+		f.Close()
+	}
 	if l.file != nil {
 		return l.file.Close()
 	}
 	return nil
 }
 
+// Compact rotates the log files that have anything in them and gzips
+// their older backups. It returns the files rotated and the bytes saved by
+// compressing.
+func (l *Logger) Compact() (rotated int, saved int64, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	files := make([]*rotatingFile, 0, len(l.processes)+1)
+	if l.file != nil {
+		files = append(files, l.file)
+	}
+	for _, f := range l.processes {
+		files = append(files, f)
+	}
+	var errs []error
+	for _, f := range files {
+		if f.file != nil && f.size > 0 && f.maxBackups > 0 {
+			if err := f.rotate(); err != nil {
+				errs = append(errs, fmt.Errorf("rotate %s: %w", f.path, err))
+				continue
+			}
+			rotated++
+		}
+		n, err := f.compress()
+		saved += n
+		if err != nil {
+			errs = append(errs, fmt.Errorf("compress %s: %w", f.path, err))
+		}
+	}
+	return rotated, saved, errors.Join(errs...)
+}
+
 // Writer returns the underlying io.Writer for standard library compatibility
 func (l *Logger) Writer() io.Writer {
 	return l.stdLogger.Writer()
@@ -290,6 +329,26 @@ func Fatal(format string, v ...interface{}) {
 	}
 }
 
+// Compact rotates and compresses the global logger's files
+func Compact() (rotated int, saved int64, err error) {
+	if l := Get(); l != nil {
+		return l.Compact()
+	}
+	return 0, 0, nil
+}
+
+func Process(name, line string) {
+	if l := Get(); l != nil {
+		l.Process(name, line)
+	}
+}
+
+func SetProcessFiles(enabled bool) {
+	if l := Get(); l != nil {
+		l.SetProcessFiles(enabled)
+	}
+}
+
 func SetDebug(enabled bool) {
 	if l := Get(); l != nil {
 		l.SetDebug(enabled)
@@ -0,0 +1,157 @@
+package logger
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+)
+
+// rotatingFile is an append-only log file that is moved aside to .1, .2,
+// ... once it reaches its size limit. It is not safe for concurrent use.
+type rotatingFile struct {
+	path       string
+	maxSizeMB  int
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+func openRotating(path string, maxSizeMB, maxBackups int) (*rotatingFile, error) {
+	f := &rotatingFile{path: path, maxSizeMB: maxSizeMB, maxBackups: maxBackups}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// open opens the file for appending
+func (f *rotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	// Get current file size
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	f.file = file
+	f.size = info.Size()
+	return nil
+}
+
+// Write appends p and rotates the file once it has reached the limit
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	if rerr := f.rotateIfNeeded(); err == nil && rerr != nil {
+		err = rerr
+	}
+	return n, err
+}
+
+// rotateIfNeeded checks if rotation is needed and performs it
+func (f *rotatingFile) rotateIfNeeded() error {
+	if f.maxSizeMB <= 0 || f.size < int64(f.maxSizeMB)*1024*1024 {
+		return nil
+	}
+	return f.rotate()
+}
+
+// rotate moves the file aside to .1 and starts a new one. Backups may have
+// been compressed to .N.gz by compress.
+func (f *rotatingFile) rotate() error {
+	f.file.Close()
+	f.file = nil
+
+	// Rotate backups
+	for i := f.maxBackups - 1; i >= 1; i-- {
+		for _, ext := range []string{"", ".gz"} {
+			from, to := fmt.Sprintf("%s.%d%s", f.path, i, ext), fmt.Sprintf("%s.%d", f.path, i+1)
+			if _, err := os.Stat(from); err != nil {
+				continue
+			}
+			// the older backup, in whichever form, makes way
+			os.Remove(to)
+			os.Remove(to + ".gz")
+			os.Rename(from, to+ext)
+		}
+	}
+
+	// Move current file to .1
+	if f.maxBackups > 0 {
+		os.Rename(f.path, f.path+".1")
+	} else {
+		os.Remove(f.path)
+	}
+
+	return f.open()
+}
+
+// compress gzips the backups from .2 on that aren't yet, leaving .1 as it
+// is for reading. It returns the bytes saved.
+func (f *rotatingFile) compress() (int64, error) {
+	var saved int64
+	for i := 2; i <= f.maxBackups; i++ {
+		path := fmt.Sprintf("%s.%d", f.path, i)
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		size, err := gzipFile(path)
+		if err != nil {
+			return saved, err
+		}
+		saved += info.Size() - size
+	}
+	return saved, nil
+}
+
+// gzipFile replaces path with path.gz and returns the compressed size
+func gzipFile(path string) (int64, error) {
+	in, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer in.Close()
+	out, err := os.Create(path + ".gz.tmp")
+	if err != nil {
+		return 0, err
+	}
+	zw := gzip.NewWriter(out)
+	_, err = io.Copy(zw, in)
+	if cerr := zw.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(path+".gz.tmp", path+".gz")
+	}
+	if err != nil {
+		os.Remove(path + ".gz.tmp")
+		return 0, err
+	}
+	info, err := os.Stat(path + ".gz")
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), os.Remove(path)
+}
+
+func (f *rotatingFile) Close() error {
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
@@ -0,0 +1,121 @@
+package maintenance
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Defaults for the zero values of the housekeeping settings
+const (
+	DefaultKeepBackups = 3
+	DefaultKeepDays    = 14
+)
+
+// Task is the outcome of one housekeeping step
+type Task struct {
+	Name    string `json:"name"`
+	OK      bool   `json:"ok"`
+	Detail  string `json:"detail,omitempty"`
+	Removed int    `json:"removed,omitempty"` // files removed
+	Freed   int64  `json:"freed,omitempty"`   // bytes freed
+}
+
+// Report is the summary of a housekeeping run
+type Report struct {
+	Trigger    string    `json:"trigger"` // "manual" or "schedule"
+	Tasks      []Task    `json:"tasks"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+}
+
+// OK reports whether every task succeeded
+func (r Report) OK() bool {
+	for _, t := range r.Tasks {
+		if !t.OK {
+			return false
+		}
+	}
+	return true
+}
+
+// Text is the report as lines for a chat or an email
+func (r Report) Text() string {
+	var b strings.Builder
+	status := "completed"
+	if !r.OK() {
+		status = "completed with problems"
+	}
+	fmt.Fprintf(&b, "Maintenance %s in %s", status, r.FinishedAt.Sub(r.StartedAt).Round(time.Second))
+	for _, t := range r.Tasks {
+		mark := "ok"
+		if !t.OK {
+			mark = "FAILED"
+		}
+		fmt.Fprintf(&b, "\n- %s: %s", t.Name, mark)
+		if t.Removed > 0 {
+			fmt.Fprintf(&b, ", %d removed", t.Removed)
+		}
+		if t.Freed > 0 {
+			fmt.Fprintf(&b, ", %s freed", formatSize(t.Freed))
+		}
+		if t.Detail != "" {
+			fmt.Fprintf(&b, " (%s)", t.Detail)
+		}
+	}
+	return b.String()
+}
+
+func formatSize(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1f GB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
+
+// Prune removes the files in dir whose names match, other than the keep
+// newest, that were last modified before cutoff. A zero cutoff removes
+// all but the keep newest. A missing dir has nothing to prune.
+func Prune(dir string, match func(name string) bool, keep int, cutoff time.Time) (removed int, freed int64, err error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var files []os.FileInfo
+	for _, e := range entries {
+		if e.IsDir() || !match(e.Name()) {
+			continue
+		}
+		if info, err := e.Info(); err == nil {
+			files = append(files, info)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].ModTime().After(files[j].ModTime()) })
+
+	var errs []error
+	for i, info := range files {
+		if i < keep || (!cutoff.IsZero() && !info.ModTime().Before(cutoff)) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, info.Name())); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		removed++
+		freed += info.Size()
+	}
+	return removed, freed, errors.Join(errs...)
+}
@@ -0,0 +1,70 @@
+package maintenance
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrune(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	files := map[string]time.Duration{
+		"srtla-manager.1.bak": 40 * 24 * time.Hour,
+		"srtla-manager.2.bak": 30 * 24 * time.Hour,
+		"srtla-manager.3.bak": 20 * 24 * time.Hour,
+		"srtla-manager.4.bak": time.Hour,
+		"notes.txt":           50 * 24 * time.Hour,
+	}
+	for name, age := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("12345"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, now.Add(-age), now.Add(-age)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	isBackup := func(name string) bool { return strings.HasSuffix(name, ".bak") }
+
+	// the two newest stay, and of the rest only those before the cutoff go
+	removed, freed, err := Prune(dir, isBackup, 2, now.Add(-35*24*time.Hour))
+	if err != nil || removed != 1 || freed != 5 {
+		t.Fatalf("Expected 1 file of 5 bytes removed, got %d %d %v", removed, freed, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "srtla-manager.1.bak")); !os.IsNotExist(err) {
+		t.Error("Expected the oldest backup removed")
+	}
+
+	removed, _, _ = Prune(dir, isBackup, 1, time.Time{})
+	if removed != 2 {
+		t.Errorf("Expected all but the newest backup removed, got %d", removed)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "notes.txt")); err != nil {
+		t.Error("Expected files that don't match to stay")
+	}
+
+	if removed, _, err := Prune(filepath.Join(dir, "missing"), isBackup, 0, time.Time{}); err != nil || removed != 0 {
+		t.Errorf("Expected nothing to prune in a missing dir, got %d %v", removed, err)
+	}
+}
+
+func TestReportText(t *testing.T) {
+	start := at(12, 3, 0)
+	r := Report{
+		StartedAt:  start,
+		FinishedAt: start.Add(12 * time.Second),
+		Tasks: []Task{
+			{Name: "backups", OK: true, Removed: 2, Freed: 3 << 20},
+			{Name: "self-test", OK: false, Detail: "ModemManager inactive"},
+		},
+	}
+	want := "Maintenance completed with problems in 12s\n" +
+		"- backups: ok, 2 removed, 3.0 MB freed\n" +
+		"- self-test: FAILED (ModemManager inactive)"
+	if got := r.Text(); got != want {
+		t.Errorf("Unexpected report:\n%s", got)
+	}
+}
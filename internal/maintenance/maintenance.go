@@ -0,0 +1,101 @@
+// Package maintenance schedules OS updates and housekeeping into a weekly
+// maintenance window and names the ways the privileged installer can run
+// the updates.
+package maintenance
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Ways an OS update can be run
+const (
+	MethodApt    = "apt"    // apt-get update && apt-get upgrade
+	MethodScript = "script" // the installer's own update script
+)
+
+// Methods lists the values accepted for maintenance.os_update
+var Methods = []string{MethodApt, MethodScript}
+
+// Days lists the values accepted for maintenance.days
+var Days = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// MaxDuration caps a window, so it can't cover the whole week by accident
+const MaxDuration = 24 * time.Hour
+
+// Window opens at the same local time on each of its days
+type Window struct {
+	days     [7]bool
+	hour     int
+	minute   int
+	duration time.Duration
+}
+
+// ParseWindow builds a window from day names, an HH:MM start and a length.
+// No days means every day.
+func ParseWindow(days []string, start string, duration time.Duration) (Window, error) {
+	var w Window
+	if len(days) == 0 {
+		for i := range w.days {
+			w.days[i] = true
+		}
+	}
+	for _, name := range days {
+		day := dayIndex(name)
+		if day < 0 {
+			return Window{}, fmt.Errorf("unknown day %q (expected one of %s)", name, strings.Join(Days, ", "))
+		}
+		w.days[day] = true
+	}
+
+	t, err := time.Parse("15:04", start)
+	if err != nil {
+		return Window{}, fmt.Errorf("start %q is not a time of day (HH:MM)", start)
+	}
+	w.hour, w.minute = t.Hour(), t.Minute()
+
+	if duration <= 0 || duration > MaxDuration {
+		return Window{}, fmt.Errorf("duration %s is out of range (1m to %s)", duration, MaxDuration)
+	}
+	w.duration = duration
+	return w, nil
+}
+
+func dayIndex(name string) int {
+	for i, d := range Days {
+		if strings.EqualFold(name, d) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Current returns when the window containing t opened, and false when t is
+// outside the window. A window that runs past midnight belongs to the day it
+// opened on.
+func (w Window) Current(t time.Time) (time.Time, bool) {
+	for back := 0; back <= 1; back++ {
+		open := w.openOn(t, -back)
+		if w.days[open.Weekday()] && !t.Before(open) && t.Before(open.Add(w.duration)) {
+			return open, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Next returns when the window next opens after t
+func (w Window) Next(t time.Time) time.Time {
+	for ahead := 0; ahead <= 7; ahead++ {
+		open := w.openOn(t, ahead)
+		if w.days[open.Weekday()] && open.After(t) {
+			return open
+		}
+	}
+	return time.Time{}
+}
+
+// openOn returns the opening time on the day offset days from t
+func (w Window) openOn(t time.Time, offset int) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day()+offset, w.hour, w.minute, 0, 0, t.Location())
+}
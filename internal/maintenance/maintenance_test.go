@@ -0,0 +1,93 @@
+package maintenance
+
+import (
+	"testing"
+	"time"
+)
+
+// 2026-10-12 is a Monday
+func at(day, hour, minute int) time.Time {
+	return time.Date(2026, 10, day, hour, minute, 0, 0, time.UTC)
+}
+
+func TestParseWindowErrors(t *testing.T) {
+	cases := []struct {
+		days     []string
+		start    string
+		duration time.Duration
+	}{
+		{[]string{"someday"}, "03:00", time.Hour},
+		{nil, "3am", time.Hour},
+		{nil, "25:00", time.Hour},
+		{nil, "03:00", 0},
+		{nil, "03:00", 25 * time.Hour},
+	}
+	for _, c := range cases {
+		if _, err := ParseWindow(c.days, c.start, c.duration); err == nil {
+			t.Errorf("Expected an error for %v %q %s", c.days, c.start, c.duration)
+		}
+	}
+}
+
+func TestCurrent(t *testing.T) {
+	w, err := ParseWindow([]string{"Mon", "wed"}, "03:00", 2*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		t    time.Time
+		open bool
+	}{
+		{at(12, 2, 59), false},
+		{at(12, 3, 0), true},
+		{at(12, 4, 59), true},
+		{at(12, 5, 0), false},
+		{at(13, 3, 30), false}, // Tuesday
+		{at(14, 3, 30), true},
+	}
+	for _, c := range cases {
+		open, ok := w.Current(c.t)
+		if ok != c.open {
+			t.Errorf("Current(%s) = %v, want %v", c.t, ok, c.open)
+		}
+		if ok && (open.Hour() != 3 || open.Day() != c.t.Day()) {
+			t.Errorf("Current(%s) opened at %s", c.t, open)
+		}
+	}
+}
+
+// A window past midnight belongs to the day it opened on
+func TestCurrentPastMidnight(t *testing.T) {
+	w, err := ParseWindow([]string{"sun"}, "23:00", 3*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	open, ok := w.Current(at(12, 1, 0))
+	if !ok || !open.Equal(at(11, 23, 0)) {
+		t.Errorf("Expected the window opened Sunday 23:00, got %s %v", open, ok)
+	}
+	if _, ok := w.Current(at(13, 1, 0)); ok {
+		t.Error("Expected no window early Tuesday")
+	}
+}
+
+func TestNext(t *testing.T) {
+	w, err := ParseWindow([]string{"wed"}, "03:00", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next := w.Next(at(12, 12, 0)); !next.Equal(at(14, 3, 0)) {
+		t.Errorf("Expected Wednesday 03:00, got %s", next)
+	}
+	// while open, the next window is a week away
+	if next := w.Next(at(14, 3, 30)); !next.Equal(at(21, 3, 0)) {
+		t.Errorf("Expected the following Wednesday, got %s", next)
+	}
+
+	daily, _ := ParseWindow(nil, "03:00", time.Hour)
+	if next := daily.Next(at(12, 4, 0)); !next.Equal(at(13, 3, 0)) {
+		t.Errorf("Expected tomorrow 03:00, got %s", next)
+	}
+}
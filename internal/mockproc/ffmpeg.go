@@ -0,0 +1,103 @@
+package mockproc
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// publishDelay is how long the mock waits for the "camera" to publish
+const publishDelay = 300 * time.Millisecond
+
+// runFFmpeg prints what ffmpeg prints while copying a camera's RTMP stream
+// to its outputs, until it is stopped or a fault is injected
+func runFFmpeg(args []string, stdout, stderr io.Writer) int {
+	for _, a := range args {
+		switch a {
+		case "-version":
+			fmt.Fprintln(stdout, "ffmpeg version 6.1-mock Copyright (c) 2000-2023 the FFmpeg developers")
+			return 0
+		case "-encoders":
+			fmt.Fprintln(stdout, "Encoders:")
+			fmt.Fprintln(stdout, " V....D libx264              libx264 H.264 / AVC / MPEG-4 AVC / MPEG-4 part 10 (codec h264)")
+			fmt.Fprintln(stdout, " A....D aac                  AAC (Advanced Audio Coding)")
+			return 0
+		}
+	}
+
+	f := loadFaults("ffmpeg")
+	if f.exitCode != 0 {
+		fmt.Fprintln(stderr, "Error opening input: Input/output error (injected)")
+		return f.exitCode
+	}
+
+	input := "rtmp://0.0.0.0:1935/live"
+	for i, a := range args {
+		if a == "-i" && i+1 < len(args) {
+			input = args[i+1]
+		}
+	}
+	kbps := envInt("MOCK_FFMPEG_BITRATE", 6000)
+	fps := envInt("MOCK_FFMPEG_FPS", 30)
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+
+	select {
+	case <-stop:
+		return 0
+	case <-time.After(publishDelay):
+	}
+
+	fmt.Fprintf(stderr, "[rtmp @ 0x55d0c0] Opening '%s' for reading\n", input)
+	if strings.HasPrefix(input, "rtmp://") {
+		fmt.Fprintf(stderr, "Input #0, flv, from '%s':\n", input)
+	} else {
+		fmt.Fprintf(stderr, "Input #0, mpegts, from '%s':\n", input)
+	}
+	fmt.Fprintf(stderr, "  Duration: N/A, start: 0.000000, bitrate: %d kb/s\n", kbps)
+	fmt.Fprintf(stderr, "  Stream #0:0: Video: h264 (High), yuv420p(progressive), 1920x1080, %d kb/s, %d fps, %d tbr, 1k tbn\n", kbps-128, fps, fps)
+	fmt.Fprintln(stderr, "  Stream #0:1: Audio: aac (LC), 48000 Hz, stereo, fltp, 128 kb/s")
+	fmt.Fprintln(stderr, "Output #0, tee, to 'mock':")
+	fmt.Fprintln(stderr, "Stream mapping:")
+	fmt.Fprintln(stderr, "  Stream #0:0 -> #0:0 (copy)")
+	fmt.Fprintln(stderr, "  Stream #0:1 -> #0:1 (copy)")
+
+	start := time.Now()
+	tick := time.NewTicker(ProgressInterval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-stop:
+			fmt.Fprintln(stderr, "Exiting normally, received signal 15.")
+			return 0
+		case <-tick.C:
+		}
+
+		elapsed := time.Since(start)
+		if f.failed(elapsed) {
+			fmt.Fprintf(stderr, "[rtmp @ 0x55d0c0] Error in the pull function. (injected)\n")
+			fmt.Fprintln(stderr, "Error while decoding stream #0:0: Input/output error")
+			return 1
+		}
+		if f.stalled(elapsed) {
+			continue
+		}
+
+		secs := elapsed.Seconds()
+		frames := int(secs * float64(fps))
+		sizeKB := int(secs * float64(kbps) / 8)
+		fmt.Fprintf(stderr, "frame=%6d fps=%3d q=-1.0 size=%8dkB time=%s bitrate=%7.1fkbits/s speed=1.00x\n",
+			frames, fps, sizeKB, timestamp(elapsed), float64(kbps))
+	}
+}
+
+// timestamp formats d as ffmpeg's HH:MM:SS.cc
+func timestamp(d time.Duration) string {
+	cs := int(d / (10 * time.Millisecond))
+	return fmt.Sprintf("%02d:%02d:%02d.%02d", cs/360000, cs/6000%60, cs/100%60, cs%100)
+}
@@ -0,0 +1,112 @@
+// Package mockproc provides stand-ins for ffmpeg and srtla_send that print
+// realistic log and progress lines without touching cameras, modems or the
+// network. A binary that calls Dispatch first behaves as the mock named by
+// the file it was started as, so Install only has to link those names to
+// it and put them first on PATH.
+//
+// Failures are injected through environment variables, where NAME is FFMPEG
+// or SRTLA_SEND:
+//
+//	MOCK_<NAME>_EXIT_CODE   fail on start with this exit code
+//	MOCK_<NAME>_FAIL_AFTER  exit with an error after this long, e.g. 30s
+//	MOCK_<NAME>_STALL_AFTER stop printing progress after this long, but keep running
+//	MOCK_FFMPEG_BITRATE     kbps the camera appears to send (default 6000)
+//	MOCK_FFMPEG_FPS         frame rate the camera appears to send (default 30)
+package mockproc
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Names are the binaries mocked
+var Names = []string{"ffmpeg", "srtla_send"}
+
+// ProgressInterval is how often the mocks print progress
+const ProgressInterval = 500 * time.Millisecond
+
+// Dispatch runs the mock and exits if the process was started under one of
+// the mocked names. It returns otherwise.
+func Dispatch() {
+	name := strings.TrimSuffix(filepath.Base(os.Args[0]), ".exe")
+	var run func(args []string, stdout, stderr io.Writer) int
+	switch name {
+	case "ffmpeg":
+		run = runFFmpeg
+	case "srtla_send":
+		run = runSRTLASend
+	default:
+		return
+	}
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// Install links each mocked name in dir to the running executable
+func Install(dir string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, name := range Names {
+		if runtime.GOOS == "windows" {
+			name += ".exe"
+		}
+		link := filepath.Join(dir, name)
+		os.Remove(link)
+		if err := os.Symlink(exe, link); err != nil {
+			return fmt.Errorf("link %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Enable installs the mocks in dir and puts dir first on PATH, so every
+// process started from now on runs them instead of the real binaries
+func Enable(dir string) error {
+	if err := Install(dir); err != nil {
+		return err
+	}
+	return os.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+// faults are the failures injected into one mock
+type faults struct {
+	exitCode   int
+	failAfter  time.Duration
+	stallAfter time.Duration
+}
+
+func loadFaults(name string) faults {
+	prefix := "MOCK_" + strings.ToUpper(name) + "_"
+	var f faults
+	f.exitCode, _ = strconv.Atoi(os.Getenv(prefix + "EXIT_CODE"))
+	f.failAfter, _ = time.ParseDuration(os.Getenv(prefix + "FAIL_AFTER"))
+	f.stallAfter, _ = time.ParseDuration(os.Getenv(prefix + "STALL_AFTER"))
+	return f
+}
+
+// stalled reports whether progress should stop being printed
+func (f faults) stalled(elapsed time.Duration) bool {
+	return f.stallAfter > 0 && elapsed >= f.stallAfter
+}
+
+// failed reports whether the mock should exit with an error
+func (f faults) failed(elapsed time.Duration) bool {
+	return f.failAfter > 0 && elapsed >= f.failAfter
+}
+
+func envInt(key string, def int) int {
+	if v, err := strconv.Atoi(os.Getenv(key)); err == nil && v > 0 {
+		return v
+	}
+	return def
+}
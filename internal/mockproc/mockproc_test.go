@@ -0,0 +1,142 @@
+package mockproc
+
+import (
+	"os"
+	"runtime"
+	"testing"
+	"time"
+
+	"srtla-manager/internal/process"
+)
+
+// The test binary doubles as the mocks: started as ffmpeg or srtla_send
+// through the links Install makes, it runs the mock instead of the tests
+func TestMain(m *testing.M) {
+	Dispatch()
+	os.Exit(m.Run())
+}
+
+func enable(t *testing.T) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks need privileges on windows")
+	}
+	dir := t.TempDir()
+	if err := Install(dir); err != nil {
+		t.Fatalf("Install: %v", err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func waitFor(t *testing.T, timeout time.Duration, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %s", what)
+}
+
+// The ffmpeg handler reaches streaming and measures the camera's ingest
+// from the mock's input dump and progress lines
+func TestFFmpegStreams(t *testing.T) {
+	enable(t)
+	t.Setenv("MOCK_FFMPEG_BITRATE", "4000")
+
+	h := process.NewFFmpegHandler()
+	if err := h.Start(19351, "live", 16001); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer h.Stop()
+
+	waitFor(t, 5*time.Second, "ingest stats", func() bool { return h.Stats().Ingest.Valid })
+
+	stats := h.Stats()
+	if stats.State != process.FFmpegStreaming {
+		t.Errorf("Expected state %s, got %s", process.FFmpegStreaming, stats.State)
+	}
+	if stats.Bitrate != 4000 {
+		t.Errorf("Expected bitrate 4000, got %v", stats.Bitrate)
+	}
+	if stats.Ingest.Resolution != "1920x1080" || stats.Ingest.DeclaredFPS != 30 {
+		t.Errorf("Expected 1920x1080 at 30 fps, got %s at %v", stats.Ingest.Resolution, stats.Ingest.DeclaredFPS)
+	}
+}
+
+// An injected failure makes ffmpeg exit with an error, as a dropped camera does
+func TestFFmpegFailAfter(t *testing.T) {
+	enable(t)
+	t.Setenv("MOCK_FFMPEG_FAIL_AFTER", "1s")
+
+	h := process.NewFFmpegHandler()
+	if err := h.Start(19352, "live", 16002); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer h.Stop()
+
+	waitFor(t, 2*time.Second, "ffmpeg to run", func() bool { return h.ProcessState() == process.StateRunning })
+	waitFor(t, 5*time.Second, "ffmpeg to exit", func() bool { return h.ProcessState() != process.StateRunning })
+}
+
+// A stalled ffmpeg keeps running but goes stale, which the health monitor
+// restarts it for
+func TestFFmpegStallAfter(t *testing.T) {
+	enable(t)
+	t.Setenv("MOCK_FFMPEG_STALL_AFTER", "1s")
+
+	h := process.NewFFmpegHandler()
+	if err := h.Start(19353, "live", 16003); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer h.Stop()
+
+	waitFor(t, 5*time.Second, "ffmpeg to go stale", func() bool { return h.IsStale(time.Second) })
+	if h.ProcessState() != process.StateRunning {
+		t.Errorf("Expected a stalled ffmpeg to keep running, got %s", h.ProcessState())
+	}
+}
+
+// The srtla_send handler registers, connects and reports the bitrate, and
+// keeps running when given new IPs
+func TestSRTLASendConnects(t *testing.T) {
+	enable(t)
+
+	h := process.NewSRTLAHandler()
+	if err := h.Start("srtla_send", 16004, "127.0.0.1", 5000, []string{"192.0.2.1", "192.0.2.2"}, false, false, false, false); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer h.Stop()
+
+	waitFor(t, 5*time.Second, "a bitrate report", func() bool { return h.Stats().TotalBitrate > 0 })
+	if state := h.Stats().State; state != process.SRTLAConnected {
+		t.Errorf("Expected state %s, got %s", process.SRTLAConnected, state)
+	}
+
+	if err := h.ReloadIPs([]string{"192.0.2.1"}); err != nil {
+		t.Fatalf("ReloadIPs: %v", err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	if h.ProcessState() != process.StateRunning {
+		t.Errorf("Expected srtla_send to survive a reload, got %s", h.ProcessState())
+	}
+}
+
+// An injected exit code fails srtla_send on start
+func TestSRTLASendExitCode(t *testing.T) {
+	enable(t)
+	t.Setenv("MOCK_SRTLA_SEND_EXIT_CODE", "3")
+
+	h := process.NewSRTLAHandler()
+	if err := h.Start("srtla_send", 16005, "127.0.0.1", 5000, []string{"192.0.2.1"}, false, false, false, false); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer h.Stop()
+
+	waitFor(t, 5*time.Second, "srtla_send to exit", func() bool { return h.ProcessState() != process.StateRunning })
+	if state := h.Stats().State; state == process.SRTLAConnected {
+		t.Errorf("Expected srtla_send not to connect, got %s", state)
+	}
+}
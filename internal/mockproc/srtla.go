@@ -0,0 +1,102 @@
+package mockproc
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// statsInterval is how often the srtla_send mock reports its bitrate
+const statsInterval = 2 * time.Second
+
+// runSRTLASend prints what srtla_send prints while registering and bonding
+// the links in its IPs file. It re-reads the file on SIGHUP like the real
+// one does.
+func runSRTLASend(args []string, stdout, stderr io.Writer) int {
+	for _, a := range args {
+		if a == "--version" || a == "-v" {
+			fmt.Fprintln(stdout, "srtla_send 3.0.0-mock")
+			return 0
+		}
+	}
+	if len(args) < 4 {
+		fmt.Fprintln(stderr, "Usage: srtla_send SRT_LISTEN_PORT SRTLA_HOST SRTLA_PORT BIND_IPS_FILE")
+		return 2
+	}
+	host, port, ipsFile := args[1], args[2], args[3]
+
+	f := loadFaults("srtla_send")
+	if f.exitCode != 0 {
+		fmt.Fprintf(stderr, "Failed to resolve %s: injected failure\n", host)
+		return f.exitCode
+	}
+
+	ips, err := readIPs(ipsFile)
+	if err != nil || len(ips) == 0 {
+		fmt.Fprintf(stderr, "Failed to read the bind IPs from %s\n", ipsFile)
+		return 1
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+
+	fmt.Fprintf(stdout, "Listening for SRT on 0.0.0.0:%s\n", args[0])
+	for _, ip := range ips {
+		fmt.Fprintf(stdout, "%s: registering with %s:%s (reg1)\n", ip, host, port)
+	}
+	for _, ip := range ips {
+		fmt.Fprintf(stdout, "%s: reg2 received\n", ip)
+	}
+	fmt.Fprintf(stdout, "Registration complete, connected with %d link(s)\n", len(ips))
+
+	start := time.Now()
+	tick := time.NewTicker(statsInterval)
+	defer tick.Stop()
+	for {
+		select {
+		case sig := <-signals:
+			if sig != syscall.SIGHUP {
+				return 0
+			}
+			if reloaded, err := readIPs(ipsFile); err == nil && len(reloaded) > 0 {
+				ips = reloaded
+			}
+			fmt.Fprintf(stdout, "Reloaded %s: %s\n", ipsFile, strings.Join(ips, ", "))
+			continue
+		case <-tick.C:
+		}
+
+		elapsed := time.Since(start)
+		if f.failed(elapsed) {
+			fmt.Fprintf(stderr, "All links failed: connection to %s:%s timed out (injected)\n", host, port)
+			return 1
+		}
+		if f.stalled(elapsed) {
+			continue
+		}
+
+		mbps := float64(envInt("MOCK_FFMPEG_BITRATE", 6000)) / 1000
+		for _, ip := range ips {
+			fmt.Fprintf(stdout, "%s: window=%d rtt=%.1f quality=1.00\n", ip, 20000, 45.0)
+		}
+		fmt.Fprintf(stdout, "Total: %.2f Mbps over %d link(s)\n", mbps, len(ips))
+	}
+}
+
+func readIPs(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var ips []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			ips = append(ips, line)
+		}
+	}
+	return ips, nil
+}
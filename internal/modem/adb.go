@@ -149,6 +149,12 @@ func (p *ADBProvider) GetModemInfo(deviceID string) (*ModemInfo, error) {
 		p.getInterfaceStats(info.Interface, info)
 	}
 
+	// Get temperature and thermal throttling, from the dongle or the host
+	p.getThermal(deviceID, info)
+	if info.TemperatureC == nil && info.Interface != "" {
+		info.TemperatureC = hostTemperature(info.Interface)
+	}
+
 	return info, nil
 }
 
@@ -0,0 +1,81 @@
+package modem
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ConnectTimeout bounds bringing a modem's data connection up or down
+const ConnectTimeout = 90 * time.Second
+
+// Settings are what a modem connects to the network with
+type Settings struct {
+	APN      string
+	User     string
+	Password string
+	IPType   string // ipv4, ipv6 or ipv4v6; the modem's default when empty
+}
+
+// simpleConnect returns the settings as mmcli's --simple-connect list
+func (s Settings) simpleConnect() string {
+	parts := []string{"apn=" + s.APN}
+	if s.User != "" {
+		parts = append(parts, "user="+s.User)
+	}
+	if s.Password != "" {
+		parts = append(parts, "password="+s.Password)
+	}
+	if s.IPType != "" {
+		parts = append(parts, "ip-type="+s.IPType)
+	}
+	return strings.Join(parts, ",")
+}
+
+// CanConnect reports whether a modem in state would be brought onto the
+// network by connecting it. A locked SIM, a failed modem or one already
+// connecting or connected is left alone.
+func CanConnect(state string) bool {
+	switch state {
+	case "disabled", "enabled", "searching", "registered":
+		return true
+	}
+	return false
+}
+
+// Connect enables a modem and brings up its data connection with settings
+func (m *Manager) Connect(id string, s Settings) error {
+	if strings.TrimSpace(s.APN) == "" {
+		return fmt.Errorf("apn is empty")
+	}
+	mmID, err := m.mmcliID(id)
+	if err != nil {
+		return err
+	}
+	return runMMCLI("simple-connect", "-m", mmID, "--simple-connect="+s.simpleConnect())
+}
+
+// Disconnect brings down a modem's data connections
+func (m *Manager) Disconnect(id string) error {
+	mmID, err := m.mmcliID(id)
+	if err != nil {
+		return err
+	}
+	return runMMCLI("simple-disconnect", "-m", mmID, "--simple-disconnect")
+}
+
+func (m *Manager) mmcliID(id string) (string, error) {
+	if strings.HasPrefix(id, "adb:") {
+		return "", fmt.Errorf("connecting is not supported for adb devices")
+	}
+	if !m.mmcliAvail {
+		return "", fmt.Errorf("mmcli not available")
+	}
+	return strings.TrimPrefix(id, "mmcli:"), nil
+}
+
+// runMMCLI runs an mmcli command, naming it by op in errors so the
+// password in the arguments isn't repeated
+func runMMCLI(op string, args ...string) error {
+	return runCommand(ConnectTimeout, "mmcli "+op, "mmcli", args...)
+}
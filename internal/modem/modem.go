@@ -29,6 +29,9 @@ type ModemInfo struct {
 	Interface     string `json:"interface"`
 	DataTx        int64  `json:"data_tx"`
 	DataRx        int64  `json:"data_rx"`
+
+	TemperatureC *float64 `json:"temperature_c,omitempty"` // hottest sensor, where the dongle exposes one
+	Throttling   string   `json:"throttling,omitempty"`    // thermal state, from none to shutdown, where reported
 }
 
 type Manager struct {
@@ -207,6 +210,7 @@ func (m *Manager) getMMCLIModem(id string) (*ModemInfo, error) {
 				CurrentModes       string   `json:"current-modes"`
 				OwnNumbers         []string `json:"own-numbers"`
 				Bearers            []string `json:"bearers"`
+				SIM                string   `json:"sim"`
 				PrimaryPort        string   `json:"primary-port"`
 				Ports              []string `json:"ports"`
 			} `json:"generic"`
@@ -251,6 +255,11 @@ func (m *Manager) getMMCLIModem(id string) (*ModemInfo, error) {
 		info.NetworkType = parseNetworkType(modemResp.Modem.Generic.CurrentModes)
 	}
 
+	if sim := modemResp.Modem.Generic.SIM; sim != "" && sim != "--" {
+		parts := strings.Split(sim, "/")
+		info.IMSI = m.getSIMIMSI(parts[len(parts)-1])
+	}
+
 	if len(modemResp.Modem.Generic.OwnNumbers) > 0 {
 		info.PhoneNumber = modemResp.Modem.Generic.OwnNumbers[0]
 	}
@@ -271,9 +280,10 @@ func (m *Manager) getMMCLIModem(id string) (*ModemInfo, error) {
 		m.getBearerInfo(bearerID, info)
 	}
 
-	// Get data usage from interface
+	// Get data usage and temperature from interface
 	if info.Interface != "" {
 		m.getInterfaceStats(info.Interface, info)
+		info.TemperatureC = hostTemperature(info.Interface)
 	}
 
 	return info, nil
@@ -292,6 +302,25 @@ func parseNetworkType(modes string) string {
 	return ""
 }
 
+func (m *Manager) getSIMIMSI(simID string) string {
+	output, err := exec.Command("mmcli", "-i", simID, "-J").Output()
+	if err != nil {
+		return ""
+	}
+
+	var simResp struct {
+		SIM struct {
+			Properties struct {
+				IMSI string `json:"imsi"`
+			} `json:"properties"`
+		} `json:"sim"`
+	}
+	if err := json.Unmarshal(output, &simResp); err != nil {
+		return ""
+	}
+	return simResp.SIM.Properties.IMSI
+}
+
 func (m *Manager) getBearerInfo(bearerID string, info *ModemInfo) {
 	cmd := exec.Command("mmcli", "-b", bearerID, "-J")
 	output, err := cmd.Output()
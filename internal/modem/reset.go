@@ -0,0 +1,103 @@
+package modem
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"srtla-manager/internal"
+)
+
+// Ways of resetting a stuck modem
+const (
+	ResetModem = "modem" // ModemManager's reset, or a reboot over ADB
+	ResetUSB   = "usb"   // power-cycle its USB port with uhubctl
+)
+
+// ResetMethods lists the values accepted for a reset's method
+var ResetMethods = []string{ResetModem, ResetUSB}
+
+// usbOffSeconds is how long a power-cycled port is left off
+const usbOffSeconds = 5
+
+// Reset restarts a modem: through ModemManager, or by rebooting an ADB
+// dongle
+func (m *Manager) Reset(id string) error {
+	if deviceID, ok := strings.CutPrefix(id, "adb:"); ok {
+		if !m.adbProvider.IsAvailable() {
+			return fmt.Errorf("adb not available")
+		}
+		return runCommand(30*time.Second, "adb reboot", "adb", "-s", deviceID, "reboot")
+	}
+	mmID, err := m.mmcliID(id)
+	if err != nil {
+		return err
+	}
+	return runMMCLI("reset", "-m", mmID, "--reset")
+}
+
+// PowerCycle switches off the USB port a modem's network interface is on
+// and back on, which revives dongles that no longer answer at all. uhubctl
+// needs root, so it runs through srtla-installer unless the manager is root.
+func PowerCycle(iface string) error {
+	if _, err := exec.LookPath("uhubctl"); err != nil {
+		return fmt.Errorf("uhubctl not available")
+	}
+	dev, err := filepath.EvalSymlinks(filepath.Join("/sys/class/net", iface, "device"))
+	if err != nil {
+		return fmt.Errorf("%s is not a USB device: %w", iface, err)
+	}
+	hub, port, err := usbPort(dev)
+	if err != nil {
+		return err
+	}
+	if os.Geteuid() == 0 {
+		return runCommand(30*time.Second, "uhubctl", "uhubctl", "-l", hub, "-p", port, "-a", "cycle", "-d", fmt.Sprint(usbOffSeconds))
+	}
+	resp, err := internal.PowerCycleWithInstaller(hub, port, usbOffSeconds)
+	if err != nil {
+		return fmt.Errorf("uhubctl: %w", err)
+	}
+	if !resp.Success {
+		return fmt.Errorf("uhubctl: %s", strings.TrimSpace(resp.Message))
+	}
+	return nil
+}
+
+// usbPort returns the hub location and port, as uhubctl takes them, of a
+// USB device or interface sysfs path such as .../usb1/1-1/1-1.3/1-1.3:1.0
+func usbPort(devPath string) (hub, port string, err error) {
+	name := filepath.Base(devPath)
+	if strings.Contains(name, ":") {
+		name = filepath.Base(filepath.Dir(devPath))
+	}
+	// 1-1.3 is port 3 of hub 1-1; 1-2 is port 2 of root hub 1
+	if i := strings.LastIndex(name, "."); i > 0 {
+		return name[:i], name[i+1:], nil
+	}
+	if i := strings.LastIndex(name, "-"); i > 0 {
+		return name[:i], name[i+1:], nil
+	}
+	return "", "", fmt.Errorf("%s is not on a USB port", devPath)
+}
+
+// runCommand runs a command for up to timeout, returning its output as the
+// error when it fails
+func runCommand(timeout time.Duration, op, name string, args ...string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, name, args...).CombinedOutput()
+	if err != nil {
+		msg := strings.TrimSpace(string(output))
+		if msg == "" {
+			msg = err.Error()
+		}
+		return fmt.Errorf("%s: %s", op, msg)
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package modem
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Thermal states a dongle reports through Android's thermal service, in
+// rising order. Anything past ThrottleNone slows the modem down.
+const (
+	ThrottleNone      = "none"
+	ThrottleLight     = "light"
+	ThrottleModerate  = "moderate"
+	ThrottleSevere    = "severe"
+	ThrottleCritical  = "critical"
+	ThrottleEmergency = "emergency"
+	ThrottleShutdown  = "shutdown"
+)
+
+var throttleStates = []string{ThrottleNone, ThrottleLight, ThrottleModerate, ThrottleSevere, ThrottleCritical, ThrottleEmergency, ThrottleShutdown}
+
+// HotTemperature is when a modem without a thermal state is taken to be
+// throttling; it counts as cooled again CoolMargin below it
+const (
+	HotTemperature = 70.0
+	CoolMargin     = 5.0
+)
+
+// Hot reports whether a modem is throttling or too hot, given whether it
+// was before, so a temperature hovering at the limit isn't reported over
+// and over
+func (m ModemInfo) Hot(was bool) bool {
+	if m.Throttling != "" {
+		return m.Throttling != ThrottleNone
+	}
+	if m.TemperatureC == nil {
+		return false
+	}
+	if was {
+		return *m.TemperatureC > HotTemperature-CoolMargin
+	}
+	return *m.TemperatureC >= HotTemperature
+}
+
+var (
+	thermalStatusRe = regexp.MustCompile(`Thermal Status: (\d+)`)
+	thermalValueRe  = regexp.MustCompile(`mValue=(-?[0-9.]+)`)
+)
+
+// parseThermalService reads the thermal state and hottest sensor from
+// `dumpsys thermalservice`
+func parseThermalService(output string) (temp *float64, throttling string) {
+	if m := thermalStatusRe.FindStringSubmatch(output); m != nil {
+		if n, err := strconv.Atoi(m[1]); err == nil && n >= 0 && n < len(throttleStates) {
+			throttling = throttleStates[n]
+		}
+	}
+	for _, m := range thermalValueRe.FindAllStringSubmatch(output, -1) {
+		if v, err := strconv.ParseFloat(m[1], 64); err == nil && (temp == nil || v > *temp) {
+			temp = &v
+		}
+	}
+	return temp, throttling
+}
+
+// parseMilliCelsius reads sysfs temperatures, one per line, returning the
+// hottest. Most are in thousandths of a degree; some drivers give degrees.
+func parseMilliCelsius(output string) *float64 {
+	var hottest *float64
+	for _, line := range strings.Fields(output) {
+		v, err := strconv.ParseFloat(line, 64)
+		if err != nil || v <= 0 {
+			continue
+		}
+		if v > 1000 {
+			v /= 1000
+		}
+		if hottest == nil || v > *hottest {
+			hottest = &v
+		}
+	}
+	return hottest
+}
+
+// getThermal reads the dongle's temperature and thermal state over ADB,
+// from the thermal service or else its thermal zones
+func (p *ADBProvider) getThermal(deviceID string, info *ModemInfo) {
+	output, err := exec.Command("adb", "-s", deviceID, "shell", "dumpsys", "thermalservice").Output()
+	if err == nil {
+		info.TemperatureC, info.Throttling = parseThermalService(string(output))
+	}
+	if info.TemperatureC != nil {
+		return
+	}
+	output, err = exec.Command("adb", "-s", deviceID, "shell", "cat /sys/class/thermal/thermal_zone*/temp").Output()
+	if err == nil {
+		info.TemperatureC = parseMilliCelsius(string(output))
+	}
+}
+
+// hostTemperature reads the hottest hwmon sensor of the USB device behind
+// a network interface, for dongles whose driver exposes one
+func hostTemperature(iface string) *float64 {
+	dev, err := filepath.EvalSymlinks(filepath.Join("/sys/class/net", iface, "device"))
+	if err != nil {
+		return nil
+	}
+	// the sensor may sit on the USB interface or the device above it
+	for i := 0; i < 2; i++ {
+		files, _ := filepath.Glob(filepath.Join(dev, "hwmon", "hwmon*", "temp*_input"))
+		var values []string
+		for _, f := range files {
+			if data, err := os.ReadFile(f); err == nil {
+				values = append(values, strings.TrimSpace(string(data)))
+			}
+		}
+		if t := parseMilliCelsius(strings.Join(values, "\n")); t != nil {
+			return t
+		}
+		dev = filepath.Dir(dev)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultSMTPPort is used when an Email sender has no port
+const DefaultSMTPPort = 587
+
+// implicitTLSPort speaks TLS from the start rather than through STARTTLS
+const implicitTLSPort = 465
+
+// Email mails each notification through an SMTP server. Port 465 uses TLS
+// from the start; other ports switch to TLS when the server offers
+// STARTTLS. Credentials are only sent over TLS.
+type Email struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+func (e Email) Name() string { return "email" }
+
+func (e Email) Send(ctx context.Context, n Notification) error {
+	port := e.Port
+	if port == 0 {
+		port = DefaultSMTPPort
+	}
+	addr := net.JoinHostPort(e.Host, strconv.Itoa(port))
+	tlsConfig := &tls.Config{ServerName: e.Host}
+
+	dialer := &net.Dialer{Timeout: client.Timeout}
+	var conn net.Conn
+	var err error
+	if port == implicitTLSPort {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(client.Timeout)
+	}
+	conn.SetDeadline(deadline)
+
+	c, err := smtp.NewClient(conn, e.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if port != implicitTLSPort {
+		if ok, _ := c.Extension("STARTTLS"); ok {
+			if err := c.StartTLS(tlsConfig); err != nil {
+				return err
+			}
+		}
+	}
+	if e.Username != "" {
+		// PlainAuth refuses to send the password without TLS
+		if err := c.Auth(smtp.PlainAuth("", e.Username, e.Password, e.Host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(e.From); err != nil {
+		return err
+	}
+	for _, to := range e.To {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(mailMessage(e.From, e.To, n)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// mailMessage is n as a plain text mail. The subject is the first line of
+// the message.
+func mailMessage(from string, to []string, n Notification) []byte {
+	text := Message(n)
+	subject, _, _ := strings.Cut(text, "\n")
+	if r := []rune(subject); len(r) > 120 {
+		subject = string(r[:117]) + "..."
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", n.Time.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	for _, line := range strings.Split(text, "\n") {
+		// SMTP wants lines ending in CRLF
+		b.WriteString(strings.TrimRight(line, "\r"))
+		b.WriteString("\r\n")
+	}
+	return b.Bytes()
+}
@@ -0,0 +1,118 @@
+// Package notify sends notifications to remote services, a webhook, a
+// Telegram chat or email, through a queue kept on disk. While the uplink is down
+// nothing is lost: notifications wait in the queue, across restarts, and
+// go out in order once they can be delivered, marked as delayed.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DelayedAfter is how late a notification may be delivered before it is
+// marked as delayed
+const DelayedAfter = 30 * time.Second
+
+// TelegramAPI is the Telegram Bot API
+const TelegramAPI = "https://api.telegram.org"
+
+var client = &http.Client{Timeout: 10 * time.Second}
+
+// Notification is one message about an event on the unit
+type Notification struct {
+	ID      string    `json:"id"`
+	Event   string    `json:"event"`
+	Text    string    `json:"text"`
+	Host    string    `json:"host,omitempty"`
+	Time    time.Time `json:"time"`    // when the event happened
+	Delayed bool      `json:"delayed"` // delivered more than DelayedAfter after Time
+}
+
+// Sender delivers notifications to one service
+type Sender interface {
+	Name() string
+	Send(ctx context.Context, n Notification) error
+}
+
+// Webhook POSTs each notification as JSON
+type Webhook struct {
+	URL string
+}
+
+func (w Webhook) Name() string { return "webhook" }
+
+func (w Webhook) Send(ctx context.Context, n Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return do(req)
+}
+
+// Telegram sends each notification as a message from a bot to a chat
+type Telegram struct {
+	API    string // TelegramAPI unless set
+	Token  string
+	ChatID string
+}
+
+func (t Telegram) Name() string { return "telegram" }
+
+func (t Telegram) Send(ctx context.Context, n Notification) error {
+	api := t.API
+	if api == "" {
+		api = TelegramAPI
+	}
+	body, err := json.Marshal(map[string]string{"chat_id": t.ChatID, "text": Message(n)})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/bot%s/sendMessage", api, t.Token), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// keep the token out of errors, which end up in the log
+	if err := do(req); err != nil {
+		return errors.New(strings.ReplaceAll(err.Error(), t.Token, "***"))
+	}
+	return nil
+}
+
+// Message is the text of a notification for a chat, with the time it
+// happened when it arrives late
+func Message(n Notification) string {
+	msg := n.Text
+	if n.Host != "" {
+		msg = n.Host + ": " + msg
+	}
+	if n.Delayed {
+		msg += fmt.Sprintf(" (delayed, at %s)", n.Time.Local().Format("15:04:05 Jan 2"))
+	}
+	return msg
+}
+
+func do(req *http.Request) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("server answered %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSender records what it was sent and fails while down
+type fakeSender struct {
+	mu   sync.Mutex
+	down bool
+	got  []Notification
+}
+
+func (f *fakeSender) Name() string { return "fake" }
+
+func (f *fakeSender) Send(ctx context.Context, n Notification) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.down {
+		return errors.New("network is unreachable")
+	}
+	f.got = append(f.got, n)
+	return nil
+}
+
+func TestQueueSurvivesOutageAndRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.json")
+	sender := &fakeSender{down: true}
+
+	q, err := Open(path, 10, sender)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-time.Minute)
+	q.Push(Notification{ID: "1", Event: "link_down", Text: "link one down", Time: old})
+	q.Push(Notification{ID: "2", Event: "link_up", Text: "link one up", Time: time.Now()})
+
+	if q.deliver(context.Background()) {
+		t.Fatal("Expected delivery to fail while the sender is down")
+	}
+	if s := q.Status(); s.Pending != 2 || s.LastError != "fake: network is unreachable" {
+		t.Fatalf("Status() = %+v", s)
+	}
+
+	// the queue is reopened after a restart, with the uplink back
+	sender.down = false
+	q, err = Open(path, 10, sender)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !q.deliver(context.Background()) {
+		t.Fatal("Expected everything to be delivered")
+	}
+	if len(sender.got) != 2 || sender.got[0].ID != "1" || sender.got[1].ID != "2" {
+		t.Fatalf("Expected both notifications in order, got %+v", sender.got)
+	}
+	if !sender.got[0].Delayed || !sender.got[0].Time.Equal(old) {
+		t.Errorf("Expected the late notification delayed with its original time, got %+v", sender.got[0])
+	}
+	if sender.got[1].Delayed {
+		t.Error("Expected a recent notification not to be marked delayed")
+	}
+	if s := q.Status(); s.Pending != 0 || s.Sent != 2 || s.LastError != "" {
+		t.Errorf("Status() = %+v", s)
+	}
+}
+
+func TestQueueDropsOldest(t *testing.T) {
+	sender := &fakeSender{down: true}
+	q, err := Open(filepath.Join(t.TempDir(), "queue.json"), 2, sender)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"1", "2", "3"} {
+		q.Push(Notification{ID: id, Time: time.Now()})
+	}
+	sender.down = false
+	q.deliver(context.Background())
+	if len(sender.got) != 2 || sender.got[0].ID != "2" {
+		t.Errorf("Expected the oldest to be dropped, got %+v", sender.got)
+	}
+	if s := q.Status(); s.Dropped != 1 {
+		t.Errorf("Dropped = %d, want 1", s.Dropped)
+	}
+}
+
+func TestTelegram(t *testing.T) {
+	var body map[string]string
+	var path string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		json.NewDecoder(r.Body).Decode(&body)
+	}))
+	defer srv.Close()
+
+	tg := Telegram{API: srv.URL, Token: "123:abc", ChatID: "42"}
+	n := Notification{Text: "link two down", Host: "cam1", Time: time.Date(2026, 10, 15, 14, 3, 9, 0, time.Local), Delayed: true}
+	if err := tg.Send(context.Background(), n); err != nil {
+		t.Fatal(err)
+	}
+	if path != "/bot123:abc/sendMessage" || body["chat_id"] != "42" {
+		t.Errorf("Sent to %s with chat %q", path, body["chat_id"])
+	}
+	if body["text"] != "cam1: link two down (delayed, at 14:03:09 Oct 15)" {
+		t.Errorf("text = %q", body["text"])
+	}
+
+	srv.Close()
+	if err := tg.Send(context.Background(), n); err == nil || strings.Contains(err.Error(), "123:abc") {
+		t.Errorf("Expected an error without the token, got %v", err)
+	}
+}
+
+func TestMailMessage(t *testing.T) {
+	n := Notification{Event: "maintenance_report", Text: "Maintenance completed in 12s\n- backups: ok", Host: "unit-2", Time: time.Date(2026, 10, 12, 3, 0, 0, 0, time.UTC)}
+	got := string(mailMessage("unit@example.com", []string{"ops@example.com", "tech@example.com"}, n))
+	want := "From: unit@example.com\r\n" +
+		"To: ops@example.com, tech@example.com\r\n" +
+		"Subject: unit-2: Maintenance completed in 12s\r\n" +
+		"Date: Mon, 12 Oct 2026 03:00:00 +0000\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n" +
+		"\r\n" +
+		"unit-2: Maintenance completed in 12s\r\n" +
+		"- backups: ok\r\n"
+	if got != want {
+		t.Errorf("Unexpected mail:\n%q", got)
+	}
+}
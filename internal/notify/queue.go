@@ -0,0 +1,209 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// RetryInterval is how long delivery waits after a failure before trying
+// again, unless kicked sooner
+const RetryInterval = 15 * time.Second
+
+// entry is a notification waiting for one sender
+type entry struct {
+	Sender string `json:"sender"`
+	Notification
+}
+
+// Status describes the queue
+type Status struct {
+	Pending   int        `json:"pending"` // deliveries waiting
+	Sent      int        `json:"sent"`    // delivered since the queue was opened
+	Dropped   int        `json:"dropped"` // oldest ones dropped since, when the queue was full
+	LastError string     `json:"last_error,omitempty"`
+	LastSent  *time.Time `json:"last_sent,omitempty"`
+}
+
+// Queue holds notifications on disk until each sender has taken them, in
+// the order they were pushed
+type Queue struct {
+	path    string
+	max     int
+	senders map[string]Sender
+	kick    chan struct{}
+
+	mu       sync.Mutex
+	entries  []entry
+	sent     int
+	dropped  int
+	lastErr  string
+	lastSent time.Time
+}
+
+// Open loads the queue kept at path, for at most max waiting deliveries.
+// Deliveries left for a sender that is no longer given are dropped.
+func Open(path string, max int, senders ...Sender) (*Queue, error) {
+	q := &Queue{path: path, max: max, senders: make(map[string]Sender), kick: make(chan struct{}, 1)}
+	for _, s := range senders {
+		q.senders[s.Name()] = s
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if len(data) > 0 {
+		var entries []entry
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		for _, e := range entries {
+			if _, ok := q.senders[e.Sender]; ok {
+				q.entries = append(q.entries, e)
+			}
+		}
+		if len(q.entries) != len(entries) {
+			if err := q.saveLocked(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return q, nil
+}
+
+// Push queues n for every sender. When the queue is full the oldest
+// deliveries make way.
+func (q *Queue) Push(n Notification) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for name := range q.senders {
+		q.entries = append(q.entries, entry{Sender: name, Notification: n})
+	}
+	if over := len(q.entries) - q.max; over > 0 {
+		q.entries = q.entries[over:]
+		q.dropped += over
+	}
+	err := q.saveLocked()
+	q.Kick()
+	return err
+}
+
+// Kick makes Run try to deliver now rather than at its next retry
+func (q *Queue) Kick() {
+	select {
+	case q.kick <- struct{}{}:
+	default:
+	}
+}
+
+// Run delivers until ctx is done
+func (q *Queue) Run(ctx context.Context) {
+	retry := time.NewTimer(RetryInterval)
+	defer retry.Stop()
+	for {
+		retry.Stop()
+		if !q.deliver(ctx) {
+			retry.Reset(RetryInterval)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-q.kick:
+		case <-retry.C:
+		}
+	}
+}
+
+// deliver sends what is waiting, oldest first. A sender that fails is left
+// alone for the rest of the pass so its notifications stay in order. It
+// reports whether everything went out.
+func (q *Queue) deliver(ctx context.Context) bool {
+	failed := make(map[string]bool)
+	for {
+		q.mu.Lock()
+		var next *entry
+		for i := range q.entries {
+			if !failed[q.entries[i].Sender] {
+				e := q.entries[i]
+				next = &e
+				break
+			}
+		}
+		q.mu.Unlock()
+		if next == nil {
+			return len(failed) == 0
+		}
+		if ctx.Err() != nil {
+			return false
+		}
+
+		n := next.Notification
+		n.Delayed = time.Since(n.Time) > DelayedAfter
+		err := q.senders[next.Sender].Send(ctx, n)
+
+		q.mu.Lock()
+		if err != nil {
+			failed[next.Sender] = true
+			q.lastErr = fmt.Sprintf("%s: %v", next.Sender, err)
+		} else {
+			q.remove(next.Sender, next.ID)
+			q.sent++
+			q.lastErr = ""
+			q.lastSent = time.Now()
+			q.saveLocked()
+		}
+		q.mu.Unlock()
+	}
+}
+
+func (q *Queue) remove(sender, id string) {
+	for i, e := range q.entries {
+		if e.Sender == sender && e.ID == id {
+			q.entries = append(q.entries[:i], q.entries[i+1:]...)
+			return
+		}
+	}
+}
+
+// Status returns the queue's counts and its last error
+func (q *Queue) Status() Status {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	s := Status{Pending: len(q.entries), Sent: q.sent, Dropped: q.dropped, LastError: q.lastErr}
+	if !q.lastSent.IsZero() {
+		t := q.lastSent
+		s.LastSent = &t
+	}
+	return s
+}
+
+// saveLocked writes the queue to a temporary file and renames it over the
+// old one, so a power loss leaves one or the other
+func (q *Queue) saveLocked() error {
+	entries := q.entries
+	if entries == nil {
+		entries = []entry{}
+	}
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(q.path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", q.path, err)
+	}
+	tmp := q.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, q.path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", q.path, err)
+	}
+	return nil
+}
@@ -0,0 +1,317 @@
+// Package openapi builds an OpenAPI 3 document from routes and the Go
+// types they take and return. Schemas are derived from the types' JSON
+// encoding, so the document can't drift from what the API sends.
+package openapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Version is the OpenAPI version documents are written in
+const Version = "3.0.3"
+
+// Document is an OpenAPI document
+type Document struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       Info                             `json:"info"`
+	Servers    []Server                         `json:"servers,omitempty"`
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components Components                       `json:"components"`
+
+	names map[reflect.Type]string // of the schemas in Components
+}
+
+// Info describes the API
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// Server is where the API is served
+type Server struct {
+	URL string `json:"url"`
+}
+
+// Components holds the schemas operations refer to, and how requests
+// authenticate
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// SecurityScheme is a way a request authenticates
+type SecurityScheme struct {
+	Type   string `json:"type"`
+	Scheme string `json:"scheme,omitempty"`
+	In     string `json:"in,omitempty"`
+	Name   string `json:"name,omitempty"`
+}
+
+// Operation is a method on a path
+type Operation struct {
+	OperationID string              `json:"operationId,omitempty"`
+	Summary     string              `json:"summary,omitempty"`
+	Tags        []string            `json:"tags,omitempty"`
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+// Parameter is a path or query parameter
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema"`
+}
+
+// RequestBody is what an operation takes
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response is what an operation returns for a status
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType is the schema of a body
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema describes a JSON value. The empty schema allows any value.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+// Endpoint is a route to add to a document. Request and Response are
+// values of the types the body is decoded into and encoded from; nil for
+// no body.
+type Endpoint struct {
+	OperationID string
+	Summary     string
+	Tag         string
+	Query       []string // query parameters
+	Request     any
+	Response    any
+	Status      int // of a successful response; 200 when 0
+}
+
+// New returns an empty document
+func New(title, version string) *Document {
+	return &Document{
+		OpenAPI:    Version,
+		Info:       Info{Title: title, Version: version},
+		Paths:      make(map[string]map[string]*Operation),
+		Components: Components{Schemas: make(map[string]*Schema)},
+		names:      make(map[reflect.Type]string),
+	}
+}
+
+// pathParam matches a ServeMux wildcard: {name} or {name...}
+var pathParam = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)(\.\.\.)?\}`)
+
+// Add adds an operation on path, written as for a ServeMux. Its wildcards
+// become path parameters.
+func (d *Document) Add(method, path string, e Endpoint) {
+	op := &Operation{
+		OperationID: e.OperationID,
+		Summary:     e.Summary,
+		Responses:   make(map[string]Response),
+	}
+	if e.Tag != "" {
+		op.Tags = []string{e.Tag}
+	}
+	for _, m := range pathParam.FindAllStringSubmatch(path, -1) {
+		op.Parameters = append(op.Parameters, Parameter{Name: m[1], In: "path", Required: true, Schema: &Schema{Type: "string"}})
+	}
+	for _, q := range e.Query {
+		op.Parameters = append(op.Parameters, Parameter{Name: q, In: "query", Schema: &Schema{Type: "string"}})
+	}
+	if e.Request != nil {
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]MediaType{"application/json": {Schema: d.Schema(e.Request)}},
+		}
+	}
+
+	status := e.Status
+	if status == 0 {
+		status = 200
+	}
+	ok := Response{Description: "Success"}
+	if e.Response != nil {
+		ok.Content = map[string]MediaType{"application/json": {Schema: d.Schema(e.Response)}}
+	}
+	op.Responses[strconv.Itoa(status)] = ok
+	op.Responses["default"] = Response{Description: "Error"}
+
+	path = pathParam.ReplaceAllString(path, "{$1}")
+	if d.Paths[path] == nil {
+		d.Paths[path] = make(map[string]*Operation)
+	}
+	d.Paths[path][strings.ToLower(method)] = op
+}
+
+// SetErrorSchema makes every operation's default response v's schema
+func (d *Document) SetErrorSchema(v any) {
+	errResp := Response{Description: "Error", Content: map[string]MediaType{"application/json": {Schema: d.Schema(v)}}}
+	for _, ops := range d.Paths {
+		for _, op := range ops {
+			op.Responses["default"] = errResp
+		}
+	}
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	durationType      = reflect.TypeOf(time.Duration(0))
+	rawMessageType    = reflect.TypeOf(json.RawMessage{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// Schema returns the schema of v's type. Named struct types are added to
+// the components and referred to.
+func (d *Document) Schema(v any) *Schema {
+	return d.schema(reflect.TypeOf(v))
+}
+
+func (d *Document) schema(t reflect.Type) *Schema {
+	if t == nil {
+		return &Schema{}
+	}
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == durationType:
+		return &Schema{Type: "integer", Format: "int64"}
+	case t == rawMessageType:
+		return &Schema{}
+	case t.Kind() != reflect.Pointer && t.Implements(jsonMarshalerType),
+		t.Kind() != reflect.Pointer && reflect.PointerTo(t).Implements(jsonMarshalerType):
+		return &Schema{}
+	case t.Kind() != reflect.Pointer && t.Implements(textMarshalerType),
+		t.Kind() != reflect.Pointer && reflect.PointerTo(t).Implements(textMarshalerType):
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		s := d.schema(t.Elem())
+		if s.Ref != "" {
+			return s
+		}
+		s.Nullable = true
+		return s
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: d.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: d.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return d.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + d.name(t)}
+	}
+	// interfaces, and whatever else encodes as anything
+	return &Schema{}
+}
+
+// name returns the component name of a named struct type, adding its
+// schema first if it isn't there yet. A name taken by another package's
+// type is qualified with the package.
+func (d *Document) name(t reflect.Type) string {
+	if name, ok := d.names[t]; ok {
+		return name
+	}
+	name := t.Name()
+	if i := strings.IndexByte(name, '['); i >= 0 {
+		name = name[:i] // generic instance
+	}
+	if _, taken := d.Components.Schemas[name]; taken {
+		pkg := t.PkgPath()
+		pkg = pkg[strings.LastIndexByte(pkg, '/')+1:]
+		name = strings.ToUpper(pkg[:1]) + pkg[1:] + name
+	}
+	d.names[t] = name
+	// reserved before the fields, which may refer back to it
+	d.Components.Schemas[name] = &Schema{}
+	*d.Components.Schemas[name] = *d.structSchema(t)
+	return name
+}
+
+// structSchema returns the schema of a struct as encoding/json writes it:
+// its exported fields by their JSON names, with untagged embedded structs'
+// fields promoted
+func (d *Document) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		ft := f.Type
+		if f.Anonymous && name == "" {
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				embedded := d.structSchema(ft)
+				for k, v := range embedded.Properties {
+					if _, ok := s.Properties[k]; !ok {
+						s.Properties[k] = v
+					}
+				}
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		s.Properties[name] = d.schema(ft)
+	}
+	return s
+}
+
+// Operations returns the number of operations in the document
+func (d *Document) Operations() int {
+	n := 0
+	for _, ops := range d.Paths {
+		n += len(ops)
+	}
+	return n
+}
@@ -0,0 +1,123 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+)
+
+type base struct {
+	ID      string    `json:"id"`
+	Created time.Time `json:"created"`
+}
+
+type node struct {
+	base
+	Name     string            `json:"name"`
+	Port     int               `json:"port,omitempty"`
+	Ratio    float64           `json:"ratio"`
+	Enabled  *bool             `json:"enabled"`
+	Children []node            `json:"children"`
+	Labels   map[string]string `json:"labels"`
+	Extra    json.RawMessage   `json:"extra"`
+	Addr     net.IP            `json:"addr"`
+	Secret   string            `json:"-"`
+	hidden   string
+	Untagged bool
+}
+
+func TestSchema(t *testing.T) {
+	d := New("test", "1")
+	s := d.Schema(node{})
+	if s.Ref != "#/components/schemas/node" {
+		t.Fatalf("Expected a reference to the component, got %+v", s)
+	}
+
+	props := d.Components.Schemas["node"].Properties
+	want := map[string]string{
+		"id":       "string",
+		"created":  "string",
+		"name":     "string",
+		"port":     "integer",
+		"ratio":    "number",
+		"enabled":  "boolean",
+		"children": "array",
+		"labels":   "object",
+		"extra":    "",
+		"addr":     "string",
+		"Untagged": "boolean",
+	}
+	if len(props) != len(want) {
+		t.Errorf("Expected %d properties, got %d: %v", len(want), len(props), props)
+	}
+	for name, typ := range want {
+		p, ok := props[name]
+		if !ok {
+			t.Errorf("Missing property %q", name)
+			continue
+		}
+		if p.Type != typ {
+			t.Errorf("Property %q: expected type %q, got %q", name, typ, p.Type)
+		}
+	}
+	if props["created"].Format != "date-time" {
+		t.Errorf("Expected time.Time to be a date-time, got %+v", props["created"])
+	}
+	if !props["enabled"].Nullable {
+		t.Error("Expected a pointer to be nullable")
+	}
+	if props["children"].Items.Ref != s.Ref {
+		t.Errorf("Expected a recursive type to refer to itself, got %+v", props["children"].Items)
+	}
+	if props["labels"].AdditionalProperties.Type != "string" {
+		t.Errorf("Expected a map's values to be described, got %+v", props["labels"])
+	}
+}
+
+func TestAdd(t *testing.T) {
+	type request struct {
+		Enabled bool `json:"enabled"`
+	}
+	d := New("test", "1")
+	d.Add("PUT", "/api/outputs/{name}/enabled", Endpoint{OperationID: "setOutputEnabled", Request: request{}, Response: []string{}})
+	d.Add("GET", "/api/files/{path...}", Endpoint{Query: []string{"download"}, Status: 202})
+	d.SetErrorSchema(struct {
+		Error string `json:"error"`
+	}{})
+
+	op := d.Paths["/api/outputs/{name}/enabled"]["put"]
+	if op == nil {
+		t.Fatalf("Expected the operation, got paths %v", d.Paths)
+	}
+	if len(op.Parameters) != 1 || op.Parameters[0].Name != "name" || op.Parameters[0].In != "path" || !op.Parameters[0].Required {
+		t.Errorf("Expected the wildcard as a path parameter, got %+v", op.Parameters)
+	}
+	if op.RequestBody == nil || op.RequestBody.Content["application/json"].Schema.Ref != "#/components/schemas/request" {
+		t.Errorf("Expected the request body's schema, got %+v", op.RequestBody)
+	}
+	if op.Responses["200"].Content["application/json"].Schema.Type != "array" {
+		t.Errorf("Expected the response's schema, got %+v", op.Responses["200"])
+	}
+	if op.Responses["default"].Content["application/json"].Schema.Properties["error"] == nil {
+		t.Errorf("Expected the error schema, got %+v", op.Responses["default"])
+	}
+
+	op = d.Paths["/api/files/{path}"]["get"]
+	if op == nil {
+		t.Fatalf("Expected a rest wildcard to become a parameter, got paths %v", d.Paths)
+	}
+	if len(op.Parameters) != 2 || op.Parameters[1].In != "query" {
+		t.Errorf("Expected a path and a query parameter, got %+v", op.Parameters)
+	}
+	if _, ok := op.Responses["202"]; !ok {
+		t.Errorf("Expected the success status, got %v", op.Responses)
+	}
+	if d.Operations() != 2 {
+		t.Errorf("Expected 2 operations, got %d", d.Operations())
+	}
+
+	if _, err := json.Marshal(d); err != nil {
+		t.Fatal(err)
+	}
+}
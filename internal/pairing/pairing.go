@@ -0,0 +1,226 @@
+// Package pairing lets a passive unit watch an active one over the LAN so it
+// can take over publishing when the active unit disappears.
+package pairing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"srtla-manager/internal/config"
+)
+
+const (
+	RoleActive  = "active"
+	RolePassive = "passive"
+
+	// TokenHeader carries the shared pairing token on peer requests
+	TokenHeader = "X-Pairing-Token"
+
+	// StatePath is polled on the peer by the monitor
+	StatePath = "/api/pairing/state"
+)
+
+// SharedConfig is the part of the configuration that is mirrored between
+// units. Bind IPs and binary paths are host specific and stay local.
+type SharedConfig struct {
+	RTMP        config.RTMPConfig `json:"rtmp"`
+	SRT         config.SRTConfig  `json:"srt"`
+	SRTLA       bool              `json:"srtla_enabled"`
+	RemoteHost  string            `json:"remote_host"`
+	RemotePort  int               `json:"remote_port"`
+	Classic     bool              `json:"classic"`
+	NoQuality   bool              `json:"no_quality"`
+	Exploration bool              `json:"exploration"`
+	Transport   string            `json:"transport"`
+}
+
+// NewSharedConfig extracts the shared sections from cfg
+func NewSharedConfig(cfg config.Config) SharedConfig {
+	return SharedConfig{
+		RTMP:        cfg.RTMP,
+		SRT:         cfg.SRT,
+		SRTLA:       cfg.SRTLA.Enabled,
+		RemoteHost:  cfg.SRTLA.RemoteHost,
+		RemotePort:  cfg.SRTLA.RemotePort,
+		Classic:     cfg.SRTLA.Classic,
+		NoQuality:   cfg.SRTLA.NoQuality,
+		Exploration: cfg.SRTLA.Exploration,
+		Transport:   cfg.SRTLA.Transport,
+	}
+}
+
+// Apply copies the shared sections into cfg, reporting whether anything changed
+func (s SharedConfig) Apply(cfg *config.Config) bool {
+	if reflect.DeepEqual(NewSharedConfig(*cfg), s) {
+		return false
+	}
+	cfg.RTMP = s.RTMP
+	cfg.SRT = s.SRT
+	cfg.SRTLA.Enabled = s.SRTLA
+	cfg.SRTLA.RemoteHost = s.RemoteHost
+	cfg.SRTLA.RemotePort = s.RemotePort
+	cfg.SRTLA.Classic = s.Classic
+	cfg.SRTLA.NoQuality = s.NoQuality
+	cfg.SRTLA.Exploration = s.Exploration
+	cfg.SRTLA.Transport = s.Transport
+	return true
+}
+
+// PeerState is what a unit reports about itself to its peer
+type PeerState struct {
+	Role         string       `json:"role"`
+	PipelineMode string       `json:"pipeline_mode"`
+	Version      string       `json:"version"`
+	Config       SharedConfig `json:"config"`
+}
+
+// Status describes the monitor's view of the peer
+type Status struct {
+	PeerURL   string     `json:"peer_url"`
+	Reachable bool       `json:"reachable"`
+	LastSeen  *time.Time `json:"last_seen,omitempty"`
+	LastError string     `json:"last_error,omitempty"`
+	Peer      *PeerState `json:"peer,omitempty"`
+}
+
+// Monitor polls the peer's state and reports when it stops answering
+type Monitor struct {
+	peerURL  string
+	token    string
+	interval time.Duration
+	timeout  time.Duration
+	client   *http.Client
+
+	mu        sync.RWMutex
+	last      *PeerState
+	lastSeen  time.Time
+	lastError string
+	lost      bool
+
+	onState func(PeerState)
+	onLost  func(last PeerState)
+}
+
+// NewMonitor creates a monitor for peerURL. The peer is considered lost once
+// it has not answered for timeout.
+func NewMonitor(peerURL, token string, timeout time.Duration) *Monitor {
+	interval := timeout / 5
+	if interval < time.Second {
+		interval = time.Second
+	}
+	return &Monitor{
+		peerURL:  strings.TrimRight(peerURL, "/"),
+		token:    token,
+		interval: interval,
+		timeout:  timeout,
+		client:   &http.Client{Timeout: interval},
+	}
+}
+
+// OnState registers a callback for every successful poll
+func (m *Monitor) OnState(fn func(PeerState)) {
+	m.onState = fn
+}
+
+// OnLost registers a callback fired once when the peer stops answering,
+// receiving the last state seen before it went away
+func (m *Monitor) OnLost(fn func(last PeerState)) {
+	m.onLost = fn
+}
+
+// Run polls the peer until ctx is cancelled
+func (m *Monitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		m.poll(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *Monitor) poll(ctx context.Context) {
+	state, err := m.fetch(ctx)
+
+	m.mu.Lock()
+	if err == nil {
+		m.last = &state
+		m.lastSeen = time.Now()
+		m.lastError = ""
+		m.lost = false
+		m.mu.Unlock()
+
+		if m.onState != nil {
+			m.onState(state)
+		}
+		return
+	}
+
+	m.lastError = err.Error()
+	var fire bool
+	var last PeerState
+	if m.last != nil && !m.lost && time.Since(m.lastSeen) > m.timeout {
+		m.lost = true
+		fire = true
+		last = *m.last
+	}
+	m.mu.Unlock()
+
+	if fire && m.onLost != nil {
+		m.onLost(last)
+	}
+}
+
+func (m *Monitor) fetch(ctx context.Context) (PeerState, error) {
+	var state PeerState
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, m.peerURL+StatePath, nil)
+	if err != nil {
+		return state, err
+	}
+	req.Header.Set(TokenHeader, m.token)
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return state, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return state, fmt.Errorf("peer returned %s", resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&state); err != nil {
+		return state, fmt.Errorf("failed to decode peer state: %w", err)
+	}
+	return state, nil
+}
+
+// Status returns the current view of the peer
+func (m *Monitor) Status() Status {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	status := Status{
+		PeerURL:   m.peerURL,
+		LastError: m.lastError,
+	}
+	if m.last != nil {
+		seen := m.lastSeen
+		peer := *m.last
+		status.LastSeen = &seen
+		status.Peer = &peer
+		status.Reachable = time.Since(m.lastSeen) <= m.timeout && m.lastError == ""
+	}
+	return status
+}
@@ -0,0 +1,184 @@
+// Package power watches the unit's power source so the manager can save
+// energy while running on battery.
+package power
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SysfsRoot is where the kernel exposes batteries, chargers and UPS HATs
+const SysfsRoot = "/sys/class/power_supply"
+
+// Source values reported in Status
+const (
+	SourceMains   = "mains"
+	SourceBattery = "battery"
+	SourceUnknown = "unknown"
+)
+
+// Status describes the current power source
+type Status struct {
+	Source    string    `json:"source"`
+	OnBattery bool      `json:"on_battery"`
+	Capacity  int       `json:"capacity"`           // percent, -1 when unknown
+	Provider  string    `json:"provider,omitempty"` // "sysfs" or "ups"
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Monitor polls the power source and reports when it switches between mains
+// and battery
+type Monitor struct {
+	upsName  string
+	interval time.Duration
+
+	mu     sync.RWMutex
+	status Status
+
+	onChange func(Status)
+}
+
+// NewMonitor creates a monitor. When upsName is set the NUT UPS of that name
+// (queried with upsc) is used instead of sysfs.
+func NewMonitor(upsName string, interval time.Duration) *Monitor {
+	return &Monitor{
+		upsName:  upsName,
+		interval: interval,
+		status:   Status{Source: SourceUnknown, Capacity: -1},
+	}
+}
+
+// OnChange registers a callback fired when OnBattery flips
+func (m *Monitor) OnChange(fn func(Status)) {
+	m.onChange = fn
+}
+
+// Status returns the last polled status
+func (m *Monitor) Status() Status {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.status
+}
+
+// Run polls the power source until ctx is cancelled
+func (m *Monitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		m.poll()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *Monitor) poll() {
+	var status Status
+	if m.upsName != "" {
+		status = readUPS(m.upsName)
+	} else {
+		status = readSysfs(SysfsRoot)
+	}
+	status.UpdatedAt = time.Now()
+
+	m.mu.Lock()
+	changed := status.OnBattery != m.status.OnBattery
+	m.status = status
+	m.mu.Unlock()
+
+	if changed && m.onChange != nil {
+		m.onChange(status)
+	}
+}
+
+// readSysfs reports battery power when no mains/USB supply is online and at
+// least one battery is discharging
+func readSysfs(root string) Status {
+	status := Status{Source: SourceUnknown, Capacity: -1, Provider: "sysfs"}
+
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return status
+	}
+
+	var mainsOnline, discharging, sawSupply bool
+	for _, e := range entries {
+		dir := filepath.Join(root, e.Name())
+		switch readAttr(dir, "type") {
+		case "Mains", "USB", "USB_C", "USB_PD":
+			sawSupply = true
+			if readAttr(dir, "online") == "1" {
+				mainsOnline = true
+			}
+		case "Battery", "UPS":
+			sawSupply = true
+			if capacity, err := strconv.Atoi(readAttr(dir, "capacity")); err == nil {
+				status.Capacity = capacity
+			}
+			if readAttr(dir, "status") == "Discharging" {
+				discharging = true
+			}
+		}
+	}
+
+	if !sawSupply {
+		return status
+	}
+	status.OnBattery = discharging && !mainsOnline
+	status.Source = SourceMains
+	if status.OnBattery {
+		status.Source = SourceBattery
+	}
+	return status
+}
+
+// readUPS queries a NUT UPS; "OB" in ups.status means on battery
+func readUPS(name string) Status {
+	status := Status{Source: SourceUnknown, Capacity: -1, Provider: "ups"}
+
+	output, err := exec.Command("upsc", name).Output()
+	if err != nil {
+		return status
+	}
+
+	for _, line := range strings.Split(string(output), "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "ups.status":
+			status.Source = SourceMains
+			for _, flag := range strings.Fields(value) {
+				if flag == "OB" {
+					status.OnBattery = true
+					status.Source = SourceBattery
+				}
+			}
+		case "battery.charge":
+			if capacity, err := strconv.Atoi(value); err == nil {
+				status.Capacity = capacity
+			}
+		}
+	}
+	return status
+}
+
+func readAttr(dir, name string) string {
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
@@ -0,0 +1,69 @@
+package power
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeSupply(t *testing.T, root, name string, attrs map[string]string) {
+	t.Helper()
+	dir := filepath.Join(root, name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range attrs {
+		if err := os.WriteFile(filepath.Join(dir, k), []byte(v+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestReadSysfs(t *testing.T) {
+	tests := []struct {
+		name      string
+		mains     string
+		battery   string
+		onBattery bool
+	}{
+		{"charging on mains", "1", "Charging", false},
+		{"mains unplugged", "0", "Discharging", true},
+		{"full on mains", "1", "Full", false},
+	}
+
+	for _, tt := range tests {
+		root := t.TempDir()
+		writeSupply(t, root, "AC", map[string]string{"type": "Mains", "online": tt.mains})
+		writeSupply(t, root, "BAT0", map[string]string{"type": "Battery", "status": tt.battery, "capacity": "64"})
+
+		status := readSysfs(root)
+		if status.OnBattery != tt.onBattery {
+			t.Errorf("%s: expected on_battery=%v, got %v", tt.name, tt.onBattery, status.OnBattery)
+		}
+		if status.Capacity != 64 {
+			t.Errorf("%s: expected capacity 64, got %d", tt.name, status.Capacity)
+		}
+	}
+}
+
+// Units without any power_supply entries are assumed to be on mains
+func TestReadSysfsNoSupplies(t *testing.T) {
+	status := readSysfs(t.TempDir())
+	if status.OnBattery || status.Source != SourceUnknown || status.Capacity != -1 {
+		t.Errorf("Expected unknown mains-powered status, got %+v", status)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	battery := Status{OnBattery: true}
+
+	if got := Select(ModeAuto, battery); got.Name != ModeLowPower {
+		t.Errorf("auto on battery: expected low_power, got %s", got.Name)
+	}
+	if got := Select("", Status{}); got.Name != ModeNormal {
+		t.Errorf("unset on mains: expected normal, got %s", got.Name)
+	}
+	if got := Select(ModeNormal, battery); got.Name != ModeNormal {
+		t.Errorf("manual override: expected normal, got %s", got.Name)
+	}
+}
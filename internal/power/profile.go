@@ -0,0 +1,72 @@
+package power
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Profile modes accepted in power.profile
+const (
+	ModeAuto     = "auto" // follow the power source
+	ModeNormal   = "normal"
+	ModeLowPower = "low_power"
+)
+
+// Modes lists the values accepted for power.profile
+var Modes = []string{ModeAuto, ModeNormal, ModeLowPower}
+
+// Profile holds the tunables that trade responsiveness for battery life
+type Profile struct {
+	Name           string
+	StatsInterval  time.Duration // stats broadcast
+	ModemInterval  time.Duration // modem/USB network refresh
+	WiFiInterval   time.Duration // WiFi refresh
+	PreviewFPS     int           // USB camera preview cap, 0 = camera rate
+	StreamPreviews bool          // HLS preview alongside the outgoing stream
+}
+
+// MarshalJSON reports intervals in seconds
+func (p Profile) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Name           string  `json:"name"`
+		StatsInterval  float64 `json:"stats_interval"`
+		ModemInterval  float64 `json:"modem_interval"`
+		WiFiInterval   float64 `json:"wifi_interval"`
+		PreviewFPS     int     `json:"preview_fps"`
+		StreamPreviews bool    `json:"stream_previews"`
+	}{p.Name, p.StatsInterval.Seconds(), p.ModemInterval.Seconds(), p.WiFiInterval.Seconds(), p.PreviewFPS, p.StreamPreviews})
+}
+
+// Normal is the default profile
+var Normal = Profile{
+	Name:           ModeNormal,
+	StatsInterval:  time.Second,
+	ModemInterval:  5 * time.Second,
+	WiFiInterval:   10 * time.Second,
+	StreamPreviews: true,
+}
+
+// LowPower stretches polling and drops previews; the preview transcode is
+// the biggest battery drain on backpack units
+var LowPower = Profile{
+	Name:           ModeLowPower,
+	StatsInterval:  3 * time.Second,
+	ModemInterval:  15 * time.Second,
+	WiFiInterval:   60 * time.Second,
+	PreviewFPS:     5,
+	StreamPreviews: false,
+}
+
+// Select returns the profile for mode given the current power status
+func Select(mode string, status Status) Profile {
+	switch mode {
+	case ModeNormal:
+		return Normal
+	case ModeLowPower:
+		return LowPower
+	}
+	if status.OnBattery {
+		return LowPower
+	}
+	return Normal
+}
@@ -0,0 +1,143 @@
+// Package previewtoken signs expiring tokens granting access to the HLS
+// preview files under one path, so a player can be embedded without opening
+// the preview to everyone who can reach the port. A token is the expiry and
+// an HMAC of the path and expiry; the key lives in memory, so tokens stop
+// working when the manager restarts.
+package previewtoken
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Param is the query parameter carrying a token
+const Param = "token"
+
+var (
+	// ErrInvalid is returned for a token that is malformed or wasn't
+	// signed for the path
+	ErrInvalid = errors.New("invalid preview token")
+	// ErrExpired is returned for a token past its expiry
+	ErrExpired = errors.New("preview token expired")
+)
+
+// Signer signs and checks tokens
+type Signer struct {
+	mu  sync.RWMutex
+	key []byte
+}
+
+// NewSigner returns a signer with a random key
+func NewSigner() *Signer {
+	s := &Signer{}
+	s.Rotate()
+	return s
+}
+
+// Rotate replaces the key, so every token signed so far stops working
+func (s *Signer) Rotate() {
+	key := make([]byte, 32)
+	_, _ = rand.Read(key)
+	s.mu.Lock()
+	s.key = key
+	s.mu.Unlock()
+}
+
+// Sign returns a token for the files under prefix, such as "/preview/",
+// valid until expires
+func (s *Signer) Sign(prefix string, expires time.Time) string {
+	exp := strconv.FormatInt(expires.Unix(), 10)
+	return exp + "." + base64.RawURLEncoding.EncodeToString(s.mac(prefix, exp))
+}
+
+// Verify checks that token was signed for prefix and is valid at now
+func (s *Signer) Verify(token, prefix string, now time.Time) error {
+	exp, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return ErrInvalid
+	}
+	unix, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil {
+		return ErrInvalid
+	}
+	got, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(got, s.mac(prefix, exp)) {
+		return ErrInvalid
+	}
+	if !now.Before(time.Unix(unix, 0)) {
+		return ErrExpired
+	}
+	return nil
+}
+
+func (s *Signer) mac(prefix, exp string) []byte {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	mac := hmac.New(sha256.New, s.key)
+	fmt.Fprintf(mac, "%s|%s", prefix, exp)
+	return mac.Sum(nil)
+}
+
+// AddToURL returns u with token as its token parameter
+func AddToURL(u, token string) string {
+	sep := "?"
+	if strings.Contains(u, "?") {
+		sep = "&"
+	}
+	return u + sep + Param + "=" + url.QueryEscape(token)
+}
+
+// AddToPlaylist returns an HLS playlist with token added to the URI of
+// every segment, variant and key it lists, so the player passes it on
+func AddToPlaylist(playlist []byte, token string) []byte {
+	var out bytes.Buffer
+	sc := bufio.NewScanner(bytes.NewReader(playlist))
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for sc.Scan() {
+		line := sc.Text()
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+		case strings.HasPrefix(trimmed, "#"):
+			line = addToAttributes(line, token)
+		default:
+			line = AddToURL(trimmed, token)
+		}
+		out.WriteString(line)
+		out.WriteByte('\n')
+	}
+	return out.Bytes()
+}
+
+// addToAttributes adds token to the URI="..." attributes of a tag
+func addToAttributes(line, token string) string {
+	const attr = `URI="`
+	var b strings.Builder
+	for {
+		i := strings.Index(line, attr)
+		if i < 0 {
+			break
+		}
+		end := strings.IndexByte(line[i+len(attr):], '"')
+		if end < 0 {
+			break
+		}
+		uri := line[i+len(attr) : i+len(attr)+end]
+		b.WriteString(line[:i+len(attr)])
+		b.WriteString(AddToURL(uri, token))
+		line = line[i+len(attr)+end:]
+	}
+	b.WriteString(line)
+	return b.String()
+}
@@ -0,0 +1,61 @@
+package previewtoken
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	s := NewSigner()
+	now := time.Unix(1700000000, 0)
+	token := s.Sign("/preview/", now.Add(time.Hour))
+
+	if err := s.Verify(token, "/preview/", now); err != nil {
+		t.Errorf("valid token: %v", err)
+	}
+	if err := s.Verify(token, "/return/", now); !errors.Is(err, ErrInvalid) {
+		t.Errorf("other path: got %v", err)
+	}
+	if err := s.Verify(token, "/preview/", now.Add(time.Hour)); !errors.Is(err, ErrExpired) {
+		t.Errorf("at expiry: got %v", err)
+	}
+	for _, bad := range []string{"", "abc", "1700003600", "1700003600.", "9999999999." + token[len("1700003600."):], token + "x"} {
+		if err := s.Verify(bad, "/preview/", now); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%q: got %v", bad, err)
+		}
+	}
+
+	if err := NewSigner().Verify(token, "/preview/", now); !errors.Is(err, ErrInvalid) {
+		t.Errorf("other signer: got %v", err)
+	}
+	s.Rotate()
+	if err := s.Verify(token, "/preview/", now); !errors.Is(err, ErrInvalid) {
+		t.Errorf("after rotate: got %v", err)
+	}
+}
+
+func TestAddToPlaylist(t *testing.T) {
+	in := "#EXTM3U\n" +
+		"#EXT-X-VERSION:6\n" +
+		"#EXT-X-MAP:URI=\"init.mp4\"\n" +
+		"#EXT-X-KEY:METHOD=AES-128,URI=\"key.bin?v=2\",IV=0x01\n" +
+		"\n" +
+		"#EXTINF:2.000000,\n" +
+		"segment_001.ts\n" +
+		"#EXTINF:2.000000,\n" +
+		"  segment_002.ts\r\n"
+	want := "#EXTM3U\n" +
+		"#EXT-X-VERSION:6\n" +
+		"#EXT-X-MAP:URI=\"init.mp4?token=1.a-b\"\n" +
+		"#EXT-X-KEY:METHOD=AES-128,URI=\"key.bin?v=2&token=1.a-b\",IV=0x01\n" +
+		"\n" +
+		"#EXTINF:2.000000,\n" +
+		"segment_001.ts?token=1.a-b\n" +
+		"#EXTINF:2.000000,\n" +
+		"segment_002.ts?token=1.a-b\n"
+
+	if got := string(AddToPlaylist([]byte(in), "1.a-b")); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,149 @@
+package process
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// AncillaryStats reports captions and splice markers found in the incoming
+// stream and whether the running pipeline forwards them to the SRT output
+type AncillaryStats struct {
+	InputFormat    string   `json:"input_format,omitempty"`
+	Captions       bool     `json:"captions"` // CEA-608/708 carried in video SEI
+	SCTE35         bool     `json:"scte35"`
+	DataStreams    []string `json:"data_streams,omitempty"`
+	StripsCaptions bool     `json:"strips_captions"`
+	StripsSCTE35   bool     `json:"strips_scte35"`
+	Warnings       []string `json:"warnings,omitempty"`
+}
+
+type ancillaryStream struct {
+	kind     string // Video, Audio, Data, Subtitle
+	codec    string
+	captions bool
+}
+
+// ancillaryParser follows ffmpeg's input dump and stream mapping to work out
+// which ancillary data reaches the outputs
+type ancillaryParser struct {
+	section string // "input", "output", "mapping" or "" once the header is done
+	inputs  map[string]*ancillaryStream
+	mapped  map[string]string // input stream index -> codec action of output #0
+	stats   AncillaryStats
+}
+
+var (
+	ancInputRegex   = regexp.MustCompile(`^\s*Input #0, ([^,]+),`)
+	ancStreamRegex  = regexp.MustCompile(`^\s*Stream #0:(\d+)[^:]*: (Video|Audio|Data|Subtitle): (\w+)`)
+	ancMappingRegex = regexp.MustCompile(`^\s*Stream #0:(\d+) -> #0:\d+ \((.+)\)`)
+)
+
+func newAncillaryParser() *ancillaryParser {
+	return &ancillaryParser{
+		inputs: make(map[string]*ancillaryStream),
+		mapped: make(map[string]string),
+	}
+}
+
+// parse consumes one log line and reports whether the mapping was evaluated.
+// ffmpeg prints the output header before or after the mapping depending on
+// whether encoders are involved, so the mapping ends at the first line that
+// is not part of it.
+func (p *ancillaryParser) parse(line string) bool {
+	if m := ancInputRegex.FindStringSubmatch(line); len(m) > 1 {
+		*p = *newAncillaryParser()
+		p.section = "input"
+		p.stats.InputFormat = m[1]
+		return false
+	}
+
+	switch p.section {
+	case "mapping":
+		if m := ancMappingRegex.FindStringSubmatch(line); len(m) > 2 {
+			p.mapped[m[1]] = m[2]
+			return false
+		}
+		p.section = ""
+		p.evaluate()
+		return true
+	case "input", "output":
+		if strings.Contains(line, "Stream mapping:") {
+			p.section = "mapping"
+			return false
+		}
+		if strings.HasPrefix(strings.TrimSpace(line), "Output #") {
+			p.section = "output"
+			return false
+		}
+	}
+
+	if p.section != "input" {
+		return false
+	}
+
+	m := ancStreamRegex.FindStringSubmatch(line)
+	if len(m) < 4 {
+		return false
+	}
+	s := &ancillaryStream{kind: m[2], codec: m[3]}
+	if s.kind == "Video" && strings.Contains(line, "Closed Captions") {
+		s.captions = true
+		p.stats.Captions = true
+	}
+	if s.kind == "Data" {
+		p.stats.DataStreams = append(p.stats.DataStreams, s.codec)
+		if s.codec == "scte_35" {
+			p.stats.SCTE35 = true
+		}
+	}
+	p.inputs[m[1]] = s
+	return false
+}
+
+// evaluate flags ancillary data that will not survive the output mapping
+func (p *ancillaryParser) evaluate() {
+	p.stats.StripsCaptions = false
+	p.stats.StripsSCTE35 = false
+	p.stats.Warnings = nil
+
+	indices := make([]string, 0, len(p.inputs))
+	for idx := range p.inputs {
+		indices = append(indices, idx)
+	}
+	sort.Strings(indices)
+
+	for _, idx := range indices {
+		s := p.inputs[idx]
+		action, ok := p.mapped[idx]
+		switch {
+		case s.captions && !ok:
+			p.stats.StripsCaptions = true
+			p.stats.Warnings = append(p.stats.Warnings, fmt.Sprintf("video stream #0:%s carries captions but is not forwarded", idx))
+		case s.captions && action != "copy":
+			p.stats.StripsCaptions = true
+			p.stats.Warnings = append(p.stats.Warnings, fmt.Sprintf("video stream #0:%s is re-encoded; embedded captions may be lost", idx))
+		case s.kind == "Data" && s.codec == "scte_35" && !ok:
+			p.stats.StripsSCTE35 = true
+			p.stats.Warnings = append(p.stats.Warnings, fmt.Sprintf("SCTE-35 stream #0:%s is not forwarded", idx))
+		case s.kind == "Data" && !ok:
+			p.stats.Warnings = append(p.stats.Warnings, fmt.Sprintf("data stream #0:%s (%s) is not forwarded", idx, s.codec))
+		}
+	}
+
+	// RTMP has no SCTE-35 stream type; cue points arrive as AMF data that the
+	// MPEG-TS muxer cannot carry
+	if p.stats.InputFormat == "flv" && !p.stats.SCTE35 && len(p.stats.DataStreams) > 0 {
+		p.stats.StripsSCTE35 = true
+		p.stats.Warnings = append(p.stats.Warnings, "RTMP data/cue point streams are not converted to SCTE-35 in the MPEG-TS output")
+	}
+}
+
+// snapshot returns a copy safe to hand out to callers
+func (p *ancillaryParser) snapshot() AncillaryStats {
+	s := p.stats
+	s.DataStreams = append([]string(nil), p.stats.DataStreams...)
+	s.Warnings = append([]string(nil), p.stats.Warnings...)
+	return s
+}
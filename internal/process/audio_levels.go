@@ -0,0 +1,70 @@
+package process
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ChannelLevel is the level of one audio channel in dBFS, LoudnessFloor
+// for digital silence
+type ChannelLevel struct {
+	Peak float64 `json:"peak"`
+	RMS  float64 `json:"rms"`
+}
+
+// AudioLevels are the per-channel levels of the pass-through audio, for a
+// VU meter
+type AudioLevels struct {
+	Valid      bool           `json:"valid"`
+	Channels   []ChannelLevel `json:"channels"`
+	LastUpdate time.Time      `json:"last_update"`
+}
+
+// audioMeterFilter measures each channel over 9600 samples, 0.2s at 48kHz,
+// and has ametadata log the result
+const audioMeterFilter = "asetnsamples=n=9600:p=0," +
+	"astats=metadata=1:reset=1:measure_perchannel=Peak_level+RMS_level:measure_overall=none," +
+	"ametadata=mode=print"
+
+var audioLevelRegex = regexp.MustCompile(`lavfi\.astats\.(\d+)\.(Peak_level|RMS_level)=(\S+)`)
+
+// isAudioLevelLine reports whether line was logged by the meter's ametadata
+// filter
+func isAudioLevelLine(line string) bool {
+	return strings.Contains(line, "Parsed_ametadata")
+}
+
+// audioLevelParser collects the levels ametadata logs one line at a time.
+// Each measurement starts with a frame: line, which completes the previous.
+type audioLevelParser struct {
+	pending []ChannelLevel
+}
+
+// parse consumes one ametadata line and returns the levels once a
+// measurement is complete
+func (p *audioLevelParser) parse(line string, now time.Time) (AudioLevels, bool) {
+	if m := audioLevelRegex.FindStringSubmatch(line); len(m) > 3 {
+		ch, err := strconv.Atoi(m[1])
+		if err != nil || ch < 1 || ch > 64 {
+			return AudioLevels{}, false
+		}
+		for len(p.pending) < ch {
+			p.pending = append(p.pending, ChannelLevel{Peak: LoudnessFloor, RMS: LoudnessFloor})
+		}
+		if m[2] == "Peak_level" {
+			p.pending[ch-1].Peak = parseLoudnessValue(m[3])
+		} else {
+			p.pending[ch-1].RMS = parseLoudnessValue(m[3])
+		}
+		return AudioLevels{}, false
+	}
+
+	if !strings.Contains(line, "frame:") || len(p.pending) == 0 {
+		return AudioLevels{}, false
+	}
+	levels := AudioLevels{Valid: true, Channels: p.pending, LastUpdate: now}
+	p.pending = nil
+	return levels, true
+}
@@ -0,0 +1,84 @@
+package process
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Where the stream's audio comes from
+const (
+	AudioCamera  = "camera"  // the camera's own audio
+	AudioReplace = "replace" // the input device instead of the camera
+	AudioMix     = "mix"     // the camera and the input device mixed
+)
+
+// AudioMixConfig processes the audio of the RTMP pipeline instead of
+// copying the camera's
+type AudioMixConfig struct {
+	Source  string  // camera (default), replace or mix
+	Backend string  // alsa (default) or pulse
+	Device  string  // input device, e.g. plughw:CARD=Device
+	GainDB  float64 // applied to the result
+	DelayMs int     // delays the result, to bring it in line with the video
+}
+
+// usesDevice reports whether the input device is opened
+func (c AudioMixConfig) usesDevice() bool {
+	return c.Source == AudioReplace || c.Source == AudioMix
+}
+
+// active reports whether the audio has to be decoded rather than copied
+func (c AudioMixConfig) active() bool {
+	return c.usesDevice() || c.GainDB != 0 || c.DelayMs > 0
+}
+
+// audioGraph returns the extra input arguments and the -filter_complex
+// graph producing the stream's audio as [aout], or nothing when the camera
+// audio is copied as it is. With monitor filters, a copy of the audio runs
+// through them to [amon] for the null output.
+func audioGraph(mix AudioMixConfig, resync bool, monitor []string) ([]string, string) {
+	if !mix.active() {
+		return nil, ""
+	}
+
+	var input []string
+	if mix.usesDevice() {
+		backend := mix.Backend
+		if backend == "" {
+			backend = "alsa"
+		}
+		// the device is opened after the camera's input, once a publisher
+		// connected, and its buffer covers ffmpeg probing the camera
+		input = []string{"-f", backend, "-thread_queue_size", "1024", "-i", mix.Device}
+	}
+
+	var graph string
+	switch mix.Source {
+	case AudioReplace:
+		graph = "[1:a]"
+	case AudioMix:
+		graph = "[0:a:0][1:a]amix=inputs=2:duration=first:dropout_transition=0,"
+	default:
+		graph = "[0:a:0]"
+	}
+
+	chain := []string{}
+	if resync {
+		chain = append(chain, "aresample=async=1")
+	}
+	if mix.DelayMs > 0 {
+		chain = append(chain, fmt.Sprintf("adelay=delays=%d:all=1", mix.DelayMs))
+	}
+	if mix.GainDB != 0 {
+		chain = append(chain, fmt.Sprintf("volume=%.1fdB", mix.GainDB))
+	}
+	if len(chain) == 0 {
+		chain = append(chain, "anull")
+	}
+	graph += strings.Join(chain, ",")
+
+	if len(monitor) == 0 {
+		return input, graph + "[aout]"
+	}
+	return input, graph + ",asplit=2[aout][amonin];[amonin]" + strings.Join(monitor, ",") + "[amon]"
+}
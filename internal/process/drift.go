@@ -0,0 +1,81 @@
+package process
+
+import (
+	"strconv"
+	"time"
+)
+
+// DriftStats compares the stream's clock with the system clock. A camera
+// or capture card whose clock runs fast or slow delivers more or less than
+// a second of media per second, which over a long broadcast shows up as
+// audio drifting away from the video.
+type DriftStats struct {
+	Valid      bool      `json:"valid"`
+	DriftMs    float64   `json:"drift_ms"` // media time ahead (+) or behind (-) wall time since the input started
+	PPM        float64   `json:"ppm"`      // the same as a rate, parts per million
+	LastUpdate time.Time `json:"last_update"`
+}
+
+// driftSettle is how long after the input starts the drift is measured
+// from, skipping ffmpeg's probing and the burst of buffered media that
+// follows it
+const driftSettle = 10 * time.Second
+
+// driftMinWindow is the shortest time the drift is reported over
+const driftMinWindow = 30 * time.Second
+
+// resyncAudioArgs re-encode the audio, stretching or squeezing it to follow
+// its timestamps so it can't drift away from the video
+var resyncAudioArgs = []string{"-af", "aresample=async=1", "-c:a", "aac"}
+
+// driftMeter measures drift from ffmpeg's progress lines
+type driftMeter struct {
+	started time.Time // first progress line of this input
+	base    ingestSample
+	stats   DriftStats
+}
+
+func newDriftMeter() *driftMeter {
+	return &driftMeter{}
+}
+
+// parse consumes one log line and reports whether the stats changed
+func (m *driftMeter) parse(line string, now time.Time) bool {
+	if ingestInputRegex.MatchString(line) {
+		*m = driftMeter{}
+		return true
+	}
+
+	match := progressTimeRegex.FindStringSubmatch(line)
+	if len(match) < 4 {
+		return false
+	}
+	hours, _ := strconv.ParseFloat(match[1], 64)
+	mins, _ := strconv.ParseFloat(match[2], 64)
+	secs, _ := strconv.ParseFloat(match[3], 64)
+	media := hours*3600 + mins*60 + secs
+
+	if m.started.IsZero() {
+		m.started = now
+		return false
+	}
+	if m.base.at.IsZero() {
+		if now.Sub(m.started) >= driftSettle {
+			m.base = ingestSample{at: now, media: media}
+		}
+		return false
+	}
+
+	wall := now.Sub(m.base.at).Seconds()
+	if wall < driftMinWindow.Seconds() {
+		return false
+	}
+	ahead := (media - m.base.media) - wall
+	m.stats = DriftStats{
+		Valid:      true,
+		DriftMs:    ahead * 1000,
+		PPM:        ahead / wall * 1e6,
+		LastUpdate: now,
+	}
+	return true
+}
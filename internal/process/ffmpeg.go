@@ -12,6 +12,9 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"srtla-manager/internal/events"
+	"srtla-manager/internal/publisher"
 )
 
 // killProcessOnPort kills any process listening on the given port
@@ -50,6 +53,36 @@ type FFmpegStats struct {
 	Duration   time.Duration
 	ClientIP   string
 	LastUpdate time.Time
+	FirstFrame time.Time // when output started, zero until then
+	Loudness   LoudnessStats
+	Audio      AudioLevels
+	Ancillary  AncillaryStats
+	Ingest     IngestStats // rates received from the camera, see IngestStats
+	Drift      DriftStats  // stream clock against the system clock
+}
+
+// DefaultReconnectGrace is how long a dropped publisher is waited for
+// before ffmpeg is restarted as if from scratch
+const DefaultReconnectGrace = 10 * time.Second
+
+// PublisherChange reports a camera connecting to or leaving the RTMP listener
+type PublisherChange struct {
+	Connected bool   `json:"connected"`
+	Addr      string `json:"addr,omitempty"`
+	Dropped   bool   `json:"dropped,omitempty"`   // the connection was lost rather than stopped
+	Reconnect bool   `json:"reconnect,omitempty"` // it came back within the grace period
+}
+
+// TopicPublisher carries publisher connections and drops
+var TopicPublisher = events.NewAuditedTopic[PublisherChange]("publisher")
+
+// listenArgs are the arguments of the last StartWithPreview, for Relisten
+type listenArgs struct {
+	rtmpPort  int
+	streamKey string
+	srtPort   int
+	bindAddr  string
+	hlsDir    string
 }
 
 type FFmpegHandler struct {
@@ -57,7 +90,25 @@ type FFmpegHandler struct {
 	mu          sync.RWMutex
 	stats       FFmpegStats
 	mode        FFmpegMode
-	logCallback func(LogLine)
+	bus         *events.Bus
+	loudness    bool
+	meters      bool // log per-channel audio levels
+	levels      audioLevelParser
+	resync      bool // resample the RTMP camera's audio to its timestamps
+	audioMix    AudioMixConfig
+	captureAddr string
+	secret      string           // RTSP credentials, kept out of the log
+	restream    []RestreamTarget // destinations also sent the stream while streaming
+	teed        []RestreamTarget // destinations the running ffmpeg tees to
+	switched    string           // MPEG-TS input used in place of the RTMP listener, "" for none
+	ancillary   *ancillaryParser
+	ingest      *ingestParser
+	drift       *driftMeter
+
+	publishers    *publisher.Tracker
+	listen        *listenArgs
+	publisherAddr string // address of the connected publisher, "" if none
+	publisherIn   bool   // a publisher connected to this run of ffmpeg
 
 	bitrateRegex *regexp.Regexp
 	fpsRegex     *regexp.Regexp
@@ -83,16 +134,147 @@ func NewFFmpegHandler() *FFmpegHandler {
 		clientRegex:        regexp.MustCompile(`Opening '.*' for (reading|writing)`),
 		previewPorts:       make(map[string]int),
 		streamBroadcasters: make(map[string]*StreamBroadcaster),
+		ancillary:          newAncillaryParser(),
+		ingest:             newIngestParser(),
+		drift:              newDriftMeter(),
+		publishers:         publisher.NewTracker(DefaultReconnectGrace, 50),
 	}
 
 	h.proc.SetLogCallback(h.handleLog)
+	h.proc.SetExitCallback(h.handleExit)
 	return h
 }
 
-func (h *FFmpegHandler) SetLogCallback(cb func(LogLine)) {
+// SetBus publishes ffmpeg's log lines and state changes on bus
+func (h *FFmpegHandler) SetBus(bus *events.Bus) {
+	h.mu.Lock()
+	h.bus = bus
+	h.mu.Unlock()
+	h.proc.SetBus(bus)
+}
+
+// SetReconnectGrace sets how long a dropped publisher is waited for; 0 turns
+// the grace period off
+func (h *FFmpegHandler) SetReconnectGrace(grace time.Duration) {
+	h.publishers.SetGrace(grace)
+}
+
+// Publishers returns the publisher connection history
+func (h *FFmpegHandler) Publishers() publisher.Status {
+	return h.publishers.Status(time.Now())
+}
+
+// PublisherInGrace reports whether the publisher dropped within the grace
+// period and may be about to reconnect
+func (h *FFmpegHandler) PublisherInGrace() bool {
+	return h.publishers.InGrace(time.Now())
+}
+
+// SetLoudnessMonitoring enables EBU R128 measurement of the pass-through audio.
+// It takes effect the next time the RTMP pipeline is started.
+func (h *FFmpegHandler) SetLoudnessMonitoring(enabled bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.loudness = enabled
+}
+
+// SetAudioMeters enables per-channel peak and RMS measurement of the
+// pass-through audio. It takes effect the next time the RTMP pipeline is
+// started.
+func (h *FFmpegHandler) SetAudioMeters(enabled bool) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
-	h.logCallback = cb
+	h.meters = enabled
+}
+
+// SetAudioMix sets where the RTMP pipeline's audio comes from and how it
+// is processed. It takes effect the next time the RTMP pipeline is started.
+func (h *FFmpegHandler) SetAudioMix(mix AudioMixConfig) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.audioMix = mix
+}
+
+// SetAudioResync resamples the RTMP camera's audio to its timestamps,
+// stretching or squeezing it to correct clock drift, instead of copying it.
+// The audio is then re-encoded to AAC. It takes effect the next time the
+// RTMP pipeline is started.
+func (h *FFmpegHandler) SetAudioResync(enabled bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.resync = enabled
+}
+
+// SetPassthroughCapture duplicates the muxed SRT leg to a loopback UDP
+// address so a recorder sees the exact bytes sent to srtla. An empty addr
+// disables it. It takes effect the next time a streaming pipeline is started.
+func (h *FFmpegHandler) SetPassthroughCapture(addr string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.captureAddr = addr
+}
+
+// SetRestreamTargets sets the destinations the stream is also sent to
+// while streaming. ffmpeg tees the stream to each target's UDP port, where
+// its RestreamOutput picks it up, so a destination that fails affects
+// neither the SRT leg nor the others. It takes effect the next time a
+// streaming pipeline is started.
+func (h *FFmpegHandler) SetRestreamTargets(targets []RestreamTarget) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.restream = targets
+}
+
+// RestreamTargets returns the destinations the running ffmpeg tees to, nil
+// when it isn't streaming
+func (h *FFmpegHandler) RestreamTargets() []RestreamTarget {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.teed
+}
+
+// SetSwitchedInput makes ffmpeg read the MPEG-TS stream at url, the output
+// of the ingest failover switch, instead of listening for a camera itself.
+// An empty url goes back to listening. It takes effect the next time ffmpeg
+// is started or relistens.
+func (h *FFmpegHandler) SetSwitchedInput(url string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.switched = url
+}
+
+// Listening reports whether ffmpeg is running on the RTMP listener or the
+// switched input, rather than on a USB or RTSP camera
+func (h *FFmpegHandler) Listening() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.listen != nil && h.mode != "" && h.proc.State() == StateRunning
+}
+
+// srtOutputURL returns the SRT leg URL, wrapped in the tee protocol when a
+// passthrough capture is set. The tee protocol writes the single mux output
+// to both destinations, unlike a second tee muxer slave which would re-mux.
+func (h *FFmpegHandler) srtOutputURL(srtPort int) string {
+	// SRT options for robust streaming:
+	// - mode=caller: FFmpeg initiates connection to SRTLA
+	// - connect_timeout=10000000: 10 second connection timeout (in microseconds)
+	// - latency=200000: 200ms latency buffer (in microseconds)
+	// - pkt_size=1316: optimal packet size for MPEG-TS over SRT
+	srtURL := fmt.Sprintf("srt://127.0.0.1:%d?mode=caller&connect_timeout=10000000&latency=200000&pkt_size=1316", srtPort)
+
+	h.mu.RLock()
+	addr := h.captureAddr
+	h.mu.RUnlock()
+
+	if addr == "" {
+		return srtURL
+	}
+	return fmt.Sprintf("tee:%s|udp://%s?pkt_size=1316", srtURL, addr)
+}
+
+// teeSlave escapes a URL for use as a tee muxer slave
+func teeSlave(url string) string {
+	return strings.ReplaceAll(url, "|", `\|`)
 }
 
 func (h *FFmpegHandler) Mode() FFmpegMode {
@@ -111,18 +293,60 @@ func (h *FFmpegHandler) StartWithBindAddress(rtmpPort int, streamKey string, srt
 
 // StartWithPreview behaves like StartWithBindAddress but also tees to an HLS output when hlsDir is provided.
 func (h *FFmpegHandler) StartWithPreview(rtmpPort int, streamKey string, srtPort int, bindAddr string, hlsDir string) error {
-	// Kill any zombie processes on the RTMP port before starting
-	if err := killProcessOnPort(rtmpPort); err != nil {
-		log.Printf("[WARN] Failed to cleanup port %d: %v", rtmpPort, err)
+	return h.startListen(listenArgs{rtmpPort, streamKey, srtPort, bindAddr, hlsDir}, false)
+}
+
+// Relisten restarts ffmpeg with the arguments of the last StartWithPreview
+// so a dropped publisher can reconnect. The HLS preview carries on from its
+// last segment and the stream keeps its first frame time, so a brief drop
+// doesn't look like a new stream.
+func (h *FFmpegHandler) Relisten() error {
+	h.mu.RLock()
+	last := h.listen
+	h.mu.RUnlock()
+	if last == nil {
+		return fmt.Errorf("ffmpeg has not listened for a publisher yet")
+	}
+	return h.startListen(*last, true)
+}
+
+func (h *FFmpegHandler) startListen(a listenArgs, resume bool) error {
+	rtmpPort, streamKey, srtPort, bindAddr, hlsDir := a.rtmpPort, a.streamKey, a.srtPort, a.bindAddr, a.hlsDir
+
+	h.mu.RLock()
+	switched := h.switched
+	h.mu.RUnlock()
+
+	// Kill any zombie processes on the RTMP port before starting. With a
+	// switched input the port belongs to an ingest relay.
+	if switched == "" {
+		if err := killProcessOnPort(rtmpPort); err != nil {
+			log.Printf("[WARN] Failed to cleanup port %d: %v", rtmpPort, err)
+		}
 	}
 
 	h.mu.Lock()
+	h.endPublisherLocked()
+	firstFrame := h.stats.FirstFrame
 	h.stats = FFmpegStats{State: FFmpegWaiting}
+	if resume {
+		h.stats.FirstFrame = firstFrame
+	}
+	h.listen = &a
 	if srtPort > 0 {
 		h.mode = FFmpegModeStreaming
 	} else {
 		h.mode = FFmpegModeReceiveOnly
 	}
+	loudness := h.loudness
+	meters := h.meters
+	resync := h.resync
+	mix := h.audioMix
+	restream := h.restream
+	h.teed = nil
+	if srtPort > 0 {
+		h.teed = restream
+	}
 	h.mu.Unlock()
 
 	rtmpURL := fmt.Sprintf("rtmp://%s:%d/%s", bindAddr, rtmpPort, streamKey)
@@ -130,22 +354,23 @@ func (h *FFmpegHandler) StartWithPreview(rtmpPort int, streamKey string, srtPort
 
 	// SRT leg is optional; skip when srtPort is 0 (e.g., preview-only flow)
 	if srtPort > 0 {
-		// SRT options for robust streaming:
-		// - mode=caller: FFmpeg initiates connection to SRTLA
-		// - connect_timeout=10000000: 10 second connection timeout (in microseconds)
-		// - latency=200000: 200ms latency buffer (in microseconds)
-		// - pkt_size=1316: optimal packet size for MPEG-TS over SRT
-		srtURL := fmt.Sprintf("srt://127.0.0.1:%d?mode=caller&connect_timeout=10000000&latency=200000&pkt_size=1316", srtPort)
-		outputs = append(outputs, fmt.Sprintf("[f=mpegts]%s", srtURL))
+		outputs = append(outputs, fmt.Sprintf("[f=mpegts]%s", teeSlave(h.srtOutputURL(srtPort))))
+		for _, target := range restream {
+			outputs = append(outputs, fmt.Sprintf("[f=mpegts:onfail=ignore]%s", teeSlave(relayOutput(target.Port))))
+		}
 	}
 	if hlsDir != "" {
-		if err := os.RemoveAll(hlsDir); err != nil {
+		flags := "delete_segments+omit_endlist"
+		if resume {
+			// continue the playlist, marking the gap for players
+			flags += "+append_list+discont_start"
+		} else if err := os.RemoveAll(hlsDir); err != nil {
 			return err
 		}
 		if err := os.MkdirAll(hlsDir, 0777); err != nil {
 			return err
 		}
-		hlsOut := fmt.Sprintf("[f=hls:hls_time=1:hls_list_size=10:hls_flags=delete_segments+omit_endlist]%s/playlist.m3u8", hlsDir)
+		hlsOut := fmt.Sprintf("[f=hls:hls_time=1:hls_list_size=10:hls_flags=%s]%s/playlist.m3u8", flags, hlsDir)
 		outputs = append(outputs, hlsOut)
 	}
 
@@ -154,15 +379,51 @@ func (h *FFmpegHandler) StartWithPreview(rtmpPort int, streamKey string, srtPort
 		return fmt.Errorf("no outputs configured for ffmpeg")
 	}
 
-	args := []string{
+	input := []string{"-listen", "1", "-i", rtmpURL}
+	if switched != "" {
+		input = []string{"-f", "mpegts", "-i", switched}
+	}
+	var audioFilters []string
+	if loudness {
+		audioFilters = append(audioFilters, "ebur128=peak=true")
+	}
+	if meters {
+		audioFilters = append(audioFilters, audioMeterFilter)
+	}
+	device, graph := audioGraph(mix, resync, audioFilters)
+
+	args := append([]string{
 		"-hide_banner",
 		"-loglevel", "info",
-		"-listen", "1",
-		"-i", rtmpURL,
-		"-c", "copy",
-		"-map", "0",
+	}, input...)
+	args = append(args, device...)
+	if graph != "" {
+		args = append(args, "-filter_complex", graph)
+	}
+	args = append(args, "-c", "copy", "-map", "0")
+	if graph != "" {
+		// everything but the camera's audio is still copied
+		args = append(args, "-map", "-0:a", "-map", "[aout]", "-c:a", "aac")
+	} else if resync {
+		args = append(args, resyncAudioArgs...)
+	}
+	args = append(args,
 		"-f", "tee",
 		strings.Join(outputs, "|"),
+	)
+
+	// Measure the audio on a separate null output: the processed audio
+	// from the graph, otherwise the first audio track decoded into the
+	// ebur128 and meter filters while the tee output above keeps copying
+	// the untouched stream
+	if graph != "" && len(audioFilters) > 0 {
+		args = append(args, "-map", "[amon]", "-f", "null", "-")
+	} else if len(audioFilters) > 0 {
+		args = append(args,
+			"-map", "0:a:0?",
+			"-af", strings.Join(audioFilters, ","),
+			"-f", "null", "-",
+		)
 	}
 
 	return h.proc.Start("ffmpeg", args...)
@@ -170,8 +431,10 @@ func (h *FFmpegHandler) StartWithPreview(rtmpPort int, streamKey string, srtPort
 
 func (h *FFmpegHandler) Stop() error {
 	h.mu.Lock()
+	h.endPublisherLocked()
 	h.stats = FFmpegStats{State: FFmpegStopped}
 	h.mode = ""
+	h.teed = nil
 	h.mu.Unlock()
 	return h.proc.Stop()
 }
@@ -235,21 +498,100 @@ func (h *FFmpegHandler) ProcessState() State {
 }
 
 func (h *FFmpegHandler) handleLog(log LogLine) {
+	// ebur128 logs every 100ms; keep those lines out of the log stream
+	if isLoudnessLine(log.Line) {
+		if ls, ok := parseLoudnessLine(log.Line); ok {
+			h.mu.Lock()
+			h.stats.Loudness = ls
+			h.mu.Unlock()
+		}
+		return
+	}
+	// so does the audio meter
+	if isAudioLevelLine(log.Line) {
+		h.mu.Lock()
+		if levels, ok := h.levels.parse(log.Line, time.Now()); ok {
+			h.stats.Audio = levels
+		}
+		h.mu.Unlock()
+		return
+	}
+
 	h.mu.Lock()
-	cb := h.logCallback
+	bus := h.bus
+	secret := h.secret
 	h.mu.Unlock()
 
-	if cb != nil {
-		cb(log)
+	if secret != "" {
+		log.Line = strings.ReplaceAll(log.Line, secret, "***")
 	}
+	events.Publish(bus, TopicLog, log)
 
 	h.parseLogLine(log.Line)
 }
 
+// handleExit records a connected publisher as dropped when ffmpeg exits on
+// its own, which is what an RTMP listener does when its publisher goes away
+func (h *FFmpegHandler) handleExit() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.publisherIn {
+		return
+	}
+	h.publishers.Dropped(time.Now())
+	events.Publish(h.bus, TopicPublisher, PublisherChange{Addr: h.publisherAddr, Dropped: true})
+	h.publisherIn = false
+	h.publisherAddr = ""
+}
+
+// endPublisherLocked records a connected publisher as stopped because ffmpeg
+// is being stopped or replaced. h.mu must be held.
+func (h *FFmpegHandler) endPublisherLocked() {
+	if !h.publisherIn {
+		return
+	}
+	h.publishers.Stopped(time.Now())
+	events.Publish(h.bus, TopicPublisher, PublisherChange{Addr: h.publisherAddr})
+	h.publisherIn = false
+	h.publisherAddr = ""
+}
+
+// publisherConnectedLocked records the publisher of this run of ffmpeg.
+// h.mu must be held.
+func (h *FFmpegHandler) publisherConnectedLocked() {
+	if h.publisherIn || h.listen == nil {
+		return
+	}
+	addr := publisher.Peer(h.listen.rtmpPort)
+	if addr == "" {
+		addr = h.stats.ClientIP
+	}
+	h.stats.ClientIP = addr
+	h.publisherIn = true
+	h.publisherAddr = addr
+	reconnect := h.publishers.Connected(addr, time.Now())
+	events.Publish(h.bus, TopicPublisher, PublisherChange{Connected: true, Addr: addr, Reconnect: reconnect})
+}
+
 func (h *FFmpegHandler) parseLogLine(line string) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
+	if h.ancillary.parse(line) {
+		h.stats.Ancillary = h.ancillary.snapshot()
+		for _, w := range h.stats.Ancillary.Warnings {
+			log.Printf("[WARN] FFmpeg ancillary data: %s", w)
+		}
+	}
+
+	if h.ingest.parse(line, time.Now()) {
+		h.stats.Ingest = h.ingest.stats
+	}
+
+	if h.drift.parse(line, time.Now()) {
+		h.stats.Drift = h.drift.stats
+	}
+
 	if strings.Contains(line, "Opening 'rtmp://") && strings.Contains(line, "reading") {
 		h.stats.State = FFmpegConnected
 		h.stats.LastUpdate = time.Now()
@@ -261,9 +603,17 @@ func (h *FFmpegHandler) parseLogLine(line string) {
 		}
 	}
 
+	// the input is only probed once a publisher has connected
+	if strings.Contains(line, "Input #0, flv") {
+		h.publisherConnectedLocked()
+	}
+
 	if strings.Contains(line, "Stream mapping") || strings.Contains(line, "Output #0") {
 		h.stats.State = FFmpegStreaming
 		h.stats.LastUpdate = time.Now()
+		if h.stats.FirstFrame.IsZero() {
+			h.stats.FirstFrame = h.stats.LastUpdate
+		}
 	}
 
 	if match := h.bitrateRegex.FindStringSubmatch(line); len(match) > 1 {
@@ -328,12 +678,15 @@ type USBCaptureConfig struct {
 	InputFormat string // mjpeg, h264, yuyv422
 	SRTPort     int
 	HLSDir      string
+	MaxFPS      int // caps the HTTP preview output frame rate, 0 = capture rate
 }
 
 // StartUSBCapture starts capturing from a USB camera via V4L2
 func (h *FFmpegHandler) StartUSBCapture(config USBCaptureConfig) error {
 	h.mu.Lock()
+	h.endPublisherLocked()
 	h.stats = FFmpegStats{State: FFmpegWaiting}
+	h.listen = nil
 	if config.SRTPort > 0 {
 		h.mode = FFmpegModeStreaming
 	} else {
@@ -341,10 +694,23 @@ func (h *FFmpegHandler) StartUSBCapture(config USBCaptureConfig) error {
 	}
 	h.mu.Unlock()
 
-	args := []string{
+	args := append([]string{
 		"-hide_banner",
 		"-loglevel", "info",
+	}, usbInputArgs(config)...)
+
+	outputs, err := h.outputArgs(config.SRTPort, config.HLSDir)
+	if err != nil {
+		return err
 	}
+	args = append(args, outputs...)
+
+	return h.proc.Start("ffmpeg", args...)
+}
+
+// usbInputArgs returns the arguments capturing and encoding a USB camera
+func usbInputArgs(config USBCaptureConfig) []string {
+	var args []string
 
 	// Add hardware device for VAAPI encoding
 	if config.Encoder == "h264_vaapi" {
@@ -370,111 +736,110 @@ func (h *FFmpegHandler) StartUSBCapture(config USBCaptureConfig) error {
 		"-i", config.DevicePath,
 	)
 
-	// Encoding options based on encoder type
-	var videoCodec []string
-	switch config.Encoder {
+	args = append(args, videoCodecArgs(config.Encoder, config.Bitrate)...)
+	return append(args, "-map", "0")
+}
+
+// Encoders lists the video encoders a capture can use; copy passes the
+// source's H.264 through
+var Encoders = []string{"copy", "libx264", "libopenh264", "h264_vaapi", "h264_nvenc"}
+
+// videoCodecArgs returns the video encoding arguments for an encoder:
+// libx264, libopenh264, h264_vaapi, h264_nvenc or copy. Anything else picks
+// the best software encoder available.
+func videoCodecArgs(encoder string, bitrate int) []string {
+	switch encoder {
 	case "copy":
 		// Passthrough - no re-encoding (for cameras with H.264 output)
-		videoCodec = []string{"-c:v", "copy"}
+		return []string{"-c:v", "copy"}
 	case "h264_vaapi":
 		// VAAPI hardware encoding (Intel/AMD)
-		videoCodec = []string{
+		return []string{
 			"-vf", "format=nv12,hwupload",
 			"-c:v", "h264_vaapi",
-			"-b:v", fmt.Sprintf("%dk", config.Bitrate),
+			"-b:v", fmt.Sprintf("%dk", bitrate),
 		}
 	case "h264_nvenc":
 		// NVIDIA hardware encoding
-		videoCodec = []string{
+		return []string{
 			"-c:v", "h264_nvenc",
 			"-preset", "p4",
 			"-tune", "ll",
-			"-b:v", fmt.Sprintf("%dk", config.Bitrate),
+			"-b:v", fmt.Sprintf("%dk", bitrate),
 		}
 	case "libopenh264":
 		// OpenH264 software encoding (available on distros without libx264)
 		// Add format conversion filter to handle yuvj422p -> yuvj420p colorspace conversion
-		videoCodec = []string{
+		return []string{
 			"-vf", "format=yuvj420p",
 			"-c:v", "libopenh264",
-			"-b:v", fmt.Sprintf("%dk", config.Bitrate),
+			"-b:v", fmt.Sprintf("%dk", bitrate),
 		}
 	case "libx264":
 		// libx264 software encoding
-		videoCodec = []string{
+		return []string{
 			"-c:v", "libx264",
 			"-preset", "ultrafast",
 			"-tune", "zerolatency",
-			"-b:v", fmt.Sprintf("%dk", config.Bitrate),
+			"-b:v", fmt.Sprintf("%dk", bitrate),
 		}
 	default:
 		// Auto-detect best software encoder
-		encoder := DetectSoftwareEncoder()
-		if encoder == "libopenh264" {
-			videoCodec = []string{
-				"-vf", "format=yuvj420p",
-				"-c:v", "libopenh264",
-				"-b:v", fmt.Sprintf("%dk", config.Bitrate),
-			}
-		} else {
-			videoCodec = []string{
-				"-c:v", "libx264",
-				"-preset", "ultrafast",
-				"-tune", "zerolatency",
-				"-b:v", fmt.Sprintf("%dk", config.Bitrate),
-			}
+		if DetectSoftwareEncoder() == "libopenh264" {
+			return videoCodecArgs("libopenh264", bitrate)
 		}
+		return videoCodecArgs("libx264", bitrate)
 	}
+}
 
-	args = append(args, videoCodec...)
-
+// outputArgs returns the output arguments sending the mapped streams to SRT,
+// the HLS preview or both
+func (h *FFmpegHandler) outputArgs(srtPort int, hlsDir string) ([]string, error) {
 	// Prepare HLS directory if needed
-	if config.HLSDir != "" {
-		if err := os.RemoveAll(config.HLSDir); err != nil {
-			return err
+	if hlsDir != "" {
+		if err := os.RemoveAll(hlsDir); err != nil {
+			return nil, err
 		}
-		if err := os.MkdirAll(config.HLSDir, 0777); err != nil {
-			return err
+		if err := os.MkdirAll(hlsDir, 0777); err != nil {
+			return nil, err
 		}
 	}
 
-	hasSRT := config.SRTPort > 0
-	hasHLS := config.HLSDir != ""
+	hasSRT := srtPort > 0
+	hasHLS := hlsDir != ""
 
 	if !hasSRT && !hasHLS {
-		return fmt.Errorf("no outputs configured for ffmpeg")
+		return nil, fmt.Errorf("no outputs configured for ffmpeg")
 	}
 
 	if hasSRT && hasHLS {
 		// Multiple outputs — use tee muxer
-		srtURL := fmt.Sprintf("srt://127.0.0.1:%d?mode=caller&connect_timeout=10000000&latency=200000&pkt_size=1316", config.SRTPort)
-		hlsOut := fmt.Sprintf("[f=hls:hls_time=1:hls_list_size=10:hls_flags=delete_segments+omit_endlist]%s/playlist.m3u8", config.HLSDir)
+		srtURL := teeSlave(h.srtOutputURL(srtPort))
+		hlsOut := fmt.Sprintf("[f=hls:hls_time=1:hls_list_size=10:hls_flags=delete_segments+omit_endlist]%s/playlist.m3u8", hlsDir)
 		teeOutput := fmt.Sprintf("[f=mpegts]%s|%s", srtURL, hlsOut)
-		args = append(args, "-map", "0", "-f", "tee", teeOutput)
+		return []string{"-f", "tee", teeOutput}, nil
 	} else if hasSRT {
 		// SRT only
-		srtURL := fmt.Sprintf("srt://127.0.0.1:%d?mode=caller&connect_timeout=10000000&latency=200000&pkt_size=1316", config.SRTPort)
-		args = append(args, "-f", "mpegts", srtURL)
-	} else {
-		// HLS only — output directly with explicit HLS options
-		args = append(args,
-			"-f", "hls",
-			"-hls_time", "1",
-			"-hls_list_size", "10",
-			"-hls_flags", "delete_segments+omit_endlist",
-			"-flvflags", "+discardcorrupt",
-			fmt.Sprintf("%s/playlist.m3u8", config.HLSDir),
-		)
+		return []string{"-f", "mpegts", h.srtOutputURL(srtPort)}, nil
 	}
-
-	return h.proc.Start("ffmpeg", args...)
+	// HLS only — output directly with explicit HLS options
+	return []string{
+		"-f", "hls",
+		"-hls_time", "1",
+		"-hls_list_size", "10",
+		"-hls_flags", "delete_segments+omit_endlist",
+		"-flvflags", "+discardcorrupt",
+		fmt.Sprintf("%s/playlist.m3u8", hlsDir),
+	}, nil
 }
 
 // StartUSBCameraStream starts capturing from a USB camera and streams MJPEG to stdout
 // This is used for real-time HTTP streaming previews (no files, instant playback)
 func (h *FFmpegHandler) StartUSBCameraStream(config USBCaptureConfig) error {
 	h.mu.Lock()
+	h.endPublisherLocked()
 	h.stats = FFmpegStats{State: FFmpegWaiting}
+	h.listen = nil
 	h.mode = FFmpegModeReceiveOnly
 	h.mu.Unlock()
 
@@ -522,7 +887,9 @@ func (h *FFmpegHandler) StartUSBCameraHTTPPreview(cameraID string, config USBCap
 	}
 
 	h.mu.Lock()
+	h.endPublisherLocked()
 	h.stats = FFmpegStats{State: FFmpegWaiting}
+	h.listen = nil
 	h.mode = FFmpegModeReceiveOnly
 	h.mu.Unlock()
 
@@ -552,6 +919,11 @@ func (h *FFmpegHandler) StartUSBCameraHTTPPreview(cameraID string, config USBCap
 		"-vf", "format=yuvj420p",
 		"-c:v", "mjpeg",
 		"-q:v", "5",
+	)
+	if config.MaxFPS > 0 && config.MaxFPS < config.FPS {
+		args = append(args, "-r", fmt.Sprintf("%d", config.MaxFPS))
+	}
+	args = append(args,
 		"-f", "mpjpeg",
 		"pipe:1", // Output to stdout
 	)
@@ -601,6 +973,42 @@ func DetectSoftwareEncoder() string {
 	return "libx264"
 }
 
+// encoderDevices are the devices a hardware encoder needs besides ffmpeg
+// support
+var encoderDevices = map[string]string{
+	"h264_vaapi": "/dev/dri/renderD128",
+	"h264_nvenc": "/dev/nvidia0",
+}
+
+// AvailableEncoders reports which of Encoders can be used: built into
+// ffmpeg and, for hardware encoders, with their device present. copy is
+// always available.
+func AvailableEncoders() map[string]bool {
+	available := map[string]bool{"copy": true}
+	out, err := exec.Command("ffmpeg", "-encoders", "-hide_banner").Output()
+	if err != nil {
+		return available
+	}
+	listed := make(map[string]bool)
+	for _, line := range strings.Split(string(out), "\n") {
+		if fields := strings.Fields(line); len(fields) > 1 {
+			listed[fields[1]] = true
+		}
+	}
+	for _, encoder := range Encoders {
+		if encoder == "copy" || !listed[encoder] {
+			continue
+		}
+		if device, ok := encoderDevices[encoder]; ok {
+			if _, err := os.Stat(device); err != nil {
+				continue
+			}
+		}
+		available[encoder] = true
+	}
+	return available
+}
+
 // StreamBroadcaster broadcasts MJPEG stream to multiple HTTP clients
 type StreamBroadcaster struct {
 	mu        sync.RWMutex
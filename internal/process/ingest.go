@@ -0,0 +1,140 @@
+package process
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// IngestStats describes what the camera is delivering to the RTMP listener.
+// The rates are measured over a few seconds of wall time, so unlike ffmpeg's
+// running averages they drop as soon as the camera's uplink to the box does.
+type IngestStats struct {
+	Valid           bool      `json:"valid"`
+	Bitrate         float64   `json:"bitrate"`          // kbps received
+	FPS             float64   `json:"fps"`              // frames received per second
+	Speed           float64   `json:"speed"`            // seconds of media received per second
+	DeclaredBitrate float64   `json:"declared_bitrate"` // kbps announced by the camera, 0 if unknown
+	DeclaredFPS     float64   `json:"declared_fps"`     // frame rate announced by the camera, 0 if unknown
+	Resolution      string    `json:"resolution,omitempty"`
+	LastUpdate      time.Time `json:"last_update"`
+}
+
+// ingestWindow is the shortest interval the rates are measured over
+const ingestWindow = 2 * time.Second
+
+var (
+	ingestInputRegex    = regexp.MustCompile(`^\s*Input #0, `)
+	ingestDurationRegex = regexp.MustCompile(`^\s*Duration: .*bitrate: (\d+) kb/s`)
+	ingestStreamRegex   = regexp.MustCompile(`^\s*Stream #0:\d+[^:]*: (Video|Audio|Data)`)
+	ingestKbpsRegex     = regexp.MustCompile(`(\d+) kb/s`)
+	ingestFPSRegex      = regexp.MustCompile(`([\d.]+) fps`)
+	ingestSizeRegex     = regexp.MustCompile(`(\d{2,5}x\d{2,5})`)
+
+	progressFrameRegex = regexp.MustCompile(`frame=\s*(\d+)`)
+	progressSizeRegex  = regexp.MustCompile(`size=\s*(\d+)(?:kB|KiB)`)
+	progressTimeRegex  = regexp.MustCompile(`time=\s*(\d+):(\d+):([\d.]+)`)
+)
+
+type ingestSample struct {
+	at     time.Time
+	frames int64
+	bytes  int64
+	media  float64 // seconds
+}
+
+// ingestParser reads the camera's announced format from ffmpeg's input dump
+// and measures the received rates from its progress lines. The stream is
+// copied, so the output progress is also what came in.
+type ingestParser struct {
+	input        bool // within the input dump
+	durationKbps float64
+	streamKbps   float64
+	last         ingestSample
+	stats        IngestStats
+}
+
+func newIngestParser() *ingestParser {
+	return &ingestParser{}
+}
+
+// parse consumes one log line and reports whether the stats changed
+func (p *ingestParser) parse(line string, now time.Time) bool {
+	if ingestInputRegex.MatchString(line) {
+		*p = ingestParser{input: true}
+		return true
+	}
+	if p.input {
+		return p.parseInput(line)
+	}
+
+	m := progressTimeRegex.FindStringSubmatch(line)
+	if len(m) < 4 {
+		return false
+	}
+	hours, _ := strconv.ParseFloat(m[1], 64)
+	mins, _ := strconv.ParseFloat(m[2], 64)
+	secs, _ := strconv.ParseFloat(m[3], 64)
+	sample := ingestSample{at: now, media: hours*3600 + mins*60 + secs, bytes: -1}
+	if m := progressFrameRegex.FindStringSubmatch(line); len(m) > 1 {
+		sample.frames, _ = strconv.ParseInt(m[1], 10, 64)
+	}
+	if m := progressSizeRegex.FindStringSubmatch(line); len(m) > 1 {
+		if kb, err := strconv.ParseInt(m[1], 10, 64); err == nil {
+			sample.bytes = kb * 1024
+		}
+	}
+
+	if p.last.at.IsZero() {
+		p.last = sample
+		return false
+	}
+	elapsed := now.Sub(p.last.at)
+	if elapsed < ingestWindow {
+		return false
+	}
+
+	dt := elapsed.Seconds()
+	p.stats.FPS = float64(sample.frames-p.last.frames) / dt
+	p.stats.Speed = (sample.media - p.last.media) / dt
+	if sample.bytes >= 0 && p.last.bytes >= 0 {
+		p.stats.Bitrate = float64(sample.bytes-p.last.bytes) * 8 / 1000 / dt
+	}
+	p.stats.Valid = true
+	p.stats.LastUpdate = now
+	p.last = sample
+	return true
+}
+
+// parseInput reads the announced bitrate, frame rate and resolution. The
+// container's bitrate is preferred; RTMP often only has it per stream.
+func (p *ingestParser) parseInput(line string) bool {
+	if m := ingestDurationRegex.FindStringSubmatch(line); len(m) > 1 {
+		p.durationKbps, _ = strconv.ParseFloat(m[1], 64)
+	} else if m := ingestStreamRegex.FindStringSubmatch(line); len(m) > 1 {
+		if m := ingestKbpsRegex.FindStringSubmatch(line); len(m) > 1 {
+			kbps, _ := strconv.ParseFloat(m[1], 64)
+			p.streamKbps += kbps
+		}
+		if m[1] == "Video" {
+			if m := ingestFPSRegex.FindStringSubmatch(line); len(m) > 1 {
+				p.stats.DeclaredFPS, _ = strconv.ParseFloat(m[1], 64)
+			}
+			if m := ingestSizeRegex.FindStringSubmatch(line); len(m) > 1 {
+				p.stats.Resolution = m[1]
+			}
+		}
+	} else if strings.Contains(line, "Output #") || strings.Contains(line, "Stream mapping") {
+		p.input = false
+		return false
+	} else {
+		return false
+	}
+
+	p.stats.DeclaredBitrate = p.durationKbps
+	if p.stats.DeclaredBitrate == 0 {
+		p.stats.DeclaredBitrate = p.streamKbps
+	}
+	return true
+}
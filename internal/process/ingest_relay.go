@@ -0,0 +1,193 @@
+package process
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"srtla-manager/internal/events"
+)
+
+// Size and rate of the slate. It is encoded once, so it stays small.
+const (
+	slateSize    = "1280x720"
+	slateFPS     = 30
+	slateBitrate = 1000 // kbps
+)
+
+// IngestRelay hands one source on as MPEG-TS to a loopback UDP port: a
+// camera pushing to an RTMP stream key or SRT stream ID, unchanged, or an
+// RTSP camera, a USB camera or a slate, encoded. ffmpeg exits when the source goes away, so the
+// relay has to be started again for the next one.
+type IngestRelay struct {
+	proc *Process
+
+	mu     sync.Mutex
+	secret string // RTSP credentials or SRT passphrase, kept out of the log
+}
+
+func NewIngestRelay(name string) *IngestRelay {
+	return &IngestRelay{proc: New("ingest-" + name)}
+}
+
+// SetBus publishes the relay's log lines and state changes on bus
+func (r *IngestRelay) SetBus(bus *events.Bus) {
+	r.proc.SetLogCallback(func(line LogLine) {
+		r.mu.Lock()
+		secret := r.secret
+		r.mu.Unlock()
+		if secret != "" {
+			line.Line = strings.ReplaceAll(line.Line, secret, "***")
+		}
+		events.Publish(bus, TopicLog, line)
+	})
+	r.proc.SetBus(bus)
+}
+
+// Start listens on bindAddr:rtmpPort for streamKey and sends to udpPort
+func (r *IngestRelay) Start(bindAddr string, rtmpPort int, streamKey string, udpPort int) error {
+	if err := killProcessOnPort(rtmpPort); err != nil {
+		log.Printf("[WARN] Failed to cleanup port %d: %v", rtmpPort, err)
+	}
+	return r.proc.Start("ffmpeg",
+		"-hide_banner",
+		"-loglevel", "warning",
+		"-listen", "1",
+		"-i", fmt.Sprintf("rtmp://%s:%d/%s", bindAddr, rtmpPort, streamKey),
+		"-c", "copy",
+		"-map", "0",
+		"-f", "mpegts",
+		relayOutput(udpPort),
+	)
+}
+
+// StartSRT listens on loopback srtPort for the camera the SRT gate hands on
+// and sends to udpPort. A latencyMs of 0 uses SRT's default; passphrase, if
+// set, is the one the camera encrypts with.
+func (r *IngestRelay) StartSRT(srtPort, latencyMs int, passphrase string, udpPort int) error {
+	r.mu.Lock()
+	r.secret = passphrase
+	r.mu.Unlock()
+
+	// ffmpeg takes the options as written, without unescaping them
+	input := fmt.Sprintf("srt://127.0.0.1:%d?mode=listener", srtPort)
+	if latencyMs > 0 {
+		input += fmt.Sprintf("&latency=%d", latencyMs*1000) // microseconds
+	}
+	if passphrase != "" {
+		input += "&passphrase=" + passphrase
+	}
+	return r.proc.Start("ffmpeg",
+		"-hide_banner",
+		"-loglevel", "warning",
+		"-i", input,
+		"-c", "copy",
+		"-map", "0",
+		"-f", "mpegts",
+		relayOutput(udpPort),
+	)
+}
+
+// StartRTSP pulls an RTSP camera and sends it to udpPort. SRTPort and
+// HLSDir of config are not used.
+func (r *IngestRelay) StartRTSP(config RTSPCaptureConfig, udpPort int) error {
+	input, secret, err := rtspInputArgs(config)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.secret = secret
+	r.mu.Unlock()
+
+	args := append([]string{"-hide_banner", "-loglevel", "warning"}, input...)
+	return r.proc.Start("ffmpeg", append(args, "-f", "mpegts", relayOutput(udpPort))...)
+}
+
+// StartUSB captures a USB camera and sends it to udpPort. SRTPort and
+// HLSDir of config are not used.
+func (r *IngestRelay) StartUSB(config USBCaptureConfig, udpPort int) error {
+	args := append([]string{"-hide_banner", "-loglevel", "warning"}, usbInputArgs(config)...)
+	return r.proc.Start("ffmpeg", append(args, "-f", "mpegts", relayOutput(udpPort))...)
+}
+
+// StartSlate sends a still image, or color bars when image is "", with
+// silent audio to udpPort in real time
+func (r *IngestRelay) StartSlate(image string, udpPort int) error {
+	args := []string{"-hide_banner", "-loglevel", "warning"}
+	if image != "" {
+		args = append(args, "-re", "-loop", "1", "-framerate", fmt.Sprint(slateFPS), "-i", image)
+	} else {
+		args = append(args, "-re", "-f", "lavfi", "-i", fmt.Sprintf("smptebars=size=%s:rate=%d", slateSize, slateFPS))
+	}
+	w, h, _ := strings.Cut(slateSize, "x")
+	args = append(args,
+		"-re", "-f", "lavfi", "-i", "anullsrc=r=48000:cl=stereo",
+		"-vf", fmt.Sprintf("scale=%s:%s:force_original_aspect_ratio=decrease,pad=%s:%s:(ow-iw)/2:(oh-ih)/2,format=yuv420p", w, h, w, h),
+		"-map", "0:v",
+		"-map", "1:a",
+	)
+	// not videoCodecArgs, whose openh264 filter would replace the scaling
+	args = append(args,
+		"-c:v", DetectSoftwareEncoder(),
+		"-b:v", fmt.Sprintf("%dk", slateBitrate),
+		"-g", fmt.Sprint(slateFPS),
+		"-c:a", "aac",
+		"-f", "mpegts",
+		relayOutput(udpPort),
+	)
+	return r.proc.Start("ffmpeg", args...)
+}
+
+// TestPatternConfig is the bars and tone a test stream sends
+type TestPatternConfig struct {
+	Width   int
+	Height  int
+	FPS     int
+	Bitrate int // kbps, held constant so the links carry what a camera would
+	ToneHz  int
+}
+
+// StartTestPattern sends SMPTE bars with a sine tone to udpPort in real
+// time, encoded at a constant bitrate
+func (r *IngestRelay) StartTestPattern(config TestPatternConfig, udpPort int) error {
+	encoder := DetectSoftwareEncoder()
+	bitrate := fmt.Sprintf("%dk", config.Bitrate)
+	args := []string{
+		"-hide_banner", "-loglevel", "warning",
+		"-re", "-f", "lavfi", "-i", fmt.Sprintf("smptehdbars=size=%dx%d:rate=%d", config.Width, config.Height, config.FPS),
+		"-re", "-f", "lavfi", "-i", fmt.Sprintf("sine=frequency=%d:sample_rate=48000", config.ToneHz),
+		"-map", "0:v",
+		"-map", "1:a",
+		"-vf", "format=yuv420p",
+		"-c:v", encoder,
+		"-b:v", bitrate,
+		"-minrate", bitrate,
+		"-maxrate", bitrate,
+		"-bufsize", bitrate,
+		"-g", fmt.Sprint(config.FPS),
+	}
+	if encoder == "libx264" {
+		// bars compress to next to nothing; pad them out to the bitrate
+		args = append(args, "-x264-params", "nal-hrd=cbr")
+	}
+	args = append(args,
+		"-c:a", "aac",
+		"-ac", "2",
+		"-f", "mpegts",
+		relayOutput(udpPort),
+	)
+	return r.proc.Start("ffmpeg", args...)
+}
+
+func relayOutput(udpPort int) string {
+	return fmt.Sprintf("udp://127.0.0.1:%d?pkt_size=1316", udpPort)
+}
+
+func (r *IngestRelay) Stop() error {
+	return r.proc.Stop()
+}
+
+func (r *IngestRelay) ProcessState() State {
+	return r.proc.State()
+}
@@ -0,0 +1,81 @@
+package process
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LoudnessFloor is reported in place of -inf (silence) so values stay JSON-encodable
+const LoudnessFloor = -120.0
+
+// LoudnessStats holds the EBU R128 measurements of the pass-through audio
+type LoudnessStats struct {
+	Valid      bool      `json:"valid"`
+	Elapsed    float64   `json:"elapsed"`    // seconds of audio measured
+	Momentary  float64   `json:"momentary"`  // LUFS, 400ms window
+	ShortTerm  float64   `json:"short_term"` // LUFS, 3s window
+	Integrated float64   `json:"integrated"` // LUFS since ffmpeg start
+	Range      float64   `json:"range"`      // LU
+	TruePeak   float64   `json:"true_peak"`  // dBTP, maximum across channels
+	LastUpdate time.Time `json:"last_update"`
+}
+
+var (
+	loudnessElapsedRegex    = regexp.MustCompile(`\bt:\s*([\d.]+)`)
+	loudnessMomentaryRegex  = regexp.MustCompile(`\bM:\s*(-?[\d.]+|-?inf)`)
+	loudnessShortTermRegex  = regexp.MustCompile(`\bS:\s*(-?[\d.]+|-?inf)`)
+	loudnessIntegratedRegex = regexp.MustCompile(`\bI:\s*(-?[\d.]+|-?inf)\s*LUFS`)
+	loudnessRangeRegex      = regexp.MustCompile(`\bLRA:\s*(-?[\d.]+)\s*LU`)
+	loudnessTruePeakRegex   = regexp.MustCompile(`\sTPK:((?:\s+-?(?:[\d.]+|inf))+)\s*dBFS`)
+)
+
+// isLoudnessLine reports whether line is a per-frame log line of the ebur128 filter
+func isLoudnessLine(line string) bool {
+	return strings.Contains(line, "Parsed_ebur128") && strings.Contains(line, " t: ")
+}
+
+func parseLoudnessValue(s string) float64 {
+	v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || math.IsInf(v, 0) || math.IsNaN(v) || v < LoudnessFloor {
+		return LoudnessFloor
+	}
+	return v
+}
+
+// parseLoudnessLine extracts measurements from an ebur128 frame log line
+func parseLoudnessLine(line string) (LoudnessStats, bool) {
+	ls := LoudnessStats{TruePeak: LoudnessFloor}
+
+	m := loudnessIntegratedRegex.FindStringSubmatch(line)
+	if len(m) < 2 {
+		return ls, false
+	}
+	ls.Integrated = parseLoudnessValue(m[1])
+
+	if m := loudnessElapsedRegex.FindStringSubmatch(line); len(m) > 1 {
+		ls.Elapsed, _ = strconv.ParseFloat(m[1], 64)
+	}
+	if m := loudnessMomentaryRegex.FindStringSubmatch(line); len(m) > 1 {
+		ls.Momentary = parseLoudnessValue(m[1])
+	}
+	if m := loudnessShortTermRegex.FindStringSubmatch(line); len(m) > 1 {
+		ls.ShortTerm = parseLoudnessValue(m[1])
+	}
+	if m := loudnessRangeRegex.FindStringSubmatch(line); len(m) > 1 {
+		ls.Range, _ = strconv.ParseFloat(m[1], 64)
+	}
+	if m := loudnessTruePeakRegex.FindStringSubmatch(line); len(m) > 1 {
+		for _, field := range strings.Fields(m[1]) {
+			if v := parseLoudnessValue(field); v > ls.TruePeak {
+				ls.TruePeak = v
+			}
+		}
+	}
+
+	ls.Valid = true
+	ls.LastUpdate = time.Now()
+	return ls, true
+}
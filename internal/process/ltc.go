@@ -0,0 +1,174 @@
+package process
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os/exec"
+	"sync"
+	"time"
+
+	"srtla-manager/internal/events"
+	"srtla-manager/internal/timecode"
+)
+
+// ltcSampleRate is the rate LTC audio is captured at
+const ltcSampleRate = 48000
+
+// ltcRestartDelay is how long to wait before reopening the capture device
+const ltcRestartDelay = 3 * time.Second
+
+// LTCReader decodes linear timecode from an ALSA capture device. ffmpeg
+// captures the first channel as raw samples and the decoding happens here.
+type LTCReader struct {
+	mu        sync.Mutex
+	cancel    context.CancelFunc
+	device    string // device being read, "" when stopped
+	last      timecode.Timecode
+	lastAt    time.Time
+	lastError string
+	bus       *events.Bus
+}
+
+func NewLTCReader() *LTCReader {
+	return &LTCReader{}
+}
+
+// SetBus publishes the reader's log lines on bus
+func (r *LTCReader) SetBus(bus *events.Bus) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.bus = bus
+}
+
+// Start reads LTC from device until Stop, reopening it when capture fails.
+// It does nothing when the device is already being read.
+func (r *LTCReader) Start(device string) {
+	r.mu.Lock()
+	running := r.device == device
+	r.mu.Unlock()
+	if running {
+		return
+	}
+	r.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r.mu.Lock()
+	r.cancel = cancel
+	r.device = device
+	r.last = timecode.Timecode{}
+	r.lastAt = time.Time{}
+	r.lastError = ""
+	r.mu.Unlock()
+
+	go func() {
+		for {
+			err := r.capture(ctx, device)
+			if ctx.Err() != nil {
+				return
+			}
+			r.mu.Lock()
+			if err != nil {
+				r.lastError = err.Error()
+			}
+			r.mu.Unlock()
+			r.log(fmt.Sprintf("[LTC] Capture from %s stopped: %v", device, err))
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(ltcRestartDelay):
+			}
+		}
+	}()
+}
+
+func (r *LTCReader) capture(ctx context.Context, device string) error {
+	cmd := exec.CommandContext(ctx, "ffmpeg",
+		"-hide_banner",
+		"-loglevel", "error",
+		"-f", "alsa",
+		"-i", device,
+		"-af", "pan=mono|c0=c0",
+		"-ar", fmt.Sprint(ltcSampleRate),
+		"-f", "s16le",
+		"pipe:1",
+	)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	go func() {
+		scanner := bufio.NewScanner(stderr)
+		for scanner.Scan() {
+			r.log(scanner.Text())
+		}
+	}()
+
+	decoder := timecode.NewLTCDecoder(ltcSampleRate)
+	reader := bufio.NewReader(stdout)
+	buf := make([]byte, 4096)
+	samples := make([]int16, len(buf)/2)
+	for {
+		n, err := io.ReadFull(reader, buf)
+		for i := 0; i < n/2; i++ {
+			samples[i] = int16(binary.LittleEndian.Uint16(buf[2*i:]))
+		}
+		decoder.Decode(samples[:n/2], func(tc timecode.Timecode) {
+			r.mu.Lock()
+			r.last = tc
+			r.lastAt = time.Now()
+			r.lastError = ""
+			r.mu.Unlock()
+		})
+		if err != nil {
+			break
+		}
+	}
+	if err := cmd.Wait(); err != nil {
+		return err
+	}
+	return fmt.Errorf("ffmpeg exited")
+}
+
+// Stop ends capture
+func (r *LTCReader) Stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cancel != nil {
+		r.cancel()
+		r.cancel = nil
+	}
+	r.device = ""
+}
+
+// Last returns the last decoded word and when it arrived; the time is zero
+// until LTC has been heard
+func (r *LTCReader) Last() (timecode.Timecode, time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.last, r.lastAt
+}
+
+// LastError is the reason capture last stopped
+func (r *LTCReader) LastError() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lastError
+}
+
+func (r *LTCReader) log(line string) {
+	r.mu.Lock()
+	bus := r.bus
+	r.mu.Unlock()
+	events.Publish(bus, TopicLog, LogLine{Timestamp: time.Now(), Source: "ltc", Line: line})
+}
@@ -9,6 +9,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"srtla-manager/internal/events"
 )
 
 type State string
@@ -21,11 +23,24 @@ const (
 )
 
 type LogLine struct {
-	Timestamp time.Time
-	Source    string
-	Line      string
+	Timestamp time.Time `json:"timestamp"`
+	Source    string    `json:"source"`
+	Line      string    `json:"line"`
+}
+
+// StateChange reports a process starting, stopping or failing
+type StateChange struct {
+	Name  string `json:"name"`
+	State State  `json:"state"`
+	Error string `json:"error,omitempty"`
 }
 
+// Topics published by the process handlers
+var (
+	TopicLog   = events.NewTopic[LogLine]("log")
+	TopicState = events.NewAuditedTopic[StateChange]("process_state")
+)
+
 type Process struct {
 	mu          sync.RWMutex
 	name        string
@@ -34,10 +49,24 @@ type Process struct {
 	lastError   string
 	startTime   time.Time
 	logCallback func(LogLine)
+	onExit      func()
 	cancel      context.CancelFunc
+	exited      chan struct{} // closed once cmd has been waited for
+	bus         *events.Bus
+}
+
+// names holds the name of every process created, so their log lines can be
+// told apart from the manager's own
+var names sync.Map
+
+// IsProcess reports whether a log line source is a child process
+func IsProcess(source string) bool {
+	_, ok := names.Load(source)
+	return ok
 }
 
 func New(name string) *Process {
+	names.Store(name, struct{}{})
 	return &Process{
 		name:  name,
 		state: StateStopped,
@@ -50,6 +79,29 @@ func (p *Process) SetLogCallback(cb func(LogLine)) {
 	p.logCallback = cb
 }
 
+// SetExitCallback sets a function called when the process exits without
+// being stopped
+func (p *Process) SetExitCallback(cb func()) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.onExit = cb
+}
+
+// SetBus publishes the process's state changes on bus
+func (p *Process) SetBus(bus *events.Bus) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.bus = bus
+}
+
+func (p *Process) publishState() {
+	p.mu.RLock()
+	bus := p.bus
+	change := StateChange{Name: p.name, State: p.state, Error: p.lastError}
+	p.mu.RUnlock()
+	events.Publish(bus, TopicState, change)
+}
+
 func (p *Process) Start(cmdPath string, args ...string) error {
 	p.mu.Lock()
 	if p.state == StateRunning || p.state == StateStarting {
@@ -94,32 +146,47 @@ func (p *Process) Start(cmdPath string, args ...string) error {
 		return err
 	}
 
+	exited := make(chan struct{})
 	p.mu.Lock()
 	p.cmd = cmd
 	p.cancel = cancel
+	p.exited = exited
 	p.state = StateRunning
 	p.startTime = time.Now()
 	p.mu.Unlock()
+	p.publishState()
 
 	go p.readOutput(stdout, p.name)
 	go p.readOutput(stderr, p.name)
 
+	// the only Wait on cmd; Stop waits on exited
 	go func() {
 		err := cmd.Wait()
+		close(exited)
 		p.mu.Lock()
-		defer p.mu.Unlock()
 		// Only update state if this is still the active command.
 		// A new Start() may have replaced p.cmd while we were waiting.
 		if p.cmd != cmd {
+			p.mu.Unlock()
 			return
 		}
-		if err != nil && p.state != StateStopped {
+		// Stop has already reported a requested stop
+		exited := p.state != StateStopped
+		if err != nil && exited {
 			p.state = StateError
 			p.lastError = err.Error()
-		} else if p.state != StateStopped {
+		} else if exited {
 			p.state = StateStopped
 		}
 		p.cmd = nil
+		onExit := p.onExit
+		p.mu.Unlock()
+		if exited {
+			p.publishState()
+			if onExit != nil {
+				onExit()
+			}
+		}
 	}()
 
 	return nil
@@ -150,9 +217,10 @@ func (p *Process) Uptime() time.Duration {
 
 func (p *Process) setState(state State, errMsg string) {
 	p.mu.Lock()
-	defer p.mu.Unlock()
 	p.state = state
 	p.lastError = errMsg
+	p.mu.Unlock()
+	p.publishState()
 }
 
 func (p *Process) readOutput(r io.Reader, source string) {
@@ -0,0 +1,101 @@
+package process
+
+import (
+	"fmt"
+	"strings"
+
+	"srtla-manager/internal/events"
+)
+
+// RestreamTarget is a restream destination and the loopback UDP port ffmpeg
+// tees the stream to for it
+type RestreamTarget struct {
+	Name string
+	URL  string
+	Port int
+
+	// rtmps:// only
+	TLSVerify bool   // check the server's certificate
+	CAFile    string // CAs to check it against; the system's when empty
+	CertFile  string // client certificate and key
+	KeyFile   string
+}
+
+// tlsArgs returns ffmpeg's TLS options for an rtmps:// target, which its
+// rtmp protocol hands on to the TLS connection under it
+func (t RestreamTarget) tlsArgs() []string {
+	if !strings.HasPrefix(t.URL, "rtmps://") {
+		return nil
+	}
+	verify := "0"
+	if t.TLSVerify {
+		verify = "1"
+	}
+	args := []string{"-tls_verify", verify}
+	if t.CAFile != "" {
+		args = append(args, "-ca_file", t.CAFile)
+	}
+	if t.CertFile != "" {
+		args = append(args, "-cert_file", t.CertFile, "-key_file", t.KeyFile)
+	}
+	return args
+}
+
+// RestreamOutput sends the stream from its target's UDP port on to the
+// destination's RTMP server or SRT peer. Each destination has its own, so one that
+// fails can be restarted without touching the SRT leg or the others.
+type RestreamOutput struct {
+	proc   *Process
+	target RestreamTarget
+}
+
+func NewRestreamOutput(target RestreamTarget) *RestreamOutput {
+	return &RestreamOutput{proc: New("restream-" + target.Name), target: target}
+}
+
+// SetBus publishes the output's log lines, with the URL and its stream key
+// left out, and state changes on bus
+func (o *RestreamOutput) SetBus(bus *events.Bus) {
+	o.proc.SetLogCallback(func(line LogLine) {
+		line.Line = strings.ReplaceAll(line.Line, o.target.URL, "***")
+		events.Publish(bus, TopicLog, line)
+	})
+	o.proc.SetBus(bus)
+}
+
+func (o *RestreamOutput) Target() RestreamTarget {
+	return o.target
+}
+
+// Start reads the target's port and sends to its URL, as FLV to an RTMP
+// server or MPEG-TS to an SRT peer. ffmpeg keeps waiting on the port while
+// nothing is teed to it.
+func (o *RestreamOutput) Start() error {
+	format := "flv"
+	if strings.HasPrefix(o.target.URL, "srt://") {
+		format = "mpegts"
+	}
+	args := []string{
+		"-hide_banner",
+		"-loglevel", "warning",
+		"-f", "mpegts",
+		"-i", fmt.Sprintf("udp://127.0.0.1:%d?fifo_size=100000&overrun_nonfatal=1", o.target.Port),
+		"-c", "copy",
+		"-map", "0",
+		"-f", format,
+	}
+	args = append(args, o.target.tlsArgs()...)
+	return o.proc.Start("ffmpeg", append(args, o.target.URL)...)
+}
+
+func (o *RestreamOutput) Stop() error {
+	return o.proc.Stop()
+}
+
+func (o *RestreamOutput) ProcessState() State {
+	return o.proc.State()
+}
+
+func (o *RestreamOutput) LastError() string {
+	return o.proc.LastError()
+}
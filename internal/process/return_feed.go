@@ -0,0 +1,117 @@
+package process
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"srtla-manager/internal/events"
+)
+
+// ReturnFeedConfig describes the program return to pull and how to preview it
+type ReturnFeedConfig struct {
+	URL         string // SRT/RTMP URL of the production's program output
+	Width       int    // scale to this width; 0 copies the stream as is
+	BitrateKbps int    // video bitrate when scaling
+	MaxFPS      int    // cap the frame rate when scaling; 0 keeps the source rate
+	HLSDir      string
+}
+
+// ReturnFeedStatus reports the return feed player
+type ReturnFeedStatus struct {
+	State     State   `json:"state"`
+	LastError string  `json:"last_error,omitempty"`
+	Uptime    float64 `json:"uptime_seconds"`
+	URL       string  `json:"url,omitempty"`
+}
+
+// ReturnFeedHandler pulls the program output into a short local HLS playlist
+// with its own ffmpeg, independent of the outgoing pipeline
+type ReturnFeedHandler struct {
+	proc *Process
+	mu   sync.RWMutex
+	cfg  ReturnFeedConfig
+}
+
+func NewReturnFeedHandler() *ReturnFeedHandler {
+	return &ReturnFeedHandler{proc: New("return")}
+}
+
+// SetBus publishes the return feed's log lines and state changes on bus
+func (h *ReturnFeedHandler) SetBus(bus *events.Bus) {
+	h.proc.SetLogCallback(func(line LogLine) { events.Publish(bus, TopicLog, line) })
+	h.proc.SetBus(bus)
+}
+
+// Start pulls the return feed. Segments are one second long and only a few
+// are kept so players join close to live.
+func (h *ReturnFeedHandler) Start(cfg ReturnFeedConfig) error {
+	if err := os.RemoveAll(cfg.HLSDir); err != nil {
+		return fmt.Errorf("failed to clear return feed directory: %w", err)
+	}
+	if err := os.MkdirAll(cfg.HLSDir, 0777); err != nil {
+		return fmt.Errorf("failed to create return feed directory: %w", err)
+	}
+
+	h.mu.Lock()
+	h.cfg = cfg
+	h.mu.Unlock()
+
+	args := []string{
+		"-hide_banner",
+		"-loglevel", "warning",
+		"-fflags", "nobuffer",
+		"-rw_timeout", "5000000", // give up after 5s without data so the monitor can reconnect
+		"-i", cfg.URL,
+	}
+
+	if cfg.Width > 0 {
+		vf := fmt.Sprintf("scale=%d:-2", cfg.Width)
+		if cfg.MaxFPS > 0 {
+			vf += fmt.Sprintf(",fps=%d", cfg.MaxFPS)
+		}
+		args = append(args,
+			"-vf", vf,
+			"-c:v", "libx264",
+			"-preset", "ultrafast",
+			"-tune", "zerolatency",
+			"-b:v", fmt.Sprintf("%dk", cfg.BitrateKbps),
+			"-force_key_frames", "expr:gte(t,n_forced*1)",
+			"-c:a", "aac",
+			"-b:a", "64k",
+		)
+	} else {
+		args = append(args, "-c", "copy")
+	}
+
+	args = append(args,
+		"-f", "hls",
+		"-hls_time", "1",
+		"-hls_list_size", "3",
+		"-hls_flags", "delete_segments+omit_endlist+independent_segments",
+		filepath.Join(cfg.HLSDir, "playlist.m3u8"),
+	)
+
+	return h.proc.Start("ffmpeg", args...)
+}
+
+func (h *ReturnFeedHandler) Stop() error {
+	return h.proc.Stop()
+}
+
+func (h *ReturnFeedHandler) Status() ReturnFeedStatus {
+	h.mu.RLock()
+	cfg := h.cfg
+	h.mu.RUnlock()
+
+	status := ReturnFeedStatus{
+		State:     h.proc.State(),
+		LastError: h.proc.LastError(),
+		Uptime:    h.proc.Uptime().Seconds(),
+	}
+	if status.State == StateRunning {
+		status.URL = cfg.URL
+	}
+	return status
+}
@@ -0,0 +1,100 @@
+package process
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// RTSPTransports lists the lower transports an RTSP camera can be pulled over
+var RTSPTransports = []string{"tcp", "udp"}
+
+// RTSPCaptureConfig holds configuration for pulling an RTSP camera
+type RTSPCaptureConfig struct {
+	URL       string // rtsp:// or rtsps:// URL of the camera's stream
+	Username  string
+	Password  string
+	Transport string // tcp or udp
+	Encoder   string // copy, libx264, libopenh264, h264_vaapi, h264_nvenc
+	Bitrate   int    // kbps, when re-encoding
+	SRTPort   int
+	HLSDir    string
+	Resync    bool // resample the audio to its timestamps, see SetAudioResync
+}
+
+// StartRTSPCapture pulls an RTSP camera and sends it to SRT and the HLS
+// preview like the RTMP and USB paths. The first video and audio tracks are
+// kept; audio is converted to AAC, since IP cameras often send G.711 or PCM
+// which MPEG-TS can't carry.
+func (h *FFmpegHandler) StartRTSPCapture(config RTSPCaptureConfig) error {
+	input, secret, err := rtspInputArgs(config)
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	h.endPublisherLocked()
+	h.stats = FFmpegStats{State: FFmpegWaiting}
+	h.listen = nil
+	h.secret = secret
+	if config.SRTPort > 0 {
+		h.mode = FFmpegModeStreaming
+	} else {
+		h.mode = FFmpegModeReceiveOnly
+	}
+	h.mu.Unlock()
+
+	args := append([]string{
+		"-hide_banner",
+		"-loglevel", "info",
+	}, input...)
+
+	outputs, err := h.outputArgs(config.SRTPort, config.HLSDir)
+	if err != nil {
+		return err
+	}
+	args = append(args, outputs...)
+
+	return h.proc.Start("ffmpeg", args...)
+}
+
+// rtspInputArgs returns the arguments pulling and encoding the camera, and
+// the credentials they contain so they can be kept out of the log
+func rtspInputArgs(config RTSPCaptureConfig) ([]string, string, error) {
+	u, err := url.Parse(config.URL)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid RTSP URL: %w", err)
+	}
+	secret := ""
+	if config.Username != "" {
+		u.User = url.UserPassword(config.Username, config.Password)
+		if config.Password != "" {
+			secret = u.User.String()
+		}
+	}
+
+	var args []string
+	if config.Encoder == "h264_vaapi" {
+		args = append(args, "-vaapi_device", "/dev/dri/renderD128")
+	}
+	transport := config.Transport
+	if transport == "" {
+		transport = "tcp"
+	}
+	args = append(args,
+		"-rtsp_transport", transport,
+		"-timeout", "5000000", // give up after 5s without data so the monitor can restart
+		"-i", u.String(),
+	)
+
+	args = append(args, videoCodecArgs(config.Encoder, config.Bitrate)...)
+	if config.Resync {
+		args = append(args, resyncAudioArgs...)
+	} else {
+		args = append(args, "-c:a", "aac")
+	}
+	args = append(args,
+		"-map", "0:v:0",
+		"-map", "0:a:0?",
+	)
+	return args, secret, nil
+}
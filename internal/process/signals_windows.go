@@ -15,20 +15,16 @@ func (p *Process) Stop() error {
 	}
 	cmd := p.cmd
 	cancel := p.cancel
+	exited := p.exited
 	p.state = StateStopped
 	p.mu.Unlock()
+	p.publishState()
 
 	if cmd.Process != nil {
-		done := make(chan struct{})
-		go func() {
-			cmd.Wait()
-			close(done)
-		}()
-
 		cmd.Process.Kill()
 
 		select {
-		case <-done:
+		case <-exited:
 		case <-time.After(5 * time.Second):
 		}
 	}
@@ -0,0 +1,44 @@
+package process
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StartSRTBonding relays the local SRT stream to the receiver with
+// srt-live-transmit using a broadcast socket group, one member per bind IP.
+// It shares the SRTLA handler's process slot and stats so the rest of the
+// pipeline doesn't care which transport is running.
+func (h *SRTLAHandler) StartSRTBonding(binaryPath string, localPort int, remoteHost string, remotePort int, bindIPs []string) error {
+	h.mu.Lock()
+	h.stats = SRTLAStats{State: SRTLAStarting, Connections: []ConnectionStats{}}
+	h.mu.Unlock()
+
+	var members []string
+	for _, ip := range bindIPs {
+		ip = strings.TrimSpace(ip)
+		if net.ParseIP(ip) == nil {
+			continue
+		}
+		members = append(members, net.JoinHostPort(remoteHost, strconv.Itoa(remotePort)))
+	}
+	if len(members) == 0 {
+		return fmt.Errorf("no valid bind IPs found - cannot start SRT bonding without uplinks")
+	}
+
+	args := []string{
+		fmt.Sprintf("srt://:%d?mode=listener", localPort),
+		fmt.Sprintf("srt:////group?type=broadcast&nodes=%s", strings.Join(members, ",")),
+	}
+
+	h.handleLog(LogLine{
+		Timestamp: time.Now(),
+		Source:    "srtla_send",
+		Line:      fmt.Sprintf("[STARTING] %s %v", binaryPath, formatStartupArgs(args)),
+	})
+
+	return h.proc.Start(binaryPath, args...)
+}
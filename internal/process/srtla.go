@@ -10,6 +10,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"srtla-manager/internal/events"
 )
 
 type SRTLAState string
@@ -42,11 +44,11 @@ type SRTLAStats struct {
 }
 
 type SRTLAHandler struct {
-	proc        *Process
-	mu          sync.RWMutex
-	stats       SRTLAStats
-	logCallback func(LogLine)
-	ipsFile     string
+	proc    *Process
+	mu      sync.RWMutex
+	stats   SRTLAStats
+	bus     *events.Bus
+	ipsFile string
 
 	bitrateRegex *regexp.Regexp
 	connRegex    *regexp.Regexp
@@ -69,13 +71,15 @@ func NewSRTLAHandler() *SRTLAHandler {
 	return h
 }
 
-func (h *SRTLAHandler) SetLogCallback(cb func(LogLine)) {
+// SetBus publishes srtla_send's log lines and state changes on bus
+func (h *SRTLAHandler) SetBus(bus *events.Bus) {
 	h.mu.Lock()
-	defer h.mu.Unlock()
-	h.logCallback = cb
+	h.bus = bus
+	h.mu.Unlock()
+	h.proc.SetBus(bus)
 }
 
-func (h *SRTLAHandler) Start(binaryPath string, localPort int, remoteHost string, remotePort int, bindIPs []string, classic, noQuality, exploration bool) error {
+func (h *SRTLAHandler) Start(binaryPath string, localPort int, remoteHost string, remotePort int, bindIPs []string, classic, noQuality, exploration, srtla2 bool) error {
 	h.mu.Lock()
 	h.stats = SRTLAStats{State: SRTLAStarting, Connections: []ConnectionStats{}}
 	h.mu.Unlock()
@@ -106,6 +110,9 @@ func (h *SRTLAHandler) Start(binaryPath string, localPort int, remoteHost string
 	if exploration {
 		args = append(args, "--exploration")
 	}
+	if srtla2 {
+		args = append(args, "--srtla2")
+	}
 
 	// Log the startup command
 	h.handleLog(LogLine{
@@ -197,12 +204,10 @@ func (h *SRTLAHandler) writeIPsFile(ips []string) error {
 
 func (h *SRTLAHandler) handleLog(log LogLine) {
 	h.mu.Lock()
-	cb := h.logCallback
+	bus := h.bus
 	h.mu.Unlock()
 
-	if cb != nil {
-		cb(log)
-	}
+	events.Publish(bus, TopicLog, log)
 
 	h.parseLogLine(log.Line)
 }
@@ -0,0 +1,185 @@
+package process
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"srtla-manager/internal/events"
+)
+
+type ReceiverState string
+
+const (
+	ReceiverStopped   ReceiverState = "stopped"
+	ReceiverListening ReceiverState = "listening" // waiting for senders
+	ReceiverReceiving ReceiverState = "receiving" // at least one sender connected
+	ReceiverError     ReceiverState = "error"
+)
+
+// ReceiverConnection is one sender link registered with srtla_rec
+type ReceiverConnection struct {
+	Addr  string    `json:"addr"`
+	Group string    `json:"group"`
+	Since time.Time `json:"since"`
+}
+
+type ReceiverStats struct {
+	State       ReceiverState        `json:"state"`
+	Groups      int                  `json:"groups"` // bonded senders
+	Connections []ReceiverConnection `json:"connections"`
+	Registered  int64                `json:"registered"` // links registered since start
+	Removed     int64                `json:"removed"`    // links dropped or timed out since start
+	LastUpdate  time.Time            `json:"last_update"`
+}
+
+// SRTLARecHandler supervises srtla_rec, which accepts bonded SRTLA links
+// from senders and forwards each group as one SRT stream to an SRT server
+type SRTLARecHandler struct {
+	proc  *Process
+	mu    sync.RWMutex
+	stats ReceiverStats
+	conns map[string]ReceiverConnection // addr -> link
+	bus   *events.Bus
+
+	addrRegex  *regexp.Regexp
+	groupRegex *regexp.Regexp
+}
+
+func NewSRTLARecHandler() *SRTLARecHandler {
+	h := &SRTLARecHandler{
+		proc:       New("srtla_rec"),
+		stats:      ReceiverStats{State: ReceiverStopped, Connections: []ReceiverConnection{}},
+		conns:      make(map[string]ReceiverConnection),
+		addrRegex:  regexp.MustCompile(`(\d+\.\d+\.\d+\.\d+:\d+)`),
+		groupRegex: regexp.MustCompile(`(?i)group:?\s*(0x[0-9a-f]+)`),
+	}
+	h.proc.SetLogCallback(h.handleLog)
+	return h
+}
+
+// SetBus publishes srtla_rec's log lines and state changes on bus
+func (h *SRTLARecHandler) SetBus(bus *events.Bus) {
+	h.mu.Lock()
+	h.bus = bus
+	h.mu.Unlock()
+	h.proc.SetBus(bus)
+}
+
+// Start runs srtla_rec listening for senders on listenPort and forwarding to
+// the SRT server at srtHost:srtPort
+func (h *SRTLARecHandler) Start(binaryPath string, listenPort int, srtHost string, srtPort int) error {
+	h.mu.Lock()
+	h.stats = ReceiverStats{State: ReceiverListening, Connections: []ReceiverConnection{}}
+	h.conns = make(map[string]ReceiverConnection)
+	h.mu.Unlock()
+
+	args := []string{
+		strconv.Itoa(listenPort),
+		srtHost,
+		strconv.Itoa(srtPort),
+	}
+	return h.proc.Start(binaryPath, args...)
+}
+
+func (h *SRTLARecHandler) Stop() error {
+	h.mu.Lock()
+	h.stats = ReceiverStats{State: ReceiverStopped, Connections: []ReceiverConnection{}}
+	h.conns = make(map[string]ReceiverConnection)
+	h.mu.Unlock()
+	return h.proc.Stop()
+}
+
+func (h *SRTLARecHandler) Stats() ReceiverStats {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	stats := h.stats
+	stats.Connections = append([]ReceiverConnection(nil), h.stats.Connections...)
+	if h.proc.State() == StateError {
+		stats.State = ReceiverError
+	}
+	return stats
+}
+
+func (h *SRTLARecHandler) ProcessState() State {
+	return h.proc.State()
+}
+
+// LastError returns why srtla_rec last exited, if it failed
+func (h *SRTLARecHandler) LastError() string {
+	return h.proc.LastError()
+}
+
+func (h *SRTLARecHandler) handleLog(log LogLine) {
+	h.mu.Lock()
+	bus := h.bus
+	h.mu.Unlock()
+
+	events.Publish(bus, TopicLog, log)
+
+	h.parseLogLine(log.Line)
+}
+
+// parseLogLine follows links registering and going away. srtla_rec logs
+// them as "<addr> (group <ptr>): connection registered" and "... removed";
+// a group removed as inactive takes its links with it.
+func (h *SRTLARecHandler) parseLogLine(line string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	lower := strings.ToLower(line)
+	addr := ""
+	if m := h.addrRegex.FindStringSubmatch(line); m != nil {
+		addr = m[1]
+	}
+	group := ""
+	if m := h.groupRegex.FindStringSubmatch(line); m != nil {
+		group = strings.ToLower(m[1])
+	}
+
+	changed := false
+	switch {
+	case strings.Contains(lower, "registered") && addr != "":
+		if _, ok := h.conns[addr]; !ok {
+			h.conns[addr] = ReceiverConnection{Addr: addr, Group: group, Since: time.Now()}
+			h.stats.Registered++
+			changed = true
+		}
+	case strings.Contains(lower, "removed") && addr != "":
+		if _, ok := h.conns[addr]; ok {
+			delete(h.conns, addr)
+			h.stats.Removed++
+			changed = true
+		}
+	case strings.Contains(lower, "removed") && group != "":
+		for a, c := range h.conns {
+			if c.Group == group {
+				delete(h.conns, a)
+				h.stats.Removed++
+				changed = true
+			}
+		}
+	}
+	if !changed {
+		return
+	}
+
+	groups := map[string]bool{}
+	conns := make([]ReceiverConnection, 0, len(h.conns))
+	for _, c := range h.conns {
+		conns = append(conns, c)
+		groups[c.Group] = true
+	}
+	sort.Slice(conns, func(i, j int) bool { return conns[i].Since.Before(conns[j].Since) })
+
+	h.stats.Connections = conns
+	h.stats.Groups = len(groups)
+	h.stats.State = ReceiverListening
+	if len(conns) > 0 {
+		h.stats.State = ReceiverReceiving
+	}
+	h.stats.LastUpdate = time.Now()
+}
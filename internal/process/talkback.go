@@ -0,0 +1,114 @@
+package process
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"srtla-manager/internal/events"
+)
+
+// TalkbackConfig describes the director's return audio and where it plays
+type TalkbackConfig struct {
+	Port        int     // UDP port the RTP stream arrives on
+	PayloadType int     // RTP payload type the sender uses for Opus
+	Device      string  // ALSA playback device
+	Volume      float64 // linear gain applied before playout
+}
+
+// TalkbackStatus reports the talkback player
+type TalkbackStatus struct {
+	State       State   `json:"state"`
+	LastError   string  `json:"last_error,omitempty"`
+	Uptime      float64 `json:"uptime_seconds"`
+	Port        int     `json:"port,omitempty"`
+	PayloadType int     `json:"payload_type,omitempty"`
+	Device      string  `json:"device,omitempty"`
+	Volume      float64 `json:"volume,omitempty"`
+}
+
+// TalkbackHandler plays an incoming RTP/Opus stream out of a local audio
+// device with its own ffmpeg, independent of the video pipeline
+type TalkbackHandler struct {
+	proc *Process
+	mu   sync.RWMutex
+	cfg  TalkbackConfig
+}
+
+func NewTalkbackHandler() *TalkbackHandler {
+	return &TalkbackHandler{proc: New("talkback")}
+}
+
+// SetBus publishes the talkback player's log lines and state changes on bus
+func (h *TalkbackHandler) SetBus(bus *events.Bus) {
+	h.proc.SetLogCallback(func(line LogLine) { events.Publish(bus, TopicLog, line) })
+	h.proc.SetBus(bus)
+}
+
+// TalkbackSDP describes the stream the player expects. Senders use the same
+// description with host set to an address of this unit.
+func TalkbackSDP(cfg TalkbackConfig, host string) string {
+	pt := strconv.Itoa(cfg.PayloadType)
+	return "v=0\r\n" +
+		"o=- 0 0 IN IP4 " + host + "\r\n" +
+		"s=srtla-manager talkback\r\n" +
+		"c=IN IP4 " + host + "\r\n" +
+		"t=0 0\r\n" +
+		"m=audio " + strconv.Itoa(cfg.Port) + " RTP/AVP " + pt + "\r\n" +
+		"a=rtpmap:" + pt + " opus/48000/2\r\n" +
+		"a=recvonly\r\n"
+}
+
+// Start listens for the talkback stream and plays it out
+func (h *TalkbackHandler) Start(cfg TalkbackConfig) error {
+	sdpPath := filepath.Join(os.TempDir(), "srtla-talkback.sdp")
+	if err := os.WriteFile(sdpPath, []byte(TalkbackSDP(cfg, "0.0.0.0")), 0644); err != nil {
+		return fmt.Errorf("failed to write talkback SDP: %w", err)
+	}
+
+	h.mu.Lock()
+	h.cfg = cfg
+	h.mu.Unlock()
+
+	// Keep buffering to a minimum: cues are only useful if they arrive on time
+	args := []string{
+		"-hide_banner",
+		"-loglevel", "warning",
+		"-protocol_whitelist", "file,udp,rtp",
+		"-fflags", "nobuffer",
+		"-flags", "low_delay",
+		"-probesize", "32",
+		"-analyzeduration", "0",
+		"-i", sdpPath,
+		"-af", fmt.Sprintf("volume=%.2f", cfg.Volume),
+		"-f", "alsa",
+		cfg.Device,
+	}
+
+	return h.proc.Start("ffmpeg", args...)
+}
+
+func (h *TalkbackHandler) Stop() error {
+	return h.proc.Stop()
+}
+
+func (h *TalkbackHandler) Status() TalkbackStatus {
+	h.mu.RLock()
+	cfg := h.cfg
+	h.mu.RUnlock()
+
+	status := TalkbackStatus{
+		State:     h.proc.State(),
+		LastError: h.proc.LastError(),
+		Uptime:    h.proc.Uptime().Seconds(),
+	}
+	if status.State == StateRunning {
+		status.Port = cfg.Port
+		status.PayloadType = cfg.PayloadType
+		status.Device = cfg.Device
+		status.Volume = cfg.Volume
+	}
+	return status
+}
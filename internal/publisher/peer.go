@@ -0,0 +1,75 @@
+package publisher
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// tcpEstablished is the connection state in /proc/net/tcp
+const tcpEstablished = "01"
+
+// Peer returns the address of the client connected to local TCP port, or ""
+// when there is none or the kernel's tables can't be read (outside Linux)
+func Peer(port int) string {
+	for _, table := range []string{"/proc/net/tcp", "/proc/net/tcp6"} {
+		data, err := os.ReadFile(table)
+		if err != nil {
+			continue
+		}
+		if addr := peerFromTable(data, port); addr != "" {
+			return addr
+		}
+	}
+	return ""
+}
+
+// peerFromTable finds an established connection on the local port in the
+// contents of /proc/net/tcp or tcp6
+func peerFromTable(data []byte, port int) string {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Scan() // header
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || fields[3] != tcpEstablished {
+			continue
+		}
+		_, localPort, ok := parseHexAddr(fields[1])
+		if !ok || localPort != port {
+			continue
+		}
+		if ip, _, ok := parseHexAddr(fields[2]); ok {
+			if v4 := ip.To4(); v4 != nil {
+				ip = v4
+			}
+			return ip.String()
+		}
+	}
+	return ""
+}
+
+// parseHexAddr decodes an address such as 0100007F:0793. The IP is stored
+// as 32-bit words in host (little endian) order.
+func parseHexAddr(s string) (net.IP, int, bool) {
+	hexIP, hexPort, found := strings.Cut(s, ":")
+	if !found {
+		return nil, 0, false
+	}
+	port, err := strconv.ParseUint(hexPort, 16, 16)
+	if err != nil {
+		return nil, 0, false
+	}
+	raw, err := hex.DecodeString(hexIP)
+	if err != nil || (len(raw) != net.IPv4len && len(raw) != net.IPv6len) {
+		return nil, 0, false
+	}
+	ip := make(net.IP, len(raw))
+	for i := 0; i < len(raw); i += 4 {
+		ip[i], ip[i+1], ip[i+2], ip[i+3] = raw[i+3], raw[i+2], raw[i+1], raw[i]
+	}
+	return ip, int(port), true
+}
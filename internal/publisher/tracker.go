@@ -0,0 +1,208 @@
+// Package publisher tracks the camera connections to the RTMP listener.
+// ffmpeg exits when its publisher goes away, so a camera on flaky WiFi
+// shows up as a run of short sessions; the tracker tells a publisher that
+// dropped and may come straight back from one that has gone, and counts
+// each client's reconnects.
+package publisher
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// FlapWindow is how far back drops count towards flapping
+	FlapWindow = time.Minute
+	// FlapDrops is the number of drops within FlapWindow that marks a
+	// client as flapping
+	FlapDrops = 3
+)
+
+// Session is one publisher connection
+type Session struct {
+	Addr           string     `json:"addr"`
+	ConnectedAt    time.Time  `json:"connected_at"`
+	DisconnectedAt *time.Time `json:"disconnected_at,omitempty"`
+	Dropped        bool       `json:"dropped"`   // the connection was lost rather than stopped
+	Reconnect      bool       `json:"reconnect"` // it came back within the grace period
+}
+
+// Client counts the sessions from one address
+type Client struct {
+	Addr          string    `json:"addr"`
+	Connects      int       `json:"connects"`
+	Reconnects    int       `json:"reconnects"`
+	Drops         int       `json:"drops"`
+	LastConnected time.Time `json:"last_connected"`
+	LastDropped   time.Time `json:"last_dropped,omitempty"`
+	Flapping      bool      `json:"flapping"`
+
+	recentDrops []time.Time
+}
+
+// Status is a snapshot of the tracker
+type Status struct {
+	Connected    bool       `json:"connected"`
+	Current      *Session   `json:"current,omitempty"`
+	GraceSeconds int        `json:"grace_seconds"`
+	GraceUntil   *time.Time `json:"grace_until,omitempty"` // set while waiting for a dropped publisher
+	Clients      []Client   `json:"clients"`
+	History      []Session  `json:"history"` // newest first
+}
+
+// Tracker records publisher sessions
+type Tracker struct {
+	mu          sync.Mutex
+	grace       time.Duration
+	historySize int
+	current     *Session
+	dropped     *Session // last session, while within the grace period
+	history     []Session
+	clients     map[string]*Client
+}
+
+// NewTracker keeps the last historySize sessions. A publisher that returns
+// within grace of dropping counts as a reconnect.
+func NewTracker(grace time.Duration, historySize int) *Tracker {
+	return &Tracker{
+		grace:       grace,
+		historySize: historySize,
+		clients:     make(map[string]*Client),
+	}
+}
+
+// SetGrace changes the grace period; 0 turns it off
+func (t *Tracker) SetGrace(grace time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.grace = grace
+}
+
+// Connected records a publisher connecting from addr and reports whether it
+// is a reconnect
+func (t *Tracker) Connected(addr string, at time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.current != nil {
+		t.closeLocked(at, false)
+	}
+
+	reconnect := t.inGraceLocked(at)
+	t.dropped = nil
+	t.current = &Session{Addr: addr, ConnectedAt: at, Reconnect: reconnect}
+
+	c := t.client(addr)
+	c.Connects++
+	c.LastConnected = at
+	if reconnect {
+		c.Reconnects++
+	}
+	return reconnect
+}
+
+// Dropped records the publisher's connection being lost
+func (t *Tracker) Dropped(at time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.current == nil {
+		return
+	}
+	session := t.closeLocked(at, true)
+	t.dropped = &session
+
+	c := t.client(session.Addr)
+	c.Drops++
+	c.LastDropped = at
+	c.recentDrops = append(c.recentDrops, at)
+}
+
+// Stopped records the listener being stopped on purpose; there is no grace
+// period afterwards
+func (t *Tracker) Stopped(at time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.current != nil {
+		t.closeLocked(at, false)
+	}
+	t.dropped = nil
+}
+
+// InGrace reports whether a publisher dropped less than the grace period
+// ago and hasn't come back yet
+func (t *Tracker) InGrace(now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.inGraceLocked(now)
+}
+
+func (t *Tracker) inGraceLocked(now time.Time) bool {
+	return t.dropped != nil && t.grace > 0 && now.Sub(*t.dropped.DisconnectedAt) < t.grace
+}
+
+// Status returns the current session, per-client counts and the history
+func (t *Tracker) Status(now time.Time) Status {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	status := Status{
+		Connected:    t.current != nil,
+		GraceSeconds: int(t.grace.Seconds()),
+		Clients:      []Client{},
+		History:      make([]Session, 0, len(t.history)),
+	}
+	if t.current != nil {
+		current := *t.current
+		status.Current = &current
+	}
+	if t.inGraceLocked(now) {
+		until := t.dropped.DisconnectedAt.Add(t.grace)
+		status.GraceUntil = &until
+	}
+	for _, c := range t.clients {
+		c.recentDrops = trimBefore(c.recentDrops, now.Add(-FlapWindow))
+		client := *c
+		client.Flapping = len(c.recentDrops) >= FlapDrops
+		client.recentDrops = nil
+		status.Clients = append(status.Clients, client)
+	}
+	sort.Slice(status.Clients, func(i, j int) bool {
+		return status.Clients[i].LastConnected.After(status.Clients[j].LastConnected)
+	})
+	for i := len(t.history) - 1; i >= 0; i-- {
+		status.History = append(status.History, t.history[i])
+	}
+	return status
+}
+
+// closeLocked ends the current session and adds it to the history
+func (t *Tracker) closeLocked(at time.Time, dropped bool) Session {
+	session := *t.current
+	session.DisconnectedAt = &at
+	session.Dropped = dropped
+	t.current = nil
+
+	t.history = append(t.history, session)
+	if len(t.history) > t.historySize {
+		t.history = t.history[len(t.history)-t.historySize:]
+	}
+	return session
+}
+
+func (t *Tracker) client(addr string) *Client {
+	c, ok := t.clients[addr]
+	if !ok {
+		c = &Client{Addr: addr}
+		t.clients[addr] = c
+	}
+	return c
+}
+
+func trimBefore(times []time.Time, cutoff time.Time) []time.Time {
+	i := 0
+	for i < len(times) && times[i].Before(cutoff) {
+		i++
+	}
+	return times[i:]
+}
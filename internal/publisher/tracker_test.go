@@ -0,0 +1,89 @@
+package publisher
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReconnectWithinGrace(t *testing.T) {
+	tr := NewTracker(10*time.Second, 10)
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	if tr.Connected("192.168.4.2", start) {
+		t.Error("first connection counted as a reconnect")
+	}
+	tr.Dropped(start.Add(time.Minute))
+	if !tr.InGrace(start.Add(time.Minute + 5*time.Second)) {
+		t.Error("not in grace 5s after the drop")
+	}
+	if !tr.Connected("192.168.4.2", start.Add(time.Minute+5*time.Second)) {
+		t.Error("return within grace not counted as a reconnect")
+	}
+
+	tr.Dropped(start.Add(2 * time.Minute))
+	if tr.InGrace(start.Add(2*time.Minute + 11*time.Second)) {
+		t.Error("still in grace after it ran out")
+	}
+	if tr.Connected("192.168.4.2", start.Add(3*time.Minute)) {
+		t.Error("return after grace counted as a reconnect")
+	}
+
+	status := tr.Status(start.Add(3 * time.Minute))
+	if len(status.Clients) != 1 {
+		t.Fatalf("got %d clients, want 1", len(status.Clients))
+	}
+	c := status.Clients[0]
+	if c.Connects != 3 || c.Reconnects != 1 || c.Drops != 2 {
+		t.Errorf("got %+v", c)
+	}
+	if len(status.History) != 2 || !status.History[0].Dropped || status.Current == nil {
+		t.Errorf("got history %+v, current %+v", status.History, status.Current)
+	}
+}
+
+func TestStoppedHasNoGrace(t *testing.T) {
+	tr := NewTracker(10*time.Second, 10)
+	now := time.Now()
+	tr.Connected("10.0.0.5", now)
+	tr.Stopped(now.Add(time.Second))
+	if tr.InGrace(now.Add(2 * time.Second)) {
+		t.Error("in grace after a deliberate stop")
+	}
+	if h := tr.Status(now).History; len(h) != 1 || h[0].Dropped {
+		t.Errorf("got history %+v", h)
+	}
+}
+
+func TestFlapping(t *testing.T) {
+	tr := NewTracker(10*time.Second, 2)
+	now := time.Now()
+	for i := 0; i < FlapDrops; i++ {
+		at := now.Add(time.Duration(i) * 10 * time.Second)
+		tr.Connected("10.0.0.5", at)
+		tr.Dropped(at.Add(time.Second))
+	}
+
+	status := tr.Status(now.Add(30 * time.Second))
+	if !status.Clients[0].Flapping {
+		t.Error("client not flapping after three drops in a minute")
+	}
+	if len(status.History) != 2 {
+		t.Errorf("history kept %d sessions, want 2", len(status.History))
+	}
+	if tr.Status(now.Add(5 * time.Minute)).Clients[0].Flapping {
+		t.Error("client still flapping minutes later")
+	}
+}
+
+func TestPeerFromTable(t *testing.T) {
+	table := `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000:078F 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1 1 0000000000000000 100 0 0 10 0
+   1: 0104A8C0:078F 0204A8C0:C350 01 00000000:00000000 00:00000000 00000000     0        0 2 1 0000000000000000 20 4 30 10 -1
+`
+	if got := peerFromTable([]byte(table), 1935); got != "192.168.4.2" {
+		t.Errorf("got %q, want 192.168.4.2", got)
+	}
+	if got := peerFromTable([]byte(table), 1936); got != "" {
+		t.Errorf("got %q for an unused port", got)
+	}
+}
@@ -0,0 +1,173 @@
+// Package quota tracks how much data each modem has used in its billing
+// period, from the growth of its byte counters, and keeps the totals on
+// disk so a restart doesn't forget them.
+package quota
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Period is how often a budget starts again
+type Period string
+
+const (
+	Daily   Period = "daily"
+	Monthly Period = "monthly"
+)
+
+// Periods lists the values accepted for a quota's period
+var Periods = []string{string(Daily), string(Monthly)}
+
+// Start returns when the period containing now began. Monthly periods
+// begin on resetDay, 1 to 28; 0 is the 1st.
+func Start(p Period, resetDay int, now time.Time) time.Time {
+	y, m, d := now.Date()
+	if p == Daily {
+		return time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+	}
+	if resetDay < 1 {
+		resetDay = 1
+	}
+	if d < resetDay {
+		m--
+	}
+	return time.Date(y, m, resetDay, 0, 0, 0, 0, now.Location())
+}
+
+// Usage is a modem's data use in its current period
+type Usage struct {
+	PeriodStart time.Time `json:"period_start"`
+	Bytes       int64     `json:"bytes"` // sent and received
+	LastTx      int64     `json:"last_tx"`
+	LastRx      int64     `json:"last_rx"`
+}
+
+// Tracker adds up each modem's usage, by IMEI
+type Tracker struct {
+	path string
+
+	mu    sync.Mutex
+	usage map[string]*Usage
+	dirty bool
+}
+
+// Open loads the usage kept at path
+func Open(path string) (*Tracker, error) {
+	t := &Tracker{path: path, usage: make(map[string]*Usage)}
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &t.usage); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+	}
+	return t, nil
+}
+
+// Record adds the growth of a modem's counters since its last reading and
+// returns its usage. A new period starts from zero. Counters that went
+// back, because the modem reconnected, count from zero again; the first
+// reading of a modem only sets where counting starts.
+func (t *Tracker) Record(imei string, tx, rx int64, p Period, resetDay int, now time.Time) Usage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	start := Start(p, resetDay, now)
+	u, ok := t.usage[imei]
+	if !ok {
+		u = &Usage{PeriodStart: start, LastTx: tx, LastRx: rx}
+		t.usage[imei] = u
+		t.dirty = true
+		return *u
+	}
+	if !u.PeriodStart.Equal(start) {
+		u.PeriodStart = start
+		u.Bytes = 0
+		t.dirty = true
+	}
+
+	delta := growth(u.LastTx, tx) + growth(u.LastRx, rx)
+	if delta > 0 || tx != u.LastTx || rx != u.LastRx {
+		u.Bytes += delta
+		u.LastTx, u.LastRx = tx, rx
+		t.dirty = true
+	}
+	return *u
+}
+
+func growth(last, now int64) int64 {
+	if now < last {
+		return now
+	}
+	return now - last
+}
+
+// Usage returns a modem's usage
+func (t *Tracker) Usage(imei string) (Usage, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	u, ok := t.usage[imei]
+	if !ok {
+		return Usage{}, false
+	}
+	return *u, true
+}
+
+// Reset zeroes a modem's usage for the rest of its period, e.g. after a
+// top-up
+func (t *Tracker) Reset(imei string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if u, ok := t.usage[imei]; ok {
+		u.Bytes = 0
+		t.dirty = true
+	}
+}
+
+// Save writes the usage to disk if it changed, through a temporary file
+// so a power loss leaves the old or the new totals
+func (t *Tracker) Save() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.dirty {
+		return nil
+	}
+
+	data, err := json.Marshal(t.usage)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(t.path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", t.path, err)
+	}
+	tmp := t.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, t.path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", t.path, err)
+	}
+	t.dirty = false
+	return nil
+}
+
+// Crossed returns the percentages of limit among thresholds that usage
+// passed in going from before to after, lowest first
+func Crossed(thresholds []int, limit, before, after int64) []int {
+	var crossed []int
+	for _, pct := range thresholds {
+		at := limit * int64(pct) / 100
+		if before < at && after >= at {
+			crossed = append(crossed, pct)
+		}
+	}
+	return crossed
+}
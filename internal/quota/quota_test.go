@@ -0,0 +1,80 @@
+package quota
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestStart(t *testing.T) {
+	at := time.Date(2026, 3, 4, 15, 30, 0, 0, time.UTC)
+	cases := []struct {
+		p        Period
+		resetDay int
+		want     time.Time
+	}{
+		{Daily, 0, time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC)},
+		{Monthly, 0, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{Monthly, 4, time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC)},
+		{Monthly, 15, time.Date(2026, 2, 15, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, c := range cases {
+		if got := Start(c.p, c.resetDay, at); !got.Equal(c.want) {
+			t.Errorf("Start(%s, %d) = %v, want %v", c.p, c.resetDay, got, c.want)
+		}
+	}
+	if got := Start(Monthly, 20, time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)); !got.Equal(time.Date(2025, 12, 20, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected a January date before the reset day to fall in December's period, got %v", got)
+	}
+}
+
+func TestTracker(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.json")
+	tr, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	day := time.Date(2026, 3, 4, 10, 0, 0, 0, time.UTC)
+
+	if u := tr.Record("356938035643809", 1000, 5000, Daily, 0, day); u.Bytes != 0 {
+		t.Errorf("Expected the first reading to count nothing, got %d", u.Bytes)
+	}
+	if u := tr.Record("356938035643809", 1500, 6000, Daily, 0, day.Add(time.Minute)); u.Bytes != 1500 {
+		t.Errorf("Bytes = %d, want 1500", u.Bytes)
+	}
+	// the modem reconnected and its counters started again
+	if u := tr.Record("356938035643809", 200, 300, Daily, 0, day.Add(2*time.Minute)); u.Bytes != 2000 {
+		t.Errorf("Bytes = %d after a counter reset, want 2000", u.Bytes)
+	}
+	if err := tr.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	tr, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u, ok := tr.Usage("356938035643809"); !ok || u.Bytes != 2000 {
+		t.Fatalf("Expected the usage to survive reopening, got %+v", u)
+	}
+	if u := tr.Record("356938035643809", 300, 300, Daily, 0, day.Add(24*time.Hour)); u.Bytes != 100 {
+		t.Errorf("Expected a new day to start from zero, got %d", u.Bytes)
+	}
+	tr.Reset("356938035643809")
+	if u, _ := tr.Usage("356938035643809"); u.Bytes != 0 {
+		t.Errorf("Bytes = %d after Reset", u.Bytes)
+	}
+}
+
+func TestCrossed(t *testing.T) {
+	if got := Crossed([]int{80, 90, 100}, 1000, 700, 950); !slices.Equal(got, []int{80, 90}) {
+		t.Errorf("Crossed() = %v, want [80 90]", got)
+	}
+	if got := Crossed([]int{80, 90, 100}, 1000, 950, 1000); !slices.Equal(got, []int{100}) {
+		t.Errorf("Crossed() = %v, want [100]", got)
+	}
+	if got := Crossed([]int{80}, 1000, 850, 900); got != nil {
+		t.Errorf("Expected nothing crossed twice, got %v", got)
+	}
+}
@@ -0,0 +1,220 @@
+package relay
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Base URLs of the provider APIs
+const (
+	DigitalOceanAPI = "https://api.digitalocean.com/v2"
+	HetznerAPI      = "https://api.hetzner.cloud/v1"
+)
+
+// Defaults for servers created at the providers: the smallest plan, which
+// relays a few streams comfortably
+const (
+	DefaultDigitalOceanRegion = "fra1"
+	DefaultDigitalOceanSize   = "s-1vcpu-1gb"
+	DefaultDigitalOceanImage  = "ubuntu-24-04-x64"
+	DefaultHetznerLocation    = "fsn1"
+	DefaultHetznerType        = "cx22"
+	DefaultHetznerImage       = "ubuntu-24.04"
+)
+
+// pollInterval is how often a new server is checked for its address
+const pollInterval = 5 * time.Second
+
+var client = &http.Client{Timeout: 30 * time.Second}
+
+// Cloud creates servers at a provider
+type Cloud interface {
+	// Create starts a server that runs userData as it first boots
+	Create(ctx context.Context, name, userData string) (id string, err error)
+	// Address returns the server's public IPv4 address once it is running,
+	// or "" until then
+	Address(ctx context.Context, id string) (string, error)
+}
+
+// Provision creates a server that installs the relay and waits for its
+// address. The relay is still being installed when it returns; that takes a
+// few minutes more.
+func Provision(ctx context.Context, c Cloud, name string, s Settings) (id, host string, err error) {
+	id, err = c.Create(ctx, name, Script(s))
+	if err != nil {
+		return "", "", err
+	}
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		host, err = c.Address(ctx, id)
+		if err != nil || host != "" {
+			return id, host, err
+		}
+		select {
+		case <-ctx.Done():
+			return id, "", fmt.Errorf("server %s didn't come up: %w", id, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// DigitalOcean creates droplets
+type DigitalOcean struct {
+	Token   string
+	Region  string
+	Size    string
+	Image   string
+	BaseURL string // DigitalOceanAPI when empty
+}
+
+func (d DigitalOcean) Create(ctx context.Context, name, userData string) (string, error) {
+	body := map[string]any{
+		"name":      name,
+		"region":    or(d.Region, DefaultDigitalOceanRegion),
+		"size":      or(d.Size, DefaultDigitalOceanSize),
+		"image":     or(d.Image, DefaultDigitalOceanImage),
+		"user_data": userData,
+		"tags":      []string{"srtla-relay"},
+	}
+	var resp struct {
+		Droplet struct {
+			ID int64 `json:"id"`
+		} `json:"droplet"`
+	}
+	if err := call(ctx, http.MethodPost, or(d.BaseURL, DigitalOceanAPI)+"/droplets", d.Token, body, &resp); err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(resp.Droplet.ID, 10), nil
+}
+
+func (d DigitalOcean) Address(ctx context.Context, id string) (string, error) {
+	var resp struct {
+		Droplet struct {
+			Status   string `json:"status"`
+			Networks struct {
+				V4 []struct {
+					IPAddress string `json:"ip_address"`
+					Type      string `json:"type"`
+				} `json:"v4"`
+			} `json:"networks"`
+		} `json:"droplet"`
+	}
+	if err := call(ctx, http.MethodGet, or(d.BaseURL, DigitalOceanAPI)+"/droplets/"+id, d.Token, nil, &resp); err != nil {
+		return "", err
+	}
+	if resp.Droplet.Status != "active" {
+		return "", nil
+	}
+	for _, n := range resp.Droplet.Networks.V4 {
+		if n.Type == "public" {
+			return n.IPAddress, nil
+		}
+	}
+	return "", nil
+}
+
+// Hetzner creates Hetzner Cloud servers
+type Hetzner struct {
+	Token      string
+	Location   string
+	ServerType string
+	Image      string
+	BaseURL    string // HetznerAPI when empty
+}
+
+func (h Hetzner) Create(ctx context.Context, name, userData string) (string, error) {
+	body := map[string]any{
+		"name":        name,
+		"location":    or(h.Location, DefaultHetznerLocation),
+		"server_type": or(h.ServerType, DefaultHetznerType),
+		"image":       or(h.Image, DefaultHetznerImage),
+		"user_data":   userData,
+		"labels":      map[string]string{"role": "srtla-relay"},
+	}
+	var resp struct {
+		Server struct {
+			ID int64 `json:"id"`
+		} `json:"server"`
+	}
+	if err := call(ctx, http.MethodPost, or(h.BaseURL, HetznerAPI)+"/servers", h.Token, body, &resp); err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(resp.Server.ID, 10), nil
+}
+
+func (h Hetzner) Address(ctx context.Context, id string) (string, error) {
+	var resp struct {
+		Server struct {
+			Status    string `json:"status"`
+			PublicNet struct {
+				IPv4 struct {
+					IP string `json:"ip"`
+				} `json:"ipv4"`
+			} `json:"public_net"`
+		} `json:"server"`
+	}
+	if err := call(ctx, http.MethodGet, or(h.BaseURL, HetznerAPI)+"/servers/"+id, h.Token, nil, &resp); err != nil {
+		return "", err
+	}
+	if resp.Server.Status != "running" {
+		return "", nil
+	}
+	return resp.Server.PublicNet.IPv4.IP, nil
+}
+
+// call sends body as JSON with the bearer token and decodes the answer
+// into out. A refusal is returned with the provider's message.
+func call(ctx context.Context, method, url, token string, body, out any) error {
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, r)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		var e struct {
+			Message string `json:"message"` // DigitalOcean
+			Error   struct {
+				Message string `json:"message"`
+			} `json:"error"` // Hetzner
+		}
+		json.Unmarshal(data, &e)
+		msg := or(e.Message, e.Error.Message)
+		if msg == "" {
+			msg = resp.Status
+		}
+		return fmt.Errorf("%s %s: %s", method, url, msg)
+	}
+	return json.Unmarshal(data, out)
+}
+
+func or(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
@@ -0,0 +1,170 @@
+// Package relay provisions the receive side of a bonded stream on a remote
+// server: srtla_rec gathers the links and hands the stream to an SRT
+// listener that the studio pulls from. The server is either a VPS reached
+// over SSH or one created for the purpose through a cloud provider's API,
+// which installs the relay with cloud-init as it boots.
+package relay
+
+import (
+	"crypto/rand"
+	"fmt"
+	"strings"
+)
+
+// Methods of reaching the server
+const (
+	MethodSSH          = "ssh"
+	MethodDigitalOcean = "digitalocean"
+	MethodHetzner      = "hetzner"
+)
+
+// Methods lists the valid methods
+var Methods = []string{MethodSSH, MethodDigitalOcean, MethodHetzner}
+
+// Defaults for the zero values of Settings
+const (
+	DefaultSRTLAPort  = 5000
+	DefaultOutputPort = 4000
+)
+
+// outputKeyLength is the AES key length in bytes the SRT output is
+// encrypted with
+const outputKeyLength = 32
+
+// internalSRTPort is where srtla_rec hands the stream to srt-live-transmit
+// on the server
+const internalSRTPort = 4001
+
+// srtlaRepo is built on the server for srtla_rec
+const srtlaRepo = "https://github.com/BELABOX/srtla.git"
+
+// Settings are the ports of the relay and the passphrase of its output
+type Settings struct {
+	SRTLAPort  int    // UDP port srtla_rec listens on for the links
+	OutputPort int    // UDP port the studio pulls the SRT stream from
+	Passphrase string // SRT passphrase of the output; unencrypted when empty
+}
+
+// NewSettings returns the settings for a new relay, with a random
+// passphrase so only the studio given the output URL can pull the stream
+func NewSettings(srtlaPort, outputPort int) Settings {
+	s := Settings{SRTLAPort: srtlaPort, OutputPort: outputPort, Passphrase: rand.Text()}
+	return s.withDefaults()
+}
+
+func (s Settings) withDefaults() Settings {
+	if s.SRTLAPort == 0 {
+		s.SRTLAPort = DefaultSRTLAPort
+	}
+	if s.OutputPort == 0 {
+		s.OutputPort = DefaultOutputPort
+	}
+	return s
+}
+
+// encryption returns the SRT URL parameters encrypting the output, or ""
+// for none
+func (s Settings) encryption() string {
+	if s.Passphrase == "" {
+		return ""
+	}
+	return fmt.Sprintf("&passphrase=%s&pbkeylen=%d", s.Passphrase, outputKeyLength)
+}
+
+// Result is a provisioned relay
+type Result struct {
+	Method     string `json:"method"`
+	Host       string `json:"host"`                // public address of the server
+	ServerID   string `json:"server_id,omitempty"` // at the cloud provider
+	SRTLAPort  int    `json:"srtla_port"`
+	OutputURL  string `json:"output_url"`        // for the studio to pull, passphrase included
+	Passphrase string `json:"passphrase"`        // of the output
+	Installing bool   `json:"installing"`        // cloud-init is still installing the relay
+	Applied    bool   `json:"applied,omitempty"` // set as srtla.remote_host and remote_port
+}
+
+// NewResult describes the relay on host
+func NewResult(method, host string, s Settings) Result {
+	s = s.withDefaults()
+	return Result{
+		Method:     method,
+		Host:       host,
+		SRTLAPort:  s.SRTLAPort,
+		OutputURL:  fmt.Sprintf("srt://%s:%d?mode=caller%s", host, s.OutputPort, s.encryption()),
+		Passphrase: s.Passphrase,
+	}
+}
+
+// Script is the shell script that installs the relay on a Debian or Ubuntu
+// server as root. It can be run again to update or change the ports.
+func Script(s Settings) string {
+	s = s.withDefaults()
+	r := strings.NewReplacer(
+		"{{SRTLA_PORT}}", fmt.Sprint(s.SRTLAPort),
+		"{{OUTPUT_PORT}}", fmt.Sprint(s.OutputPort),
+		"{{INTERNAL_PORT}}", fmt.Sprint(internalSRTPort),
+		"{{SRTLA_REPO}}", srtlaRepo,
+		"{{ENCRYPTION}}", s.encryption(),
+	)
+	return r.Replace(scriptTemplate)
+}
+
+const scriptTemplate = `#!/bin/bash
+set -euo pipefail
+export DEBIAN_FRONTEND=noninteractive
+
+echo "Installing packages"
+apt-get update -q
+apt-get install -y -q build-essential git srt-tools
+
+echo "Building srtla_rec"
+rm -rf /usr/local/src/srtla
+git clone --depth 1 {{SRTLA_REPO}} /usr/local/src/srtla
+make -C /usr/local/src/srtla srtla_rec
+install -m 0755 /usr/local/src/srtla/srtla_rec /usr/local/bin/srtla_rec
+
+echo "Installing services"
+cat > /etc/systemd/system/srtla-rec.service <<'EOF'
+[Unit]
+Description=SRTLA receiver
+After=network-online.target srt-relay.service
+Wants=network-online.target
+
+[Service]
+ExecStart=/usr/local/bin/srtla_rec {{SRTLA_PORT}} 127.0.0.1 {{INTERNAL_PORT}}
+Restart=always
+RestartSec=2
+DynamicUser=yes
+
+[Install]
+WantedBy=multi-user.target
+EOF
+
+cat > /etc/systemd/system/srt-relay.service <<'EOF'
+[Unit]
+Description=SRT relay for the bonded stream
+After=network-online.target
+Wants=network-online.target
+
+[Service]
+ExecStart=/usr/bin/srt-live-transmit -a:yes srt://127.0.0.1:{{INTERNAL_PORT}}?mode=listener "srt://:{{OUTPUT_PORT}}?mode=listener{{ENCRYPTION}}"
+Restart=always
+RestartSec=2
+DynamicUser=yes
+
+[Install]
+WantedBy=multi-user.target
+EOF
+# the unit holds the passphrase
+chmod 600 /etc/systemd/system/srt-relay.service
+
+if command -v ufw >/dev/null && ufw status | grep -q active; then
+	ufw allow {{SRTLA_PORT}}/udp
+	ufw allow {{OUTPUT_PORT}}/udp
+fi
+
+systemctl daemon-reload
+systemctl enable srt-relay.service srtla-rec.service
+systemctl restart srt-relay.service srtla-rec.service
+echo "Relay ready: SRTLA on UDP {{SRTLA_PORT}}, SRT out on UDP {{OUTPUT_PORT}}"
+`
@@ -0,0 +1,132 @@
+package relay
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestScript(t *testing.T) {
+	script := Script(Settings{SRTLAPort: 5050, Passphrase: "secretsecret"})
+	for _, want := range []string{
+		"srtla_rec 5050 127.0.0.1 4001",
+		`"srt://:4000?mode=listener&passphrase=secretsecret&pbkeylen=32"`,
+		"chmod 600 /etc/systemd/system/srt-relay.service",
+		"ufw allow 5050/udp",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("Expected the script to contain %q", want)
+		}
+	}
+	if strings.Contains(script, "{{") {
+		t.Error("Expected every placeholder replaced")
+	}
+}
+
+func TestSSHArgs(t *testing.T) {
+	args := strings.Join(SSH{Host: "relay.example.com", User: "deploy", KeyFile: "/home/srtla/.ssh/id_ed25519"}.args(), " ")
+	for _, want := range []string{"-p 22", "BatchMode=yes", "-i /home/srtla/.ssh/id_ed25519", "-- deploy@relay.example.com sudo -n bash -s"} {
+		if !strings.Contains(args, want) {
+			t.Errorf("Expected %q in %q", want, args)
+		}
+	}
+	if args := strings.Join(SSH{Host: "203.0.113.9", Port: 2222}.args(), " "); !strings.HasSuffix(args, "-- root@203.0.113.9 bash -s") || !strings.Contains(args, "-p 2222") {
+		t.Errorf("Unexpected args %q", args)
+	}
+}
+
+func TestDigitalOcean(t *testing.T) {
+	polls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer dop_v1_x" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"id":"unauthorized","message":"Unable to authenticate you"}`))
+			return
+		}
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/droplets":
+			var body map[string]any
+			json.NewDecoder(r.Body).Decode(&body)
+			if body["region"] != DefaultDigitalOceanRegion || !strings.Contains(body["user_data"].(string), "srtla_rec") {
+				t.Errorf("Unexpected droplet %v", body)
+			}
+			w.Write([]byte(`{"droplet":{"id":3164444}}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/droplets/3164444":
+			polls++
+			if polls == 1 {
+				w.Write([]byte(`{"droplet":{"status":"new","networks":{"v4":[]}}}`))
+				return
+			}
+			w.Write([]byte(`{"droplet":{"status":"active","networks":{"v4":[
+				{"ip_address":"10.110.0.2","type":"private"},
+				{"ip_address":"203.0.113.20","type":"public"}]}}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	do := DigitalOcean{Token: "dop_v1_x", BaseURL: server.URL}
+	id, err := do.Create(context.Background(), "relay", Script(Settings{}))
+	if err != nil || id != "3164444" {
+		t.Fatalf("Create: %q, %v", id, err)
+	}
+	if host, err := do.Address(context.Background(), id); err != nil || host != "" {
+		t.Errorf("Expected no address while the droplet is new, got %q, %v", host, err)
+	}
+	if host, err := do.Address(context.Background(), id); err != nil || host != "203.0.113.20" {
+		t.Errorf("Expected the public address, got %q, %v", host, err)
+	}
+
+	_, err = DigitalOcean{Token: "wrong", BaseURL: server.URL}.Create(context.Background(), "relay", "")
+	if err == nil || !strings.Contains(err.Error(), "Unable to authenticate you") {
+		t.Errorf("Expected the provider's message, got %v", err)
+	}
+}
+
+func TestHetznerProvision(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/servers":
+			w.Write([]byte(`{"server":{"id":42}}`))
+		case r.URL.Path == "/servers/42":
+			w.Write([]byte(`{"server":{"status":"running","public_net":{"ipv4":{"ip":"198.51.100.4"}}}}`))
+		case r.URL.Path == "/servers/43":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"code":"not_found","message":"server not found"}}`))
+		}
+	}))
+	defer server.Close()
+
+	id, host, err := Provision(context.Background(), Hetzner{Token: "x", BaseURL: server.URL}, "relay", Settings{})
+	if err != nil || id != "42" || host != "198.51.100.4" {
+		t.Errorf("Provision: %q, %q, %v", id, host, err)
+	}
+	if _, err := (Hetzner{Token: "x", BaseURL: server.URL}).Address(context.Background(), "43"); err == nil || !strings.Contains(err.Error(), "server not found") {
+		t.Errorf("Expected the provider's message, got %v", err)
+	}
+}
+
+func TestNewResult(t *testing.T) {
+	r := NewResult(MethodSSH, "203.0.113.9", Settings{OutputPort: 4100, Passphrase: "secretsecret"})
+	if r.SRTLAPort != DefaultSRTLAPort || r.OutputURL != "srt://203.0.113.9:4100?mode=caller&passphrase=secretsecret&pbkeylen=32" || r.Passphrase != "secretsecret" {
+		t.Errorf("Unexpected result %+v", r)
+	}
+}
+
+func TestNewSettings(t *testing.T) {
+	s := NewSettings(0, 4100)
+	if s.SRTLAPort != DefaultSRTLAPort || s.OutputPort != 4100 {
+		t.Errorf("Unexpected ports %+v", s)
+	}
+	// SRT takes passphrases of 10 to 79 characters
+	if n := len(s.Passphrase); n < 10 || n > 79 {
+		t.Errorf("Passphrase %q has %d characters", s.Passphrase, n)
+	}
+	if NewSettings(0, 0).Passphrase == s.Passphrase {
+		t.Error("Expected a new passphrase for every relay")
+	}
+}
@@ -0,0 +1,85 @@
+package relay
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// DefaultSSHPort and DefaultSSHUser are used when a target leaves them out
+const (
+	DefaultSSHPort = 22
+	DefaultSSHUser = "root"
+)
+
+// SSH installs the relay on a server over the system ssh client. The key
+// must be authorized on the server; there is no password prompt. A user
+// other than root needs sudo without a password.
+type SSH struct {
+	Host    string
+	Port    int
+	User    string
+	KeyFile string // "" uses ssh's default identity
+}
+
+// args are the ssh arguments that run the script from stdin as root
+func (t SSH) args() []string {
+	port, user := t.Port, t.User
+	if port == 0 {
+		port = DefaultSSHPort
+	}
+	if user == "" {
+		user = DefaultSSHUser
+	}
+	args := []string{
+		"-p", strconv.Itoa(port),
+		"-o", "BatchMode=yes",
+		"-o", "StrictHostKeyChecking=accept-new",
+		"-o", "ConnectTimeout=15",
+	}
+	if t.KeyFile != "" {
+		args = append(args, "-i", t.KeyFile)
+	}
+	remote := "bash -s"
+	if user != "root" {
+		remote = "sudo -n bash -s"
+	}
+	// "--" keeps the destination from being read as an option
+	return append(args, "--", user+"@"+t.Host, remote)
+}
+
+// Install runs the install script on the server, passing each line it
+// prints to output
+func (t SSH) Install(ctx context.Context, s Settings, output func(string)) error {
+	cmd := exec.CommandContext(ctx, "ssh", t.args()...)
+	cmd.Stdin = strings.NewReader(Script(s))
+	pr, pw := io.Pipe()
+	cmd.Stdout, cmd.Stderr = pw, pw
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("ssh: %w", err)
+	}
+	done := make(chan struct{})
+	var last string
+	go func() {
+		defer close(done)
+		scanner := bufio.NewScanner(pr)
+		for scanner.Scan() {
+			last = scanner.Text()
+			if output != nil {
+				output(last)
+			}
+		}
+	}()
+	err := cmd.Wait()
+	pw.Close()
+	<-done
+	if err != nil {
+		return fmt.Errorf("install on %s: %w: %s", t.Host, err, last)
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+// Package restream builds RTMP ingest URLs for streaming platforms from a
+// stream key and a region, and publish URLs for self-hosted MediaMTX and SRS
+// servers, and checks that an ingest server answers before a destination
+// is saved.
+package restream
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// KeyPlaceholder marks where the stream key goes in a region's template
+const KeyPlaceholder = "{stream_key}"
+
+// Platforms with known ingest servers
+const (
+	Twitch  = "twitch"
+	YouTube = "youtube"
+)
+
+// TwitchIngestsURL lists Twitch's ingest servers
+const TwitchIngestsURL = "https://ingest.twitch.tv/ingests"
+
+// DialTimeout bounds a reachability check
+const DialTimeout = 5 * time.Second
+
+// Region is one ingest server of a platform
+type Region struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Template string `json:"template"` // ingest URL with KeyPlaceholder for the key
+}
+
+// Platform is a streaming service and its ingest servers
+type Platform struct {
+	ID      string   `json:"id"`
+	Name    string   `json:"name"`
+	Regions []Region `json:"regions"`
+}
+
+// twitchAuto lets Twitch pick the closest server. It is the only region
+// known without asking Twitch.
+var twitchAuto = Region{ID: "auto", Name: "Automatic (closest)", Template: "rtmp://live.twitch.tv/app/" + KeyPlaceholder}
+
+var youtubeRegions = []Region{
+	{ID: "primary", Name: "Primary", Template: "rtmp://a.rtmp.youtube.com/live2/" + KeyPlaceholder},
+	{ID: "backup", Name: "Backup", Template: "rtmp://b.rtmp.youtube.com/live2?backup=1/" + KeyPlaceholder},
+}
+
+// Platforms returns the known platforms. twitch are Twitch's regions, from
+// TwitchIngests; without them only the automatic server is offered.
+func Platforms(twitch []Region) []Platform {
+	return []Platform{
+		{ID: Twitch, Name: "Twitch", Regions: append([]Region{twitchAuto}, twitch...)},
+		{ID: YouTube, Name: "YouTube", Regions: youtubeRegions},
+	}
+}
+
+// Build returns the ingest URL for a platform, region and stream key
+func Build(platforms []Platform, platform, region, key string) (string, error) {
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return "", fmt.Errorf("stream key is required")
+	}
+	if strings.ContainsAny(key, "/?#& ") {
+		return "", fmt.Errorf("stream key contains characters a key can't have")
+	}
+	for _, p := range platforms {
+		if p.ID != platform {
+			continue
+		}
+		for _, r := range p.Regions {
+			if r.ID == region {
+				return strings.Replace(r.Template, KeyPlaceholder, key, 1), nil
+			}
+		}
+		return "", fmt.Errorf("%s has no region %q", p.Name, region)
+	}
+	return "", fmt.Errorf("unknown platform %q", platform)
+}
+
+// twitchIngest is an entry of Twitch's ingest list
+type twitchIngest struct {
+	ID           int     `json:"_id"`
+	Name         string  `json:"name"`
+	URLTemplate  string  `json:"url_template"`
+	Availability float64 `json:"availability"`
+	Priority     int     `json:"priority"`
+}
+
+// TwitchIngests fetches Twitch's ingest servers, in Twitch's order of
+// preference. Unavailable servers are left out.
+func TwitchIngests(ctx context.Context, client *http.Client, listURL string) ([]Region, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, listURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", listURL, resp.Status)
+	}
+
+	var body struct {
+		Ingests []twitchIngest `json:"ingests"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("invalid ingest list: %w", err)
+	}
+
+	sort.SliceStable(body.Ingests, func(i, j int) bool { return body.Ingests[i].Priority < body.Ingests[j].Priority })
+	regions := make([]Region, 0, len(body.Ingests))
+	for _, in := range body.Ingests {
+		if in.Availability <= 0 || !strings.Contains(in.URLTemplate, KeyPlaceholder) {
+			continue
+		}
+		regions = append(regions, Region{ID: fmt.Sprint(in.ID), Name: in.Name, Template: in.URLTemplate})
+	}
+	return regions, nil
+}
+
+// TLS is how an rtmps:// ingest server's certificate is checked and the
+// client certificate sent to it
+type TLS struct {
+	Insecure bool   // don't check the server's certificate
+	CAFile   string // PEM CAs the server must chain to; the system's when empty
+	CertFile string // PEM client certificate, with KeyFile
+	KeyFile  string
+}
+
+// Config returns the TLS settings for connecting to host
+func (t TLS) Config(host string) (*tls.Config, error) {
+	cfg := &tls.Config{ServerName: host, InsecureSkipVerify: t.Insecure}
+	if t.CAFile != "" {
+		pem, err := os.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in CA file %s", t.CAFile)
+		}
+	}
+	if t.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+// CheckReachable connects to the ingest server of an RTMP URL. It only
+// shows the server is up; the key itself is checked when streaming starts.
+// An rtmps:// server's certificate is checked with the system's CAs.
+func CheckReachable(ctx context.Context, rawURL string) error {
+	return CheckReachableTLS(ctx, rawURL, TLS{})
+}
+
+// CheckReachableTLS is CheckReachable with the TLS settings for an
+// rtmps:// server, whose certificate must pass them
+func CheckReachableTLS(ctx context.Context, rawURL string, t TLS) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("%q is not a valid URL", rawURL)
+	}
+	defaultPort, ok := map[string]string{"rtmp": "1935", "rtmps": "443"}[u.Scheme]
+	if !ok {
+		return fmt.Errorf("URL scheme must be rtmp or rtmps")
+	}
+	port := u.Port()
+	if port == "" {
+		port = defaultPort
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, DialTimeout)
+	defer cancel()
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(u.Hostname(), port))
+	if err != nil {
+		return fmt.Errorf("ingest server %s is unreachable: %w", u.Hostname(), err)
+	}
+	defer conn.Close()
+	if u.Scheme != "rtmps" {
+		return nil
+	}
+
+	cfg, err := t.Config(u.Hostname())
+	if err != nil {
+		return err
+	}
+	tlsConn := tls.Client(conn, cfg)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return fmt.Errorf("TLS with ingest server %s failed: %w", u.Hostname(), err)
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package restream
+
+import (
+	"context"
+	"encoding/pem"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBuild(t *testing.T) {
+	platforms := Platforms(nil)
+
+	cases := []struct {
+		platform, region, key string
+		want                  string
+	}{
+		{Twitch, "auto", "live_123_abc", "rtmp://live.twitch.tv/app/live_123_abc"},
+		{YouTube, "primary", "abcd-efgh", "rtmp://a.rtmp.youtube.com/live2/abcd-efgh"},
+		{YouTube, "backup", " abcd-efgh ", "rtmp://b.rtmp.youtube.com/live2?backup=1/abcd-efgh"},
+	}
+	for _, c := range cases {
+		got, err := Build(platforms, c.platform, c.region, c.key)
+		if err != nil || got != c.want {
+			t.Errorf("Build(%s, %s) = %q, %v; want %q", c.platform, c.region, got, err, c.want)
+		}
+	}
+
+	for _, bad := range [][3]string{
+		{Twitch, "auto", ""},
+		{Twitch, "auto", "key/../other"},
+		{Twitch, "mars", "key"},
+		{"vimeo", "auto", "key"},
+	} {
+		if _, err := Build(platforms, bad[0], bad[1], bad[2]); err == nil {
+			t.Errorf("Expected an error for %v", bad)
+		}
+	}
+}
+
+func TestTwitchIngests(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"ingests": [
+			{"_id": 2, "name": "Europe: London", "url_template": "rtmp://lhr.example/app/{stream_key}", "availability": 1, "priority": 2},
+			{"_id": 1, "name": "Europe: Frankfurt", "url_template": "rtmp://fra.example/app/{stream_key}", "availability": 1, "priority": 1},
+			{"_id": 3, "name": "Offline", "url_template": "rtmp://off.example/app/{stream_key}", "availability": 0, "priority": 0}
+		]}`))
+	}))
+	defer srv.Close()
+
+	regions, err := TwitchIngests(context.Background(), srv.Client(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(regions) != 2 || regions[0].ID != "1" || regions[1].Name != "Europe: London" {
+		t.Fatalf("Unexpected regions: %+v", regions)
+	}
+
+	got, err := Build(Platforms(regions), Twitch, "2", "key")
+	if err != nil || got != "rtmp://lhr.example/app/key" {
+		t.Errorf("Expected the London server, got %q, %v", got, err)
+	}
+}
+
+func TestCheckReachable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+
+	if err := CheckReachable(context.Background(), "rtmp://"+addr+"/app/key"); err != nil {
+		t.Errorf("Expected %s to be reachable: %v", addr, err)
+	}
+	ln.Close()
+	if err := CheckReachable(context.Background(), "rtmp://"+addr+"/app/key"); err == nil {
+		t.Error("Expected a closed port to be unreachable")
+	}
+	if err := CheckReachable(context.Background(), "http://example.com/"); err == nil {
+		t.Error("Expected http to be refused")
+	}
+}
+
+func TestCheckReachableTLS(t *testing.T) {
+	srv := httptest.NewTLSServer(http.NotFoundHandler())
+	defer srv.Close()
+	rawURL := "rtmps://" + srv.Listener.Addr().String() + "/app/key"
+
+	if err := CheckReachable(context.Background(), rawURL); err == nil {
+		t.Error("Expected a certificate from an unknown CA to be refused")
+	}
+	if err := CheckReachableTLS(context.Background(), rawURL, TLS{Insecure: true}); err != nil {
+		t.Errorf("Expected insecure to skip the check: %v", err)
+	}
+
+	ca := filepath.Join(t.TempDir(), "ca.pem")
+	pemData := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(ca, pemData, 0600); err != nil {
+		t.Fatal(err)
+	}
+	// the test server's certificate is for 127.0.0.1 and example.com
+	if err := CheckReachableTLS(context.Background(), rawURL, TLS{CAFile: ca}); err != nil {
+		t.Errorf("Expected the server to pass with its CA: %v", err)
+	}
+	if err := CheckReachableTLS(context.Background(), rawURL, TLS{CAFile: ca, CertFile: ca, KeyFile: ca + ".missing"}); err == nil {
+		t.Error("Expected a missing client key to fail")
+	}
+}
+
+func TestServerURL(t *testing.T) {
+	cases := []struct {
+		server Server
+		want   string
+	}{
+		{Server{Kind: MediaMTX, Protocol: ProtocolRTMP, Host: "10.0.0.5", Path: "cam1"}, "rtmp://10.0.0.5:1935/cam1"},
+		{Server{Kind: MediaMTX, Protocol: ProtocolSRT, Host: "media.example.com", Path: "/cam1/"}, "srt://media.example.com:8890?streamid=publish%3Acam1"},
+		{Server{Kind: SRS, Protocol: ProtocolRTMP, Host: "srs.local", Port: 1936, Path: "cam1"}, "rtmp://srs.local:1936/live/cam1"},
+		{Server{Kind: SRS, Protocol: ProtocolSRT, Host: "fd00::5", Path: "event/cam1"}, "srt://[fd00::5]:10080?streamid=%23%21%3A%3Ar%3Devent%2Fcam1%2Cm%3Dpublish"},
+	}
+	for _, c := range cases {
+		got, err := c.server.URL()
+		if err != nil || got != c.want {
+			t.Errorf("%+v: URL() = %q, %v; want %q", c.server, got, err, c.want)
+		}
+	}
+
+	for _, bad := range []Server{
+		{Kind: "wowza", Protocol: ProtocolRTMP, Host: "h", Path: "p"},
+		{Kind: MediaMTX, Protocol: "rtsp", Host: "h", Path: "p"},
+		{Kind: MediaMTX, Protocol: ProtocolRTMP, Host: "", Path: "p"},
+		{Kind: MediaMTX, Protocol: ProtocolRTMP, Host: "h/x", Path: "p"},
+		{Kind: MediaMTX, Protocol: ProtocolRTMP, Host: "h", Path: "a b"},
+		{Kind: MediaMTX, Protocol: ProtocolRTMP, Host: "h", Path: ""},
+		{Kind: MediaMTX, Protocol: ProtocolRTMP, Host: "h", Port: 70000, Path: "p"},
+	} {
+		if _, err := bad.URL(); err == nil {
+			t.Errorf("Expected an error for %+v", bad)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package restream
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Self-hosted media servers the stream can be published to
+const (
+	MediaMTX = "mediamtx"
+	SRS      = "srs"
+)
+
+// Protocols a server is published to over
+const (
+	ProtocolRTMP = "rtmp"
+	ProtocolSRT  = "srt"
+)
+
+// ServerKinds and ServerProtocols are the values Server accepts
+var (
+	ServerKinds     = []string{MediaMTX, SRS}
+	ServerProtocols = []string{ProtocolRTMP, ProtocolSRT}
+)
+
+// srsDefaultApp is the app SRS publishes a path without one under
+const srsDefaultApp = "live"
+
+var serverPathPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+(/[A-Za-z0-9_.-]+)*$`)
+
+// Server is a MediaMTX or SRS instance and the path the stream is published
+// at. Port is the server's default for the protocol when 0.
+type Server struct {
+	Kind     string `json:"kind"`
+	Protocol string `json:"protocol"`
+	Host     string `json:"host"`
+	Port     int    `json:"port"`
+	Path     string `json:"path"`
+}
+
+// DefaultPort returns the port the server listens on for the protocol out
+// of the box
+func (s Server) DefaultPort() int {
+	if s.Protocol == ProtocolSRT {
+		if s.Kind == SRS {
+			return 10080
+		}
+		return 8890
+	}
+	return 1935
+}
+
+// URL returns the URL publishing to the server. SRT carries the path in
+// the stream ID, in the form each server expects.
+func (s Server) URL() (string, error) {
+	if s.Kind != MediaMTX && s.Kind != SRS {
+		return "", fmt.Errorf("unknown server %q", s.Kind)
+	}
+	if s.Protocol != ProtocolRTMP && s.Protocol != ProtocolSRT {
+		return "", fmt.Errorf("%s can't be published to over %q", s.Kind, s.Protocol)
+	}
+	host := strings.TrimSpace(s.Host)
+	if host == "" || strings.ContainsAny(host, "/?#@ ") {
+		return "", fmt.Errorf("host must be a host name or IP address")
+	}
+	port := s.Port
+	if port == 0 {
+		port = s.DefaultPort()
+	}
+	if port < 1 || port > 65535 {
+		return "", fmt.Errorf("port must be between 1 and 65535")
+	}
+	path := strings.Trim(s.Path, "/")
+	if !serverPathPattern.MatchString(path) {
+		return "", fmt.Errorf("path must be letters, digits, '_', '.' or '-', separated by '/'")
+	}
+	if s.Kind == SRS && !strings.Contains(path, "/") {
+		path = srsDefaultApp + "/" + path
+	}
+
+	u := url.URL{Scheme: s.Protocol, Host: net.JoinHostPort(host, strconv.Itoa(port))}
+	switch {
+	case s.Protocol == ProtocolRTMP:
+		u.Path = "/" + path
+	case s.Kind == MediaMTX:
+		u.RawQuery = url.Values{"streamid": {"publish:" + path}}.Encode()
+	default:
+		u.RawQuery = url.Values{"streamid": {"#!::r=" + path + ",m=publish"}}.Encode()
+	}
+	return u.String(), nil
+}
@@ -0,0 +1,278 @@
+// Package routing sets up source-based policy routing for the bind IPs, so
+// traffic bound to a modem's address leaves through that modem. Without it
+// the kernel picks the main table's default route for every link and the
+// bond quietly collapses onto one uplink.
+//
+// Each bind IP gets its own routing table, holding the interface's subnet
+// and a default route through its gateway, and an "ip rule" sending
+// traffic from the address to that table.
+package routing
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"srtla-manager/internal"
+)
+
+// Defaults of the first table and rule priority used
+const (
+	DefaultTableBase    = 100
+	DefaultRulePriority = 1000
+)
+
+// Config numbers the tables and rules. The bind IP at position i of the
+// list uses TableBase+i and RulePriority+i.
+type Config struct {
+	TableBase    int
+	RulePriority int
+}
+
+// Link is a bind IP and how it reaches the internet
+type Link struct {
+	Slot      int    `json:"slot"` // position in the bind IP list
+	IP        string `json:"ip"`
+	Interface string `json:"interface"`
+	Network   string `json:"network,omitempty"` // the interface's subnet, e.g. 192.168.8.0/24
+	Gateway   string `json:"gateway,omitempty"` // "" for point-to-point links
+}
+
+// Entry is the table and rule of one link
+type Entry struct {
+	Link
+	Table    int `json:"table"`
+	Priority int `json:"priority"`
+}
+
+// Plan numbers the table and rule of each link
+func Plan(links []Link, cfg Config) []Entry {
+	if cfg.TableBase == 0 {
+		cfg.TableBase = DefaultTableBase
+	}
+	if cfg.RulePriority == 0 {
+		cfg.RulePriority = DefaultRulePriority
+	}
+	entries := make([]Entry, len(links))
+	for i, l := range links {
+		entries[i] = Entry{Link: l, Table: cfg.TableBase + l.Slot, Priority: cfg.RulePriority + l.Slot}
+	}
+	return entries
+}
+
+// addCommands are the ip commands that set up an entry. Routes are
+// replaced, so they can be run again over what is there.
+func (e Entry) addCommands() [][]string {
+	table := strconv.Itoa(e.Table)
+	var cmds [][]string
+	if e.Network != "" {
+		cmds = append(cmds, []string{"route", "replace", e.Network, "dev", e.Interface, "src", e.IP, "table", table})
+	}
+	if e.Gateway != "" {
+		cmds = append(cmds, []string{"route", "replace", "default", "via", e.Gateway, "dev", e.Interface, "table", table})
+	} else {
+		cmds = append(cmds, []string{"route", "replace", "default", "dev", e.Interface, "table", table})
+	}
+	return append(cmds, []string{"rule", "add", "from", e.IP + "/32", "lookup", table, "priority", strconv.Itoa(e.Priority)})
+}
+
+// delCommands are the ip commands that remove an entry
+func (e Entry) delCommands() [][]string {
+	return [][]string{
+		{"rule", "del", "priority", strconv.Itoa(e.Priority)},
+		{"route", "flush", "table", strconv.Itoa(e.Table)},
+	}
+}
+
+// Manager keeps the entries it has set up in line with what is wanted
+type Manager struct {
+	run func(args ...string) error
+
+	mu      sync.Mutex
+	applied map[string]Entry // by bind IP
+}
+
+// NewManager returns a manager that runs ip as root, through srtla-installer
+// when the process isn't root itself
+func NewManager() *Manager {
+	return &Manager{run: runIP, applied: make(map[string]Entry)}
+}
+
+// Apply sets up the entries that are new or have changed and removes those
+// no longer wanted. It reports whether anything changed.
+func (m *Manager) Apply(entries []Entry) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	want := make(map[string]Entry, len(entries))
+	for _, e := range entries {
+		want[e.IP] = e
+	}
+	var errs []error
+	changed := false
+	// stale entries go first: their table and priority may be reused
+	for ip, e := range m.applied {
+		if w, ok := want[ip]; ok && w == e {
+			continue
+		}
+		changed = true
+		errs = append(errs, m.remove(e))
+		delete(m.applied, ip)
+	}
+	for _, e := range entries {
+		if _, ok := m.applied[e.IP]; ok {
+			continue
+		}
+		changed = true
+		if err := m.add(e); err != nil {
+			errs = append(errs, fmt.Errorf("%s on %s: %w", e.IP, e.Interface, err))
+			continue
+		}
+		m.applied[e.IP] = e
+	}
+	return changed, errors.Join(errs...)
+}
+
+// Clear removes every entry set up
+func (m *Manager) Clear() error {
+	_, err := m.Apply(nil)
+	return err
+}
+
+// Applied returns the entries set up, by table
+func (m *Manager) Applied() []Entry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]Entry, 0, len(m.applied))
+	for _, e := range m.applied {
+		out = append(out, e)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Table < out[j].Table })
+	return out
+}
+
+func (m *Manager) add(e Entry) error {
+	// a rule left from an earlier run would be duplicated
+	_ = m.run("rule", "del", "priority", strconv.Itoa(e.Priority))
+	for _, args := range e.addCommands() {
+		if err := m.run(args...); err != nil {
+			_ = m.remove(e)
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Manager) remove(e Entry) error {
+	var errs []error
+	for _, args := range e.delCommands() {
+		if err := m.run(args...); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// runIP runs ip with args
+func runIP(args ...string) error {
+	if os.Geteuid() != 0 {
+		resp, err := internal.RouteWithInstaller(args)
+		if err != nil {
+			return fmt.Errorf("ip %s: %w", strings.Join(args, " "), err)
+		}
+		if !resp.Success {
+			return fmt.Errorf("ip %s: %s", strings.Join(args, " "), resp.Message)
+		}
+		return nil
+	}
+	output, err := exec.Command("ip", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("ip %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// Discover finds the interface, subnet and gateway of each bind IP. IPs
+// that aren't on any interface are returned as missing.
+func Discover(ips []string) (links []Link, missing []string) {
+	gateways, _ := readGateways()
+	for i, ip := range ips {
+		l, ok := linkOf(ip)
+		if !ok {
+			missing = append(missing, ip)
+			continue
+		}
+		l.Slot = i
+		l.Gateway = gateways[l.Interface]
+		links = append(links, l)
+	}
+	return links, missing
+}
+
+// linkOf finds the interface with ip and its subnet
+func linkOf(ip string) (Link, bool) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return Link{}, false
+	}
+	for _, iface := range ifaces {
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			ipnet, ok := addr.(*net.IPNet)
+			if !ok || ipnet.IP.String() != ip {
+				continue
+			}
+			l := Link{IP: ip, Interface: iface.Name}
+			// a /32 on a point-to-point link has no subnet worth a route
+			if ones, bits := ipnet.Mask.Size(); ones < bits {
+				network := net.IPNet{IP: ipnet.IP.Mask(ipnet.Mask), Mask: ipnet.Mask}
+				l.Network = network.String()
+			}
+			return l, true
+		}
+	}
+	return Link{}, false
+}
+
+// readGateways returns the default gateway of each interface from the main
+// routing table
+func readGateways() (map[string]string, error) {
+	data, err := os.ReadFile("/proc/net/route")
+	if err != nil {
+		return map[string]string{}, err
+	}
+	return parseGateways(string(data)), nil
+}
+
+// parseGateways reads /proc/net/route: addresses are little-endian hex.
+// The lowest metric wins when an interface has several default routes.
+func parseGateways(data string) map[string]string {
+	gateways := make(map[string]string)
+	metrics := make(map[string]int)
+	for _, line := range strings.Split(data, "\n")[1:] {
+		fields := strings.Fields(line)
+		if len(fields) < 8 || fields[1] != "00000000" || fields[7] != "00000000" {
+			continue
+		}
+		gw, err := strconv.ParseUint(fields[2], 16, 32)
+		if err != nil || gw == 0 {
+			continue
+		}
+		metric, _ := strconv.Atoi(fields[6])
+		if m, ok := metrics[fields[0]]; ok && m <= metric {
+			continue
+		}
+		metrics[fields[0]] = metric
+		gateways[fields[0]] = net.IPv4(byte(gw), byte(gw>>8), byte(gw>>16), byte(gw>>24)).String()
+	}
+	return gateways
+}
@@ -0,0 +1,96 @@
+package routing
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const sampleRoute = "Iface\tDestination\tGateway \tFlags\tRefCnt\tUse\tMetric\tMask\t\tMTU\tWindow\tIRTT\n" +
+	"eth0\t00000000\t010200C0\t0003\t0\t0\t100\t00000000\t0\t0\t0\n" +
+	"eth0\t000200C0\t00000000\t0001\t0\t0\t100\t00FFFFFF\t0\t0\t0\n" +
+	"usb0\t00000000\t0108A8C0\t0003\t0\t0\t700\t00000000\t0\t0\t0\n" +
+	"usb0\t00000000\t0208A8C0\t0003\t0\t0\t200\t00000000\t0\t0\t0\n" +
+	"wwan0\t00000000\t00000000\t0001\t0\t0\t0\t00000000\t0\t0\t0\n"
+
+func TestParseGateways(t *testing.T) {
+	got := parseGateways(sampleRoute)
+	want := map[string]string{"eth0": "192.0.2.1", "usb0": "192.168.8.2"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestPlanNumbersBySlot(t *testing.T) {
+	links := []Link{{Slot: 0, IP: "10.0.0.2"}, {Slot: 2, IP: "10.0.1.2"}}
+	entries := Plan(links, Config{})
+	if entries[0].Table != 100 || entries[0].Priority != 1000 {
+		t.Errorf("Unexpected first entry %+v", entries[0])
+	}
+	if entries[1].Table != 102 || entries[1].Priority != 1002 {
+		t.Errorf("Expected a missing link to keep its slot, got %+v", entries[1])
+	}
+	if e := Plan(links, Config{TableBase: 50, RulePriority: 200}); e[1].Table != 52 || e[1].Priority != 202 {
+		t.Errorf("Expected the configured bases, got %+v", e[1])
+	}
+}
+
+func newTestManager() (*Manager, *[]string) {
+	var cmds []string
+	m := &Manager{applied: make(map[string]Entry), run: func(args ...string) error {
+		cmds = append(cmds, strings.Join(args, " "))
+		return nil
+	}}
+	return m, &cmds
+}
+
+func TestManagerApply(t *testing.T) {
+	m, cmds := newTestManager()
+	entries := Plan([]Link{
+		{Slot: 0, IP: "192.168.8.100", Interface: "usb0", Network: "192.168.8.0/24", Gateway: "192.168.8.1"},
+		{Slot: 1, IP: "10.64.1.2", Interface: "wwan0"},
+	}, Config{})
+
+	changed, err := m.Apply(entries)
+	if err != nil || !changed {
+		t.Fatalf("Expected a change without error, got %v %v", changed, err)
+	}
+	want := []string{
+		"rule del priority 1000",
+		"route replace 192.168.8.0/24 dev usb0 src 192.168.8.100 table 100",
+		"route replace default via 192.168.8.1 dev usb0 table 100",
+		"rule add from 192.168.8.100/32 lookup 100 priority 1000",
+		"rule del priority 1001",
+		"route replace default dev wwan0 table 101",
+		"rule add from 10.64.1.2/32 lookup 101 priority 1001",
+	}
+	if !reflect.DeepEqual(*cmds, want) {
+		t.Errorf("Unexpected commands:\n%s", strings.Join(*cmds, "\n"))
+	}
+
+	*cmds = nil
+	if changed, _ := m.Apply(entries); changed || len(*cmds) != 0 {
+		t.Errorf("Expected applying again to do nothing, ran %v", *cmds)
+	}
+
+	// the modem's gateway moved: its entry is redone, the other kept
+	entries[0].Gateway = "192.168.8.254"
+	*cmds = nil
+	if changed, _ := m.Apply(entries); !changed {
+		t.Error("Expected a changed gateway to count as a change")
+	}
+	if len(*cmds) < 2 || (*cmds)[0] != "rule del priority 1000" || (*cmds)[1] != "route flush table 100" {
+		t.Errorf("Expected the stale entry removed first, ran %v", *cmds)
+	}
+	if len(m.Applied()) != 2 {
+		t.Errorf("Expected 2 entries, got %+v", m.Applied())
+	}
+
+	*cmds = nil
+	if err := m.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Applied()) != 0 || len(*cmds) != 4 {
+		t.Errorf("Expected both entries removed, ran %v", *cmds)
+	}
+}
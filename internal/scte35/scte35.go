@@ -0,0 +1,136 @@
+// Package scte35 encodes SCTE-35 splice_info_sections for ad insertion cues.
+package scte35
+
+import "time"
+
+const (
+	tableID             = 0xFC
+	commandSpliceInsert = 0x05
+
+	// ClockRate is the 90kHz clock used for PTS values and durations
+	ClockRate = 90000
+)
+
+// SpliceInsert describes a splice_insert() command. Without a PTS the splice
+// is signalled as immediate.
+type SpliceInsert struct {
+	EventID         uint32
+	Cancel          bool
+	OutOfNetwork    bool // true for a break start (cue-out), false for the return (cue-in)
+	PTS             *uint64
+	Duration        time.Duration // break duration, zero when unknown
+	AutoReturn      bool
+	UniqueProgramID uint16
+	AvailNum        uint8
+	AvailsExpected  uint8
+}
+
+// bitWriter packs MSB-first bit fields
+type bitWriter struct {
+	buf   []byte
+	nbits uint
+}
+
+func (w *bitWriter) write(value uint64, bits uint) {
+	for i := int(bits) - 1; i >= 0; i-- {
+		if w.nbits%8 == 0 {
+			w.buf = append(w.buf, 0)
+		}
+		if value>>uint(i)&1 == 1 {
+			w.buf[len(w.buf)-1] |= 1 << (7 - w.nbits%8)
+		}
+		w.nbits++
+	}
+}
+
+func durationTicks(d time.Duration) uint64 {
+	return uint64(d.Seconds()*ClockRate) & (1<<33 - 1)
+}
+
+func (s *SpliceInsert) encodeCommand() []byte {
+	w := &bitWriter{}
+	w.write(uint64(s.EventID), 32)
+	if s.Cancel {
+		w.write(1, 1)
+		w.write(0x7F, 7)
+		return w.buf
+	}
+	w.write(0, 1)
+	w.write(0x7F, 7)
+
+	immediate := s.PTS == nil
+	hasDuration := s.Duration > 0
+	w.write(boolBit(s.OutOfNetwork), 1)
+	w.write(1, 1) // program_splice_flag
+	w.write(boolBit(hasDuration), 1)
+	w.write(boolBit(immediate), 1)
+	w.write(0xF, 4)
+
+	if !immediate {
+		w.write(1, 1) // time_specified_flag
+		w.write(0x3F, 6)
+		w.write(*s.PTS&(1<<33-1), 33)
+	}
+	if hasDuration {
+		w.write(boolBit(s.AutoReturn), 1)
+		w.write(0x3F, 6)
+		w.write(durationTicks(s.Duration), 33)
+	}
+
+	w.write(uint64(s.UniqueProgramID), 16)
+	w.write(uint64(s.AvailNum), 8)
+	w.write(uint64(s.AvailsExpected), 8)
+	return w.buf
+}
+
+// Encode returns the complete splice_info_section including CRC_32
+func (s *SpliceInsert) Encode() []byte {
+	cmd := s.encodeCommand()
+
+	// Everything after section_length: 11 fixed bytes, command, descriptor loop length, CRC
+	sectionLength := 11 + len(cmd) + 2 + 4
+
+	w := &bitWriter{}
+	w.write(tableID, 8)
+	w.write(0, 1)   // section_syntax_indicator
+	w.write(0, 1)   // private_indicator
+	w.write(0x3, 2) // sap_type: not specified
+	w.write(uint64(sectionLength), 12)
+	w.write(0, 8)      // protocol_version
+	w.write(0, 1)      // encrypted_packet
+	w.write(0, 6)      // encryption_algorithm
+	w.write(0, 33)     // pts_adjustment
+	w.write(0, 8)      // cw_index
+	w.write(0xFFF, 12) // tier
+	w.write(uint64(len(cmd)), 12)
+	w.write(commandSpliceInsert, 8)
+
+	out := append(w.buf, cmd...)
+	out = append(out, 0, 0) // descriptor_loop_length
+
+	crc := CRC32(out)
+	return append(out, byte(crc>>24), byte(crc>>16), byte(crc>>8), byte(crc))
+}
+
+// CRC32 computes the MPEG-2 CRC used by PSI and SCTE-35 sections
+func CRC32(data []byte) uint32 {
+	crc := uint32(0xFFFFFFFF)
+	for _, b := range data {
+		crc ^= uint32(b) << 24
+		for i := 0; i < 8; i++ {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ 0x04C11DB7
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+func boolBit(b bool) uint64 {
+	if b {
+		return 1
+	}
+	return 0
+}
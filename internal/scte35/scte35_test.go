@@ -0,0 +1,61 @@
+package scte35
+
+import (
+	"testing"
+	"time"
+)
+
+// Test immediate cue-out with a break duration
+func TestEncodeSpliceInsertImmediate(t *testing.T) {
+	s := &SpliceInsert{
+		EventID:      1,
+		OutOfNetwork: true,
+		Duration:     30 * time.Second,
+		AutoReturn:   true,
+	}
+	section := s.Encode()
+	t.Logf("Section (%d bytes): %x", len(section), section)
+
+	if section[0] != 0xFC {
+		t.Errorf("Expected table_id 0xFC, got 0x%02x", section[0])
+	}
+
+	sectionLength := int(section[1]&0x0F)<<8 | int(section[2])
+	if sectionLength != len(section)-3 {
+		t.Errorf("Expected section_length %d, got %d", len(section)-3, sectionLength)
+	}
+
+	if section[13] != 0x05 {
+		t.Errorf("Expected splice_insert command type, got 0x%02x", section[13])
+	}
+
+	// out_of_network, program_splice, duration and immediate flags all set
+	if flags := section[19] >> 4; flags != 0xF {
+		t.Errorf("Expected flags 0xF, got 0x%x", flags)
+	}
+
+	// CRC over a section including its CRC is zero
+	if crc := CRC32(section); crc != 0 {
+		t.Errorf("CRC check failed: 0x%08x", crc)
+	}
+}
+
+// Test cue-in at a given PTS
+func TestEncodeSpliceInsertTimed(t *testing.T) {
+	pts := uint64(10 * ClockRate)
+	s := &SpliceInsert{EventID: 2, PTS: &pts}
+	section := s.Encode()
+
+	// splice_time follows the flags: time_specified_flag set, then 33-bit PTS
+	if section[20]&0x80 == 0 {
+		t.Error("Expected time_specified_flag to be set")
+	}
+	got := uint64(section[20]&0x01)<<32 | uint64(section[21])<<24 | uint64(section[22])<<16 | uint64(section[23])<<8 | uint64(section[24])
+	if got != pts {
+		t.Errorf("Expected PTS %d, got %d", pts, got)
+	}
+
+	if crc := CRC32(section); crc != 0 {
+		t.Errorf("CRC check failed: 0x%08x", crc)
+	}
+}
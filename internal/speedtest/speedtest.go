@@ -0,0 +1,112 @@
+// Package speedtest measures the upload throughput of a single network
+// link by sending generated data from one local address to an HTTP server
+// that accepts and discards uploads.
+package speedtest
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultURL accepts uploads of any size
+const DefaultURL = "https://speed.cloudflare.com/__up"
+
+// DefaultDuration is how long data is sent over each link
+const DefaultDuration = 10 * time.Second
+
+// responseTimeout bounds the wait for the server to take the rest of the
+// upload and answer once sending stopped
+const responseTimeout = 15 * time.Second
+
+// Result is the upload throughput over one local address
+type Result struct {
+	BindIP  string  `json:"bind_ip"`
+	Kbps    float64 `json:"kbps"`
+	Bytes   int64   `json:"bytes"`
+	Seconds float64 `json:"seconds"`
+	Error   string  `json:"error,omitempty"`
+}
+
+// chunk is sent over and over; random data keeps compression on the path
+// from inflating the result
+var chunk = func() []byte {
+	buf := make([]byte, 64*1024)
+	rand.Read(buf)
+	return buf
+}()
+
+// body yields chunk until its deadline passes
+type body struct {
+	deadline time.Time
+	sent     atomic.Int64
+	offset   int
+}
+
+func (b *body) Read(p []byte) (int, error) {
+	if !time.Now().Before(b.deadline) {
+		return 0, io.EOF
+	}
+	n := copy(p, chunk[b.offset:])
+	b.offset = (b.offset + n) % len(chunk)
+	b.sent.Add(int64(n))
+	return n, nil
+}
+
+// Upload POSTs generated data to url from bindIP for d. The rate is taken
+// up to the server's answer, so data still queued on the way when sending
+// stopped is counted only once it arrived.
+func Upload(ctx context.Context, url, bindIP string, d time.Duration) Result {
+	result := Result{BindIP: bindIP}
+	ip := net.ParseIP(bindIP)
+	if ip == nil {
+		result.Error = fmt.Sprintf("invalid bind IP %q", bindIP)
+		return result
+	}
+
+	dialer := &net.Dialer{LocalAddr: &net.TCPAddr{IP: ip}, Timeout: 10 * time.Second}
+	transport := &http.Transport{
+		DialContext:         dialer.DialContext,
+		TLSHandshakeTimeout: 10 * time.Second,
+		DisableKeepAlives:   true,
+	}
+	defer transport.CloseIdleConnections()
+	client := &http.Client{Transport: transport}
+
+	ctx, cancel := context.WithTimeout(ctx, d+responseTimeout)
+	defer cancel()
+
+	start := time.Now()
+	b := &body{deadline: start.Add(d)}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, b)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	resp, err := client.Do(req)
+	elapsed := time.Since(start)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		result.Error = fmt.Sprintf("server answered %s", resp.Status)
+		return result
+	}
+
+	result.Bytes = b.sent.Load()
+	result.Seconds = elapsed.Seconds()
+	if result.Seconds > 0 {
+		result.Kbps = float64(result.Bytes) * 8 / 1000 / result.Seconds
+	}
+	return result
+}
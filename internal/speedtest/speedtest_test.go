@@ -0,0 +1,52 @@
+package speedtest
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestUpload(t *testing.T) {
+	var received atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, _ := io.Copy(io.Discard, r.Body)
+		received.Store(n)
+	}))
+	defer srv.Close()
+
+	result := Upload(context.Background(), srv.URL, "127.0.0.1", 200*time.Millisecond)
+	if result.Error != "" {
+		t.Fatalf("Upload() failed: %s", result.Error)
+	}
+	if result.Bytes == 0 || result.Bytes != received.Load() {
+		t.Errorf("Bytes = %d, server received %d", result.Bytes, received.Load())
+	}
+	if result.Seconds < 0.2 || result.Kbps <= 0 {
+		t.Errorf("Seconds = %v, Kbps = %v", result.Seconds, result.Kbps)
+	}
+	if result.BindIP != "127.0.0.1" {
+		t.Errorf("BindIP = %q", result.BindIP)
+	}
+}
+
+func TestUploadErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	if result := Upload(context.Background(), srv.URL, "127.0.0.1", 50*time.Millisecond); result.Error != "server answered 403 Forbidden" {
+		t.Errorf("Expected the server's status, got %q", result.Error)
+	}
+	if result := Upload(context.Background(), srv.URL, "not-an-ip", time.Millisecond); result.Error == "" {
+		t.Error("Expected an invalid bind IP to be rejected")
+	}
+	if result := Upload(context.Background(), srv.URL, "192.0.2.1", time.Millisecond); result.Error == "" {
+		t.Error("Expected binding to an address the host doesn't have to fail")
+	}
+}
@@ -0,0 +1,93 @@
+package srt
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"srtla-manager/internal/events"
+	"srtla-manager/internal/process"
+)
+
+// reportEvery is how often srt-live-transmit reports, in packets. At a
+// typical 4-8 Mbps that is about once a second.
+const reportEvery = 500
+
+// latencyMs matches the latency of ffmpeg's SRT output
+const latencyMs = 200
+
+// Probe relays ffmpeg's SRT stream to the bonding process through
+// srt-live-transmit and keeps its statistics
+type Probe struct {
+	proc *process.Process
+
+	mu    sync.RWMutex
+	bus   *events.Bus
+	stats Stats
+}
+
+func NewProbe() *Probe {
+	p := &Probe{proc: process.New("srt-probe")}
+	p.proc.SetLogCallback(p.handleLog)
+	return p
+}
+
+// SetBus publishes the relay's log lines and state changes on bus
+func (p *Probe) SetBus(bus *events.Bus) {
+	p.mu.Lock()
+	p.bus = bus
+	p.mu.Unlock()
+	p.proc.SetBus(bus)
+}
+
+// Start listens for ffmpeg on listenPort and relays to targetPort on
+// loopback. The relay reconnects on its own when either side drops.
+func (p *Probe) Start(binaryPath string, listenPort, targetPort int) error {
+	if binaryPath == "" {
+		return fmt.Errorf("srt-live-transmit not found")
+	}
+
+	p.mu.Lock()
+	p.stats = Stats{}
+	p.mu.Unlock()
+
+	args := []string{
+		fmt.Sprintf("-s:%d", reportEvery),
+		"-pf:json",
+		fmt.Sprintf("srt://:%d?mode=listener&latency=%d", listenPort, latencyMs),
+		fmt.Sprintf("srt://127.0.0.1:%d?mode=caller&latency=%d", targetPort, latencyMs),
+	}
+	return p.proc.Start(binaryPath, args...)
+}
+
+func (p *Probe) Stop() error {
+	return p.proc.Stop()
+}
+
+func (p *Probe) ProcessState() process.State {
+	return p.proc.State()
+}
+
+// Stats returns the link statistics, zero until the first report
+func (p *Probe) Stats() Stats {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	st := p.stats
+	st.Connected = p.proc.State() == process.StateRunning &&
+		!st.UpdatedAt.IsZero() && time.Since(st.UpdatedAt) < StaleAfter
+	return st
+}
+
+func (p *Probe) handleLog(line process.LogLine) {
+	if s, ok := parseSample(line.Line); ok {
+		p.mu.Lock()
+		p.stats.add(s, line.Timestamp)
+		p.mu.Unlock()
+		return
+	}
+
+	p.mu.RLock()
+	bus := p.bus
+	p.mu.RUnlock()
+	events.Publish(bus, process.TopicLog, line)
+}
@@ -0,0 +1,96 @@
+// Package srt measures the health of the SRT leg from ffmpeg to the bonding
+// process. A relay run with srt-live-transmit sits between them and reports
+// the sending socket's statistics, which cover the whole path to the
+// receiver since srtla_send forwards SRT unchanged.
+package srt
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// StaleAfter is how long after the last report the link counts as down
+const StaleAfter = 5 * time.Second
+
+// Stats is the health of the SRT link as last reported
+type Stats struct {
+	Connected            bool      `json:"connected"`
+	RTTMs                float64   `json:"rtt_ms"`
+	BandwidthMbps        float64   `json:"bandwidth_mbps"` // estimated link capacity
+	SendRateMbps         float64   `json:"send_rate_mbps"`
+	LossPercent          float64   `json:"loss_percent"`    // of packets sent in the last report
+	RetransPercent       float64   `json:"retrans_percent"` // of packets sent in the last report
+	PacketsSent          int64     `json:"packets_sent"`    // since the relay started
+	PacketsLost          int64     `json:"packets_lost"`
+	PacketsRetransmitted int64     `json:"packets_retransmitted"`
+	PacketsDropped       int64     `json:"packets_dropped"` // too late to send, lost to the receiver
+	SendBufferMs         float64   `json:"send_buffer_ms"`  // unacknowledged data waiting in the send buffer
+	SendBufferPackets    int64     `json:"send_buffer_packets"`
+	FlightPackets        int64     `json:"flight_packets"`
+	UpdatedAt            time.Time `json:"updated_at,omitempty"`
+}
+
+// sample is one report of srt-live-transmit's JSON statistics. Counters
+// cover the interval since the previous report.
+type sample struct {
+	Window struct {
+		Flight int64 `json:"flight"`
+	} `json:"window"`
+	Link struct {
+		RTT       float64 `json:"rtt"`
+		Bandwidth float64 `json:"bandwidth"`
+	} `json:"link"`
+	Send struct {
+		Packets              int64   `json:"packets"`
+		PacketsLost          int64   `json:"packetsLost"`
+		PacketsDropped       int64   `json:"packetsDropped"`
+		PacketsRetransmitted int64   `json:"packetsRetransmitted"`
+		PacketsBuf           int64   `json:"packetsBuf"`
+		MsBuf                float64 `json:"msBuf"`
+		MbitRate             float64 `json:"mbitRate"`
+	} `json:"send"`
+	Recv struct {
+		Packets int64 `json:"packets"`
+	} `json:"recv"`
+}
+
+// parseSample reads a statistics line. Reports of the receiving socket,
+// facing ffmpeg, are skipped: only the sending side says anything about the
+// link.
+func parseSample(line string) (sample, bool) {
+	var s sample
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "{") {
+		return s, false
+	}
+	if err := json.Unmarshal([]byte(line), &s); err != nil {
+		return s, false
+	}
+	if s.Send.Packets == 0 && s.Recv.Packets > 0 {
+		return s, false
+	}
+	return s, true
+}
+
+// add folds a report into the stats
+func (st *Stats) add(s sample, now time.Time) {
+	st.RTTMs = s.Link.RTT
+	st.BandwidthMbps = s.Link.Bandwidth
+	st.SendRateMbps = s.Send.MbitRate
+	st.SendBufferMs = s.Send.MsBuf
+	st.SendBufferPackets = s.Send.PacketsBuf
+	st.FlightPackets = s.Window.Flight
+
+	st.PacketsSent += s.Send.Packets
+	st.PacketsLost += s.Send.PacketsLost
+	st.PacketsRetransmitted += s.Send.PacketsRetransmitted
+	st.PacketsDropped += s.Send.PacketsDropped
+
+	st.LossPercent, st.RetransPercent = 0, 0
+	if s.Send.Packets > 0 {
+		st.LossPercent = float64(s.Send.PacketsLost) * 100 / float64(s.Send.Packets)
+		st.RetransPercent = float64(s.Send.PacketsRetransmitted) * 100 / float64(s.Send.Packets)
+	}
+	st.UpdatedAt = now
+}
@@ -0,0 +1,43 @@
+package srt
+
+import (
+	"testing"
+	"time"
+)
+
+const sendReport = `{"sid":1040,"time":2034,"window":{"flow":8192,"congestion":8192,"flight":37},"link":{"rtt":84.2,"bandwidth":38.5,"maxBandwidth":1000},"send":{"packets":1000,"packetsUnique":980,"packetsLost":12,"packetsDropped":1,"packetsRetransmitted":20,"packetsFilterExtra":0,"bytes":1316000,"bytesUnique":1289680,"bytesDropped":1316,"byteAvailBuf":12058624,"msBuf":160,"mbitRate":5.9,"sendPeriod":1.8,"packetsBuf":42},"recv":{"packets":0,"packetsUnique":0,"packetsLost":0,"packetsDropped":0,"packetsRetransmitted":0,"packetsBelated":0,"packetsFilterExtra":0,"packetsFilterSupply":0,"packetsFilterLoss":0,"bytes":0,"bytesUnique":0,"bytesLost":0,"bytesDropped":0,"mbitRate":0}}`
+
+const recvReport = `{"sid":1039,"time":2034,"window":{"flow":8192,"congestion":8192,"flight":0},"link":{"rtt":0.3,"bandwidth":900,"maxBandwidth":1000},"send":{"packets":0,"packetsLost":0},"recv":{"packets":1000,"mbitRate":5.9}}`
+
+func TestParseSample(t *testing.T) {
+	if _, ok := parseSample(sendReport); !ok {
+		t.Fatal("Expected the sending report to parse")
+	}
+	if _, ok := parseSample(recvReport); ok {
+		t.Error("Expected the receiving socket's report to be skipped")
+	}
+	if _, ok := parseSample("Accepted SRT source connection"); ok {
+		t.Error("Expected a log line not to parse")
+	}
+}
+
+func TestStatsAdd(t *testing.T) {
+	s, _ := parseSample(sendReport)
+	var st Stats
+	now := time.Now()
+	st.add(s, now)
+	st.add(s, now)
+
+	if st.RTTMs != 84.2 || st.BandwidthMbps != 38.5 || st.SendRateMbps != 5.9 {
+		t.Errorf("Unexpected link stats: %+v", st)
+	}
+	if st.PacketsSent != 2000 || st.PacketsLost != 24 || st.PacketsRetransmitted != 40 || st.PacketsDropped != 2 {
+		t.Errorf("Expected counters to accumulate, got %+v", st)
+	}
+	if st.LossPercent != 1.2 || st.RetransPercent != 2 {
+		t.Errorf("Expected 1.2%% loss and 2%% retransmitted, got %v and %v", st.LossPercent, st.RetransPercent)
+	}
+	if st.SendBufferMs != 160 || st.SendBufferPackets != 42 || st.FlightPackets != 37 {
+		t.Errorf("Unexpected buffer stats: %+v", st)
+	}
+}
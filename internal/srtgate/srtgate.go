@@ -0,0 +1,472 @@
+// Package srtgate accepts SRT callers on one UDP port by the streamid they
+// call with and hands each on to the loopback SRT listener set up for it.
+// Callers whose streamid isn't routed are rejected during the handshake.
+//
+// The gate answers the caller's induction itself so it can read the
+// streamid from the conclusion before any listener sees the caller. It then
+// runs the handshake again with the listener and relays datagrams both ways
+// for the rest of the connection. Encryption is negotiated between the
+// caller and the listener and passes through unchanged.
+package srtgate
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Handshake fields, from the SRT specification
+const (
+	headerSize    = 16
+	handshakeSize = headerSize + 48 // header and handshake body, before extensions
+
+	hsVersion4   = 4
+	hsVersion5   = 5
+	hsMagic      = 0x4A17 // extension field of a listener's HSv5 induction
+	hsInduction  = 1
+	hsConclusion = 0xFFFFFFFF
+	extStreamID  = 5 // SRT_CMD_SID
+
+	// RejectForbidden is the SRT_REJX_FORBIDDEN reason callers whose
+	// streamid isn't routed are rejected with
+	RejectForbidden = 1403
+)
+
+// Timing of sessions
+const (
+	SessionTimeout   = 10 * time.Second       // SRT keepalives every second keep a session well within this
+	listenerTimeout  = 250 * time.Millisecond // per induction sent to a listener
+	listenerAttempts = 4
+	rejectQuiet      = 5 * time.Second // a caller retrying with a rejected streamid is reported once in this long
+)
+
+// maxDatagram fits the largest UDP datagram
+const maxDatagram = 65536
+
+// Route is where callers with one streamid are handed on to
+type Route struct {
+	Name string
+	Port int // loopback UDP port of the route's SRT listener
+}
+
+// Event reports a caller being accepted or rejected
+type Event struct {
+	Remote   string
+	Route    string // "" when rejected
+	StreamID string // set when rejected, so a mistyped one can be spotted
+	Err      error  // set when the route's listener didn't answer
+}
+
+// session is one caller and its connection to a listener
+type session struct {
+	peer     *net.UDPAddr
+	callerID uint32 // the caller's SRT socket ID
+	route    Route
+
+	mu       sync.Mutex
+	upstream *net.UDPConn // nil while the listener handshake is under way
+	cookie   uint32       // the listener's SYN cookie
+	last     time.Time
+	closed   bool
+}
+
+func (s *session) touch() {
+	s.mu.Lock()
+	s.last = time.Now()
+	s.mu.Unlock()
+}
+
+// Gate routes SRT callers by streamid
+type Gate struct {
+	routes  map[string]Route
+	onEvent func(Event)
+	secret  []byte
+
+	mu       sync.Mutex
+	sessions map[string]*session  // by caller address
+	rejected map[string]time.Time // caller address and streamid, when last reported
+}
+
+// New returns a gate routing each streamid of routes. A streamid in SRT's
+// access control syntax, "#!::r=name,...", also matches a route keyed by
+// its resource name. onEvent, if not nil, is called as callers are accepted
+// or rejected.
+func New(routes map[string]Route, onEvent func(Event)) *Gate {
+	secret := make([]byte, 32)
+	_, _ = rand.Read(secret)
+	return &Gate{
+		routes:   routes,
+		onEvent:  onEvent,
+		secret:   secret,
+		sessions: make(map[string]*session),
+		rejected: make(map[string]time.Time),
+	}
+}
+
+// Run listens on addr until ctx is done
+func (g *Gate) Run(ctx context.Context, addr string) error {
+	udpAddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return err
+	}
+	conn, err := net.ListenUDP("udp", udpAddr)
+	if err != nil {
+		return fmt.Errorf("listen on %s: %w", addr, err)
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				conn.Close()
+				return
+			case <-stop:
+				conn.Close()
+				return
+			case <-ticker.C:
+				g.expire(time.Now())
+			}
+		}
+	}()
+	defer g.closeAll()
+
+	buf := make([]byte, maxDatagram)
+	for {
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		g.handle(conn, from, buf[:n])
+	}
+}
+
+// handle answers or relays one datagram from a caller
+func (g *Gate) handle(conn *net.UDPConn, from *net.UDPAddr, pkt []byte) {
+	g.mu.Lock()
+	s := g.sessions[from.String()]
+	g.mu.Unlock()
+
+	if !isHandshake(pkt) {
+		if s != nil {
+			s.mu.Lock()
+			up := s.upstream
+			s.last = time.Now()
+			s.mu.Unlock()
+			if up != nil {
+				_, _ = up.Write(pkt)
+			}
+		}
+		return
+	}
+
+	switch binary.BigEndian.Uint32(pkt[36:]) {
+	case hsInduction:
+		_, _ = conn.WriteToUDP(g.inductionResponse(pkt, from), from)
+	case hsConclusion:
+		if s != nil && s.callerID != binary.BigEndian.Uint32(pkt[40:]) {
+			// the caller has started over with a new socket
+			g.close(s)
+			s = nil
+		}
+		if s != nil {
+			// a retransmitted conclusion goes to the listener with its
+			// cookie; one that arrives while the listener is being
+			// called is dropped, and the caller sends it again
+			s.mu.Lock()
+			up, cookie := s.upstream, s.cookie
+			s.last = time.Now()
+			s.mu.Unlock()
+			if up != nil {
+				_, _ = up.Write(withCookie(pkt, cookie))
+			}
+			return
+		}
+		if !g.validCookie(binary.BigEndian.Uint32(pkt[44:]), from, time.Now()) {
+			return
+		}
+		id := StreamID(pkt)
+		route, ok := g.route(id)
+		if !ok {
+			_, _ = conn.WriteToUDP(rejection(pkt, RejectForbidden), from)
+			g.reportRejected(from, id)
+			return
+		}
+		s = &session{peer: from, callerID: binary.BigEndian.Uint32(pkt[40:]), route: route, last: time.Now()}
+		g.mu.Lock()
+		g.sessions[from.String()] = s
+		g.mu.Unlock()
+		go g.connect(conn, s, append([]byte(nil), pkt...))
+	}
+}
+
+// connect runs the handshake with the route's listener and relays what the
+// listener sends back to the caller until the session ends
+func (g *Gate) connect(conn *net.UDPConn, s *session, conclusion []byte) {
+	up, err := net.DialUDP("udp", nil, &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: s.route.Port})
+	if err == nil {
+		var cookie uint32
+		if cookie, err = induce(up, conclusion); err == nil {
+			s.mu.Lock()
+			if s.closed {
+				err = errors.New("caller started over")
+			} else {
+				s.upstream, s.cookie = up, cookie
+			}
+			s.mu.Unlock()
+			if err == nil {
+				_, err = up.Write(withCookie(conclusion, cookie))
+			}
+		}
+		if err != nil {
+			up.Close()
+		}
+	}
+	if err != nil {
+		// the caller's next conclusion tries again
+		g.remove(s)
+		g.report(Event{Remote: s.peer.String(), Route: s.route.Name, Err: err})
+		return
+	}
+	g.report(Event{Remote: s.peer.String(), Route: s.route.Name})
+
+	buf := make([]byte, maxDatagram)
+	for {
+		n, err := up.Read(buf)
+		if err != nil {
+			// closed when the session ends; a listener that went away
+			// leaves the session to expire
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+		s.touch()
+		_, _ = conn.WriteToUDP(buf[:n], s.peer)
+	}
+}
+
+// induce sends the listener an induction made from the caller's conclusion
+// and returns the listener's SYN cookie
+func induce(up *net.UDPConn, conclusion []byte) (uint32, error) {
+	req := make([]byte, handshakeSize)
+	copy(req, conclusion)
+	binary.BigEndian.PutUint32(req[4:], 0)
+	binary.BigEndian.PutUint32(req[12:], 0)
+	binary.BigEndian.PutUint32(req[16:], hsVersion4)
+	binary.BigEndian.PutUint16(req[20:], 0)
+	binary.BigEndian.PutUint16(req[22:], 2) // what HSv4 callers put there
+	binary.BigEndian.PutUint32(req[36:], hsInduction)
+	binary.BigEndian.PutUint32(req[44:], 0)
+
+	buf := make([]byte, maxDatagram)
+	for attempt := 0; attempt < listenerAttempts; attempt++ {
+		if _, err := up.Write(req); err != nil {
+			return 0, err
+		}
+		deadline := time.Now().Add(listenerTimeout)
+		_ = up.SetReadDeadline(deadline)
+		for {
+			n, err := up.Read(buf)
+			if err != nil {
+				break
+			}
+			resp := buf[:n]
+			if isHandshake(resp) && binary.BigEndian.Uint32(resp[36:]) == hsInduction {
+				_ = up.SetReadDeadline(time.Time{})
+				return binary.BigEndian.Uint32(resp[44:]), nil
+			}
+		}
+	}
+	_ = up.SetReadDeadline(time.Time{})
+	return 0, errors.New("listener did not answer the handshake")
+}
+
+// inductionResponse answers a caller's induction as an HSv5 listener does
+func (g *Gate) inductionResponse(req []byte, from *net.UDPAddr) []byte {
+	resp := make([]byte, handshakeSize)
+	copy(resp, req)
+	binary.BigEndian.PutUint32(resp[4:], 0)
+	binary.BigEndian.PutUint32(resp[12:], binary.BigEndian.Uint32(req[40:])) // to the caller's socket
+	binary.BigEndian.PutUint32(resp[16:], hsVersion5)
+	binary.BigEndian.PutUint16(resp[20:], 0) // no key length advertised
+	binary.BigEndian.PutUint16(resp[22:], hsMagic)
+	binary.BigEndian.PutUint32(resp[44:], g.cookie(from, time.Now()))
+	return resp
+}
+
+// rejection answers a caller's conclusion with a rejection reason
+func rejection(req []byte, reason uint32) []byte {
+	resp := make([]byte, handshakeSize)
+	copy(resp, req)
+	binary.BigEndian.PutUint32(resp[4:], 0)
+	binary.BigEndian.PutUint32(resp[12:], binary.BigEndian.Uint32(req[40:]))
+	binary.BigEndian.PutUint32(resp[16:], hsVersion5)
+	binary.BigEndian.PutUint32(resp[36:], reason)
+	return resp
+}
+
+// withCookie returns a copy of a handshake with its SYN cookie replaced
+func withCookie(pkt []byte, cookie uint32) []byte {
+	out := append([]byte(nil), pkt...)
+	binary.BigEndian.PutUint32(out[44:], cookie)
+	return out
+}
+
+// cookie is the SYN cookie of a caller for the minute of now
+func (g *Gate) cookie(from *net.UDPAddr, now time.Time) uint32 {
+	mac := hmac.New(sha256.New, g.secret)
+	fmt.Fprintf(mac, "%s|%d", from, now.Unix()/60)
+	return binary.BigEndian.Uint32(mac.Sum(nil))
+}
+
+// validCookie accepts cookies of this minute and the one before
+func (g *Gate) validCookie(cookie uint32, from *net.UDPAddr, now time.Time) bool {
+	return cookie == g.cookie(from, now) || cookie == g.cookie(from, now.Add(-time.Minute))
+}
+
+// route returns the route of a streamid
+func (g *Gate) route(id string) (Route, bool) {
+	if r, ok := g.routes[id]; ok {
+		return r, true
+	}
+	if name := Resource(id); name != "" {
+		r, ok := g.routes[name]
+		return r, ok
+	}
+	return Route{}, false
+}
+
+func (g *Gate) reportRejected(from *net.UDPAddr, id string) {
+	key := from.String() + "|" + id
+	now := time.Now()
+	g.mu.Lock()
+	last, seen := g.rejected[key]
+	if !seen || now.Sub(last) >= rejectQuiet {
+		g.rejected[key] = now
+	}
+	g.mu.Unlock()
+	if !seen || now.Sub(last) >= rejectQuiet {
+		g.report(Event{Remote: from.String(), StreamID: id})
+	}
+}
+
+func (g *Gate) report(ev Event) {
+	if g.onEvent != nil {
+		g.onEvent(ev)
+	}
+}
+
+// close ends a session
+func (g *Gate) close(s *session) {
+	g.remove(s)
+	s.mu.Lock()
+	s.closed = true
+	if s.upstream != nil {
+		s.upstream.Close()
+	}
+	s.mu.Unlock()
+}
+
+// remove drops a session
+func (g *Gate) remove(s *session) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.sessions[s.peer.String()] == s {
+		delete(g.sessions, s.peer.String())
+	}
+}
+
+// expire closes sessions that have gone quiet
+func (g *Gate) expire(now time.Time) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for key, s := range g.sessions {
+		s.mu.Lock()
+		idle := now.Sub(s.last) >= SessionTimeout
+		if idle {
+			s.closed = true
+			if s.upstream != nil {
+				s.upstream.Close()
+			}
+		}
+		s.mu.Unlock()
+		if idle {
+			delete(g.sessions, key)
+		}
+	}
+	for key, at := range g.rejected {
+		if now.Sub(at) >= rejectQuiet {
+			delete(g.rejected, key)
+		}
+	}
+}
+
+func (g *Gate) closeAll() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for key, s := range g.sessions {
+		s.mu.Lock()
+		s.closed = true
+		if s.upstream != nil {
+			s.upstream.Close()
+		}
+		s.mu.Unlock()
+		delete(g.sessions, key)
+	}
+}
+
+// isHandshake reports whether pkt is an SRT handshake control packet
+func isHandshake(pkt []byte) bool {
+	return len(pkt) >= handshakeSize && binary.BigEndian.Uint16(pkt) == 0x8000
+}
+
+// StreamID returns the streamid extension of a conclusion handshake, ""
+// when it has none. SRT sends the streamid in 32-bit words whose bytes are
+// reversed.
+func StreamID(pkt []byte) string {
+	for off := handshakeSize; off+4 <= len(pkt); {
+		typ := binary.BigEndian.Uint16(pkt[off:])
+		end := off + 4 + 4*int(binary.BigEndian.Uint16(pkt[off+2:]))
+		if end > len(pkt) {
+			return ""
+		}
+		if typ == extStreamID {
+			id := make([]byte, 0, end-off-4)
+			for i := off + 4; i < end; i += 4 {
+				id = append(id, pkt[i+3], pkt[i+2], pkt[i+1], pkt[i])
+			}
+			return strings.TrimRight(string(id), "\x00")
+		}
+		off = end
+	}
+	return ""
+}
+
+// Resource returns the r= value of a streamid in SRT's access control
+// syntax, "#!::r=name,m=publish", or "" for any other streamid
+func Resource(id string) string {
+	rest, ok := strings.CutPrefix(id, "#!::")
+	if !ok {
+		return ""
+	}
+	for _, kv := range strings.Split(rest, ",") {
+		if v, ok := strings.CutPrefix(kv, "r="); ok {
+			return v
+		}
+	}
+	return ""
+}
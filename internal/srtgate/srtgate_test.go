@@ -0,0 +1,219 @@
+package srtgate
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+const (
+	callerSocket   = 0x1111
+	listenerSocket = 0x2222
+	listenerCookie = 0xC0FFEE
+)
+
+// handshake returns a handshake from socket with an optional streamid
+// extension, its words byte-reversed as SRT sends them
+func handshake(version, reqType, socket, cookie uint32, streamID string) []byte {
+	pkt := make([]byte, handshakeSize)
+	binary.BigEndian.PutUint16(pkt, 0x8000)
+	binary.BigEndian.PutUint32(pkt[16:], version)
+	binary.BigEndian.PutUint32(pkt[36:], reqType)
+	binary.BigEndian.PutUint32(pkt[40:], socket)
+	binary.BigEndian.PutUint32(pkt[44:], cookie)
+	if streamID != "" {
+		id := []byte(streamID)
+		for len(id)%4 != 0 {
+			id = append(id, 0)
+		}
+		ext := make([]byte, 4, 4+len(id))
+		binary.BigEndian.PutUint16(ext, extStreamID)
+		binary.BigEndian.PutUint16(ext[2:], uint16(len(id)/4))
+		for i := 0; i < len(id); i += 4 {
+			ext = append(ext, id[i+3], id[i+2], id[i+1], id[i])
+		}
+		pkt = append(pkt, ext...)
+	}
+	return pkt
+}
+
+// fakeListener answers like an SRT listener and echoes data packets
+func fakeListener(t *testing.T) int {
+	t.Helper()
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buf := make([]byte, maxDatagram)
+		for {
+			n, from, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			pkt := buf[:n]
+			if !isHandshake(pkt) {
+				conn.WriteToUDP(pkt, from)
+				continue
+			}
+			socket := binary.BigEndian.Uint32(pkt[40:])
+			switch binary.BigEndian.Uint32(pkt[36:]) {
+			case hsInduction:
+				resp := handshake(hsVersion5, hsInduction, socket, listenerCookie, "")
+				binary.BigEndian.PutUint32(resp[12:], socket)
+				conn.WriteToUDP(resp, from)
+			case hsConclusion:
+				if binary.BigEndian.Uint32(pkt[44:]) != listenerCookie {
+					continue
+				}
+				resp := handshake(hsVersion5, hsConclusion, listenerSocket, 0, "")
+				binary.BigEndian.PutUint32(resp[12:], socket)
+				conn.WriteToUDP(resp, from)
+			}
+		}
+	}()
+	return conn.LocalAddr().(*net.UDPAddr).Port
+}
+
+func freeUDPPort(t *testing.T) int {
+	t.Helper()
+	c, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	return c.LocalAddr().(*net.UDPAddr).Port
+}
+
+// startGate runs a gate and returns a caller connected to it
+func startGate(t *testing.T, routes map[string]Route, events chan Event) net.Conn {
+	t.Helper()
+	port := freeUDPPort(t)
+	g := New(routes, func(ev Event) { events <- ev })
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- g.Run(ctx, net.JoinHostPort("127.0.0.1", strconv.Itoa(port))) }()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	caller, err := net.Dial("udp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { caller.Close() })
+	return caller
+}
+
+// exchange sends pkt until a reply arrives
+func exchange(t *testing.T, caller net.Conn, pkt []byte) []byte {
+	t.Helper()
+	buf := make([]byte, maxDatagram)
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		caller.Write(pkt)
+		caller.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+		n, err := caller.Read(buf)
+		if err == nil {
+			return buf[:n]
+		}
+	}
+	t.Fatal("No reply")
+	return nil
+}
+
+// connect runs a caller's handshake with streamID and returns the reply
+// to its conclusion
+func connect(t *testing.T, caller net.Conn, streamID string) []byte {
+	t.Helper()
+	induction := exchange(t, caller, handshake(hsVersion4, hsInduction, callerSocket, 0, ""))
+	if v := binary.BigEndian.Uint32(induction[16:]); v != hsVersion5 {
+		t.Fatalf("Induction answered with version %d", v)
+	}
+	if magic := binary.BigEndian.Uint16(induction[22:]); magic != hsMagic {
+		t.Fatalf("Induction answered with extension field %#x", magic)
+	}
+	cookie := binary.BigEndian.Uint32(induction[44:])
+	return exchange(t, caller, handshake(hsVersion5, hsConclusion, callerSocket, cookie, streamID))
+}
+
+func TestGateAcceptsRoutedStreamID(t *testing.T) {
+	events := make(chan Event, 4)
+	routes := map[string]Route{"cam1-key": {Name: "cam1", Port: fakeListener(t)}}
+	caller := startGate(t, routes, events)
+
+	resp := connect(t, caller, "#!::r=cam1-key,m=publish")
+	if typ := binary.BigEndian.Uint32(resp[36:]); typ != hsConclusion {
+		t.Fatalf("Conclusion answered with %d", typ)
+	}
+	if socket := binary.BigEndian.Uint32(resp[40:]); socket != listenerSocket {
+		t.Errorf("Expected the listener's answer, got socket %#x", socket)
+	}
+	if ev := <-events; ev.Route != "cam1" || ev.Err != nil {
+		t.Errorf("Unexpected event %+v", ev)
+	}
+
+	data := []byte("data packet, no control bit set")
+	if got := exchange(t, caller, data); string(got) != string(data) {
+		t.Errorf("Relayed %q", got)
+	}
+}
+
+func TestGateRejectsUnknownStreamID(t *testing.T) {
+	events := make(chan Event, 4)
+	routes := map[string]Route{"cam1-key": {Name: "cam1", Port: fakeListener(t)}}
+	caller := startGate(t, routes, events)
+
+	resp := connect(t, caller, "wrong-key")
+	if typ := binary.BigEndian.Uint32(resp[36:]); typ != RejectForbidden {
+		t.Fatalf("Expected rejection %d, got %d", RejectForbidden, typ)
+	}
+	if socket := binary.BigEndian.Uint32(resp[12:]); socket != callerSocket {
+		t.Errorf("Rejection sent to socket %#x", socket)
+	}
+	if ev := <-events; ev.Route != "" || ev.StreamID != "wrong-key" {
+		t.Errorf("Unexpected event %+v", ev)
+	}
+}
+
+func TestGateIgnoresForgedCookie(t *testing.T) {
+	events := make(chan Event, 4)
+	routes := map[string]Route{"cam1-key": {Name: "cam1", Port: fakeListener(t)}}
+	caller := startGate(t, routes, events)
+
+	caller.Write(handshake(hsVersion5, hsConclusion, callerSocket, 1234, "cam1-key"))
+	caller.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	if _, err := caller.Read(make([]byte, maxDatagram)); err == nil {
+		t.Error("Expected a conclusion without the gate's cookie to go unanswered")
+	}
+}
+
+func TestStreamID(t *testing.T) {
+	for _, id := range []string{"a", "abcd", "cam1-key", "#!::r=live/cam,m=publish"} {
+		if got := StreamID(handshake(hsVersion5, hsConclusion, 1, 0, id)); got != id {
+			t.Errorf("StreamID = %q, want %q", got, id)
+		}
+	}
+	if got := StreamID(handshake(hsVersion5, hsConclusion, 1, 0, "")); got != "" {
+		t.Errorf("Expected no streamid, got %q", got)
+	}
+}
+
+func TestResource(t *testing.T) {
+	tests := map[string]string{
+		"#!::r=cam1,m=publish": "cam1",
+		"#!::m=publish,r=cam2": "cam2",
+		"#!::m=publish":        "",
+		"cam1":                 "",
+	}
+	for id, want := range tests {
+		if got := Resource(id); got != want {
+			t.Errorf("Resource(%q) = %q, want %q", id, got, want)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+// Package startpolicy checks the links a stream would go out on against the
+// conditions it has to meet before going live: how many links are up, how
+// much they measured they can upload together, and links that must be
+// among them. It keeps a stream from being started on one flaky link by
+// someone who didn't notice the others were down.
+package startpolicy
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Names of the checks
+const (
+	CheckMinLinks     = "min_links"
+	CheckMinBandwidth = "min_bandwidth"
+	CheckRequiredLink = "required_link"
+)
+
+// Policy is what has to be true of the links before a stream starts. The
+// zero Policy lets any stream start.
+type Policy struct {
+	MinLinks         int
+	MinBandwidthKbps int
+	RequiredLinks    []string // interface names or bind IPs
+}
+
+// Link is a link the stream would bond over
+type Link struct {
+	BindIP    string  `json:"bind_ip"`
+	Interface string  `json:"interface,omitempty"`
+	Kbps      float64 `json:"kbps,omitempty"` // upload the last speed test measured
+	Tested    bool    `json:"tested"`         // a recent enough speed test measured the link
+}
+
+// Check is one condition of a policy and whether the links meet it
+type Check struct {
+	Name    string `json:"name"`
+	Met     bool   `json:"met"`
+	Message string `json:"message"`
+}
+
+// Enabled reports whether the policy has any conditions
+func (p Policy) Enabled() bool {
+	return p.MinLinks > 0 || p.MinBandwidthKbps > 0 || len(p.RequiredLinks) > 0
+}
+
+// Evaluate checks links against each of the policy's conditions
+func (p Policy) Evaluate(links []Link) []Check {
+	var checks []Check
+
+	if p.MinLinks > 0 {
+		c := Check{Name: CheckMinLinks, Met: len(links) >= p.MinLinks}
+		c.Message = fmt.Sprintf("%d of at least %d links up", len(links), p.MinLinks)
+		checks = append(checks, c)
+	}
+
+	if p.MinBandwidthKbps > 0 {
+		var total float64
+		tested := 0
+		for _, l := range links {
+			if l.Tested {
+				total += l.Kbps
+				tested++
+			}
+		}
+		c := Check{Name: CheckMinBandwidth, Met: total >= float64(p.MinBandwidthKbps)}
+		switch {
+		case tested == 0:
+			c.Message = fmt.Sprintf("no speed test of the links up; at least %d kbps needed", p.MinBandwidthKbps)
+		default:
+			c.Message = fmt.Sprintf("%.0f of at least %d kbps measured over %d of %d links", total, p.MinBandwidthKbps, tested, len(links))
+		}
+		checks = append(checks, c)
+	}
+
+	for _, name := range p.RequiredLinks {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		up := slices.ContainsFunc(links, func(l Link) bool { return l.BindIP == name || l.Interface == name })
+		c := Check{Name: CheckRequiredLink, Met: up, Message: name + " is up"}
+		if !up {
+			c.Message = name + " is not up"
+		}
+		checks = append(checks, c)
+	}
+	return checks
+}
+
+// Unmet returns the checks that weren't met
+func Unmet(checks []Check) []Check {
+	var unmet []Check
+	for _, c := range checks {
+		if !c.Met {
+			unmet = append(unmet, c)
+		}
+	}
+	return unmet
+}
+
+// Summary joins the messages of checks
+func Summary(checks []Check) string {
+	msgs := make([]string, len(checks))
+	for i, c := range checks {
+		msgs[i] = c.Message
+	}
+	return strings.Join(msgs, "; ")
+}
@@ -0,0 +1,53 @@
+package startpolicy
+
+import "testing"
+
+func TestEvaluate(t *testing.T) {
+	links := []Link{
+		{BindIP: "10.0.0.2", Interface: "wwan0", Kbps: 3000, Tested: true},
+		{BindIP: "10.0.1.2", Interface: "wwan1", Kbps: 2500, Tested: true},
+		{BindIP: "192.168.8.100", Interface: "usb0"},
+	}
+
+	cases := []struct {
+		name   string
+		policy Policy
+		links  []Link
+		unmet  []string
+	}{
+		{"no policy", Policy{}, nil, nil},
+		{"enough links", Policy{MinLinks: 2}, links, nil},
+		{"too few links", Policy{MinLinks: 2}, links[:1], []string{CheckMinLinks}},
+		{"enough bandwidth", Policy{MinBandwidthKbps: 5000}, links, nil},
+		{"too little bandwidth", Policy{MinBandwidthKbps: 6000}, links, []string{CheckMinBandwidth}},
+		{"untested", Policy{MinBandwidthKbps: 1}, links[2:], []string{CheckMinBandwidth}},
+		{"required by interface and IP", Policy{RequiredLinks: []string{"wwan1", "192.168.8.100"}}, links, nil},
+		{"required link down", Policy{RequiredLinks: []string{"wwan1", " wwan2 "}}, links, []string{CheckRequiredLink}},
+		{"all unmet", Policy{MinLinks: 3, MinBandwidthKbps: 1000, RequiredLinks: []string{"wwan0"}}, nil,
+			[]string{CheckMinLinks, CheckMinBandwidth, CheckRequiredLink}},
+	}
+	for _, c := range cases {
+		checks := c.policy.Evaluate(c.links)
+		unmet := Unmet(checks)
+		if len(unmet) != len(c.unmet) {
+			t.Errorf("%s: expected unmet %v, got %+v", c.name, c.unmet, unmet)
+			continue
+		}
+		for i, u := range unmet {
+			if u.Name != c.unmet[i] || u.Message == "" {
+				t.Errorf("%s: expected unmet %v, got %+v", c.name, c.unmet, unmet)
+			}
+		}
+	}
+
+	if (Policy{}).Enabled() || !(Policy{RequiredLinks: []string{"wwan0"}}).Enabled() {
+		t.Error("Expected a policy to be enabled only with conditions")
+	}
+}
+
+func TestSummary(t *testing.T) {
+	checks := Policy{MinLinks: 2, RequiredLinks: []string{"wwan0"}}.Evaluate(nil)
+	if got, want := Summary(checks), "0 of at least 2 links up; wwan0 is not up"; got != want {
+		t.Errorf("Summary = %q, want %q", got, want)
+	}
+}
@@ -1,8 +1,14 @@
+// Package stats keeps the manager's metrics and recent log lines. Metrics
+// come from registered sources and every snapshot is written to a set of
+// sinks: the in-memory history behind /api/status and, when configured,
+// Prometheus, a file on disk and an MQTT broker.
 package stats
 
 import (
 	"sync"
 	"time"
+
+	"srtla-manager/internal/logger"
 )
 
 const (
@@ -10,81 +16,231 @@ const (
 	HistoryInterval = time.Second
 )
 
-type DataPoint struct {
-	Timestamp     time.Time `json:"timestamp"`
-	FFmpegBitrate float64   `json:"ffmpeg_bitrate"`
-	SRTLABitrate  float64   `json:"srtla_bitrate"`
-	FPS           float64   `json:"fps"`
+// Metrics the history is built from
+const (
+	MetricFFmpegBitrate = "ffmpeg_bitrate_kbps"
+	MetricFFmpegFPS     = "ffmpeg_fps"
+	MetricSRTLABitrate  = "srtla_bitrate_kbps"
+)
+
+// Kind is how a metric's value behaves
+type Kind string
+
+const (
+	Gauge   Kind = "gauge"   // goes up and down
+	Counter Kind = "counter" // only goes up, e.g. packets sent
+)
+
+// Metric describes a series
+type Metric struct {
+	Name string
+	Help string
+	Kind Kind
+}
+
+// Sample is one value of a metric, optionally split by labels
+type Sample struct {
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Value  float64           `json:"value"`
+}
+
+// Snapshot is every sample taken at one time
+type Snapshot struct {
+	Time    time.Time `json:"time"`
+	Samples []Sample  `json:"samples"`
+}
+
+// Value returns the unlabelled sample of a metric, or 0
+func (s Snapshot) Value(name string) float64 {
+	for _, sample := range s.Samples {
+		if sample.Name == name && len(sample.Labels) == 0 {
+			return sample.Value
+		}
+	}
+	return 0
+}
+
+// AddFunc adds a sample. labels are name, value pairs.
+type AddFunc func(name string, value float64, labels ...string)
+
+// Source adds its current samples to a snapshot
+type Source func(add AddFunc)
+
+// Sink receives every snapshot the collector takes
+type Sink interface {
+	Write(Snapshot) error
+	Close() error
+}
+
+type namedSink struct {
+	name    string
+	sink    Sink
+	lastErr string
 }
 
+// Collector is the metric registry. Collect takes a snapshot from the
+// sources and hands it to the sinks.
 type Collector struct {
-	mu      sync.RWMutex
-	history []DataPoint
-	pos     int
-	full    bool
+	mu      sync.Mutex
+	metrics []Metric
+	sources []Source
+	sinks   []*namedSink
+	ring    *Ring
 }
 
+// NewCollector returns a collector with the in-memory history as its only
+// sink
 func NewCollector() *Collector {
+	ring := NewRing(HistorySize)
 	return &Collector{
-		history: make([]DataPoint, HistorySize),
+		ring:  ring,
+		sinks: []*namedSink{{name: "history", sink: ring}},
+	}
+}
+
+// Register describes metrics for sinks that need to know their type
+func (c *Collector) Register(metrics ...Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, m := range metrics {
+		if _, ok := c.find(m.Name); !ok {
+			c.metrics = append(c.metrics, m)
+		}
+	}
+}
+
+// Metrics returns the registered metrics
+func (c *Collector) Metrics() []Metric {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Metric(nil), c.metrics...)
+}
+
+// Lookup returns a registered metric
+func (c *Collector) Lookup(name string) (Metric, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.find(name)
+}
+
+func (c *Collector) find(name string) (Metric, bool) {
+	for _, m := range c.metrics {
+		if m.Name == name {
+			return m, true
+		}
 	}
+	return Metric{}, false
 }
 
-func (c *Collector) Record(ffmpegBitrate, srtlaBitrate, fps float64) {
+// AddSource adds a source of samples
+func (c *Collector) AddSource(s Source) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	c.sources = append(c.sources, s)
+}
 
-	c.history[c.pos] = DataPoint{
-		Timestamp:     time.Now(),
-		FFmpegBitrate: ffmpegBitrate,
-		SRTLABitrate:  srtlaBitrate,
-		FPS:           fps,
+// SetSink adds, replaces or, when s is nil, removes a named sink. A
+// replaced sink is closed.
+func (c *Collector) SetSink(name string, s Sink) {
+	c.mu.Lock()
+	var old Sink
+	for i, ns := range c.sinks {
+		if ns.name == name {
+			old = ns.sink
+			c.sinks = append(c.sinks[:i], c.sinks[i+1:]...)
+			break
+		}
+	}
+	if s != nil {
+		c.sinks = append(c.sinks, &namedSink{name: name, sink: s})
 	}
+	c.mu.Unlock()
 
-	c.pos = (c.pos + 1) % HistorySize
-	if c.pos == 0 {
-		c.full = true
+	if old != nil && old != s {
+		old.Close()
 	}
 }
 
-func (c *Collector) History() []DataPoint {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	var result []DataPoint
-	if c.full {
-		result = make([]DataPoint, HistorySize)
-		copy(result, c.history[c.pos:])
-		copy(result[HistorySize-c.pos:], c.history[:c.pos])
-	} else {
-		result = make([]DataPoint, c.pos)
-		copy(result, c.history[:c.pos])
+// SinkErrors returns the last error of each failing sink
+func (c *Collector) SinkErrors() map[string]string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	errs := map[string]string{}
+	for _, ns := range c.sinks {
+		if ns.lastErr != "" {
+			errs[ns.name] = ns.lastErr
+		}
 	}
-	return result
+	return errs
 }
 
-func (c *Collector) LatestBitrate() (ffmpeg, srtla float64) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+// Collect takes a snapshot and writes it to every sink. A failing sink is
+// logged when its error changes, not on every snapshot.
+func (c *Collector) Collect() Snapshot {
+	c.mu.Lock()
+	sources := append([]Source(nil), c.sources...)
+	sinks := append([]*namedSink(nil), c.sinks...)
+	c.mu.Unlock()
 
-	if !c.full && c.pos == 0 {
-		return 0, 0
+	snap := Snapshot{Time: time.Now()}
+	add := func(name string, value float64, labels ...string) {
+		snap.Samples = append(snap.Samples, Sample{Name: name, Labels: labelMap(labels), Value: value})
 	}
+	for _, src := range sources {
+		src(add)
+	}
+
+	for _, ns := range sinks {
+		err := ns.sink.Write(snap)
+		msg := ""
+		if err != nil {
+			msg = err.Error()
+		}
+		c.mu.Lock()
+		changed := msg != ns.lastErr
+		ns.lastErr = msg
+		c.mu.Unlock()
+		if changed && err != nil {
+			logger.Warn("Stats: %s sink: %v", ns.name, err)
+		} else if changed {
+			logger.Info("Stats: %s sink recovered", ns.name)
+		}
+	}
+	return snap
+}
 
-	idx := c.pos - 1
-	if idx < 0 {
-		idx = HistorySize - 1
+func labelMap(pairs []string) map[string]string {
+	if len(pairs) < 2 {
+		return nil
 	}
+	labels := make(map[string]string, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		labels[pairs[i]] = pairs[i+1]
+	}
+	return labels
+}
+
+// Snapshots returns the snapshots in the in-memory history, oldest first
+func (c *Collector) Snapshots() []Snapshot {
+	return c.ring.Snapshots()
+}
 
-	return c.history[idx].FFmpegBitrate, c.history[idx].SRTLABitrate
+// History returns the in-memory history, oldest first
+func (c *Collector) History() []DataPoint {
+	return c.ring.History()
+}
+
+func (c *Collector) LatestBitrate() (ffmpeg, srtla float64) {
+	latest, ok := c.ring.Latest()
+	if !ok {
+		return 0, 0
+	}
+	return latest.Value(MetricFFmpegBitrate), latest.Value(MetricSRTLABitrate)
 }
 
 func (c *Collector) Clear() {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	c.history = make([]DataPoint, HistorySize)
-	c.pos = 0
-	c.full = false
+	c.ring.Clear()
 }
 
 type LogBuffer struct {
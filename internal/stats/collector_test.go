@@ -0,0 +1,179 @@
+package stats
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func testCollector() *Collector {
+	c := NewCollector()
+	c.Register(
+		Metric{Name: MetricFFmpegBitrate, Help: "Bitrate in kbps", Kind: Gauge},
+		Metric{Name: "link_bitrate_kbps", Kind: Gauge},
+	)
+	c.AddSource(func(add AddFunc) {
+		add(MetricFFmpegBitrate, 4500)
+		add(MetricFFmpegFPS, 30)
+		add("link_bitrate_kbps", 2000, "ip", "10.0.0.2")
+		add("link_bitrate_kbps", 2500, "ip", "10.0.1.2")
+	})
+	return c
+}
+
+func TestCollectorHistory(t *testing.T) {
+	c := testCollector()
+	c.Collect()
+	c.Collect()
+
+	history := c.History()
+	if len(history) != 2 {
+		t.Fatalf("got %d points, want 2", len(history))
+	}
+	if history[1].FFmpegBitrate != 4500 || history[1].FPS != 30 {
+		t.Errorf("got %+v", history[1])
+	}
+	if ff, _ := c.LatestBitrate(); ff != 4500 {
+		t.Errorf("latest bitrate %v, want 4500", ff)
+	}
+}
+
+func TestPrometheusText(t *testing.T) {
+	c := testCollector()
+	p := NewPrometheus(c)
+	c.SetSink("prometheus", p)
+	c.Collect()
+
+	var buf bytes.Buffer
+	if err := p.WriteText(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP srtla_manager_ffmpeg_bitrate_kbps Bitrate in kbps
+# TYPE srtla_manager_ffmpeg_bitrate_kbps gauge
+srtla_manager_ffmpeg_bitrate_kbps 4500
+# TYPE srtla_manager_ffmpeg_fps untyped
+srtla_manager_ffmpeg_fps 30
+# TYPE srtla_manager_link_bitrate_kbps gauge
+srtla_manager_link_bitrate_kbps{ip="10.0.0.2"} 2000
+srtla_manager_link_bitrate_kbps{ip="10.0.1.2"} 2500
+`
+	if buf.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestStoreRotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stats.jsonl")
+	store, err := NewStore(path, time.Second, 200) // two lines per file
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 10; i++ {
+		snap := Snapshot{
+			Time:    start.Add(time.Duration(i) * 500 * time.Millisecond),
+			Samples: []Sample{{Name: MetricFFmpegBitrate, Value: float64(i)}},
+		}
+		if err := store.Write(snap); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// one snapshot a second was kept, and the two oldest rotated away
+	snaps, err := ReadStore(path, start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var values []float64
+	for _, snap := range snaps {
+		values = append(values, snap.Value(MetricFFmpegBitrate))
+	}
+	if len(values) != 3 || values[0] != 4 || values[2] != 8 {
+		t.Fatalf("got stored values %v, want [4 6 8]", values)
+	}
+
+	recent, err := ReadStore(path, start.Add(4*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recent) != 1 {
+		t.Errorf("got %d snapshots since 4s, want 1", len(recent))
+	}
+}
+
+func TestMQTTPublishes(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("can't listen: %v", err)
+	}
+	defer ln.Close()
+
+	published := make(chan []byte, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		typ, connect := readPacket(t, conn)
+		if typ != 0x10 || !bytes.HasSuffix(connect, []byte("unit-1")) {
+			t.Errorf("unexpected CONNECT %#x % x", typ, connect)
+		}
+		conn.Write([]byte{0x20, 0x02, 0x00, 0x00})
+		typ, body := readPacket(t, conn)
+		published <- append([]byte{typ}, body...)
+	}()
+
+	m, err := NewMQTT(MQTTOptions{Broker: "mqtt://" + ln.Addr().String(), Topic: "units/1", ClientID: "unit-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	m.Write(Snapshot{Time: time.Now(), Samples: []Sample{{Name: MetricFFmpegFPS, Value: 25}}})
+
+	select {
+	case packet := <-published:
+		if packet[0] != 0x31 {
+			t.Fatalf("got packet type %#x, want retained PUBLISH", packet[0])
+		}
+		body := packet[1:]
+		topicLen := int(body[0])<<8 | int(body[1])
+		if topic := string(body[2 : 2+topicLen]); topic != "units/1" {
+			t.Errorf("topic %q, want units/1", topic)
+		}
+		var snap Snapshot
+		if err := json.Unmarshal(body[2+topicLen:], &snap); err != nil {
+			t.Fatal(err)
+		}
+		if snap.Value(MetricFFmpegFPS) != 25 {
+			t.Errorf("got %+v", snap)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("nothing published")
+	}
+}
+
+// readPacket reads one MQTT packet with a remaining length under 16KB,
+// returning its type byte and body
+func readPacket(t *testing.T, r io.Reader) (byte, []byte) {
+	head := make([]byte, 2)
+	if _, err := io.ReadFull(r, head); err != nil {
+		t.Error(err)
+		return 0, nil
+	}
+	n := int(head[1] & 0x7F)
+	if head[1]&0x80 != 0 {
+		next := make([]byte, 1)
+		io.ReadFull(r, next)
+		n += int(next[0]) << 7
+	}
+	body := make([]byte, n)
+	io.ReadFull(r, body)
+	return head[0], body
+}
@@ -0,0 +1,208 @@
+package stats
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// InterfaceHistorySize is ten minutes of points at the stats tick of one
+// second
+const InterfaceHistorySize = 600
+
+// Byte counters of every network interface, labelled by interface, so
+// traffic the stream doesn't send (the web UI, camera WiFi, updates) shows
+// next to it
+const (
+	MetricInterfaceRxBytes = "interface_rx_bytes_total"
+	MetricInterfaceTxBytes = "interface_tx_bytes_total"
+)
+
+// InterfaceCounters are the byte counters of one interface
+type InterfaceCounters struct {
+	RxBytes int64
+	TxBytes int64
+}
+
+// ReadNetDev reads the counters of each interface from /proc/net/dev,
+// other than loopback. It returns nothing where there is no such file.
+func ReadNetDev() map[string]InterfaceCounters {
+	f, err := os.Open("/proc/net/dev")
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	return ParseNetDev(f)
+}
+
+// ParseNetDev parses /proc/net/dev: two header lines, then per interface
+// "name:" followed by eight receive and eight transmit counters, bytes
+// first
+func ParseNetDev(r io.Reader) map[string]InterfaceCounters {
+	counters := make(map[string]InterfaceCounters)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		name, rest, ok := strings.Cut(scanner.Text(), ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "lo" {
+			continue
+		}
+		fields := strings.Fields(rest)
+		if len(fields) < 9 {
+			continue
+		}
+		rx, err1 := strconv.ParseInt(fields[0], 10, 64)
+		tx, err2 := strconv.ParseInt(fields[8], 10, 64)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		counters[name] = InterfaceCounters{RxBytes: rx, TxBytes: tx}
+	}
+	return counters
+}
+
+// InterfacePoint is one entry of an interface's traffic history. The rates
+// are over the time since the point before.
+type InterfacePoint struct {
+	Timestamp time.Time `json:"timestamp"`
+	RxBytes   int64     `json:"rx_bytes"`
+	TxBytes   int64     `json:"tx_bytes"`
+	RxKbps    float64   `json:"rx_kbps"`
+	TxKbps    float64   `json:"tx_kbps"`
+}
+
+// InterfacePoints returns the interface samples of a snapshot, by
+// interface, without rates
+func InterfacePoints(snap Snapshot) map[string]InterfacePoint {
+	points := make(map[string]InterfacePoint)
+	for _, s := range snap.Samples {
+		name, ok := s.Labels["interface"]
+		if !ok {
+			continue
+		}
+		p := points[name]
+		switch s.Name {
+		case MetricInterfaceRxBytes:
+			p.RxBytes = int64(s.Value)
+		case MetricInterfaceTxBytes:
+			p.TxBytes = int64(s.Value)
+		default:
+			continue
+		}
+		p.Timestamp = snap.Time
+		points[name] = p
+	}
+	return points
+}
+
+// InterfaceRates fills in the rates of points, oldest first, from the
+// counters of the point before each. A counter that went back, as when an
+// interface is recreated, gives no rate for that point.
+func InterfaceRates(points []InterfacePoint) {
+	for i := 1; i < len(points); i++ {
+		prev, p := points[i-1], &points[i]
+		secs := p.Timestamp.Sub(prev.Timestamp).Seconds()
+		if secs <= 0 || p.RxBytes < prev.RxBytes || p.TxBytes < prev.TxBytes {
+			p.RxKbps, p.TxKbps = 0, 0
+			continue
+		}
+		p.RxKbps = float64(p.RxBytes-prev.RxBytes) * 8 / 1000 / secs
+		p.TxKbps = float64(p.TxBytes-prev.TxBytes) * 8 / 1000 / secs
+	}
+}
+
+// InterfaceHistory keeps the recent traffic of each interface in memory, a
+// point per snapshot. An interface that goes away keeps its history until
+// the others' no longer reaches back to it.
+type InterfaceHistory struct {
+	mu         sync.RWMutex
+	size       int
+	interfaces map[string][]InterfacePoint
+}
+
+// NewInterfaceHistory keeps up to size points per interface
+func NewInterfaceHistory(size int) *InterfaceHistory {
+	return &InterfaceHistory{size: size, interfaces: make(map[string][]InterfacePoint)}
+}
+
+func (h *InterfaceHistory) Write(snap Snapshot) error {
+	points := InterfacePoints(snap)
+	if len(points) == 0 {
+		return nil
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for name, p := range points {
+		history := h.interfaces[name]
+		if n := len(history); n > 0 {
+			pair := []InterfacePoint{history[n-1], p}
+			InterfaceRates(pair)
+			p = pair[1]
+		}
+		history = append(history, p)
+		if len(history) > h.size {
+			history = history[len(history)-h.size:]
+		}
+		h.interfaces[name] = history
+	}
+	// forget interfaces gone for longer than the history of those still
+	// there goes back
+	var oldest time.Time
+	for name := range points {
+		if t := h.interfaces[name][0].Timestamp; oldest.IsZero() || t.Before(oldest) {
+			oldest = t
+		}
+	}
+	for name, history := range h.interfaces {
+		if history[len(history)-1].Timestamp.Before(oldest) {
+			delete(h.interfaces, name)
+		}
+	}
+	return nil
+}
+
+func (h *InterfaceHistory) Close() error {
+	return nil
+}
+
+// Interfaces returns the names of the interfaces with history, sorted
+func (h *InterfaceHistory) Interfaces() []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	names := make([]string, 0, len(h.interfaces))
+	for name := range h.interfaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// History returns an interface's points at or after since, oldest first
+func (h *InterfaceHistory) History(name string, since time.Time) []InterfacePoint {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	result := []InterfacePoint{}
+	for _, p := range h.interfaces[name] {
+		if !p.Timestamp.Before(since) {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// Oldest returns the time of the oldest point kept for an interface
+func (h *InterfaceHistory) Oldest(name string) (time.Time, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	history := h.interfaces[name]
+	if len(history) == 0 {
+		return time.Time{}, false
+	}
+	return history[0].Timestamp, true
+}
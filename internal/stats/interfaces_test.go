@@ -0,0 +1,69 @@
+package stats
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const netDev = `Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+    lo:  123456     100    0    0    0     0          0         0   123456     100    0    0    0     0       0          0
+  eth0: 9876543   12000    0    0    0     0          0        10  1234567    9000    0    0    0     0       0          0
+wwan0:1000 10 0 0 0 0 0 0 2000 20 0 0 0 0 0 0
+`
+
+func TestParseNetDev(t *testing.T) {
+	counters := ParseNetDev(strings.NewReader(netDev))
+	if len(counters) != 2 {
+		t.Fatalf("Expected eth0 and wwan0 without lo, got %+v", counters)
+	}
+	if c := counters["eth0"]; c.RxBytes != 9876543 || c.TxBytes != 1234567 {
+		t.Errorf("eth0 = %+v", c)
+	}
+	if c := counters["wwan0"]; c.RxBytes != 1000 || c.TxBytes != 2000 {
+		t.Errorf("Expected a name run into its counters to parse, got %+v", c)
+	}
+}
+
+func interfaceSnapshot(t time.Time, counters map[string]InterfaceCounters) Snapshot {
+	snap := Snapshot{Time: t, Samples: []Sample{{Name: MetricFFmpegBitrate, Value: 4000}}}
+	for name, c := range counters {
+		labels := map[string]string{"interface": name}
+		snap.Samples = append(snap.Samples,
+			Sample{Name: MetricInterfaceRxBytes, Labels: labels, Value: float64(c.RxBytes)},
+			Sample{Name: MetricInterfaceTxBytes, Labels: labels, Value: float64(c.TxBytes)})
+	}
+	return snap
+}
+
+func TestInterfaceHistory(t *testing.T) {
+	h := NewInterfaceHistory(3)
+	start := time.Now()
+
+	h.Write(interfaceSnapshot(start, map[string]InterfaceCounters{"eth0": {0, 0}, "wlan0": {0, 0}}))
+	h.Write(interfaceSnapshot(start.Add(time.Second), map[string]InterfaceCounters{"eth0": {125000, 250000}, "wlan0": {10, 10}}))
+	history := h.History("eth0", time.Time{})
+	if len(history) != 2 || history[0].RxKbps != 0 || history[1].RxKbps != 1000 || history[1].TxKbps != 2000 {
+		t.Fatalf("Unexpected history %+v", history)
+	}
+
+	// the counter going back gives no rate rather than a negative one
+	h.Write(interfaceSnapshot(start.Add(2*time.Second), map[string]InterfaceCounters{"eth0": {100, 100}}))
+	if p := h.History("eth0", start.Add(2*time.Second)); len(p) != 1 || p[0].RxKbps != 0 || p[0].TxKbps != 0 {
+		t.Errorf("Expected no rate after a reset, got %+v", p)
+	}
+
+	for i := 3; i < 6; i++ {
+		h.Write(interfaceSnapshot(start.Add(time.Duration(i)*time.Second), map[string]InterfaceCounters{"eth0": {int64(i) * 1000, 0}}))
+	}
+	if history := h.History("eth0", time.Time{}); len(history) != 3 {
+		t.Errorf("Expected the newest 3 points, got %d", len(history))
+	}
+	if names := h.Interfaces(); len(names) != 1 || names[0] != "eth0" {
+		t.Errorf("Expected wlan0 forgotten once eth0's history no longer reaches it, got %v", names)
+	}
+	if oldest, ok := h.Oldest("eth0"); !ok || !oldest.Equal(start.Add(3*time.Second)) {
+		t.Errorf("Oldest() = %v", oldest)
+	}
+}
@@ -0,0 +1,135 @@
+package stats
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	ModemHistorySize     = 720 // an hour at 5 second intervals
+	ModemHistoryInterval = 5 * time.Second
+)
+
+// Modem metrics, labelled by modem; the network type is a 1 labelled with
+// the type as well
+const (
+	MetricModemSignalPercent = "modem_signal_percent"
+	MetricModemSignalDBm     = "modem_signal_dbm"
+	MetricModemNetworkType   = "modem_network_type"
+	MetricModemTxBytes       = "modem_tx_bytes_total"
+	MetricModemRxBytes       = "modem_rx_bytes_total"
+	MetricModemTemperature   = "modem_temperature_celsius" // where the dongle exposes one
+)
+
+// ModemPoint is one entry of a modem's signal history
+type ModemPoint struct {
+	Timestamp     time.Time `json:"timestamp"`
+	SignalPercent int       `json:"signal_percent"`
+	SignalDBm     int       `json:"signal_dbm"`
+	NetworkType   string    `json:"network_type"`
+	TxBytes       int64     `json:"tx_bytes"`
+	RxBytes       int64     `json:"rx_bytes"`
+	TemperatureC  *float64  `json:"temperature_c,omitempty"`
+}
+
+// ModemPoints returns the modem samples of a snapshot, by modem
+func ModemPoints(snap Snapshot) map[string]ModemPoint {
+	points := make(map[string]ModemPoint)
+	for _, s := range snap.Samples {
+		id, ok := s.Labels["modem"]
+		if !ok {
+			continue
+		}
+		p := points[id]
+		switch s.Name {
+		case MetricModemSignalPercent:
+			p.SignalPercent = int(s.Value)
+		case MetricModemSignalDBm:
+			p.SignalDBm = int(s.Value)
+		case MetricModemNetworkType:
+			p.NetworkType = s.Labels["type"]
+		case MetricModemTxBytes:
+			p.TxBytes = int64(s.Value)
+		case MetricModemRxBytes:
+			p.RxBytes = int64(s.Value)
+		case MetricModemTemperature:
+			v := s.Value
+			p.TemperatureC = &v
+		default:
+			continue
+		}
+		p.Timestamp = snap.Time
+		points[id] = p
+	}
+	return points
+}
+
+// ModemHistory keeps the recent signal history of each modem in memory. It
+// is a sink, so it sees the same samples as the stats store, which keeps
+// them for longer.
+type ModemHistory struct {
+	mu     sync.RWMutex
+	size   int
+	every  time.Duration
+	last   time.Time
+	modems map[string][]ModemPoint
+}
+
+// NewModemHistory keeps up to size points per modem, at most one per every
+func NewModemHistory(size int, every time.Duration) *ModemHistory {
+	return &ModemHistory{size: size, every: every, modems: make(map[string][]ModemPoint)}
+}
+
+func (m *ModemHistory) Write(snap Snapshot) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.last.IsZero() && snap.Time.Sub(m.last) < m.every {
+		return nil
+	}
+	points := ModemPoints(snap)
+	if len(points) == 0 {
+		return nil
+	}
+	for id, p := range points {
+		history := append(m.modems[id], p)
+		if len(history) > m.size {
+			history = history[len(history)-m.size:]
+		}
+		m.modems[id] = history
+	}
+	m.last = snap.Time
+	return nil
+}
+
+func (m *ModemHistory) Close() error {
+	return nil
+}
+
+// History returns a modem's points at or after since, oldest first, and
+// whether the modem has any history at all
+func (m *ModemHistory) History(id string, since time.Time) ([]ModemPoint, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	history, ok := m.modems[id]
+	result := []ModemPoint{}
+	for _, p := range history {
+		if !p.Timestamp.Before(since) {
+			result = append(result, p)
+		}
+	}
+	return result, ok
+}
+
+// Oldest returns the time of the oldest point kept for a modem
+func (m *ModemHistory) Oldest(id string) (time.Time, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	history := m.modems[id]
+	if len(history) == 0 {
+		return time.Time{}, false
+	}
+	return history[0].Timestamp, true
+}
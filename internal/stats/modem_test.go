@@ -0,0 +1,65 @@
+package stats
+
+import (
+	"testing"
+	"time"
+)
+
+func modemSnapshot(t time.Time, id string, percent float64, networkType string, tx float64) Snapshot {
+	return Snapshot{Time: t, Samples: []Sample{
+		{Name: MetricFFmpegBitrate, Value: 4000},
+		{Name: MetricModemSignalPercent, Labels: map[string]string{"modem": id}, Value: percent},
+		{Name: MetricModemSignalDBm, Labels: map[string]string{"modem": id}, Value: -95},
+		{Name: MetricModemNetworkType, Labels: map[string]string{"modem": id, "type": networkType}, Value: 1},
+		{Name: MetricModemTxBytes, Labels: map[string]string{"modem": id}, Value: tx},
+		{Name: MetricModemTemperature, Labels: map[string]string{"modem": id}, Value: 48.5},
+	}}
+}
+
+func TestModemPoints(t *testing.T) {
+	now := time.Now()
+	points := ModemPoints(modemSnapshot(now, "0", 72, "lte", 1024))
+	if len(points) != 1 {
+		t.Fatalf("ModemPoints() returned %d modems, want 1", len(points))
+	}
+	p := points["0"]
+	if p.SignalPercent != 72 || p.SignalDBm != -95 || p.NetworkType != "lte" || p.TxBytes != 1024 || !p.Timestamp.Equal(now) ||
+		p.TemperatureC == nil || *p.TemperatureC != 48.5 {
+		t.Errorf("ModemPoints() = %+v", p)
+	}
+}
+
+func TestModemHistory(t *testing.T) {
+	h := NewModemHistory(3, 5*time.Second)
+	start := time.Now()
+
+	for i := range 10 {
+		h.Write(modemSnapshot(start.Add(time.Duration(i)*time.Second), "0", float64(i), "lte", 0))
+	}
+	history, ok := h.History("0", time.Time{})
+	if !ok || len(history) != 2 {
+		t.Fatalf("Expected a point every 5s, got %d", len(history))
+	}
+	if history[0].SignalPercent != 0 || history[1].SignalPercent != 5 {
+		t.Errorf("History() = %+v", history)
+	}
+
+	for i := 2; i < 6; i++ {
+		h.Write(modemSnapshot(start.Add(time.Duration(i)*5*time.Second), "0", float64(i), "5g", 0))
+	}
+	history, _ = h.History("0", time.Time{})
+	if len(history) != 3 || history[0].SignalPercent != 3 || history[2].NetworkType != "5g" {
+		t.Errorf("Expected the newest 3 points, got %+v", history)
+	}
+	if oldest, _ := h.Oldest("0"); !oldest.Equal(history[0].Timestamp) {
+		t.Errorf("Oldest() = %v, want %v", oldest, history[0].Timestamp)
+	}
+
+	history, _ = h.History("0", start.Add(25*time.Second))
+	if len(history) != 1 {
+		t.Errorf("Expected since to leave 1 point, got %d", len(history))
+	}
+	if _, ok := h.History("1", time.Time{}); ok {
+		t.Error("Expected no history for an unknown modem")
+	}
+}
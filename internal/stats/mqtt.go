@@ -0,0 +1,250 @@
+package stats
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	mqttDialTimeout  = 5 * time.Second
+	mqttWriteTimeout = 5 * time.Second
+	mqttRetry        = 10 * time.Second
+)
+
+// MQTTOptions configures the MQTT sink
+type MQTTOptions struct {
+	Broker   string // mqtt://host[:1883] or mqtts://host[:8883]
+	Topic    string
+	ClientID string
+	Username string
+	Password string
+	Every    time.Duration // least time between published snapshots
+}
+
+// MQTT publishes snapshots as retained JSON messages with QoS 0. Publishing
+// happens in the background so a slow or missing broker never holds up the
+// stats loop; only the newest snapshot waits to be sent.
+type MQTT struct {
+	opts    MQTTOptions
+	pending chan Snapshot
+	cancel  context.CancelFunc
+	done    chan struct{}
+
+	mu      sync.Mutex
+	last    time.Time
+	lastErr error
+}
+
+// NewMQTT starts publishing to the broker
+func NewMQTT(opts MQTTOptions) (*MQTT, error) {
+	if _, _, err := mqttAddress(opts.Broker); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &MQTT{
+		opts:    opts,
+		pending: make(chan Snapshot, 1),
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	go m.run(ctx)
+	return m, nil
+}
+
+// Write queues the snapshot and reports the last publishing error
+func (m *MQTT) Write(s Snapshot) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.last.IsZero() || s.Time.Sub(m.last) >= m.opts.Every {
+		m.last = s.Time
+		// replace a snapshot still waiting for the broker
+		select {
+		case <-m.pending:
+		default:
+		}
+		m.pending <- s
+	}
+	return m.lastErr
+}
+
+func (m *MQTT) Close() error {
+	m.cancel()
+	<-m.done
+	return nil
+}
+
+func (m *MQTT) setErr(err error) {
+	m.mu.Lock()
+	m.lastErr = err
+	m.mu.Unlock()
+}
+
+func (m *MQTT) run(ctx context.Context) {
+	defer close(m.done)
+
+	var conn net.Conn
+	defer func() {
+		if conn != nil {
+			conn.SetWriteDeadline(time.Now().Add(mqttWriteTimeout))
+			conn.Write([]byte{0xE0, 0x00}) // DISCONNECT
+			conn.Close()
+		}
+	}()
+
+	for {
+		var snap Snapshot
+		select {
+		case <-ctx.Done():
+			return
+		case snap = <-m.pending:
+		}
+
+		payload, err := json.Marshal(snap)
+		if err != nil {
+			m.setErr(err)
+			continue
+		}
+
+		if conn == nil {
+			if conn, err = m.connect(ctx); err != nil {
+				m.setErr(err)
+				conn = nil
+				// don't hammer a broker that is down
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(mqttRetry):
+				}
+				continue
+			}
+		}
+
+		conn.SetWriteDeadline(time.Now().Add(mqttWriteTimeout))
+		if _, err := conn.Write(mqttPublish(m.opts.Topic, payload)); err != nil {
+			m.setErr(fmt.Errorf("mqtt publish: %w", err))
+			conn.Close()
+			conn = nil
+			continue
+		}
+		m.setErr(nil)
+	}
+}
+
+func (m *MQTT) connect(ctx context.Context) (net.Conn, error) {
+	addr, useTLS, _ := mqttAddress(m.opts.Broker)
+	dialer := &net.Dialer{Timeout: mqttDialTimeout}
+
+	var conn net.Conn
+	var err error
+	if useTLS {
+		host, _, _ := net.SplitHostPort(addr)
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: host}}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("mqtt connect: %w", err)
+	}
+
+	conn.SetDeadline(time.Now().Add(mqttDialTimeout))
+	if _, err := conn.Write(mqttConnect(m.opts.ClientID, m.opts.Username, m.opts.Password)); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("mqtt connect: %w", err)
+	}
+	ack := make([]byte, 4)
+	if _, err := io.ReadFull(conn, ack); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("mqtt connect: %w", err)
+	}
+	if ack[0] != 0x20 || ack[3] != 0 {
+		conn.Close()
+		return nil, fmt.Errorf("mqtt connect: broker refused connection (code %d)", ack[3])
+	}
+	conn.SetDeadline(time.Time{})
+	return conn, nil
+}
+
+// mqttAddress turns a broker URL into host:port
+func mqttAddress(broker string) (addr string, useTLS bool, err error) {
+	u, err := url.Parse(broker)
+	if err != nil || u.Host == "" {
+		return "", false, fmt.Errorf("invalid MQTT broker %q", broker)
+	}
+	port := "1883"
+	switch u.Scheme {
+	case "mqtt":
+	case "mqtts":
+		useTLS = true
+		port = "8883"
+	default:
+		return "", false, fmt.Errorf("invalid MQTT broker %q: scheme must be mqtt or mqtts", broker)
+	}
+	if u.Port() != "" {
+		port = u.Port()
+	}
+	return net.JoinHostPort(u.Hostname(), port), useTLS, nil
+}
+
+// mqttConnect builds an MQTT 3.1.1 CONNECT packet with a clean session and
+// no keep-alive, since QoS 0 publishing never waits on the broker
+func mqttConnect(clientID, username, password string) []byte {
+	var body bytes.Buffer
+	body.Write(mqttString("MQTT"))
+	body.WriteByte(4) // protocol level 3.1.1
+	flags := byte(0x02)
+	if username != "" {
+		flags |= 0x80
+		if password != "" {
+			flags |= 0x40
+		}
+	}
+	body.WriteByte(flags)
+	body.Write([]byte{0, 0}) // keep-alive off
+	body.Write(mqttString(clientID))
+	if username != "" {
+		body.Write(mqttString(username))
+		if password != "" {
+			body.Write(mqttString(password))
+		}
+	}
+	return mqttPacket(0x10, body.Bytes())
+}
+
+// mqttPublish builds a retained QoS 0 PUBLISH packet
+func mqttPublish(topic string, payload []byte) []byte {
+	body := append(mqttString(topic), payload...)
+	return mqttPacket(0x31, body)
+}
+
+func mqttPacket(header byte, body []byte) []byte {
+	packet := []byte{header}
+	// remaining length: seven bits per byte, high bit set when more follow
+	n := len(body)
+	for {
+		b := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			b |= 0x80
+		}
+		packet = append(packet, b)
+		if n == 0 {
+			break
+		}
+	}
+	return append(packet, body...)
+}
+
+func mqttString(s string) []byte {
+	b := binary.BigEndian.AppendUint16(nil, uint16(len(s)))
+	return append(b, s...)
+}
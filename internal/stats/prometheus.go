@@ -0,0 +1,101 @@
+package stats
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// PrometheusNamespace prefixes every exported metric name
+const PrometheusNamespace = "srtla_manager_"
+
+// Prometheus serves the latest snapshot in the Prometheus text format
+type Prometheus struct {
+	collector *Collector
+	mu        sync.RWMutex
+	latest    Snapshot
+}
+
+// NewPrometheus returns a sink that takes metric types from collector
+func NewPrometheus(collector *Collector) *Prometheus {
+	return &Prometheus{collector: collector}
+}
+
+func (p *Prometheus) Write(s Snapshot) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.latest = s
+	return nil
+}
+
+func (p *Prometheus) Close() error {
+	return nil
+}
+
+// WriteText writes the latest snapshot in the text exposition format
+func (p *Prometheus) WriteText(w io.Writer) error {
+	p.mu.RLock()
+	snap := p.latest
+	p.mu.RUnlock()
+
+	// samples of one metric must be grouped under a single TYPE line
+	var order []string
+	groups := map[string][]Sample{}
+	for _, s := range snap.Samples {
+		if _, ok := groups[s.Name]; !ok {
+			order = append(order, s.Name)
+		}
+		groups[s.Name] = append(groups[s.Name], s)
+	}
+
+	for _, name := range order {
+		full := PrometheusNamespace + name
+		kind := "untyped"
+		if m, ok := p.collector.Lookup(name); ok {
+			if m.Help != "" {
+				if _, err := fmt.Fprintf(w, "# HELP %s %s\n", full, m.Help); err != nil {
+					return err
+				}
+			}
+			kind = string(m.Kind)
+		}
+		if _, err := fmt.Fprintf(w, "# TYPE %s %s\n", full, kind); err != nil {
+			return err
+		}
+		for _, s := range groups[name] {
+			value := strconv.FormatFloat(s.Value, 'g', -1, 64)
+			if _, err := fmt.Fprintf(w, "%s%s %s\n", full, labelString(s.Labels), value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ServeHTTP serves the scrape endpoint
+func (p *Prometheus) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	p.WriteText(w)
+}
+
+// labelString formats labels in a stable order, e.g. {ip="10.0.0.2"}
+func labelString(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		v := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(labels[k])
+		parts[i] = fmt.Sprintf("%s=\"%s\"", k, v)
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
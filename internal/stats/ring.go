@@ -0,0 +1,97 @@
+package stats
+
+import (
+	"sync"
+	"time"
+)
+
+// DataPoint is one entry of the bitrate/fps history shown in the UI
+type DataPoint struct {
+	Timestamp     time.Time `json:"timestamp"`
+	FFmpegBitrate float64   `json:"ffmpeg_bitrate"`
+	SRTLABitrate  float64   `json:"srtla_bitrate"`
+	FPS           float64   `json:"fps"`
+}
+
+// Ring keeps the most recent snapshots in memory
+type Ring struct {
+	mu    sync.RWMutex
+	snaps []Snapshot
+	pos   int
+	full  bool
+}
+
+func NewRing(size int) *Ring {
+	return &Ring{snaps: make([]Snapshot, size)}
+}
+
+func (r *Ring) Write(s Snapshot) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.snaps[r.pos] = s
+	r.pos = (r.pos + 1) % len(r.snaps)
+	if r.pos == 0 {
+		r.full = true
+	}
+	return nil
+}
+
+func (r *Ring) Close() error {
+	return nil
+}
+
+// Snapshots returns the kept snapshots, oldest first
+func (r *Ring) Snapshots() []Snapshot {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var result []Snapshot
+	if r.full {
+		result = make([]Snapshot, len(r.snaps))
+		copy(result, r.snaps[r.pos:])
+		copy(result[len(r.snaps)-r.pos:], r.snaps[:r.pos])
+	} else {
+		result = make([]Snapshot, r.pos)
+		copy(result, r.snaps[:r.pos])
+	}
+	return result
+}
+
+// Latest returns the newest snapshot
+func (r *Ring) Latest() (Snapshot, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if !r.full && r.pos == 0 {
+		return Snapshot{}, false
+	}
+	idx := r.pos - 1
+	if idx < 0 {
+		idx = len(r.snaps) - 1
+	}
+	return r.snaps[idx], true
+}
+
+// History returns the bitrate/fps history, oldest first
+func (r *Ring) History() []DataPoint {
+	snaps := r.Snapshots()
+	result := make([]DataPoint, len(snaps))
+	for i, s := range snaps {
+		result[i] = DataPoint{
+			Timestamp:     s.Time,
+			FFmpegBitrate: s.Value(MetricFFmpegBitrate),
+			SRTLABitrate:  s.Value(MetricSRTLABitrate),
+			FPS:           s.Value(MetricFFmpegFPS),
+		}
+	}
+	return result
+}
+
+func (r *Ring) Clear() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.snaps = make([]Snapshot, len(r.snaps))
+	r.pos = 0
+	r.full = false
+}
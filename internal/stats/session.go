@@ -0,0 +1,143 @@
+package stats
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// SessionStats are the running totals of the current or last streaming
+// session
+type SessionStats struct {
+	Active       bool              `json:"active"`
+	StartedAt    *time.Time        `json:"started_at,omitempty"`
+	OnAirSeconds int64             `json:"on_air_seconds"`
+	BytesSent    uint64            `json:"bytes_sent"`
+	Links        map[string]uint64 `json:"links"` // bytes sent per interface
+	Restarts     int               `json:"restarts"`
+	DriftMs      float64           `json:"drift_ms"`     // stream clock against the system clock, latest
+	MaxDriftMs   float64           `json:"max_drift_ms"` // largest drift either way this session
+}
+
+// linkUsage tracks one interface's transmit counter
+type linkUsage struct {
+	last  uint64 // counter at the last reading
+	total uint64 // bytes sent this session
+}
+
+// Session totals a streaming session: how long it has been on air, the
+// bytes each link has sent and how often the pipeline was restarted. The
+// totals are kept after the session ends until the next one starts.
+type Session struct {
+	mu       sync.Mutex
+	started  time.Time // zero before the first session
+	ended    time.Time // zero while the session is on air
+	restarts int
+	links    map[string]*linkUsage
+	drift    float64 // ms
+	maxDrift float64 // ms, signed
+}
+
+func NewSession() *Session {
+	return &Session{links: make(map[string]*linkUsage)}
+}
+
+// Start begins a new session, clearing the last one's totals
+func (s *Session) Start(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.started, s.ended = now, time.Time{}
+	s.restarts = 0
+	s.drift, s.maxDrift = 0, 0
+	s.links = make(map[string]*linkUsage)
+}
+
+// End stops the on-air clock. It does nothing when no session is on air.
+func (s *Session) End(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.active() {
+		s.ended = now
+	}
+}
+
+// AddRestart counts a restart of the pipeline while on air
+func (s *Session) AddRestart() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.active() {
+		s.restarts++
+	}
+}
+
+// AddDrift records the latest drift of the stream's clock in ms
+func (s *Session) AddDrift(ms float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.active() {
+		return
+	}
+	s.drift = ms
+	if math.Abs(ms) > math.Abs(s.maxDrift) {
+		s.maxDrift = ms
+	}
+}
+
+// AddCounters adds the transmit byte counters of the links in use. The
+// first reading of an interface is its baseline, and a counter that went
+// backwards is taken to have been reset, as when a modem reconnects.
+func (s *Session) AddCounters(counters map[string]uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.active() {
+		return
+	}
+	for iface, n := range counters {
+		link, ok := s.links[iface]
+		if !ok {
+			s.links[iface] = &linkUsage{last: n}
+			continue
+		}
+		if n >= link.last {
+			link.total += n - link.last
+		} else {
+			link.total += n
+		}
+		link.last = n
+	}
+}
+
+// Stats returns the session's totals as of now
+func (s *Session) Stats(now time.Time) SessionStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := SessionStats{
+		Active:     s.active(),
+		Links:      make(map[string]uint64, len(s.links)),
+		Restarts:   s.restarts,
+		DriftMs:    s.drift,
+		MaxDriftMs: s.maxDrift,
+	}
+	if s.started.IsZero() {
+		return stats
+	}
+	started := s.started
+	stats.StartedAt = &started
+
+	end := now
+	if !s.ended.IsZero() {
+		end = s.ended
+	}
+	stats.OnAirSeconds = int64(end.Sub(s.started) / time.Second)
+
+	for iface, link := range s.links {
+		stats.Links[iface] = link.total
+		stats.BytesSent += link.total
+	}
+	return stats
+}
+
+func (s *Session) active() bool {
+	return !s.started.IsZero() && s.ended.IsZero()
+}
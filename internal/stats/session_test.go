@@ -0,0 +1,64 @@
+package stats
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSession(t *testing.T) {
+	s := NewSession()
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	s.AddRestart()
+	s.AddCounters(map[string]uint64{"wwan0": 100})
+	if st := s.Stats(start); st.Active || st.StartedAt != nil || st.Restarts != 0 {
+		t.Fatalf("Expected no session yet, got %+v", st)
+	}
+
+	s.Start(start)
+	s.AddCounters(map[string]uint64{"wwan0": 1000, "usb0": 50})
+	s.AddCounters(map[string]uint64{"wwan0": 4000, "usb0": 250})
+	s.AddCounters(map[string]uint64{"wwan0": 500, "usb0": 300}) // wwan0 reconnected
+	s.AddRestart()
+
+	st := s.Stats(start.Add(90 * time.Minute))
+	if !st.Active || st.OnAirSeconds != 5400 || st.Restarts != 1 {
+		t.Errorf("Unexpected session: %+v", st)
+	}
+	if st.Links["wwan0"] != 3500 || st.Links["usb0"] != 250 || st.BytesSent != 3750 {
+		t.Errorf("Unexpected usage: %+v", st.Links)
+	}
+
+	s.End(start.Add(2 * time.Hour))
+	s.AddCounters(map[string]uint64{"wwan0": 9000})
+	st = s.Stats(start.Add(3 * time.Hour))
+	if st.Active || st.OnAirSeconds != 7200 || st.BytesSent != 3750 {
+		t.Errorf("Expected the totals to stop with the session, got %+v", st)
+	}
+
+	s.Start(start.Add(4 * time.Hour))
+	if st := s.Stats(start.Add(4 * time.Hour)); st.BytesSent != 0 || st.Restarts != 0 || st.OnAirSeconds != 0 {
+		t.Errorf("Expected a new session to start from zero, got %+v", st)
+	}
+}
+
+func TestSessionDrift(t *testing.T) {
+	s := NewSession()
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	s.AddDrift(50)
+	s.Start(start)
+	s.AddDrift(-20)
+	s.AddDrift(-120)
+	s.AddDrift(80)
+
+	st := s.Stats(start.Add(time.Hour))
+	if st.DriftMs != 80 || st.MaxDriftMs != -120 {
+		t.Errorf("Unexpected drift: %+v", st)
+	}
+
+	s.Start(start.Add(2 * time.Hour))
+	if st := s.Stats(start.Add(2 * time.Hour)); st.DriftMs != 0 || st.MaxDriftMs != 0 {
+		t.Errorf("Expected a new session to start without drift, got %+v", st)
+	}
+}
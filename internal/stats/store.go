@@ -0,0 +1,63 @@
+package stats
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"srtla-manager/internal/jsonl"
+)
+
+// Store appends snapshots to a JSON lines file so stats survive restarts
+type Store struct {
+	w     *jsonl.Writer
+	every time.Duration
+
+	mu   sync.Mutex
+	last time.Time
+}
+
+// NewStore opens path for appending, keeping a snapshot at most once per
+// every. The file is rotated once it reaches maxBytes.
+func NewStore(path string, every time.Duration, maxBytes int64) (*Store, error) {
+	w, err := jsonl.Open(path, maxBytes)
+	if err != nil {
+		return nil, err
+	}
+	return &Store{w: w, every: every}, nil
+}
+
+func (s *Store) Write(snap Snapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.last.IsZero() && snap.Time.Sub(s.last) < s.every {
+		return nil
+	}
+	if err := s.w.Append(snap); err != nil {
+		return err
+	}
+	s.last = snap.Time
+	return nil
+}
+
+func (s *Store) Close() error {
+	return s.w.Close()
+}
+
+// ReadStore returns the stored snapshots taken at or after since, oldest
+// first
+func ReadStore(path string, since time.Time) ([]Snapshot, error) {
+	var result []Snapshot
+	err := jsonl.Read(path, func(line []byte) {
+		var snap Snapshot
+		// skip a line cut short by a power loss
+		if json.Unmarshal(line, &snap) != nil {
+			return
+		}
+		if !snap.Time.Before(since) {
+			result = append(result, snap)
+		}
+	})
+	return result, err
+}
@@ -0,0 +1,240 @@
+// Package stun discovers the public address a NAT maps a local UDP port to
+// with STUN binding requests (RFC 5389), so two units behind NATs can reach
+// each other directly in SRT rendezvous mode.
+package stun
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+)
+
+// DefaultServer is asked when no servers are configured
+const DefaultServer = "stun.l.google.com:19302"
+
+// Timeout bounds the wait for each server's answer
+const Timeout = 3 * time.Second
+
+// retransmitInterval is how long a request waits for its answer before it
+// is sent again
+const retransmitInterval = 500 * time.Millisecond
+
+const (
+	magicCookie     = 0x2112A442
+	bindingRequest  = 0x0001
+	bindingResponse = 0x0101
+
+	attrMappedAddress    = 0x0001
+	attrXORMappedAddress = 0x0020
+
+	headerSize = 20
+)
+
+// Mapping is the public address of a local UDP port as the servers saw it
+type Mapping struct {
+	BindIP        string `json:"bind_ip"`
+	LocalPort     int    `json:"local_port"`
+	Public        string `json:"public,omitempty"` // ip:port the peer should rendezvous with
+	PortPreserved bool   `json:"port_preserved"`   // the NAT kept the local port
+	Symmetric     bool   `json:"symmetric"`        // servers saw different addresses; rendezvous is unlikely to get through
+	Servers       int    `json:"servers"`          // servers that answered
+	Error         string `json:"error,omitempty"`  // none answered
+}
+
+// Discover asks each server in turn, from one socket on bindIP:localPort,
+// which address the request came from. A localPort of 0 picks a free one.
+// Asking more than one server tells whether the NAT maps the port to the
+// same public port whoever it talks to, which rendezvous relies on.
+func Discover(ctx context.Context, servers []string, bindIP string, localPort int) Mapping {
+	m := Mapping{BindIP: bindIP, LocalPort: localPort}
+	ip := net.ParseIP(bindIP)
+	if ip == nil {
+		m.Error = fmt.Sprintf("invalid bind IP %q", bindIP)
+		return m
+	}
+	if len(servers) == 0 {
+		servers = []string{DefaultServer}
+	}
+
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: ip, Port: localPort})
+	if err != nil {
+		m.Error = err.Error()
+		return m
+	}
+	defer conn.Close()
+	m.LocalPort = conn.LocalAddr().(*net.UDPAddr).Port
+
+	var lastErr error
+	for _, server := range servers {
+		public, err := query(ctx, conn, server)
+		if err != nil {
+			lastErr = fmt.Errorf("%s: %w", server, err)
+			continue
+		}
+		m.Servers++
+		if m.Public == "" {
+			m.Public = public.String()
+			m.PortPreserved = public.Port == m.LocalPort
+		} else if m.Public != public.String() {
+			m.Symmetric = true
+		}
+	}
+	if m.Servers == 0 && lastErr != nil {
+		m.Error = lastErr.Error()
+	}
+	return m
+}
+
+// query sends a binding request to server from conn and returns the mapped
+// address in its answer
+func query(ctx context.Context, conn *net.UDPConn, server string) (*net.UDPAddr, error) {
+	raddr, err := net.ResolveUDPAddr("udp", server)
+	if err != nil {
+		return nil, err
+	}
+
+	var txID [12]byte
+	rand.Read(txID[:])
+	req := newBindingRequest(txID)
+
+	deadline := time.Now().Add(Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	buf := make([]byte, 1500)
+	for time.Now().Before(deadline) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if _, err := conn.WriteToUDP(req, raddr); err != nil {
+			return nil, err
+		}
+		conn.SetReadDeadline(minTime(time.Now().Add(retransmitInterval), deadline))
+		for {
+			n, from, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				var ne net.Error
+				if errors.As(err, &ne) && ne.Timeout() {
+					break
+				}
+				return nil, err
+			}
+			if !from.IP.Equal(raddr.IP) || from.Port != raddr.Port {
+				continue
+			}
+			addr, err := parseResponse(buf[:n], txID)
+			if err != nil {
+				continue
+			}
+			return addr, nil
+		}
+	}
+	return nil, errors.New("no answer")
+}
+
+func newBindingRequest(txID [12]byte) []byte {
+	b := make([]byte, headerSize)
+	binary.BigEndian.PutUint16(b[0:], bindingRequest)
+	binary.BigEndian.PutUint16(b[2:], 0)
+	binary.BigEndian.PutUint32(b[4:], magicCookie)
+	copy(b[8:], txID[:])
+	return b
+}
+
+// parseResponse returns the mapped address from a binding success response
+// to the request txID, preferring XOR-MAPPED-ADDRESS
+func parseResponse(b []byte, txID [12]byte) (*net.UDPAddr, error) {
+	if len(b) < headerSize {
+		return nil, errors.New("short message")
+	}
+	if binary.BigEndian.Uint16(b[0:]) != bindingResponse {
+		return nil, errors.New("not a binding success response")
+	}
+	if binary.BigEndian.Uint32(b[4:]) != magicCookie || [12]byte(b[8:20]) != txID {
+		return nil, errors.New("not an answer to this request")
+	}
+	length := int(binary.BigEndian.Uint16(b[2:]))
+	if headerSize+length > len(b) {
+		return nil, errors.New("truncated message")
+	}
+
+	var mapped *net.UDPAddr
+	attrs := b[headerSize : headerSize+length]
+	for len(attrs) >= 4 {
+		typ := binary.BigEndian.Uint16(attrs[0:])
+		size := int(binary.BigEndian.Uint16(attrs[2:]))
+		if 4+size > len(attrs) {
+			return nil, errors.New("truncated attribute")
+		}
+		value := attrs[4 : 4+size]
+		switch typ {
+		case attrXORMappedAddress:
+			if addr, err := parseAddress(value, b[4:20]); err == nil {
+				return addr, nil
+			}
+		case attrMappedAddress:
+			if addr, err := parseAddress(value, nil); err == nil {
+				mapped = addr
+			}
+		}
+		// attributes are padded to four bytes
+		attrs = attrs[min(len(attrs), 4+(size+3)&^3):]
+	}
+	if mapped == nil {
+		return nil, errors.New("no mapped address")
+	}
+	return mapped, nil
+}
+
+// parseAddress decodes a (XOR-)MAPPED-ADDRESS value. xor is the magic
+// cookie and transaction ID for XOR-MAPPED-ADDRESS, nil otherwise.
+func parseAddress(v, xor []byte) (*net.UDPAddr, error) {
+	if len(v) < 4 {
+		return nil, errors.New("short address")
+	}
+	var size int
+	switch v[1] {
+	case 0x01:
+		size = net.IPv4len
+	case 0x02:
+		size = net.IPv6len
+	default:
+		return nil, fmt.Errorf("unknown address family %d", v[1])
+	}
+	if len(v) < 4+size {
+		return nil, errors.New("short address")
+	}
+
+	port := binary.BigEndian.Uint16(v[2:])
+	ip := make(net.IP, size)
+	copy(ip, v[4:4+size])
+	if xor != nil {
+		port ^= uint16(magicCookie >> 16)
+		for i := range ip {
+			ip[i] ^= xor[i]
+		}
+	}
+	return &net.UDPAddr{IP: ip, Port: int(port)}, nil
+}
+
+// ValidServer reports whether s is a host:port a server can be asked at
+func ValidServer(s string) bool {
+	host, port, err := net.SplitHostPort(s)
+	if err != nil || host == "" {
+		return false
+	}
+	n, err := strconv.Atoi(port)
+	return err == nil && n > 0 && n <= 65535
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
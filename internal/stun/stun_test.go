@@ -0,0 +1,129 @@
+package stun
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"strconv"
+	"testing"
+)
+
+// serve answers binding requests on a loopback socket with the address
+// they came from, reported as seen from port offset further on
+func serve(t *testing.T, offset int) string {
+	t.Helper()
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, from, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			if n < headerSize || binary.BigEndian.Uint16(buf) != bindingRequest {
+				continue
+			}
+			resp := make([]byte, headerSize+12)
+			binary.BigEndian.PutUint16(resp[0:], bindingResponse)
+			binary.BigEndian.PutUint16(resp[2:], 12)
+			copy(resp[4:20], buf[4:20])
+			binary.BigEndian.PutUint16(resp[20:], attrXORMappedAddress)
+			binary.BigEndian.PutUint16(resp[22:], 8)
+			resp[25] = 0x01
+			binary.BigEndian.PutUint16(resp[26:], uint16(from.Port+offset)^uint16(magicCookie>>16))
+			ip := from.IP.To4()
+			for i := range 4 {
+				resp[28+i] = ip[i] ^ resp[4+i]
+			}
+			conn.WriteToUDP(resp, from)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestDiscover(t *testing.T) {
+	a, b := serve(t, 0), serve(t, 0)
+
+	m := Discover(context.Background(), []string{a, b}, "127.0.0.1", 0)
+	if m.Error != "" {
+		t.Fatalf("Discover() failed: %s", m.Error)
+	}
+	if m.LocalPort == 0 || m.Public != net.JoinHostPort("127.0.0.1", strconv.Itoa(m.LocalPort)) {
+		t.Errorf("Public = %q for local port %d", m.Public, m.LocalPort)
+	}
+	if !m.PortPreserved || m.Symmetric || m.Servers != 2 {
+		t.Errorf("PortPreserved = %v, Symmetric = %v, Servers = %d", m.PortPreserved, m.Symmetric, m.Servers)
+	}
+}
+
+func TestDiscoverSymmetric(t *testing.T) {
+	a, b := serve(t, 0), serve(t, 1)
+
+	m := Discover(context.Background(), []string{a, b}, "127.0.0.1", 0)
+	if m.Error != "" {
+		t.Fatalf("Discover() failed: %s", m.Error)
+	}
+	if !m.Symmetric {
+		t.Error("Expected different mappings per server to be reported as symmetric")
+	}
+}
+
+func TestDiscoverErrors(t *testing.T) {
+	if m := Discover(context.Background(), nil, "not-an-ip", 0); m.Error == "" {
+		t.Error("Expected an invalid bind IP to be rejected")
+	}
+	if m := Discover(context.Background(), nil, "192.0.2.1", 0); m.Error == "" {
+		t.Error("Expected binding to an address the host doesn't have to fail")
+	}
+}
+
+func TestParseResponse(t *testing.T) {
+	var txID [12]byte
+	copy(txID[:], "abcdefghijkl")
+
+	resp := make([]byte, headerSize+12)
+	binary.BigEndian.PutUint16(resp[0:], bindingResponse)
+	binary.BigEndian.PutUint16(resp[2:], 12)
+	binary.BigEndian.PutUint32(resp[4:], magicCookie)
+	copy(resp[8:], txID[:])
+	binary.BigEndian.PutUint16(resp[20:], attrMappedAddress)
+	binary.BigEndian.PutUint16(resp[22:], 8)
+	resp[25] = 0x01
+	binary.BigEndian.PutUint16(resp[26:], 5000)
+	copy(resp[28:], net.IPv4(203, 0, 113, 7).To4())
+
+	addr, err := parseResponse(resp, txID)
+	if err != nil {
+		t.Fatalf("parseResponse() failed: %v", err)
+	}
+	if addr.String() != "203.0.113.7:5000" {
+		t.Errorf("parseResponse() = %s, want 203.0.113.7:5000", addr)
+	}
+
+	var other [12]byte
+	if _, err := parseResponse(resp, other); err == nil {
+		t.Error("Expected an answer to another request to be rejected")
+	}
+	if _, err := parseResponse(resp[:10], txID); err == nil {
+		t.Error("Expected a short message to be rejected")
+	}
+}
+
+func TestValidServer(t *testing.T) {
+	for s, want := range map[string]bool{
+		"stun.l.google.com:19302": true,
+		"192.0.2.1:3478":          true,
+		"stun.example.com":        false,
+		":3478":                   false,
+		"host:0":                  false,
+	} {
+		if got := ValidServer(s); got != want {
+			t.Errorf("ValidServer(%q) = %v, want %v", s, got, want)
+		}
+	}
+}
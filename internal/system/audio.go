@@ -0,0 +1,47 @@
+package system
+
+import (
+	"bufio"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// AudioDevice is an ALSA playback card
+type AudioDevice struct {
+	Device string `json:"device"` // ALSA name to pass to ffmpeg, e.g. plughw:CARD=Device
+	Card   string `json:"card"`   // short card ID
+	Name   string `json:"name"`
+	USB    bool   `json:"usb"`
+}
+
+// Lines look like " 1 [Device         ]: USB-Audio - USB Audio Device"
+var asoundCardRegex = regexp.MustCompile(`^\s*\d+\s+\[(\S+)\s*\]:\s*(\S+)\s+-\s+(.+)$`)
+
+// ListAudioDevices returns the sound cards from /proc/asound/cards, preceded
+// by the ALSA default device. Cards are addressed through plughw so any
+// sample rate and channel count ffmpeg produces is converted.
+func ListAudioDevices() []AudioDevice {
+	devices := []AudioDevice{{Device: "default", Card: "default", Name: "System default"}}
+
+	f, err := os.Open("/proc/asound/cards")
+	if err != nil {
+		return devices
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		m := asoundCardRegex.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			continue
+		}
+		devices = append(devices, AudioDevice{
+			Device: "plughw:CARD=" + m[1],
+			Card:   m[1],
+			Name:   strings.TrimSpace(m[3]),
+			USB:    m[2] == "USB-Audio",
+		})
+	}
+	return devices
+}
@@ -0,0 +1,32 @@
+package system
+
+import (
+	"bufio"
+	"os"
+	"strings"
+)
+
+// CPUModel returns the board model from the device tree, such as
+// "Raspberry Pi Zero W Rev 1.1", or the CPU's model name. It is "" when
+// neither can be read.
+func CPUModel() string {
+	if data, err := os.ReadFile("/proc/device-tree/model"); err == nil {
+		if model := strings.TrimSpace(strings.TrimRight(string(data), "\x00")); model != "" {
+			return model
+		}
+	}
+
+	f, err := os.Open("/proc/cpuinfo")
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if ok && strings.TrimSpace(key) == "model name" {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
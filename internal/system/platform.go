@@ -0,0 +1,44 @@
+package system
+
+import (
+	"os/exec"
+	"runtime"
+)
+
+// PlatformCapabilities reports which host integrations are usable on the
+// running OS. Non-Linux builds are intended for API/UI development only.
+type PlatformCapabilities struct {
+	OS           string `json:"os"`
+	Arch         string `json:"arch"`
+	Production   bool   `json:"production"`
+	USBNet       bool   `json:"usbnet"`
+	Modems       bool   `json:"modems"`
+	WiFi         bool   `json:"wifi"`
+	USBCameras   bool   `json:"usb_cameras"`
+	Bluetooth    bool   `json:"bluetooth"`
+	Installer    bool   `json:"installer"`
+	SignalReload bool   `json:"signal_reload"`
+}
+
+// GetPlatformCapabilities returns the capability flags for the current host.
+func GetPlatformCapabilities() PlatformCapabilities {
+	linux := runtime.GOOS == "linux"
+
+	return PlatformCapabilities{
+		OS:           runtime.GOOS,
+		Arch:         runtime.GOARCH,
+		Production:   linux,
+		USBNet:       linux,
+		Modems:       linux && (hasBinary("mmcli") || hasBinary("adb")),
+		WiFi:         linux && hasBinary("nmcli"),
+		USBCameras:   linux,
+		Bluetooth:    linux,
+		Installer:    linux,
+		SignalReload: runtime.GOOS != "windows",
+	}
+}
+
+func hasBinary(name string) bool {
+	_, err := exec.LookPath(name)
+	return err == nil
+}
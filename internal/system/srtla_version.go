@@ -0,0 +1,148 @@
+package system
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SRTLAFeature names an optional srtla_send capability
+type SRTLAFeature string
+
+const (
+	SRTLAFeatureClassic     SRTLAFeature = "classic"     // --classic
+	SRTLAFeatureNoQuality   SRTLAFeature = "no_quality"  // --no-quality
+	SRTLAFeatureExploration SRTLAFeature = "exploration" // --exploration
+	SRTLAFeatureIPsReload   SRTLAFeature = "ips_reload"  // re-read the IPs file on SIGHUP
+	SRTLAFeatureSRTLA2      SRTLAFeature = "srtla2"      // --srtla2
+)
+
+// srtlaFeatureMatrix maps each feature to the first srtla_send release that
+// supports it. Older releases exit on unknown flags, so these gate what we pass.
+var srtlaFeatureMatrix = map[SRTLAFeature][3]int{
+	SRTLAFeatureIPsReload:   {1, 0, 0},
+	SRTLAFeatureClassic:     {2, 0, 0},
+	SRTLAFeatureNoQuality:   {2, 1, 0},
+	SRTLAFeatureExploration: {2, 2, 0},
+	SRTLAFeatureSRTLA2:      {3, 0, 0},
+}
+
+// srtlaPackages are the dpkg package names srtla_send has shipped under
+var srtlaPackages = []string{"srtla", "srtla-send", "srtla_send"}
+
+var srtlaVersionRegex = regexp.MustCompile(`v?(\d+)\.(\d+)(?:\.(\d+))?`)
+
+// SRTLACapabilities describes the installed srtla_send and what it supports
+type SRTLACapabilities struct {
+	Version  string                `json:"version,omitempty"`
+	Source   string                `json:"source,omitempty"` // "binary" or "package"
+	Features map[SRTLAFeature]bool `json:"features"`
+
+	parsed [3]int
+	known  bool
+}
+
+// Supports reports whether the installed srtla_send has the feature. When the
+// version can't be determined every feature is assumed to be available, so
+// custom builds keep working as before.
+func (c SRTLACapabilities) Supports(f SRTLAFeature) bool {
+	if !c.known {
+		return true
+	}
+	min, ok := srtlaFeatureMatrix[f]
+	if !ok {
+		return true
+	}
+	return !versionLess(c.parsed, min)
+}
+
+var srtlaCache struct {
+	mu      sync.Mutex
+	path    string
+	modTime time.Time
+	caps    SRTLACapabilities
+}
+
+// DetectSRTLA returns the version and feature set of the srtla_send binary.
+// The result is cached until the binary on disk changes.
+func DetectSRTLA(binaryPath string) SRTLACapabilities {
+	absPath := FindSRTLA(binaryPath)
+	if absPath == "" {
+		return newSRTLACapabilities("", "")
+	}
+
+	var modTime time.Time
+	if info, err := os.Stat(absPath); err == nil {
+		modTime = info.ModTime()
+	}
+
+	srtlaCache.mu.Lock()
+	defer srtlaCache.mu.Unlock()
+
+	if srtlaCache.path == absPath && srtlaCache.modTime.Equal(modTime) {
+		return srtlaCache.caps
+	}
+
+	caps := newSRTLACapabilities(srtlaVersionFromBinary(absPath), "binary")
+	if !caps.known {
+		caps = newSRTLACapabilities(srtlaVersionFromPackage(), "package")
+	}
+
+	srtlaCache.path = absPath
+	srtlaCache.modTime = modTime
+	srtlaCache.caps = caps
+	return caps
+}
+
+func newSRTLACapabilities(version, source string) SRTLACapabilities {
+	caps := SRTLACapabilities{Features: make(map[SRTLAFeature]bool, len(srtlaFeatureMatrix))}
+	if m := srtlaVersionRegex.FindStringSubmatch(version); m != nil {
+		for i := 0; i < 3; i++ {
+			caps.parsed[i], _ = strconv.Atoi(m[i+1])
+		}
+		caps.Version = strings.TrimPrefix(m[0], "v")
+		caps.Source = source
+		caps.known = true
+	}
+	for f := range srtlaFeatureMatrix {
+		caps.Features[f] = caps.Supports(f)
+	}
+	return caps
+}
+
+// srtlaVersionFromBinary runs srtla_send --version
+func srtlaVersionFromBinary(path string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, path, "--version").CombinedOutput()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// srtlaVersionFromPackage asks dpkg for the installed package version
+func srtlaVersionFromPackage() string {
+	for _, pkg := range srtlaPackages {
+		output, err := exec.Command("dpkg-query", "-W", "-f=${Version}", pkg).Output()
+		if err == nil && len(output) > 0 {
+			return strings.TrimSpace(string(output))
+		}
+	}
+	return ""
+}
+
+func versionLess(a, b [3]int) bool {
+	for i := 0; i < 3; i++ {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return false
+}
@@ -7,6 +7,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 )
 
@@ -60,6 +61,22 @@ func CheckSRTLA(binaryPath string) DependencyStatus {
 		InstallCommand: getSRTLAInstallCommand(),
 	}
 
+	absPath := FindSRTLA(binaryPath)
+	if absPath == "" {
+		return status
+	}
+
+	status.Installed = true
+	status.Path = absPath
+
+	status.Version = DetectSRTLA(absPath).Version
+
+	return status
+}
+
+// FindSRTLA resolves the srtla_send binary without executing it.
+// Returns the absolute path, or "" when the binary cannot be found.
+func FindSRTLA(binaryPath string) string {
 	searchPaths := []string{binaryPath}
 	if binaryPath == "" || binaryPath == "srtla_send" {
 		if runtime.GOOS == "windows" {
@@ -83,19 +100,27 @@ func CheckSRTLA(binaryPath string) DependencyStatus {
 			continue
 		}
 		if _, err := os.Stat(absPath); err == nil {
-			status.Installed = true
-			status.Path = absPath
-
-			cmd := exec.Command(absPath, "-v")
-			output, err := cmd.Output()
-			if err == nil {
-				status.Version = strings.TrimSpace(string(output))
-			}
-			break
+			return absPath
 		}
 	}
 
-	return status
+	return ""
+}
+
+// FindSRTLiveTransmit resolves srt-live-transmit on PATH, used for native SRT
+// bonding. Returns "" when it isn't installed.
+func FindSRTLiveTransmit() string {
+	path, err := exec.LookPath("srt-live-transmit")
+	if err != nil {
+		return ""
+	}
+	return path
+}
+
+// IsFFmpegInstalled reports whether ffmpeg is on PATH without executing it.
+func IsFFmpegInstalled() bool {
+	_, err := exec.LookPath("ffmpeg")
+	return err == nil
 }
 
 func ListNetworkInterfaces() []NetworkInterface {
@@ -285,3 +310,31 @@ func detectOS() string {
 func GetOSInfo() string {
 	return detectOS()
 }
+
+// ClockSynchronized reports whether systemd-timesyncd or chrony has
+// disciplined the system clock. It is false where timedatectl is missing.
+func ClockSynchronized() bool {
+	out, err := exec.Command("timedatectl", "show", "-p", "NTPSynchronized", "--value").Output()
+	return err == nil && strings.TrimSpace(string(out)) == "yes"
+}
+
+// ServiceState returns the state systemctl reports for a unit, such as
+// active, failed or inactive. It is "unknown" where systemctl is missing.
+func ServiceState(unit string) string {
+	// is-active exits non-zero for anything but active, still printing the state
+	out, _ := exec.Command("systemctl", "is-active", unit).Output()
+	if state := strings.TrimSpace(string(out)); state != "" {
+		return state
+	}
+	return "unknown"
+}
+
+// InterfaceTxBytes returns the bytes an interface has sent since it came up.
+// It fails where /sys/class/net is missing.
+func InterfaceTxBytes(name string) (uint64, error) {
+	data, err := os.ReadFile(filepath.Join("/sys/class/net", name, "statistics", "tx_bytes"))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+}
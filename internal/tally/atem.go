@@ -0,0 +1,132 @@
+package tally
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"time"
+)
+
+// ATEMPort is the ATEM switcher control port
+const ATEMPort = 9910
+
+// ATEM packet header flags, in the top five bits of the first word
+const (
+	atemFlagReliable = 0x01
+	atemFlagHello    = 0x02
+	atemFlagAck      = 0x10
+)
+
+const atemHeaderLen = 12
+
+// atemTimeout is how long the switcher may stay silent; it sends keepalives
+// well within this while a client is connected
+const atemTimeout = 5 * time.Second
+
+// WatchATEM follows the TlIn (tally by input) command over the ATEM UDP
+// protocol. Only tally is read; no commands are ever sent to the switcher.
+func WatchATEM(ctx context.Context, host string, input int, update func(State)) error {
+	raddr, err := net.ResolveUDPAddr("udp", net.JoinHostPort(host, fmt.Sprint(ATEMPort)))
+	if err != nil {
+		return fmt.Errorf("ATEM: %w", err)
+	}
+	conn, err := net.DialUDP("udp", nil, raddr)
+	if err != nil {
+		return fmt.Errorf("ATEM: %w", err)
+	}
+	defer conn.Close()
+
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	const clientSession = 0x53ab
+	hello := atemHeader(atemFlagHello, clientSession, 0, 8)
+	hello = append(hello, 0x01, 0, 0, 0, 0, 0, 0, 0)
+	if _, err := conn.Write(hello); err != nil {
+		return fmt.Errorf("ATEM: %w", err)
+	}
+
+	buf := make([]byte, 2048)
+	connected := false
+	for {
+		conn.SetReadDeadline(time.Now().Add(atemTimeout))
+		n, err := conn.Read(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			if !connected {
+				return fmt.Errorf("ATEM: no answer from %s: %w", host, err)
+			}
+			return fmt.Errorf("ATEM: connection lost: %w", err)
+		}
+		if n < atemHeaderLen {
+			continue
+		}
+
+		pkt := buf[:n]
+		flags := pkt[0] >> 3
+		session := binary.BigEndian.Uint16(pkt[2:4])
+
+		if flags&atemFlagHello != 0 {
+			if n > atemHeaderLen && pkt[atemHeaderLen] != 0x02 {
+				return fmt.Errorf("ATEM: switcher refused the connection (code %d)", pkt[atemHeaderLen])
+			}
+			conn.Write(atemHeader(atemFlagAck, clientSession, 0, 0))
+			connected = true
+			continue
+		}
+
+		if flags&atemFlagReliable != 0 {
+			packetID := binary.BigEndian.Uint16(pkt[10:12])
+			conn.Write(atemHeader(atemFlagAck, session, packetID, 0))
+		}
+
+		if program, preview, ok := parseATEMTally(pkt[atemHeaderLen:]); ok {
+			update(inputState(program, preview, input))
+		}
+	}
+}
+
+// atemHeader builds a packet header for a packet carrying payloadLen bytes
+func atemHeader(flags uint8, session, ackID uint16, payloadLen int) []byte {
+	h := make([]byte, atemHeaderLen)
+	binary.BigEndian.PutUint16(h[0:2], uint16(flags)<<11|uint16(atemHeaderLen+payloadLen))
+	binary.BigEndian.PutUint16(h[2:4], session)
+	binary.BigEndian.PutUint16(h[4:6], ackID)
+	return h
+}
+
+// parseATEMTally walks the commands in a packet payload and decodes the last
+// TlIn: a count followed by one byte per input, bit 0 program and bit 1
+// preview
+func parseATEMTally(payload []byte) (program, preview []bool, ok bool) {
+	for len(payload) >= 8 {
+		size := int(binary.BigEndian.Uint16(payload[0:2]))
+		if size < 8 || size > len(payload) {
+			return program, preview, ok
+		}
+		name := string(payload[4:8])
+		data := payload[8:size]
+		payload = payload[size:]
+
+		if name != "TlIn" || len(data) < 2 {
+			continue
+		}
+		count := int(binary.BigEndian.Uint16(data[0:2]))
+		if count > len(data)-2 {
+			count = len(data) - 2
+		}
+		program = make([]bool, count)
+		preview = make([]bool, count)
+		for i := 0; i < count; i++ {
+			program[i] = data[2+i]&0x01 != 0
+			preview[i] = data[2+i]&0x02 != 0
+		}
+		ok = true
+	}
+	return program, preview, ok
+}
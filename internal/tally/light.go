@@ -0,0 +1,82 @@
+package tally
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+
+	"srtla-manager/internal/gpio"
+)
+
+// GPIOLight drives a program pin and an optional preview pin
+type GPIOLight struct {
+	program *gpio.Pin
+	preview *gpio.Pin // nil when unused
+}
+
+// NewGPIOLight exports the pins and configures them as outputs, initially off
+func NewGPIOLight(programPin, previewPin int, activeLow bool) (*GPIOLight, error) {
+	program, err := gpio.Output(programPin, activeLow)
+	if err != nil {
+		return nil, err
+	}
+	l := &GPIOLight{program: program}
+	if previewPin > 0 {
+		if l.preview, err = gpio.Output(previewPin, activeLow); err != nil {
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+func (l *GPIOLight) Set(state State) error {
+	if err := l.program.Set(state == Program); err != nil {
+		return err
+	}
+	if l.preview != nil {
+		return l.preview.Set(state == Preview)
+	}
+	return nil
+}
+
+// Close turns the light off and leaves the pins exported
+func (l *GPIOLight) Close() error {
+	return l.Set(Off)
+}
+
+// SerialLight writes one line per state change to a USB serial tally:
+// "PGM", "PVW" or "OFF"
+type SerialLight struct {
+	f *os.File
+}
+
+// serialCommands are the lines sent for each state
+var serialCommands = map[State]string{
+	Off:     "OFF\n",
+	Preview: "PVW\n",
+	Program: "PGM\n",
+}
+
+// NewSerialLight opens the device at the given baud rate
+func NewSerialLight(device string, baud int) (*SerialLight, error) {
+	if out, err := exec.Command("stty", "-F", device, strconv.Itoa(baud), "raw", "-echo").CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("failed to configure %s: %v: %s", device, err, out)
+	}
+	f, err := os.OpenFile(device, os.O_WRONLY, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", device, err)
+	}
+	l := &SerialLight{f: f}
+	return l, l.Set(Off)
+}
+
+func (l *SerialLight) Set(state State) error {
+	_, err := l.f.WriteString(serialCommands[state])
+	return err
+}
+
+func (l *SerialLight) Close() error {
+	l.Set(Off)
+	return l.f.Close()
+}
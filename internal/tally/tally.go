@@ -0,0 +1,102 @@
+// Package tally drives an on-camera tally light from the pipeline state or
+// from a vision switcher's tally feed (Blackmagic ATEM, vMix).
+package tally
+
+import (
+	"context"
+	"time"
+)
+
+// State is what the light shows
+type State string
+
+const (
+	Off     State = "off"
+	Preview State = "preview"
+	Program State = "program"
+)
+
+// States lists the values accepted when forcing the light
+var States = []string{string(Off), string(Preview), string(Program)}
+
+// Sources of tally state
+const (
+	SourcePipeline = "pipeline"
+	SourceSwitcher = "switcher"
+)
+
+// Sources lists the values accepted for tally.source
+var Sources = []string{SourcePipeline, SourceSwitcher}
+
+// Supported switchers
+const (
+	SwitcherATEM = "atem"
+	SwitcherVMix = "vmix"
+)
+
+// Switchers lists the values accepted for switcher.type
+var Switchers = []string{SwitcherATEM, SwitcherVMix}
+
+// Outputs that can drive a light
+const (
+	OutputGPIO   = "gpio"
+	OutputSerial = "serial"
+)
+
+// Outputs lists the values accepted for tally.output
+var Outputs = []string{OutputGPIO, OutputSerial}
+
+// Light is a physical tally output
+type Light interface {
+	Set(State) error
+	Close() error
+}
+
+// WatcherFor returns the watcher for a switcher type
+func WatcherFor(switcher string) Watcher {
+	if switcher == SwitcherVMix {
+		return WatchVMix
+	}
+	return WatchATEM
+}
+
+// Watcher follows a switcher's tally for one input, calling update whenever
+// it changes. It returns when the connection drops or ctx is done.
+type Watcher func(ctx context.Context, host string, input int, update func(State)) error
+
+// reconnectDelay is how long to wait before reconnecting to a switcher
+const reconnectDelay = 3 * time.Second
+
+// Watch runs watcher until ctx is done, reconnecting after failures. The
+// light goes off while the switcher is unreachable so talent is never told
+// they're live on stale information.
+func Watch(ctx context.Context, watcher Watcher, host string, input int, update func(State), onError func(error)) {
+	for {
+		err := watcher(ctx, host, input, update)
+		if ctx.Err() != nil {
+			return
+		}
+		update(Off)
+		if err != nil && onError != nil {
+			onError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(reconnectDelay):
+		}
+	}
+}
+
+// inputState picks an input's state out of a switcher tally list (1-based)
+func inputState(program, preview []bool, input int) State {
+	i := input - 1
+	switch {
+	case i >= 0 && i < len(program) && program[i]:
+		return Program
+	case i >= 0 && i < len(preview) && preview[i]:
+		return Preview
+	default:
+		return Off
+	}
+}
@@ -0,0 +1,62 @@
+package tally
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestParseVMixTally(t *testing.T) {
+	tests := []struct {
+		line  string
+		input int
+		want  State
+		ok    bool
+	}{
+		{"TALLY OK 0121\r\n", 2, Program, true},
+		{"TALLY OK 0121", 3, Preview, true},
+		{"TALLY OK 0121", 1, Off, true},
+		{"TALLY OK 0121", 9, Off, true}, // input beyond the list
+		{"SUBSCRIBE OK TALLY", 1, Off, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseVMixTally(tt.line, tt.input)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("%q input %d: expected %s/%v, got %s/%v", tt.line, tt.input, tt.want, tt.ok, got, ok)
+		}
+	}
+}
+
+func atemCommand(name string, data []byte) []byte {
+	cmd := make([]byte, 8, 8+len(data))
+	binary.BigEndian.PutUint16(cmd[0:2], uint16(8+len(data)))
+	copy(cmd[4:8], name)
+	return append(cmd, data...)
+}
+
+func TestParseATEMTally(t *testing.T) {
+	payload := atemCommand("_ver", []byte{0, 2, 0, 30})
+	payload = append(payload, atemCommand("TlIn", []byte{0, 4, 0x00, 0x01, 0x02, 0x03})...)
+
+	program, preview, ok := parseATEMTally(payload)
+	if !ok {
+		t.Fatal("Expected TlIn to be found")
+	}
+	if got := inputState(program, preview, 2); got != Program {
+		t.Errorf("Input 2: expected program, got %s", got)
+	}
+	if got := inputState(program, preview, 3); got != Preview {
+		t.Errorf("Input 3: expected preview, got %s", got)
+	}
+	// Program wins when an input is on both buses
+	if got := inputState(program, preview, 4); got != Program {
+		t.Errorf("Input 4: expected program, got %s", got)
+	}
+	if got := inputState(program, preview, 1); got != Off {
+		t.Errorf("Input 1: expected off, got %s", got)
+	}
+
+	if _, _, ok := parseATEMTally(atemCommand("Time", []byte{1, 2, 3, 4})); ok {
+		t.Error("Expected no tally in a packet without TlIn")
+	}
+}
@@ -0,0 +1,62 @@
+package tally
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// VMixPort is the vMix TCP API port
+const VMixPort = 8099
+
+// WatchVMix subscribes to tally over the vMix TCP API. vMix answers with
+// "TALLY OK 0121..." lines: one digit per input, 0 off, 1 program, 2 preview.
+func WatchVMix(ctx context.Context, host string, input int, update func(State)) error {
+	var d net.Dialer
+	dialCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	conn, err := d.DialContext(dialCtx, "tcp", net.JoinHostPort(host, fmt.Sprint(VMixPort)))
+	cancel()
+	if err != nil {
+		return fmt.Errorf("vMix: %w", err)
+	}
+	defer conn.Close()
+
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	if _, err := conn.Write([]byte("SUBSCRIBE TALLY\r\n")); err != nil {
+		return fmt.Errorf("vMix: %w", err)
+	}
+
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		if state, ok := parseVMixTally(scanner.Text(), input); ok {
+			update(state)
+		}
+	}
+	if err := scanner.Err(); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("vMix: %w", err)
+	}
+	return fmt.Errorf("vMix: connection closed")
+}
+
+// parseVMixTally reads an input's state from a "TALLY OK ..." line
+func parseVMixTally(line string, input int) (State, bool) {
+	digits, ok := strings.CutPrefix(strings.TrimSpace(line), "TALLY OK ")
+	if !ok {
+		return Off, false
+	}
+
+	program := make([]bool, len(digits))
+	preview := make([]bool, len(digits))
+	for i, c := range digits {
+		program[i] = c == '1'
+		preview[i] = c == '2'
+	}
+	return inputState(program, preview, input), true
+}
@@ -0,0 +1,109 @@
+package timecode
+
+// LTC (SMPTE 12M linear timecode) is an 80-bit word per frame sent with
+// biphase mark coding: every bit cell starts with a transition and a one has
+// a second transition in the middle of the cell.
+
+const ltcBits = 80
+
+// ltcSync is the sync word in bits 64-79, in transmission order
+var ltcSync = [16]byte{0, 0, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 0, 1}
+
+// ltcHysteresis keeps noise around zero from being read as transitions
+const ltcHysteresis = 512
+
+// LTCDecoder decodes LTC from signed 16-bit mono samples
+type LTCDecoder struct {
+	period float64 // estimated samples per bit cell
+	high   bool
+	count  int // samples since the last transition
+	half   int // length of a pending half cell, 0 when none
+	bits   [ltcBits]byte
+}
+
+// NewLTCDecoder returns a decoder for the given sample rate. The bit cell
+// length is tracked from the signal, so any frame rate is followed.
+func NewLTCDecoder(sampleRate int) *LTCDecoder {
+	// start from 25 fps; 24 and 30 fps cells are within the tracking range
+	return &LTCDecoder{period: float64(sampleRate) / (25 * ltcBits)}
+}
+
+// Decode feeds samples through the decoder, calling frame for every complete
+// timecode word. The word arrives as the frame it labels ends.
+func (d *LTCDecoder) Decode(samples []int16, frame func(Timecode)) {
+	for _, s := range samples {
+		d.count++
+		switch {
+		case !d.high && s > ltcHysteresis:
+			d.high = true
+		case d.high && s < -ltcHysteresis:
+			d.high = false
+		default:
+			if float64(d.count) > 4*d.period {
+				d.half = 0 // signal lost
+			}
+			continue
+		}
+		d.transition(d.count, frame)
+		d.count = 0
+	}
+}
+
+func (d *LTCDecoder) transition(interval int, frame func(Timecode)) {
+	if float64(interval) < 0.75*d.period {
+		if d.half == 0 {
+			d.half = interval
+			return
+		}
+		d.track(d.half + interval)
+		d.half = 0
+		d.push(1, frame)
+		return
+	}
+	d.half = 0
+	d.track(interval)
+	d.push(0, frame)
+}
+
+// track follows slow changes in the bit cell length, e.g. varispeed
+func (d *LTCDecoder) track(cell int) {
+	if float64(cell) > 2*d.period {
+		return
+	}
+	d.period = 0.9*d.period + 0.1*float64(cell)
+}
+
+func (d *LTCDecoder) push(bit byte, frame func(Timecode)) {
+	copy(d.bits[:], d.bits[1:])
+	d.bits[ltcBits-1] = bit
+	for i, b := range ltcSync {
+		if d.bits[64+i] != b {
+			return
+		}
+	}
+	if tc, ok := d.word(); ok {
+		frame(tc)
+	}
+}
+
+// word reads the timecode out of a complete LTC word
+func (d *LTCDecoder) word() (Timecode, bool) {
+	bcd := func(start, n int) int {
+		v := 0
+		for i := 0; i < n; i++ {
+			v |= int(d.bits[start+i]) << i
+		}
+		return v
+	}
+	tc := Timecode{
+		Frames:    bcd(8, 2)*10 + bcd(0, 4),
+		Seconds:   bcd(24, 3)*10 + bcd(16, 4),
+		Minutes:   bcd(40, 3)*10 + bcd(32, 4),
+		Hours:     bcd(56, 2)*10 + bcd(48, 4),
+		DropFrame: d.bits[10] == 1,
+	}
+	if tc.Frames >= 30 || tc.Seconds >= 60 || tc.Minutes >= 60 || tc.Hours >= 24 {
+		return Timecode{}, false
+	}
+	return tc, true
+}
@@ -0,0 +1,105 @@
+// Package timecode handles SMPTE time-of-day timecode, derived from the
+// system clock or decoded from an LTC audio signal.
+package timecode
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Sources of timecode
+const (
+	SourceClock = "clock"
+	SourceLTC   = "ltc"
+)
+
+// Sources lists the values accepted for timecode.source
+var Sources = []string{SourceClock, SourceLTC}
+
+// Rate is a timecode frame rate. Drop-frame is only defined for 30 fps,
+// where it counts 29.97 fps in 30 fps labels.
+type Rate struct {
+	FPS       int
+	DropFrame bool
+}
+
+// Frequency is the real number of frames per second
+func (r Rate) Frequency() float64 {
+	if r.DropFrame {
+		return float64(r.FPS) * 1000 / 1001
+	}
+	return float64(r.FPS)
+}
+
+// FramesPerDay is the number of frame labels in 24 hours
+func (r Rate) FramesPerDay() int {
+	if r.DropFrame {
+		// two labels are skipped each minute except every tenth
+		return 24 * (r.FPS*3600 - 2*54)
+	}
+	return 24 * 3600 * r.FPS
+}
+
+// Timecode is an SMPTE HH:MM:SS:FF label
+type Timecode struct {
+	Hours     int  `json:"hours"`
+	Minutes   int  `json:"minutes"`
+	Seconds   int  `json:"seconds"`
+	Frames    int  `json:"frames"`
+	DropFrame bool `json:"drop_frame"`
+}
+
+// String formats the timecode, separating drop-frame frames with ';'
+func (tc Timecode) String() string {
+	sep := ":"
+	if tc.DropFrame {
+		sep = ";"
+	}
+	return fmt.Sprintf("%02d:%02d:%02d%s%02d", tc.Hours, tc.Minutes, tc.Seconds, sep, tc.Frames)
+}
+
+// FrameCount is the number of frames since midnight
+func (tc Timecode) FrameCount(r Rate) int {
+	n := ((tc.Hours*60+tc.Minutes)*60+tc.Seconds)*r.FPS + tc.Frames
+	if r.DropFrame {
+		minutes := tc.Hours*60 + tc.Minutes
+		n -= 2 * (minutes - minutes/10)
+	}
+	return n
+}
+
+// FromFrames labels the nth frame since midnight, wrapping at 24 hours
+func FromFrames(n int, r Rate) Timecode {
+	n %= r.FramesPerDay()
+	if n < 0 {
+		n += r.FramesPerDay()
+	}
+	if r.DropFrame {
+		perTen := 10*60*r.FPS - 9*2
+		perMinute := 60*r.FPS - 2
+		tens, rem := n/perTen, n%perTen
+		n += 9 * 2 * tens
+		if rem > 1 {
+			n += 2 * ((rem - 2) / perMinute)
+		}
+	}
+	return Timecode{
+		Hours:     n / (3600 * r.FPS),
+		Minutes:   n / (60 * r.FPS) % 60,
+		Seconds:   n / r.FPS % 60,
+		Frames:    n % r.FPS,
+		DropFrame: r.DropFrame,
+	}
+}
+
+// At is the time-of-day timecode of t in its location
+func At(t time.Time, r Rate) Timecode {
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	return FromFrames(int(t.Sub(midnight).Seconds()*r.Frequency()), r)
+}
+
+// Add advances tc by d
+func (tc Timecode) Add(d time.Duration, r Rate) Timecode {
+	return FromFrames(tc.FrameCount(r)+int(math.Round(d.Seconds()*r.Frequency())), r)
+}
@@ -0,0 +1,101 @@
+package timecode
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDropFrameLabels(t *testing.T) {
+	df := Rate{FPS: 30, DropFrame: true}
+	tests := []struct {
+		frames int
+		want   string
+	}{
+		{0, "00:00:00;00"},
+		{1799, "00:00:59;29"},
+		{1800, "00:01:00;02"},
+		{17981, "00:09:59;29"},
+		{17982, "00:10:00;00"},
+		{107892, "01:00:00;00"},
+		{df.FramesPerDay(), "00:00:00;00"},
+	}
+	for _, tt := range tests {
+		tc := FromFrames(tt.frames, df)
+		if got := tc.String(); got != tt.want {
+			t.Errorf("FromFrames(%d) = %s, want %s", tt.frames, got, tt.want)
+		}
+		if n := tc.FrameCount(df); n != tt.frames%df.FramesPerDay() {
+			t.Errorf("FrameCount(%s) = %d, want %d", tc, n, tt.frames)
+		}
+	}
+}
+
+func TestAt(t *testing.T) {
+	r := Rate{FPS: 25}
+	at := time.Date(2024, 5, 1, 13, 45, 10, 520*int(time.Millisecond), time.UTC)
+	if got := At(at, r).String(); got != "13:45:10:13" {
+		t.Errorf("At = %s, want 13:45:10:13", got)
+	}
+	if got := At(at, r).Add(time.Second, r).String(); got != "13:45:11:13" {
+		t.Errorf("Add = %s, want 13:45:11:13", got)
+	}
+}
+
+// encodeLTC renders one LTC word with biphase mark coding
+func encodeLTC(tc Timecode, samplesPerBit int, level *int16) []int16 {
+	var bits [ltcBits]byte
+	set := func(start, n, v int) {
+		for i := 0; i < n; i++ {
+			bits[start+i] = byte(v >> i & 1)
+		}
+	}
+	set(0, 4, tc.Frames%10)
+	set(8, 2, tc.Frames/10)
+	if tc.DropFrame {
+		bits[10] = 1
+	}
+	set(16, 4, tc.Seconds%10)
+	set(24, 3, tc.Seconds/10)
+	set(32, 4, tc.Minutes%10)
+	set(40, 3, tc.Minutes/10)
+	set(48, 4, tc.Hours%10)
+	set(56, 2, tc.Hours/10)
+	copy(bits[64:], ltcSync[:])
+
+	var out []int16
+	for _, b := range bits {
+		*level = -*level
+		for i := 0; i < samplesPerBit; i++ {
+			if b == 1 && i == samplesPerBit/2 {
+				*level = -*level
+			}
+			out = append(out, *level)
+		}
+	}
+	return out
+}
+
+func TestLTCDecode(t *testing.T) {
+	for _, fps := range []int{24, 25, 30} {
+		r := Rate{FPS: fps, DropFrame: fps == 30}
+		start := Timecode{Hours: 10, Minutes: 9, Seconds: 59, Frames: fps - 2, DropFrame: r.DropFrame}
+
+		var samples []int16
+		level := int16(8000)
+		for i := 0; i < 5; i++ {
+			samples = append(samples, encodeLTC(start.Add(time.Duration(i)*time.Second/time.Duration(fps), r), 48000/(fps*ltcBits), &level)...)
+		}
+		// the last bit ends at the next word's leading edge
+		samples = append(samples, -level)
+
+		var got []string
+		NewLTCDecoder(48000).Decode(samples, func(tc Timecode) { got = append(got, tc.String()) })
+		if len(got) < 4 {
+			t.Fatalf("%d fps: decoded %d words, want at least 4", fps, len(got))
+		}
+		last := start.Add(4*time.Second/time.Duration(fps), r).String()
+		if got[len(got)-1] != last {
+			t.Errorf("%d fps: last word %s, want %s", fps, got[len(got)-1], last)
+		}
+	}
+}
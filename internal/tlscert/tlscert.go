@@ -0,0 +1,214 @@
+// Package tlscert manages the web server's TLS certificate: a self-signed
+// one generated on first boot, or one uploaded by the user. The server reads
+// it through a Store, so a new certificate is used without a restart.
+package tlscert
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SelfSignedValidity is how long a generated certificate is valid for
+const SelfSignedValidity = 10 * 365 * 24 * time.Hour
+
+// Info describes a certificate
+type Info struct {
+	Subject     string    `json:"subject"`
+	Issuer      string    `json:"issuer"`
+	DNSNames    []string  `json:"dns_names"`
+	IPAddresses []string  `json:"ip_addresses"`
+	NotBefore   time.Time `json:"not_before"`
+	NotAfter    time.Time `json:"not_after"`
+	SelfSigned  bool      `json:"self_signed"`
+	SHA256      string    `json:"sha256"` // fingerprint, to check against what the browser shows
+}
+
+// Store holds the certificate served by the web server
+type Store struct {
+	certFile string
+	keyFile  string
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+}
+
+// NewStore serves the certificate in certFile and keyFile
+func NewStore(certFile, keyFile string) *Store {
+	return &Store{certFile: certFile, keyFile: keyFile}
+}
+
+// EnsureSelfSigned generates a self-signed certificate for hosts unless a
+// certificate is already in place, then loads it. It reports whether one was
+// generated.
+func (s *Store) EnsureSelfSigned(hosts []string) (bool, error) {
+	_, certErr := os.Stat(s.certFile)
+	_, keyErr := os.Stat(s.keyFile)
+	if certErr == nil && keyErr == nil {
+		return false, s.Load()
+	}
+	if err := s.GenerateSelfSigned(hosts); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// GenerateSelfSigned replaces the certificate with a new self-signed one for
+// hosts, which may be names or IP addresses
+func (s *Store) GenerateSelfSigned(hosts []string) error {
+	certPEM, keyPEM, err := SelfSigned(hosts, time.Now())
+	if err != nil {
+		return err
+	}
+	return s.Install(certPEM, keyPEM)
+}
+
+// Install checks that certPEM and keyPEM are a matching pair, writes them and
+// starts serving them
+func (s *Store) Install(certPEM, keyPEM []byte) error {
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return fmt.Errorf("invalid certificate or key: %w", err)
+	}
+	if err := writeFile(s.certFile, certPEM, 0644); err != nil {
+		return err
+	}
+	if err := writeFile(s.keyFile, keyPEM, 0600); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.cert = &cert
+	s.mu.Unlock()
+	return nil
+}
+
+// Load reads the certificate from disk
+func (s *Store) Load() error {
+	cert, err := tls.LoadX509KeyPair(s.certFile, s.keyFile)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.cert = &cert
+	s.mu.Unlock()
+	return nil
+}
+
+// GetCertificate is used as tls.Config.GetCertificate
+func (s *Store) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.cert == nil {
+		return nil, errors.New("no certificate loaded")
+	}
+	return s.cert, nil
+}
+
+// Info describes the certificate being served, or nil before one is loaded
+func (s *Store) Info() *Info {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.cert == nil || len(s.cert.Certificate) == 0 {
+		return nil
+	}
+	leaf, err := x509.ParseCertificate(s.cert.Certificate[0])
+	if err != nil {
+		return nil
+	}
+	info := describe(leaf)
+	return &info
+}
+
+// SelfSigned creates a self-signed certificate and ECDSA P-256 key for hosts,
+// PEM encoded
+func SelfSigned(hosts []string, now time.Time) ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "srtla-manager", Organization: []string{"srtla-manager"}},
+		NotBefore:             now.Add(-time.Hour), // tolerate a clock slightly behind
+		NotAfter:              now.Add(SelfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		} else if h != "" {
+			tmpl.DNSNames = append(tmpl.DNSNames, h)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return certPEM, keyPEM, nil
+}
+
+func describe(c *x509.Certificate) Info {
+	sum := sha256.Sum256(c.Raw)
+	fingerprint := strings.ToUpper(hex.EncodeToString(sum[:]))
+	var pairs []string
+	for i := 0; i < len(fingerprint); i += 2 {
+		pairs = append(pairs, fingerprint[i:i+2])
+	}
+
+	info := Info{
+		Subject:     c.Subject.String(),
+		Issuer:      c.Issuer.String(),
+		DNSNames:    append([]string{}, c.DNSNames...),
+		IPAddresses: []string{},
+		NotBefore:   c.NotBefore,
+		NotAfter:    c.NotAfter,
+		SelfSigned:  c.CheckSignature(c.SignatureAlgorithm, c.RawTBSCertificate, c.Signature) == nil,
+		SHA256:      strings.Join(pairs, ":"),
+	}
+	for _, ip := range c.IPAddresses {
+		info.IPAddresses = append(info.IPAddresses, ip.String())
+	}
+	return info
+}
+
+// writeFile replaces path atomically so a crash can't leave half a key
+func writeFile(path string, data []byte, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,65 @@
+package tlscert
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestEnsureSelfSigned(t *testing.T) {
+	dir := t.TempDir()
+	s := NewStore(filepath.Join(dir, "tls", "cert.pem"), filepath.Join(dir, "tls", "key.pem"))
+
+	generated, err := s.EnsureSelfSigned([]string{"srtla.local", "10.42.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !generated {
+		t.Error("no certificate generated on first boot")
+	}
+
+	info := s.Info()
+	if info == nil || !info.SelfSigned {
+		t.Fatalf("got %+v", info)
+	}
+	if len(info.DNSNames) != 1 || info.DNSNames[0] != "srtla.local" ||
+		len(info.IPAddresses) != 1 || info.IPAddresses[0] != "10.42.0.1" {
+		t.Errorf("got names %v, addresses %v", info.DNSNames, info.IPAddresses)
+	}
+	if fi, err := os.Stat(filepath.Join(dir, "tls", "key.pem")); err != nil || fi.Mode().Perm() != 0600 {
+		t.Errorf("key file: %v, %v", fi, err)
+	}
+
+	// the second boot keeps it
+	again := NewStore(filepath.Join(dir, "tls", "cert.pem"), filepath.Join(dir, "tls", "key.pem"))
+	if generated, err := again.EnsureSelfSigned(nil); err != nil || generated {
+		t.Errorf("got generated %v, %v", generated, err)
+	}
+	if again.Info().SHA256 != info.SHA256 {
+		t.Error("certificate changed between boots")
+	}
+}
+
+func TestInstallRejectsMismatchedKey(t *testing.T) {
+	dir := t.TempDir()
+	s := NewStore(filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem"))
+
+	certA, _, err := SelfSigned([]string{"a"}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, keyB, err := SelfSigned([]string{"b"}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Install(certA, keyB); err == nil {
+		t.Error("mismatched certificate and key installed")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "cert.pem")); !os.IsNotExist(err) {
+		t.Error("rejected certificate was written")
+	}
+	if _, err := s.GetCertificate(nil); err == nil {
+		t.Error("serving a certificate after a failed install")
+	}
+}
@@ -0,0 +1,176 @@
+// Package tracing records pipeline lifecycle spans and exports them to an
+// OpenTelemetry collector over OTLP/HTTP, using the OpenTelemetry SDK. Spans
+// are nil while tracing is off, so callers never need to check.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the instrumentation scope of the spans
+const tracerName = "srtla-manager"
+
+var (
+	mu       sync.RWMutex
+	provider *sdktrace.TracerProvider
+)
+
+// Init enables tracing and starts exporting spans to the given OTLP/HTTP
+// endpoint (e.g. http://collector:4318); /v1/traces is added unless the
+// endpoint already ends in it. Calling Init with an empty endpoint leaves
+// tracing disabled; all span operations are then no-ops.
+func Init(endpoint, serviceName, serviceVersion string) error {
+	if endpoint == "" {
+		return nil
+	}
+	if serviceName == "" {
+		serviceName = "srtla-manager"
+	}
+
+	target := strings.TrimSuffix(endpoint, "/")
+	if !strings.HasSuffix(target, "/v1/traces") {
+		target += "/v1/traces"
+	}
+	exp, err := otlptracehttp.New(context.Background(), otlptracehttp.WithEndpointURL(target))
+	if err != nil {
+		return fmt.Errorf("invalid OTLP endpoint %q: %w", endpoint, err)
+	}
+
+	attrs := []attribute.KeyValue{attribute.String("service.name", serviceName)}
+	if serviceVersion != "" {
+		attrs = append(attrs, attribute.String("service.version", serviceVersion))
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(resource.NewSchemaless(attrs...)),
+	)
+
+	mu.Lock()
+	old := provider
+	provider = tp
+	mu.Unlock()
+
+	if old != nil {
+		old.Shutdown(context.Background())
+	}
+	return nil
+}
+
+// Shutdown flushes buffered spans and stops the exporter.
+func Shutdown(ctx context.Context) error {
+	mu.Lock()
+	tp := provider
+	provider = nil
+	mu.Unlock()
+
+	if tp == nil {
+		return nil
+	}
+	return tp.Shutdown(ctx)
+}
+
+// Enabled reports whether spans are currently being exported.
+func Enabled() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return provider != nil
+}
+
+// Span is a single timed operation within a trace.
+type Span struct {
+	span trace.Span
+}
+
+// Start begins a new span as a child of any span carried in ctx. The returned
+// context carries the new span so nested operations become its children.
+// When tracing is disabled the returned span is nil; all Span methods accept
+// a nil receiver so callers never need to check.
+func Start(ctx context.Context, name string) (context.Context, *Span) {
+	mu.RLock()
+	tp := provider
+	mu.RUnlock()
+
+	if tp == nil {
+		return ctx, nil
+	}
+	ctx, span := tp.Tracer(tracerName).Start(ctx, name)
+	return ctx, &Span{span: span}
+}
+
+// FromContext returns the span carried in ctx, or nil.
+func FromContext(ctx context.Context) *Span {
+	if ctx == nil {
+		return nil
+	}
+	span := trace.SpanFromContext(ctx)
+	if !span.SpanContext().IsValid() {
+		return nil
+	}
+	return &Span{span: span}
+}
+
+// SetAttribute attaches a key/value pair to the span.
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	var kv attribute.KeyValue
+	switch v := value.(type) {
+	case string:
+		kv = attribute.String(key, v)
+	case bool:
+		kv = attribute.Bool(key, v)
+	case int:
+		kv = attribute.Int(key, v)
+	case int64:
+		kv = attribute.Int64(key, v)
+	case float64:
+		kv = attribute.Float64(key, v)
+	default:
+		kv = attribute.String(key, fmt.Sprint(v))
+	}
+	s.span.SetAttributes(kv)
+}
+
+// AddEvent records a timestamped milestone within the span.
+func (s *Span) AddEvent(name string) {
+	if s == nil {
+		return
+	}
+	s.span.AddEvent(name)
+}
+
+// RecordError marks the span as failed. A nil error is ignored.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+// End completes the span and queues it for export. Subsequent calls are no-ops.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.span.End()
+}
+
+// TraceID returns the hex-encoded trace ID, or "" for a nil span.
+func (s *Span) TraceID() string {
+	if s == nil {
+		return ""
+	}
+	return s.span.SpanContext().TraceID().String()
+}
@@ -0,0 +1,114 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// Test that spans are no-ops while tracing is disabled
+func TestStartDisabled(t *testing.T) {
+	ctx, span := Start(context.Background(), "noop")
+	if span != nil {
+		t.Fatalf("expected nil span when tracing disabled")
+	}
+	// Methods must be safe on a nil span
+	span.SetAttribute("k", "v")
+	span.RecordError(errors.New("boom"))
+	span.End()
+	if FromContext(ctx) != nil {
+		t.Errorf("expected no span in context")
+	}
+}
+
+// Test that child spans share the parent's trace and are exported as an
+// OTLP/HTTP request that decodes with the OTLP protobuf schema
+func TestExportParentChild(t *testing.T) {
+	var mu sync.Mutex
+	var received collectortrace.ExportTraceServiceRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/x-protobuf" {
+			t.Errorf("unexpected content type %s", ct)
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("read: %v", err)
+		}
+		var req collectortrace.ExportTraceServiceRequest
+		if err := proto.Unmarshal(body, &req); err != nil {
+			t.Errorf("decode: %v", err)
+		}
+		mu.Lock()
+		received.ResourceSpans = append(received.ResourceSpans, req.ResourceSpans...)
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/x-protobuf")
+	}))
+	defer srv.Close()
+
+	if err := Init(srv.URL, "test-svc", "v1.2.3"); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+
+	ctx, parent := Start(context.Background(), "stream.start")
+	parent.SetAttribute("srtla.enabled", true)
+	_, child := Start(ctx, "srtla.start")
+	child.RecordError(errors.New("no bind IPs"))
+	child.End()
+	parent.End()
+	if parent.TraceID() == "" || parent.TraceID() != child.TraceID() {
+		t.Errorf("expected a shared trace ID, got %q and %q", parent.TraceID(), child.TraceID())
+	}
+
+	if err := Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received.ResourceSpans) != 1 {
+		t.Fatalf("expected 1 resource span, got %d", len(received.ResourceSpans))
+	}
+	rs := received.ResourceSpans[0]
+	resource := map[string]string{}
+	for _, kv := range rs.Resource.Attributes {
+		resource[kv.Key] = kv.Value.GetStringValue()
+	}
+	if resource["service.name"] != "test-svc" || resource["service.version"] != "v1.2.3" {
+		t.Errorf("unexpected resource %v", resource)
+	}
+	spans := rs.ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+
+	byName := map[string]*tracepb.Span{}
+	for _, s := range spans {
+		byName[s.Name] = s
+	}
+	p, c := byName["stream.start"], byName["srtla.start"]
+	if !bytes.Equal(p.TraceId, c.TraceId) || hex.EncodeToString(p.TraceId) != parent.TraceID() {
+		t.Errorf("child trace %x != parent trace %x", c.TraceId, p.TraceId)
+	}
+	if !bytes.Equal(c.ParentSpanId, p.SpanId) {
+		t.Errorf("child parent %x != parent span %x", c.ParentSpanId, p.SpanId)
+	}
+	if c.Status.GetCode() != tracepb.Status_STATUS_CODE_ERROR || c.Status.GetMessage() != "no bind IPs" {
+		t.Errorf("expected error status on child, got %v", c.Status)
+	}
+	if len(p.Attributes) != 1 || p.Attributes[0].Key != "srtla.enabled" || !p.Attributes[0].Value.GetBoolValue() {
+		t.Errorf("unexpected parent attributes %v", p.Attributes)
+	}
+}
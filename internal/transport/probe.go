@@ -0,0 +1,140 @@
+package transport
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"net"
+	"strconv"
+	"time"
+)
+
+// SRTLA registration packet types
+const (
+	srtlaTypeReg1   = 0x9200
+	srtlaTypeReg2   = 0x9201
+	srtlaTypeRegErr = 0x9210
+	srtlaTypeRegNGP = 0x9211
+
+	srtlaIDLen = 256
+)
+
+// SRT handshake constants (HSv5 induction)
+const (
+	srtHandshakeVersion4 = 4
+	srtHandshakeVersion5 = 5
+	srtInductionType     = 1
+	srtMagicCode         = 0x4A17
+	srtHandshakeLen      = 64
+)
+
+// ProbeTimeout bounds each probe's wait for a reply
+const ProbeTimeout = 2 * time.Second
+
+// Probe asks the receiver which bonding transports it accepts. It sends an
+// SRTLA REG1 and, if that goes unanswered, an SRT induction handshake.
+//
+// A REG1 makes SRTLA receivers allocate a group that is dropped again after
+// their idle timeout. srtla2 receivers append a protocol version after the
+// group ID in REG2; classic receivers reply with exactly type + ID.
+func Probe(ctx context.Context, host string, port int) Receiver {
+	recv := Receiver{Host: host, Port: port, ProbedAt: time.Now()}
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
+
+	reply, err := exchange(ctx, addr, srtlaReg1())
+	if err == nil && len(reply) >= 2 {
+		switch binary.BigEndian.Uint16(reply) {
+		case srtlaTypeReg2:
+			recv.Reachable = true
+			recv.SRTLA = true
+			recv.SRTLA2 = len(reply) > 2+srtlaIDLen && reply[2+srtlaIDLen] >= 2
+			return recv
+		case srtlaTypeRegErr, srtlaTypeRegNGP:
+			recv.Reachable = true
+			recv.SRTLA = true
+			return recv
+		}
+	}
+
+	reply, err = exchange(ctx, addr, srtInduction())
+	if err != nil {
+		recv.Error = err.Error()
+		return recv
+	}
+	if isSRTv5Induction(reply) {
+		// Socket groups need an HSv5 listener. Whether it was built with
+		// bonding only shows up in the conclusion handshake at connect time.
+		recv.Reachable = true
+		recv.SRTBonding = true
+		return recv
+	}
+
+	recv.Error = "receiver answered with an unrecognised packet"
+	return recv
+}
+
+// exchange sends one datagram and waits for a single reply
+func exchange(ctx context.Context, addr string, pkt []byte) ([]byte, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(ProbeTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+
+	if _, err := conn.Write(pkt); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 1500)
+	n, err := conn.Read(buf)
+	if err != nil {
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return nil, errors.New("no reply from receiver")
+		}
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+// srtlaReg1 builds a REG1 with a random group ID
+func srtlaReg1() []byte {
+	pkt := make([]byte, 2+srtlaIDLen)
+	binary.BigEndian.PutUint16(pkt, srtlaTypeReg1)
+	rand.Read(pkt[2:])
+	return pkt
+}
+
+// srtInduction builds a caller's induction handshake
+func srtInduction() []byte {
+	pkt := make([]byte, srtHandshakeLen)
+	binary.BigEndian.PutUint32(pkt[0:], 0x80000000) // control packet, type handshake
+	binary.BigEndian.PutUint32(pkt[16:], srtHandshakeVersion4)
+	binary.BigEndian.PutUint16(pkt[22:], 2) // extension field for HSv5 callers
+	var isn [4]byte
+	rand.Read(isn[:])
+	binary.BigEndian.PutUint32(pkt[24:], binary.BigEndian.Uint32(isn[:])&0x7FFFFFFF)
+	binary.BigEndian.PutUint32(pkt[28:], 1500) // MTU
+	binary.BigEndian.PutUint32(pkt[32:], 8192) // flow window
+	binary.BigEndian.PutUint32(pkt[36:], srtInductionType)
+	binary.BigEndian.PutUint32(pkt[40:], binary.BigEndian.Uint32(isn[:])|1) // socket ID
+	return pkt
+}
+
+// isSRTv5Induction reports whether pkt is an induction response from an HSv5 listener
+func isSRTv5Induction(pkt []byte) bool {
+	if len(pkt) < srtHandshakeLen || binary.BigEndian.Uint32(pkt[0:]) != 0x80000000 {
+		return false
+	}
+	return binary.BigEndian.Uint32(pkt[16:]) == srtHandshakeVersion5 &&
+		binary.BigEndian.Uint16(pkt[22:]) == srtMagicCode &&
+		binary.BigEndian.Uint32(pkt[36:]) == srtInductionType
+}
@@ -0,0 +1,94 @@
+// Package transport chooses how the local SRT stream is bonded across uplinks
+// and probes the receiver for the bonding protocols it accepts.
+package transport
+
+import (
+	"fmt"
+	"time"
+)
+
+// Kind identifies a bonding transport
+type Kind string
+
+const (
+	Auto       Kind = "auto"        // best transport both ends support
+	SRTLA      Kind = "srtla"       // classic SRTLA via srtla_send
+	SRTLA2     Kind = "srtla2"      // SRTLA protocol v2 via srtla_send --srtla2
+	SRTBonding Kind = "srt_bonding" // native SRT socket groups via srt-live-transmit
+)
+
+// Names lists the values accepted for srtla.transport
+var Names = []string{string(Auto), string(SRTLA), string(SRTLA2), string(SRTBonding)}
+
+// autoOrder is the preference order when the transport is Auto
+var autoOrder = []Kind{SRTLA2, SRTLA, SRTBonding}
+
+// Local describes the transports this unit can send with. Classic SRTLA is
+// always assumed available; a missing srtla_send is reported elsewhere.
+type Local struct {
+	SRTLA2     bool `json:"srtla2"`
+	SRTBonding bool `json:"srt_bonding"`
+}
+
+// Supports reports whether this unit can send with k
+func (l Local) Supports(k Kind) bool {
+	switch k {
+	case SRTLA:
+		return true
+	case SRTLA2:
+		return l.SRTLA2
+	case SRTBonding:
+		return l.SRTBonding
+	}
+	return false
+}
+
+// Receiver is the result of probing the remote end
+type Receiver struct {
+	Host       string    `json:"host"`
+	Port       int       `json:"port"`
+	Reachable  bool      `json:"reachable"` // the receiver answered at least one probe
+	SRTLA      bool      `json:"srtla"`
+	SRTLA2     bool      `json:"srtla2"`
+	SRTBonding bool      `json:"srt_bonding"`
+	ProbedAt   time.Time `json:"probed_at"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Supports reports whether the receiver accepts k
+func (r Receiver) Supports(k Kind) bool {
+	switch k {
+	case SRTLA:
+		return r.SRTLA
+	case SRTLA2:
+		return r.SRTLA2
+	case SRTBonding:
+		return r.SRTBonding
+	}
+	return false
+}
+
+// Select resolves the configured transport against what both ends support.
+// An unreachable receiver tells us nothing, so Auto falls back to classic
+// SRTLA and explicit choices are trusted as long as this unit supports them.
+func Select(configured Kind, local Local, recv Receiver) (Kind, error) {
+	if configured == "" || configured == Auto {
+		if !recv.Reachable {
+			return SRTLA, nil
+		}
+		for _, k := range autoOrder {
+			if local.Supports(k) && recv.Supports(k) {
+				return k, nil
+			}
+		}
+		return SRTLA, nil
+	}
+
+	if !local.Supports(configured) {
+		return "", fmt.Errorf("transport %s is not supported by this unit", configured)
+	}
+	if recv.Reachable && !recv.Supports(configured) {
+		return "", fmt.Errorf("receiver %s:%d does not accept %s", recv.Host, recv.Port, configured)
+	}
+	return configured, nil
+}
@@ -0,0 +1,96 @@
+package transport
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"testing"
+)
+
+// Auto prefers srtla2, then classic SRTLA, when both ends support them
+func TestSelectAuto(t *testing.T) {
+	tests := []struct {
+		name  string
+		local Local
+		recv  Receiver
+		want  Kind
+	}{
+		{"unreachable receiver", Local{SRTLA2: true}, Receiver{}, SRTLA},
+		{"both support srtla2", Local{SRTLA2: true}, Receiver{Reachable: true, SRTLA: true, SRTLA2: true}, SRTLA2},
+		{"receiver classic only", Local{SRTLA2: true}, Receiver{Reachable: true, SRTLA: true}, SRTLA},
+		{"local classic only", Local{}, Receiver{Reachable: true, SRTLA: true, SRTLA2: true}, SRTLA},
+		{"srt listener", Local{SRTBonding: true}, Receiver{Reachable: true, SRTBonding: true}, SRTBonding},
+	}
+
+	for _, tt := range tests {
+		got, err := Select(Auto, tt.local, tt.recv)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.want, got)
+		}
+	}
+}
+
+// Explicit choices fail when either end is known not to support them
+func TestSelectExplicit(t *testing.T) {
+	if _, err := Select(SRTBonding, Local{}, Receiver{}); err == nil {
+		t.Error("Expected error when srt-live-transmit is unavailable")
+	}
+	if _, err := Select(SRTLA2, Local{SRTLA2: true}, Receiver{Reachable: true, SRTLA: true}); err == nil {
+		t.Error("Expected error when the receiver only speaks classic SRTLA")
+	}
+	if got, err := Select(SRTLA2, Local{SRTLA2: true}, Receiver{}); err != nil || got != SRTLA2 {
+		t.Errorf("Expected srtla2 to be trusted for an unreachable receiver, got %s, %v", got, err)
+	}
+}
+
+// A receiver answering REG1 with REG2 is detected as SRTLA; a version byte
+// after the group ID marks srtla2
+func TestProbeSRTLA(t *testing.T) {
+	for _, version := range []byte{0, 2} {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		go func() {
+			buf := make([]byte, 1500)
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil || n != 2+srtlaIDLen {
+				return
+			}
+			reply := append([]byte{}, buf[:n]...)
+			binary.BigEndian.PutUint16(reply, srtlaTypeReg2)
+			if version > 0 {
+				reply = append(reply, version)
+			}
+			conn.WriteTo(reply, addr)
+		}()
+
+		port := conn.LocalAddr().(*net.UDPAddr).Port
+		recv := Probe(context.Background(), "127.0.0.1", port)
+		conn.Close()
+
+		if !recv.Reachable || !recv.SRTLA {
+			t.Fatalf("Expected SRTLA receiver, got %+v", recv)
+		}
+		if recv.SRTLA2 != (version >= 2) {
+			t.Errorf("version %d: expected srtla2=%v, got %v", version, version >= 2, recv.SRTLA2)
+		}
+	}
+}
+
+func TestIsSRTv5Induction(t *testing.T) {
+	pkt := srtInduction()
+	if isSRTv5Induction(pkt) {
+		t.Error("A v4 caller induction must not be taken for a listener response")
+	}
+
+	binary.BigEndian.PutUint32(pkt[16:], srtHandshakeVersion5)
+	binary.BigEndian.PutUint16(pkt[22:], srtMagicCode)
+	if !isSRTv5Induction(pkt) {
+		t.Error("Expected HSv5 induction response to be recognised")
+	}
+}
@@ -1,9 +1,6 @@
 package updates
 
-import (
-	"os/exec"
-	"strings"
-)
+import "srtla-manager/internal/system"
 
 const (
 	SRTLASendOwner = "irlserver"
@@ -45,49 +42,11 @@ func (s *SRTLASendChecker) GetCurrentVersion() string {
 	return s.checker.currentVersion
 }
 
-// getCurrentSRTLASendVersion attempts to get the installed srtla_send version
+// getCurrentSRTLASendVersion returns the installed srtla_send version as a tag
 func getCurrentSRTLASendVersion() string {
-	// Try to get version from srtla_send binary
-	cmd := exec.Command("srtla_send", "--version")
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		// If command fails, try alternative locations
-		for _, path := range []string{"/usr/local/bin/srtla_send", "/usr/bin/srtla_send", "./srtla_send"} {
-			cmd = exec.Command(path, "--version")
-			if output, err = cmd.CombinedOutput(); err == nil {
-				break
-			}
-		}
-
-		// If still no version found, return unknown
-		if err != nil {
-			return "v0.0.0-unknown"
-		}
-	}
-
-	// Parse version from output
-	// Expected format: "srtla_send version v1.2.3" or similar
-	outputStr := strings.TrimSpace(string(output))
-	parts := strings.Fields(outputStr)
-
-	// Look for version string
-	for _, part := range parts {
-		if strings.HasPrefix(part, "v") || strings.HasPrefix(part, "V") {
-			return part
-		}
+	caps := system.DetectSRTLA("srtla_send")
+	if caps.Version == "" {
+		return "v0.0.0-unknown"
 	}
-
-	// If version not found in expected format, try to extract any version-like string
-	for i, part := range parts {
-		if (part == "version" || part == "Version") && i+1 < len(parts) {
-			return parts[i+1]
-		}
-	}
-
-	// Fallback: return the entire output if it looks like a version
-	if len(parts) > 0 && (strings.Contains(outputStr, ".") || strings.HasPrefix(outputStr, "v")) {
-		return strings.TrimSpace(outputStr)
-	}
-
-	return "v0.0.0-unknown"
+	return "v" + caps.Version
 }
@@ -0,0 +1,329 @@
+// Package upload receives files from the browser in chunks, so a large
+// .deb can be sideloaded over a flaky link and resumed where it stopped.
+// Uploads land in a quarantine directory that only the manager can read,
+// and are only handed on once their size and SHA-256 match what was
+// declared when the upload was created.
+package upload
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMaxSize limits an upload when the store is opened without a limit
+const DefaultMaxSize = 512 << 20
+
+// Errors of the store
+var (
+	ErrNotFound   = errors.New("no such upload")
+	ErrIncomplete = errors.New("upload is not complete")
+	ErrTooLarge   = errors.New("chunk runs past the declared size")
+	ErrBusy       = errors.New("another chunk of the upload is being written")
+)
+
+// OffsetError is returned for a chunk that doesn't start where the upload
+// stopped. Offset is where the next chunk must start.
+type OffsetError struct {
+	Offset int64
+}
+
+func (e *OffsetError) Error() string {
+	return fmt.Sprintf("upload continues at offset %d", e.Offset)
+}
+
+// ChecksumError is returned when the file received isn't the one declared
+type ChecksumError struct {
+	Want, Got string
+}
+
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("checksum mismatch: declared %s, received %s", e.Want, e.Got)
+}
+
+// Upload is a file being received
+type Upload struct {
+	ID       string    `json:"id"`
+	Filename string    `json:"filename"`
+	Package  string    `json:"package,omitempty"` // name the .deb must declare
+	SHA256   string    `json:"sha256"`
+	Size     int64     `json:"size"`
+	Offset   int64     `json:"offset"` // bytes received
+	Created  time.Time `json:"created"`
+	Updated  time.Time `json:"updated"`
+
+	writing bool // a chunk is being written
+}
+
+// Complete reports whether every byte has been received
+func (u Upload) Complete() bool {
+	return u.Offset == u.Size
+}
+
+// Store keeps uploads in a directory, each as its data and a JSON sidecar,
+// so an upload can be resumed after a restart
+type Store struct {
+	dir     string
+	maxSize int64
+
+	mu      sync.Mutex
+	uploads map[string]*Upload
+}
+
+// Open opens the store in dir, creating it, and picks up the uploads left
+// there. maxSize limits each upload; 0 uses DefaultMaxSize.
+func Open(dir string, maxSize int64) (*Store, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSize
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	s := &Store{dir: dir, maxSize: maxSize, uploads: make(map[string]*Upload)}
+
+	sidecars, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	for _, path := range sidecars {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var u Upload
+		if json.Unmarshal(data, &u) != nil || u.ID == "" {
+			continue
+		}
+		info, err := os.Stat(s.dataPath(u.ID))
+		if err != nil {
+			os.Remove(path)
+			continue
+		}
+		u.Offset, u.Updated = info.Size(), info.ModTime()
+		s.uploads[u.ID] = &u
+	}
+	return s, nil
+}
+
+// MaxSize is the largest upload accepted
+func (s *Store) MaxSize() int64 {
+	return s.maxSize
+}
+
+func (s *Store) dataPath(id string) string {
+	return filepath.Join(s.dir, id+".part")
+}
+
+// debPath is where a verified upload is staged for the privileged
+// installer, which only takes paths ending in .deb
+func (s *Store) debPath(id string) string {
+	return filepath.Join(s.dir, id+".deb")
+}
+
+func (s *Store) sidecarPath(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
+
+// Create starts an upload of size bytes whose SHA-256 is sha256Hex. pkg,
+// when set, is the package name the .deb must declare.
+func (s *Store) Create(filename string, size int64, sha256Hex, pkg string) (Upload, error) {
+	if size <= 0 || size > s.maxSize {
+		return Upload{}, fmt.Errorf("size must be between 1 and %d bytes", s.maxSize)
+	}
+	b := make([]byte, 16)
+	rand.Read(b)
+	now := time.Now()
+	u := Upload{
+		ID:       hex.EncodeToString(b),
+		Filename: filepath.Base(filename),
+		Package:  pkg,
+		SHA256:   strings.ToLower(sha256Hex),
+		Size:     size,
+		Created:  now,
+		Updated:  now,
+	}
+
+	data, err := json.Marshal(u)
+	if err != nil {
+		return Upload{}, err
+	}
+	if err := os.WriteFile(s.sidecarPath(u.ID), data, 0600); err != nil {
+		return Upload{}, err
+	}
+	if err := os.WriteFile(s.dataPath(u.ID), nil, 0600); err != nil {
+		os.Remove(s.sidecarPath(u.ID))
+		return Upload{}, err
+	}
+
+	s.mu.Lock()
+	s.uploads[u.ID] = &u
+	s.mu.Unlock()
+	return u, nil
+}
+
+// Get returns an upload
+func (s *Store) Get(id string) (Upload, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.uploads[id]
+	if !ok {
+		return Upload{}, false
+	}
+	return *u, true
+}
+
+// Write appends the chunk read from r, which must start at offset. A chunk
+// cut short is kept up to where it stopped, so the next one resumes there.
+// The store isn't locked while the chunk is read, so a slow client holds up
+// only its own upload.
+func (s *Store) Write(id string, offset int64, r io.Reader) (Upload, error) {
+	s.mu.Lock()
+	u, ok := s.uploads[id]
+	if !ok {
+		s.mu.Unlock()
+		return Upload{}, ErrNotFound
+	}
+	if offset != u.Offset {
+		s.mu.Unlock()
+		return *u, &OffsetError{Offset: u.Offset}
+	}
+	if u.writing {
+		s.mu.Unlock()
+		return *u, ErrBusy
+	}
+	u.writing = true
+	size := u.Size
+	s.mu.Unlock()
+
+	n, err := s.appendChunk(id, size-offset, r)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u.writing = false
+	u.Offset += n
+	u.Updated = time.Now()
+	return *u, err
+}
+
+// appendChunk appends up to remaining bytes of r to an upload's data,
+// returning how many were kept
+func (s *Store) appendChunk(id string, remaining int64, r io.Reader) (int64, error) {
+	f, err := os.OpenFile(s.dataPath(id), os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return 0, err
+	}
+	// one byte over tells a chunk that runs past the end
+	n, err := io.Copy(f, io.LimitReader(r, remaining+1))
+	if n > remaining {
+		info, serr := f.Stat()
+		if serr == nil {
+			f.Truncate(info.Size() - (n - remaining))
+		}
+		n, err = remaining, ErrTooLarge
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return n, err
+}
+
+// Verify checks that a complete upload is the file declared and returns
+// its path
+func (s *Store) Verify(id string) (string, error) {
+	u, ok := s.Get(id)
+	if !ok {
+		return "", ErrNotFound
+	}
+	if !u.Complete() {
+		return "", ErrIncomplete
+	}
+	f, err := os.Open(s.dataPath(id))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != u.SHA256 {
+		return "", &ChecksumError{Want: u.SHA256, Got: got}
+	}
+	return s.dataPath(id), nil
+}
+
+// Stage links a verified upload's data under the name the privileged
+// installer takes a package by, returning that path
+func (s *Store) Stage(id string) (string, error) {
+	if _, ok := s.Get(id); !ok {
+		return "", ErrNotFound
+	}
+	path := s.debPath(id)
+	os.Remove(path)
+	if err := os.Link(s.dataPath(id), path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// Remove deletes an upload and its data
+func (s *Store) Remove(id string) error {
+	s.mu.Lock()
+	_, ok := s.uploads[id]
+	delete(s.uploads, id)
+	s.mu.Unlock()
+	if !ok {
+		return ErrNotFound
+	}
+	os.Remove(s.sidecarPath(id))
+	os.Remove(s.debPath(id))
+	return os.Remove(s.dataPath(id))
+}
+
+// Expire removes the uploads nothing was written to for maxAge, returning
+// how many
+func (s *Store) Expire(maxAge time.Duration) int {
+	s.mu.Lock()
+	var stale []string
+	for id, u := range s.uploads {
+		if !u.writing && time.Since(u.Updated) > maxAge {
+			stale = append(stale, id)
+		}
+	}
+	s.mu.Unlock()
+	for _, id := range stale {
+		s.Remove(id)
+	}
+	return len(stale)
+}
+
+// debMagic starts every .deb: an ar archive whose first member is
+// debian-binary
+const debMagic = "!<arch>\ndebian-binary"
+
+// DebPackage returns the package name a .deb declares, checking first that
+// the file is one
+func DebPackage(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	head := make([]byte, len(debMagic))
+	_, err = io.ReadFull(f, head)
+	f.Close()
+	if err != nil || string(head) != debMagic {
+		return "", errors.New("not a Debian package")
+	}
+	output, err := exec.Command("dpkg-deb", "--field", path, "Package").Output()
+	if err != nil {
+		return "", fmt.Errorf("dpkg-deb: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
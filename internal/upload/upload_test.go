@@ -0,0 +1,160 @@
+package upload
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func checksum(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestChunkedUpload(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := "hello, sideloaded package"
+	u, err := s.Create("../../pkg.deb", int64(len(content)), checksum(content), "srtla-send")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Filename != "pkg.deb" {
+		t.Errorf("Expected the directories dropped from the name, got %q", u.Filename)
+	}
+
+	if _, err := s.Write(u.ID, 0, strings.NewReader(content[:10])); err != nil {
+		t.Fatal(err)
+	}
+	// a chunk sent again after a lost answer is refused with where to go on
+	var offErr *OffsetError
+	if _, err := s.Write(u.ID, 0, strings.NewReader(content[:10])); !errors.As(err, &offErr) || offErr.Offset != 10 {
+		t.Fatalf("Expected an offset error at 10, got %v", err)
+	}
+	if _, err := s.Verify(u.ID); !errors.Is(err, ErrIncomplete) {
+		t.Errorf("Expected an incomplete upload, got %v", err)
+	}
+
+	// the upload survives reopening the store
+	s, err = Open(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := s.Get(u.ID); !ok || got.Offset != 10 {
+		t.Fatalf("Expected the upload resumed at 10, got %+v", got)
+	}
+	u, err = s.Write(u.ID, 10, strings.NewReader(content[10:]))
+	if err != nil || !u.Complete() {
+		t.Fatalf("Write: %+v, %v", u, err)
+	}
+	path, err := s.Verify(u.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != content {
+		t.Errorf("Received %q", data)
+	}
+
+	if err := s.Remove(u.ID); err != nil {
+		t.Fatal(err)
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "*")); len(files) != 0 {
+		t.Errorf("Expected nothing left, got %v", files)
+	}
+}
+
+func TestUploadRejects(t *testing.T) {
+	s, err := Open(t.TempDir(), 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Create("big.deb", 17, checksum(""), ""); err == nil {
+		t.Error("Expected an upload over the limit refused")
+	}
+
+	u, _ := s.Create("a.deb", 4, checksum("abcd"), "")
+	if u, err = s.Write(u.ID, 0, strings.NewReader("abcdef")); !errors.Is(err, ErrTooLarge) || u.Offset != 4 {
+		t.Errorf("Expected the chunk cut at the declared size, got %+v, %v", u, err)
+	}
+
+	u, _ = s.Create("b.deb", 4, checksum("abcd"), "")
+	s.Write(u.ID, 0, strings.NewReader("abce"))
+	var sumErr *ChecksumError
+	if _, err := s.Verify(u.ID); !errors.As(err, &sumErr) {
+		t.Errorf("Expected a checksum error, got %v", err)
+	}
+
+	if _, err := s.Write("missing", 0, strings.NewReader("x")); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected not found, got %v", err)
+	}
+}
+
+func TestExpire(t *testing.T) {
+	s, _ := Open(t.TempDir(), 0)
+	old, _ := s.Create("old.deb", 1, checksum("x"), "")
+	s.Create("new.deb", 1, checksum("x"), "")
+	s.mu.Lock()
+	s.uploads[old.ID].Updated = time.Now().Add(-48 * time.Hour)
+	s.mu.Unlock()
+
+	if n := s.Expire(24 * time.Hour); n != 1 {
+		t.Errorf("Expected 1 expired, got %d", n)
+	}
+	if _, ok := s.Get(old.ID); ok {
+		t.Error("Expected the idle upload removed")
+	}
+}
+
+func TestDebPackageRejectsOtherFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fake.deb")
+	os.WriteFile(path, []byte("#!/bin/sh\nrm -rf /\n"), 0600)
+	if _, err := DebPackage(path); err == nil {
+		t.Error("Expected a file that isn't a .deb refused")
+	}
+}
+
+func TestWriteDoesNotHoldStore(t *testing.T) {
+	s, err := Open(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	slow, _ := s.Create("slow.deb", 4, checksum("abcd"), "")
+	other, _ := s.Create("other.deb", 4, checksum("efgh"), "")
+
+	// a client that sends part of its chunk and stalls
+	pr, pw := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		_, err := s.Write(slow.ID, 0, pr)
+		done <- err
+	}()
+	pw.Write([]byte("ab"))
+
+	if _, err := s.Write(slow.ID, 0, strings.NewReader("abcd")); !errors.Is(err, ErrBusy) {
+		t.Errorf("Expected a second chunk of the same upload refused, got %v", err)
+	}
+	if _, ok := s.Get(slow.ID); !ok {
+		t.Error("Expected the stalled upload's status")
+	}
+	if u, err := s.Write(other.ID, 0, strings.NewReader("efgh")); err != nil || !u.Complete() {
+		t.Errorf("Expected another upload to go on, got %+v, %v", u, err)
+	}
+
+	pw.Write([]byte("cd"))
+	pw.Close()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Verify(slow.ID); err != nil {
+		t.Errorf("Verify: %v", err)
+	}
+}
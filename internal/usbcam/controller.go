@@ -5,8 +5,13 @@ import (
 	"log"
 	"sync"
 	"time"
+
+	"srtla-manager/internal/events"
 )
 
+// TopicCameraState carries a camera's state after each change
+var TopicCameraState = events.NewTopic[CameraState]("usbcam")
+
 // StreamState represents the streaming state
 type StreamState string
 
@@ -42,7 +47,7 @@ type Controller struct {
 	scanner      *Scanner
 	cameraStates map[string]*CameraState
 	activeCamera string // ID of currently streaming camera (only one at a time)
-	updateChan   chan *CameraState
+	bus          *events.Bus
 
 	// Callback for starting FFmpeg capture
 	startCapture func(config CaptureConfig) error
@@ -67,10 +72,16 @@ func NewController(scanner *Scanner) *Controller {
 	return &Controller{
 		scanner:      scanner,
 		cameraStates: make(map[string]*CameraState),
-		updateChan:   make(chan *CameraState, 10),
 	}
 }
 
+// SetBus publishes camera state changes on bus
+func (c *Controller) SetBus(bus *events.Bus) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.bus = bus
+}
+
 // SetCaptureHandlers sets the callbacks for starting/stopping FFmpeg capture
 func (c *Controller) SetCaptureHandlers(start func(CaptureConfig) error, stop func() error) {
 	c.mu.Lock()
@@ -315,17 +326,12 @@ func (c *Controller) StopActiveCamera() error {
 	return c.StopStreaming(active)
 }
 
-// SubscribeUpdates returns a channel for camera state updates
-func (c *Controller) SubscribeUpdates() <-chan *CameraState {
-	return c.updateChan
-}
-
 func (c *Controller) notifyUpdate(state *CameraState) {
-	select {
-	case c.updateChan <- state:
-	default:
-		// Channel full, skip notification
-	}
+	c.mu.RLock()
+	bus := c.bus
+	snapshot := *state
+	c.mu.RUnlock()
+	events.Publish(bus, TopicCameraState, snapshot)
 }
 
 // DefaultStreamConfig returns default streaming configuration
@@ -0,0 +1,18 @@
+//go:build !linux
+
+package usbcam
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// queryCapability is unavailable outside Linux; V4L2 is a Linux-only API.
+func queryCapability(devPath string) (*v4l2Capability, error) {
+	return nil, fmt.Errorf("V4L2 not supported on %s", runtime.GOOS)
+}
+
+// enumFormats is unavailable outside Linux; V4L2 is a Linux-only API.
+func enumFormats(devPath string) ([]VideoFormat, error) {
+	return nil, fmt.Errorf("V4L2 not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,171 @@
+package usbnet
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// MaxNameLength is the longest name a device can be given
+const MaxNameLength = 64
+
+// ErrUnknownDevice is returned for an ID that is neither connected nor
+// named
+var ErrUnknownDevice = errors.New("unknown device")
+
+// Mapping is what is remembered about a named device
+type Mapping struct {
+	Name          string    `json:"name"`
+	LastInterface string    `json:"last_interface,omitempty"`
+	LastSeen      time.Time `json:"last_seen,omitempty"`
+}
+
+// mappingFile is the layout of device_mappings.json
+type mappingFile struct {
+	Devices map[string]Mapping `json:"devices"`
+}
+
+// mappingStore keeps device names by ID in a JSON file. A path of ""
+// keeps them in memory only.
+type mappingStore struct {
+	path string
+
+	mu      sync.Mutex
+	devices map[string]Mapping
+}
+
+// loadMappings reads the mappings at path; a missing file has none
+func loadMappings(path string) (*mappingStore, error) {
+	s := &mappingStore{path: path, devices: make(map[string]Mapping)}
+	if path == "" {
+		return s, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+	var f mappingFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return s, fmt.Errorf("parse %s: %w", path, err)
+	}
+	for id, m := range f.Devices {
+		s.devices[id] = m
+	}
+	return s, nil
+}
+
+// saveLocked writes the mappings out. s.mu must be held.
+func (s *mappingStore) saveLocked() error {
+	if s.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(mappingFile{Devices: s.devices}, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Clean(s.path))
+}
+
+func (s *mappingStore) get(id string) (Mapping, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, ok := s.devices[id]
+	return m, ok
+}
+
+func (s *mappingStore) all() map[string]Mapping {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make(map[string]Mapping, len(s.devices))
+	for id, m := range s.devices {
+		out[id] = m
+	}
+	return out
+}
+
+// rename names a device, remembering the interface it is on if it is
+// connected
+func (s *mappingStore) rename(id, name string, dev *DeviceStatus) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m := s.devices[id]
+	m.Name = name
+	if dev != nil {
+		m.LastInterface, m.LastSeen = dev.Interface, dev.LastSeen
+	}
+	s.devices[id] = m
+	return s.saveLocked()
+}
+
+// forget drops a device's mapping
+func (s *mappingStore) forget(id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.devices[id]; !ok {
+		return false, nil
+	}
+	delete(s.devices, id)
+	return true, s.saveLocked()
+}
+
+// seen records the interfaces named devices are on, saving only when one
+// has moved so a scan every few seconds doesn't rewrite the file
+func (s *mappingStore) seen(devices []DeviceStatus) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	moved := false
+	for _, dev := range devices {
+		m, ok := s.devices[dev.ID]
+		if !ok {
+			continue
+		}
+		if m.LastInterface != dev.Interface {
+			moved = true
+		}
+		m.LastInterface, m.LastSeen = dev.Interface, dev.LastSeen
+		s.devices[dev.ID] = m
+	}
+	if !moved {
+		return nil
+	}
+	return s.saveLocked()
+}
+
+// deviceID returns the ID a device keeps across reconnects: its USB serial,
+// or its MAC address without colons when the serial is unknown
+func deviceID(dev DeviceStatus) string {
+	if dev.Serial != "" && !strings.HasPrefix(dev.Serial, "unknown-") {
+		return dev.Serial
+	}
+	if dev.MAC != "" {
+		return strings.ReplaceAll(dev.MAC, ":", "")
+	}
+	return dev.Interface
+}
+
+// ValidateName checks a device name: printable and at most MaxNameLength
+// characters, "" clearing it
+func ValidateName(name string) error {
+	if len([]rune(name)) > MaxNameLength {
+		return fmt.Errorf("name must be at most %d characters", MaxNameLength)
+	}
+	for _, r := range name {
+		if !unicode.IsPrint(r) {
+			return errors.New("name must not contain control characters")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,6 @@
+//go:build linux
+
+package usbnet
+
+// platformSupported reports whether the reconciler can manage interfaces on this OS.
+const platformSupported = true
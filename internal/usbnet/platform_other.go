@@ -0,0 +1,7 @@
+//go:build !linux
+
+package usbnet
+
+// platformSupported is false outside Linux: RNDIS discovery relies on sysfs,
+// iproute2 and NetworkManager, none of which exist on macOS or Windows.
+const platformSupported = false
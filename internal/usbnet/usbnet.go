@@ -2,13 +2,18 @@ package usbnet
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
+
+	"srtla-manager/internal"
 )
 
 // Options configures the USB network reconciler.
@@ -17,6 +22,7 @@ type Options struct {
 	NMMethod       string
 	EnableADBBlock bool
 	Logger         *log.Logger
+	ScanInterval   time.Duration // 0 scans only on start and when asked
 }
 
 // Option mutates Options.
@@ -29,6 +35,7 @@ func DefaultOptions() Options {
 		NMMethod:       "dbus",
 		EnableADBBlock: false,
 		Logger:         nil,
+		ScanInterval:   3 * time.Second,
 	}
 }
 
@@ -60,6 +67,14 @@ func WithLogger(l *log.Logger) Option {
 	}
 }
 
+// WithScanInterval sets how often devices are scanned for; 0 leaves it to
+// Scan.
+func WithScanInterval(d time.Duration) Option {
+	return func(o *Options) {
+		o.ScanInterval = d
+	}
+}
+
 // Start launches the USB network reconciler and returns a stop function.
 // This is a placeholder; full reconciliation logic will be added incrementally.
 func Start(parent context.Context, opts ...Option) (*Service, error) {
@@ -73,6 +88,18 @@ func Start(parent context.Context, opts ...Option) (*Service, error) {
 		logger = log.New(io.Discard, "", log.LstdFlags)
 	}
 
+	// Device names are kept even where the reconciler can't run
+	names, err := loadMappings(cfg.PersistPath)
+	if err != nil {
+		logger.Printf("warning: failed to load device mappings: %v", err)
+	}
+
+	// On non-Linux development hosts return an inert service so the API still works
+	if !platformSupported {
+		logger.Printf("usbnet reconciler not supported on this platform; USB network devices disabled")
+		return &Service{names: names}, nil
+	}
+
 	// Ensure persist directory exists
 	if cfg.PersistPath != "" {
 		dir := filepath.Dir(cfg.PersistPath)
@@ -84,14 +111,17 @@ func Start(parent context.Context, opts ...Option) (*Service, error) {
 
 	ctx, cancel := context.WithCancel(parent)
 	m := &manager{
-		ctx:    ctx,
-		cancel: cancel,
-		opts:   cfg,
-		log:    logger,
-		done:   make(chan struct{}),
+		ctx:     ctx,
+		cancel:  cancel,
+		opts:    cfg,
+		log:     logger,
+		done:    make(chan struct{}),
+		names:   names,
+		aliases: make(map[string]string),
+		scanReq: make(chan chan struct{}),
 	}
 
-	svc := &Service{m: m}
+	svc := &Service{m: m, names: names}
 	go m.run()
 
 	return svc, nil
@@ -99,7 +129,8 @@ func Start(parent context.Context, opts ...Option) (*Service, error) {
 
 // Service exposes reconciler status and lifecycle control.
 type Service struct {
-	m *manager
+	m     *manager
+	names *mappingStore
 }
 
 // Stop blocks until the reconciler exits.
@@ -112,16 +143,111 @@ func (s *Service) Stop() error {
 	return nil
 }
 
+// Scan scans for devices now and tries to bring up those pending, returning
+// once done.
+func (s *Service) Scan(ctx context.Context) error {
+	if s == nil || s.m == nil {
+		return fmt.Errorf("usbnet reconciler not running")
+	}
+	done := make(chan struct{})
+	select {
+	case s.m.scanReq <- done:
+	case <-s.m.done:
+		return fmt.Errorf("usbnet reconciler stopped")
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // Status returns the latest known device states.
 func (s *Service) Status() []DeviceStatus {
 	if s == nil || s.m == nil {
 		return nil
 	}
-	return s.m.snapshot()
+	devices := s.m.snapshot()
+	for i := range devices {
+		if m, ok := s.names.get(devices[i].ID); ok {
+			devices[i].Name = m.Name
+		}
+	}
+	return devices
+}
+
+// Mappings returns the named devices by ID, connected or not.
+func (s *Service) Mappings() map[string]Mapping {
+	if s == nil || s.names == nil {
+		return map[string]Mapping{}
+	}
+	return s.names.all()
+}
+
+// Rename gives a connected or named device a name, also set as its
+// interface's alias. An empty name forgets the device.
+func (s *Service) Rename(id, name string) error {
+	if s == nil || s.names == nil {
+		return ErrUnknownDevice
+	}
+	name = strings.TrimSpace(name)
+	if err := ValidateName(name); err != nil {
+		return err
+	}
+	if name == "" {
+		return s.Forget(id)
+	}
+	dev := s.device(id)
+	if _, named := s.names.get(id); dev == nil && !named {
+		return ErrUnknownDevice
+	}
+	if err := s.names.rename(id, name, dev); err != nil {
+		return fmt.Errorf("save device mappings: %w", err)
+	}
+	if dev != nil {
+		s.m.setAlias(dev.Interface, name)
+	}
+	return nil
+}
+
+// Forget drops a device's name and clears its interface's alias.
+func (s *Service) Forget(id string) error {
+	if s == nil || s.names == nil {
+		return ErrUnknownDevice
+	}
+	found, err := s.names.forget(id)
+	if !found {
+		return ErrUnknownDevice
+	}
+	if err != nil {
+		return fmt.Errorf("save device mappings: %w", err)
+	}
+	if dev := s.device(id); dev != nil {
+		s.m.setAlias(dev.Interface, "")
+	}
+	return nil
+}
+
+// device returns the connected device with an ID, nil if there is none
+func (s *Service) device(id string) *DeviceStatus {
+	if s.m == nil {
+		return nil
+	}
+	for _, dev := range s.m.snapshot() {
+		if dev.ID == id {
+			return &dev
+		}
+	}
+	return nil
 }
 
 // DeviceStatus represents a USB RNDIS interface state.
 type DeviceStatus struct {
+	ID        string    `json:"id"`             // serial, or MAC when the serial is unknown
+	Name      string    `json:"name,omitempty"` // given by the user
 	Serial    string    `json:"serial"`
 	MAC       string    `json:"mac"`
 	Interface string    `json:"interface"`
@@ -141,8 +267,15 @@ type manager struct {
 	mu      sync.RWMutex
 	devices []DeviceStatus
 
+	names   *mappingStore
+	aliasMu sync.Mutex
+	aliases map[string]string // alias set on each interface
+
 	scanner  *Scanner
 	nmClient *NMClient
+
+	scanReq   chan chan struct{}   // Scan asking for a scan, closed when done
+	lastRetry map[string]time.Time // by interface, to avoid thrashing pending devices
 }
 
 func (m *manager) run() {
@@ -159,47 +292,25 @@ func (m *manager) run() {
 	// Scan immediately on start
 	m.scan()
 
-	// Periodic scan every 3 seconds
-	ticker := time.NewTicker(3 * time.Second)
-	defer ticker.Stop()
-
-	// Track retry state for pending devices (to avoid thrashing)
-	lastRetry := make(map[string]time.Time)
-	retryInterval := 10 * time.Second
+	// Periodic scan, unless scans are left to Scan
+	var tick <-chan time.Time
+	if m.opts.ScanInterval > 0 {
+		ticker := time.NewTicker(m.opts.ScanInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	m.lastRetry = make(map[string]time.Time)
 
 	for {
 		select {
-		case <-ticker.C:
+		case <-tick:
 			m.scan()
+			m.reconcileAll()
 
-			// Attempt to reconcile pending devices
-			m.mu.RLock()
-			devices := make([]DeviceStatus, len(m.devices))
-			copy(devices, m.devices)
-			m.mu.RUnlock()
-
-			for i, dev := range devices {
-				if dev.State == "pending" {
-					// Throttle retries to avoid constant errors
-					if lastTime, exists := lastRetry[dev.Interface]; exists && time.Since(lastTime) < retryInterval {
-						continue
-					}
-
-					m.log.Printf("reconciling pending device %s on %s", dev.Serial, dev.Interface)
-					lastRetry[dev.Interface] = time.Now()
-
-					// Try to bring it up
-					if err := m.reconcilePending(&devices[i]); err != nil {
-						m.log.Printf("failed to reconcile %s: %v", dev.Interface, err)
-						// Update device error state
-						m.mu.Lock()
-						if j := m.findDeviceIndex(dev.Interface); j >= 0 {
-							m.devices[j].Error = err.Error()
-						}
-						m.mu.Unlock()
-					}
-				}
-			}
+		case done := <-m.scanReq:
+			m.scan()
+			m.reconcileAll()
+			close(done)
 
 		case <-m.ctx.Done():
 			m.log.Printf("usbnet reconciler stopped")
@@ -208,8 +319,53 @@ func (m *manager) run() {
 	}
 }
 
+// reconcileAll attempts to bring up pending devices
+func (m *manager) reconcileAll() {
+	retryInterval := 10 * time.Second
+
+	m.mu.RLock()
+	devices := make([]DeviceStatus, len(m.devices))
+	copy(devices, m.devices)
+	m.mu.RUnlock()
+
+	for i, dev := range devices {
+		if dev.State == "pending" {
+			// Throttle retries to avoid constant errors
+			if lastTime, exists := m.lastRetry[dev.Interface]; exists && time.Since(lastTime) < retryInterval {
+				continue
+			}
+
+			m.log.Printf("reconciling pending device %s on %s", dev.Serial, dev.Interface)
+			m.lastRetry[dev.Interface] = time.Now()
+
+			// Try to bring it up
+			if err := m.reconcilePending(&devices[i]); err != nil {
+				m.log.Printf("failed to reconcile %s: %v", dev.Interface, err)
+				// Update device error state
+				m.mu.Lock()
+				if j := m.findDeviceIndex(dev.Interface); j >= 0 {
+					m.devices[j].Error = err.Error()
+				}
+				m.mu.Unlock()
+			}
+		}
+	}
+}
+
 func (m *manager) scan() {
 	devices := m.scanner.Scan()
+	for i := range devices {
+		devices[i].ID = deviceID(devices[i])
+	}
+	if err := m.names.seen(devices); err != nil {
+		m.log.Printf("warning: failed to save device mappings: %v", err)
+	}
+	// a named device that came back on another interface takes its name along
+	for _, dev := range devices {
+		if mapping, ok := m.names.get(dev.ID); ok {
+			m.setAlias(dev.Interface, mapping.Name)
+		}
+	}
 	m.mu.Lock()
 	m.devices = devices
 	m.mu.Unlock()
@@ -226,6 +382,31 @@ func (m *manager) snapshot() []DeviceStatus {
 	return out
 }
 
+// setAlias sets an interface's alias, shown by "ip link" and other tools,
+// unless it was already set to alias; "" clears it. A failure isn't
+// retried on every scan.
+func (m *manager) setAlias(iface, alias string) {
+	m.aliasMu.Lock()
+	defer m.aliasMu.Unlock()
+	if current, ok := m.aliases[iface]; ok && current == alias {
+		return
+	}
+	m.aliases[iface] = alias
+	if os.Geteuid() != 0 {
+		resp, err := internal.LinkAliasWithInstaller(iface, alias)
+		if err == nil && !resp.Success {
+			err = errors.New(resp.Message)
+		}
+		if err != nil {
+			m.log.Printf("failed to set alias of %s: %v", iface, err)
+		}
+		return
+	}
+	if output, err := exec.Command("ip", "link", "set", "dev", iface, "alias", alias).CombinedOutput(); err != nil {
+		m.log.Printf("failed to set alias of %s: %v: %s", iface, err, strings.TrimSpace(string(output)))
+	}
+}
+
 // reconcilePending attempts to bring up a pending interface and get it a DHCP lease.
 func (m *manager) reconcilePending(dev *DeviceStatus) error {
 	// Strategy 1: Try NetworkManager if available
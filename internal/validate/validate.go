@@ -0,0 +1,221 @@
+// Package validate checks request payloads field by field so invalid values
+// are rejected with a precise message instead of reaching FFmpeg or the
+// camera as arguments.
+package validate
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// Limits shared by every handler that accepts video parameters
+const (
+	MinBitrateKbps = 100
+	MaxBitrateKbps = 50000
+	MinWidth       = 160
+	MaxWidth       = 7680
+	MinHeight      = 120
+	MaxHeight      = 4320
+	MinFPS         = 1
+	MaxFPS         = 120
+)
+
+// FieldError describes why a single field was rejected. Field is the JSON
+// path of the value, e.g. "srtla.remote_port".
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Errors is a list of field errors and implements error.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		msgs[i] = fe.Field + ": " + fe.Message
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Validator collects field errors. The zero value is ready to use.
+type Validator struct {
+	errs Errors
+}
+
+// New returns an empty Validator.
+func New() *Validator {
+	return &Validator{}
+}
+
+// Addf records an error for field.
+func (v *Validator) Addf(field, format string, args ...interface{}) {
+	v.errs = append(v.errs, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// Err returns the collected errors, or nil if every check passed.
+func (v *Validator) Err() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}
+
+// Required checks that value is not blank.
+func (v *Validator) Required(field, value string) {
+	if strings.TrimSpace(value) == "" {
+		v.Addf(field, "is required")
+	}
+}
+
+// Port checks for a usable TCP/UDP port.
+func (v *Validator) Port(field string, port int) {
+	if port < 1 || port > 65535 {
+		v.Addf(field, "port %d is invalid (must be 1-65535)", port)
+	}
+}
+
+// Range checks min <= value <= max.
+func (v *Validator) Range(field string, value, min, max int) {
+	if value < min || value > max {
+		v.Addf(field, "%d is out of range (%d-%d)", value, min, max)
+	}
+}
+
+// IP checks for an IPv4 or IPv6 address. Empty values are skipped; pair with
+// Required where the field is mandatory.
+func (v *Validator) IP(field, value string) {
+	if value != "" && net.ParseIP(strings.TrimSpace(value)) == nil {
+		v.Addf(field, "'%s' is not a valid IP address", value)
+	}
+}
+
+// IPv4 checks for an IPv4 address. Empty values are skipped.
+func (v *Validator) IPv4(field, value string) {
+	if value == "" {
+		return
+	}
+	if ip := net.ParseIP(strings.TrimSpace(value)); ip == nil || ip.To4() == nil {
+		v.Addf(field, "'%s' is not a valid IPv4 address", value)
+	}
+}
+
+// CIDROrIP checks for a CIDR block or a bare IP. Empty values are skipped.
+func (v *Validator) CIDROrIP(field, value string) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return
+	}
+	if _, _, err := net.ParseCIDR(value); err != nil && net.ParseIP(value) == nil {
+		v.Addf(field, "'%s' is not a valid CIDR or IP address", value)
+	}
+}
+
+// MAC checks for a hardware address. Empty values are skipped.
+func (v *Validator) MAC(field, value string) {
+	if value == "" {
+		return
+	}
+	if _, err := net.ParseMAC(value); err != nil {
+		v.Addf(field, "'%s' is not a valid MAC address", value)
+	}
+}
+
+// URL checks for an absolute URL, optionally restricted to schemes. Empty
+// values are skipped.
+func (v *Validator) URL(field, value string, schemes ...string) {
+	if value == "" {
+		return
+	}
+	u, err := url.Parse(strings.TrimSpace(value))
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		v.Addf(field, "'%s' is not a valid URL", value)
+		return
+	}
+	if len(schemes) > 0 && !contains(schemes, u.Scheme) {
+		v.Addf(field, "URL scheme must be one of %s", strings.Join(schemes, ", "))
+	}
+}
+
+// OneOf checks that value is one of allowed. Empty values are skipped so
+// optional enums can fall back to their defaults.
+func (v *Validator) OneOf(field, value string, allowed ...string) {
+	if value != "" && !contains(allowed, value) {
+		v.Addf(field, "'%s' must be one of %s", value, strings.Join(allowed, ", "))
+	}
+}
+
+// Bitrate checks a video bitrate in kbps. Zero means "use the default".
+func (v *Validator) Bitrate(field string, kbps int) {
+	if kbps != 0 {
+		v.Range(field, kbps, MinBitrateKbps, MaxBitrateKbps)
+	}
+}
+
+// FPS checks a frame rate. Zero means "use the default".
+func (v *Validator) FPS(field string, fps int) {
+	if fps != 0 {
+		v.Range(field, fps, MinFPS, MaxFPS)
+	}
+}
+
+// Resolution checks a frame size. Zero for both means "use the default";
+// otherwise both dimensions must be set, even (for 4:2:0 encoders) and in
+// range.
+func (v *Validator) Resolution(widthField, heightField string, width, height int) {
+	if width == 0 && height == 0 {
+		return
+	}
+	if width < MinWidth || width > MaxWidth {
+		v.Addf(widthField, "%d is out of range (%d-%d)", width, MinWidth, MaxWidth)
+	} else if width%2 != 0 {
+		v.Addf(widthField, "%d must be even", width)
+	}
+	if height < MinHeight || height > MaxHeight {
+		v.Addf(heightField, "%d is out of range (%d-%d)", height, MinHeight, MaxHeight)
+	} else if height%2 != 0 {
+		v.Addf(heightField, "%d must be even", height)
+	}
+}
+
+// SSID checks an 802.11 network name (1-32 bytes). Empty values are skipped.
+func (v *Validator) SSID(field, value string) {
+	if len(value) > 32 {
+		v.Addf(field, "must be at most 32 bytes")
+	}
+}
+
+// Passphrase checks a WPA-PSK passphrase (8-63 characters, or a raw 64 hex
+// digit key). Empty values are skipped for open networks and saved profiles.
+func (v *Validator) Passphrase(field, value string) {
+	if value == "" || (len(value) >= 8 && len(value) <= 63) {
+		return
+	}
+	if _, err := hex.DecodeString(value); len(value) == 64 && err == nil {
+		return
+	}
+	v.Addf(field, "must be 8-63 characters")
+}
+
+// Token checks a release tag or similar identifier that ends up in file
+// paths and URLs: letters, digits, '.', '-', '_' and '+' only.
+func (v *Validator) Token(field, value string) {
+	for _, c := range value {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune(".-_+", c)) {
+			v.Addf(field, "'%s' contains invalid characters", value)
+			return
+		}
+	}
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
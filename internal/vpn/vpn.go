@@ -0,0 +1,330 @@
+// Package vpn brings up a WireGuard or Tailscale tunnel so the manager can
+// be reached in the field without port forwarding, which a modem behind
+// CGNAT can't do. The tunnel dials out over whichever link has a route, so
+// it works wherever the unit has internet.
+//
+// WireGuard is driven with wg-quick and a config in /etc/wireguard; the
+// Tailscale daemon with the tailscale CLI. Both need root, so they run
+// through srtla-installer unless the manager already is root.
+package vpn
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"srtla-manager/internal"
+)
+
+// Providers of the tunnel
+const (
+	ProviderWireGuard = "wireguard"
+	ProviderTailscale = "tailscale"
+)
+
+// Providers lists the valid providers
+var Providers = []string{ProviderWireGuard, ProviderTailscale}
+
+// Interface names used when none is set
+const (
+	DefaultWireGuardInterface = "wg0"
+	TailscaleInterface        = "tailscale0"
+)
+
+// handshakeTimeout is how long after its last handshake a WireGuard peer
+// still counts as online. Handshakes are renewed every two minutes while
+// traffic flows.
+const handshakeTimeout = 3 * time.Minute
+
+// Config selects the tunnel
+type Config struct {
+	Provider  string
+	Interface string // WireGuard config name in /etc/wireguard; wg0 when empty
+	AuthKey   string // Tailscale pre-auth key, for the first login
+	Hostname  string // Tailscale machine name; the system hostname when empty
+}
+
+// InterfaceName returns the network interface of the tunnel
+func (c Config) InterfaceName() string {
+	if c.Provider == ProviderTailscale {
+		return TailscaleInterface
+	}
+	if c.Interface != "" {
+		return c.Interface
+	}
+	return DefaultWireGuardInterface
+}
+
+// Peer is a machine at the other end of the tunnel
+type Peer struct {
+	Name          string    `json:"name"` // public key for WireGuard
+	Endpoint      string    `json:"endpoint,omitempty"`
+	Online        bool      `json:"online"`
+	LastHandshake time.Time `json:"last_handshake,omitempty"`
+	RxBytes       int64     `json:"rx_bytes"`
+	TxBytes       int64     `json:"tx_bytes"`
+}
+
+// Status is the state of the tunnel
+type Status struct {
+	Provider  string   `json:"provider"`
+	Interface string   `json:"interface"`
+	Up        bool     `json:"up"`
+	IPs       []string `json:"ips,omitempty"`      // addresses of the unit in the tunnel
+	State     string   `json:"state,omitempty"`    // Tailscale backend state, e.g. NeedsLogin
+	AuthURL   string   `json:"auth_url,omitempty"` // to log the unit in to Tailscale
+	Peers     []Peer   `json:"peers"`
+}
+
+// Command is a command to run as root. The auth key is never put on the
+// command line, where ps would show it: it is written to a file only root
+// can read, passed as --auth-key=file:<path>.
+type Command struct {
+	Name    string
+	Args    []string
+	AuthKey string
+}
+
+func (c Command) String() string {
+	return strings.TrimSpace(c.Name + " " + strings.Join(c.Args, " "))
+}
+
+// Runner runs a command as root and returns its combined output
+type Runner func(ctx context.Context, cmd Command) ([]byte, error)
+
+// Tunnel controls one tunnel
+type Tunnel struct {
+	cfg Config
+	run Runner
+}
+
+// New returns a tunnel running its commands with run, or as root when run
+// is nil
+func New(cfg Config, run Runner) *Tunnel {
+	if run == nil {
+		run = runRoot
+	}
+	return &Tunnel{cfg: cfg, run: run}
+}
+
+// runRoot runs cmd itself when the manager is root, otherwise through
+// srtla-installer, which only runs the commands a tunnel uses
+func runRoot(ctx context.Context, cmd Command) ([]byte, error) {
+	if os.Geteuid() != 0 {
+		resp, err := internal.VPNWithInstaller(cmd.Name, cmd.Args, cmd.AuthKey)
+		if err != nil {
+			return nil, err
+		}
+		if !resp.Success {
+			return []byte(resp.Output), errors.New(resp.Message)
+		}
+		return []byte(resp.Output), nil
+	}
+
+	args := cmd.Args
+	if cmd.AuthKey != "" {
+		path, err := writeAuthKey(cmd.AuthKey)
+		if err != nil {
+			return nil, err
+		}
+		defer os.Remove(path)
+		args = append(slices.Clone(args), "--auth-key=file:"+path)
+	}
+	return exec.CommandContext(ctx, cmd.Name, args...).CombinedOutput()
+}
+
+// writeAuthKey writes key to a new file readable by its owner only
+func writeAuthKey(key string) (string, error) {
+	f, err := os.CreateTemp("", "tailscale-authkey-*")
+	if err != nil {
+		return "", err
+	}
+	_, err = f.WriteString(key)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+func (t *Tunnel) command(ctx context.Context, cmd Command) ([]byte, error) {
+	output, err := t.run(ctx, cmd)
+	if err != nil {
+		return output, fmt.Errorf("%s: %w: %s", cmd, err, strings.TrimSpace(string(output)))
+	}
+	return output, nil
+}
+
+// Up brings the tunnel up. A WireGuard interface that is already there is
+// left as it is.
+func (t *Tunnel) Up(ctx context.Context) error {
+	switch t.cfg.Provider {
+	case ProviderWireGuard:
+		if interfaceExists(t.cfg.InterfaceName()) {
+			return nil
+		}
+		_, err := t.command(ctx, Command{Name: "wg-quick", Args: []string{"up", t.cfg.InterfaceName()}})
+		return err
+	case ProviderTailscale:
+		_, err := t.command(ctx, Command{Name: "tailscale", Args: t.tailscaleUpArgs(), AuthKey: t.cfg.AuthKey})
+		return err
+	}
+	return fmt.Errorf("unknown VPN provider %q", t.cfg.Provider)
+}
+
+func (t *Tunnel) tailscaleUpArgs() []string {
+	// without a key, up waits for a login; the timeout returns and the
+	// login URL shows in the status
+	args := []string{"up", "--timeout=20s"}
+	if t.cfg.Hostname != "" {
+		args = append(args, "--hostname="+t.cfg.Hostname)
+	}
+	return args
+}
+
+// Down takes the tunnel down. A WireGuard interface that isn't there is
+// already down.
+func (t *Tunnel) Down(ctx context.Context) error {
+	switch t.cfg.Provider {
+	case ProviderWireGuard:
+		if !interfaceExists(t.cfg.InterfaceName()) {
+			return nil
+		}
+		_, err := t.command(ctx, Command{Name: "wg-quick", Args: []string{"down", t.cfg.InterfaceName()}})
+		return err
+	case ProviderTailscale:
+		_, err := t.command(ctx, Command{Name: "tailscale", Args: []string{"down"}})
+		return err
+	}
+	return fmt.Errorf("unknown VPN provider %q", t.cfg.Provider)
+}
+
+// Status returns the state of the tunnel and its peers
+func (t *Tunnel) Status(ctx context.Context) (Status, error) {
+	st := Status{Provider: t.cfg.Provider, Interface: t.cfg.InterfaceName(), Peers: []Peer{}}
+	switch t.cfg.Provider {
+	case ProviderWireGuard:
+		if !interfaceExists(st.Interface) {
+			return st, nil
+		}
+		st.Up = true
+		st.IPs = InterfaceIPs(st.Interface)
+		output, err := t.command(ctx, Command{Name: "wg", Args: []string{"show", st.Interface, "dump"}})
+		if err != nil {
+			return st, err
+		}
+		st.Peers = parseWGDump(string(output), time.Now())
+		return st, nil
+	case ProviderTailscale:
+		output, err := t.command(ctx, Command{Name: "tailscale", Args: []string{"status", "--json"}})
+		if err != nil {
+			return st, err
+		}
+		err = parseTailscaleStatus(output, &st)
+		return st, err
+	}
+	return st, fmt.Errorf("unknown VPN provider %q", t.cfg.Provider)
+}
+
+// parseWGDump reads the peers from "wg show <interface> dump": a line for
+// the interface, then one per peer of public key, preshared key, endpoint,
+// allowed IPs, latest handshake, bytes received, bytes sent and keepalive,
+// separated by tabs
+func parseWGDump(dump string, now time.Time) []Peer {
+	peers := []Peer{}
+	lines := strings.Split(strings.TrimSpace(dump), "\n")
+	for _, line := range lines[min(1, len(lines)):] {
+		fields := strings.Split(line, "\t")
+		if len(fields) < 7 {
+			continue
+		}
+		p := Peer{Name: fields[0]}
+		if fields[2] != "(none)" {
+			p.Endpoint = fields[2]
+		}
+		if sec, _ := strconv.ParseInt(fields[4], 10, 64); sec > 0 {
+			p.LastHandshake = time.Unix(sec, 0)
+			p.Online = now.Sub(p.LastHandshake) < handshakeTimeout
+		}
+		p.RxBytes, _ = strconv.ParseInt(fields[5], 10, 64)
+		p.TxBytes, _ = strconv.ParseInt(fields[6], 10, 64)
+		peers = append(peers, p)
+	}
+	return peers
+}
+
+// tailscaleStatus is the part of "tailscale status --json" read
+type tailscaleStatus struct {
+	BackendState string
+	AuthURL      string
+	Self         *tailscalePeer
+	Peer         map[string]tailscalePeer
+}
+
+type tailscalePeer struct {
+	HostName      string
+	TailscaleIPs  []string
+	CurAddr       string
+	Online        bool
+	LastHandshake time.Time
+	RxBytes       int64
+	TxBytes       int64
+}
+
+func parseTailscaleStatus(data []byte, st *Status) error {
+	var ts tailscaleStatus
+	if err := json.Unmarshal(data, &ts); err != nil {
+		return fmt.Errorf("tailscale status: %w", err)
+	}
+	st.State = ts.BackendState
+	st.AuthURL = ts.AuthURL
+	st.Up = ts.BackendState == "Running"
+	if ts.Self != nil {
+		st.IPs = ts.Self.TailscaleIPs
+	}
+	for _, p := range ts.Peer {
+		peer := Peer{Name: p.HostName, Endpoint: p.CurAddr, Online: p.Online, RxBytes: p.RxBytes, TxBytes: p.TxBytes}
+		if p.LastHandshake.Year() > 1 {
+			peer.LastHandshake = p.LastHandshake
+		}
+		st.Peers = append(st.Peers, peer)
+	}
+	sort.Slice(st.Peers, func(i, j int) bool { return st.Peers[i].Name < st.Peers[j].Name })
+	return nil
+}
+
+func interfaceExists(name string) bool {
+	_, err := net.InterfaceByName(name)
+	return err == nil
+}
+
+// InterfaceIPs returns the IPv4 addresses on the interface
+func InterfaceIPs(name string) []string {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil
+	}
+	var ips []string
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.To4() != nil {
+			ips = append(ips, ipNet.IP.String())
+		}
+	}
+	return ips
+}
@@ -0,0 +1,107 @@
+package vpn
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseWGDump(t *testing.T) {
+	now := time.Unix(1_700_000_100, 0)
+	dump := "privkey\tpubkey\t51820\toff\n" +
+		"peerA\t(none)\t203.0.113.5:51820\t10.8.0.0/24\t1700000000\t1024\t2048\t25\n" +
+		"peerB\t(none)\t(none)\t10.8.1.0/24\t0\t0\t0\toff\n"
+
+	peers := parseWGDump(dump, now)
+	if len(peers) != 2 {
+		t.Fatalf("Expected 2 peers, got %+v", peers)
+	}
+	a, b := peers[0], peers[1]
+	if a.Name != "peerA" || a.Endpoint != "203.0.113.5:51820" || !a.Online || a.RxBytes != 1024 || a.TxBytes != 2048 {
+		t.Errorf("Unexpected peer %+v", a)
+	}
+	if b.Endpoint != "" || b.Online || !b.LastHandshake.IsZero() {
+		t.Errorf("Expected a peer never seen to be offline, got %+v", b)
+	}
+
+	if peers := parseWGDump(dump, now.Add(time.Hour)); peers[0].Online {
+		t.Error("Expected a peer with an old handshake to be offline")
+	}
+	if peers := parseWGDump("", now); len(peers) != 0 {
+		t.Errorf("Expected no peers, got %+v", peers)
+	}
+}
+
+func TestParseTailscaleStatus(t *testing.T) {
+	data := []byte(`{
+		"BackendState": "Running",
+		"Self": {"HostName": "rig", "TailscaleIPs": ["100.101.102.103", "fd7a:115c:a1e0::1"]},
+		"Peer": {
+			"k2": {"HostName": "studio", "CurAddr": "198.51.100.7:41641", "Online": true, "RxBytes": 10, "TxBytes": 20},
+			"k1": {"HostName": "laptop", "Online": false, "LastHandshake": "0001-01-01T00:00:00Z"}
+		}
+	}`)
+	st := Status{Peers: []Peer{}}
+	if err := parseTailscaleStatus(data, &st); err != nil {
+		t.Fatal(err)
+	}
+	if !st.Up || st.State != "Running" || len(st.IPs) != 2 {
+		t.Errorf("Unexpected status %+v", st)
+	}
+	if len(st.Peers) != 2 || st.Peers[0].Name != "laptop" || st.Peers[1].Endpoint != "198.51.100.7:41641" || !st.Peers[1].Online {
+		t.Errorf("Unexpected peers %+v", st.Peers)
+	}
+	if !st.Peers[0].LastHandshake.IsZero() {
+		t.Errorf("Expected no handshake, got %v", st.Peers[0].LastHandshake)
+	}
+
+	st = Status{}
+	parseTailscaleStatus([]byte(`{"BackendState": "NeedsLogin", "AuthURL": "https://login.tailscale.com/a/x"}`), &st)
+	if st.Up || st.AuthURL == "" {
+		t.Errorf("Expected a tunnel waiting for a login, got %+v", st)
+	}
+}
+
+func TestTailscaleCommands(t *testing.T) {
+	var calls []string
+	var keys []string
+	run := func(ctx context.Context, cmd Command) ([]byte, error) {
+		calls = append(calls, cmd.String())
+		keys = append(keys, cmd.AuthKey)
+		if cmd.Args[0] == "down" {
+			return []byte("not running"), errors.New("exit status 1")
+		}
+		return nil, nil
+	}
+	tun := New(Config{Provider: ProviderTailscale, AuthKey: "tskey-abc", Hostname: "rig"}, run)
+	if err := tun.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if want := "tailscale up --timeout=20s --hostname=rig"; calls[0] != want {
+		t.Errorf("Expected %q, got %q", want, calls[0])
+	}
+	if keys[0] != "tskey-abc" {
+		t.Errorf("Expected the auth key beside the command, got %q", keys[0])
+	}
+	if err := tun.Down(context.Background()); err == nil || !strings.Contains(err.Error(), "not running") {
+		t.Errorf("Expected the output in the error, got %v", err)
+	}
+}
+
+func TestInterfaceName(t *testing.T) {
+	cases := []struct {
+		cfg  Config
+		want string
+	}{
+		{Config{Provider: ProviderWireGuard}, "wg0"},
+		{Config{Provider: ProviderWireGuard, Interface: "mgmt"}, "mgmt"},
+		{Config{Provider: ProviderTailscale, Interface: "mgmt"}, "tailscale0"},
+	}
+	for _, c := range cases {
+		if got := c.cfg.InterfaceName(); got != c.want {
+			t.Errorf("%+v: expected %s, got %s", c.cfg, c.want, got)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package wifi
+
+import (
+	"context"
+	"strings"
+
+	"srtla-manager/internal/events"
+)
+
+// TopicStatus carries the connection status when it may have changed
+var TopicStatus = events.NewTopic[ConnectionInfo]("wifi")
+
+// Backend queries NetworkManager state. The D-Bus backend returns structured
+// data; nmcli is kept as a fallback for hosts without system bus access.
+type Backend interface {
+	Name() string
+	AccessPoints(rescan bool) ([]AccessPoint, error)
+	ConnectionStatus() *ConnectionInfo
+}
+
+// Watcher is implemented by backends that can report connection changes as
+// they happen.
+type Watcher interface {
+	Watch(ctx context.Context, changed func()) error
+}
+
+// AccessPoint is a single BSS seen by the WiFi device.
+type AccessPoint struct {
+	SSID      string
+	Signal    int
+	Security  string
+	Active    bool
+	Frequency int // MHz
+}
+
+func newBackend(log Logger) Backend {
+	if b, err := newDBusBackend(); err == nil {
+		log.Printf("using NetworkManager D-Bus backend")
+		return b
+	} else {
+		log.Printf("NetworkManager D-Bus unavailable (%v), falling back to nmcli", err)
+	}
+	return &nmcliBackend{}
+}
+
+// Watch calls changed whenever the backend reports a connection state change.
+// It returns immediately with an error if the backend cannot watch.
+func (m *Manager) Watch(ctx context.Context, changed func()) error {
+	w, ok := m.backend.(Watcher)
+	if !ok {
+		return errWatchUnsupported
+	}
+	return w.Watch(ctx, changed)
+}
+
+// PublishStatus publishes the current connection status on bus
+func (m *Manager) PublishStatus(bus *events.Bus) {
+	if status := m.GetConnectionStatus(); status != nil {
+		events.Publish(bus, TopicStatus, *status)
+	}
+}
+
+// PublishChanges publishes the connection status on bus whenever the backend
+// reports a change, until ctx is done. It returns an error straight away if
+// the backend cannot watch.
+func (m *Manager) PublishChanges(ctx context.Context, bus *events.Bus) error {
+	return m.Watch(ctx, func() { m.PublishStatus(bus) })
+}
+
+// splitTerse splits a line of nmcli terse output into fields, honouring the
+// backslash escaping nmcli applies to ':' and '\' inside values.
+func splitTerse(line string) []string {
+	var fields []string
+	var cur strings.Builder
+	escaped := false
+	for _, r := range line {
+		switch {
+		case escaped:
+			cur.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == ':':
+			fields = append(fields, cur.String())
+			cur.Reset()
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	return append(fields, cur.String())
+}
@@ -0,0 +1,308 @@
+package wifi
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	nmDest          = "org.freedesktop.NetworkManager"
+	nmPath          = dbus.ObjectPath("/org/freedesktop/NetworkManager")
+	nmDeviceIface   = nmDest + ".Device"
+	nmWirelessIface = nmDest + ".Device.Wireless"
+	nmAPIface       = nmDest + ".AccessPoint"
+	nmIP4Iface      = nmDest + ".IP4Config"
+	nmActiveIface   = nmDest + ".Connection.Active"
+	nmSettingsIface = nmDest + ".Settings.Connection"
+
+	nmDeviceTypeWifi       = 2
+	nmDeviceStateActivated = 100
+	nm80211ModeAP          = 3
+
+	// NM80211ApFlags / NM80211ApSecurityFlags
+	nmAPFlagPrivacy    = 0x1
+	nmSecKeyMgmtPSK    = 0x100
+	nmSecKeyMgmt8021X  = 0x200
+	nmSecKeyMgmtSAE    = 0x400
+	nmSecKeyMgmtOWE    = 0x800
+	dbusScanTimeout    = 15 * time.Second
+	dbusScanPollPeriod = 500 * time.Millisecond
+)
+
+// dbusBackend talks to NetworkManager over the system bus.
+type dbusBackend struct {
+	conn *dbus.Conn
+}
+
+func newDBusBackend() (*dbusBackend, error) {
+	conn, err := dbus.SystemBus()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.Object(nmDest, nmPath).GetProperty(nmDest + ".Version"); err != nil {
+		return nil, err
+	}
+	return &dbusBackend{conn: conn}, nil
+}
+
+func (b *dbusBackend) Name() string {
+	return "dbus"
+}
+
+func (b *dbusBackend) prop(path dbus.ObjectPath, iface, name string) (interface{}, error) {
+	v, err := b.conn.Object(nmDest, path).GetProperty(iface + "." + name)
+	if err != nil {
+		return nil, err
+	}
+	return v.Value(), nil
+}
+
+func (b *dbusBackend) props(path dbus.ObjectPath, iface string) (map[string]dbus.Variant, error) {
+	var props map[string]dbus.Variant
+	err := b.conn.Object(nmDest, path).Call("org.freedesktop.DBus.Properties.GetAll", 0, iface).Store(&props)
+	return props, err
+}
+
+// wifiDevices returns the object paths of all WiFi devices.
+func (b *dbusBackend) wifiDevices() ([]dbus.ObjectPath, error) {
+	var devices []dbus.ObjectPath
+	if err := b.conn.Object(nmDest, nmPath).Call(nmDest+".GetDevices", 0).Store(&devices); err != nil {
+		return nil, err
+	}
+
+	var wifi []dbus.ObjectPath
+	for _, dev := range devices {
+		if t, err := b.prop(dev, nmDeviceIface, "DeviceType"); err == nil && t == uint32(nmDeviceTypeWifi) {
+			wifi = append(wifi, dev)
+		}
+	}
+	return wifi, nil
+}
+
+// requestScan triggers a scan on dev and waits for it to complete.
+func (b *dbusBackend) requestScan(dev dbus.ObjectPath) error {
+	before, _ := b.prop(dev, nmWirelessIface, "LastScan")
+
+	obj := b.conn.Object(nmDest, dev)
+	if err := obj.Call(nmWirelessIface+".RequestScan", 0, map[string]dbus.Variant{}).Err; err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(dbusScanTimeout)
+	for time.Now().Before(deadline) {
+		time.Sleep(dbusScanPollPeriod)
+		if after, err := b.prop(dev, nmWirelessIface, "LastScan"); err == nil && after != before {
+			return nil
+		}
+	}
+	return fmt.Errorf("scan timed out")
+}
+
+func (b *dbusBackend) AccessPoints(rescan bool) ([]AccessPoint, error) {
+	devices, err := b.wifiDevices()
+	if err != nil {
+		return nil, err
+	}
+
+	var aps []AccessPoint
+	for _, dev := range devices {
+		if rescan {
+			if err := b.requestScan(dev); err != nil {
+				return nil, fmt.Errorf("scan failed: %w", err)
+			}
+		}
+
+		active, _ := b.prop(dev, nmWirelessIface, "ActiveAccessPoint")
+
+		var paths []dbus.ObjectPath
+		if err := b.conn.Object(nmDest, dev).Call(nmWirelessIface+".GetAllAccessPoints", 0).Store(&paths); err != nil {
+			return nil, err
+		}
+
+		for _, path := range paths {
+			props, err := b.props(path, nmAPIface)
+			if err != nil {
+				continue // AP vanished between listing and reading
+			}
+			ssid, _ := props["Ssid"].Value().([]byte)
+			strength, _ := props["Strength"].Value().(byte)
+			freq, _ := props["Frequency"].Value().(uint32)
+			flags, _ := props["Flags"].Value().(uint32)
+			wpa, _ := props["WpaFlags"].Value().(uint32)
+			rsn, _ := props["RsnFlags"].Value().(uint32)
+
+			aps = append(aps, AccessPoint{
+				SSID:      string(ssid),
+				Signal:    int(strength),
+				Security:  apSecurity(flags, wpa, rsn),
+				Active:    active == path,
+				Frequency: int(freq),
+			})
+		}
+	}
+
+	return aps, nil
+}
+
+// apSecurity renders AP security flags the way nmcli does.
+func apSecurity(flags, wpa, rsn uint32) string {
+	var parts []string
+	if flags&nmAPFlagPrivacy != 0 && wpa == 0 && rsn == 0 {
+		parts = append(parts, "WEP")
+	}
+	if wpa != 0 {
+		parts = append(parts, "WPA1")
+	}
+	if rsn&(nmSecKeyMgmtPSK|nmSecKeyMgmt8021X) != 0 {
+		parts = append(parts, "WPA2")
+	}
+	if rsn&nmSecKeyMgmtSAE != 0 {
+		parts = append(parts, "WPA3")
+	}
+	if rsn&nmSecKeyMgmtOWE != 0 {
+		parts = append(parts, "OWE")
+	}
+	if (wpa|rsn)&nmSecKeyMgmt8021X != 0 {
+		parts = append(parts, "802.1X")
+	}
+	return strings.Join(parts, " ")
+}
+
+func (b *dbusBackend) ConnectionStatus() *ConnectionInfo {
+	info := &ConnectionInfo{}
+
+	devices, err := b.wifiDevices()
+	if err != nil {
+		info.LastError = fmt.Sprintf("failed to get devices: %v", err)
+		return info
+	}
+
+	for _, dev := range devices {
+		if state, _ := b.prop(dev, nmDeviceIface, "State"); state != uint32(nmDeviceStateActivated) {
+			continue
+		}
+
+		info.Connected = true
+		if iface, ok := b.propString(dev, nmDeviceIface, "Interface"); ok {
+			info.Interface = iface
+		}
+		info.IP = b.deviceIPv4(dev)
+
+		if mode, _ := b.prop(dev, nmWirelessIface, "Mode"); mode == uint32(nm80211ModeAP) {
+			// Hotspot: the SSID lives in the connection profile
+			info.SSID = b.activeSSID(dev)
+			if info.IP == "" {
+				info.IP = "10.42.0.1" // Default hotspot IP
+			}
+			return info
+		}
+
+		if apPath, _ := b.prop(dev, nmWirelessIface, "ActiveAccessPoint"); apPath != nil {
+			if props, err := b.props(apPath.(dbus.ObjectPath), nmAPIface); err == nil {
+				ssid, _ := props["Ssid"].Value().([]byte)
+				strength, _ := props["Strength"].Value().(byte)
+				freq, _ := props["Frequency"].Value().(uint32)
+				info.SSID = string(ssid)
+				info.Signal = int(strength)
+				if freq > 0 {
+					info.Frequency = fmt.Sprintf("%d MHz", freq)
+				}
+			}
+		}
+		return info
+	}
+
+	return info
+}
+
+func (b *dbusBackend) propString(path dbus.ObjectPath, iface, name string) (string, bool) {
+	v, err := b.prop(path, iface, name)
+	if err != nil {
+		return "", false
+	}
+	s, ok := v.(string)
+	return s, ok
+}
+
+// deviceIPv4 returns the first IPv4 address assigned to dev.
+func (b *dbusBackend) deviceIPv4(dev dbus.ObjectPath) string {
+	cfg, err := b.prop(dev, nmDeviceIface, "Ip4Config")
+	if err != nil {
+		return ""
+	}
+	path, ok := cfg.(dbus.ObjectPath)
+	if !ok || path == "/" {
+		return ""
+	}
+
+	data, err := b.prop(path, nmIP4Iface, "AddressData")
+	if err != nil {
+		return ""
+	}
+	addrs, _ := data.([]map[string]dbus.Variant)
+	for _, addr := range addrs {
+		if a, ok := addr["address"].Value().(string); ok {
+			return a
+		}
+	}
+	return ""
+}
+
+// activeSSID reads the SSID from the profile of dev's active connection.
+func (b *dbusBackend) activeSSID(dev dbus.ObjectPath) string {
+	active, err := b.prop(dev, nmDeviceIface, "ActiveConnection")
+	if err != nil {
+		return ""
+	}
+	conn, err := b.prop(active.(dbus.ObjectPath), nmActiveIface, "Connection")
+	if err != nil {
+		return ""
+	}
+
+	var settings map[string]map[string]dbus.Variant
+	if err := b.conn.Object(nmDest, conn.(dbus.ObjectPath)).Call(nmSettingsIface+".GetSettings", 0).Store(&settings); err != nil {
+		return ""
+	}
+	ssid, _ := settings["802-11-wireless"]["ssid"].Value().([]byte)
+	return string(ssid)
+}
+
+// Watch reports WiFi devices entering or leaving the activated state.
+func (b *dbusBackend) Watch(ctx context.Context, changed func()) error {
+	match := []dbus.MatchOption{
+		dbus.WithMatchInterface(nmDeviceIface),
+		dbus.WithMatchMember("StateChanged"),
+	}
+	if err := b.conn.AddMatchSignal(match...); err != nil {
+		return err
+	}
+	defer b.conn.RemoveMatchSignal(match...)
+
+	signals := make(chan *dbus.Signal, 16)
+	b.conn.Signal(signals)
+	defer b.conn.RemoveSignal(signals)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case sig := <-signals:
+			if sig == nil || sig.Name != nmDeviceIface+".StateChanged" || len(sig.Body) < 2 {
+				continue
+			}
+			newState, _ := sig.Body[0].(uint32)
+			oldState, _ := sig.Body[1].(uint32)
+			if newState != nmDeviceStateActivated && oldState != nmDeviceStateActivated {
+				continue
+			}
+			if t, err := b.prop(sig.Path, nmDeviceIface, "DeviceType"); err != nil || t != uint32(nmDeviceTypeWifi) {
+				continue
+			}
+			changed()
+		}
+	}
+}
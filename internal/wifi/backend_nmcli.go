@@ -0,0 +1,171 @@
+package wifi
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+var errWatchUnsupported = errors.New("backend does not support watching")
+
+// nmcliBackend parses nmcli terse output.
+type nmcliBackend struct{}
+
+func (b *nmcliBackend) Name() string {
+	return "nmcli"
+}
+
+func (b *nmcliBackend) AccessPoints(rescan bool) ([]AccessPoint, error) {
+	args := []string{"-t", "-f", "SSID,SIGNAL,SECURITY,ACTIVE,FREQ", "dev", "wifi", "list"}
+	if rescan {
+		args = append(args, "--rescan", "yes")
+	}
+	output, err := exec.Command("nmcli", args...).Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var aps []AccessPoint
+	for _, line := range strings.Split(string(output), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		parts := splitTerse(line)
+		if len(parts) < 5 {
+			continue
+		}
+
+		ap := AccessPoint{
+			SSID:     strings.TrimSpace(parts[0]),
+			Security: strings.TrimSpace(parts[2]),
+			Active:   strings.TrimSpace(parts[3]) == "yes",
+		}
+		fmt.Sscanf(strings.TrimSpace(parts[1]), "%d", &ap.Signal)
+		fmt.Sscanf(strings.TrimSpace(parts[4]), "%d", &ap.Frequency)
+		aps = append(aps, ap)
+	}
+
+	return aps, nil
+}
+
+func (b *nmcliBackend) ConnectionStatus() *ConnectionInfo {
+	info := &ConnectionInfo{
+		Connected: false,
+	}
+
+	// Check if any WiFi connection is active
+	cmd := exec.Command("nmcli", "-t", "-f", "ACTIVE,NAME,TYPE", "con", "show", "--active")
+	output, err := cmd.Output()
+	if err != nil {
+		info.LastError = fmt.Sprintf("failed to get active connections: %v", err)
+		return info
+	}
+
+	var wifiConnName string
+	var isHotspot bool
+	for _, line := range strings.Split(string(output), "\n") {
+		parts := splitTerse(line)
+		if len(parts) >= 3 && strings.Contains(parts[2], "802-11-wireless") {
+			wifiConnName = strings.TrimSpace(parts[1])
+			// Check if this is a hotspot connection
+			if strings.HasPrefix(wifiConnName, "srtla-hotspot-") {
+				isHotspot = true
+			}
+			break
+		}
+	}
+
+	if wifiConnName == "" {
+		return info
+	}
+
+	info.Connected = true
+
+	// If this is a hotspot, get hotspot-specific info
+	if isHotspot {
+		// Get SSID from the connection
+		cmd = exec.Command("nmcli", "-t", "-f", "802-11-wireless.ssid", "con", "show", wifiConnName)
+		output, err = cmd.Output()
+		if err == nil {
+			for _, line := range strings.Split(string(output), "\n") {
+				if strings.Contains(line, "ssid") {
+					parts := splitTerse(line)
+					if len(parts) >= 2 {
+						info.SSID = strings.TrimSpace(strings.Join(parts[1:], ":"))
+					}
+				}
+			}
+		}
+
+		// Get hotspot IP address
+		info.IP = hotspotIP()
+		if info.IP == "" {
+			info.IP = "10.42.0.1" // Default hotspot IP
+		}
+
+		// Get interface name
+		cmd = exec.Command("nmcli", "-t", "-f", "DEVICE", "con", "show", "--active", wifiConnName)
+		output, err = cmd.Output()
+		if err == nil {
+			for _, line := range strings.Split(string(output), "\n") {
+				if strings.Contains(line, "DEVICE") {
+					parts := splitTerse(line)
+					if len(parts) >= 2 {
+						info.Interface = strings.TrimSpace(parts[1])
+					}
+				}
+			}
+		}
+
+		return info
+	}
+
+	info.Connected = true
+
+	// Get details about the WiFi connection
+	cmd = exec.Command("nmcli", "-t", "-f", "connection.id,802-11-wireless-properties.ssid,ipv4.addresses,signal", "con", "show", wifiConnName)
+	output, err = cmd.Output()
+	if err == nil {
+		for _, line := range strings.Split(string(output), "\n") {
+			if strings.Contains(line, "ssid") {
+				parts := splitTerse(line)
+				if len(parts) >= 2 {
+					info.SSID = strings.TrimSpace(strings.Join(parts[1:], ":"))
+				}
+			} else if strings.Contains(line, "addresses") {
+				parts := splitTerse(line)
+				if len(parts) >= 2 {
+					addr := strings.TrimSpace(parts[1])
+					// Extract IP from CIDR format
+					if idx := strings.Index(addr, "/"); idx > 0 {
+						info.IP = addr[:idx]
+					}
+				}
+			}
+		}
+	}
+
+	// Get interface info for signal strength
+	cmd = exec.Command("nmcli", "-t", "-f", "DEVICE,SIGNAL", "dev", "wifi")
+	output, err = cmd.Output()
+	if err == nil {
+		for _, line := range strings.Split(string(output), "\n") {
+			parts := splitTerse(line)
+			if len(parts) >= 2 {
+				iface := strings.TrimSpace(parts[0])
+				signal := 0
+				fmt.Sscanf(strings.TrimSpace(parts[1]), "%d", &signal)
+				if signal > 0 {
+					info.Interface = iface
+					info.Signal = signal
+					break
+				}
+			}
+		}
+	}
+
+	return info
+}
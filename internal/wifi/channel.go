@@ -0,0 +1,209 @@
+package wifi
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ChannelRescanInterval is how often the channels are re-evaluated while no
+// hotspot is running, and how old a decision may be when one is started
+const ChannelRescanInterval = 5 * time.Minute
+
+// Hotspot bands
+const (
+	Band24 = "2.4"
+	Band5  = "5"
+)
+
+// Channels considered for the hotspot. On 2.4GHz only the non-overlapping
+// channels are used; on 5GHz only those without DFS, which many drivers
+// can't host an AP on and which would leave the camera waiting for a radar
+// check.
+var (
+	channels24 = []int{1, 6, 11}
+	channels5  = []int{36, 40, 44, 48, 149, 153, 157, 161, 165}
+)
+
+// ChannelScore is how busy one candidate channel is
+type ChannelScore struct {
+	Channel    int     `json:"channel"`
+	Networks   int     `json:"networks"`   // access points on or overlapping the channel
+	Congestion float64 `json:"congestion"` // sum of their signal strength, weighted by overlap
+}
+
+// ChannelChoice is the channel picked for a band and why
+type ChannelChoice struct {
+	Band       string         `json:"band"`
+	Channel    int            `json:"channel"`
+	Reason     string         `json:"reason"`
+	Candidates []ChannelScore `json:"candidates"`
+	ScannedAt  time.Time      `json:"scanned_at"`
+}
+
+// ChannelReport is the channel of the running hotspot and the latest
+// evaluation of each band
+type ChannelReport struct {
+	Active *ChannelChoice  `json:"active,omitempty"` // decision the running hotspot was started with
+	Bands  []ChannelChoice `json:"bands"`
+}
+
+// frequencyChannel converts a frequency in MHz to its band and channel
+func frequencyChannel(mhz int) (string, int) {
+	switch {
+	case mhz == 2484:
+		return Band24, 14
+	case mhz >= 2412 && mhz < 2484:
+		return Band24, (mhz - 2407) / 5
+	case mhz >= 5000 && mhz < 5900:
+		return Band5, (mhz - 5000) / 5
+	}
+	return "", 0
+}
+
+// PickChannel picks the least congested channel of band from a scan. On
+// 2.4GHz a network also counts against channels within four of its own, in
+// proportion to how much the 20MHz channels overlap.
+func PickChannel(aps []AccessPoint, band string, scannedAt time.Time) ChannelChoice {
+	candidates := channels24
+	spread := 5
+	if band == Band5 {
+		candidates = channels5
+		spread = 1
+	}
+
+	scores := make([]ChannelScore, len(candidates))
+	for i, ch := range candidates {
+		scores[i].Channel = ch
+		for _, ap := range aps {
+			apBand, apCh := frequencyChannel(ap.Frequency)
+			if apBand != band {
+				continue
+			}
+			d := apCh - ch
+			if d < 0 {
+				d = -d
+			}
+			if d >= spread {
+				continue
+			}
+			scores[i].Networks++
+			scores[i].Congestion += float64(ap.Signal) / 100 * float64(spread-d) / float64(spread)
+		}
+	}
+
+	best := scores[0]
+	for _, s := range scores[1:] {
+		if s.Congestion < best.Congestion {
+			best = s
+		}
+	}
+
+	choice := ChannelChoice{
+		Band:       band,
+		Channel:    best.Channel,
+		Candidates: scores,
+		ScannedAt:  scannedAt,
+	}
+	if best.Networks == 0 {
+		choice.Reason = fmt.Sprintf("channel %d is clear", best.Channel)
+	} else {
+		choice.Reason = fmt.Sprintf("channel %d is the least congested (%d networks nearby, congestion %.2f)",
+			best.Channel, best.Networks, best.Congestion)
+	}
+	return choice
+}
+
+// ChooseChannel returns the least congested channel of band, scanning again
+// when the last decision is older than maxAge. Scanning isn't possible while
+// the device is an access point, so the last decision is used then.
+func (m *Manager) ChooseChannel(band string, maxAge time.Duration) (ChannelChoice, error) {
+	m.channelMu.Lock()
+	last, ok := m.channels[band]
+	m.channelMu.Unlock()
+	if ok && (time.Since(last.ScannedAt) < maxAge || m.HotspotActive()) {
+		return last, nil
+	}
+
+	if err := m.scanChannels(); err != nil {
+		if ok {
+			m.log.Printf("%v, using the decision from %s", err, last.ScannedAt.Format(time.Kitchen))
+			return last, nil
+		}
+		return ChannelChoice{}, err
+	}
+
+	m.channelMu.Lock()
+	defer m.channelMu.Unlock()
+	return m.channels[band], nil
+}
+
+// scanChannels runs a spectrum scan and picks a channel for each band
+func (m *Manager) scanChannels() error {
+	aps, err := m.backend.AccessPoints(true)
+	if err != nil {
+		return fmt.Errorf("spectrum scan failed: %w", err)
+	}
+
+	now := time.Now()
+	m.channelMu.Lock()
+	defer m.channelMu.Unlock()
+	for _, band := range []string{Band24, Band5} {
+		choice := PickChannel(aps, band, now)
+		if prev, ok := m.channels[band]; !ok || prev.Channel != choice.Channel {
+			m.log.Printf("hotspot channel for %s GHz: %s", band, choice.Reason)
+		}
+		m.channels[band] = choice
+	}
+	return nil
+}
+
+// ScanChannels re-evaluates both bands now. It fails while the hotspot is up.
+func (m *Manager) ScanChannels() error {
+	if m.HotspotActive() {
+		return fmt.Errorf("can't scan while the hotspot is running")
+	}
+	return m.scanChannels()
+}
+
+// ChannelReport returns the channel decisions
+func (m *Manager) ChannelReport() ChannelReport {
+	m.channelMu.Lock()
+	defer m.channelMu.Unlock()
+
+	report := ChannelReport{Bands: []ChannelChoice{}}
+	if m.activeChannel != nil {
+		active := *m.activeChannel
+		report.Active = &active
+	}
+	for _, c := range m.channels {
+		report.Bands = append(report.Bands, c)
+	}
+	sort.Slice(report.Bands, func(i, j int) bool { return report.Bands[i].Band < report.Bands[j].Band })
+	return report
+}
+
+// EvaluateChannels re-evaluates both bands every interval while idle reports
+// true and no hotspot is running, so a hotspot starts on a current decision
+// without waiting for a scan. It returns when ctx is done.
+func (m *Manager) EvaluateChannels(ctx context.Context, interval time.Duration, idle func() bool) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// a scan briefly takes the radio off a WiFi uplink
+		if !idle() || m.HotspotActive() {
+			continue
+		}
+		if err := m.scanChannels(); err != nil {
+			m.log.Printf("channel evaluation failed: %v", err)
+		}
+	}
+}
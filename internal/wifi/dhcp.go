@@ -0,0 +1,112 @@
+package wifi
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// dnsmasqSharedDir is read by the dnsmasq instance NetworkManager starts
+	// for connections in shared mode, i.e. our hotspot
+	dnsmasqSharedDir  = "/etc/NetworkManager/dnsmasq-shared.d"
+	reservationsFile  = "srtla-manager-reservations.conf"
+	dnsmasqLeasesGlob = "/var/lib/NetworkManager/dnsmasq-*.leases"
+)
+
+// Reservation pins a hotspot client's MAC address to a fixed IP.
+type Reservation struct {
+	Name string `json:"name"`
+	MAC  string `json:"mac"`
+	IP   string `json:"ip"`
+}
+
+// Lease is a DHCP lease handed out by the hotspot.
+type Lease struct {
+	MAC      string `json:"mac"`
+	IP       string `json:"ip"`
+	Hostname string `json:"hostname"`
+}
+
+// ValidateReservation checks the MAC and IPv4 address of a reservation.
+func ValidateReservation(mac, ip string) error {
+	if _, err := net.ParseMAC(mac); err != nil {
+		return fmt.Errorf("invalid MAC address: %s", mac)
+	}
+	parsed := net.ParseIP(ip)
+	if parsed == nil || parsed.To4() == nil {
+		return fmt.Errorf("invalid IPv4 address: %s", ip)
+	}
+	return nil
+}
+
+// SetReservations writes the DHCP reservations for the hotspot. NetworkManager
+// reads them when the hotspot is activated, so an active hotspot has to be
+// restarted to pick up changes.
+func (m *Manager) SetReservations(reservations []Reservation) error {
+	path := filepath.Join(dnsmasqSharedDir, reservationsFile)
+
+	if len(reservations) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove reservations: %w", err)
+		}
+		return nil
+	}
+
+	sort.Slice(reservations, func(i, j int) bool {
+		return reservations[i].IP < reservations[j].IP
+	})
+
+	var b strings.Builder
+	b.WriteString("# Managed by srtla-manager, changes will be overwritten\n")
+	for _, r := range reservations {
+		if err := ValidateReservation(r.MAC, r.IP); err != nil {
+			return fmt.Errorf("reservation %s: %w", r.Name, err)
+		}
+		fmt.Fprintf(&b, "dhcp-host=%s,%s\n", strings.ToLower(r.MAC), r.IP)
+	}
+
+	if err := os.MkdirAll(dnsmasqSharedDir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dnsmasqSharedDir, err)
+	}
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write reservations: %w", err)
+	}
+
+	m.log.Printf("wrote %d DHCP reservation(s) to %s", len(reservations), path)
+	return nil
+}
+
+// HotspotLeases returns the clients that obtained an address from the hotspot.
+func (m *Manager) HotspotLeases() []Lease {
+	files, _ := filepath.Glob(dnsmasqLeasesGlob)
+
+	leases := []Lease{}
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			continue
+		}
+
+		// dnsmasq lease format: <expiry> <mac> <ip> <hostname> <client-id>
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) < 4 {
+				continue
+			}
+			hostname := fields[3]
+			if hostname == "*" {
+				hostname = ""
+			}
+			leases = append(leases, Lease{MAC: fields[1], IP: fields[2], Hostname: hostname})
+		}
+		f.Close()
+	}
+
+	return leases
+}
@@ -0,0 +1,164 @@
+package wifi
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// CertDir holds CA certificates uploaded for 802.1X networks.
+const CertDir = "/var/lib/srtla-manager/wifi-certs"
+
+const (
+	SecurityPSK        = "wpa-psk"
+	SecurityEnterprise = "wpa-eap"
+)
+
+// ConnectOptions describes a client connection. Security defaults to PSK (or
+// open when no password is given).
+type ConnectOptions struct {
+	SSID     string
+	Password string
+	Hidden   bool
+	Security string
+
+	// 802.1X settings, used when Security is SecurityEnterprise
+	EAPMethod         string // peap or ttls
+	Identity          string
+	AnonymousIdentity string
+	Phase2Auth        string // defaults to mschapv2
+	CACertPath        string
+}
+
+var certNameRegex = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// connectEnterprise creates (or replaces) a WPA-Enterprise profile and activates it.
+func (m *Manager) connectEnterprise(opts ConnectOptions) error {
+	if opts.Identity == "" {
+		return fmt.Errorf("identity is required for WPA-Enterprise")
+	}
+
+	method := opts.EAPMethod
+	if method == "" {
+		method = "peap"
+	}
+	if method != "peap" && method != "ttls" {
+		return fmt.Errorf("unsupported EAP method: %s", method)
+	}
+	phase2 := opts.Phase2Auth
+	if phase2 == "" {
+		phase2 = "mschapv2"
+	}
+
+	if strings.ContainsAny(opts.Password, "\r\n") {
+		return fmt.Errorf("password must be a single line")
+	}
+
+	// Name the profile after the SSID like "nmcli dev wifi connect" does, so
+	// ForgetNetwork works the same for both
+	name := opts.SSID
+
+	// Add the profile under another name first, so a failed add leaves the
+	// previous one in place. The password isn't set here, where ps would
+	// show it, but given when activating.
+	newName := name + " (new)"
+	exec.Command("nmcli", "con", "delete", "id", newName).Run()
+	args := []string{
+		"con", "add", "type", "wifi", "con-name", newName, "ifname", "*",
+		"connection.autoconnect", "no",
+		"ssid", opts.SSID,
+		"wifi-sec.key-mgmt", "wpa-eap",
+		"802-1x.eap", method,
+		"802-1x.identity", opts.Identity,
+		"802-1x.password-flags", "0",
+		"802-1x.phase2-auth", phase2,
+	}
+	if opts.AnonymousIdentity != "" {
+		args = append(args, "802-1x.anonymous-identity", opts.AnonymousIdentity)
+	}
+	if opts.CACertPath != "" {
+		args = append(args, "802-1x.ca-cert", opts.CACertPath)
+	}
+	if opts.Hidden {
+		args = append(args, "802-11-wireless.hidden", "yes")
+	}
+
+	if output, err := exec.Command("nmcli", args...).CombinedOutput(); err != nil {
+		m.log.Printf("failed to create enterprise profile: %v: %s", err, string(output))
+		exec.Command("nmcli", "con", "delete", "id", newName).Run()
+		return fmt.Errorf("failed to create connection for %s: %w", opts.SSID, err)
+	}
+
+	// Replace any previous profile so changed credentials take effect
+	exec.Command("nmcli", "con", "delete", "id", name).Run()
+	if output, err := exec.Command("nmcli", "con", "modify", "id", newName, "connection.id", name, "connection.autoconnect", "yes").CombinedOutput(); err != nil {
+		m.log.Printf("failed to rename enterprise profile: %v: %s", err, string(output))
+		return fmt.Errorf("failed to create connection for %s: %w", opts.SSID, err)
+	}
+
+	upArgs := []string{"con", "up", "id", name}
+	if opts.Password != "" {
+		// NetworkManager saves a secret given while activating with the
+		// profile, as its flags are 0, so autoconnect has it later
+		passwdFile, err := writePasswdFile("802-1x.password:" + opts.Password + "\n")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(passwdFile)
+		upArgs = append(upArgs, "passwd-file", passwdFile)
+	}
+	if output, err := exec.Command("nmcli", upArgs...).CombinedOutput(); err != nil {
+		m.log.Printf("failed to activate enterprise profile: %v: %s", err, string(output))
+		return fmt.Errorf("failed to connect to %s: %w", opts.SSID, err)
+	}
+
+	return nil
+}
+
+// writePasswdFile writes secrets for "nmcli con up ... passwd-file" to a
+// file only the manager can read, returning its path
+func writePasswdFile(secrets string) (string, error) {
+	f, err := os.CreateTemp("", "srtla-wifi-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to write secrets file: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(secrets); err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("failed to write secrets file: %w", err)
+	}
+	return f.Name(), nil
+}
+
+// SaveCACert validates a PEM encoded CA certificate and stores it under
+// CertDir, returning the path to use as CACertPath.
+func (m *Manager) SaveCACert(name string, data []byte) (string, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return "", fmt.Errorf("not a PEM encoded certificate")
+	}
+	if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+		return "", fmt.Errorf("invalid certificate: %w", err)
+	}
+
+	name = certNameRegex.ReplaceAllString(name, "_")
+	if name == "" || name == "." || name == ".." {
+		name = "ca"
+	}
+
+	if err := os.MkdirAll(CertDir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create cert directory: %w", err)
+	}
+	path := filepath.Join(CertDir, name+".pem")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return "", fmt.Errorf("failed to save certificate: %w", err)
+	}
+
+	m.log.Printf("saved CA certificate: %s", path)
+	return path, nil
+}
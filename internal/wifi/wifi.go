@@ -4,12 +4,27 @@ import (
 	"fmt"
 	"os/exec"
 	"strings"
+	"sync"
 	"time"
 )
 
+// ScanMinInterval rate-limits background rescans triggered by readers.
+const ScanMinInterval = 15 * time.Second
+
 // Manager handles WiFi operations via nmcli.
 type Manager struct {
-	log Logger
+	log     Logger
+	backend Backend
+
+	scanMu    sync.Mutex
+	scanned   []NetworkInfo
+	scannedAt time.Time
+	scanErr   error
+	scanning  bool
+
+	channelMu     sync.Mutex
+	channels      map[string]ChannelChoice // latest decision per band
+	activeChannel *ChannelChoice           // decision the running hotspot was started with
 }
 
 // Logger is a minimal logging interface.
@@ -41,13 +56,13 @@ type ConnectionInfo struct {
 type HotspotConfig struct {
 	SSID     string `json:"ssid"`
 	Password string `json:"password"`
-	Band     string `json:"band"` // 2.4 or 5
-	Channel  int    `json:"channel"`
+	Band     string `json:"band"`    // 2.4 or 5
+	Channel  int    `json:"channel"` // 0 picks the least congested channel
 }
 
 // NewManager creates a WiFi manager.
 func NewManager(log Logger) *Manager {
-	return &Manager{log: log}
+	return &Manager{log: log, backend: newBackend(log), channels: make(map[string]ChannelChoice)}
 }
 
 // IsAvailable checks if nmcli is available.
@@ -56,10 +71,80 @@ func (m *Manager) IsAvailable() bool {
 	return cmd.Run() == nil
 }
 
+// ScanResult is a cached WiFi scan.
+type ScanResult struct {
+	Networks   []NetworkInfo
+	ScannedAt  time.Time
+	Err        error
+	Refreshing bool
+}
+
+// CachedNetworks returns the last scan immediately and starts a background
+// rescan when the result is older than ScanMinInterval. The very first call
+// blocks until a scan has completed.
+func (m *Manager) CachedNetworks() ScanResult {
+	m.scanMu.Lock()
+	empty := m.scannedAt.IsZero()
+	m.scanMu.Unlock()
+
+	if empty {
+		return m.Scan(false)
+	}
+
+	m.scanMu.Lock()
+	defer m.scanMu.Unlock()
+
+	if !m.scanning && time.Since(m.scannedAt) > ScanMinInterval {
+		m.scanning = true
+		go m.scan(false)
+	}
+
+	return ScanResult{
+		Networks:   m.scanned,
+		ScannedAt:  m.scannedAt,
+		Err:        m.scanErr,
+		Refreshing: m.scanning,
+	}
+}
+
+// Scan runs a blocking scan and updates the cache. With rescan set, nmcli is
+// asked to perform a fresh radio scan instead of reporting its own cache.
+func (m *Manager) Scan(rescan bool) ScanResult {
+	m.scanMu.Lock()
+	m.scanning = true
+	m.scanMu.Unlock()
+
+	m.scan(rescan)
+
+	m.scanMu.Lock()
+	defer m.scanMu.Unlock()
+	return ScanResult{
+		Networks:  m.scanned,
+		ScannedAt: m.scannedAt,
+		Err:       m.scanErr,
+	}
+}
+
+func (m *Manager) scan(rescan bool) {
+	networks, err := m.listNetworks(rescan)
+
+	m.scanMu.Lock()
+	defer m.scanMu.Unlock()
+	if err == nil {
+		m.scanned = networks
+	}
+	m.scanErr = err
+	m.scannedAt = time.Now()
+	m.scanning = false
+}
+
 // ListNetworks returns available WiFi networks.
 func (m *Manager) ListNetworks() ([]NetworkInfo, error) {
-	cmd := exec.Command("nmcli", "-t", "-f", "SSID,SIGNAL,SECURITY,ACTIVE,FREQ", "dev", "wifi", "list")
-	output, err := cmd.Output()
+	return m.listNetworks(false)
+}
+
+func (m *Manager) listNetworks(rescan bool) ([]NetworkInfo, error) {
+	aps, err := m.backend.AccessPoints(rescan)
 	if err != nil {
 		m.log.Printf("failed to list networks: %v", err)
 		return nil, err
@@ -73,60 +158,39 @@ func (m *Manager) ListNetworks() ([]NetworkInfo, error) {
 	}
 
 	networkMap := make(map[string]*bandInfo)
-	for _, line := range strings.Split(string(output), "\n") {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
-
-		parts := strings.Split(line, ":")
-		if len(parts) < 5 {
-			continue
-		}
-
-		ssid := strings.TrimSpace(parts[0])
-		if ssid == "" {
+	for _, ap := range aps {
+		if ap.SSID == "" {
 			continue // Skip hidden networks for now
 		}
 
-		signal := 0
-		fmt.Sscanf(strings.TrimSpace(parts[1]), "%d", &signal)
-
-		security := strings.TrimSpace(parts[2])
-		active := strings.TrimSpace(parts[3]) == "yes"
-
-		// Parse frequency to determine band
-		freq := 0
-		fmt.Sscanf(strings.TrimSpace(parts[4]), "%d", &freq)
-
-		isBand24 := freq >= 2400 && freq < 3000
-		isBand5 := freq >= 5000 && freq < 6000
+		isBand24 := ap.Frequency >= 2400 && ap.Frequency < 3000
+		isBand5 := ap.Frequency >= 5000 && ap.Frequency < 6000
 
 		// Get or create entry for this SSID
-		if _, found := networkMap[ssid]; !found {
-			networkMap[ssid] = &bandInfo{
+		if _, found := networkMap[ap.SSID]; !found {
+			networkMap[ap.SSID] = &bandInfo{
 				net: NetworkInfo{
-					SSID:      ssid,
-					Signal:    signal,
-					Security:  security,
-					Connected: active,
+					SSID:      ap.SSID,
+					Signal:    ap.Signal,
+					Security:  ap.Security,
+					Connected: ap.Active,
 					DualBand:  false,
 				},
 			}
 		}
 
 		// Update with strongest signal
-		if signal > networkMap[ssid].net.Signal {
-			networkMap[ssid].net.Signal = signal
-			networkMap[ssid].net.Connected = active
+		if ap.Signal > networkMap[ap.SSID].net.Signal {
+			networkMap[ap.SSID].net.Signal = ap.Signal
+			networkMap[ap.SSID].net.Connected = ap.Active
 		}
 
 		// Track which bands this SSID appears on
 		if isBand24 {
-			networkMap[ssid].band24 = true
+			networkMap[ap.SSID].band24 = true
 		}
 		if isBand5 {
-			networkMap[ssid].band5 = true
+			networkMap[ap.SSID].band5 = true
 		}
 	}
 
@@ -144,136 +208,48 @@ func (m *Manager) ListNetworks() ([]NetworkInfo, error) {
 
 // GetConnectionStatus returns current WiFi connection status.
 func (m *Manager) GetConnectionStatus() *ConnectionInfo {
-	info := &ConnectionInfo{
-		Connected: false,
-	}
-
-	// Check if any WiFi connection is active
-	cmd := exec.Command("nmcli", "-t", "-f", "ACTIVE,NAME,TYPE", "con", "show", "--active")
-	output, err := cmd.Output()
-	if err != nil {
-		info.LastError = fmt.Sprintf("failed to get active connections: %v", err)
-		return info
-	}
-
-	var wifiConnName string
-	var isHotspot bool
-	for _, line := range strings.Split(string(output), "\n") {
-		parts := strings.Split(line, ":")
-		if len(parts) >= 3 && strings.Contains(parts[2], "802-11-wireless") {
-			wifiConnName = strings.TrimSpace(parts[1])
-			// Check if this is a hotspot connection
-			if strings.HasPrefix(wifiConnName, "srtla-hotspot-") {
-				isHotspot = true
-			}
-			break
-		}
-	}
-
-	if wifiConnName == "" {
-		return info
-	}
-
-	info.Connected = true
-
-	// If this is a hotspot, get hotspot-specific info
-	if isHotspot {
-		// Get SSID from the connection
-		cmd = exec.Command("nmcli", "-t", "-f", "802-11-wireless.ssid", "con", "show", wifiConnName)
-		output, err = cmd.Output()
-		if err == nil {
-			for _, line := range strings.Split(string(output), "\n") {
-				if strings.Contains(line, "ssid") {
-					parts := strings.Split(line, ":")
-					if len(parts) >= 2 {
-						info.SSID = strings.TrimSpace(parts[1])
-					}
-				}
-			}
-		}
-
-		// Get hotspot IP address
-		info.IP = m.GetHotspotIP()
-		if info.IP == "" {
-			info.IP = "10.42.0.1" // Default hotspot IP
-		}
-
-		// Get interface name
-		cmd = exec.Command("nmcli", "-t", "-f", "DEVICE", "con", "show", "--active", wifiConnName)
-		output, err = cmd.Output()
-		if err == nil {
-			for _, line := range strings.Split(string(output), "\n") {
-				if strings.Contains(line, "DEVICE") {
-					parts := strings.Split(line, ":")
-					if len(parts) >= 2 {
-						info.Interface = strings.TrimSpace(parts[1])
-					}
-				}
-			}
-		}
-
-		return info
-	}
-
-	info.Connected = true
-
-	// Get details about the WiFi connection
-	cmd = exec.Command("nmcli", "-t", "-f", "connection.id,802-11-wireless-properties.ssid,ipv4.addresses,signal", "con", "show", wifiConnName)
-	output, err = cmd.Output()
-	if err == nil {
-		for _, line := range strings.Split(string(output), "\n") {
-			if strings.Contains(line, "ssid") {
-				parts := strings.Split(line, ":")
-				if len(parts) >= 2 {
-					info.SSID = strings.TrimSpace(parts[1])
-				}
-			} else if strings.Contains(line, "addresses") {
-				parts := strings.Split(line, ":")
-				if len(parts) >= 2 {
-					addr := strings.TrimSpace(parts[1])
-					// Extract IP from CIDR format
-					if idx := strings.Index(addr, "/"); idx > 0 {
-						info.IP = addr[:idx]
-					}
-				}
-			}
-		}
-	}
-
-	// Get interface info for signal strength
-	cmd = exec.Command("nmcli", "-t", "-f", "DEVICE,SIGNAL", "dev", "wifi")
-	output, err = cmd.Output()
-	if err == nil {
-		for _, line := range strings.Split(string(output), "\n") {
-			parts := strings.Split(line, ":")
-			if len(parts) >= 2 {
-				iface := strings.TrimSpace(parts[0])
-				signal := 0
-				fmt.Sscanf(strings.TrimSpace(parts[1]), "%d", &signal)
-				if signal > 0 {
-					info.Interface = iface
-					info.Signal = signal
-					break
-				}
-			}
-		}
-	}
+	return m.backend.ConnectionStatus()
+}
 
-	return info
+// Backend returns the name of the NetworkManager backend in use.
+func (m *Manager) Backend() string {
+	return m.backend.Name()
 }
 
 // Connect connects to a WiFi network.
 func (m *Manager) Connect(ssid, password string) error {
+	return m.ConnectWithOptions(ConnectOptions{SSID: ssid, Password: password})
+}
+
+// ConnectWithOptions connects to a WiFi network, including hidden and
+// WPA-Enterprise networks.
+func (m *Manager) ConnectWithOptions(opts ConnectOptions) error {
 	if !m.IsAvailable() {
 		return fmt.Errorf("nmcli not available")
 	}
 
+	ssid := opts.SSID
 	m.log.Printf("connecting to WiFi network: %s", ssid)
 
+	switch opts.Security {
+	case "", SecurityPSK:
+	case SecurityEnterprise:
+		if err := m.connectEnterprise(opts); err != nil {
+			return err
+		}
+		m.log.Printf("connected to WiFi: %s", ssid)
+		return nil
+	default:
+		return fmt.Errorf("unsupported security type: %s", opts.Security)
+	}
+
 	// Try to connect (will create connection if it doesn't exist)
 	args := []string{"dev", "wifi", "connect", ssid}
-	if password != "" {
-		args = append(args, "password", password)
+	if opts.Password != "" {
+		args = append(args, "password", opts.Password)
+	}
+	if opts.Hidden {
+		args = append(args, "hidden", "yes")
 	}
 
 	cmd := exec.Command("nmcli", args...)
@@ -302,7 +278,7 @@ func (m *Manager) Disconnect() error {
 
 	var wifiDevice string
 	for _, line := range strings.Split(string(output), "\n") {
-		parts := strings.Split(line, ":")
+		parts := splitTerse(line)
 		if len(parts) >= 2 && strings.Contains(parts[1], "wifi") {
 			wifiDevice = strings.TrimSpace(parts[0])
 			break
@@ -344,7 +320,22 @@ func (m *Manager) CreateHotspot(config HotspotConfig) error {
 		return fmt.Errorf("password must be at least 8 characters")
 	}
 
-	m.log.Printf("creating hotspot: %s", config.SSID)
+	if config.Band == "" {
+		config.Band = Band24
+	}
+	choice := ChannelChoice{Band: config.Band, Channel: config.Channel, Reason: fmt.Sprintf("channel %d set by the user", config.Channel)}
+	if config.Channel == 0 {
+		// pick the channel before the device turns into an access point,
+		// after which it can't scan
+		var err error
+		choice, err = m.ChooseChannel(config.Band, ChannelRescanInterval)
+		if err != nil {
+			m.log.Printf("%v, leaving the channel to NetworkManager", err)
+			choice = ChannelChoice{Band: config.Band, Reason: "no scan available, picked by NetworkManager"}
+		}
+	}
+
+	m.log.Printf("creating hotspot: %s (%s GHz, %s)", config.SSID, config.Band, choice.Reason)
 
 	// Find WiFi device
 	cmd := exec.Command("nmcli", "-t", "-f", "DEVICE,TYPE", "dev")
@@ -355,7 +346,7 @@ func (m *Manager) CreateHotspot(config HotspotConfig) error {
 
 	var wifiDevice string
 	for _, line := range strings.Split(string(output), "\n") {
-		parts := strings.Split(line, ":")
+		parts := splitTerse(line)
 		if len(parts) >= 2 && strings.Contains(parts[1], "wifi") {
 			wifiDevice = strings.TrimSpace(parts[0])
 			break
@@ -390,9 +381,13 @@ func (m *Manager) CreateHotspot(config HotspotConfig) error {
 	wifiArgs := []string{
 		"con", "modify", connName,
 		"802-11-wireless.mode", "ap",
+		"802-11-wireless.band", nmBand(config.Band),
 		"802-11-wireless-security.key-mgmt", "wpa-psk",
 		"802-11-wireless-security.psk", config.Password,
 	}
+	if choice.Channel != 0 {
+		wifiArgs = append(wifiArgs, "802-11-wireless.channel", fmt.Sprint(choice.Channel))
+	}
 
 	cmd = exec.Command("nmcli", wifiArgs...)
 	output, err = cmd.CombinedOutput()
@@ -423,10 +418,22 @@ func (m *Manager) CreateHotspot(config HotspotConfig) error {
 		return fmt.Errorf("failed to activate hotspot: %w", err)
 	}
 
+	m.channelMu.Lock()
+	m.activeChannel = &choice
+	m.channelMu.Unlock()
+
 	m.log.Printf("hotspot activated: %s", config.SSID)
 	return nil
 }
 
+// nmBand is NetworkManager's name for a hotspot band
+func nmBand(band string) string {
+	if band == Band5 {
+		return "a"
+	}
+	return "bg"
+}
+
 // StopHotspot stops the hotspot and cleans up the connection.
 func (m *Manager) StopHotspot() error {
 	if !m.IsAvailable() {
@@ -440,9 +447,13 @@ func (m *Manager) StopHotspot() error {
 		return err
 	}
 
+	m.channelMu.Lock()
+	m.activeChannel = nil
+	m.channelMu.Unlock()
+
 	var lastErr error
 	for _, line := range strings.Split(string(output), "\n") {
-		parts := strings.Split(line, ":")
+		parts := splitTerse(line)
 		if len(parts) >= 2 {
 			connName := strings.TrimSpace(parts[0])
 			connType := strings.TrimSpace(parts[1])
@@ -465,6 +476,21 @@ func (m *Manager) StopHotspot() error {
 	return lastErr
 }
 
+// HotspotActive reports whether a hotspot created by CreateHotspot is up.
+func (m *Manager) HotspotActive() bool {
+	output, err := exec.Command("nmcli", "-t", "-f", "NAME,TYPE", "con", "show", "--active").Output()
+	if err != nil {
+		return false
+	}
+	for _, line := range strings.Split(string(output), "\n") {
+		parts := splitTerse(line)
+		if len(parts) >= 2 && strings.Contains(parts[1], "802-11-wireless") && strings.HasPrefix(strings.TrimSpace(parts[0]), "srtla-hotspot-") {
+			return true
+		}
+	}
+	return false
+}
+
 // ForgetNetwork removes a saved WiFi network.
 func (m *Manager) ForgetNetwork(ssid string) error {
 	if !m.IsAvailable() {
@@ -8,6 +8,10 @@ import (
 // GetHotspotIP returns the IP address of the active hotspot interface.
 // Returns empty string if no hotspot is active.
 func (m *Manager) GetHotspotIP() string {
+	return hotspotIP()
+}
+
+func hotspotIP() string {
 	// Look for active hotspot connection
 	cmd := exec.Command("nmcli", "-t", "-f", "NAME,DEVICE", "con", "show", "--active")
 	output, err := cmd.Output()
@@ -17,7 +21,7 @@ func (m *Manager) GetHotspotIP() string {
 
 	var hotspotDevice string
 	for _, line := range strings.Split(string(output), "\n") {
-		parts := strings.Split(line, ":")
+		parts := splitTerse(line)
 		if len(parts) >= 2 {
 			connName := strings.TrimSpace(parts[0])
 			device := strings.TrimSpace(parts[1])
@@ -1,7 +1,37 @@
 export class API {
+    // Extracts the message from an error response. API errors are JSON
+    // envelopes ({error, code, request_id, fields}); anything else is used as text.
+    static async errorMessage(resp) {
+        const text = await resp.text();
+        try {
+            const body = JSON.parse(text);
+            if (body && Array.isArray(body.fields) && body.fields.length > 0) {
+                return body.fields.map(f => `${f.field}: ${f.message}`).join('; ');
+            }
+            if (body && body.error) return body.error;
+        } catch (e) {
+            // not JSON
+        }
+        return text || `HTTP ${resp.status}`;
+    }
+
+    // Builds the Error thrown for an error response, carrying its status and
+    // any details the envelope has
+    static async error(resp) {
+        const text = await resp.clone().text();
+        const err = new Error(await API.errorMessage(resp));
+        err.status = resp.status;
+        try {
+            err.details = JSON.parse(text).details;
+        } catch (e) {
+            // not JSON
+        }
+        return err;
+    }
+
     static async get(url) {
         const resp = await fetch(url);
-        if (!resp.ok) throw new Error(`HTTP ${resp.status}`);
+        if (!resp.ok) throw await API.error(resp);
         return resp.json();
     }
 
@@ -11,10 +41,7 @@ export class API {
             headers: data ? { 'Content-Type': 'application/json' } : {},
             body: data ? JSON.stringify(data) : undefined
         });
-        if (!resp.ok) {
-            const text = await resp.text();
-            throw new Error(text || `HTTP ${resp.status}`);
-        }
+        if (!resp.ok) throw await API.error(resp);
         return resp.json().catch(() => ({}));
     }
 
@@ -24,16 +51,13 @@ export class API {
             headers: { 'Content-Type': 'application/json' },
             body: JSON.stringify(data)
         });
-        if (!resp.ok) {
-            const text = await resp.text();
-            throw new Error(text || `HTTP ${resp.status}`);
-        }
+        if (!resp.ok) throw await API.error(resp);
         return resp.json().catch(() => ({}));
     }
 
     static async delete(url) {
         const resp = await fetch(url, { method: 'DELETE' });
-        if (!resp.ok) throw new Error(`HTTP ${resp.status}`);
+        if (!resp.ok) throw await API.error(resp);
         return resp.json().catch(() => ({}));
     }
 }
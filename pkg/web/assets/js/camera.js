@@ -455,8 +455,11 @@ export class CameraManager {
         showNotification(`Starting preview for ${camera.name}...`, 'info');
 
         try {
-            // Request preview stream from camera
+            // Request preview stream from camera. The live settings are sent
+            // along so a preview that reuses the live stream matches them.
+            const liveConfig = await this.buildDefaultConfig(cameraId, wifiSSID, wifiPassword);
             const data = await API.post(`/api/cameras/${cameraId}/preview`, {
+                ...liveConfig,
                 wifi_ssid: wifiSSID,
                 wifi_password: wifiPassword
             });
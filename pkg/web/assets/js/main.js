@@ -20,7 +20,7 @@ class SRTLAManager {
         // Initialize managers
         this.chart = new ChartManager();
         this.updates = new UpdateManager();
-        this.ws = new WebSocketManager((msg) => this.handleMessage(msg));
+        this.ws = new WebSocketManager((msg) => this.handleMessage(msg), (full) => this.resync(full));
         this.modem = new ModemManager();
         this.usbnet = new USBNetManager();
         this.network = new NetworkManager();
@@ -32,6 +32,7 @@ class SRTLAManager {
     }
 
     init() {
+        this.loadBranding();
         this.chart.init();
         this.loadConfig();
         this.network.loadDependencies();
@@ -50,6 +51,27 @@ class SRTLAManager {
         setInterval(() => this.wifi.updateStatus(), CONFIG.wifiRefreshInterval);
     }
 
+    // Applies the branding pack from web.branding_dir: product name, logo
+    // and the colors in css/base.css
+    async loadBranding() {
+        try {
+            const branding = await API.get('/api/branding');
+            document.title = branding.product_name;
+            document.getElementById('productName').textContent = branding.product_name;
+            const logo = document.getElementById('brandLogo');
+            if (branding.logo) {
+                logo.src = branding.logo;
+                logo.alt = branding.product_name;
+                logo.hidden = false;
+            }
+            for (const [name, value] of Object.entries(branding.colors || {})) {
+                document.documentElement.style.setProperty(`--${name}`, value);
+            }
+        } catch (e) {
+            console.error('Failed to load branding:', e);
+        }
+    }
+
     async loadConfig() {
         try {
             const config = await API.get('/api/config');
@@ -104,9 +126,16 @@ class SRTLAManager {
             const config = {
                 rtmp: {
                     listen_port: parseInt(document.getElementById('rtmpPort').value),
-                    stream_key: document.getElementById('streamKey').value
+                    stream_key: document.getElementById('streamKey').value,
+                    reconnect_grace_seconds: currentConfig.rtmp?.reconnect_grace_seconds ?? 10,
+                    ingests: currentConfig.rtmp?.ingests,
+                    audio_resync: currentConfig.rtmp?.audio_resync ?? false
+                },
+                srt: {
+                    local_port: currentConfig.srt?.local_port || 6000,
+                    probe: currentConfig.srt?.probe || false,
+                    probe_port: currentConfig.srt?.probe_port || 6010
                 },
-                srt: { local_port: 6000 },
                 srtla: {
                     enabled: document.getElementById('srtlaEnabled').checked,
                     binary_path: 'srtla_send',
@@ -116,9 +145,52 @@ class SRTLAManager {
                     bind_ips_file: document.getElementById('ipsFilePath').value.trim(),
                     classic: currentConfig.srtla?.classic || false,
                     no_quality: currentConfig.srtla?.no_quality || false,
-                    exploration: currentConfig.srtla?.exploration || false
+                    exploration: currentConfig.srtla?.exploration || false,
+                    transport: currentConfig.srtla?.transport || 'auto',
+                    bind_sets: currentConfig.srtla?.bind_sets || {},
+                    bind_set: currentConfig.srtla?.bind_set || '',
+                    link_policies: currentConfig.srtla?.link_policies || {}
                 },
-                web: { port: 8080 },
+                web: { port: 8080, tls: currentConfig.web?.tls, branding_dir: currentConfig.web?.branding_dir },
+                power: currentConfig.power,
+                dji_preview: currentConfig.dji_preview,
+                talkback: currentConfig.talkback,
+                return_feed: currentConfig.return_feed,
+                switcher: currentConfig.switcher,
+                tally: currentConfig.tally,
+                timecode: currentConfig.timecode,
+                buttons: currentConfig.buttons,
+                display: currentConfig.display,
+                alerts: currentConfig.alerts,
+                notifications: currentConfig.notifications,
+                quota: currentConfig.quota,
+                modem_connect: currentConfig.modem_connect,
+                routing: currentConfig.routing,
+                overlay: currentConfig.overlay,
+                link_probe: currentConfig.link_probe,
+                vpn: currentConfig.vpn,
+                discovery: currentConfig.discovery,
+                start_policy: currentConfig.start_policy,
+                metrics: currentConfig.metrics,
+                audit: currentConfig.audit,
+                access: currentConfig.access,
+                receiver: currentConfig.receiver,
+                ingest: currentConfig.ingest,
+                loudness: currentConfig.loudness,
+                audio: currentConfig.audio,
+                abr: currentConfig.abr,
+                maintenance: currentConfig.maintenance,
+                rtsp: currentConfig.rtsp,
+                restream: currentConfig.restream,
+                srt_ingest: currentConfig.srt_ingest,
+                failover: currentConfig.failover,
+                speedtest: currentConfig.speedtest,
+                stun: currentConfig.stun,
+                hotspot: currentConfig.hotspot,
+                usb_cameras: currentConfig.usb_cameras,
+                camera_groups: currentConfig.camera_groups,
+                profiles: currentConfig.profiles || {},
+                profile: currentConfig.profile || '',
                 logging: currentConfig.logging || {
                     debug: false,
                     file_path: 'logs/srtla-manager.log',
@@ -134,6 +206,19 @@ class SRTLAManager {
         }
     }
 
+    // Reloads what a reconnect didn't replay: the logs always, and the rest
+    // of the state when the session was lost or events were missed
+    resync(full) {
+        this.loadLogs();
+        if (full) {
+            this.modem.load();
+            this.usbnet.load();
+            this.wifi.load();
+            this.camera.load();
+            this.usbcam.load();
+        }
+    }
+
     handleMessage(msg) {
         switch (msg.type) {
             case 'stats': this.updateStats(msg.data); break;
@@ -143,20 +228,28 @@ class SRTLAManager {
             case 'usbnet': this.usbnet.update(msg.data); break;
             case 'wifi': this.wifi.updateStatus(); break;
             case 'srtla_install': this.handleSRTLAInstallProgress(msg.data); break;
+            case 'config':
+                // the config file was edited on the unit; show what it holds now
+                if (msg.data.external) {
+                    this.loadConfig();
+                    showNotification('Configuration reloaded from file', 'info');
+                }
+                break;
         }
     }
 
     handleSRTLAInstallProgress(data) {
-        const { level, message } = data;
-        console.log(`[SRTLA Install ${level}]: ${message}`);
-        
-        // Show notification for important updates
+        const { job_id, kind, percent, level, message } = data;
+        console.log(`[SRTLA Install ${job_id} ${level}]: ${percent}% ${message}`);
+
+        // Messages are per job, so concurrent operations keep their own titles
+        const name = kind === 'update' ? 'Update' : 'Installation';
         if (level === 'error') {
-            showNotification('Installation Error', message, 'error');
+            showNotification(`${name} Error`, message, 'error');
         } else if (level === 'success') {
-            showNotification('Installation Success', message, 'success');
-        } else {
-            showNotification('Installation Progress', message, 'info');
+            showNotification(`${name} Success`, message, 'success');
+        } else if (!message.startsWith('Downloaded ')) {
+            showNotification(`${name} Progress (${percent}%)`, message, 'info');
         }
     }
 
@@ -192,9 +285,33 @@ class SRTLAManager {
             if (conn) conn.textContent = data.srtla.connections?.length || 0;
         }
 
+        this.updateLinkHealth(data.link_health);
         this.chart.update(data.ffmpeg?.bitrate, data.srtla?.bitrate);
     }
 
+    // One traffic light for the bonded uplink, with what costs it most
+    updateLinkHealth(health) {
+        const level = document.getElementById('linkHealthLevel');
+        if (!level) return;
+        const score = document.getElementById('linkHealthScore');
+        const links = document.getElementById('linkHealthLinks');
+        const reason = document.getElementById('linkHealthReason');
+        if (!health) {
+            level.textContent = '-';
+            level.className = 'status-indicator stopped';
+            if (score) score.textContent = '-';
+            if (links) links.textContent = '-';
+            if (reason) reason.textContent = '';
+            return;
+        }
+        const classes = { green: 'streaming', amber: 'waiting', red: 'error' };
+        level.textContent = health.level;
+        level.className = 'status-indicator ' + (classes[health.level] || 'stopped');
+        if (score) score.textContent = health.score;
+        if (links) links.textContent = `${health.links_up} / ${health.links}`;
+        if (reason) reason.textContent = health.reasons?.[0] || '';
+    }
+
     updatePipelineMode(mode) {
         const el = document.getElementById('pipelineMode');
         if (el) {
@@ -276,7 +393,10 @@ class SRTLAManager {
 
     async downloadLogs() {
         try {
-            window.location.href = '/api/logs/download';
+            const process = document.getElementById('logProcess')?.value;
+            window.location.href = process
+                ? `/api/logs/download?process=${encodeURIComponent(process)}`
+                : '/api/logs/download';
             showNotification('Downloading logs...', 'success');
         } catch (e) {
             showNotification(`Failed to download logs: ${e.message}`, 'error');
@@ -297,14 +417,30 @@ class SRTLAManager {
         }
     }
 
-    async startStream() {
+    async startStream(force = false) {
         try {
             document.getElementById('startBtn').disabled = true;
-            await API.post('/api/stream/start');
+            const resp = await API.post('/api/stream/start', force ? { force: true } : null);
             document.getElementById('stopBtn').disabled = false;
-            showNotification('Stream started');
+            const degraded = resp && resp.degraded;
+            if (degraded) {
+                const missing = degraded.missing.map(l => l.bind_ip).join(', ');
+                showNotification('Stream started',
+                    `On ${degraded.active_links} of ${degraded.configured_links} links; missing ${missing}`, 'info');
+            } else {
+                showNotification('Stream started');
+            }
         } catch (e) {
             document.getElementById('startBtn').disabled = false;
+            // the start policy isn't met: say why and let the operator
+            // decide to go live anyway
+            if (e.status === 412 && !force) {
+                const reasons = (e.details || []).map(c => `- ${c.message}`).join('\n');
+                if (confirm(`The stream isn't ready to start:\n${reasons}\n\nStart anyway?`)) {
+                    return this.startStream(true);
+                }
+                return;
+            }
             showNotification(`Failed to start: ${e.message}`, 'error');
         }
     }
@@ -454,7 +590,7 @@ class SRTLAManager {
             scanCamerasBtn: () => this.camera.startScan(),
             stopScanBtn: () => this.camera.stopScan(),
             scanUSBCamsBtn: () => this.usbcam.scan(),
-            refreshWiFiNetworks: () => this.wifi.updateNetworks(),
+            refreshWiFiNetworks: () => this.wifi.updateNetworks(true),
             connectWiFiBtn: () => this.wifi.connect(),
             disconnectWiFiBtn: () => this.wifi.disconnect(),
             createHotspotBtn: () => this.wifi.createHotspot(),
@@ -472,6 +608,15 @@ class SRTLAManager {
             if (el) el.addEventListener('input', () => this.updateRTMPUrl());
         });
 
+        // Show 802.1X fields only for enterprise networks
+        const wifiSecurity = document.getElementById('wifiSecurity');
+        if (wifiSecurity) {
+            wifiSecurity.addEventListener('change', (e) => {
+                const fields = document.getElementById('wifiEnterpriseFields');
+                if (fields) fields.style.display = e.target.value === 'wpa-eap' ? '' : 'none';
+            });
+        }
+
         // Debug mode toggle
         const debugToggle = document.getElementById('debugModeToggle');
         if (debugToggle) {
@@ -499,6 +644,14 @@ class SRTLAManager {
                 const id = t.getAttribute('data-camera-id');
                 if (id && confirm('Remove this camera?')) this.camera.forget(id);
             }
+            if (t.classList.contains('btn-usbnet-rename')) {
+                const id = t.getAttribute('data-usbnet-id');
+                if (id) this.usbnet.rename(id, t.getAttribute('data-usbnet-name'));
+            }
+            if (t.classList.contains('btn-usbnet-forget')) {
+                const id = t.getAttribute('data-usbnet-id');
+                if (id) this.usbnet.forget(id);
+            }
             if (t.classList.contains('modem-ussd-btn') && !t.disabled) {
                 const id = t.getAttribute('data-modem-id');
                 if (id) this.modem.promptUSSD(id);
@@ -94,7 +94,7 @@ export class ModemManager {
                 body: JSON.stringify({ code })
             });
             if (!resp.ok) {
-                const err = await resp.text();
+                const err = await API.errorMessage(resp);
                 showNotification(`USSD failed: ${err}`, 'error');
                 return;
             }
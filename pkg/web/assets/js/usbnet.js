@@ -1,6 +1,6 @@
 // USB Network Device Management
 import { API } from './api.js';
-import { escapeHtml } from './utils.js';
+import { escapeHtml, showNotification } from './utils.js';
 
 export class USBNetManager {
     constructor() {}
@@ -20,12 +20,38 @@ export class USBNetManager {
         const grid = document.getElementById('usbnetGrid');
         if (!grid) return;
 
-        if (!data.devices || data.devices.length === 0) {
+        const devices = data.devices || [];
+        // named devices that aren't plugged in can still be renamed or forgotten
+        const connected = new Set(devices.map(dev => dev.id));
+        const absent = Object.entries(data.mappings || {})
+            .filter(([id]) => !connected.has(id))
+            .map(([id, m]) => ({ id, name: m.name, interface: m.last_interface, last_seen: m.last_seen, state: 'absent' }));
+
+        if (devices.length === 0 && absent.length === 0) {
             grid.innerHTML = '<div class="usbnet-none">No USB RNDIS devices detected</div>';
             return;
         }
 
-        grid.innerHTML = data.devices.map(dev => this.renderCard(dev)).join('');
+        grid.innerHTML = [...devices, ...absent].map(dev => this.renderCard(dev)).join('');
+    }
+
+    async rename(id, current) {
+        const name = prompt('Name for this device (e.g. Verizon SIM 1):', current || '');
+        if (name === null) return;
+        try {
+            this.update(await API.put(`/api/usbnet/${encodeURIComponent(id)}`, { name }));
+        } catch (e) {
+            showNotification(`Rename failed: ${e.message}`, 'error');
+        }
+    }
+
+    async forget(id) {
+        if (!confirm('Forget the name of this device?')) return;
+        try {
+            this.update(await API.delete(`/api/usbnet/${encodeURIComponent(id)}`));
+        } catch (e) {
+            showNotification(`Forget failed: ${e.message}`, 'error');
+        }
     }
 
     renderCard(device) {
@@ -38,7 +64,7 @@ export class USBNetManager {
         return `
             <div class="usbnet-card ${errorClass}">
                 <div class="device-header">
-                    <span class="device-serial">Serial: ${escapeHtml(device.serial || 'Unknown')}</span>
+                    <span class="device-serial">${device.name ? escapeHtml(device.name) : `Serial: ${escapeHtml(device.serial || 'Unknown')}`}</span>
                     <span class="device-state ${stateClass}">${device.state || 'unknown'}</span>
                 </div>
                 <div class="device-details">
@@ -57,6 +83,10 @@ export class USBNetManager {
                 </div>
                 ${errorMsg}
                 <div class="device-timestamp">Last seen: ${lastSeen}</div>
+                <div class="device-actions">
+                    <button class="btn btn-small btn-usbnet-rename" data-usbnet-id="${escapeHtml(device.id || '')}" data-usbnet-name="${escapeHtml(device.name || '')}">Rename</button>
+                    ${device.name ? `<button class="btn btn-small btn-usbnet-forget" data-usbnet-id="${escapeHtml(device.id || '')}">Forget</button>` : ''}
+                </div>
             </div>`;
     }
 }
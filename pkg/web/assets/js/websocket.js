@@ -1,16 +1,32 @@
 // WebSocket connection manager
 export class WebSocketManager {
-    constructor(onMessage) {
+    // onResync is called after a reconnect that couldn't replay everything
+    // missed, so the state has to be loaded again
+    constructor(onMessage, onResync) {
         this.ws = null;
         this.onMessage = onMessage;
+        this.onResync = onResync;
         this.reconnectInterval = 3000;
+        this.session = null;
     }
 
     connect() {
         const protocol = window.location.protocol === 'https:' ? 'wss:' : 'ws:';
-        const wsUrl = `${protocol}//${window.location.host}/ws`;
+        // Opening the UI with ?encoding=cbor asks for binary stats frames,
+        // which are much smaller on metered links
+        const params = new URLSearchParams();
+        if (new URLSearchParams(window.location.search).get('encoding') === 'cbor') {
+            params.set('encoding', 'cbor');
+        }
+        // Resuming the last session replays what was missed while away
+        if (this.session) {
+            params.set('session', this.session);
+        }
+        const query = params.toString() ? `?${params}` : '';
+        const wsUrl = `${protocol}//${window.location.host}/ws${query}`;
         
         this.ws = new WebSocket(wsUrl);
+        this.ws.binaryType = 'arraybuffer';
         
         this.ws.onopen = () => this.updateStatus(true);
         this.ws.onclose = () => {
@@ -20,7 +36,13 @@ export class WebSocketManager {
         this.ws.onerror = () => this.updateStatus(false);
         this.ws.onmessage = (event) => {
             try {
-                const msg = JSON.parse(event.data);
+                const msg = typeof event.data === 'string'
+                    ? JSON.parse(event.data)
+                    : decodeCBOR(event.data);
+                if (msg.type === 'session') {
+                    this.handleSession(msg.data);
+                    return;
+                }
                 this.onMessage(msg);
             } catch (e) {
                 console.error('Failed to parse message:', e);
@@ -28,6 +50,15 @@ export class WebSocketManager {
         };
     }
 
+    handleSession(info) {
+        const reconnected = this.session !== null;
+        this.session = info.token;
+        if (reconnected && this.onResync) {
+            // log lines are never replayed, so they are always reloaded
+            this.onResync(!info.resumed || info.gap);
+        }
+    }
+
     updateStatus(connected) {
         const el = document.getElementById('wsStatus');
         if (el) {
@@ -36,3 +67,62 @@ export class WebSocketManager {
         }
     }
 }
+
+// Decodes the subset of CBOR the server emits: integers, floats, strings,
+// byte strings, arrays, maps, booleans and null.
+function decodeCBOR(buffer) {
+    const view = new DataView(buffer);
+    const bytes = new Uint8Array(buffer);
+    const text = new TextDecoder();
+    let offset = 0;
+
+    const readArg = (info) => {
+        if (info < 24) return info;
+        let value;
+        switch (info) {
+            case 24: value = view.getUint8(offset); offset += 1; break;
+            case 25: value = view.getUint16(offset); offset += 2; break;
+            case 26: value = view.getUint32(offset); offset += 4; break;
+            case 27: value = Number(view.getBigUint64(offset)); offset += 8; break;
+            default: throw new Error(`Unsupported CBOR argument ${info}`);
+        }
+        return value;
+    };
+
+    const decode = () => {
+        const head = view.getUint8(offset++);
+        const major = head >> 5;
+        const info = head & 0x1f;
+
+        if (major === 7) {
+            switch (info) {
+                case 20: return false;
+                case 21: return true;
+                case 22: return null;
+                case 26: { const f = view.getFloat32(offset); offset += 4; return f; }
+                case 27: { const f = view.getFloat64(offset); offset += 8; return f; }
+                default: throw new Error(`Unsupported CBOR simple value ${info}`);
+            }
+        }
+
+        const arg = readArg(info);
+        switch (major) {
+            case 0: return arg;
+            case 1: return -1 - arg;
+            case 2: { const b = bytes.slice(offset, offset + arg); offset += arg; return b; }
+            case 3: { const s = text.decode(bytes.subarray(offset, offset + arg)); offset += arg; return s; }
+            case 4: return Array.from({ length: arg }, decode);
+            case 5: {
+                const obj = {};
+                for (let i = 0; i < arg; i++) {
+                    const key = decode();
+                    obj[key] = decode();
+                }
+                return obj;
+            }
+            default: throw new Error(`Unsupported CBOR major type ${major}`);
+        }
+    };
+
+    return decode();
+}
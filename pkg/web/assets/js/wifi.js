@@ -50,9 +50,9 @@ export class WiFiManager {
         }
     }
 
-    async updateNetworks() {
+    async updateNetworks(force = false) {
         try {
-            const data = await API.get('/api/wifi/networks');
+            const data = await API.get(force ? '/api/wifi/networks?force=true' : '/api/wifi/networks');
             const list = document.getElementById('wifiNetworksList');
             if (!list) return;
             
@@ -106,8 +106,37 @@ export class WiFiManager {
             return;
         }
 
+        const req = {
+            ssid,
+            password,
+            hidden: document.getElementById('wifiHidden')?.checked || false,
+            security: document.getElementById('wifiSecurity')?.value || 'wpa-psk'
+        };
+
         try {
-            const data = await API.post('/api/wifi/connect', { ssid, password });
+            if (req.security === 'wpa-eap') {
+                req.identity = document.getElementById('wifiIdentity')?.value.trim() || '';
+                req.eap_method = document.getElementById('wifiEAPMethod')?.value || 'peap';
+                if (!req.identity) {
+                    showNotification('Please enter an identity', 'error');
+                    return;
+                }
+
+                const certFile = document.getElementById('wifiCACert')?.files[0];
+                if (certFile) {
+                    const resp = await fetch(`/api/wifi/ca-cert?name=${encodeURIComponent(ssid)}`, {
+                        method: 'POST',
+                        body: await certFile.text()
+                    });
+                    if (!resp.ok) {
+                        showNotification(`CA certificate rejected: ${await API.errorMessage(resp)}`, 'error');
+                        return;
+                    }
+                    req.ca_cert_path = (await resp.json()).path;
+                }
+            }
+
+            const data = await API.post('/api/wifi/connect', req);
             if (data.success) {
                 this.lastWifiCreds = { ssid, password };
                 showNotification(data.message, 'success');
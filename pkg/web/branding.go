@@ -0,0 +1,107 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// BrandingFile is the description of a branding pack, in the pack's directory
+const BrandingFile = "branding.json"
+
+// DefaultProductName is shown when no branding pack sets one
+const DefaultProductName = "SRTLA Manager"
+
+// BrandingColors are the colors a branding pack may override, named after the
+// CSS variables in css/base.css without the leading dashes
+var BrandingColors = []string{
+	"bg-primary", "bg-secondary", "bg-card", "bg-elevated",
+	"text-primary", "text-secondary", "text-muted",
+	"accent", "accent-light", "accent-glow",
+	"success", "warning", "error",
+	"border", "border-light",
+}
+
+// Branding is what a branding pack changes besides the files it overrides
+type Branding struct {
+	ProductName string            `json:"product_name"`
+	Logo        string            `json:"logo,omitempty"` // path of an image within the pack
+	Colors      map[string]string `json:"colors,omitempty"`
+}
+
+// DefaultBranding is the look of the embedded assets
+func DefaultBranding() Branding {
+	return Branding{ProductName: DefaultProductName, Colors: map[string]string{}}
+}
+
+// LoadBranding reads branding.json from a pack directory. A pack without one
+// only overrides files.
+func LoadBranding(dir string) (Branding, error) {
+	b := DefaultBranding()
+	data, err := os.ReadFile(filepath.Join(dir, BrandingFile))
+	if os.IsNotExist(err) {
+		return b, nil
+	}
+	if err != nil {
+		return b, err
+	}
+	if err := json.Unmarshal(data, &b); err != nil {
+		return DefaultBranding(), fmt.Errorf("%s: %w", BrandingFile, err)
+	}
+	if b.ProductName == "" {
+		b.ProductName = DefaultProductName
+	}
+	if b.Colors == nil {
+		b.Colors = map[string]string{}
+	}
+
+	if b.Logo != "" && !fs.ValidPath(b.Logo) {
+		return DefaultBranding(), fmt.Errorf("%s: logo must be a path within the pack", BrandingFile)
+	}
+	for name, value := range b.Colors {
+		if !contains(BrandingColors, name) {
+			return DefaultBranding(), fmt.Errorf("%s: unknown color %q", BrandingFile, name)
+		}
+		// the values end up in a style attribute
+		if value == "" || strings.ContainsAny(value, ";{}<>\"'\\") {
+			return DefaultBranding(), fmt.Errorf("%s: invalid value for color %q", BrandingFile, name)
+		}
+	}
+	return b, nil
+}
+
+// Overlay serves files from the branding pack directory returned by dir,
+// falling back to base for anything the pack doesn't have. dir is consulted
+// on every request, so switching packs doesn't need a restart.
+func Overlay(base fs.FS, dir func() string) fs.FS {
+	return overlayFS{base: base, dir: dir}
+}
+
+type overlayFS struct {
+	base fs.FS
+	dir  func() string
+}
+
+func (o overlayFS) Open(name string) (fs.File, error) {
+	if d := o.dir(); d != "" {
+		if f, err := os.DirFS(d).Open(name); err == nil {
+			if fi, err := f.Stat(); err == nil && !fi.IsDir() {
+				return f, nil
+			}
+			f.Close()
+		}
+	}
+	return o.base.Open(name)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
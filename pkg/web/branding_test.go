@@ -0,0 +1,82 @@
+package web
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestOverlay(t *testing.T) {
+	base := fstest.MapFS{
+		"index.html":   {Data: []byte("embedded index")},
+		"css/base.css": {Data: []byte("embedded css")},
+	}
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "css"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "css", "base.css"), []byte("pack css"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	pack := ""
+	fsys := Overlay(base, func() string { return pack })
+	read := func(name string) string {
+		f, err := fsys.Open(name)
+		if err != nil {
+			t.Fatalf("open %s: %v", name, err)
+		}
+		defer f.Close()
+		data, _ := io.ReadAll(f)
+		return string(data)
+	}
+
+	if got := read("css/base.css"); got != "embedded css" {
+		t.Errorf("without a pack got %q", got)
+	}
+	pack = dir
+	if got := read("css/base.css"); got != "pack css" {
+		t.Errorf("with a pack got %q", got)
+	}
+	if got := read("index.html"); got != "embedded index" {
+		t.Errorf("file missing from the pack got %q", got)
+	}
+	if _, err := fsys.Open("../etc/passwd"); err == nil {
+		t.Error("opened a path outside the pack")
+	}
+}
+
+func TestLoadBranding(t *testing.T) {
+	tests := []struct {
+		name    string
+		json    string
+		wantErr bool
+		want    string
+	}{
+		{"no file", "", false, DefaultProductName},
+		{"name and colors", `{"product_name": "RentaCast", "colors": {"accent": "#ff6600"}}`, false, "RentaCast"},
+		{"unknown color", `{"colors": {"font": "red"}}`, true, DefaultProductName},
+		{"style injection", `{"colors": {"accent": "red; background: url(x)"}}`, true, DefaultProductName},
+		{"logo outside the pack", `{"logo": "../logo.png"}`, true, DefaultProductName},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if tt.json != "" {
+				if err := os.WriteFile(filepath.Join(dir, BrandingFile), []byte(tt.json), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			b, err := LoadBranding(dir)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v", err)
+			}
+			if b.ProductName != tt.want {
+				t.Errorf("got product name %q, want %q", b.ProductName, tt.want)
+			}
+		})
+	}
+}